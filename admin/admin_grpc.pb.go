@@ -0,0 +1,221 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: admin.proto
+
+package admin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// LogAdminClient is the client API for LogAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LogAdminClient interface {
+	// ListLoggers lists registered loggers with their current levels.
+	ListLoggers(ctx context.Context, in *ListLoggersRequest, opts ...grpc.CallOption) (*ListLoggersResponse, error)
+	// GetLevel returns current level of logger.
+	GetLevel(ctx context.Context, in *GetLevelRequest, opts ...grpc.CallOption) (*LoggerLevel, error)
+	// SetLevel changes level of logger.
+	SetLevel(ctx context.Context, in *SetLevelRequest, opts ...grpc.CallOption) (*LoggerLevel, error)
+	// DumpEffectiveConfig returns effective config of logger in JSON.
+	DumpEffectiveConfig(ctx context.Context, in *DumpEffectiveConfigRequest, opts ...grpc.CallOption) (*DumpEffectiveConfigResponse, error)
+}
+
+type logAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLogAdminClient(cc grpc.ClientConnInterface) LogAdminClient {
+	return &logAdminClient{cc}
+}
+
+func (c *logAdminClient) ListLoggers(ctx context.Context, in *ListLoggersRequest, opts ...grpc.CallOption) (*ListLoggersResponse, error) {
+	out := new(ListLoggersResponse)
+	err := c.cc.Invoke(ctx, "/rk.logger.admin.v1.LogAdmin/ListLoggers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logAdminClient) GetLevel(ctx context.Context, in *GetLevelRequest, opts ...grpc.CallOption) (*LoggerLevel, error) {
+	out := new(LoggerLevel)
+	err := c.cc.Invoke(ctx, "/rk.logger.admin.v1.LogAdmin/GetLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logAdminClient) SetLevel(ctx context.Context, in *SetLevelRequest, opts ...grpc.CallOption) (*LoggerLevel, error) {
+	out := new(LoggerLevel)
+	err := c.cc.Invoke(ctx, "/rk.logger.admin.v1.LogAdmin/SetLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logAdminClient) DumpEffectiveConfig(ctx context.Context, in *DumpEffectiveConfigRequest, opts ...grpc.CallOption) (*DumpEffectiveConfigResponse, error) {
+	out := new(DumpEffectiveConfigResponse)
+	err := c.cc.Invoke(ctx, "/rk.logger.admin.v1.LogAdmin/DumpEffectiveConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LogAdminServer is the server API for LogAdmin service.
+// All implementations must embed UnimplementedLogAdminServer
+// for forward compatibility
+type LogAdminServer interface {
+	// ListLoggers lists registered loggers with their current levels.
+	ListLoggers(context.Context, *ListLoggersRequest) (*ListLoggersResponse, error)
+	// GetLevel returns current level of logger.
+	GetLevel(context.Context, *GetLevelRequest) (*LoggerLevel, error)
+	// SetLevel changes level of logger.
+	SetLevel(context.Context, *SetLevelRequest) (*LoggerLevel, error)
+	// DumpEffectiveConfig returns effective config of logger in JSON.
+	DumpEffectiveConfig(context.Context, *DumpEffectiveConfigRequest) (*DumpEffectiveConfigResponse, error)
+	mustEmbedUnimplementedLogAdminServer()
+}
+
+// UnimplementedLogAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedLogAdminServer struct {
+}
+
+func (UnimplementedLogAdminServer) ListLoggers(context.Context, *ListLoggersRequest) (*ListLoggersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLoggers not implemented")
+}
+func (UnimplementedLogAdminServer) GetLevel(context.Context, *GetLevelRequest) (*LoggerLevel, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLevel not implemented")
+}
+func (UnimplementedLogAdminServer) SetLevel(context.Context, *SetLevelRequest) (*LoggerLevel, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLevel not implemented")
+}
+func (UnimplementedLogAdminServer) DumpEffectiveConfig(context.Context, *DumpEffectiveConfigRequest) (*DumpEffectiveConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DumpEffectiveConfig not implemented")
+}
+func (UnimplementedLogAdminServer) mustEmbedUnimplementedLogAdminServer() {}
+
+// UnsafeLogAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LogAdminServer will
+// result in compilation errors.
+type UnsafeLogAdminServer interface {
+	mustEmbedUnimplementedLogAdminServer()
+}
+
+func RegisterLogAdminServer(s grpc.ServiceRegistrar, srv LogAdminServer) {
+	s.RegisterService(&LogAdmin_ServiceDesc, srv)
+}
+
+func _LogAdmin_ListLoggers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLoggersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogAdminServer).ListLoggers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rk.logger.admin.v1.LogAdmin/ListLoggers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogAdminServer).ListLoggers(ctx, req.(*ListLoggersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogAdmin_GetLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogAdminServer).GetLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rk.logger.admin.v1.LogAdmin/GetLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogAdminServer).GetLevel(ctx, req.(*GetLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogAdmin_SetLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogAdminServer).SetLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rk.logger.admin.v1.LogAdmin/SetLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogAdminServer).SetLevel(ctx, req.(*SetLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogAdmin_DumpEffectiveConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DumpEffectiveConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogAdminServer).DumpEffectiveConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rk.logger.admin.v1.LogAdmin/DumpEffectiveConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogAdminServer).DumpEffectiveConfig(ctx, req.(*DumpEffectiveConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LogAdmin_ServiceDesc is the grpc.ServiceDesc for LogAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LogAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rk.logger.admin.v1.LogAdmin",
+	HandlerType: (*LogAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListLoggers",
+			Handler:    _LogAdmin_ListLoggers_Handler,
+		},
+		{
+			MethodName: "GetLevel",
+			Handler:    _LogAdmin_GetLevel_Handler,
+		},
+		{
+			MethodName: "SetLevel",
+			Handler:    _LogAdmin_SetLevel_Handler,
+		},
+		{
+			MethodName: "DumpEffectiveConfig",
+			Handler:    _LogAdmin_DumpEffectiveConfig_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin.proto",
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package admin provides gRPC service which manages levels and inspects configs of loggers
+// registered in rklogger.LevelRegistry. admin.pb.go and admin_grpc.pb.go are regenerated by make proto.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/rookie-ninja/rk-logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements LogAdminServer with levels and configs in rklogger.LevelRegistry.
+type Server struct {
+	UnimplementedLogAdminServer
+	registry *rklogger.LevelRegistry
+}
+
+// NewServer creates Server with registry, rklogger.DefaultLevelRegistry() is used if registry is nil.
+func NewServer(registry *rklogger.LevelRegistry) *Server {
+	if registry == nil {
+		registry = rklogger.DefaultLevelRegistry()
+	}
+
+	return &Server{registry: registry}
+}
+
+// Register registers LogAdmin service with levels in registry to grpc server.
+func Register(server *grpc.Server, registry *rklogger.LevelRegistry) {
+	RegisterLogAdminServer(server, NewServer(registry))
+}
+
+// ListLoggers lists registered loggers with their current levels.
+func (server *Server) ListLoggers(ctx context.Context, req *ListLoggersRequest) (*ListLoggersResponse, error) {
+	res := &ListLoggersResponse{}
+	for _, name := range server.registry.Names() {
+		if level, err := server.registry.GetLevel(name); err == nil {
+			res.Loggers = append(res.Loggers, &LoggerLevel{Name: name, Level: rklogger.LevelName(level)})
+		}
+	}
+
+	return res, nil
+}
+
+// GetLevel returns current level of logger.
+func (server *Server) GetLevel(ctx context.Context, req *GetLevelRequest) (*LoggerLevel, error) {
+	level, err := server.registry.GetLevel(req.GetName())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return &LoggerLevel{Name: req.GetName(), Level: rklogger.LevelName(level)}, nil
+}
+
+// SetLevel changes level of logger.
+func (server *Server) SetLevel(ctx context.Context, req *SetLevelRequest) (*LoggerLevel, error) {
+	level, err := rklogger.ParseLevel(req.GetLevel())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := server.registry.SetLevel(req.GetName(), level); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return &LoggerLevel{Name: req.GetName(), Level: rklogger.LevelName(level)}, nil
+}
+
+// DumpEffectiveConfig returns effective config of logger in JSON.
+func (server *Server) DumpEffectiveConfig(ctx context.Context, req *DumpEffectiveConfigRequest) (*DumpEffectiveConfigResponse, error) {
+	config, err := server.registry.Config(req.GetName())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &DumpEffectiveConfigResponse{Name: req.GetName(), Config: string(bytes)}, nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+// Start grpc server with LogAdmin service over in-memory connection and create client of it
+func newTestClient(t *testing.T, registry *rklogger.LevelRegistry) LogAdminClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	Register(server, registry)
+	go server.Serve(listener)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+
+	return NewLogAdminClient(conn)
+}
+
+// Create registry with config of logger named db
+func newTestRegistry() *rklogger.LevelRegistry {
+	config := zap.NewProductionConfig()
+	config.OutputPaths = []string{"stdout"}
+
+	registry := rklogger.NewLevelRegistry()
+	registry.RegisterConfig("db", rklogger.NewConfig(&config, nil))
+	registry.Register("app", zap.NewAtomicLevelAt(zap.DebugLevel))
+
+	return registry
+}
+
+func TestNewServer_WithNilRegistry(t *testing.T) {
+	server := NewServer(nil)
+	assert.Equal(t, rklogger.DefaultLevelRegistry(), server.registry)
+}
+
+func TestServer_ListLoggers(t *testing.T) {
+	client := newTestClient(t, newTestRegistry())
+
+	res, err := client.ListLoggers(context.Background(), &ListLoggersRequest{})
+	assert.Nil(t, err)
+	assert.Len(t, res.GetLoggers(), 2)
+	assert.Equal(t, "app", res.GetLoggers()[0].GetName())
+	assert.Equal(t, "debug", res.GetLoggers()[0].GetLevel())
+	assert.Equal(t, "db", res.GetLoggers()[1].GetName())
+	assert.Equal(t, "info", res.GetLoggers()[1].GetLevel())
+}
+
+func TestServer_GetLevel(t *testing.T) {
+	client := newTestClient(t, newTestRegistry())
+
+	res, err := client.GetLevel(context.Background(), &GetLevelRequest{Name: "db"})
+	assert.Nil(t, err)
+	assert.Equal(t, "info", res.GetLevel())
+
+	// with unknown logger
+	_, err = client.GetLevel(context.Background(), &GetLevelRequest{Name: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_SetLevel(t *testing.T) {
+	registry := newTestRegistry()
+	client := newTestClient(t, registry)
+
+	res, err := client.SetLevel(context.Background(), &SetLevelRequest{Name: "db", Level: "warn"})
+	assert.Nil(t, err)
+	assert.Equal(t, "warn", res.GetLevel())
+
+	level, err := registry.GetLevel("db")
+	assert.Nil(t, err)
+	assert.Equal(t, zap.WarnLevel, level)
+
+	// with invalid level
+	_, err = client.SetLevel(context.Background(), &SetLevelRequest{Name: "db", Level: "invalid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// with unknown logger
+	_, err = client.SetLevel(context.Background(), &SetLevelRequest{Name: "unknown", Level: "warn"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_DumpEffectiveConfig(t *testing.T) {
+	registry := newTestRegistry()
+	client := newTestClient(t, registry)
+	assert.Nil(t, registry.SetLevel("db", zap.ErrorLevel))
+
+	res, err := client.DumpEffectiveConfig(context.Background(), &DumpEffectiveConfigRequest{Name: "db"})
+	assert.Nil(t, err)
+	assert.Equal(t, "db", res.GetName())
+
+	config := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal([]byte(res.GetConfig()), &config))
+	assert.Equal(t, "error", config["zap"].(map[string]interface{})["level"])
+
+	// with logger registered without config
+	_, err = client.DumpEffectiveConfig(context.Background(), &DumpEffectiveConfigRequest{Name: "app"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
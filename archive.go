@@ -0,0 +1,194 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultArchiveKey is the default key template of archived files.
+	DefaultArchiveKey = "{hostname}/{name}"
+	// DefaultArchiveRetries is the default number of retries of failed uploads.
+	DefaultArchiveRetries = 3
+	// DefaultArchiveBackoff is the default backoff of the first retry, which is doubled for each retry.
+	DefaultArchiveBackoff = time.Second
+	// DefaultArchiveTimeout is the default timeout of each upload.
+	DefaultArchiveTimeout = 5 * time.Minute
+)
+
+// Archiver uploads file to object storage with key.
+type Archiver interface {
+	Archive(ctx context.Context, path, key string) error
+}
+
+// ArchiverFactory creates archiver with archive config.
+type ArchiverFactory func(config *ArchiveConfig) (Archiver, error)
+
+// ArchiveConfig uploads rotated files of file outputs to object storage, after hooks of rotation section.
+// Providers are registered by importing their packages, like github.com/rookie-ninja/rk-logger/archive/s3,
+// or with RegisterArchiver().
+//
+// Example config file in YAML:
+//
+//	archive:
+//	  provider: s3
+//	  bucket: app-logs
+//	  key: "{hostname}/%Y/%m/%d/{name}"
+//	  encryption: aws:kms
+//	  encryptionKey: alias/app-logs
+//	  deleteAfterUpload: true
+//	  retries: 5
+type ArchiveConfig struct {
+	// Provider is s3, gcs, azure or provider registered with RegisterArchiver().
+	Provider string `json:"provider" yaml:"provider"`
+	// Bucket is bucket of s3 and gcs, or container of azure.
+	Bucket string `json:"bucket" yaml:"bucket"`
+	// Key is template of object keys, {name} is name of file and tokens of ExpandFilename() are expanded with
+	// modification time of file in UTC, DefaultArchiveKey by default.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// Encryption is server side encryption of provider, like AES256 or aws:kms of s3.
+	Encryption string `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	// EncryptionKey is key of server side encryption, like KMS key id of s3, KMS key name of gcs
+	// or encryption scope of azure.
+	EncryptionKey string `json:"encryptionKey,omitempty" yaml:"encryptionKey,omitempty"`
+	// DeleteAfterUpload deletes files once they are uploaded.
+	DeleteAfterUpload bool `json:"deleteAfterUpload,omitempty" yaml:"deleteAfterUpload,omitempty"`
+	// Retries is the number of retries of failed uploads, DefaultArchiveRetries if zero, no retry if negative.
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// Backoff is backoff of the first retry which is doubled for each retry, like 1s, DefaultArchiveBackoff by default.
+	Backoff string `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	// Timeout is timeout of each upload, like 10m, DefaultArchiveTimeout by default.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Region is region of provider, like us-east-1 of s3.
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+	// Endpoint replaces endpoint of provider, like endpoint of minio or emulators.
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// Options are settings of providers, see documents of providers.
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+var (
+	// archiver factories keyed by lower case provider
+	archiverFactories = make(map[string]ArchiverFactory)
+	archiverMutex     sync.RWMutex
+)
+
+// RegisterArchiver registers factory of archivers with provider, so that it could be chosen by provider of
+// archive section. Error would be returned if the provider is already registered.
+func RegisterArchiver(provider string, factory ArchiverFactory) error {
+	if factory == nil {
+		return errors.Errorf("archiver factory is nil, provider:%s", provider)
+	}
+
+	provider = strings.ToLower(provider)
+
+	archiverMutex.Lock()
+	defer archiverMutex.Unlock()
+
+	if _, ok := archiverFactories[provider]; ok {
+		return errors.Errorf("archiver is already registered, provider:%s", provider)
+	}
+
+	archiverFactories[provider] = factory
+	return nil
+}
+
+// StringOption returns option of config as string, empty if missing or not a string.
+func (config *ArchiveConfig) StringOption(key string) string {
+	value, _ := config.Options[key].(string)
+	return value
+}
+
+// Create rotation hook which uploads rotated files with archiver of provider
+func newArchiveHook(config *ArchiveConfig) (RotationHook, error) {
+	archiverMutex.RLock()
+	factory, ok := archiverFactories[strings.ToLower(config.Provider)]
+	archiverMutex.RUnlock()
+
+	if !ok {
+		return nil, errors.Errorf("archiver is not registered, import package of provider, provider:%s", config.Provider)
+	}
+
+	retries := config.Retries
+	if retries == 0 {
+		retries = DefaultArchiveRetries
+	}
+
+	backoff, err := parseArchiveDuration(config.Backoff, DefaultArchiveBackoff)
+	if err != nil {
+		return nil, errors.Errorf("invalid backoff of archive, backoff:%s", config.Backoff)
+	}
+
+	timeout, err := parseArchiveDuration(config.Timeout, DefaultArchiveTimeout)
+	if err != nil {
+		return nil, errors.Errorf("invalid timeout of archive, timeout:%s", config.Timeout)
+	}
+
+	archiver, err := factory(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create archiver, provider:%s", config.Provider)
+	}
+
+	return func(ctx context.Context, path string) (string, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		key := archiveKey(config.Key, path, info.ModTime().UTC())
+		for attempt := 0; ; attempt++ {
+			uploadCtx, cancel := context.WithTimeout(ctx, timeout)
+			err = archiver.Archive(uploadCtx, path, key)
+			cancel()
+
+			if err == nil || attempt >= retries {
+				break
+			}
+			time.Sleep(backoff << uint(attempt))
+		}
+
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to archive file, provider:%s, key:%s", config.Provider, key)
+		}
+
+		if config.DeleteAfterUpload {
+			return path, os.Remove(path)
+		}
+
+		return path, nil
+	}, nil
+}
+
+// Expand key template with name of file and its modification time
+func archiveKey(template, path string, t time.Time) string {
+	if len(template) == 0 {
+		template = DefaultArchiveKey
+	}
+
+	key := ExpandFilename(template, t)
+	return strings.TrimPrefix(strings.Replace(key, "{name}", filepath.Base(path), -1), "/")
+}
+
+// Parse positive duration, the default one is returned if text is empty
+func parseArchiveDuration(text string, defaultValue time.Duration) (time.Duration, error) {
+	if len(text) == 0 {
+		return defaultValue, nil
+	}
+
+	res, err := time.ParseDuration(text)
+	if err != nil || res <= 0 {
+		return 0, errors.Errorf("invalid duration:%s", text)
+	}
+
+	return res, nil
+}
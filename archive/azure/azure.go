@@ -0,0 +1,173 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package azure registers azure provider with rklogger.RegisterArchiver, so that rotated files are uploaded to
+// Azure Blob Storage as block blobs by archive section:
+//
+//	archive:
+//	  provider: azure
+//	  bucket: app-logs
+//	  encryptionKey: app-logs-scope
+//	  options:
+//	    account: mystorageaccount
+//	    sasToken: ${AZURE_STORAGE_SAS_TOKEN}
+//	    accessTier: Cool
+//
+// Import the package for side effects in order to enable the provider:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/archive/azure"
+//
+// Bucket is the container of blobs, and encryptionKey is encryption scope which encrypts blobs.
+// Endpoint replaces https://<account>.blob.core.windows.net, like endpoint of azurite in tests.
+//
+// Options:
+//
+//	account:    storage account, AZURE_STORAGE_ACCOUNT by default
+//	sasToken:   shared access signature with create and write permissions, AZURE_STORAGE_SAS_TOKEN by default
+//	accessTier: access tier of blobs, like Hot, Cool or Archive
+//
+// Blobs are uploaded with a single request which is limited to 5000MiB. Archivers could be created with NewArchiver
+// as well.
+package azure
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// Provider is the provider of archive section which uploads files to Azure Blob Storage.
+	Provider = "azure"
+	// APIVersion is the version of Blob service REST API.
+	APIVersion = "2020-04-08"
+)
+
+func init() {
+	if err := rklogger.RegisterArchiver(Provider, newArchiverWithConfig); err != nil {
+		panic(err)
+	}
+}
+
+// Archiver uploads files to container of Azure Blob Storage.
+type Archiver struct {
+	client          *http.Client
+	endpoint        string
+	container       string
+	sasToken        string
+	encryptionScope string
+	accessTier      string
+}
+
+// NewArchiver creates Archiver with archive config, http.DefaultClient is used if client is nil.
+func NewArchiver(config *rklogger.ArchiveConfig, client *http.Client) (*Archiver, error) {
+	if len(config.Bucket) == 0 {
+		return nil, errors.New("container is required for azure, set it as bucket")
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	endpoint := config.Endpoint
+	if len(endpoint) == 0 {
+		account := config.StringOption("account")
+		if len(account) == 0 {
+			account = os.Getenv("AZURE_STORAGE_ACCOUNT")
+		}
+		if len(account) == 0 {
+			return nil, errors.New("account is required for azure if endpoint is not provided")
+		}
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+
+	sasToken := config.StringOption("sasToken")
+	if len(sasToken) == 0 {
+		sasToken = os.Getenv("AZURE_STORAGE_SAS_TOKEN")
+	}
+
+	return &Archiver{
+		client:          client,
+		endpoint:        strings.TrimSuffix(endpoint, "/"),
+		container:       config.Bucket,
+		sasToken:        strings.TrimPrefix(sasToken, "?"),
+		encryptionScope: config.EncryptionKey,
+		accessTier:      config.StringOption("accessTier"),
+	}, nil
+}
+
+// Create archiver with archive config
+func newArchiverWithConfig(config *rklogger.ArchiveConfig) (rklogger.Archiver, error) {
+	archiver, err := NewArchiver(config, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return archiver, nil
+}
+
+// Archive implements rklogger.Archiver, file is uploaded with Put Blob
+func (archiver *Archiver) Archive(ctx context.Context, path, key string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	u := archiver.endpoint + "/" + url.PathEscape(archiver.container) + "/" + escapeBlobName(key)
+	if len(archiver.sasToken) > 0 {
+		u += "?" + archiver.sasToken
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", APIVersion)
+	if len(archiver.encryptionScope) > 0 {
+		req.Header.Set("x-ms-encryption-scope", archiver.encryptionScope)
+	}
+	if len(archiver.accessTier) > 0 {
+		req.Header.Set("x-ms-access-tier", archiver.accessTier)
+	}
+
+	resp, err := archiver.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to put blob, container:%s, key:%s", archiver.container, key)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode != http.StatusCreated {
+		return errors.Errorf("failed to put blob, container:%s, key:%s, status:%d, response:%s",
+			archiver.container, key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// Escape segments of blob name, slashes are kept as virtual directories
+func escapeBlobName(name string) string {
+	segments := strings.Split(name, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package azure
+
+import (
+	"context"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+)
+
+// Write file into temp directory
+func writeFile(t *testing.T, content string) string {
+	filePath := path.Join(t.TempDir(), "app.log")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	return filePath
+}
+
+func TestArchiver_HappyCase(t *testing.T) {
+	var uri, body string
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		uri, body, header = r.RequestURI, string(content), r.Header
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	archiver, err := newArchiverWithConfig(&rklogger.ArchiveConfig{
+		Bucket:        "ut-container",
+		EncryptionKey: "ut-scope",
+		Endpoint:      server.URL + "/ut-account/",
+		Options:       map[string]interface{}{"sasToken": "?sv=2020-04-08&sig=ut", "accessTier": "Cool"},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, archiver.Archive(context.Background(), writeFile(t, "ut-content"), "host 1/app.log"))
+	assert.Equal(t, "/ut-account/ut-container/host%201/app.log?sv=2020-04-08&sig=ut", uri)
+	assert.Equal(t, "ut-content", body)
+	assert.Equal(t, "BlockBlob", header.Get("x-ms-blob-type"))
+	assert.Equal(t, APIVersion, header.Get("x-ms-version"))
+	assert.Equal(t, "ut-scope", header.Get("x-ms-encryption-scope"))
+	assert.Equal(t, "Cool", header.Get("x-ms-access-tier"))
+}
+
+func TestArchiver_WithRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("AuthenticationFailed"))
+	}))
+	defer server.Close()
+
+	archiver, err := NewArchiver(&rklogger.ArchiveConfig{Bucket: "ut-container", Endpoint: server.URL}, server.Client())
+	assert.Nil(t, err)
+
+	err = archiver.Archive(context.Background(), writeFile(t, "ut-content"), "app.log")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "AuthenticationFailed")
+}
+
+func TestNewArchiver_WithAccount(t *testing.T) {
+	os.Setenv("AZURE_STORAGE_ACCOUNT", "utaccount")
+	os.Setenv("AZURE_STORAGE_SAS_TOKEN", "sig=ut")
+	defer os.Unsetenv("AZURE_STORAGE_ACCOUNT")
+	defer os.Unsetenv("AZURE_STORAGE_SAS_TOKEN")
+
+	archiver, err := NewArchiver(&rklogger.ArchiveConfig{Bucket: "ut-container"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://utaccount.blob.core.windows.net", archiver.endpoint)
+	assert.Equal(t, "sig=ut", archiver.sasToken)
+	assert.Equal(t, http.DefaultClient, archiver.client)
+}
+
+func TestNewArchiver_WithInvalidConfig(t *testing.T) {
+	archiver, err := NewArchiver(&rklogger.ArchiveConfig{}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, archiver)
+
+	archiver, err = NewArchiver(&rklogger.ArchiveConfig{Bucket: "ut-container"}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, archiver)
+}
+
+func TestRegister(t *testing.T) {
+	assert.NotNil(t, rklogger.RegisterArchiver(Provider, newArchiverWithConfig))
+}
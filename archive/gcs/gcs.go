@@ -0,0 +1,165 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package gcs registers gcs provider with rklogger.RegisterArchiver, so that rotated files are uploaded to
+// Google Cloud Storage by archive section:
+//
+//	archive:
+//	  provider: gcs
+//	  bucket: app-logs
+//	  encryptionKey: projects/my-project/locations/global/keyRings/logs/cryptoKeys/app
+//	  options:
+//	    credentials: /etc/app/credentials.json
+//
+// Import the package for side effects in order to enable the provider:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/archive/gcs"
+//
+// EncryptionKey is name of Cloud KMS key which encrypts objects, default encryption of bucket is used if not provided.
+// Endpoint replaces Endpoint, like endpoint of emulators in tests.
+//
+// Options:
+//
+//	credentials: path of credentials json, application default credentials by default
+//
+// Archivers could be created with NewArchiver as well.
+package gcs
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// Provider is the provider of archive section which uploads files to Google Cloud Storage.
+	Provider = "gcs"
+	// Endpoint is the default endpoint of Google Cloud Storage.
+	Endpoint = "https://storage.googleapis.com"
+
+	// scope of uploading objects
+	storageWriteScope = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+func init() {
+	if err := rklogger.RegisterArchiver(Provider, newArchiverWithConfig); err != nil {
+		panic(err)
+	}
+}
+
+// Archiver uploads files to bucket of Google Cloud Storage.
+type Archiver struct {
+	client        *http.Client
+	endpoint      string
+	bucket        string
+	encryptionKey string
+}
+
+// NewArchiver creates Archiver with archive config, http client is authorized with credentials of config
+// if client is nil.
+func NewArchiver(config *rklogger.ArchiveConfig, client *http.Client) (*Archiver, error) {
+	if len(config.Bucket) == 0 {
+		return nil, errors.New("bucket is required for gcs")
+	}
+
+	if client == nil {
+		ctx := context.Background()
+		credentials, err := findCredentials(ctx, config.StringOption("credentials"))
+		if err != nil {
+			return nil, err
+		}
+
+		client = oauth2.NewClient(ctx, credentials.TokenSource)
+	}
+
+	endpoint := config.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = Endpoint
+	}
+
+	return &Archiver{
+		client:        client,
+		endpoint:      strings.TrimSuffix(endpoint, "/"),
+		bucket:        config.Bucket,
+		encryptionKey: config.EncryptionKey,
+	}, nil
+}
+
+// Create archiver with archive config
+func newArchiverWithConfig(config *rklogger.ArchiveConfig) (rklogger.Archiver, error) {
+	archiver, err := NewArchiver(config, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return archiver, nil
+}
+
+// Find credentials with scope of writing objects from file or application default credentials
+func findCredentials(ctx context.Context, credentialsFile string) (*google.Credentials, error) {
+	if len(credentialsFile) == 0 {
+		credentials, err := google.FindDefaultCredentials(ctx, storageWriteScope)
+		return credentials, errors.Wrap(err, "failed to find default credentials of google cloud")
+	}
+
+	content, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read credentials of google cloud")
+	}
+
+	credentials, err := google.CredentialsFromJSON(ctx, content, storageWriteScope)
+	return credentials, errors.Wrapf(err, "failed to parse credentials of google cloud, path:%s", credentialsFile)
+}
+
+// Archive implements rklogger.Archiver, file is uploaded with a single media upload
+func (archiver *Archiver) Archive(ctx context.Context, path, key string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("uploadType", "media")
+	query.Set("name", key)
+	if len(archiver.encryptionKey) > 0 {
+		query.Set("kmsKeyName", archiver.encryptionKey)
+	}
+
+	u := archiver.endpoint + "/upload/storage/v1/b/" + url.PathEscape(archiver.bucket) + "/o?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := archiver.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to upload object, bucket:%s, key:%s", archiver.bucket, key)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("failed to upload object, bucket:%s, key:%s, status:%d, response:%s",
+			archiver.bucket, key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
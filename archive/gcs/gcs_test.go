@@ -0,0 +1,126 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package gcs
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+// upload request received by storage server
+type uploadRequest struct {
+	uri   string
+	token string
+	body  string
+}
+
+// Start storage server along with token endpoint of service account, uploads to bucket denied are rejected
+func newStorageServer(t *testing.T) (*httptest.Server, *[]uploadRequest) {
+	requests := make([]uploadRequest, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"ut-token","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, uploadRequest{uri: r.RequestURI, token: r.Header.Get("Authorization"), body: string(body)})
+
+		if r.URL.Path == "/upload/storage/v1/b/denied/o" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":403}}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+// Write credentials of service account whose token endpoint is tokenURL
+func writeCredentials(t *testing.T, tokenURL string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	content, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "ut-project",
+		"private_key_id": "ut",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"client_email":   "ut@ut-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURL,
+	})
+
+	filePath := path.Join(t.TempDir(), "credentials.json")
+	assert.Nil(t, ioutil.WriteFile(filePath, content, 0600))
+	return filePath
+}
+
+// Write file into temp directory
+func writeFile(t *testing.T, content string) string {
+	filePath := path.Join(t.TempDir(), "app.log")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	return filePath
+}
+
+func TestArchiver_HappyCase(t *testing.T) {
+	server, requests := newStorageServer(t)
+
+	archiver, err := newArchiverWithConfig(&rklogger.ArchiveConfig{
+		Bucket:        "ut-bucket",
+		EncryptionKey: "ut-key",
+		Endpoint:      server.URL,
+		Options:       map[string]interface{}{"credentials": writeCredentials(t, server.URL+"/token")},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, archiver.Archive(context.Background(), writeFile(t, "ut-content"), "logs/app.log"))
+	assert.Equal(t, []uploadRequest{{
+		uri:   "/upload/storage/v1/b/ut-bucket/o?kmsKeyName=ut-key&name=logs%2Fapp.log&uploadType=media",
+		token: "Bearer ut-token",
+		body:  "ut-content",
+	}}, *requests)
+}
+
+func TestArchiver_WithRejection(t *testing.T) {
+	server, _ := newStorageServer(t)
+
+	archiver, err := NewArchiver(&rklogger.ArchiveConfig{Bucket: "denied", Endpoint: server.URL + "/"}, server.Client())
+	assert.Nil(t, err)
+
+	err = archiver.Archive(context.Background(), writeFile(t, "ut-content"), "app.log")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status:403")
+}
+
+func TestNewArchiver_WithInvalidConfig(t *testing.T) {
+	archiver, err := NewArchiver(&rklogger.ArchiveConfig{}, http.DefaultClient)
+	assert.NotNil(t, err)
+	assert.Nil(t, archiver)
+
+	archiver, err = NewArchiver(&rklogger.ArchiveConfig{
+		Bucket:  "ut-bucket",
+		Options: map[string]interface{}{"credentials": path.Join(t.TempDir(), "not-exist.json")},
+	}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, archiver)
+}
+
+func TestRegister(t *testing.T) {
+	assert.NotNil(t, rklogger.RegisterArchiver(Provider, newArchiverWithConfig))
+}
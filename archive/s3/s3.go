@@ -0,0 +1,136 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package s3 registers s3 provider with rklogger.RegisterArchiver, so that rotated files are uploaded to AWS S3 or
+// S3 compatible storage like minio by archive section:
+//
+//	archive:
+//	  provider: s3
+//	  bucket: app-logs
+//	  region: us-east-1
+//	  encryption: aws:kms
+//	  encryptionKey: alias/app-logs
+//	  options:
+//	    storageClass: STANDARD_IA
+//
+// Import the package for side effects in order to enable the provider:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/archive/s3"
+//
+// Encryption is AES256 or aws:kms, and encryptionKey is KMS key id of aws:kms.
+//
+// Options:
+//
+//	storageClass:   storage class of objects, like STANDARD_IA or GLACIER
+//	forcePathStyle: true to address buckets by path instead of host, which is required by minio
+//
+// Credentials are resolved by default credential chain of AWS SDK. Archivers could be created with NewArchiver as well.
+package s3
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"os"
+)
+
+// Provider is the provider of archive section which uploads files to S3.
+const Provider = "s3"
+
+func init() {
+	if err := rklogger.RegisterArchiver(Provider, newArchiverWithConfig); err != nil {
+		panic(err)
+	}
+}
+
+// Archiver uploads files to bucket of S3.
+type Archiver struct {
+	client        s3iface.S3API
+	bucket        string
+	encryption    string
+	encryptionKey string
+	storageClass  string
+}
+
+// NewArchiver creates Archiver with archive config, client of S3 is created with region and endpoint of config
+// if client is nil.
+func NewArchiver(config *rklogger.ArchiveConfig, client s3iface.S3API) (*Archiver, error) {
+	if len(config.Bucket) == 0 {
+		return nil, errors.New("bucket is required for s3")
+	}
+
+	if client == nil {
+		// empty region and endpoint would override the ones resolved by AWS SDK
+		awsConfig := aws.Config{}
+		if len(config.Region) > 0 {
+			awsConfig.Region = aws.String(config.Region)
+		}
+		if len(config.Endpoint) > 0 {
+			awsConfig.Endpoint = aws.String(config.Endpoint)
+		}
+		if forcePathStyle, _ := config.Options["forcePathStyle"].(bool); forcePathStyle {
+			awsConfig.S3ForcePathStyle = aws.Bool(true)
+		}
+
+		sess, err := session.NewSessionWithOptions(session.Options{
+			Config:            awsConfig,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create aws session")
+		}
+
+		client = awss3.New(sess)
+	}
+
+	return &Archiver{
+		client:        client,
+		bucket:        config.Bucket,
+		encryption:    config.Encryption,
+		encryptionKey: config.EncryptionKey,
+		storageClass:  config.StringOption("storageClass"),
+	}, nil
+}
+
+// Create archiver with archive config
+func newArchiverWithConfig(config *rklogger.ArchiveConfig) (rklogger.Archiver, error) {
+	archiver, err := NewArchiver(config, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return archiver, nil
+}
+
+// Archive implements rklogger.Archiver
+func (archiver *Archiver) Archive(ctx context.Context, path, key string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	input := &awss3.PutObjectInput{
+		Bucket: aws.String(archiver.bucket),
+		Key:    aws.String(key),
+		Body:   file,
+	}
+	if len(archiver.encryption) > 0 {
+		input.ServerSideEncryption = aws.String(archiver.encryption)
+	}
+	if len(archiver.encryptionKey) > 0 {
+		input.SSEKMSKeyId = aws.String(archiver.encryptionKey)
+	}
+	if len(archiver.storageClass) > 0 {
+		input.StorageClass = aws.String(archiver.storageClass)
+	}
+
+	_, err = archiver.client.PutObjectWithContext(ctx, input)
+	return errors.Wrapf(err, "failed to put object, bucket:%s, key:%s", archiver.bucket, key)
+}
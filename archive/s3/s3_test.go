@@ -0,0 +1,98 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package s3
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+// fakeClient records objects put to S3
+type fakeClient struct {
+	s3iface.S3API
+	inputs   []*awss3.PutObjectInput
+	contents []string
+	err      error
+}
+
+func (client *fakeClient) PutObjectWithContext(ctx aws.Context, input *awss3.PutObjectInput, opts ...request.Option) (*awss3.PutObjectOutput, error) {
+	if client.err != nil {
+		return nil, client.err
+	}
+
+	content, _ := ioutil.ReadAll(input.Body)
+	client.inputs = append(client.inputs, input)
+	client.contents = append(client.contents, string(content))
+	return &awss3.PutObjectOutput{}, nil
+}
+
+// Write file into temp directory
+func writeFile(t *testing.T, content string) string {
+	filePath := path.Join(t.TempDir(), "app.log")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	return filePath
+}
+
+func TestArchiver_HappyCase(t *testing.T) {
+	client := &fakeClient{}
+	archiver, err := NewArchiver(&rklogger.ArchiveConfig{
+		Bucket:        "ut-bucket",
+		Encryption:    "aws:kms",
+		EncryptionKey: "alias/ut",
+		Options:       map[string]interface{}{"storageClass": "STANDARD_IA"},
+	}, client)
+	assert.Nil(t, err)
+
+	assert.Nil(t, archiver.Archive(context.Background(), writeFile(t, "ut-content"), "logs/app.log"))
+	assert.Len(t, client.inputs, 1)
+	assert.Equal(t, "ut-bucket", aws.StringValue(client.inputs[0].Bucket))
+	assert.Equal(t, "logs/app.log", aws.StringValue(client.inputs[0].Key))
+	assert.Equal(t, "aws:kms", aws.StringValue(client.inputs[0].ServerSideEncryption))
+	assert.Equal(t, "alias/ut", aws.StringValue(client.inputs[0].SSEKMSKeyId))
+	assert.Equal(t, "STANDARD_IA", aws.StringValue(client.inputs[0].StorageClass))
+	assert.Equal(t, []string{"ut-content"}, client.contents)
+}
+
+func TestArchiver_WithError(t *testing.T) {
+	archiver, err := NewArchiver(&rklogger.ArchiveConfig{Bucket: "ut-bucket"}, &fakeClient{err: errors.New("ut-error")})
+	assert.Nil(t, err)
+
+	assert.NotNil(t, archiver.Archive(context.Background(), writeFile(t, "ut-content"), "app.log"))
+	assert.NotNil(t, archiver.Archive(context.Background(), path.Join(t.TempDir(), "not-exist.log"), "app.log"))
+}
+
+func TestNewArchiver_WithoutBucket(t *testing.T) {
+	archiver, err := NewArchiver(&rklogger.ArchiveConfig{}, &fakeClient{})
+	assert.NotNil(t, err)
+	assert.Nil(t, archiver)
+}
+
+func TestNewArchiverWithConfig(t *testing.T) {
+	archiver, err := newArchiverWithConfig(&rklogger.ArchiveConfig{
+		Bucket:   "ut-bucket",
+		Region:   "us-east-1",
+		Endpoint: "http://localhost:9000",
+		Options:  map[string]interface{}{"forcePathStyle": true},
+	})
+	assert.Nil(t, err)
+
+	client := archiver.(*Archiver).client.(*awss3.S3)
+	assert.Equal(t, "us-east-1", aws.StringValue(client.Config.Region))
+	assert.Equal(t, "http://localhost:9000", aws.StringValue(client.Config.Endpoint))
+	assert.True(t, aws.BoolValue(client.Config.S3ForcePathStyle))
+}
+
+func TestRegister(t *testing.T) {
+	assert.NotNil(t, rklogger.RegisterArchiver(Provider, newArchiverWithConfig))
+}
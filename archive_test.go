@@ -0,0 +1,175 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// archiver which records keys and content of archived files, the first failures calls fail
+type memoryArchiver struct {
+	failures int
+	keys     []string
+	contents []string
+	mutex    sync.Mutex
+}
+
+func (archiver *memoryArchiver) Archive(ctx context.Context, path, key string) error {
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	if archiver.failures > 0 {
+		archiver.failures--
+		return errors.New("ut-error")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	archiver.keys = append(archiver.keys, key)
+	archiver.contents = append(archiver.contents, string(content))
+	return nil
+}
+
+// Keys of archived files
+func (archiver *memoryArchiver) archivedKeys() []string {
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	return append([]string{}, archiver.keys...)
+}
+
+// Register archiver with provider which is unique in tests, the provider is unregistered once the test finishes
+func registerMemoryArchiver(t *testing.T, provider string, archiver *memoryArchiver) {
+	assert.Nil(t, RegisterArchiver(provider, func(config *ArchiveConfig) (Archiver, error) {
+		return archiver, nil
+	}))
+
+	t.Cleanup(func() {
+		archiverMutex.Lock()
+		delete(archiverFactories, strings.ToLower(provider))
+		archiverMutex.Unlock()
+	})
+}
+
+func TestRegisterArchiver(t *testing.T) {
+	registerMemoryArchiver(t, "UT-Register", &memoryArchiver{})
+	assert.NotNil(t, RegisterArchiver("ut-register", func(config *ArchiveConfig) (Archiver, error) {
+		return nil, nil
+	}))
+	assert.NotNil(t, RegisterArchiver("ut-nil", nil))
+}
+
+func TestArchiveKey(t *testing.T) {
+	hostname, _ := os.Hostname()
+	now := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, hostname+"/app-2020-09-01.log.zst", archiveKey("", "/var/log/app-2020-09-01.log.zst", now))
+	assert.Equal(t, "logs/2020/09/01/app.log", archiveKey("/logs/%Y/%m/%d/{name}", "/var/log/app.log", now))
+}
+
+func TestArchiveHook_HappyCase(t *testing.T) {
+	archiver := &memoryArchiver{failures: 2}
+	registerMemoryArchiver(t, "ut-retry", archiver)
+
+	filePath := writeRotatedFile(t, "ut-content")
+	modTime := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	assert.Nil(t, os.Chtimes(filePath, modTime, modTime))
+
+	hook, err := newArchiveHook(&ArchiveConfig{
+		Provider:          "ut-retry",
+		Key:               "%Y%m%d/{name}",
+		Backoff:           "1ms",
+		DeleteAfterUpload: true,
+	})
+	assert.Nil(t, err)
+
+	res, err := hook(context.Background(), filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, filePath, res)
+	assert.Equal(t, []string{"20200901/app-2020-09-01.log"}, archiver.keys)
+	assert.Equal(t, []string{"ut-content"}, archiver.contents)
+	assert.NoFileExists(t, filePath)
+}
+
+func TestArchiveHook_WithoutRetry(t *testing.T) {
+	archiver := &memoryArchiver{failures: 1}
+	registerMemoryArchiver(t, "ut-no-retry", archiver)
+
+	filePath := writeRotatedFile(t, "ut-content")
+	hook, err := newArchiveHook(&ArchiveConfig{Provider: "ut-no-retry", Retries: -1, DeleteAfterUpload: true})
+	assert.Nil(t, err)
+
+	_, err = hook(context.Background(), filePath)
+	assert.NotNil(t, err)
+	assert.FileExists(t, filePath)
+}
+
+func TestNewArchiveHook_WithInvalidConfig(t *testing.T) {
+	registerMemoryArchiver(t, "ut-invalid", &memoryArchiver{})
+
+	for _, config := range []*ArchiveConfig{
+		{Provider: "ut-unknown"},
+		{Provider: "ut-invalid", Backoff: "1x"},
+		{Provider: "ut-invalid", Timeout: "-1s"},
+	} {
+		hook, err := newArchiveHook(config)
+		assert.NotNil(t, err)
+		assert.Nil(t, hook)
+	}
+}
+
+func TestArchive_WithConfigFile(t *testing.T) {
+	archiver := &memoryArchiver{}
+	registerMemoryArchiver(t, "ut-config", archiver)
+
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "ut.log") + `"]
+rotation:
+  maxSize: 1
+archive:
+  provider: ut-config
+  key: "{name}"
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, &ArchiveConfig{Provider: "ut-config", Key: "{name}"}, config.Archive)
+
+	outputs, _ := config.toOutputConfigs()
+	assert.Equal(t, config.Archive, outputs[0].Archive)
+
+	rotator, err := newRotator(path.Join(dir, "ut.log"), config.Rotation, config.Archive)
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	rotator.Write([]byte("ut-content\n"))
+	assert.Nil(t, rotator.Rotate())
+
+	assert.Eventually(t, func() bool {
+		return len(archiver.archivedKeys()) == 1
+	}, time.Second, 10*time.Millisecond)
+	keys := archiver.archivedKeys()
+	if assert.Len(t, keys, 1) {
+		assert.Regexp(t, `^ut-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3}\.log$`, keys[0])
+	}
+}
@@ -0,0 +1,5 @@
+maxsize = 1024
+maxage = 7
+maxbackups = 3
+localtime = true
+compress = true
@@ -0,0 +1,27 @@
+level = "debug"
+encoding = "console"
+outputPaths = ["stdout"]
+errorOutputPaths = ["stderr"]
+maxsize = 1024
+maxage = 7
+maxbackups = 3
+localtime = true
+compress = true
+
+initialFields {
+  initFieldKey = "fieldValue"
+}
+
+encoderConfig {
+  messageKey = "messagea"
+  levelKey = "level"
+  nameKey = "logger"
+  timeKey = "time"
+  callerKey = "caller"
+  stacktraceKey = "stacktrace"
+  timeEncoder = "iso8601"
+  levelEncoder = "capital"
+  durationEncoder = "second"
+  callerEncoder = "full"
+  nameEncoder = "full"
+}
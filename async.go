@@ -0,0 +1,119 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"time"
+)
+
+// Defaults of AsyncConfig
+const (
+	// DefaultAsyncQueueSize is the default max number of entries waiting to be written.
+	DefaultAsyncQueueSize = 10000
+	// DefaultAsyncBatchSize is the default max number of entries written together.
+	DefaultAsyncBatchSize = 100
+	// DefaultAsyncFlushInterval is the default max time an entry waits before it is written.
+	DefaultAsyncFlushInterval = 100 * time.Millisecond
+)
+
+// AsyncConfig writes entries of outputs in background, so that logging is not blocked by slow disks. Entries are
+// encoded by goroutines of callers and queued in a bounded queue, then written in batches by a background worker.
+// Queued entries are written once logger is synced, including entries of panic and fatal levels, and entries which
+// are not synced may be lost if process crashes. Outputs of custom cores and error outputs are written synchronously.
+//
+// Example config file in YAML:
+//
+//	async:
+//	  queueSize: 10000
+//	  overflow: dropOldest
+//	  batchSize: 100
+//	  flushInterval: 100ms
+type AsyncConfig struct {
+	// QueueSize is the max number of entries waiting to be written, DefaultAsyncQueueSize if not provided.
+	QueueSize int `json:"queueSize,omitempty" yaml:"queueSize,omitempty"`
+	// Overflow is block, dropOldest or dropNewest, which is applied while queue is full. Block by default, which blocks
+	// logging until there is room in queue, dropOldest and dropNewest drop entries instead.
+	Overflow string `json:"overflow,omitempty" yaml:"overflow,omitempty"`
+	// BatchSize is the max number of entries written together, DefaultAsyncBatchSize if not provided.
+	BatchSize int `json:"batchSize,omitempty" yaml:"batchSize,omitempty"`
+	// FlushInterval is the max time an entry waits before it is written, like 1s,
+	// DefaultAsyncFlushInterval if not provided.
+	FlushInterval string `json:"flushInterval,omitempty" yaml:"flushInterval,omitempty"`
+}
+
+// Convert config into batching config of batch.Writer
+func (config *AsyncConfig) batchConfig() (batch.Config, error) {
+	res := batch.Config{
+		Size:      DefaultAsyncBatchSize,
+		QueueSize: DefaultAsyncQueueSize,
+		Linger:    DefaultAsyncFlushInterval,
+		Overflow:  batch.Block,
+	}
+
+	if config.QueueSize > 0 {
+		res.QueueSize = config.QueueSize
+	}
+
+	if config.BatchSize > 0 {
+		res.Size = config.BatchSize
+	}
+
+	if len(config.FlushInterval) > 0 {
+		interval, err := time.ParseDuration(config.FlushInterval)
+		if err != nil || interval <= 0 {
+			return res, errors.Errorf("invalid flush interval of async, flushInterval:%s", config.FlushInterval)
+		}
+		res.Linger = interval
+	}
+
+	if len(config.Overflow) > 0 {
+		res.Overflow = batch.OverflowPolicy(config.Overflow)
+		if res.Overflow != batch.Block && res.Overflow != batch.DropNewest && res.Overflow != batch.DropOldest {
+			return res, errors.Errorf("invalid overflow of async, overflow:%s", config.Overflow)
+		}
+	}
+
+	return res, nil
+}
+
+// asyncWriter queues entries and writes them to write syncer in background
+type asyncWriter struct {
+	*batch.Writer
+	syncer zapcore.WriteSyncer
+}
+
+// Create writer which writes entries to syncer in background with batching config
+func newAsyncWriter(config batch.Config, syncer zapcore.WriteSyncer) *asyncWriter {
+	// batches are written once, errors are returned by Sync
+	config.Retries = 0
+	config.Bytes = 1 << 20
+
+	// batches are flushed by a single goroutine, so that buffer of them is reused
+	var buf []byte
+
+	return &asyncWriter{
+		Writer: batch.NewWriter(config, func(ctx context.Context, entries [][]byte) error {
+			buf = buf[:0]
+			for i := range entries {
+				buf = append(buf, entries[i]...)
+			}
+
+			_, err := syncer.Write(buf)
+			return err
+		}),
+		syncer: syncer,
+	}
+}
+
+// Sync implements zapcore.WriteSyncer, queued entries are written before write syncer is synced
+func (writer *asyncWriter) Sync() error {
+	return multierr.Append(writer.Writer.Sync(), writer.syncer.Sync())
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/stretchr/testify/assert"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingSyncer records writes and blocks them until released
+type blockingSyncer struct {
+	started chan struct{}
+	release chan struct{}
+	mutex   sync.Mutex
+	writes  []string
+}
+
+func newBlockingSyncer() *blockingSyncer {
+	return &blockingSyncer{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+}
+
+func (syncer *blockingSyncer) Write(p []byte) (int, error) {
+	select {
+	case syncer.started <- struct{}{}:
+	default:
+	}
+	<-syncer.release
+
+	syncer.mutex.Lock()
+	defer syncer.mutex.Unlock()
+	syncer.writes = append(syncer.writes, string(p))
+	return len(p), nil
+}
+
+func (syncer *blockingSyncer) Sync() error {
+	return nil
+}
+
+func TestAsyncConfig_BatchConfig(t *testing.T) {
+	res, err := (&AsyncConfig{}).batchConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultAsyncQueueSize, res.QueueSize)
+	assert.Equal(t, DefaultAsyncBatchSize, res.Size)
+	assert.Equal(t, DefaultAsyncFlushInterval, res.Linger)
+	assert.Equal(t, batch.Block, res.Overflow)
+
+	res, err = (&AsyncConfig{QueueSize: 10, BatchSize: 5, FlushInterval: "1s", Overflow: "dropOldest"}).batchConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, 10, res.QueueSize)
+	assert.Equal(t, 5, res.Size)
+	assert.Equal(t, time.Second, res.Linger)
+	assert.Equal(t, batch.DropOldest, res.Overflow)
+
+	for _, config := range []*AsyncConfig{
+		{Overflow: "ut-overflow"},
+		{FlushInterval: "1x"},
+		{FlushInterval: "-1s"},
+	} {
+		_, err := config.batchConfig()
+		assert.NotNil(t, err)
+	}
+}
+
+func TestAsyncWriter_WithOverflow(t *testing.T) {
+	for overflow, expected := range map[string][]string{
+		"dropNewest": {"ut-first\n", "ut-second\nut-third\n"},
+		"dropOldest": {"ut-first\n", "ut-third\nut-fourth\n"},
+	} {
+		syncer := newBlockingSyncer()
+		config, err := (&AsyncConfig{QueueSize: 2, BatchSize: 1, Overflow: overflow}).batchConfig()
+		assert.Nil(t, err)
+
+		// entries are queued while the first one is being written
+		writer := newAsyncWriter(config, syncer)
+		writer.Write([]byte("ut-first\n"))
+		<-syncer.started
+		writer.Write([]byte("ut-second\n"))
+		writer.Write([]byte("ut-third\n"))
+		writer.Write([]byte("ut-fourth\n"))
+		assert.Equal(t, uint64(1), writer.Dropped(), overflow)
+
+		close(syncer.release)
+		assert.Nil(t, writer.Sync())
+		assert.Equal(t, expected[0], syncer.writes[0], overflow)
+		assert.Equal(t, expected[1], strings.Join(syncer.writes[1:], ""), overflow)
+		assert.Nil(t, writer.Close())
+	}
+}
+
+func TestAsyncWriter_WithBlock(t *testing.T) {
+	syncer := newBlockingSyncer()
+	config, err := (&AsyncConfig{QueueSize: 1, BatchSize: 1}).batchConfig()
+	assert.Nil(t, err)
+
+	writer := newAsyncWriter(config, syncer)
+	defer writer.Close()
+
+	writer.Write([]byte("ut-first\n"))
+	<-syncer.started
+	writer.Write([]byte("ut-second\n"))
+
+	// caller is blocked until there is room in queue
+	written := make(chan struct{})
+	go func() {
+		writer.Write([]byte("ut-third\n"))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		assert.Fail(t, "entry is written while queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(syncer.release)
+	<-written
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, "ut-first\nut-second\nut-third\n", strings.Join(syncer.writes, ""))
+	assert.Equal(t, uint64(0), writer.Dropped())
+}
+
+func TestAsync_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+async:
+  queueSize: 100
+  flushInterval: 1h
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	// entries are written in background until logger is synced
+	logger.Info("ut-message")
+	assert.Empty(t, readFileContent(path.Join(dir, "app.log")))
+	assert.Nil(t, logger.Sync())
+	assert.Equal(t, `{"msg":"ut-message"}`+"\n", readFileContent(path.Join(dir, "app.log")))
+
+	// queued entries are written on shutdown
+	logger.Info("ut-shutdown")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.Equal(t, `{"msg":"ut-message"}`+"\n"+`{"msg":"ut-shutdown"}`+"\n", readFileContent(path.Join(dir, "app.log")))
+
+	// invalid overflow
+	config.Async.Overflow = "ut-overflow"
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestWithAsync(t *testing.T) {
+	config := NewConfigWithOptions(WithAsync(100, "dropNewest"))
+	assert.Equal(t, &AsyncConfig{QueueSize: 100, Overflow: "dropNewest"}, config.Async)
+}
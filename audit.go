@@ -0,0 +1,411 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	// AuditPrevHashKey is key of field carrying SHA-256 hash of the previous entry of audit output in hex,
+	// it is empty for the first entry.
+	AuditPrevHashKey = "auditPrevHash"
+	// AuditSignatureKey is key of field carrying HMAC-SHA256 of AuditPrevHashKey in signature entries.
+	AuditSignatureKey = "auditSignature"
+	// AuditSignatureMessage is message of signature entries.
+	AuditSignatureMessage = "audit signature"
+	// DefaultAuditSignEvery is the default number of entries between signature entries.
+	DefaultAuditSignEvery = 100
+)
+
+// max size of entries read from audit files
+const maxAuditLineSize = 16 * 1024 * 1024
+
+var (
+	auditPrevHashRegexp  = regexp.MustCompile(`"` + AuditPrevHashKey + `":"([0-9a-f]*)"`)
+	auditSignatureRegexp = regexp.MustCompile(`"` + AuditSignatureKey + `":"([0-9a-f]*)"`)
+)
+
+// AuditConfig makes an output tamper-evident, every entry written to it carries hash of the previous entry, so that
+// modified or removed entries break the chain, which is detected by VerifyAuditLog and VerifyAuditFiles. A signature
+// entry signing the chain with SignKey is written every SignEvery entries and when logger is closed, so that the chain
+// could not be rebuilt without the key. Verification with the key fails if more than SignEvery entries are not signed
+// or the chain does not end with a signature entry.
+//
+// Audit outputs require json encoding and must not be shared with other loggers, the chain continues from the last
+// entry of existing file, and continues across rotated files which are verified together by VerifyAuditFiles.
+// Audit is ignored by outputs of registered cores.
+//
+// Example config file in YAML:
+//
+//	outputs:
+//	  - path: /var/log/audit.log
+//	    audit:
+//	      signKey: ${AUDIT_SIGN_KEY}
+//	      signEvery: 1000
+type AuditConfig struct {
+	// SignKey is key of HMAC-SHA256 of signature entries, no signature entry is written if not provided.
+	SignKey string `json:"signKey,omitempty" yaml:"signKey,omitempty"`
+	// SignEvery is the number of entries between signature entries, DefaultAuditSignEvery if not provided.
+	SignEvery int `json:"signEvery,omitempty" yaml:"signEvery,omitempty"`
+}
+
+// Check whether audit outputs among outputs are encoded with json encoding
+func validateAuditOutputs(encoding string, outputs []*OutputConfig) error {
+	for i := range outputs {
+		if outputs[i].Audit == nil {
+			continue
+		}
+
+		if encoding != "json" {
+			return errors.Errorf("audit output requires json encoding, path:%s, encoding:%s", outputs[i].Path, encoding)
+		}
+
+		if outputs[i].Audit.SignEvery < 0 {
+			return errors.Errorf("signEvery of audit output is negative, path:%s, signEvery:%d",
+				outputs[i].Path, outputs[i].Audit.SignEvery)
+		}
+	}
+
+	return nil
+}
+
+// auditChain is hash chain of entries written to write syncer of audit output
+type auditChain struct {
+	mu        sync.Mutex
+	encoder   zapcore.Encoder
+	sink      zapcore.WriteSyncer
+	prev      string
+	key       []byte
+	signEvery int
+	// entries written since the last signature entry
+	unsigned int
+}
+
+// Create core writing entries of enabled levels to sink of audit output as a hash chain, the chain continues from the
+// last entry of file of output if it exists. The returned function writes a signature entry if there are unsigned
+// entries.
+func (loader *Loader) newAuditCore(output *OutputConfig, encoder zapcore.Encoder, sink zapcore.WriteSyncer, enabler zapcore.LevelEnabler) (zapcore.Core, func(), error) {
+	chain := &auditChain{
+		encoder:   encoder,
+		sink:      sink,
+		key:       []byte(output.Audit.SignKey),
+		signEvery: output.Audit.SignEvery,
+	}
+
+	if chain.signEvery == 0 {
+		chain.signEvery = DefaultAuditSignEvery
+	}
+
+	if filePath, ok := toFilePath(output.Path); ok {
+		filePath, err := loader.resolvePath(filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if chain.prev, err = loader.lastAuditHash(output, ExpandFilename(filePath, time.Now())); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &auditCore{LevelEnabler: enabler, enc: encoder.Clone(), chain: chain}, chain.seal, nil
+}
+
+// Hash of the last line of file of output, empty if file does not exist or is empty, encrypted file is decrypted
+func (loader *Loader) lastAuditHash(output *OutputConfig, filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to open audit file, path:%s", filePath)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if output.Encrypted {
+		key, err := loader.encryptionKey(output.Encryption)
+		if err != nil {
+			return "", err
+		}
+
+		if reader, err = NewDecryptReader(file, key); err != nil {
+			return "", err
+		}
+	}
+
+	var last []byte
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAuditLineSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", errors.Wrapf(err, "failed to read audit file, path:%s", filePath)
+	}
+
+	if len(last) == 0 {
+		return "", nil
+	}
+
+	return auditHash(last), nil
+}
+
+// SHA-256 of line without line ending in hex
+func auditHash(line []byte) string {
+	sum := sha256.Sum256(bytes.TrimRight(line, "\r\n"))
+	return hex.EncodeToString(sum[:])
+}
+
+// HMAC-SHA256 of hash in hex
+func auditSign(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Encode entry with hash of the previous entry and write it, a signature entry is written after it if it is due
+func (chain *auditChain) write(enc zapcore.Encoder, entry zapcore.Entry, fields []zapcore.Field) error {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	if err := chain.append(enc, entry, append(fields[:len(fields):len(fields)], zap.String(AuditPrevHashKey, chain.prev))); err != nil {
+		return err
+	}
+
+	chain.unsigned++
+	if len(chain.key) > 0 && chain.unsigned >= chain.signEvery {
+		return chain.sign()
+	}
+
+	return nil
+}
+
+// Encode entry and write it to sink, the chain is moved forward only if entry is written
+func (chain *auditChain) append(enc zapcore.Encoder, entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := enc.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	if _, err := chain.sink.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	chain.prev = auditHash(buf.Bytes())
+	return nil
+}
+
+// Write signature entry signing hash of the previous entry
+func (chain *auditChain) sign() error {
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: AuditSignatureMessage}
+	fields := []zapcore.Field{
+		zap.String(AuditSignatureKey, auditSign(chain.key, chain.prev)),
+		zap.String(AuditPrevHashKey, chain.prev),
+	}
+
+	if err := chain.append(chain.encoder, entry, fields); err != nil {
+		return err
+	}
+
+	chain.unsigned = 0
+	return nil
+}
+
+// Write signature entry if there are entries written since the last signature entry
+func (chain *auditChain) seal() {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	if len(chain.key) > 0 && chain.unsigned > 0 {
+		chain.sign()
+		chain.sink.Sync()
+	}
+}
+
+// auditCore writes entries to hash chain of audit output
+type auditCore struct {
+	zapcore.LevelEnabler
+	enc   zapcore.Encoder
+	chain *auditChain
+}
+
+// With implements zapcore.Core
+func (core *auditCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := core.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	return &auditCore{LevelEnabler: core.LevelEnabler, enc: enc, chain: core.chain}
+}
+
+// Check implements zapcore.Core
+func (core *auditCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core, entries above error level are synced the same way as zapcore.NewCore()
+func (core *auditCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if err := core.chain.write(core.enc, entry, fields); err != nil {
+		return err
+	}
+
+	if entry.Level > zapcore.ErrorLevel {
+		core.Sync()
+	}
+
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (core *auditCore) Sync() error {
+	return core.chain.sink.Sync()
+}
+
+// AuditReport is result of verifying audit files
+type AuditReport struct {
+	// Entries is the number of verified entries, including signature entries.
+	Entries int
+	// Signatures is the number of verified signature entries.
+	Signatures int
+	// LastHash is hash of the last entry, which could be kept elsewhere to detect truncation.
+	LastHash string
+	// Sealed is true if the last entry is a signature entry, it is always true if entries are verified with key.
+	Sealed bool
+}
+
+// auditVerifier verifies hash chain and signatures of audit entries across files
+type auditVerifier struct {
+	key       []byte
+	signEvery int
+	report    *AuditReport
+	// entries verified since the last signature entry
+	unsigned int
+}
+
+// VerifyAuditLog verifies hash chain of entries of audit output read from reader, which should be the first file of
+// the chain. Signature entries are verified with signKey and signEvery of AuditConfig, DefaultAuditSignEvery is used
+// if signEvery is not positive. Signatures are ignored if signKey is empty. An error with line number is returned if
+// any entry is modified, removed or inserted, or if signatures are missing while signKey is provided.
+func VerifyAuditLog(reader io.Reader, signKey string, signEvery int) (*AuditReport, error) {
+	verifier := newAuditVerifier(signKey, signEvery)
+	if err := verifier.verify(reader); err != nil {
+		return verifier.report, err
+	}
+
+	return verifier.report, verifier.seal()
+}
+
+// VerifyAuditFiles verifies hash chain of audit files in order, like rotated files followed by the current file.
+// The chain of every file either continues from the previous file or starts over, like files written after restart
+// of process, see VerifyAuditLog.
+func VerifyAuditFiles(signKey string, signEvery int, paths ...string) (*AuditReport, error) {
+	verifier := newAuditVerifier(signKey, signEvery)
+	for _, filePath := range paths {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return verifier.report, errors.Wrapf(err, "failed to open audit file, path:%s", filePath)
+		}
+
+		err = verifier.verify(file)
+		file.Close()
+		if err != nil {
+			return verifier.report, errors.Wrapf(err, "failed to verify audit file, path:%s", filePath)
+		}
+	}
+
+	return verifier.report, verifier.seal()
+}
+
+// Create verifier with key and interval of signatures, DefaultAuditSignEvery is used if signEvery is not positive
+func newAuditVerifier(signKey string, signEvery int) *auditVerifier {
+	if signEvery <= 0 {
+		signEvery = DefaultAuditSignEvery
+	}
+
+	return &auditVerifier{key: []byte(signKey), signEvery: signEvery, report: &AuditReport{}}
+}
+
+// Verify entries read from reader, the first entry either continues from the last hash of report or starts over
+func (verifier *auditVerifier) verify(reader io.Reader) error {
+	report := verifier.report
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAuditLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Bytes()
+		matches := auditPrevHashRegexp.FindAllSubmatch(raw, -1)
+		if len(matches) == 0 {
+			return errors.Errorf("audit entry without %s, line:%d", AuditPrevHashKey, line)
+		}
+
+		// the chain field is the last field of entry
+		prev := string(matches[len(matches)-1][1])
+		if prev != report.LastHash && (line > 1 || len(prev) > 0) {
+			return errors.Errorf("hash chain of audit entries is broken, entries are modified, removed or inserted, line:%d", line)
+		}
+
+		// chain starting over should follow a sealed one
+		if line == 1 && len(prev) == 0 {
+			if err := verifier.seal(); err != nil {
+				return errors.Wrapf(err, "line:%d", line)
+			}
+		}
+
+		report.Sealed = false
+		if signatures := auditSignatureRegexp.FindAllSubmatch(raw, -1); len(verifier.key) > 0 && len(signatures) > 0 {
+			signature := string(signatures[len(signatures)-1][1])
+			if !hmac.Equal([]byte(signature), []byte(auditSign(verifier.key, prev))) {
+				return errors.Errorf("invalid signature of audit entry, line:%d", line)
+			}
+
+			report.Signatures++
+			report.Sealed = true
+			verifier.unsigned = 0
+		} else if len(verifier.key) > 0 {
+			verifier.unsigned++
+			if verifier.unsigned > verifier.signEvery {
+				return errors.Errorf("audit entries are not signed, signatures are removed, line:%d, signEvery:%d",
+					line, verifier.signEvery)
+			}
+		}
+
+		report.Entries++
+		report.LastHash = auditHash(raw)
+	}
+
+	return errors.Wrap(scanner.Err(), "failed to read audit entries")
+}
+
+// Check whether the chain verified so far ends with a signature entry if key is provided
+func (verifier *auditVerifier) seal() error {
+	if len(verifier.key) > 0 && verifier.unsigned > 0 {
+		return errors.Errorf("audit entries are not sealed by signature entry, entries are truncated or signatures "+
+			"are removed, unsigned:%d", verifier.unsigned)
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Write entries to audit file of temp dir, logger is closed afterwards
+func writeAuditEntries(t *testing.T, filePath string, audit *AuditConfig, msgs ...string) {
+	config := NewConfigWithOptions(WithJSONEncoding(), WithOutput(), WithAuditOutput(filePath, *audit))
+	config.Zap.EncoderConfig.TimeKey = ""
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	for _, msg := range msgs {
+		logger.Info(msg, zap.String("user", "ut-user"))
+	}
+	assert.Nil(t, closer.Shutdown(context.Background()))
+}
+
+func TestAudit_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "audit.log")
+	writeAuditEntries(t, filePath, &AuditConfig{}, "first", "second")
+
+	lines := strings.Split(strings.TrimSpace(readFileContent(filePath)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"auditPrevHash":""`)
+	assert.Contains(t, lines[1], `"auditPrevHash":"`+auditHash([]byte(lines[0]))+`"`)
+
+	report, err := VerifyAuditFiles("", 0, filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, &AuditReport{Entries: 2, LastHash: auditHash([]byte(lines[1]))}, report)
+
+	// chain continues from existing file
+	writeAuditEntries(t, filePath, &AuditConfig{}, "third")
+	report, err = VerifyAuditFiles("", 0, filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, report.Entries)
+}
+
+func TestAudit_WithSignatures(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "audit.log")
+	audit := &AuditConfig{SignKey: "ut-key", SignEvery: 2}
+	writeAuditEntries(t, filePath, audit, "first", "second", "third")
+
+	// signature after every 2 entries and on close
+	lines := strings.Split(strings.TrimSpace(readFileContent(filePath)), "\n")
+	assert.Len(t, lines, 5)
+	assert.Contains(t, lines[2], AuditSignatureMessage)
+	assert.Contains(t, lines[4], AuditSignatureMessage)
+
+	report, err := VerifyAuditFiles("ut-key", 2, filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, report.Entries)
+	assert.Equal(t, 2, report.Signatures)
+	assert.True(t, report.Sealed)
+
+	// With invalid key
+	_, err = VerifyAuditFiles("ut-invalid", 0, filePath)
+	assert.NotNil(t, err)
+
+	// chain rebuilt without key is detected by signatures
+	lines[1] = strings.Replace(lines[1], "second", "forged", 1)
+	for i := 2; i < len(lines); i++ {
+		lines[i] = auditPrevHashRegexp.ReplaceAllString(lines[i], `"auditPrevHash":"`+auditHash([]byte(lines[i-1]))+`"`)
+	}
+	_, err = VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), "ut-key", 2)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line:3")
+
+	// truncated after the last signature
+	lines = strings.Split(readFileContent(filePath), "\n")
+	report, err = VerifyAuditLog(strings.NewReader(strings.Join(lines[:4], "\n")), "ut-key", 2)
+	assert.NotNil(t, err)
+	assert.False(t, report.Sealed)
+
+	// truncated at the last signature is detected by hash kept elsewhere only
+	report, err = VerifyAuditLog(strings.NewReader(strings.Join(lines[:3], "\n")), "ut-key", 2)
+	assert.Nil(t, err)
+	assert.True(t, report.Sealed)
+}
+
+func TestVerifyAuditLog_WithoutSignatures(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "audit.log")
+	writeAuditEntries(t, filePath, &AuditConfig{SignKey: "ut-key", SignEvery: 2}, "first", "second", "third", "fourth")
+
+	// signatures stripped and chain rebuilt without key
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(readFileContent(filePath)), "\n") {
+		if strings.Contains(line, AuditSignatureKey) {
+			continue
+		}
+
+		if len(lines) > 0 {
+			line = auditPrevHashRegexp.ReplaceAllString(line, `"auditPrevHash":"`+auditHash([]byte(lines[len(lines)-1]))+`"`)
+		}
+		lines = append(lines, line)
+	}
+	assert.Len(t, lines, 4)
+
+	// chain itself is valid
+	_, err := VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), "", 2)
+	assert.Nil(t, err)
+
+	_, err = VerifyAuditLog(strings.NewReader(strings.Join(lines, "\n")), "ut-key", 2)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line:3")
+
+	// within interval of signatures but not sealed
+	_, err = VerifyAuditLog(strings.NewReader(strings.Join(lines[:2], "\n")), "ut-key", 2)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not sealed")
+
+	// chain starting over after unsealed file
+	dir := path.Dir(filePath)
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "audit-1.log"), []byte(lines[0]+"\n"), 0600))
+	_, err = VerifyAuditFiles("ut-key", 2, path.Join(dir, "audit-1.log"), filePath)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not sealed")
+}
+
+func TestVerifyAuditLog_WithTampering(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "audit.log")
+	writeAuditEntries(t, filePath, &AuditConfig{}, "first", "second", "third")
+	lines := strings.Split(strings.TrimSpace(readFileContent(filePath)), "\n")
+
+	tampered := map[string][]string{
+		"modified": {lines[0], strings.Replace(lines[1], "second", "forged", 1), lines[2]},
+		"removed":  {lines[0], lines[2]},
+		"head":     {lines[1], lines[2]},
+		"inserted": {lines[0], lines[1], lines[1], lines[2]},
+		"plain":    {lines[0], `{"msg":"plain"}`},
+	}
+
+	for name, content := range tampered {
+		_, err := VerifyAuditLog(strings.NewReader(strings.Join(content, "\n")), "", 0)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestVerifyAuditFiles_WithRotatedFiles(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "audit.log")
+	writeAuditEntries(t, filePath, &AuditConfig{}, "first", "second")
+	assert.Nil(t, os.Rename(filePath, path.Join(dir, "audit-1.log")))
+
+	// chain of new file starts over
+	writeAuditEntries(t, filePath, &AuditConfig{}, "third")
+	report, err := VerifyAuditFiles("", 0, path.Join(dir, "audit-1.log"), filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, report.Entries)
+
+	// chain continues across files
+	content := strings.Split(strings.TrimSpace(readFileContent(path.Join(dir, "audit-1.log"))), "\n")
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "audit-2.log"), []byte(content[1]+"\n"), 0600))
+	_, err = VerifyAuditFiles("", 0, path.Join(dir, "audit-2.log"))
+	assert.NotNil(t, err)
+
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "audit-1.log"), []byte(content[0]+"\n"), 0600))
+	report, err = VerifyAuditFiles("", 0, path.Join(dir, "audit-1.log"), path.Join(dir, "audit-2.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, report.Entries)
+
+	// With missing file
+	_, err = VerifyAuditFiles("", 0, path.Join(dir, "missing.log"))
+	assert.NotNil(t, err)
+}
+
+func TestAudit_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+outputs:
+  - path: ` + path.Join(dir, "audit.log") + `
+    audit:
+      signKey: ut-key
+    allowFields: [user]
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	logger.Info("ut-login", zap.String("user", "ut-user"), zap.String("password", "ut-password"))
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	assert.Equal(t, "{\"msg\":\"ut-login\",\"user\":\"ut-user\",\"password\":\"ut-password\"}\n", readFileContent(path.Join(dir, "app.log")))
+	assert.NotContains(t, readFileContent(path.Join(dir, "audit.log")), "password")
+
+	report, err := VerifyAuditFiles("ut-key", 0, path.Join(dir, "audit.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, &AuditReport{Entries: 2, Signatures: 1, LastHash: report.LastHash, Sealed: true}, report)
+
+	// With console encoding
+	config.Zap.Encoding = ConsoleEncoding
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+
+	// With negative signEvery
+	config.Zap.Encoding = "json"
+	config.Outputs[0].Audit.SignEvery = -1
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestWithAuditOutput(t *testing.T) {
+	config := NewConfigWithOptions(WithAuditOutput("ut-audit.log", AuditConfig{SignKey: "ut-key"}))
+	assert.Equal(t, []*OutputConfig{{Path: "ut-audit.log", Audit: &AuditConfig{SignKey: "ut-key"}}}, config.Outputs)
+}
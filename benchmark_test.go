@@ -0,0 +1,109 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"path"
+	"testing"
+)
+
+// Build logger writing to file in temp directory with sections of config
+func newBenchmarkLogger(b *testing.B, sections string) *zap.Logger {
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+    levelKey: level
+    timeKey: ts
+    nameKey: logger
+    levelEncoder: lowercase
+    timeEncoder: iso8601
+    nameEncoder: full
+  initialFields:
+    service: ut-service
+  outputPaths: ["` + path.Join(newTempDir(b), "app.log") + `"]
+` + sections)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		closer.Shutdown(context.Background())
+	})
+
+	return logger
+}
+
+// Log entries with fields
+func benchmarkLogger(b *testing.B, logger *zap.Logger) {
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		logger.Info("connection is ready", zap.String("user", "ut-user"), zap.Int("pool", 10))
+	}
+}
+
+// Logger built by zap.Config.Build()
+func BenchmarkLogger_WithBuild(b *testing.B) {
+	benchmarkLogger(b, newBenchmarkLogger(b, ""))
+}
+
+// Logger built with custom core
+func BenchmarkLogger_WithCore(b *testing.B) {
+	benchmarkLogger(b, newBenchmarkLogger(b, "levels:\n  db: debug\n").Named("app"))
+}
+
+// Logger built with custom core and size limits
+func BenchmarkLogger_WithLimits(b *testing.B) {
+	benchmarkLogger(b, newBenchmarkLogger(b, "limits:\n  maxMessageSize: 1024\n  maxFieldSize: 256\n").Named("app"))
+}
+
+// Logger built with custom core and renamed level names
+func BenchmarkLogger_WithLevelNames(b *testing.B) {
+	benchmarkLogger(b, newBenchmarkLogger(b, "levelNames:\n  info: INFORMATION\n"))
+}
+
+// Logger built with custom core writing in background
+func BenchmarkLogger_WithAsync(b *testing.B) {
+	benchmarkLogger(b, newBenchmarkLogger(b, "async:\n  queueSize: 10000\n"))
+}
+
+// Logger built with custom core writing through buffer
+func BenchmarkLogger_WithBuffer(b *testing.B) {
+	benchmarkLogger(b, newBenchmarkLogger(b, "buffer:\n  size: 256KB\n"))
+}
+
+// Log entries with logger created with fields per entry, like logger of request
+func benchmarkLoggerWith(b *testing.B, logger *zap.Logger) {
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		logger.With(zap.String("request", "ut-request")).Info("connection is ready", zap.Int("pool", 10))
+	}
+}
+
+func BenchmarkLoggerWith_WithBuild(b *testing.B) {
+	benchmarkLoggerWith(b, newBenchmarkLogger(b, ""))
+}
+
+func BenchmarkLoggerWith_WithCore(b *testing.B) {
+	benchmarkLoggerWith(b, newBenchmarkLogger(b, "levels:\n  db: debug\n").Named("app"))
+}
+
+func BenchmarkLoggerWith_WithLimits(b *testing.B) {
+	benchmarkLoggerWith(b, newBenchmarkLogger(b, "limits:\n  maxMessageSize: 1024\n  maxFieldSize: 256\n"))
+}
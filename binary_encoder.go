@@ -0,0 +1,688 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// pools of buffers returned by binary encoders, and encoders of entries, objects and arrays whose buffers are reused
+var (
+	binaryPool        = buffer.NewPool()
+	binaryEncoderPool = sync.Pool{New: func() interface{} {
+		return &binaryEncoder{maps: make([]binaryMap, 1, 4)}
+	}}
+	binaryArrayEncoderPool = sync.Pool{New: func() interface{} {
+		return &binaryArrayEncoder{}
+	}}
+)
+
+// binaryFormat appends values of binary encoding, like msgpack and cbor
+type binaryFormat interface {
+	appendMapHeader(buf []byte, n int) []byte
+	appendArrayHeader(buf []byte, n int) []byte
+	appendString(buf []byte, value string) []byte
+	appendBytes(buf []byte, value []byte) []byte
+	appendInt(buf []byte, value int64) []byte
+	appendUint(buf []byte, value uint64) []byte
+	appendFloat64(buf []byte, value float64) []byte
+	appendFloat32(buf []byte, value float32) []byte
+	appendBool(buf []byte, value bool) []byte
+	appendNil(buf []byte) []byte
+}
+
+// binaryMap is encoded pairs of map whose header is appended once number of pairs is known
+type binaryMap struct {
+	key   string
+	body  []byte
+	count int
+}
+
+// binaryEncoder implements zapcore.Encoder and zapcore.PrimitiveArrayEncoder which encoders of config append to.
+// Entries are encoded as maps of binary format without line ending, since values of binary formats are
+// self-delimiting.
+type binaryEncoder struct {
+	*zapcore.EncoderConfig
+	format binaryFormat
+	// the first map is root and the rest are open namespaces, fields are added to the last one
+	maps []binaryMap
+	// header of root map which is written before body
+	header []byte
+}
+
+// Create binary encoder with encoder config and format
+func newBinaryEncoder(config *zapcore.EncoderConfig, format binaryFormat) *binaryEncoder {
+	return &binaryEncoder{
+		EncoderConfig: config,
+		format:        format,
+		maps:          make([]binaryMap, 1, 2),
+	}
+}
+
+// Get encoder from pool, buffers of maps are reused
+func getBinaryEncoder(config *zapcore.EncoderConfig, format binaryFormat) *binaryEncoder {
+	enc := binaryEncoderPool.Get().(*binaryEncoder)
+	enc.EncoderConfig = config
+	enc.format = format
+	enc.maps = enc.maps[:1]
+	enc.maps[0] = binaryMap{body: enc.maps[0].body[:0]}
+	return enc
+}
+
+// Put encoder back to pool
+func putBinaryEncoder(enc *binaryEncoder) {
+	enc.EncoderConfig = nil
+	binaryEncoderPool.Put(enc)
+}
+
+// Clone implements zapcore.Encoder
+func (enc *binaryEncoder) Clone() zapcore.Encoder {
+	clone := &binaryEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		format:        enc.format,
+		maps:          make([]binaryMap, len(enc.maps)),
+	}
+
+	for i := range enc.maps {
+		clone.maps[i] = binaryMap{
+			key:   enc.maps[i].key,
+			body:  append([]byte{}, enc.maps[i].body...),
+			count: enc.maps[i].count,
+		}
+	}
+	return clone
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *binaryEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := getBinaryEncoder(enc.EncoderConfig, enc.format)
+	defer putBinaryEncoder(final)
+
+	if len(final.TimeKey) > 0 {
+		final.AddTime(final.TimeKey, entry.Time)
+	}
+
+	if len(final.LevelKey) > 0 {
+		final.addKey(final.LevelKey)
+		cur := final.size()
+		if final.EncodeLevel != nil {
+			final.EncodeLevel(entry.Level, final)
+		}
+		if cur == final.size() {
+			final.AppendString(entry.Level.String())
+		}
+	}
+
+	if len(entry.LoggerName) > 0 && len(final.NameKey) > 0 {
+		final.addKey(final.NameKey)
+		cur := final.size()
+		if final.EncodeName != nil {
+			final.EncodeName(entry.LoggerName, final)
+		}
+		if cur == final.size() {
+			final.AppendString(entry.LoggerName)
+		}
+	}
+
+	if entry.Caller.Defined {
+		if len(final.CallerKey) > 0 {
+			final.addKey(final.CallerKey)
+			cur := final.size()
+			if final.EncodeCaller != nil {
+				final.EncodeCaller(entry.Caller, final)
+			}
+			if cur == final.size() {
+				final.AppendString(entry.Caller.String())
+			}
+		}
+
+		if len(final.FunctionKey) > 0 {
+			final.AddString(final.FunctionKey, entry.Caller.Function)
+		}
+	}
+
+	if len(final.MessageKey) > 0 {
+		final.AddString(final.MessageKey, entry.Message)
+	}
+
+	// fields added with With() are merged into root, namespaces opened by them stay open for fields of entry
+	final.maps[0].body = append(final.maps[0].body, enc.maps[0].body...)
+	final.maps[0].count += enc.maps[0].count
+	for _, ns := range enc.maps[1:] {
+		final.OpenNamespace(ns.key)
+		current := final.current()
+		current.body = append(current.body, ns.body...)
+		current.count = ns.count
+	}
+
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.closeNamespaces()
+
+	if len(entry.Stack) > 0 && len(final.StacktraceKey) > 0 {
+		final.AddString(final.StacktraceKey, entry.Stack)
+	}
+
+	final.header = final.format.appendMapHeader(final.header[:0], final.maps[0].count)
+	buf := binaryPool.Get()
+	buf.Write(final.header)
+	buf.Write(final.maps[0].body)
+	return buf, nil
+}
+
+// Close open namespaces, each of them is added to its parent as a map
+func (enc *binaryEncoder) closeNamespaces() {
+	for len(enc.maps) > 1 {
+		ns := enc.maps[len(enc.maps)-1]
+		enc.maps = enc.maps[:len(enc.maps)-1]
+		enc.addMap(ns.key, ns.count, ns.body)
+	}
+}
+
+// Append key to the current map, value is appended right after it
+func (enc *binaryEncoder) addKey(key string) {
+	current := enc.current()
+	current.body = enc.format.appendString(current.body, key)
+	current.count++
+}
+
+// The current map which fields are added to
+func (enc *binaryEncoder) current() *binaryMap {
+	return &enc.maps[len(enc.maps)-1]
+}
+
+// Size of the current map, which tells whether encoders of config appended value
+func (enc *binaryEncoder) size() int {
+	return len(enc.current().body)
+}
+
+// Add encoded map with key to the current map
+func (enc *binaryEncoder) addMap(key string, count int, body []byte) {
+	enc.addKey(key)
+	current := enc.current()
+	current.body = append(enc.format.appendMapHeader(current.body, count), body...)
+}
+
+// AddArray implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := getBinaryArrayEncoder(enc.EncoderConfig, enc.format)
+	defer putBinaryArrayEncoder(arr)
+	err := marshaler.MarshalLogArray(arr)
+
+	enc.addKey(key)
+	current := enc.current()
+	current.body = append(enc.format.appendArrayHeader(current.body, arr.count), arr.body...)
+	return err
+}
+
+// AddObject implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	obj := getBinaryEncoder(enc.EncoderConfig, enc.format)
+	defer putBinaryEncoder(obj)
+	err := marshaler.MarshalLogObject(obj)
+	obj.closeNamespaces()
+
+	enc.addMap(key, obj.maps[0].count, obj.maps[0].body)
+	return err
+}
+
+// AddBinary implements zapcore.ObjectEncoder, value is encoded as binary instead of base64 string
+func (enc *binaryEncoder) AddBinary(key string, value []byte) {
+	enc.addKey(key)
+	current := enc.current()
+	current.body = enc.format.appendBytes(current.body, value)
+}
+
+// AddByteString implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddByteString(key string, value []byte) {
+	enc.addKey(key)
+	enc.AppendByteString(value)
+}
+
+// AddBool implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddBool(key string, value bool) {
+	enc.addKey(key)
+	enc.AppendBool(value)
+}
+
+// AddComplex128 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddComplex128(key string, value complex128) {
+	enc.addKey(key)
+	enc.AppendComplex128(value)
+}
+
+// AddComplex64 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddComplex64(key string, value complex64) {
+	enc.AddComplex128(key, complex128(value))
+}
+
+// AddDuration implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddDuration(key string, value time.Duration) {
+	enc.addKey(key)
+	enc.AppendDuration(value)
+}
+
+// AddFloat64 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddFloat64(key string, value float64) {
+	enc.addKey(key)
+	enc.AppendFloat64(value)
+}
+
+// AddFloat32 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddFloat32(key string, value float32) {
+	enc.addKey(key)
+	enc.AppendFloat32(value)
+}
+
+// AddInt implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddInt(key string, value int) { enc.AddInt64(key, int64(value)) }
+
+// AddInt64 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddInt64(key string, value int64) {
+	enc.addKey(key)
+	enc.AppendInt64(value)
+}
+
+// AddInt32 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddInt32(key string, value int32) { enc.AddInt64(key, int64(value)) }
+
+// AddInt16 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddInt16(key string, value int16) { enc.AddInt64(key, int64(value)) }
+
+// AddInt8 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddInt8(key string, value int8) { enc.AddInt64(key, int64(value)) }
+
+// AddString implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddString(key, value string) {
+	enc.addKey(key)
+	enc.AppendString(value)
+}
+
+// AddTime implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddTime(key string, value time.Time) {
+	enc.addKey(key)
+	enc.AppendTime(value)
+}
+
+// AddUint implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddUint(key string, value uint) { enc.AddUint64(key, uint64(value)) }
+
+// AddUint64 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddUint64(key string, value uint64) {
+	enc.addKey(key)
+	enc.AppendUint64(value)
+}
+
+// AddUint32 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddUint32(key string, value uint32) { enc.AddUint64(key, uint64(value)) }
+
+// AddUint16 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddUint16(key string, value uint16) { enc.AddUint64(key, uint64(value)) }
+
+// AddUint8 implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddUint8(key string, value uint8) { enc.AddUint64(key, uint64(value)) }
+
+// AddUintptr implements zapcore.ObjectEncoder
+func (enc *binaryEncoder) AddUintptr(key string, value uintptr) { enc.AddUint64(key, uint64(value)) }
+
+// AddReflected implements zapcore.ObjectEncoder, value is converted with json so that it is encoded the same way
+// as json encoding
+func (enc *binaryEncoder) AddReflected(key string, value interface{}) error {
+	encoded, err := encodeReflected(enc.format, nil, value)
+	if err != nil {
+		return err
+	}
+
+	enc.addKey(key)
+	current := enc.current()
+	current.body = append(current.body, encoded...)
+	return nil
+}
+
+// OpenNamespace implements zapcore.ObjectEncoder, buffer of namespace closed before is reused
+func (enc *binaryEncoder) OpenNamespace(key string) {
+	var body []byte
+	if len(enc.maps) < cap(enc.maps) {
+		body = enc.maps[:len(enc.maps)+1][len(enc.maps)].body[:0]
+	}
+	enc.maps = append(enc.maps, binaryMap{key: key, body: body})
+}
+
+// AppendBool implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendBool(value bool) {
+	current := enc.current()
+	current.body = enc.format.appendBool(current.body, value)
+}
+
+// AppendByteString implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendByteString(value []byte) {
+	current := enc.current()
+	current.body = enc.format.appendString(current.body, string(value))
+}
+
+// AppendComplex128 implements zapcore.PrimitiveArrayEncoder, value is encoded as string like 1+2i
+func (enc *binaryEncoder) AppendComplex128(value complex128) {
+	enc.AppendString(formatComplex(value))
+}
+
+// AppendComplex64 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendComplex64(value complex64) { enc.AppendComplex128(complex128(value)) }
+
+// AppendFloat64 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendFloat64(value float64) {
+	current := enc.current()
+	current.body = enc.format.appendFloat64(current.body, value)
+}
+
+// AppendFloat32 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendFloat32(value float32) {
+	current := enc.current()
+	current.body = enc.format.appendFloat32(current.body, value)
+}
+
+// AppendInt implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendInt(value int) { enc.AppendInt64(int64(value)) }
+
+// AppendInt64 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendInt64(value int64) {
+	current := enc.current()
+	current.body = enc.format.appendInt(current.body, value)
+}
+
+// AppendInt32 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendInt32(value int32) { enc.AppendInt64(int64(value)) }
+
+// AppendInt16 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendInt16(value int16) { enc.AppendInt64(int64(value)) }
+
+// AppendInt8 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendInt8(value int8) { enc.AppendInt64(int64(value)) }
+
+// AppendString implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendString(value string) {
+	current := enc.current()
+	current.body = enc.format.appendString(current.body, value)
+}
+
+// AppendUint implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendUint(value uint) { enc.AppendUint64(uint64(value)) }
+
+// AppendUint64 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendUint64(value uint64) {
+	current := enc.current()
+	current.body = enc.format.appendUint(current.body, value)
+}
+
+// AppendUint32 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendUint32(value uint32) { enc.AppendUint64(uint64(value)) }
+
+// AppendUint16 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendUint16(value uint16) { enc.AppendUint64(uint64(value)) }
+
+// AppendUint8 implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendUint8(value uint8) { enc.AppendUint64(uint64(value)) }
+
+// AppendUintptr implements zapcore.PrimitiveArrayEncoder
+func (enc *binaryEncoder) AppendUintptr(value uintptr) { enc.AppendUint64(uint64(value)) }
+
+// AppendDuration is used by AddDuration, duration is encoded by EncodeDuration or as nanoseconds
+func (enc *binaryEncoder) AppendDuration(value time.Duration) {
+	cur := enc.size()
+	if enc.EncodeDuration != nil {
+		enc.EncodeDuration(value, enc)
+	}
+	if cur == enc.size() {
+		enc.AppendInt64(int64(value))
+	}
+}
+
+// AppendTime is used by AddTime, time is encoded by EncodeTime or as nanoseconds since epoch
+func (enc *binaryEncoder) AppendTime(value time.Time) {
+	cur := enc.size()
+	if enc.EncodeTime != nil {
+		enc.EncodeTime(value, enc)
+	}
+	if cur == enc.size() {
+		enc.AppendInt64(value.UnixNano())
+	}
+}
+
+// binaryArrayEncoder implements zapcore.ArrayEncoder, elements are counted for header of array
+type binaryArrayEncoder struct {
+	config *zapcore.EncoderConfig
+	format binaryFormat
+	body   []byte
+	count  int
+}
+
+// Get array encoder from pool, buffer is reused
+func getBinaryArrayEncoder(config *zapcore.EncoderConfig, format binaryFormat) *binaryArrayEncoder {
+	arr := binaryArrayEncoderPool.Get().(*binaryArrayEncoder)
+	arr.config = config
+	arr.format = format
+	arr.body = arr.body[:0]
+	arr.count = 0
+	return arr
+}
+
+// Put array encoder back to pool
+func putBinaryArrayEncoder(arr *binaryArrayEncoder) {
+	arr.config = nil
+	binaryArrayEncoderPool.Put(arr)
+}
+
+// AppendArray implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	nested := getBinaryArrayEncoder(arr.config, arr.format)
+	defer putBinaryArrayEncoder(nested)
+	err := marshaler.MarshalLogArray(nested)
+
+	arr.body = append(arr.format.appendArrayHeader(arr.body, nested.count), nested.body...)
+	arr.count++
+	return err
+}
+
+// AppendObject implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	obj := getBinaryEncoder(arr.config, arr.format)
+	defer putBinaryEncoder(obj)
+	err := marshaler.MarshalLogObject(obj)
+	obj.closeNamespaces()
+
+	arr.body = append(arr.format.appendMapHeader(arr.body, obj.maps[0].count), obj.maps[0].body...)
+	arr.count++
+	return err
+}
+
+// AppendReflected implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendReflected(value interface{}) error {
+	encoded, err := encodeReflected(arr.format, nil, value)
+	if err != nil {
+		return err
+	}
+
+	arr.body = append(arr.body, encoded...)
+	arr.count++
+	return nil
+}
+
+// AppendBool implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendBool(value bool) {
+	arr.body = arr.format.appendBool(arr.body, value)
+	arr.count++
+}
+
+// AppendByteString implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendByteString(value []byte) {
+	arr.body = arr.format.appendString(arr.body, string(value))
+	arr.count++
+}
+
+// AppendComplex128 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendComplex128(value complex128) {
+	arr.AppendString(formatComplex(value))
+}
+
+// AppendComplex64 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendComplex64(value complex64) {
+	arr.AppendComplex128(complex128(value))
+}
+
+// AppendDuration implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendDuration(value time.Duration) {
+	cur := arr.count
+	if arr.config.EncodeDuration != nil {
+		arr.config.EncodeDuration(value, arr)
+	}
+	if cur == arr.count {
+		arr.AppendInt64(int64(value))
+	}
+}
+
+// AppendFloat64 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendFloat64(value float64) {
+	arr.body = arr.format.appendFloat64(arr.body, value)
+	arr.count++
+}
+
+// AppendFloat32 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendFloat32(value float32) {
+	arr.body = arr.format.appendFloat32(arr.body, value)
+	arr.count++
+}
+
+// AppendInt implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendInt(value int) { arr.AppendInt64(int64(value)) }
+
+// AppendInt64 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendInt64(value int64) {
+	arr.body = arr.format.appendInt(arr.body, value)
+	arr.count++
+}
+
+// AppendInt32 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendInt32(value int32) { arr.AppendInt64(int64(value)) }
+
+// AppendInt16 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendInt16(value int16) { arr.AppendInt64(int64(value)) }
+
+// AppendInt8 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendInt8(value int8) { arr.AppendInt64(int64(value)) }
+
+// AppendString implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendString(value string) {
+	arr.body = arr.format.appendString(arr.body, value)
+	arr.count++
+}
+
+// AppendTime implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendTime(value time.Time) {
+	cur := arr.count
+	if arr.config.EncodeTime != nil {
+		arr.config.EncodeTime(value, arr)
+	}
+	if cur == arr.count {
+		arr.AppendInt64(value.UnixNano())
+	}
+}
+
+// AppendUint implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendUint(value uint) { arr.AppendUint64(uint64(value)) }
+
+// AppendUint64 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendUint64(value uint64) {
+	arr.body = arr.format.appendUint(arr.body, value)
+	arr.count++
+}
+
+// AppendUint32 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendUint32(value uint32) { arr.AppendUint64(uint64(value)) }
+
+// AppendUint16 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendUint16(value uint16) { arr.AppendUint64(uint64(value)) }
+
+// AppendUint8 implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendUint8(value uint8) { arr.AppendUint64(uint64(value)) }
+
+// AppendUintptr implements zapcore.ArrayEncoder
+func (arr *binaryArrayEncoder) AppendUintptr(value uintptr) { arr.AppendUint64(uint64(value)) }
+
+// Format complex number like 1+2i, which is the same as json encoding
+func formatComplex(value complex128) string {
+	r, i := real(value), imag(value)
+	res := strconv.FormatFloat(r, 'g', -1, 64)
+	if i >= 0 {
+		res += "+"
+	}
+	return res + strconv.FormatFloat(i, 'g', -1, 64) + "i"
+}
+
+// Encode reflected value with json and append it to buf with format, keys of maps are sorted
+func encodeReflected(format binaryFormat, buf []byte, value interface{}) ([]byte, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	return appendJSONValue(format, buf, decoded), nil
+}
+
+// Append value decoded from json with format, integers are kept as integers
+func appendJSONValue(format binaryFormat, buf []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return format.appendNil(buf)
+	case bool:
+		return format.appendBool(buf, v)
+	case string:
+		return format.appendString(buf, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return format.appendInt(buf, i)
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return format.appendUint(buf, u)
+		}
+		f, _ := v.Float64()
+		return format.appendFloat64(buf, f)
+	case []interface{}:
+		buf = format.appendArrayHeader(buf, len(v))
+		for i := range v {
+			buf = appendJSONValue(format, buf, v[i])
+		}
+		return buf
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf = format.appendMapHeader(buf, len(v))
+		for _, k := range keys {
+			buf = format.appendString(buf, k)
+			buf = appendJSONValue(format, buf, v[k])
+		}
+		return buf
+	default:
+		return format.appendNil(buf)
+	}
+}
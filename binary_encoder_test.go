@@ -0,0 +1,128 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"testing"
+	"time"
+)
+
+// Entry and fields which are shared by tests and benchmarks of encoders
+func sampleEntry() (zapcore.Entry, []zapcore.Field) {
+	entry := zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC),
+		LoggerName: "db",
+		Message:    "connection is ready",
+		Caller:     zapcore.NewEntryCaller(0, "/src/db/conn.go", 42, true),
+	}
+
+	return entry, []zapcore.Field{
+		zap.String("host", "10.0.0.1"),
+		zap.Int("port", 5432),
+		zap.Int("pool", 10),
+		zap.Duration("elapsed", 1500*time.Millisecond),
+		zap.Bool("tls", true),
+		zap.Float64("ratio", 0.75),
+		zap.Strings("tags", []string{"primary", "eu-west-1"}),
+		zap.Object("user", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("name", "admin")
+			enc.AddInt64("id", 1024)
+			return nil
+		})),
+	}
+}
+
+func TestFormatComplex(t *testing.T) {
+	assert.Equal(t, "1+2i", formatComplex(complex(1, 2)))
+	assert.Equal(t, "1.5-2i", formatComplex(complex(1.5, -2)))
+}
+
+func TestBinaryEncoder_WithNamespace(t *testing.T) {
+	enc := newBinaryEncoder(&zapcore.EncoderConfig{MessageKey: "msg", StacktraceKey: "stacktrace"}, msgpackFormat{})
+	enc.AddString("app", "ut")
+	enc.OpenNamespace("ctx")
+	enc.AddInt("a", 1)
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "m", Stack: "s"}, []zapcore.Field{zap.Int("b", 2)})
+	assert.Nil(t, err)
+
+	// {"msg":"m","app":"ut","ctx":{"a":1,"b":2},"stacktrace":"s"}
+	assert.Equal(t, []byte{
+		0x84,
+		0xa3, 'm', 's', 'g', 0xa1, 'm',
+		0xa3, 'a', 'p', 'p', 0xa2, 'u', 't',
+		0xa3, 'c', 't', 'x', 0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02,
+		0xaa, 's', 't', 'a', 'c', 'k', 't', 'r', 'a', 'c', 'e', 0xa1, 's',
+	}, buf.Bytes())
+
+	// encoder is not changed by entries
+	assert.Len(t, enc.maps, 2)
+	assert.Equal(t, 1, enc.maps[1].count)
+}
+
+func TestBinaryEncoder_Clone(t *testing.T) {
+	enc := newBinaryEncoder(&zapcore.EncoderConfig{}, msgpackFormat{})
+	enc.AddString("a", "1")
+
+	clone := enc.Clone().(*binaryEncoder)
+	clone.AddString("b", "2")
+
+	assert.Equal(t, 1, enc.maps[0].count)
+	assert.Equal(t, 2, clone.maps[0].count)
+	assert.Equal(t, []byte{0xa1, 'a', 0xa1, '1'}, enc.maps[0].body)
+}
+
+func TestBinaryEncoder_WithError(t *testing.T) {
+	enc := newBinaryEncoder(&zapcore.EncoderConfig{}, msgpackFormat{})
+
+	// With unsupported reflected value
+	assert.NotNil(t, enc.AddReflected("ch", make(chan int)))
+	assert.Equal(t, 0, enc.maps[0].count)
+
+	// With error of array marshaler, elements appended before error are kept
+	err := enc.AddArray("arr", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		arr.AppendInt(1)
+		return errors.New("ut-error")
+	}))
+	assert.NotNil(t, err)
+	assert.Equal(t, []byte{0xa3, 'a', 'r', 'r', 0x91, 0x01}, enc.maps[0].body)
+}
+
+// Size of encoded sample entry is reported as bytes/entry
+func benchmarkEncoder(b *testing.B, enc zapcore.Encoder) {
+	entry, fields := sampleEntry()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	size := 0
+	for i := 0; i < b.N; i++ {
+		buf, err := enc.EncodeEntry(entry, fields)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = buf.Len()
+		buf.Free()
+	}
+	b.ReportMetric(float64(size), "bytes/entry")
+}
+
+func BenchmarkJSONEncoder(b *testing.B) {
+	benchmarkEncoder(b, zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()))
+}
+
+func BenchmarkMsgpackEncoder(b *testing.B) {
+	enc, _ := NewMsgpackEncoder(zap.NewProductionEncoderConfig())
+	benchmarkEncoder(b, enc)
+}
+
+func BenchmarkCBOREncoder(b *testing.B) {
+	enc, _ := NewCBOREncoder(zap.NewProductionEncoderConfig())
+	benchmarkEncoder(b, enc)
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"sync"
+	"time"
+)
+
+// Defaults of BufferConfig, the same as zapcore.BufferedWriteSyncer
+const (
+	// DefaultBufferSize is the default size of buffer.
+	DefaultBufferSize = 256 * 1024
+	// DefaultBufferFlushInterval is the default interval of flushing buffer in background.
+	DefaultBufferFlushInterval = 30 * time.Second
+)
+
+// BufferConfig buffers entries of file and network outputs in memory and writes them together, which cuts overhead of
+// small writes. Buffer is flushed once it is full, on interval, and while logger is synced or closed, entries which are
+// not flushed may be lost if process crashes. It works the same way as zapcore.BufferedWriteSyncer, stdout and stderr
+// are never buffered.
+//
+// Example config file in YAML:
+//
+//	buffer:
+//	  size: 256KB
+//	  flushInterval: 1s
+type BufferConfig struct {
+	// Size is size of buffer with unit, like 256KB, DefaultBufferSize if not provided.
+	Size string `json:"size,omitempty" yaml:"size,omitempty"`
+	// FlushInterval is interval of flushing buffer in background, like 1s, DefaultBufferFlushInterval if not provided.
+	FlushInterval string `json:"flushInterval,omitempty" yaml:"flushInterval,omitempty"`
+}
+
+// writeBuffer is parsed BufferConfig
+type writeBuffer struct {
+	size     int
+	interval time.Duration
+}
+
+// Parse buffer of config, nil is returned if config is nil
+func newWriteBuffer(config *BufferConfig) (*writeBuffer, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	res := &writeBuffer{
+		size:     DefaultBufferSize,
+		interval: DefaultBufferFlushInterval,
+	}
+
+	if len(config.Size) > 0 {
+		size, err := parseByteSize(config.Size)
+		if err != nil || size <= 0 {
+			return nil, errors.Errorf("invalid size of buffer, size:%s", config.Size)
+		}
+		res.size = int(size)
+	}
+
+	if len(config.FlushInterval) > 0 {
+		interval, err := time.ParseDuration(config.FlushInterval)
+		if err != nil || interval <= 0 {
+			return nil, errors.Errorf("invalid flush interval of buffer, flushInterval:%s", config.FlushInterval)
+		}
+		res.interval = interval
+	}
+
+	return res, nil
+}
+
+// Wrap write syncer with buffer, the returned function stops flushing in background,
+// flushes buffer and closes the write syncer
+func (buf *writeBuffer) wrap(syncer zapcore.WriteSyncer, closer func()) (zapcore.WriteSyncer, func()) {
+	if buf == nil {
+		return syncer, closer
+	}
+
+	writer := &bufferedWriter{
+		WriteSyncer: syncer,
+		size:        buf.size,
+		buf:         make([]byte, 0, buf.size),
+		done:        make(chan struct{}),
+	}
+
+	writer.wait.Add(1)
+	go writer.flushOnInterval(buf.interval)
+
+	return writer, func() {
+		close(writer.done)
+		writer.wait.Wait()
+
+		writer.mutex.Lock()
+		if err := writer.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v failed to flush buffer, error:%v\n", time.Now(), err)
+		}
+		writer.mutex.Unlock()
+
+		closer()
+	}
+}
+
+// bufferedWriter buffers entries and writes them to write syncer together
+type bufferedWriter struct {
+	zapcore.WriteSyncer
+	size  int
+	buf   []byte
+	mutex sync.Mutex
+	done  chan struct{}
+	wait  sync.WaitGroup
+}
+
+// Write implements zapcore.WriteSyncer, buffer is flushed before p if there is no room for it,
+// and p is written directly if it is not smaller than buffer
+func (writer *bufferedWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if len(writer.buf)+len(p) > writer.size {
+		if err := writer.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(p) >= writer.size {
+		return writer.WriteSyncer.Write(p)
+	}
+
+	writer.buf = append(writer.buf, p...)
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer, buffer is flushed before write syncer is synced
+func (writer *bufferedWriter) Sync() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if err := writer.flush(); err != nil {
+		return err
+	}
+
+	return writer.WriteSyncer.Sync()
+}
+
+// Rotate flushes buffer into the current file and rotates it immediately if write syncer could be rotated
+func (writer *bufferedWriter) Rotate() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if err := writer.flush(); err != nil {
+		return err
+	}
+
+	if rotator, ok := writer.WriteSyncer.(interface{ Rotate() error }); ok {
+		return rotator.Rotate()
+	}
+
+	return nil
+}
+
+// Write buffered entries to write syncer, entries which are not written are kept in buffer
+func (writer *bufferedWriter) flush() error {
+	if len(writer.buf) == 0 {
+		return nil
+	}
+
+	n, err := writer.WriteSyncer.Write(writer.buf)
+	if n < 0 || n > len(writer.buf) {
+		n = len(writer.buf)
+	}
+	writer.buf = writer.buf[:copy(writer.buf, writer.buf[n:])]
+
+	return err
+}
+
+// Flush buffer on interval until done is closed, errors are reported to stderr
+func (writer *bufferedWriter) flushOnInterval(interval time.Duration) {
+	defer writer.wait.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-writer.done:
+			return
+		case <-ticker.C:
+			writer.mutex.Lock()
+			if err := writer.flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "%v failed to flush buffer, error:%v\n", time.Now(), err)
+			}
+			writer.mutex.Unlock()
+		}
+	}
+}
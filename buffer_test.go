@@ -0,0 +1,146 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Wrap counting syncer with buffer of config
+func newTestBufferedWriter(t *testing.T, config *BufferConfig) (*countingSyncer, *bufferedWriter, func()) {
+	buf, err := newWriteBuffer(config)
+	assert.Nil(t, err)
+
+	syncer := &countingSyncer{}
+	writer, closer := buf.wrap(syncer, func() {})
+	return syncer, writer.(*bufferedWriter), closer
+}
+
+func TestNewWriteBuffer(t *testing.T) {
+	buf, err := newWriteBuffer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, buf)
+
+	buf, err = newWriteBuffer(&BufferConfig{})
+	assert.Nil(t, err)
+	assert.Equal(t, &writeBuffer{size: DefaultBufferSize, interval: DefaultBufferFlushInterval}, buf)
+
+	buf, err = newWriteBuffer(&BufferConfig{Size: "1KB", FlushInterval: "1s"})
+	assert.Nil(t, err)
+	assert.Equal(t, &writeBuffer{size: 1024, interval: time.Second}, buf)
+
+	for _, config := range []*BufferConfig{
+		{Size: "1XB"},
+		{Size: "0"},
+		{FlushInterval: "1x"},
+		{FlushInterval: "-1s"},
+	} {
+		_, err := newWriteBuffer(config)
+		assert.NotNil(t, err)
+	}
+
+	// syncer is not wrapped without buffer
+	syncer := &countingSyncer{}
+	res, _ := (*writeBuffer)(nil).wrap(syncer, func() {})
+	assert.Equal(t, syncer, res)
+}
+
+func TestBufferedWriter_Write(t *testing.T) {
+	syncer, writer, closer := newTestBufferedWriter(t, &BufferConfig{Size: "16", FlushInterval: "1h"})
+
+	// entries are written together once buffer is full
+	writer.Write([]byte("ut-first\n"))
+	assert.Equal(t, int32(0), syncer.writes)
+	writer.Write([]byte("ut-second\n"))
+	assert.Equal(t, int32(1), syncer.writes)
+
+	// entries not smaller than buffer are written directly
+	writer.Write([]byte("ut-message-larger-than-buffer\n"))
+	assert.Equal(t, int32(3), syncer.writes)
+
+	// buffer is flushed before syncing
+	writer.Write([]byte("ut-third\n"))
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, int32(4), syncer.writes)
+	assert.Equal(t, int32(1), syncer.syncs)
+
+	// buffer is flushed on close
+	writer.Write([]byte("ut-fourth\n"))
+	closer()
+	assert.Equal(t, int32(5), syncer.writes)
+}
+
+func TestBufferedWriter_WithFlushInterval(t *testing.T) {
+	syncer, writer, closer := newTestBufferedWriter(t, &BufferConfig{FlushInterval: "10ms"})
+	defer closer()
+
+	writer.Write([]byte("ut-message\n"))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&syncer.writes) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBuffer_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `", "stdout"]
+lumberjack:
+  maxsize: 1
+buffer:
+  size: 256KB
+  flushInterval: 1h
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Empty(t, readFileContent(path.Join(dir, "app.log")))
+
+	// buffered entries are flushed on shutdown
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.Equal(t, `{"msg":"ut-message"}`+"\n", readFileContent(path.Join(dir, "app.log")))
+
+	// buffered entries are flushed into the current file before rotation
+	syncer, closeSyncer, err := defaultLoader.openBufferedOutput(&OutputConfig{
+		Path:       path.Join(dir, "db.log"),
+		Lumberjack: config.Lumberjack,
+		Buffer:     config.Buffer,
+	})
+	assert.Nil(t, err)
+	defer closeSyncer()
+	syncer.Write([]byte("ut-message\n"))
+	assert.Nil(t, syncer.(*bufferedWriter).Rotate())
+	assert.Len(t, listFileNames(t, dir), 3)
+	assert.Empty(t, readFileContent(path.Join(dir, "db.log")))
+
+	// stdout is not buffered
+	syncer, _, err = defaultLoader.openBufferedOutput(&OutputConfig{Path: "stdout", Buffer: config.Buffer})
+	assert.Nil(t, err)
+	_, ok := syncer.(*bufferedWriter)
+	assert.False(t, ok)
+
+	// invalid buffer
+	_, _, err = defaultLoader.openBufferedOutput(&OutputConfig{Path: path.Join(dir, "db.log"), Buffer: &BufferConfig{Size: "1XB"}})
+	assert.NotNil(t, err)
+}
+
+func TestWithBuffer(t *testing.T) {
+	config := NewConfigWithOptions(WithBuffer(BufferConfig{Size: "256KB", FlushInterval: "1s"}))
+	assert.Equal(t, &BufferConfig{Size: "256KB", FlushInterval: "1s"}, config.Buffer)
+}
@@ -0,0 +1,334 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// builder collects combined config and options of logger built by New
+type builder struct {
+	config *Config
+	loader *Loader
+	opts   []zap.Option
+}
+
+// Option is used while building logger with New.
+type Option func(*builder)
+
+// WithLevel sets level of logger, info by default.
+func WithLevel(level zapcore.Level) Option {
+	return func(b *builder) {
+		b.config.Zap.Level = zap.NewAtomicLevelAt(level)
+	}
+}
+
+// WithNameLevel overrides level of logger created with zap.Logger.Named(name), see Config.Levels for details.
+func WithNameLevel(name string, level zapcore.Level) Option {
+	return func(b *builder) {
+		if b.config.Levels == nil {
+			b.config.Levels = make(map[string]zapcore.Level)
+		}
+
+		b.config.Levels[name] = level
+	}
+}
+
+// WithJSONEncoding encodes entries as json.
+func WithJSONEncoding() Option {
+	return func(b *builder) {
+		b.config.Zap.Encoding = "json"
+	}
+}
+
+// WithConsoleEncoding encodes entries as console friendly text, which is the default encoding.
+func WithConsoleEncoding() Option {
+	return func(b *builder) {
+		b.config.Zap.Encoding = ConsoleEncoding
+	}
+}
+
+// WithCSVEncoding encodes entries as rows of comma separated values with columns, like ts, level, msg and user_id,
+// see CSVConfig.Columns for details.
+func WithCSVEncoding(columns ...string) Option {
+	return func(b *builder) {
+		b.config.Zap.Encoding = CSVEncoding
+		b.config.CSV = &CSVConfig{Columns: columns}
+	}
+}
+
+// WithTimeZone renders timestamps in location, like UTC or Asia/Shanghai, regardless of local time of host.
+func WithTimeZone(name string) Option {
+	return func(b *builder) {
+		b.config.TimeZone = name
+	}
+}
+
+// WithLimits caps sizes of messages, fields and entries, see LimitsConfig.
+func WithLimits(limits LimitsConfig) Option {
+	return func(b *builder) {
+		b.config.Limits = &limits
+	}
+}
+
+// WithEncoderConfig replaces encoder config, NewZapStdoutEncoderConfig() by default.
+func WithEncoderConfig(config zapcore.EncoderConfig) Option {
+	return func(b *builder) {
+		b.config.Zap.EncoderConfig = config
+	}
+}
+
+// WithOutput replaces output paths of logger, stdout by default.
+func WithOutput(paths ...string) Option {
+	return func(b *builder) {
+		b.config.Zap.OutputPaths = paths
+	}
+}
+
+// WithErrorOutput replaces output paths of internal errors of logger, stderr by default.
+func WithErrorOutput(paths ...string) Option {
+	return func(b *builder) {
+		b.config.Zap.ErrorOutputPaths = paths
+	}
+}
+
+// WithRotation rotates file output paths with max size in megabytes, max age in days and max number of backups.
+// Files are not rotated by default.
+func WithRotation(maxSize, maxAge, maxBackups int, compress bool) Option {
+	return func(b *builder) {
+		b.config.Lumberjack = &lumberjack.Logger{
+			MaxSize:    maxSize,
+			MaxAge:     maxAge,
+			MaxBackups: maxBackups,
+			LocalTime:  true,
+			Compress:   compress,
+		}
+	}
+}
+
+// WithTimedRotation rotates file output paths on schedule, which is daily, hourly or cron expression,
+// max number of backups is retained in local time. It takes precedence over WithRotation.
+func WithTimedRotation(schedule string, maxBackups int) Option {
+	return func(b *builder) {
+		b.config.Rotation = &RotationConfig{
+			Schedule:   schedule,
+			MaxBackups: maxBackups,
+			LocalTime:  true,
+		}
+	}
+}
+
+// WithRetention caps total size of file outputs and their rotated files, like 5GB,
+// the oldest rotated files are deleted once it exceeds.
+func WithRetention(maxTotalSize string) Option {
+	return func(b *builder) {
+		b.config.Retention = &RetentionConfig{
+			MaxTotalSize: maxTotalSize,
+		}
+	}
+}
+
+// WithPermissions sets permission and owner of files and directories created for file outputs, see PermissionsConfig.
+func WithPermissions(permissions PermissionsConfig) Option {
+	return func(b *builder) {
+		b.config.Permissions = &permissions
+	}
+}
+
+// WithSyncPolicy controls how often file outputs are synced to disk, see SyncPolicyConfig.
+func WithSyncPolicy(policy SyncPolicyConfig) Option {
+	return func(b *builder) {
+		b.config.SyncPolicy = &policy
+	}
+}
+
+// WithSharedFile makes file outputs safe to be written by multiple processes, advisory lock of file is held while
+// writing if lock is true, see SharedFileConfig.
+func WithSharedFile(lock bool) Option {
+	return func(b *builder) {
+		b.config.Shared = &SharedFileConfig{Lock: lock}
+	}
+}
+
+// WithBuffer buffers entries of file and network outputs in memory and writes them together, see BufferConfig.
+func WithBuffer(buffer BufferConfig) Option {
+	return func(b *builder) {
+		b.config.Buffer = &buffer
+	}
+}
+
+// WithAsync writes entries of outputs in background with queue of queueSize entries, overflow is block,
+// dropOldest or dropNewest which is applied while queue is full, see AsyncConfig.
+func WithAsync(queueSize int, overflow string) Option {
+	return func(b *builder) {
+		b.config.Async = &AsyncConfig{QueueSize: queueSize, Overflow: overflow}
+	}
+}
+
+// WithSampling samples entries with the same level and message per second,
+// the first initial entries are logged and every thereafter entry is logged afterwards.
+func WithSampling(initial, thereafter int) Option {
+	return func(b *builder) {
+		b.config.Zap.Sampling = &zap.SamplingConfig{
+			Initial:    initial,
+			Thereafter: thereafter,
+		}
+	}
+}
+
+// WithLevelSampling samples entries of every level with its own rate, like sampling info level only,
+// see LevelSamplingConfig.
+func WithLevelSampling(sampling LevelSamplingConfig) Option {
+	return func(b *builder) {
+		b.config.LevelSampling = &sampling
+	}
+}
+
+// WithRateLimit allows perSecond entries per second of every key up to burst entries at once, key is msg or logger,
+// all entries share the same limit if key is empty, see RateLimitConfig.
+func WithRateLimit(perSecond float64, burst int, key string) Option {
+	return func(b *builder) {
+		b.config.RateLimit = &RateLimitConfig{PerSecond: perSecond, Burst: burst, Key: key}
+	}
+}
+
+// WithDedup collapses identical consecutive entries within window into one entry carrying number of repeats,
+// like 10s, see DedupConfig.
+func WithDedup(window string) Option {
+	return func(b *builder) {
+		b.config.Dedup = &DedupConfig{Window: window}
+	}
+}
+
+// WithRoute routes entries between minLevel and maxLevel to output paths, like warn and above to error log file,
+// bound is not applied if level is empty, see RouteConfig.
+func WithRoute(minLevel, maxLevel string, paths ...string) Option {
+	return func(b *builder) {
+		b.config.Routes = append(b.config.Routes, &RouteConfig{
+			MinLevel:    minLevel,
+			MaxLevel:    maxLevel,
+			OutputPaths: paths,
+		})
+	}
+}
+
+// WithNamedRoute routes entries of loggers named name and loggers nested in it to output paths only,
+// like audit to audit log file, see RouteConfig.
+func WithNamedRoute(name string, paths ...string) Option {
+	return func(b *builder) {
+		b.config.Routes = append(b.config.Routes, &RouteConfig{
+			Names:       []string{name},
+			Exclusive:   true,
+			OutputPaths: paths,
+		})
+	}
+}
+
+// WithFilter appends filter rule which drops entries matching drop expression or keeps only entries matching keep
+// expression, like FilterConfig{Drop: `logger == "health" && level < warn`}, see FilterConfig.
+func WithFilter(filter FilterConfig) Option {
+	return func(b *builder) {
+		b.config.Filters = append(b.config.Filters, &filter)
+	}
+}
+
+// WithRedaction masks, hashes or drops sensitive values with rules before entries are encoded, see RedactionConfig.
+func WithRedaction(redaction RedactionConfig) Option {
+	return func(b *builder) {
+		b.config.Redaction = &redaction
+	}
+}
+
+// WithPseudonymization replaces identifier fields with HMAC hashes salted with value of environment variable saltEnv,
+// like user_id and ip, see PseudonymizationConfig.
+func WithPseudonymization(saltEnv string, fields ...string) Option {
+	return func(b *builder) {
+		b.config.Pseudonymization = &PseudonymizationConfig{Fields: fields, SaltEnv: saltEnv}
+	}
+}
+
+// WithAuditOutput appends output path whose entries are chained by hashes and signed periodically, so that it is
+// tamper-evident, see AuditConfig.
+func WithAuditOutput(path string, audit AuditConfig) Option {
+	return func(b *builder) {
+		b.config.Outputs = append(b.config.Outputs, &OutputConfig{Path: path, Audit: &audit})
+	}
+}
+
+// WithEncryptedOutput appends output path whose entries are encrypted with key of encryption, see EncryptionConfig.
+func WithEncryptedOutput(path string, encryption EncryptionConfig) Option {
+	return func(b *builder) {
+		b.config.Outputs = append(b.config.Outputs, &OutputConfig{Path: path, Encrypted: true, Encryption: &encryption})
+	}
+}
+
+// WithFields adds initial fields to every entry of logger.
+func WithFields(fields map[string]interface{}) Option {
+	return func(b *builder) {
+		if b.config.Zap.InitialFields == nil {
+			b.config.Zap.InitialFields = make(map[string]interface{})
+		}
+
+		for k, v := range fields {
+			b.config.Zap.InitialFields[k] = v
+		}
+	}
+}
+
+// WithLoader builds logger with Loader instead of the default one, like a Loader created with WithBaseDir().
+func WithLoader(loader *Loader) Option {
+	return func(b *builder) {
+		b.loader = loader
+	}
+}
+
+// WithZapOptions passes zap options to logger, like zap.AddCaller().
+func WithZapOptions(opts ...zap.Option) Option {
+	return func(b *builder) {
+		b.opts = append(b.opts, opts...)
+	}
+}
+
+// Create builder with base config and options
+func newBuilder(config *Config, opts ...Option) *builder {
+	b := &builder{
+		config: config,
+		loader: defaultLoader,
+	}
+
+	for i := range opts {
+		opts[i](b)
+	}
+
+	return b
+}
+
+// NewConfigWithOptions creates combined config with options, which starts from NewZapStdoutConfig().
+// Options of Loader and zap options are ignored.
+func NewConfigWithOptions(opts ...Option) *Config {
+	return newBuilder(NewConfig(NewZapStdoutConfig(), nil), opts...).config
+}
+
+// New builds zap logger with options, like:
+//
+//	logger, err := rklogger.New(
+//		rklogger.WithLevel(zap.InfoLevel),
+//		rklogger.WithJSONEncoding(),
+//		rklogger.WithRotation(100, 7, 10, true),
+//		rklogger.WithOutput("stdout", "/var/log/app.log"))
+//
+// Logger is built in the same way as NewZapLoggerWithConfig with combined config created by options.
+func New(opts ...Option) (*zap.Logger, error) {
+	return newWithConfig(NewConfig(NewZapStdoutConfig(), nil), opts...)
+}
+
+// Build zap logger with options applied to base config
+func newWithConfig(config *Config, opts ...Option) (*zap.Logger, error) {
+	b := newBuilder(config, opts...)
+	return b.loader.NewZapLoggerWithConfig(b.config, b.opts...)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"path"
+	"testing"
+)
+
+func TestNewConfigWithOptions_HappyCase(t *testing.T) {
+	config := NewConfigWithOptions(
+		WithLevel(zap.WarnLevel),
+		WithNameLevel("db", zap.DebugLevel),
+		WithJSONEncoding(),
+		WithRotation(100, 7, 10, true),
+		WithOutput("stdout", "/var/log/app.log"),
+		WithErrorOutput("stdout"),
+		WithSampling(10, 100),
+		WithFields(map[string]interface{}{"app": "ut"}))
+
+	assert.Equal(t, zap.WarnLevel, config.Zap.Level.Level())
+	assert.Equal(t, zap.DebugLevel, config.Levels["db"])
+	assert.Equal(t, "json", config.Zap.Encoding)
+	assert.Equal(t, 100, config.Lumberjack.MaxSize)
+	assert.Equal(t, 7, config.Lumberjack.MaxAge)
+	assert.Equal(t, 10, config.Lumberjack.MaxBackups)
+	assert.True(t, config.Lumberjack.Compress)
+	assert.Equal(t, []string{"stdout", "/var/log/app.log"}, config.Zap.OutputPaths)
+	assert.Equal(t, []string{"stdout"}, config.Zap.ErrorOutputPaths)
+	assert.Equal(t, &zap.SamplingConfig{Initial: 10, Thereafter: 100}, config.Zap.Sampling)
+	assert.Equal(t, "ut", config.Zap.InitialFields["app"])
+}
+
+// Without options
+func TestNewConfigWithOptions_WithoutOptions(t *testing.T) {
+	config := NewConfigWithOptions()
+	assert.Equal(t, NewZapStdoutConfig().Level.Level(), config.Zap.Level.Level())
+	assert.Equal(t, "console", config.Zap.Encoding)
+	assert.Equal(t, []string{"stdout"}, config.Zap.OutputPaths)
+	assert.Nil(t, config.Lumberjack)
+	assert.Nil(t, config.Levels)
+
+	config = NewConfigWithOptions(WithJSONEncoding(), WithConsoleEncoding())
+	assert.Equal(t, "console", config.Zap.Encoding)
+}
+
+func TestNew_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := New(
+		WithLevel(zap.InfoLevel),
+		WithJSONEncoding(),
+		WithEncoderConfig(zap.NewProductionEncoderConfig()),
+		WithRotation(100, 7, 10, true),
+		WithOutput(filePath),
+		WithFields(map[string]interface{}{"app": "ut"}))
+	assert.Nil(t, err)
+
+	logger.Debug("ut-debug")
+	logger.Info("ut-info")
+
+	content := readFileContent(filePath)
+	assert.NotContains(t, content, "ut-debug")
+	assert.Contains(t, content, `"msg":"ut-info","app":"ut"`)
+}
+
+// With loader and zap options
+func TestNew_WithLoaderAndZapOptions(t *testing.T) {
+	dir := newTempDir(t)
+	logger, err := New(
+		WithLoader(NewLoader(WithBaseDir(dir))),
+		WithZapOptions(zap.Fields(zap.String("key", "value"))),
+		WithNameLevel("db", zap.ErrorLevel),
+		WithJSONEncoding(),
+		WithOutput("logs/ut.log"))
+	assert.Nil(t, err)
+
+	logger.Info("ut-info")
+	logger.Named("db").Info("db-info")
+
+	content := readFileContent(path.Join(dir, "logs", "ut.log"))
+	assert.Contains(t, content, `"key":"value"`)
+	assert.Contains(t, content, "ut-info")
+	assert.NotContains(t, content, "db-info")
+}
+
+// With invalid output
+func TestNew_WithInvalidOutput(t *testing.T) {
+	logger, err := New(WithOutput("invalid://ut"))
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package bunyan registers bunyan encoding with rklogger.RegisterEncoder, so that entries are encoded as Bunyan
+// records, which are read by bunyan CLI and pipelines of Node.js services, by config like:
+//
+//	encoding: bunyan
+//	outputPaths:
+//	  - stdout
+//
+// Import the package for side effects in order to enable the encoding:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/bunyan"
+//
+// Entries are encoded like:
+//
+//	{"v":0,"level":50,"name":"db","hostname":"host-1","pid":1024,"time":"2020-09-01T10:00:00.000Z",
+//	 "msg":"failed to query","src":{"file":"db/conn.go","line":42},
+//	 "err":{"message":"timeout","name":"*errors.fundamental","stack":"..."},"user_id":"1"}
+//
+// Name of executable is used as name of records if logger is not named. Fields are kept at top level,
+// fields named by keys of records are overwritten by entries.
+package bunyan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Encoding is the name of encoding which encodes entries as Bunyan records.
+	Encoding = "bunyan"
+	// Version is the value of v of records.
+	Version = 0
+
+	// layout of time, which is in UTC with milliseconds
+	timeLayout = "2006-01-02T15:04:05.000Z07:00"
+	// key of error fields added by zap.Error
+	errorKey = "error"
+)
+
+// pool of buffers returned by encoder
+var bufferPool = buffer.NewPool()
+
+func init() {
+	if err := rklogger.RegisterEncoder(Encoding, NewEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// encoder encodes entries as Bunyan records, fields are encoded by wrapped json encoder and added afterwards
+type encoder struct {
+	zapcore.Encoder
+	name       string
+	host       string
+	pid        int
+	lineEnding string
+}
+
+// NewEncoder creates encoder of Bunyan records with encoder config, only duration, time and line ending settings
+// are applied, keys of message, level, time, logger name, caller and stacktrace are defined by Bunyan.
+func NewEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	lineEnding := config.LineEnding
+	if len(lineEnding) == 0 {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+
+	// entries are encoded by Bunyan, wrapped encoder encodes fields only
+	config.MessageKey, config.LevelKey, config.TimeKey = "", "", ""
+	config.NameKey, config.CallerKey, config.StacktraceKey = "", "", ""
+	config.FunctionKey = ""
+	if config.EncodeTime == nil {
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+	if config.EncodeDuration == nil {
+		config.EncodeDuration = zapcore.StringDurationEncoder
+	}
+
+	host, _ := os.Hostname()
+	return &encoder{
+		Encoder:    zapcore.NewJSONEncoder(config),
+		name:       filepath.Base(os.Args[0]),
+		host:       host,
+		pid:        os.Getpid(),
+		lineEnding: lineEnding,
+	}, nil
+}
+
+// Clone implements zapcore.Encoder
+func (enc *encoder) Clone() zapcore.Encoder {
+	return &encoder{
+		Encoder:    enc.Encoder.Clone(),
+		name:       enc.name,
+		host:       enc.host,
+		pid:        enc.pid,
+		lineEnding: enc.lineEnding,
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *encoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := enc.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded.Bytes()))
+	decoder.UseNumber()
+	record := make(map[string]interface{})
+	if err := decoder.Decode(&record); err != nil {
+		return nil, err
+	}
+
+	// error field is mapped to err which is rendered by bunyan CLI, name is only known for fields of entry
+	if msg, ok := record[errorKey].(string); ok {
+		err := map[string]interface{}{"message": msg}
+		if stack, ok := record[errorKey+"Verbose"].(string); ok {
+			err["stack"] = stack
+			delete(record, errorKey+"Verbose")
+		}
+		for i := range fields {
+			if fields[i].Key == errorKey && fields[i].Type == zapcore.ErrorType {
+				err["name"] = fmt.Sprintf("%T", fields[i].Interface)
+			}
+		}
+		record["err"] = err
+		delete(record, errorKey)
+	}
+
+	record["v"] = Version
+	record["level"] = level(entry.Level)
+	record["name"] = enc.name
+	if len(entry.LoggerName) > 0 {
+		record["name"] = entry.LoggerName
+	}
+	record["hostname"] = enc.host
+	record["pid"] = enc.pid
+	record["time"] = formatTime(entry.Time)
+	record["msg"] = entry.Message
+	if entry.Caller.Defined {
+		src := map[string]interface{}{
+			"file": strings.TrimSuffix(entry.Caller.TrimmedPath(), ":"+strconv.Itoa(entry.Caller.Line)),
+			"line": entry.Caller.Line,
+		}
+		if len(entry.Caller.Function) > 0 {
+			src["func"] = entry.Caller.Function
+		}
+		record["src"] = src
+	}
+	if len(entry.Stack) > 0 {
+		record["stack"] = entry.Stack
+	}
+
+	res, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufferPool.Get()
+	buf.Write(res)
+	buf.AppendString(enc.lineEnding)
+	return buf, nil
+}
+
+// Level of Bunyan, dpanic is mapped to error, panic and fatal are mapped to fatal
+func level(level zapcore.Level) int {
+	switch {
+	case level < zapcore.DebugLevel:
+		return 10
+	case level == zapcore.DebugLevel:
+		return 20
+	case level == zapcore.InfoLevel:
+		return 30
+	case level == zapcore.WarnLevel:
+		return 40
+	case level <= zapcore.DPanicLevel:
+		return 50
+	default:
+		return 60
+	}
+}
+
+// Format time as time of Bunyan, which is ISO8601 in UTC with milliseconds
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package bunyan
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Encode entry with bunyan encoder and decode the record
+func encodeRecord(t *testing.T, enc zapcore.Encoder, entry zapcore.Entry, fields ...zapcore.Field) map[string]interface{} {
+	buf, err := enc.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	defer buf.Free()
+
+	assert.Equal(t, byte('\n'), buf.Bytes()[buf.Len()-1])
+
+	record := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	return record
+}
+
+func TestEncoder_EncodeEntry(t *testing.T) {
+	enc, err := NewEncoder(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2020, 9, 1, 18, 0, 0, 123456789, time.FixedZone("ut", 8*3600)),
+		LoggerName: "db",
+		Message:    "ut-message",
+		Caller:     zapcore.NewEntryCaller(0, "/src/db/conn.go", 42, true),
+	}
+
+	host, _ := os.Hostname()
+	assert.Equal(t, map[string]interface{}{
+		"v":        float64(Version),
+		"level":    float64(40),
+		"name":     "db",
+		"hostname": host,
+		"pid":      float64(os.Getpid()),
+		"time":     "2020-09-01T10:00:00.123Z",
+		"msg":      "ut-message",
+		"src":      map[string]interface{}{"file": "db/conn.go", "line": float64(42)},
+		"user_id":  "ut-user",
+		"attempt":  float64(2),
+	}, encodeRecord(t, enc, entry,
+		zap.String("user_id", "ut-user"),
+		zap.Int("attempt", 2),
+		zap.String("msg", "ut-overwritten"),
+	))
+}
+
+func TestEncoder_WithError(t *testing.T) {
+	enc, err := NewEncoder(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	record := encodeRecord(t, enc, zapcore.Entry{Message: "ut-message"}, zap.Error(errors.New("ut-error")))
+	assert.Equal(t, filepath.Base(os.Args[0]), record["name"])
+	assert.NotContains(t, record, "error")
+	assert.NotContains(t, record, "errorVerbose")
+
+	errRecord := record["err"].(map[string]interface{})
+	assert.Equal(t, "ut-error", errRecord["message"])
+	assert.Equal(t, "*errors.fundamental", errRecord["name"])
+	assert.True(t, strings.HasPrefix(errRecord["stack"].(string), "ut-error\n"))
+
+	// With stacktrace of entry
+	record = encodeRecord(t, enc, zapcore.Entry{Message: "ut-message", Stack: "ut-stack"}, zap.Error(fmt.Errorf("ut-error")))
+	assert.Equal(t, map[string]interface{}{"message": "ut-error", "name": "*errors.errorString"}, record["err"])
+	assert.Equal(t, "ut-stack", record["stack"])
+}
+
+func TestLevel(t *testing.T) {
+	assert.Equal(t, 20, level(zapcore.DebugLevel))
+	assert.Equal(t, 30, level(zapcore.InfoLevel))
+	assert.Equal(t, 40, level(zapcore.WarnLevel))
+	assert.Equal(t, 50, level(zapcore.ErrorLevel))
+	assert.Equal(t, 50, level(zapcore.DPanicLevel))
+	assert.Equal(t, 60, level(zapcore.PanicLevel))
+	assert.Equal(t, 60, level(zapcore.FatalLevel))
+}
+
+// Fields added with With() are encoded as well
+func TestEncoder_Clone(t *testing.T) {
+	enc, err := NewEncoder(zapcore.EncoderConfig{LineEnding: "\r\n"})
+	assert.Nil(t, err)
+	enc.AddString("app", "ut")
+
+	buf, err := enc.Clone().EncodeEntry(zapcore.Entry{Message: "ut-message"}, nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(buf.String(), "\r\n"))
+
+	record := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "ut", record["app"])
+	assert.Equal(t, float64(30), record["level"])
+}
+
+// Encoding is registered with rklogger
+func TestRegister(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "bunyan")
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "ut.log")
+
+	raw := []byte(fmt.Sprintf(`---
+level: info
+encoding: bunyan
+outputPaths: ["%s"]
+`, filePath))
+
+	logger, _, err := rklogger.NewZapLoggerWithBytes(raw, rklogger.YAML)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `"v":0`)
+	assert.Contains(t, string(content), `"msg":"ut-message"`)
+}
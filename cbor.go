@@ -0,0 +1,106 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"go.uber.org/zap/zapcore"
+	"math"
+)
+
+// CBOREncoding is the name of encoding which encodes entries as CBOR maps of RFC 8949.
+//
+// Keys of entries and encoders of encoderConfig are applied the same way as json encoding, entries are not
+// followed by line ending since CBOR data items are self-delimiting. Binary fields are encoded as byte strings
+// instead of base64 strings.
+const CBOREncoding = "cbor"
+
+// major types of CBOR
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+)
+
+func init() {
+	if err := RegisterEncoder(CBOREncoding, NewCBOREncoder); err != nil {
+		panic(err)
+	}
+}
+
+// NewCBOREncoder creates encoder of CBOR with encoder config.
+func NewCBOREncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return newBinaryEncoder(&config, cborFormat{}), nil
+}
+
+// cborFormat implements binaryFormat with CBOR, lengths are always definite
+type cborFormat struct{}
+
+// Append head of data item with major type and argument in the shortest form
+func appendCBORHead(buf []byte, major byte, value uint64) []byte {
+	major <<= 5
+
+	switch {
+	case value < 24:
+		return append(buf, major|byte(value))
+	case value <= math.MaxUint8:
+		return append(buf, major|24, byte(value))
+	case value <= math.MaxUint16:
+		return appendUint16(append(buf, major|25), uint16(value))
+	case value <= math.MaxUint32:
+		return appendUint32(append(buf, major|26), uint32(value))
+	default:
+		return appendUint64(append(buf, major|27), value)
+	}
+}
+
+func (cborFormat) appendMapHeader(buf []byte, n int) []byte {
+	return appendCBORHead(buf, cborMap, uint64(n))
+}
+
+func (cborFormat) appendArrayHeader(buf []byte, n int) []byte {
+	return appendCBORHead(buf, cborArray, uint64(n))
+}
+
+func (cborFormat) appendString(buf []byte, value string) []byte {
+	return append(appendCBORHead(buf, cborText, uint64(len(value))), value...)
+}
+
+func (cborFormat) appendBytes(buf []byte, value []byte) []byte {
+	return append(appendCBORHead(buf, cborBytes, uint64(len(value))), value...)
+}
+
+func (cborFormat) appendInt(buf []byte, value int64) []byte {
+	if value >= 0 {
+		return appendCBORHead(buf, cborUint, uint64(value))
+	}
+	return appendCBORHead(buf, cborNegInt, uint64(-1-value))
+}
+
+func (cborFormat) appendUint(buf []byte, value uint64) []byte {
+	return appendCBORHead(buf, cborUint, value)
+}
+
+func (cborFormat) appendFloat64(buf []byte, value float64) []byte {
+	return appendUint64(append(buf, 0xfb), math.Float64bits(value))
+}
+
+func (cborFormat) appendFloat32(buf []byte, value float32) []byte {
+	return appendUint32(append(buf, 0xfa), math.Float32bits(value))
+}
+
+func (cborFormat) appendBool(buf []byte, value bool) []byte {
+	if value {
+		return append(buf, 0xf5)
+	}
+	return append(buf, 0xf4)
+}
+
+func (cborFormat) appendNil(buf []byte) []byte {
+	return append(buf, 0xf6)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewCBOREncoder(t *testing.T) {
+	enc, err := NewCBOREncoder(zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		TimeKey:        "ts",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.EpochNanosTimeEncoder,
+		EncodeDuration: zapcore.NanosDurationEncoder,
+	})
+	assert.Nil(t, err)
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{
+		Level:   zapcore.WarnLevel,
+		Time:    time.Unix(0, 1000),
+		Message: "ut",
+	}, []zapcore.Field{
+		zap.Binary("raw", []byte{0x01}),
+		zap.Durations("waits", []time.Duration{time.Microsecond}),
+		zap.Int("neg", -500),
+		zap.Any("meta", []interface{}{nil, 1.5}),
+	})
+	assert.Nil(t, err)
+
+	// {"ts":1000,"level":"warn","msg":"ut","raw":h'01',"waits":[1000],"neg":-500,"meta":[null,1.5]}
+	assert.Equal(t, []byte{
+		0xa7,
+		0x62, 't', 's', 0x19, 0x03, 0xe8,
+		0x65, 'l', 'e', 'v', 'e', 'l', 0x64, 'w', 'a', 'r', 'n',
+		0x63, 'm', 's', 'g', 0x62, 'u', 't',
+		0x63, 'r', 'a', 'w', 0x41, 0x01,
+		0x65, 'w', 'a', 'i', 't', 's', 0x81, 0x19, 0x03, 0xe8,
+		0x63, 'n', 'e', 'g', 0x39, 0x01, 0xf3,
+		0x64, 'm', 'e', 't', 'a', 0x82, 0xf6, 0xfb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, buf.Bytes())
+}
+
+// Examples of appendix A of RFC 8949
+func TestCBORFormat(t *testing.T) {
+	format := cborFormat{}
+
+	assert.Equal(t, []byte{0x00}, format.appendInt(nil, 0))
+	assert.Equal(t, []byte{0x17}, format.appendInt(nil, 23))
+	assert.Equal(t, []byte{0x18, 0x18}, format.appendInt(nil, 24))
+	assert.Equal(t, []byte{0x19, 0x03, 0xe8}, format.appendInt(nil, 1000))
+	assert.Equal(t, []byte{0x1a, 0x00, 0x0f, 0x42, 0x40}, format.appendInt(nil, 1000000))
+	assert.Equal(t, []byte{0x1b, 0x00, 0x00, 0x00, 0xe8, 0xd4, 0xa5, 0x10, 0x00}, format.appendInt(nil, 1000000000000))
+	assert.Equal(t, []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, format.appendUint(nil, math.MaxUint64))
+	assert.Equal(t, []byte{0x20}, format.appendInt(nil, -1))
+	assert.Equal(t, []byte{0x29}, format.appendInt(nil, -10))
+	assert.Equal(t, []byte{0x38, 0x63}, format.appendInt(nil, -100))
+	assert.Equal(t, []byte{0x39, 0x03, 0xe7}, format.appendInt(nil, -1000))
+	assert.Equal(t, []byte{0x3b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, format.appendInt(nil, math.MinInt64))
+
+	assert.Equal(t, []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}, format.appendFloat64(nil, 1.1))
+	assert.Equal(t, []byte{0xfa, 0x47, 0xc3, 0x50, 0x00}, format.appendFloat32(nil, 100000.0))
+	assert.Equal(t, []byte{0xf4}, format.appendBool(nil, false))
+	assert.Equal(t, []byte{0xf5}, format.appendBool(nil, true))
+	assert.Equal(t, []byte{0xf6}, format.appendNil(nil))
+
+	assert.Equal(t, []byte{0x60}, format.appendString(nil, ""))
+	assert.Equal(t, []byte{0x64, 0x49, 0x45, 0x54, 0x46}, format.appendString(nil, "IETF"))
+	assert.Equal(t, []byte{0x44, 0x01, 0x02, 0x03, 0x04}, format.appendBytes(nil, []byte{0x01, 0x02, 0x03, 0x04}))
+	assert.Equal(t, []byte{0x98, 0x19}, format.appendArrayHeader(nil, 25))
+	assert.Equal(t, []byte{0xa0}, format.appendMapHeader(nil, 0))
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package cef registers cef encoding with rklogger.RegisterEncoder, so that entries are encoded as events of
+// Common Event Format and collected by ArcSight by config like:
+//
+//	encoding: cef
+//	outputPaths:
+//	  - syslog://arcsight.example.com:514?network=tcp
+//
+// Import the package for side effects in order to enable the encoding:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/cef"
+//
+// Entries are encoded like:
+//
+//	CEF:0|rookie-ninja|app|1.0|login failed|login failed|6|rt=1598954400000 dvchost=host-1 dvcpid=1024 src=10.0.0.1 suser=admin
+//
+// Signature ID is the value of signatureId field, message is used if it is missing. Fields are flattened to extension
+// with keys joined by dot, so that fields named by keys of CEF, like src or suser, are recognized by ArcSight.
+// Fields named rt, dvchost, dvcpid, deviceFacility, caller or stacktrace are overwritten by entries.
+// Encoders with other vendor, product and version of header are created by NewEncoderWithHeader.
+package cef
+
+import (
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/extension"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Encoding is the name of encoding which encodes entries as CEF events.
+	Encoding = "cef"
+	// Version is the version of CEF which events conform to.
+	Version = 0
+
+	// key of field which is used as signature id of header
+	signatureIDKey = "signatureId"
+)
+
+// pool of buffers returned by encoder
+var bufferPool = buffer.NewPool()
+
+// keys of extension which are defined by entries, fields with the same keys are overwritten
+var entryKeys = []string{"rt", "dvchost", "dvcpid", "deviceFacility", "caller", "stacktrace"}
+
+// escapes header values and extension values
+var (
+	headerEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	extensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r", `\r`, "\n", `\n`)
+)
+
+func init() {
+	if err := rklogger.RegisterEncoder(Encoding, NewEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// Header is the device of CEF header which events are reported by.
+type Header struct {
+	Vendor  string
+	Product string
+	Version string
+}
+
+// DefaultHeader returns header with vendor rookie-ninja, name of executable as product and version 1.0.
+func DefaultHeader() Header {
+	return Header{
+		Vendor:  "rookie-ninja",
+		Product: filepath.Base(os.Args[0]),
+		Version: "1.0",
+	}
+}
+
+// encoder encodes entries as CEF events, fields are encoded by wrapped json encoder and flattened to extension
+type encoder struct {
+	zapcore.Encoder
+	header     string
+	host       string
+	pid        int
+	lineEnding string
+}
+
+// NewEncoder creates encoder of CEF events with DefaultHeader and encoder config, only duration, time and
+// line ending settings are applied, keys of message, level, time, logger name, caller and stacktrace are
+// defined by CEF.
+func NewEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return NewEncoderWithHeader(DefaultHeader())(config)
+}
+
+// NewEncoderWithHeader returns constructor of CEF encoder with header, which could be registered with
+// rklogger.RegisterEncoder by another name.
+func NewEncoderWithHeader(header Header) rklogger.EncoderConstructor {
+	prefix := "CEF:" + strconv.Itoa(Version) + "|" + headerEscaper.Replace(header.Vendor) + "|" +
+		headerEscaper.Replace(header.Product) + "|" + headerEscaper.Replace(header.Version) + "|"
+
+	return func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		lineEnding := config.LineEnding
+		if len(lineEnding) == 0 {
+			lineEnding = zapcore.DefaultLineEnding
+		}
+
+		// entries are encoded by CEF, wrapped encoder encodes fields only
+		config.MessageKey, config.LevelKey, config.TimeKey = "", "", ""
+		config.NameKey, config.CallerKey, config.StacktraceKey = "", "", ""
+		config.FunctionKey = ""
+		if config.EncodeTime == nil {
+			config.EncodeTime = zapcore.ISO8601TimeEncoder
+		}
+		if config.EncodeDuration == nil {
+			config.EncodeDuration = zapcore.StringDurationEncoder
+		}
+
+		host, _ := os.Hostname()
+		return &encoder{
+			Encoder:    zapcore.NewJSONEncoder(config),
+			header:     prefix,
+			host:       host,
+			pid:        os.Getpid(),
+			lineEnding: lineEnding,
+		}, nil
+	}
+}
+
+// Clone implements zapcore.Encoder
+func (enc *encoder) Clone() zapcore.Encoder {
+	return &encoder{
+		Encoder:    enc.Encoder.Clone(),
+		header:     enc.header,
+		host:       enc.host,
+		pid:        enc.pid,
+		lineEnding: enc.lineEnding,
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *encoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := enc.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	values, err := extension.Decode(encoded.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	signatureID := entry.Message
+	if id, ok := values[signatureIDKey].(string); ok && len(id) > 0 {
+		signatureID = id
+	}
+	delete(values, signatureIDKey)
+	for _, key := range entryKeys {
+		delete(values, key)
+	}
+
+	buf := bufferPool.Get()
+	buf.AppendString(enc.header)
+	buf.AppendString(headerEscaper.Replace(signatureID))
+	buf.AppendByte('|')
+	buf.AppendString(headerEscaper.Replace(entry.Message))
+	buf.AppendByte('|')
+	buf.AppendInt(int64(severity(entry.Level)))
+	buf.AppendByte('|')
+
+	buf.AppendString("rt=")
+	buf.AppendInt(entry.Time.UnixNano() / 1e6)
+	appendPair(buf, "dvchost", enc.host)
+	buf.AppendString(" dvcpid=")
+	buf.AppendInt(int64(enc.pid))
+	if len(entry.LoggerName) > 0 {
+		appendPair(buf, "deviceFacility", entry.LoggerName)
+	}
+	if entry.Caller.Defined {
+		appendPair(buf, "caller", entry.Caller.TrimmedPath())
+	}
+	for _, pair := range extension.Flatten(values) {
+		appendPair(buf, pair.Key, pair.Value)
+	}
+	if len(entry.Stack) > 0 {
+		appendPair(buf, "stacktrace", entry.Stack)
+	}
+
+	buf.AppendString(enc.lineEnding)
+	return buf, nil
+}
+
+// Append key value pair of extension, pairs are separated by space
+func appendPair(buf *buffer.Buffer, key, value string) {
+	buf.AppendByte(' ')
+	buf.AppendString(key)
+	buf.AppendByte('=')
+	buf.AppendString(extensionEscaper.Replace(value))
+}
+
+// Severity of CEF from 0 to 10
+func severity(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return 1
+	case level == zapcore.InfoLevel:
+		return 3
+	case level == zapcore.WarnLevel:
+		return 6
+	case level == zapcore.ErrorLevel:
+		return 8
+	case level < zapcore.FatalLevel:
+		return 9
+	default:
+		return 10
+	}
+}
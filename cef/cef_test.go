@@ -0,0 +1,102 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package cef
+
+import (
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Encode entry with encoder
+func encode(t *testing.T, enc zapcore.Encoder, entry zapcore.Entry, fields ...zapcore.Field) string {
+	buf, err := enc.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	defer buf.Free()
+	return buf.String()
+}
+
+func TestEncoder_EncodeEntry(t *testing.T) {
+	enc, err := NewEncoderWithHeader(Header{Vendor: "ut|vendor", Product: "ut-product", Version: "2.0"})(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2020, 9, 1, 18, 0, 0, 123456789, time.FixedZone("ut", 8*3600)),
+		LoggerName: "auth",
+		Message:    "login failed",
+		Caller:     zapcore.NewEntryCaller(0, "/src/auth/login.go", 42, true),
+		Stack:      "ut-stack\nline",
+	}
+
+	host, _ := os.Hostname()
+	assert.Equal(t, "CEF:0|ut\\|vendor|ut-product|2.0|100|login failed|6|rt=1598954400123 dvchost="+host+
+		" dvcpid="+strconv.Itoa(os.Getpid())+" deviceFacility=auth caller=auth/login.go:42"+
+		` query=a\=b src=10.0.0.1 suser=admin user.id=1 stacktrace=ut-stack\nline`+"\n",
+		encode(t, enc, entry,
+			zap.String("suser", "admin"),
+			zap.String("src", "10.0.0.1"),
+			zap.String("signatureId", "100"),
+			zap.String("query", "a=b"),
+			zap.Object("user", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddInt("id", 1)
+				return nil
+			})),
+			zap.String("rt", "ut-overwritten"),
+		))
+}
+
+// Signature id is message if signatureId field is missing
+func TestEncoder_WithoutSignatureID(t *testing.T) {
+	enc, err := NewEncoder(zapcore.EncoderConfig{LineEnding: "\r\n"})
+	assert.Nil(t, err)
+
+	res := encode(t, enc.Clone(), zapcore.Entry{Message: "ut|message", Time: time.Unix(1, 0)})
+	assert.True(t, strings.HasPrefix(res, "CEF:0|rookie-ninja|"+DefaultHeader().Product+"|1.0|ut\\|message|ut\\|message|3|rt=1000 "))
+	assert.True(t, strings.HasSuffix(res, "\r\n"))
+	assert.NotContains(t, res, "deviceFacility")
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, 1, severity(zapcore.DebugLevel))
+	assert.Equal(t, 3, severity(zapcore.InfoLevel))
+	assert.Equal(t, 6, severity(zapcore.WarnLevel))
+	assert.Equal(t, 8, severity(zapcore.ErrorLevel))
+	assert.Equal(t, 9, severity(zapcore.DPanicLevel))
+	assert.Equal(t, 9, severity(zapcore.PanicLevel))
+	assert.Equal(t, 10, severity(zapcore.FatalLevel))
+}
+
+// Encoding is registered with rklogger
+func TestRegister(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "cef")
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "ut.log")
+
+	raw := []byte(fmt.Sprintf(`---
+level: info
+encoding: cef
+outputPaths: ["%s"]
+`, filePath))
+
+	logger, _, err := rklogger.NewZapLoggerWithBytes(raw, rklogger.YAML)
+	assert.Nil(t, err)
+	logger.Info("ut-message", zap.String("suser", "ut"))
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "CEF:0|rookie-ninja|"))
+	assert.Contains(t, string(content), "|ut-message|ut-message|3|")
+	assert.Contains(t, string(content), " suser=ut")
+}
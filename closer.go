@@ -0,0 +1,125 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"sync"
+	"syscall"
+)
+
+// Closer flushes and closes outputs of loggers.
+// Shutdown returns error of context once context is done before outputs are closed,
+// outputs are closed in background in that case.
+type Closer interface {
+	Shutdown(ctx context.Context) error
+}
+
+var (
+	_ Closer = (*outputCloser)(nil)
+	_ Closer = (*ReloadableLogger)(nil)
+	_ Closer = (*LoggerFactory)(nil)
+)
+
+// NewZapLoggerWithCloser inits zap logger with combined config along with Closer of its outputs
+func NewZapLoggerWithCloser(config *Config, opts ...zap.Option) (*zap.Logger, Closer, error) {
+	return defaultLoader.NewZapLoggerWithCloser(config, opts...)
+}
+
+// NewZapLoggerWithCloser inits zap logger with combined config and options of Loader along with Closer of its outputs
+// Files are always opened by rk-logger instead of zap.Config.Build() so that they could be closed.
+func (loader *Loader) NewZapLoggerWithCloser(config *Config, opts ...zap.Option) (*zap.Logger, Closer, error) {
+	if config == nil {
+		return nil, nil, errors.New("config is nil")
+	}
+
+	if config.Zap == nil {
+		return nil, nil, errors.New("zap config is nil")
+	}
+
+	outputs, errOutputs := config.toOutputConfigs()
+	logger, closeAll, err := loader.buildClosableZapLogger(config, outputs, errOutputs, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loader.levels.RegisterConfig(config.loggerName(), config)
+
+	return logger, &outputCloser{
+		logger:   logger,
+		closeAll: closeAll,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// outputCloser syncs logger and closes its outputs once
+type outputCloser struct {
+	logger   *zap.Logger
+	closeAll func()
+	once     sync.Once
+	done     chan struct{}
+	err      error
+}
+
+// Shutdown implements Closer
+func (closer *outputCloser) Shutdown(ctx context.Context) error {
+	closer.once.Do(func() {
+		go func() {
+			closer.err = ignoreConsoleSyncErrors(closer.logger.Sync())
+			closer.closeAll()
+			close(closer.done)
+		}()
+	})
+
+	select {
+	case <-closer.done:
+		return closer.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown implements Closer with Close
+func (logger *ReloadableLogger) Shutdown(ctx context.Context) error {
+	return shutdownWithContext(ctx, logger.Close)
+}
+
+// Shutdown implements Closer with Close
+func (factory *LoggerFactory) Shutdown(ctx context.Context) error {
+	return shutdownWithContext(ctx, factory.Close)
+}
+
+// Run close in background and wait for it until context is done
+func shutdownWithContext(ctx context.Context, close func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drop errors of syncing stdout and stderr which could not be synced if they are terminals or pipes
+func ignoreConsoleSyncErrors(err error) error {
+	var res error
+	for _, e := range multierr.Errors(err) {
+		if errors.Is(e, syscall.EINVAL) || errors.Is(e, syscall.ENOTTY) {
+			continue
+		}
+
+		res = multierr.Append(res, e)
+	}
+
+	return res
+}
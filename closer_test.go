@@ -0,0 +1,142 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"net/url"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Sink whose Sync blocks until unblocked
+type blockingSink struct {
+	memorySink
+	unblock chan struct{}
+}
+
+func (sink *blockingSink) Sync() error {
+	<-sink.unblock
+	return nil
+}
+
+var (
+	utBlockingSinks = make(map[string]*blockingSink)
+	_               = zap.RegisterSink("ut-blocking", func(u *url.URL) (zap.Sink, error) {
+		sink := &blockingSink{unblock: make(chan struct{})}
+		utBlockingSinks[u.Host] = sink
+		return sink, nil
+	})
+)
+
+func TestNewZapLoggerWithCloser_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	config := NewConfig(NewZapStdoutConfig(), NewLumberjackConfigDefault())
+	config.Zap.OutputPaths = []string{filePath, "ut-memory://closer"}
+	config.Zap.ErrorOutputPaths = []string{"ut-memory://closer-err"}
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.True(t, utMemorySinks["closer"].synced)
+	assert.True(t, utMemorySinks["closer"].closed)
+	assert.True(t, utMemorySinks["closer-err"].closed)
+	assert.Contains(t, readFileContent(filePath), "ut-message")
+
+	// shutdown twice
+	assert.Nil(t, closer.Shutdown(context.Background()))
+}
+
+// With nil config
+func TestNewZapLoggerWithCloser_WithNilConfig(t *testing.T) {
+	logger, closer, err := NewZapLoggerWithCloser(nil)
+	assert.Nil(t, logger)
+	assert.Nil(t, closer)
+	assert.NotNil(t, err)
+
+	logger, closer, err = NewZapLoggerWithCloser(&Config{})
+	assert.Nil(t, logger)
+	assert.Nil(t, closer)
+	assert.NotNil(t, err)
+}
+
+// With invalid output
+func TestNewZapLoggerWithCloser_WithInvalidOutput(t *testing.T) {
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	config.Zap.ErrorOutputPaths = []string{"invalid://ut"}
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, logger)
+	assert.Nil(t, closer)
+	assert.NotNil(t, err)
+}
+
+// Shutdown respects deadline of context
+func TestOutputCloser_ShutdownWithDeadline(t *testing.T) {
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	config.Zap.OutputPaths = []string{"ut-blocking://closer"}
+
+	_, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, closer.Shutdown(ctx))
+
+	// outputs are closed in background once sync finished
+	close(utBlockingSinks["closer"].unblock)
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.True(t, utBlockingSinks["closer"].closed)
+}
+
+func TestReloadableLogger_Shutdown(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	assert.Nil(t, logger.Shutdown(context.Background()))
+	assert.True(t, logger.closed)
+}
+
+func TestLoggerFactory_Shutdown(t *testing.T) {
+	factory := NewLoggerFactory(map[string]*Config{})
+	assert.Nil(t, factory.Shutdown(context.Background()))
+	assert.True(t, factory.closed)
+}
+
+// Shutdown with context which is already done
+func TestShutdownWithContext_WithCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+	err := shutdownWithContext(ctx, func() error {
+		<-unblock
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestIgnoreConsoleSyncErrors(t *testing.T) {
+	assert.Nil(t, ignoreConsoleSyncErrors(nil))
+
+	einval := &os.PathError{Op: "sync", Path: "/dev/stdout", Err: syscall.EINVAL}
+	assert.Nil(t, ignoreConsoleSyncErrors(multierr.Append(einval, einval)))
+
+	other := errors.New("ut-error")
+	assert.Equal(t, other, ignoreConsoleSyncErrors(multierr.Append(einval, other)))
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// GoogleTraceKey is the key of trace recognized by Google Cloud Logging, see GoogleTrace.
+	GoogleTraceKey = "logging.googleapis.com/trace"
+	// GoogleSpanIDKey is the key of span id recognized by Google Cloud Logging.
+	GoogleSpanIDKey = "logging.googleapis.com/spanId"
+)
+
+// GoogleTrace returns field of trace which correlates entries with requests in Google Cloud Logging.
+func GoogleTrace(projectID, traceID string) zap.Field {
+	return zap.String(GoogleTraceKey, fmt.Sprintf("projects/%s/traces/%s", projectID, traceID))
+}
+
+// GoogleSpanID returns field of span id recognized by Google Cloud Logging.
+func GoogleSpanID(spanID string) zap.Field {
+	return zap.String(GoogleSpanIDKey, spanID)
+}
+
+// GoogleSeverity returns severity of Google Cloud Logging for level, like WARNING and CRITICAL.
+func GoogleSeverity(level zapcore.Level) string {
+	switch level {
+	case zapcore.DebugLevel:
+		return "DEBUG"
+	case zapcore.InfoLevel:
+		return "INFO"
+	case zapcore.WarnLevel:
+		return "WARNING"
+	case zapcore.ErrorLevel:
+		return "ERROR"
+	case zapcore.DPanicLevel:
+		return "CRITICAL"
+	case zapcore.PanicLevel:
+		return "ALERT"
+	case zapcore.FatalLevel:
+		return "EMERGENCY"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// GoogleSeverityEncoder encodes level as severity of Google Cloud Logging, like WARNING and CRITICAL.
+func GoogleSeverityEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(GoogleSeverity(level))
+}
+
+// AWSLevelEncoder encodes level as log level of CloudWatch and Lambda, like WARN and FATAL.
+func AWSLevelEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch level {
+	case zapcore.DebugLevel:
+		enc.AppendString("DEBUG")
+	case zapcore.InfoLevel:
+		enc.AppendString("INFO")
+	case zapcore.WarnLevel:
+		enc.AppendString("WARN")
+	case zapcore.ErrorLevel, zapcore.DPanicLevel:
+		enc.AppendString("ERROR")
+	default:
+		enc.AppendString("FATAL")
+	}
+}
+
+// NewGKEConfig creates combined config for Google Kubernetes Engine which logs json to stdout
+// with severity, message and time keys expected by Google Cloud Logging.
+func NewGKEConfig() *Config {
+	return NewConfig(&zap.Config{
+		Level:    zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding: "json",
+		Sampling: &zap.SamplingConfig{
+			Initial:    100,
+			Thereafter: 100,
+		},
+		EncoderConfig: zapcore.EncoderConfig{
+			TimeKey:        "time",
+			LevelKey:       "severity",
+			NameKey:        "logger",
+			CallerKey:      "caller",
+			MessageKey:     "message",
+			StacktraceKey:  "stack_trace",
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeLevel:    GoogleSeverityEncoder,
+			EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+			EncodeDuration: zapcore.StringDurationEncoder,
+			EncodeCaller:   zapcore.ShortCallerEncoder,
+		},
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stderr"},
+	}, nil)
+}
+
+// NewCloudRunConfig creates combined config for Cloud Run, which is the same as NewGKEConfig()
+// since both of them collect stdout with Google Cloud Logging.
+func NewCloudRunConfig() *Config {
+	return NewGKEConfig()
+}
+
+// NewLambdaConfig creates combined config for AWS Lambda which logs json to stdout with timestamp,
+// level and message keys of Lambda json log format, entries are not sampled since invocations are short.
+func NewLambdaConfig() *Config {
+	config := NewECSConfig()
+	config.Zap.Sampling = nil
+
+	return config
+}
+
+// NewECSConfig creates combined config for AWS ECS which logs json to stdout with timestamp,
+// level and message keys, so that entries could be queried in CloudWatch Logs Insights.
+func NewECSConfig() *Config {
+	return NewConfig(&zap.Config{
+		Level:    zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding: "json",
+		Sampling: &zap.SamplingConfig{
+			Initial:    100,
+			Thereafter: 100,
+		},
+		EncoderConfig: zapcore.EncoderConfig{
+			TimeKey:        "timestamp",
+			LevelKey:       "level",
+			NameKey:        "logger",
+			CallerKey:      "caller",
+			MessageKey:     "message",
+			StacktraceKey:  "stackTrace",
+			LineEnding:     zapcore.DefaultLineEnding,
+			EncodeLevel:    AWSLevelEncoder,
+			EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+			EncodeDuration: zapcore.StringDurationEncoder,
+			EncodeCaller:   zapcore.ShortCallerEncoder,
+		},
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stderr"},
+	}, nil)
+}
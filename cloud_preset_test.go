@@ -0,0 +1,97 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path"
+	"testing"
+)
+
+// Encode level with level encoder as a string
+func encodeLevel(encoder zapcore.LevelEncoder, level zapcore.Level) string {
+	enc := zapcore.NewMapObjectEncoder()
+	enc.AddArray("level", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		encoder(level, arr)
+		return nil
+	}))
+
+	return enc.Fields["level"].([]interface{})[0].(string)
+}
+
+// Parse the first entry written to file as map
+func readFirstEntry(t *testing.T, filePath string) map[string]interface{} {
+	entry := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal([]byte(readFileContent(filePath)), &entry))
+	return entry
+}
+
+func TestGoogleSeverityEncoder(t *testing.T) {
+	assert.Equal(t, "DEBUG", encodeLevel(GoogleSeverityEncoder, zap.DebugLevel))
+	assert.Equal(t, "INFO", encodeLevel(GoogleSeverityEncoder, zap.InfoLevel))
+	assert.Equal(t, "WARNING", encodeLevel(GoogleSeverityEncoder, zap.WarnLevel))
+	assert.Equal(t, "ERROR", encodeLevel(GoogleSeverityEncoder, zap.ErrorLevel))
+	assert.Equal(t, "CRITICAL", encodeLevel(GoogleSeverityEncoder, zap.DPanicLevel))
+	assert.Equal(t, "ALERT", encodeLevel(GoogleSeverityEncoder, zap.PanicLevel))
+	assert.Equal(t, "EMERGENCY", encodeLevel(GoogleSeverityEncoder, zap.FatalLevel))
+	assert.Equal(t, "DEFAULT", encodeLevel(GoogleSeverityEncoder, zapcore.Level(100)))
+
+	// With severity name directly
+	assert.Equal(t, "WARNING", GoogleSeverity(zap.WarnLevel))
+}
+
+func TestAWSLevelEncoder(t *testing.T) {
+	assert.Equal(t, "DEBUG", encodeLevel(AWSLevelEncoder, zap.DebugLevel))
+	assert.Equal(t, "INFO", encodeLevel(AWSLevelEncoder, zap.InfoLevel))
+	assert.Equal(t, "WARN", encodeLevel(AWSLevelEncoder, zap.WarnLevel))
+	assert.Equal(t, "ERROR", encodeLevel(AWSLevelEncoder, zap.ErrorLevel))
+	assert.Equal(t, "ERROR", encodeLevel(AWSLevelEncoder, zap.DPanicLevel))
+	assert.Equal(t, "FATAL", encodeLevel(AWSLevelEncoder, zap.FatalLevel))
+}
+
+func TestNewGKEConfig_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := NewPresetLogger(PresetGKE, WithOutput(filePath))
+	assert.Nil(t, err)
+
+	logger.Warn("ut-warn", GoogleTrace("ut-project", "ut-trace"), GoogleSpanID("ut-span"))
+
+	entry := readFirstEntry(t, filePath)
+	assert.Equal(t, "WARNING", entry["severity"])
+	assert.Equal(t, "ut-warn", entry["message"])
+	assert.Equal(t, "projects/ut-project/traces/ut-trace", entry[GoogleTraceKey])
+	assert.Equal(t, "ut-span", entry[GoogleSpanIDKey])
+	assert.Contains(t, entry, "time")
+}
+
+func TestNewCloudRunConfig(t *testing.T) {
+	assert.Equal(t, NewGKEConfig().Zap.EncoderConfig.LevelKey, NewCloudRunConfig().Zap.EncoderConfig.LevelKey)
+}
+
+func TestNewLambdaConfig_HappyCase(t *testing.T) {
+	config := NewLambdaConfig()
+	assert.Nil(t, config.Zap.Sampling)
+
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := NewPresetLogger(PresetLambda, WithOutput(filePath))
+	assert.Nil(t, err)
+
+	logger.Info("ut-info")
+
+	entry := readFirstEntry(t, filePath)
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "ut-info", entry["message"])
+	assert.Contains(t, entry, "timestamp")
+}
+
+func TestNewECSConfig(t *testing.T) {
+	config := NewECSConfig()
+	assert.NotNil(t, config.Zap.Sampling)
+	assert.Equal(t, "json", config.Zap.Encoding)
+	assert.Equal(t, []string{"stdout"}, config.Zap.OutputPaths)
+}
@@ -0,0 +1,384 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package cloudlogging registers cloudlogging scheme with rklogger.RegisterCore, so that entries are written to
+// Google Cloud Logging as structured log entries by output paths like:
+//
+//	outputPaths:
+//	  # project is detected with GOOGLE_CLOUD_PROJECT, metadata server or credentials
+//	  - cloudlogging:///app
+//	  - cloudlogging://my-project/app?labels=team:payments,tier:backend
+//
+// Import the package for side effects in order to enable the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/cloudlogging"
+//
+// Host of url is the project id and path of url is the log id, name of executable by default.
+//
+// Query parameters:
+//
+//	resource:    auto or global, monitored resource of GCE, GKE and Cloud Run is detected by default
+//	labels:      labels of every entry, like key1:value1,key2:value2
+//	credentials: path of credentials json, application default credentials by default
+//	endpoint:    endpoint of entries.write, like endpoint of emulator in tests
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//
+// Entries are not encoded by encoder of zap config, message, logger name and fields are placed in json payload,
+// levels are mapped to severities and callers to source locations. Fields with rklogger.GoogleTraceKey,
+// rklogger.GoogleSpanIDKey, TraceSampledKey and LabelsKey are mapped to trace, span id, trace sampled and labels
+// of entries instead of payload.
+package cloudlogging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Scheme is the scheme of output paths which are written to Google Cloud Logging.
+	Scheme = "cloudlogging"
+	// Endpoint is the default endpoint of entries.write.
+	Endpoint = "https://logging.googleapis.com/v2/entries:write"
+	// TraceSampledKey is the key of field which marks trace of entry as sampled.
+	TraceSampledKey = "logging.googleapis.com/trace_sampled"
+	// LabelsKey is the key of field whose string values are added to labels of entry.
+	LabelsKey = "logging.googleapis.com/labels"
+
+	// scope of access token
+	loggingWriteScope = "https://www.googleapis.com/auth/logging.write"
+)
+
+func init() {
+	if err := rklogger.RegisterCore(Scheme, newCoreWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// sourceLocation is the source location of entry
+type sourceLocation struct {
+	File     string `json:"file"`
+	Line     string `json:"line"`
+	Function string `json:"function,omitempty"`
+}
+
+// logEntry is the log entry of Google Cloud Logging
+type logEntry struct {
+	Timestamp      string                 `json:"timestamp"`
+	Severity       string                 `json:"severity"`
+	JSONPayload    map[string]interface{} `json:"jsonPayload"`
+	Labels         map[string]string      `json:"labels,omitempty"`
+	Trace          string                 `json:"trace,omitempty"`
+	SpanID         string                 `json:"spanId,omitempty"`
+	TraceSampled   bool                   `json:"traceSampled,omitempty"`
+	SourceLocation *sourceLocation        `json:"sourceLocation,omitempty"`
+}
+
+// writeRequest is the request of entries.write, entries are encoded when they are written
+type writeRequest struct {
+	LogName        string            `json:"logName"`
+	Resource       *resource         `json:"resource"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Entries        []json.RawMessage `json:"entries"`
+	PartialSuccess bool              `json:"partialSuccess"`
+}
+
+// client sends entries with entries.write, it is only used by the flushing goroutine of batch.Writer
+type client struct {
+	endpoint string
+	logName  string
+	resource *resource
+	labels   map[string]string
+	http     *http.Client
+}
+
+// loggingCore implements zapcore.Core which writes entries to Google Cloud Logging
+type loggingCore struct {
+	zapcore.LevelEnabler
+	writer *batch.Writer
+	fields []zapcore.Field
+}
+
+// Create core with url of output path and level enabler of zap config
+func newCoreWithURL(u url.URL, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	config, client, err := parseURL(context.Background(), &u, newMetadataClient())
+	if err != nil {
+		return nil, err
+	}
+
+	return newCore(enabler, config, client), nil
+}
+
+// Create core which sends batches of entries with client
+func newCore(enabler zapcore.LevelEnabler, config batch.Config, client *client) *loggingCore {
+	return &loggingCore{
+		LevelEnabler: enabler,
+		writer:       batch.NewWriter(config, client.write),
+	}
+}
+
+// Parse batching config and client from url, project id and resource are detected if missing
+func parseURL(ctx context.Context, u *url.URL, metadata *metadataClient) (batch.Config, *client, error) {
+	config := batch.DefaultConfig()
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, err
+	}
+
+	detect := true
+	labels := make(map[string]string)
+	credentialsFile, endpoint := "", Endpoint
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		switch key {
+		case "resource":
+			if value != "auto" && value != "global" {
+				return config, nil, errors.Errorf("resource is not supported in cloudlogging url, resource:%s", value)
+			}
+			detect = value == "auto"
+		case "labels":
+			for _, pair := range strings.Split(value, ",") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) != 2 || len(kv[0]) == 0 {
+					return config, nil, errors.Errorf("invalid label in cloudlogging url, label:%s", pair)
+				}
+				labels[kv[0]] = kv[1]
+			}
+		case "credentials":
+			credentialsFile = value
+		case "endpoint":
+			endpoint = value
+		default:
+			return config, nil, errors.Errorf("unknown query of cloudlogging url, key:%s", key)
+		}
+	}
+
+	credentials, err := findCredentials(ctx, credentialsFile)
+	if err != nil {
+		return config, nil, err
+	}
+
+	projectID := u.Host
+	if len(projectID) == 0 {
+		projectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if len(projectID) == 0 && detect {
+		projectID = metadata.getOrEmpty(ctx, "project/project-id")
+	}
+	if len(projectID) == 0 {
+		projectID = credentials.ProjectID
+	}
+	if len(projectID) == 0 {
+		return config, nil, errors.New("project id is missing in cloudlogging url and could not be detected")
+	}
+
+	logID := strings.Trim(u.Path, "/")
+	if len(logID) == 0 {
+		logID = filepath.Base(os.Args[0])
+	}
+
+	res := &resource{
+		Type:   "global",
+		Labels: map[string]string{"project_id": projectID},
+	}
+	if detect {
+		res = detectResource(ctx, metadata, projectID)
+	}
+
+	return config, &client{
+		endpoint: endpoint,
+		logName:  fmt.Sprintf("projects/%s/logs/%s", projectID, strings.ReplaceAll(url.PathEscape(logID), "/", "%2F")),
+		resource: res,
+		labels:   labels,
+		http:     oauth2.NewClient(ctx, credentials.TokenSource),
+	}, nil
+}
+
+// Find credentials with scope of writing logs from file or application default credentials
+func findCredentials(ctx context.Context, credentialsFile string) (*google.Credentials, error) {
+	if len(credentialsFile) == 0 {
+		credentials, err := google.FindDefaultCredentials(ctx, loggingWriteScope)
+		return credentials, errors.Wrap(err, "failed to find default credentials of google cloud")
+	}
+
+	content, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read credentials of google cloud")
+	}
+
+	credentials, err := google.CredentialsFromJSON(ctx, content, loggingWriteScope)
+	return credentials, errors.Wrapf(err, "failed to parse credentials of google cloud, path:%s", credentialsFile)
+}
+
+// With implements zapcore.Core
+func (core *loggingCore) With(fields []zapcore.Field) zapcore.Core {
+	res := make([]zapcore.Field, 0, len(core.fields)+len(fields))
+	res = append(res, core.fields...)
+	res = append(res, fields...)
+
+	return &loggingCore{
+		LevelEnabler: core.LevelEnabler,
+		writer:       core.writer,
+		fields:       res,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *loggingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core, entry is queued and sent in background
+func (core *loggingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	payload, err := json.Marshal(toLogEntry(entry, core.fields, fields))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode log entry")
+	}
+
+	_, err = core.writer.Write(payload)
+	return err
+}
+
+// Sync implements zapcore.Core, queued entries are sent
+func (core *loggingCore) Sync() error {
+	return core.writer.Sync()
+}
+
+// Close implements io.Closer, queued entries are sent before closing
+func (core *loggingCore) Close() error {
+	return core.writer.Close()
+}
+
+// Stats returns delivery counters of batches which are sent, which are listed by rklogger.ListDeliveryStats()
+func (core *loggingCore) Stats() batch.Stats {
+	return core.writer.Stats()
+}
+
+// Convert entry and fields to log entry of Google Cloud Logging
+func toLogEntry(entry zapcore.Entry, fieldLists ...[]zapcore.Field) *logEntry {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fields := range fieldLists {
+		for i := range fields {
+			fields[i].AddTo(enc)
+		}
+	}
+
+	res := &logEntry{
+		Timestamp:   entry.Time.UTC().Format(time.RFC3339Nano),
+		Severity:    rklogger.GoogleSeverity(entry.Level),
+		JSONPayload: enc.Fields,
+	}
+
+	if trace, ok := enc.Fields[rklogger.GoogleTraceKey].(string); ok {
+		res.Trace = trace
+		delete(enc.Fields, rklogger.GoogleTraceKey)
+	}
+
+	if spanID, ok := enc.Fields[rklogger.GoogleSpanIDKey].(string); ok {
+		res.SpanID = spanID
+		delete(enc.Fields, rklogger.GoogleSpanIDKey)
+	}
+
+	if sampled, ok := enc.Fields[TraceSampledKey].(bool); ok {
+		res.TraceSampled = sampled
+		delete(enc.Fields, TraceSampledKey)
+	}
+
+	if labels, ok := enc.Fields[LabelsKey].(map[string]interface{}); ok {
+		res.Labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			res.Labels[k] = fmt.Sprint(v)
+		}
+		delete(enc.Fields, LabelsKey)
+	}
+
+	if entry.Caller.Defined {
+		res.SourceLocation = &sourceLocation{
+			File:     entry.Caller.File,
+			Line:     strconv.Itoa(entry.Caller.Line),
+			Function: entry.Caller.Function,
+		}
+	}
+
+	enc.Fields["message"] = entry.Message
+	if len(entry.LoggerName) > 0 {
+		enc.Fields["logger"] = entry.LoggerName
+	}
+	// stack_trace is recognized by Error Reporting
+	if len(entry.Stack) > 0 {
+		enc.Fields["stack_trace"] = entry.Stack
+	}
+
+	return res
+}
+
+// Send entries with entries.write, partial success is enabled so that valid entries are written
+func (client *client) write(ctx context.Context, entries [][]byte) error {
+	req := &writeRequest{
+		LogName:        client.logName,
+		Resource:       client.resource,
+		Labels:         client.labels,
+		Entries:        make([]json.RawMessage, 0, len(entries)),
+		PartialSuccess: true,
+	}
+	for i := range entries {
+		req.Entries = append(req.Entries, entries[i])
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return batch.Permanent(err)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, client.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.http.Do(httpReq)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write entries, endpoint:%s", client.endpoint)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusBadRequest {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	reply, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = errors.Errorf("entries are not accepted, endpoint:%s, status:%d, body:%s",
+		client.endpoint, resp.StatusCode, reply)
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return err
+	}
+
+	return batch.Permanent(err)
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package cloudlogging
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+// loggingServer issues access tokens and records requests of entries.write
+type loggingServer struct {
+	*httptest.Server
+	requests []*writeRequest
+	tokens   []string
+	status   []int
+	mutex    sync.Mutex
+}
+
+// Start logging server along with token endpoint of service account
+func newLoggingServer(t *testing.T) *loggingServer {
+	server := &loggingServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"ut-token","token_type":"Bearer","expires_in":3600}`))
+			return
+		}
+
+		req := &writeRequest{}
+		json.NewDecoder(r.Body).Decode(req)
+		server.requests = append(server.requests, req)
+		server.tokens = append(server.tokens, r.Header.Get("Authorization"))
+
+		status := http.StatusOK
+		if len(server.status) > 0 {
+			status, server.status = server.status[0], server.status[1:]
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Write credentials of service account whose token endpoint is tokenURL
+func writeCredentials(t *testing.T, tokenURL string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	content, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "ut-credentials-project",
+		"private_key_id": "ut",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"client_email":   "ut@ut-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURL,
+	})
+
+	filePath := path.Join(t.TempDir(), "credentials.json")
+	assert.Nil(t, ioutil.WriteFile(filePath, content, 0600))
+	return filePath
+}
+
+// Create core which writes entries to logging server
+func newTestCore(t *testing.T, server *loggingServer, rawURL string) *loggingCore {
+	u, _ := url.Parse(rawURL + "&credentials=" + writeCredentials(t, server.URL+"/token") +
+		"&endpoint=" + server.URL + "/v2/entries:write")
+	config, client, err := parseURL(context.Background(), u, newMetadataServer(t, map[string]string{}))
+	assert.Nil(t, err)
+
+	return newCore(zap.InfoLevel, config, client)
+}
+
+func TestParseURL_HappyCase(t *testing.T) {
+	server := newLoggingServer(t)
+	credentials := writeCredentials(t, server.URL+"/token")
+
+	u, _ := url.Parse("cloudlogging://ut-project/app/access?resource=global&labels=team:payments,tier:backend&linger=5s&credentials=" + credentials)
+	config, client, err := parseURL(context.Background(), u, newMetadataServer(t, gceMetadata))
+	assert.Nil(t, err)
+	assert.Equal(t, Endpoint, client.endpoint)
+	assert.Equal(t, "projects/ut-project/logs/app%2Faccess", client.logName)
+	assert.Equal(t, "global", client.resource.Type)
+	assert.Equal(t, map[string]string{"team": "payments", "tier": "backend"}, client.labels)
+	assert.Equal(t, 5*time.Second, config.Linger)
+
+	// With project id of metadata server and detected resource
+	u, _ = url.Parse("cloudlogging:///app?credentials=" + credentials)
+	_, client, err = parseURL(context.Background(), u, newMetadataServer(t, gceMetadata))
+	assert.Nil(t, err)
+	assert.Equal(t, "projects/ut-project/logs/app", client.logName)
+	assert.Equal(t, "gce_instance", client.resource.Type)
+
+	// With project id of credentials
+	u, _ = url.Parse("cloudlogging://?credentials=" + credentials)
+	_, client, err = parseURL(context.Background(), u, newMetadataServer(t, map[string]string{}))
+	assert.Nil(t, err)
+	assert.Contains(t, client.logName, "projects/ut-credentials-project/logs/")
+}
+
+func TestParseURL_WithInvalidURL(t *testing.T) {
+	server := newLoggingServer(t)
+	credentials := writeCredentials(t, server.URL+"/token")
+
+	for _, raw := range []string{
+		"cloudlogging://ut-project/app?resource=gce",
+		"cloudlogging://ut-project/app?labels=team",
+		"cloudlogging://ut-project/app?unknown=x",
+		"cloudlogging://ut-project/app?linger=x",
+	} {
+		u, _ := url.Parse(raw + "&credentials=" + credentials)
+		_, _, err := parseURL(context.Background(), u, newMetadataServer(t, map[string]string{}))
+		assert.NotNil(t, err, raw)
+	}
+
+	// With missing credentials
+	u, _ := url.Parse("cloudlogging://ut-project/app?credentials=" + path.Join(t.TempDir(), "not-exist.json"))
+	_, _, err := parseURL(context.Background(), u, newMetadataServer(t, map[string]string{}))
+	assert.NotNil(t, err)
+}
+
+func TestToLogEntry(t *testing.T) {
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+		LoggerName: "ut-logger",
+		Message:    "ut-message",
+		Caller:     zapcore.NewEntryCaller(0, "ut.go", 10, true),
+		Stack:      "ut-stack",
+	}
+
+	res := toLogEntry(entry, []zapcore.Field{
+		rklogger.GoogleTrace("ut-project", "ut-trace"),
+		rklogger.GoogleSpanID("ut-span"),
+		zap.Bool(TraceSampledKey, true),
+	}, []zapcore.Field{
+		zap.Any(LabelsKey, map[string]interface{}{"key": "value", "count": 1}),
+		zap.Int("userId", 1),
+	})
+
+	assert.Equal(t, &logEntry{
+		Timestamp: "2020-01-02T03:04:05.000000006Z",
+		Severity:  "WARNING",
+		JSONPayload: map[string]interface{}{
+			"message":     "ut-message",
+			"logger":      "ut-logger",
+			"stack_trace": "ut-stack",
+			"userId":      int64(1),
+		},
+		Labels:         map[string]string{"key": "value", "count": "1"},
+		Trace:          "projects/ut-project/traces/ut-trace",
+		SpanID:         "ut-span",
+		TraceSampled:   true,
+		SourceLocation: &sourceLocation{File: "ut.go", Line: "10"},
+	}, res)
+}
+
+func TestCore_HappyCase(t *testing.T) {
+	server := newLoggingServer(t)
+	core := newTestCore(t, server, "cloudlogging://ut-project/app?labels=team:payments&linger=1h")
+
+	logger := zap.New(core).With(zap.String("key", "value"))
+	logger.Debug("ut-debug")
+	logger.Info("ut-info")
+	logger.Error("ut-error")
+	assert.Nil(t, logger.Sync())
+	assert.Nil(t, core.Close())
+
+	assert.Len(t, server.requests, 1)
+	req := server.requests[0]
+	assert.Equal(t, "projects/ut-project/logs/app", req.LogName)
+	assert.Equal(t, "global", req.Resource.Type)
+	assert.Equal(t, map[string]string{"team": "payments"}, req.Labels)
+	assert.True(t, req.PartialSuccess)
+	assert.Equal(t, "Bearer ut-token", server.tokens[0])
+
+	assert.Len(t, req.Entries, 2)
+	entry := &logEntry{}
+	assert.Nil(t, json.Unmarshal(req.Entries[1], entry))
+	assert.Equal(t, "ERROR", entry.Severity)
+	assert.Equal(t, map[string]interface{}{"message": "ut-error", "key": "value"}, entry.JSONPayload)
+}
+
+func TestCore_WithRetries(t *testing.T) {
+	server := newLoggingServer(t)
+	server.status = []int{http.StatusServiceUnavailable}
+	core := newTestCore(t, server, "cloudlogging://ut-project/app?linger=1h&backoff=1ms")
+	defer core.Close()
+
+	zap.New(core).Info("ut-info")
+	assert.Nil(t, core.Sync())
+	assert.Len(t, server.requests, 2)
+}
+
+func TestCore_WithRejectedEntries(t *testing.T) {
+	server := newLoggingServer(t)
+	server.status = []int{http.StatusBadRequest}
+	core := newTestCore(t, server, "cloudlogging://ut-project/app?linger=1h&backoff=1ms")
+	defer core.Close()
+
+	zap.New(core).Info("ut-info")
+	assert.NotNil(t, core.Sync())
+	assert.Len(t, server.requests, 1)
+}
+
+// Entries are written to Google Cloud Logging along with other outputs of config
+func TestRegister(t *testing.T) {
+	server := newLoggingServer(t)
+	outputPath := "cloudlogging://ut-project/app?resource=global&linger=1h&credentials=" +
+		writeCredentials(t, server.URL+"/token") + "&endpoint=" + server.URL + "/v2/entries:write"
+
+	logger, closer, err := rklogger.NewZapLoggerWithCloser(rklogger.NewConfigWithOptions(rklogger.WithOutput(outputPath)))
+	assert.Nil(t, err)
+
+	logger.Info("ut-info")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.Len(t, server.requests, 1)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package cloudlogging
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// default host of metadata server, which could be overridden by GCE_METADATA_HOST
+	metadataHost = "169.254.169.254"
+	// file of namespace mounted into pods of kubernetes
+	namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// resource is the monitored resource of entries
+type resource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+}
+
+// metadataClient queries metadata server of GCE, which is available on GKE and Cloud Run as well
+type metadataClient struct {
+	host   string
+	client *http.Client
+}
+
+// Create metadata client with host of GCE_METADATA_HOST or default host
+func newMetadataClient() *metadataClient {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if len(host) == 0 {
+		host = metadataHost
+	}
+
+	return &metadataClient{
+		host:   host,
+		client: &http.Client{Timeout: 2 * time.Second},
+	}
+}
+
+// Get value of metadata path, like project/project-id
+func (client *metadataClient) get(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://"+client.host+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := client.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("metadata is not found, path:%s, status:%d", path, resp.StatusCode)
+	}
+
+	value, err := ioutil.ReadAll(resp.Body)
+	return strings.TrimSpace(string(value)), err
+}
+
+// Get value of metadata path, empty string is returned on failure
+func (client *metadataClient) getOrEmpty(ctx context.Context, path string) string {
+	value, _ := client.get(ctx, path)
+	return value
+}
+
+// Detect monitored resource of Cloud Run, GKE and GCE, global resource is returned elsewhere.
+// Environment of Cloud Run and GKE are checked before querying metadata server.
+func detectResource(ctx context.Context, client *metadataClient, projectID string) *resource {
+	global := &resource{
+		Type:   "global",
+		Labels: map[string]string{"project_id": projectID},
+	}
+
+	// metadata server is not available outside of Google Cloud
+	if _, err := client.get(ctx, "instance/id"); err != nil {
+		return global
+	}
+
+	if service := os.Getenv("K_SERVICE"); len(service) > 0 {
+		return &resource{
+			Type: "cloud_run_revision",
+			Labels: map[string]string{
+				"project_id":         projectID,
+				"service_name":       service,
+				"revision_name":      os.Getenv("K_REVISION"),
+				"configuration_name": os.Getenv("K_CONFIGURATION"),
+				"location":           lastSegment(client.getOrEmpty(ctx, "instance/region")),
+			},
+		}
+	}
+
+	if len(os.Getenv("KUBERNETES_SERVICE_HOST")) > 0 {
+		namespace := os.Getenv("NAMESPACE")
+		if len(namespace) == 0 {
+			content, _ := ioutil.ReadFile(namespaceFile)
+			namespace = strings.TrimSpace(string(content))
+		}
+
+		podName, _ := os.Hostname()
+		if name := os.Getenv("POD_NAME"); len(name) > 0 {
+			podName = name
+		}
+
+		return &resource{
+			Type: "k8s_container",
+			Labels: map[string]string{
+				"project_id":     projectID,
+				"location":       client.getOrEmpty(ctx, "instance/attributes/cluster-location"),
+				"cluster_name":   client.getOrEmpty(ctx, "instance/attributes/cluster-name"),
+				"namespace_name": namespace,
+				"pod_name":       podName,
+				"container_name": os.Getenv("CONTAINER_NAME"),
+			},
+		}
+	}
+
+	return &resource{
+		Type: "gce_instance",
+		Labels: map[string]string{
+			"project_id":  projectID,
+			"instance_id": client.getOrEmpty(ctx, "instance/id"),
+			"zone":        lastSegment(client.getOrEmpty(ctx, "instance/zone")),
+		},
+	}
+}
+
+// Get last segment of metadata value, like us-central1-a of projects/123/zones/us-central1-a
+func lastSegment(value string) string {
+	return value[strings.LastIndex(value, "/")+1:]
+}
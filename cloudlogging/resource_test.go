@@ -0,0 +1,119 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package cloudlogging
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Start metadata server with values keyed by metadata path
+func newMetadataServer(t *testing.T, values map[string]string) *metadataClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		value, ok := values[strings.TrimPrefix(r.URL.Path, "/computeMetadata/v1/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	t.Cleanup(server.Close)
+
+	client := newMetadataClient()
+	client.host = strings.TrimPrefix(server.URL, "http://")
+	return client
+}
+
+// Set environment variable and unset it on cleanup
+func setEnv(t *testing.T, key, value string) {
+	assert.Nil(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		os.Unsetenv(key)
+	})
+}
+
+var gceMetadata = map[string]string{
+	"project/project-id":                   "ut-project",
+	"instance/id":                          "123",
+	"instance/zone":                        "projects/1/zones/us-central1-a",
+	"instance/region":                      "projects/1/regions/us-central1",
+	"instance/attributes/cluster-name":     "ut-cluster",
+	"instance/attributes/cluster-location": "us-central1",
+}
+
+func TestDetectResource_WithGCE(t *testing.T) {
+	res := detectResource(context.Background(), newMetadataServer(t, gceMetadata), "ut-project")
+	assert.Equal(t, &resource{
+		Type: "gce_instance",
+		Labels: map[string]string{
+			"project_id":  "ut-project",
+			"instance_id": "123",
+			"zone":        "us-central1-a",
+		},
+	}, res)
+}
+
+func TestDetectResource_WithCloudRun(t *testing.T) {
+	setEnv(t, "K_SERVICE", "ut-service")
+	setEnv(t, "K_REVISION", "ut-service-001")
+	setEnv(t, "K_CONFIGURATION", "ut-service")
+
+	res := detectResource(context.Background(), newMetadataServer(t, gceMetadata), "ut-project")
+	assert.Equal(t, &resource{
+		Type: "cloud_run_revision",
+		Labels: map[string]string{
+			"project_id":         "ut-project",
+			"service_name":       "ut-service",
+			"revision_name":      "ut-service-001",
+			"configuration_name": "ut-service",
+			"location":           "us-central1",
+		},
+	}, res)
+}
+
+func TestDetectResource_WithGKE(t *testing.T) {
+	setEnv(t, "KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	setEnv(t, "NAMESPACE", "ut-namespace")
+	setEnv(t, "POD_NAME", "ut-pod")
+	setEnv(t, "CONTAINER_NAME", "ut-container")
+
+	res := detectResource(context.Background(), newMetadataServer(t, gceMetadata), "ut-project")
+	assert.Equal(t, &resource{
+		Type: "k8s_container",
+		Labels: map[string]string{
+			"project_id":     "ut-project",
+			"location":       "us-central1",
+			"cluster_name":   "ut-cluster",
+			"namespace_name": "ut-namespace",
+			"pod_name":       "ut-pod",
+			"container_name": "ut-container",
+		},
+	}, res)
+}
+
+// Resource is global outside of Google Cloud
+func TestDetectResource_WithoutMetadata(t *testing.T) {
+	res := detectResource(context.Background(), newMetadataServer(t, map[string]string{}), "ut-project")
+	assert.Equal(t, &resource{
+		Type:   "global",
+		Labels: map[string]string{"project_id": "ut-project"},
+	}, res)
+}
+
+func TestLastSegment(t *testing.T) {
+	assert.Equal(t, "us-central1-a", lastSegment("projects/1/zones/us-central1-a"))
+	assert.Equal(t, "us-central1", lastSegment("us-central1"))
+	assert.Equal(t, "", lastSegment(""))
+}
@@ -0,0 +1,467 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package cloudwatch registers cloudwatch scheme with rklogger.RegisterWriter, so that entries are sent to
+// AWS CloudWatch Logs by output paths like:
+//
+//	outputPaths:
+//	  - cloudwatch://?group=/app/api&stream=node-1&region=us-east-1&retention=30
+//
+// Import the package for side effects in order to enable the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/cloudwatch"
+//
+// Query parameters:
+//
+//	group:       log group, which is required
+//	stream:      log stream, hostname by default
+//	region:      region of CloudWatch Logs, resolved by environment variables and shared config by default
+//	endpoint:    endpoint of CloudWatch Logs, like endpoint of localstack in tests
+//	createGroup: creates log group if missing, true by default, log stream is always created if missing
+//	retention:   retention in days of created log group, never expire by default
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//
+// Credentials are resolved by default credential chain of AWS SDK. Writers could be created with NewWriter as well.
+//
+// Events are sent on expiry of linger or once a batch is full, batches are limited to 10000 events and 1MB as
+// PutLogEvents requires, events larger than 256KB are truncated. Throttled and failed requests are retried
+// with exponential backoff, sequence tokens are tracked for log streams which still require them.
+package cloudwatch
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheme is the scheme of output paths which are sent to CloudWatch Logs.
+const Scheme = "cloudwatch"
+
+const (
+	// limits of PutLogEvents
+	maxEvents     = 10000
+	maxBatchBytes = 1048576
+	eventOverhead = 26
+	maxEventBytes = 262144 - eventOverhead
+	maxSpan       = 24 * time.Hour
+
+	// size of timestamp which prefixes queued entries
+	timestampBytes = 8
+)
+
+func init() {
+	if err := rklogger.RegisterWriter(Scheme, newWriterWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// Option overrides settings of writer created with NewWriter.
+type Option func(*options)
+
+// options of writer
+type options struct {
+	group       string
+	stream      string
+	region      string
+	endpoint    string
+	createGroup bool
+	retention   int
+	client      cloudwatchlogsiface.CloudWatchLogsAPI
+	batch       batch.Config
+}
+
+// WithRegion overrides region of CloudWatch Logs.
+func WithRegion(region string) Option {
+	return func(opts *options) {
+		opts.region = region
+	}
+}
+
+// WithEndpoint overrides endpoint of CloudWatch Logs.
+func WithEndpoint(endpoint string) Option {
+	return func(opts *options) {
+		opts.endpoint = endpoint
+	}
+}
+
+// WithGroupCreation enables or disables creation of missing log group, it is enabled by default.
+func WithGroupCreation(enabled bool) Option {
+	return func(opts *options) {
+		opts.createGroup = enabled
+	}
+}
+
+// WithRetention sets retention in days of created log group.
+func WithRetention(days int) Option {
+	return func(opts *options) {
+		opts.retention = days
+	}
+}
+
+// WithClient replaces client of CloudWatch Logs, region and endpoint are ignored.
+func WithClient(client cloudwatchlogsiface.CloudWatchLogsAPI) Option {
+	return func(opts *options) {
+		opts.client = client
+	}
+}
+
+// WithBatchSize sets max number of events in a batch, which is capped by limit of PutLogEvents.
+func WithBatchSize(size int) Option {
+	return func(opts *options) {
+		opts.batch.Size = size
+	}
+}
+
+// WithLinger sets max time an event waits in a batch which is not full.
+func WithLinger(linger time.Duration) Option {
+	return func(opts *options) {
+		opts.batch.Linger = linger
+	}
+}
+
+// WithQueueSize sets max number of events waiting to be batched.
+func WithQueueSize(size int) Option {
+	return func(opts *options) {
+		opts.batch.QueueSize = size
+	}
+}
+
+// WithRetries sets max number of retries of failed batches and delay before the first retry.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(opts *options) {
+		opts.batch.Retries = retries
+		opts.batch.Backoff = backoff
+	}
+}
+
+// NewWriter creates Writer which sends entries to log stream of log group, stream would be hostname if empty.
+func NewWriter(group, stream string, opts ...Option) (*Writer, error) {
+	options := &options{
+		group:       group,
+		stream:      stream,
+		createGroup: true,
+		batch:       batch.DefaultConfig(),
+	}
+
+	for i := range opts {
+		opts[i](options)
+	}
+
+	return newWriter(options)
+}
+
+// Create writer with url of output path
+func newWriterWithURL(u url.URL) (zapcore.WriteSyncer, error) {
+	options, err := parseURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := newWriter(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Parse options from url
+func parseURL(u *url.URL) (*options, error) {
+	options := &options{
+		createGroup: true,
+		batch:       batch.DefaultConfig(),
+	}
+
+	query, err := options.batch.ParseQuery(u.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		switch key {
+		case "group":
+			options.group = value
+		case "stream":
+			options.stream = value
+		case "region":
+			options.region = value
+		case "endpoint":
+			options.endpoint = value
+		case "createGroup":
+			options.createGroup, err = strconv.ParseBool(value)
+		case "retention":
+			options.retention, err = strconv.Atoi(value)
+		default:
+			err = errors.New("unknown query")
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid query of cloudwatch url, key:%s", key)
+		}
+	}
+
+	return options, nil
+}
+
+// Writer implements zapcore.WriteSyncer which queues entries and sends them in batches,
+// it should be closed in order to send queued entries.
+type Writer struct {
+	*batch.Writer
+}
+
+// Create writer with options, batch limits are capped by limits of PutLogEvents
+func newWriter(options *options) (*Writer, error) {
+	if len(options.group) == 0 {
+		return nil, errors.New("log group is required for cloudwatch")
+	}
+
+	if len(options.stream) == 0 {
+		options.stream, _ = os.Hostname()
+	}
+
+	if options.client == nil {
+		// empty region and endpoint would override the ones resolved by AWS SDK
+		config := aws.Config{}
+		if len(options.region) > 0 {
+			config.Region = aws.String(options.region)
+		}
+		if len(options.endpoint) > 0 {
+			config.Endpoint = aws.String(options.endpoint)
+		}
+
+		sess, err := session.NewSessionWithOptions(session.Options{
+			Config:            config,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create aws session")
+		}
+
+		options.client = cloudwatchlogs.New(sess)
+	}
+
+	config := options.batch
+	if config.Size <= 0 || config.Size > maxEvents {
+		config.Size = maxEvents
+	}
+	// queued entries are prefixed with timestamp instead of overhead of events
+	if limit := maxBatchBytes - (eventOverhead-timestampBytes)*config.Size; config.Bytes <= 0 || config.Bytes > limit {
+		config.Bytes = limit
+	}
+
+	putter := &putter{
+		client:      options.client,
+		group:       options.group,
+		stream:      options.stream,
+		createGroup: options.createGroup,
+		retention:   options.retention,
+	}
+
+	return &Writer{
+		Writer: batch.NewWriter(config, putter.put),
+	}, nil
+}
+
+// Write implements zapcore.WriteSyncer, p is queued with current time and truncated if it exceeds limit of event
+func (writer *Writer) Write(p []byte) (int, error) {
+	if _, err := writer.Writer.Write(encodeEvent(time.Now(), p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Prefix message with timestamp in milliseconds
+func encodeEvent(now time.Time, p []byte) []byte {
+	msg := p
+	for len(msg) > 0 && (msg[len(msg)-1] == '\n' || msg[len(msg)-1] == '\r') {
+		msg = msg[:len(msg)-1]
+	}
+	if len(msg) > maxEventBytes {
+		// messages must be valid utf-8, runes split by truncation are removed
+		msg = []byte(strings.ToValidUTF8(string(msg[:maxEventBytes]), ""))
+	}
+
+	res := make([]byte, timestampBytes+len(msg))
+	binary.BigEndian.PutUint64(res, uint64(now.UnixNano()/int64(time.Millisecond)))
+	copy(res[timestampBytes:], msg)
+	return res
+}
+
+// Decode event queued by Write
+func decodeEvent(entry []byte) *cloudwatchlogs.InputLogEvent {
+	return &cloudwatchlogs.InputLogEvent{
+		Timestamp: aws.Int64(int64(binary.BigEndian.Uint64(entry))),
+		Message:   aws.String(string(entry[timestampBytes:])),
+	}
+}
+
+// putter sends batches with PutLogEvents, it is only used by the flushing goroutine of batch.Writer
+type putter struct {
+	client      cloudwatchlogsiface.CloudWatchLogsAPI
+	group       string
+	stream      string
+	createGroup bool
+	retention   int
+	created     bool
+	token       *string
+	// first entry of batch being sent and number of its entries which were sent,
+	// so that retries of the same batch skip requests which succeeded
+	current *[]byte
+	sent    int
+}
+
+// Send batch in chronological order, it is split into requests if events span more than 24 hours
+func (putter *putter) put(ctx context.Context, entries [][]byte) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if putter.current != &entries[0] {
+		putter.current, putter.sent = &entries[0], 0
+	}
+
+	if !putter.created {
+		if err := putter.createStream(ctx); err != nil {
+			return err
+		}
+		putter.created = true
+	}
+
+	events := make([]*cloudwatchlogs.InputLogEvent, 0, len(entries))
+	for i := range entries {
+		events = append(events, decodeEvent(entries[i]))
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return *events[i].Timestamp < *events[j].Timestamp
+	})
+
+	var rejected error
+	for putter.sent < len(events) {
+		end := putter.sent + 1
+		for end < len(events) && *events[end].Timestamp-*events[putter.sent].Timestamp < maxSpan.Milliseconds() {
+			end++
+		}
+
+		rejectedEvents, err := putter.putEvents(ctx, events[putter.sent:end])
+		if err != nil {
+			var permanent bool
+			if permanent, err = putter.classify(err); !permanent {
+				return err
+			}
+		}
+		rejected = multierr.Combine(rejected, rejectedEvents, err)
+
+		putter.sent = end
+	}
+
+	return batch.Permanent(rejected)
+}
+
+// Send events with PutLogEvents, request is sent again once sequence token is expected.
+// Events rejected by CloudWatch Logs are returned as the first error.
+func (putter *putter) putEvents(ctx context.Context, events []*cloudwatchlogs.InputLogEvent) (error, error) {
+	input := &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(putter.group),
+		LogStreamName: aws.String(putter.stream),
+		LogEvents:     events,
+		SequenceToken: putter.token,
+	}
+
+	output, err := putter.client.PutLogEventsWithContext(ctx, input)
+	if invalid, ok := err.(*cloudwatchlogs.InvalidSequenceTokenException); ok {
+		putter.token = invalid.ExpectedSequenceToken
+		input.SequenceToken = putter.token
+		output, err = putter.client.PutLogEventsWithContext(ctx, input)
+	}
+
+	if accepted, ok := err.(*cloudwatchlogs.DataAlreadyAcceptedException); ok {
+		putter.token = accepted.ExpectedSequenceToken
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	putter.token = output.NextSequenceToken
+	if info := output.RejectedLogEventsInfo; info != nil {
+		return errors.Errorf("log events are rejected, tooOld:%d, tooNew:%d, expired:%d",
+			aws.Int64Value(info.TooOldLogEventEndIndex),
+			aws.Int64Value(info.TooNewLogEventStartIndex),
+			aws.Int64Value(info.ExpiredLogEventEndIndex)), nil
+	}
+
+	return nil, nil
+}
+
+// Classify error of PutLogEvents, throttling and unavailability are retried, log stream is recreated if missing
+func (putter *putter) classify(err error) (bool, error) {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case cloudwatchlogs.ErrCodeResourceNotFoundException:
+			putter.created, putter.token = false, nil
+		case cloudwatchlogs.ErrCodeInvalidParameterException, "AccessDeniedException":
+			return true, errors.Wrap(err, "log events are rejected")
+		}
+	}
+
+	return false, errors.Wrapf(err, "failed to put log events, group:%s, stream:%s", putter.group, putter.stream)
+}
+
+// Create log group and log stream, existing ones are kept
+func (putter *putter) createStream(ctx context.Context) error {
+	if putter.createGroup {
+		_, err := putter.client.CreateLogGroupWithContext(ctx, &cloudwatchlogs.CreateLogGroupInput{
+			LogGroupName: aws.String(putter.group),
+		})
+		if err != nil && !isAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create log group, group:%s", putter.group)
+		}
+
+		if err == nil && putter.retention > 0 {
+			_, err = putter.client.PutRetentionPolicyWithContext(ctx, &cloudwatchlogs.PutRetentionPolicyInput{
+				LogGroupName:    aws.String(putter.group),
+				RetentionInDays: aws.Int64(int64(putter.retention)),
+			})
+			if err != nil {
+				return errors.Wrapf(err, "failed to put retention policy, group:%s", putter.group)
+			}
+		}
+	}
+
+	_, err := putter.client.CreateLogStreamWithContext(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(putter.group),
+		LogStreamName: aws.String(putter.stream),
+	})
+	if err != nil && !isAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create log stream, group:%s, stream:%s", putter.group, putter.stream)
+	}
+
+	return nil
+}
+
+// Check whether resource already exists
+func isAlreadyExists(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == cloudwatchlogs.ErrCodeResourceAlreadyExistsException
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package cloudwatch
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClient records calls of CloudWatch Logs and returns errors of PutLogEvents in order
+type fakeClient struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	groups    []string
+	streams   []string
+	retention []int64
+	puts      []*cloudwatchlogs.PutLogEventsInput
+	putErrs   []error
+	groupErr  error
+	streamErr error
+	mutex     sync.Mutex
+}
+
+// CreateLogGroupWithContext implements cloudwatchlogsiface.CloudWatchLogsAPI
+func (client *fakeClient) CreateLogGroupWithContext(ctx aws.Context, input *cloudwatchlogs.CreateLogGroupInput, opts ...request.Option) (*cloudwatchlogs.CreateLogGroupOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.groups = append(client.groups, *input.LogGroupName)
+	return &cloudwatchlogs.CreateLogGroupOutput{}, client.groupErr
+}
+
+// PutRetentionPolicyWithContext implements cloudwatchlogsiface.CloudWatchLogsAPI
+func (client *fakeClient) PutRetentionPolicyWithContext(ctx aws.Context, input *cloudwatchlogs.PutRetentionPolicyInput, opts ...request.Option) (*cloudwatchlogs.PutRetentionPolicyOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.retention = append(client.retention, *input.RetentionInDays)
+	return &cloudwatchlogs.PutRetentionPolicyOutput{}, nil
+}
+
+// CreateLogStreamWithContext implements cloudwatchlogsiface.CloudWatchLogsAPI
+func (client *fakeClient) CreateLogStreamWithContext(ctx aws.Context, input *cloudwatchlogs.CreateLogStreamInput, opts ...request.Option) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.streams = append(client.streams, *input.LogStreamName)
+	return &cloudwatchlogs.CreateLogStreamOutput{}, client.streamErr
+}
+
+// PutLogEventsWithContext implements cloudwatchlogsiface.CloudWatchLogsAPI
+func (client *fakeClient) PutLogEventsWithContext(ctx aws.Context, input *cloudwatchlogs.PutLogEventsInput, opts ...request.Option) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	copied := *input
+	client.puts = append(client.puts, &copied)
+	if len(client.putErrs) > 0 {
+		err := client.putErrs[0]
+		client.putErrs = client.putErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String("next")}, nil
+}
+
+// Collect messages of PutLogEvents requests
+func (client *fakeClient) messages() [][]string {
+	res := make([][]string, 0)
+	for _, put := range client.puts {
+		messages := make([]string, 0)
+		for _, event := range put.LogEvents {
+			messages = append(messages, *event.Message)
+		}
+		res = append(res, messages)
+	}
+
+	return res
+}
+
+func TestParseURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("cloudwatch://?group=/app/api&stream=node-1&region=us-east-1&endpoint=http://localhost:4566&createGroup=false&retention=30&linger=5s")
+	options, err := parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "/app/api", options.group)
+	assert.Equal(t, "node-1", options.stream)
+	assert.Equal(t, "us-east-1", options.region)
+	assert.Equal(t, "http://localhost:4566", options.endpoint)
+	assert.False(t, options.createGroup)
+	assert.Equal(t, 30, options.retention)
+	assert.Equal(t, 5*time.Second, options.batch.Linger)
+}
+
+func TestParseURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"cloudwatch://?group=app&createGroup=x",
+		"cloudwatch://?group=app&retention=x",
+		"cloudwatch://?group=app&unknown=x",
+		"cloudwatch://?group=app&overflow=x",
+	} {
+		u, _ := url.Parse(raw)
+		_, err := parseURL(u)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestNewWriter_WithoutGroup(t *testing.T) {
+	writer, err := NewWriter("", "")
+	assert.NotNil(t, err)
+	assert.Nil(t, writer)
+}
+
+func TestNewWriter_WithLimits(t *testing.T) {
+	writer, err := NewWriter("app", "", WithClient(&fakeClient{}), WithBatchSize(20000))
+	assert.Nil(t, err)
+	defer writer.Close()
+}
+
+func TestEncodeEvent(t *testing.T) {
+	now := time.Unix(1577836800, int64(123*time.Millisecond))
+	event := decodeEvent(encodeEvent(now, []byte("hello\n")))
+	assert.Equal(t, int64(1577836800123), *event.Timestamp)
+	assert.Equal(t, "hello", *event.Message)
+
+	// With message exceeds limit
+	event = decodeEvent(encodeEvent(now, []byte(strings.Repeat("a", maxEventBytes-1)+"é")))
+	assert.Equal(t, maxEventBytes-1, len(*event.Message))
+}
+
+func TestWriter_HappyCase(t *testing.T) {
+	client := &fakeClient{}
+	writer, err := NewWriter("app", "node", WithClient(client), WithRetention(7), WithLinger(time.Hour))
+	assert.Nil(t, err)
+
+	writer.Write([]byte("a\n"))
+	writer.Write([]byte("b\n"))
+	assert.Nil(t, writer.Sync())
+	writer.Write([]byte("c\n"))
+	assert.Nil(t, writer.Close())
+
+	assert.Equal(t, []string{"app"}, client.groups)
+	assert.Equal(t, []int64{7}, client.retention)
+	assert.Equal(t, []string{"node"}, client.streams)
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, client.messages())
+	assert.Nil(t, client.puts[0].SequenceToken)
+	assert.Equal(t, "next", *client.puts[1].SequenceToken)
+}
+
+func TestWriter_WithExistingGroup(t *testing.T) {
+	exists := awserr.New(cloudwatchlogs.ErrCodeResourceAlreadyExistsException, "exists", nil)
+	client := &fakeClient{groupErr: exists, streamErr: exists}
+	writer, err := NewWriter("app", "node", WithClient(client), WithRetention(7), WithLinger(time.Hour))
+	assert.Nil(t, err)
+
+	writer.Write([]byte("a"))
+	assert.Nil(t, writer.Close())
+
+	// retention of existing group is kept
+	assert.Empty(t, client.retention)
+	assert.Equal(t, [][]string{{"a"}}, client.messages())
+}
+
+func TestWriter_WithoutGroupCreation(t *testing.T) {
+	client := &fakeClient{}
+	writer, err := NewWriter("app", "node", WithClient(client), WithGroupCreation(false), WithLinger(time.Hour))
+	assert.Nil(t, err)
+
+	writer.Write([]byte("a"))
+	assert.Nil(t, writer.Close())
+
+	assert.Empty(t, client.groups)
+	assert.Equal(t, []string{"node"}, client.streams)
+}
+
+func TestWriter_WithSequenceToken(t *testing.T) {
+	client := &fakeClient{putErrs: []error{&cloudwatchlogs.InvalidSequenceTokenException{
+		ExpectedSequenceToken: aws.String("expected"),
+	}}}
+	writer, err := NewWriter("app", "node", WithClient(client), WithLinger(time.Hour), WithRetries(0, 0))
+	assert.Nil(t, err)
+
+	writer.Write([]byte("a"))
+	assert.Nil(t, writer.Close())
+
+	assert.Len(t, client.puts, 2)
+	assert.Equal(t, "expected", *client.puts[1].SequenceToken)
+}
+
+func TestWriter_WithDataAlreadyAccepted(t *testing.T) {
+	client := &fakeClient{putErrs: []error{&cloudwatchlogs.DataAlreadyAcceptedException{
+		ExpectedSequenceToken: aws.String("expected"),
+	}}}
+	writer, err := NewWriter("app", "node", WithClient(client), WithLinger(time.Hour), WithRetries(0, 0))
+	assert.Nil(t, err)
+
+	writer.Write([]byte("a"))
+	assert.Nil(t, writer.Sync())
+	writer.Write([]byte("b"))
+	assert.Nil(t, writer.Close())
+
+	assert.Len(t, client.puts, 2)
+	assert.Equal(t, "expected", *client.puts[1].SequenceToken)
+}
+
+func TestWriter_WithThrottling(t *testing.T) {
+	client := &fakeClient{putErrs: []error{
+		awserr.New("ThrottlingException", "rate exceeded", nil),
+		awserr.New(cloudwatchlogs.ErrCodeServiceUnavailableException, "unavailable", nil),
+	}}
+	writer, err := NewWriter("app", "node", WithClient(client), WithLinger(time.Hour), WithRetries(2, time.Millisecond))
+	assert.Nil(t, err)
+
+	writer.Write([]byte("a"))
+	assert.Nil(t, writer.Close())
+	assert.Len(t, client.puts, 3)
+}
+
+func TestWriter_WithMissingStream(t *testing.T) {
+	client := &fakeClient{putErrs: []error{
+		awserr.New(cloudwatchlogs.ErrCodeResourceNotFoundException, "not found", nil),
+	}}
+	writer, err := NewWriter("app", "node", WithClient(client), WithLinger(time.Hour), WithRetries(1, time.Millisecond))
+	assert.Nil(t, err)
+
+	writer.Write([]byte("a"))
+	assert.Nil(t, writer.Close())
+
+	// stream is created again before retry
+	assert.Equal(t, []string{"node", "node"}, client.streams)
+	assert.Len(t, client.puts, 2)
+}
+
+func TestWriter_WithInvalidParameter(t *testing.T) {
+	client := &fakeClient{putErrs: []error{
+		awserr.New(cloudwatchlogs.ErrCodeInvalidParameterException, "invalid", nil),
+	}}
+	writer, err := NewWriter("app", "node", WithClient(client), WithLinger(time.Hour), WithRetries(3, time.Millisecond))
+	assert.Nil(t, err)
+
+	writer.Write([]byte("a"))
+	assert.NotNil(t, writer.Sync())
+	assert.Len(t, client.puts, 1)
+	assert.Nil(t, writer.Close())
+}
+
+func TestPutter_WithSpan(t *testing.T) {
+	client := &fakeClient{putErrs: []error{nil, awserr.New("ThrottlingException", "rate exceeded", nil)}}
+	putter := &putter{client: client, group: "app", stream: "node", created: true}
+
+	now := time.Now()
+	entries := [][]byte{
+		encodeEvent(now.Add(-25*time.Hour), []byte("a")),
+		encodeEvent(now, []byte("c")),
+		encodeEvent(now.Add(-26*time.Hour), []byte("b")),
+	}
+
+	// events are sorted and split by span, the second request is throttled
+	assert.NotNil(t, putter.put(context.Background(), entries))
+	// retry of the same batch skips requests which succeeded
+	assert.Nil(t, putter.put(context.Background(), entries))
+	assert.Equal(t, [][]string{{"b", "a"}, {"c"}, {"c"}}, client.messages())
+}
+
+func TestRegister(t *testing.T) {
+	sink, closeFunc, err := zap.Open("cloudwatch://?group=app&region=us-east-1")
+	assert.Nil(t, err)
+	assert.NotNil(t, sink)
+	closeFunc()
+
+	// With missing group
+	_, _, err = zap.Open("cloudwatch://?region=us-east-1")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Command rklogger-audit verifies hash chain and signatures of audit files written by audit outputs, like:
+//
+//	rklogger-audit -key-env AUDIT_SIGN_KEY -sign-every 1000 audit-2021-01-01.log audit.log
+//
+// Files are verified in order, rotated files should be given before the current file. It exits with status 1 if
+// entries are modified, removed or inserted, or if signatures are missing while key is provided.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"io"
+	"os"
+)
+
+func main() {
+	key := flag.String("key", "", "key of signatures, signatures are not verified if neither key nor key-env is provided")
+	keyEnv := flag.String("key-env", "", "environment variable of key of signatures")
+	signEvery := flag.Int("sign-every", rklogger.DefaultAuditSignEvery, "signEvery of audit output")
+	sealed := flag.Bool("sealed", false, "fail if the last entry is not a signature entry")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-key key | -key-env name] [-sign-every n] [-sealed] file ...\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if len(*keyEnv) > 0 {
+		*key = os.Getenv(*keyEnv)
+	}
+
+	if err := run(*key, *signEvery, *sealed, flag.Args(), os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Verify audit files in order and print summary to out
+func run(key string, signEvery int, sealed bool, files []string, out io.Writer) error {
+	if len(files) == 0 {
+		return errors.New("no audit file is given")
+	}
+
+	report, err := rklogger.VerifyAuditFiles(key, signEvery, files...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "entries:%d signatures:%d sealed:%t lastHash:%s\n",
+		report.Entries, report.Signatures, report.Sealed, report.LastHash)
+
+	if sealed && !report.Sealed {
+		return errors.New("the last audit entry is not a signature entry, entries after the last signature could be truncated")
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Create logger with audit output which writes to file of temp dir
+func writeEntries(t *testing.T, key string) string {
+	dir, _ := ioutil.TempDir("", "rklogger-audit")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	filePath := path.Join(dir, "audit.log")
+
+	logger, closer, err := rklogger.NewZapLoggerWithCloser(rklogger.NewConfigWithOptions(
+		rklogger.WithJSONEncoding(),
+		rklogger.WithOutput(),
+		rklogger.WithAuditOutput(filePath, rklogger.AuditConfig{SignKey: key})))
+	assert.Nil(t, err)
+	logger.Info("first")
+	logger.Info("second")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	return filePath
+}
+
+func TestRun(t *testing.T) {
+	filePath := writeEntries(t, "ut-key")
+
+	out := &bytes.Buffer{}
+	assert.Nil(t, run("ut-key", 0, true, []string{filePath}, out))
+	assert.True(t, strings.HasPrefix(out.String(), "entries:3 signatures:1 sealed:true lastHash:"))
+
+	// Without key
+	out.Reset()
+	assert.Nil(t, run("", 0, false, []string{filePath}, out))
+	assert.True(t, strings.HasPrefix(out.String(), "entries:3 signatures:0 sealed:false lastHash:"))
+}
+
+func TestRun_WithError(t *testing.T) {
+	filePath := writeEntries(t, "")
+
+	// Without files
+	assert.NotNil(t, run("", 0, false, nil, &bytes.Buffer{}))
+
+	// With missing file
+	assert.NotNil(t, run("", 0, false, []string{"/non-exist/audit.log"}, &bytes.Buffer{}))
+
+	// Without signatures
+	assert.NotNil(t, run("ut-key", 0, true, []string{filePath}, &bytes.Buffer{}))
+
+	// With modified entry
+	content, _ := ioutil.ReadFile(filePath)
+	assert.Nil(t, ioutil.WriteFile(filePath, bytes.Replace(content, []byte("first"), []byte("forged"), 1), 0600))
+	assert.NotNil(t, run("", 0, false, []string{filePath}, &bytes.Buffer{}))
+}
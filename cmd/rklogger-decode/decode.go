@@ -0,0 +1,403 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger/logpb"
+	"io"
+	"math"
+	"sort"
+)
+
+// decoder decodes a value from reader
+type decoder func(r *bufio.Reader) (interface{}, error)
+
+// pair is a key value pair of object
+type pair struct {
+	key   string
+	value interface{}
+}
+
+// object is a decoded map which keeps order of keys, so that entries are printed the way they were encoded
+type object []pair
+
+// MarshalJSON implements json.Marshaler
+func (obj object) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i := range obj {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(obj[i].key)
+		value, err := json.Marshal(obj[i].value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Key of object, keys other than strings are formatted
+func toKey(value interface{}) string {
+	if key, ok := value.(string); ok {
+		return key
+	}
+	return fmt.Sprint(value)
+}
+
+// Float which could be encoded as json, NaN and infinities are strings like json encoding of zap
+func toFloat(value float64) interface{} {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return value
+	}
+}
+
+// Read n bytes, buffer grows with data read so that corrupted length does not allocate at once
+func readN(r *bufio.Reader, n uint64) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if _, err := io.CopyN(buf, r, int64(n)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Read big endian unsigned integer with size of 1, 2, 4 or 8 bytes
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:size]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b[:])), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b[:])), nil
+	default:
+		return binary.BigEndian.Uint64(b[:]), nil
+	}
+}
+
+// EOF in the middle of value is unexpected
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Decode MessagePack value
+func decodeMsgpack(r *bufio.Reader) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return uint64(b), nil
+	case b <= 0x8f:
+		return decodeMsgpackMap(r, uint64(b&0x0f))
+	case b <= 0x9f:
+		return decodeMsgpackArray(r, uint64(b&0x0f))
+	case b <= 0xbf:
+		raw, err := readN(r, uint64(b&0x1f))
+		return string(raw), err
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readUint(r, 1<<(b-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readN(r, n)
+	case 0xca:
+		bits, err := readUint(r, 4)
+		return toFloat(float64(math.Float32frombits(uint32(bits)))), err
+	case 0xcb:
+		bits, err := readUint(r, 8)
+		return toFloat(math.Float64frombits(bits)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return readUint(r, 1<<(b-0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		u, err := readUint(r, size)
+		// sign extension of integers with size of bytes
+		shift := uint(64 - 8*size)
+		return int64(u<<shift) >> shift, err
+	case 0xd9, 0xda, 0xdb:
+		n, err := readUint(r, 1<<(b-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := readN(r, n)
+		return string(raw), err
+	case 0xdc, 0xdd:
+		n, err := readUint(r, 2<<(b-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackArray(r, n)
+	case 0xde, 0xdf:
+		n, err := readUint(r, 2<<(b-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgpackMap(r, n)
+	default:
+		return nil, errors.Errorf("unsupported msgpack type, type:0x%02x", b)
+	}
+}
+
+// Decode n elements of MessagePack array
+func decodeMsgpackArray(r *bufio.Reader, n uint64) (interface{}, error) {
+	res := make([]interface{}, 0)
+	for i := uint64(0); i < n; i++ {
+		value, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		res = append(res, value)
+	}
+	return res, nil
+}
+
+// Decode n pairs of MessagePack map
+func decodeMsgpackMap(r *bufio.Reader, n uint64) (interface{}, error) {
+	res := make(object, 0)
+	for i := uint64(0); i < n; i++ {
+		key, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		value, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		res = append(res, pair{key: toKey(key), value: value})
+	}
+	return res, nil
+}
+
+// Decode CBOR data item, tags are skipped and indefinite lengths are not supported
+func decodeCBOR(r *bufio.Reader) (interface{}, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	major, info := b>>5, b&0x1f
+
+	// simple values and floats
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			bits, err := readUint(r, 2)
+			return toFloat(halfToFloat(uint16(bits))), err
+		case 26:
+			bits, err := readUint(r, 4)
+			return toFloat(float64(math.Float32frombits(uint32(bits)))), err
+		case 27:
+			bits, err := readUint(r, 8)
+			return toFloat(math.Float64frombits(bits)), err
+		default:
+			return nil, errors.Errorf("unsupported cbor simple value, value:%d", info)
+		}
+	}
+
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		if arg, err = readUint(r, 1<<(info-24)); err != nil {
+			return nil, err
+		}
+	case info == 31:
+		return nil, errors.New("indefinite length of cbor is not supported")
+	default:
+		return nil, errors.Errorf("invalid cbor additional information, value:%d", info)
+	}
+
+	switch major {
+	case 0:
+		return arg, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor negative integer overflows int64")
+		}
+		return -1 - int64(arg), nil
+	case 2:
+		return readN(r, arg)
+	case 3:
+		raw, err := readN(r, arg)
+		return string(raw), err
+	case 4:
+		res := make([]interface{}, 0)
+		for i := uint64(0); i < arg; i++ {
+			value, err := decodeCBOR(r)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			res = append(res, value)
+		}
+		return res, nil
+	case 5:
+		res := make(object, 0)
+		for i := uint64(0); i < arg; i++ {
+			key, err := decodeCBOR(r)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			value, err := decodeCBOR(r)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			res = append(res, pair{key: toKey(key), value: value})
+		}
+		return res, nil
+	default:
+		// tag is skipped, the tagged data item is decoded
+		value, err := decodeCBOR(r)
+		return value, unexpectedEOF(err)
+	}
+}
+
+// Convert half precision float to float64
+func halfToFloat(bits uint16) float64 {
+	exp := int(bits>>10) & 0x1f
+	frac := float64(bits & 0x3ff)
+
+	var value float64
+	switch exp {
+	case 0:
+		value = math.Ldexp(frac, -24)
+	case 31:
+		if frac == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(frac+1024, exp-25)
+	}
+
+	if bits&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
+
+// Decode length-prefixed LogEntry of protobuf encoding, keys are names of fields of logentry.proto and empty
+// fields are omitted
+func decodeProtobuf(r *bufio.Reader) (interface{}, error) {
+	entry, err := logpb.ReadEntry(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := object{{key: "time_unix_nano", value: entry.TimeUnixNano}}
+	for _, p := range []pair{
+		{key: "level", value: entry.Level},
+		{key: "logger_name", value: entry.LoggerName},
+		{key: "caller", value: entry.Caller},
+		{key: "function", value: entry.Function},
+		{key: "message", value: entry.Message},
+		{key: "stacktrace", value: entry.Stacktrace},
+	} {
+		if len(p.value.(string)) > 0 {
+			res = append(res, p)
+		}
+	}
+
+	if len(entry.Fields) > 0 {
+		res = append(res, pair{key: "fields", value: fromProtobufFields(entry.Fields)})
+	}
+	return res, nil
+}
+
+// Convert fields of LogEntry to object sorted by keys
+func fromProtobufFields(fields map[string]*logpb.Value) object {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make(object, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, pair{key: k, value: fromProtobufValue(fields[k])})
+	}
+	return res
+}
+
+// Convert value of field, times and durations are kept as nanoseconds
+func fromProtobufValue(value *logpb.Value) interface{} {
+	switch v := value.GetKind().(type) {
+	case *logpb.Value_StringValue:
+		return v.StringValue
+	case *logpb.Value_BoolValue:
+		return v.BoolValue
+	case *logpb.Value_IntValue:
+		return v.IntValue
+	case *logpb.Value_UintValue:
+		return v.UintValue
+	case *logpb.Value_DoubleValue:
+		return toFloat(v.DoubleValue)
+	case *logpb.Value_BytesValue:
+		return v.BytesValue
+	case *logpb.Value_TimeUnixNano:
+		return v.TimeUnixNano
+	case *logpb.Value_DurationNano:
+		return v.DurationNano
+	case *logpb.Value_ArrayValue:
+		res := make([]interface{}, 0, len(v.ArrayValue.GetValues()))
+		for _, elem := range v.ArrayValue.GetValues() {
+			res = append(res, fromProtobufValue(elem))
+		}
+		return res
+	case *logpb.Value_ObjectValue:
+		return fromProtobufFields(v.ObjectValue.GetFields())
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/logpb"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// Encode entry with encoder, decode it with decoder and marshal it as json
+func roundTrip(t *testing.T, constructor rklogger.EncoderConstructor, decode decoder) string {
+	enc, err := constructor(zapcore.EncoderConfig{
+		MessageKey:  "msg",
+		LevelKey:    "level",
+		TimeKey:     "ts",
+		EncodeLevel: zapcore.LowercaseLevelEncoder,
+		EncodeTime:  zapcore.EpochNanosTimeEncoder,
+	})
+	assert.Nil(t, err)
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Unix(1, 0),
+		Message: "ut-message",
+	}, []zapcore.Field{
+		zap.Int("neg", -70000),
+		zap.Uint64("max", math.MaxUint64),
+		zap.Float32("f32", 1.5),
+		zap.Float64("nan", math.NaN()),
+		zap.Bool("ok", false),
+		zap.Binary("raw", []byte("ut")),
+		zap.Ints("ids", []int{1, 2}),
+		zap.Any("meta", map[string]interface{}{"k": nil}),
+	})
+	assert.Nil(t, err)
+
+	value, err := decode(bufio.NewReader(bytes.NewReader(buf.Bytes())))
+	assert.Nil(t, err)
+
+	res, err := json.Marshal(value)
+	assert.Nil(t, err)
+	return string(res)
+}
+
+func TestDecodeMsgpack(t *testing.T) {
+	assert.Equal(t, `{"ts":1000000000,"level":"info","msg":"ut-message","neg":-70000,"max":18446744073709551615,`+
+		`"f32":1.5,"nan":"NaN","ok":false,"raw":"dXQ=","ids":[1,2],"meta":{"k":null}}`,
+		roundTrip(t, rklogger.NewMsgpackEncoder, decodeMsgpack))
+
+	// With type to int64 sign extension
+	value, err := decodeMsgpack(bufio.NewReader(bytes.NewReader([]byte{0xd1, 0xfc, 0x18})))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1000), value)
+
+	// With truncated value
+	_, err = decodeMsgpack(bufio.NewReader(bytes.NewReader([]byte{0x82, 0xa1, 'a'})))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// With ext which is not supported
+	_, err = decodeMsgpack(bufio.NewReader(bytes.NewReader([]byte{0xd4, 0x01, 0x00})))
+	assert.NotNil(t, err)
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	assert.Equal(t, `{"ts":1000000000,"level":"info","msg":"ut-message","neg":-70000,"max":18446744073709551615,`+
+		`"f32":1.5,"nan":"NaN","ok":false,"raw":"dXQ=","ids":[1,2],"meta":{"k":null}}`,
+		roundTrip(t, rklogger.NewCBOREncoder, decodeCBOR))
+
+	// With half precision float and tag
+	value, err := decodeCBOR(bufio.NewReader(bytes.NewReader([]byte{0x82, 0xf9, 0x3e, 0x00, 0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0})))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{1.5, uint64(1363896240)}, value)
+
+	// With truncated value
+	_, err = decodeCBOR(bufio.NewReader(bytes.NewReader([]byte{0x62, 'a'})))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// With indefinite length which is not supported
+	_, err = decodeCBOR(bufio.NewReader(bytes.NewReader([]byte{0x9f, 0xff})))
+	assert.NotNil(t, err)
+}
+
+func TestHalfToFloat(t *testing.T) {
+	assert.Equal(t, 0.0, halfToFloat(0x0000))
+	assert.Equal(t, 1.0, halfToFloat(0x3c00))
+	assert.Equal(t, -4.0, halfToFloat(0xc400))
+	assert.Equal(t, 65504.0, halfToFloat(0x7bff))
+	assert.Equal(t, 5.960464477539063e-8, halfToFloat(0x0001))
+	assert.True(t, math.IsInf(halfToFloat(0x7c00), 1))
+	assert.True(t, math.IsNaN(halfToFloat(0x7e00)))
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	enc, err := logpb.NewEncoder(zapcore.EncoderConfig{})
+	assert.Nil(t, err)
+
+	buf, err := enc.EncodeEntry(zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Unix(1, 0),
+		Message: "ut-message",
+	}, []zapcore.Field{
+		zap.Int("neg", -70000),
+		zap.Float64("nan", math.NaN()),
+		zap.Binary("raw", []byte("ut")),
+		zap.Duration("elapsed", time.Second),
+		zap.Ints("ids", []int{1, 2}),
+		zap.Any("meta", map[string]interface{}{"k": nil}),
+	})
+	assert.Nil(t, err)
+
+	value, err := decodeProtobuf(bufio.NewReader(bytes.NewReader(buf.Bytes())))
+	assert.Nil(t, err)
+
+	res, err := json.Marshal(value)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"time_unix_nano":1000000000,"level":"info","message":"ut-message","fields":{"elapsed":1000000000,`+
+		`"ids":[1,2],"meta":{"k":null},"nan":"NaN","neg":-70000,"raw":"dXQ="}}`, string(res))
+
+	// With truncated value
+	_, err = decodeProtobuf(bufio.NewReader(bytes.NewReader([]byte{0x05, 0x08})))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
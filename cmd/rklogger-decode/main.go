@@ -0,0 +1,95 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Command rklogger-decode prints entries encoded with msgpack, cbor or protobuf encoding as json lines, like:
+//
+//	rklogger-decode -format msgpack app.log
+//	kafka-console-consumer --topic app-logs ... | rklogger-decode -format cbor
+//
+// Entries are read from files of arguments, or stdin if no file is given.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger/logpb"
+	"io"
+	"os"
+)
+
+func main() {
+	format := flag.String("format", "msgpack", "encoding of entries, msgpack, cbor or protobuf")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-format msgpack|cbor|protobuf] [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*format, flag.Args(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Decode entries of files, or stdin if no file is given, and print them as json lines to out
+func run(format string, files []string, stdin io.Reader, out io.Writer) error {
+	var decode decoder
+	switch format {
+	case "msgpack":
+		decode = decodeMsgpack
+	case "cbor":
+		decode = decodeCBOR
+	case logpb.Encoding:
+		decode = decodeProtobuf
+	default:
+		return errors.Errorf("format is not supported, format:%s", format)
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	if len(files) == 0 {
+		return decodeAll(decode, stdin, w)
+	}
+
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		err = decodeAll(decode, f, w)
+		f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode file, file:%s", name)
+		}
+	}
+
+	return nil
+}
+
+// Decode entries until EOF and print each of them as a json line
+func decodeAll(decode decoder, in io.Reader, w *bufio.Writer) error {
+	r := bufio.NewReader(in)
+	for i := 0; ; i++ {
+		value, err := decode(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode entry, index:%d", i)
+		}
+
+		line, err := json.Marshal(value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to print entry, index:%d", i)
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+}
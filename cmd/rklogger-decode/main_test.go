@@ -0,0 +1,70 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// Create logger with encoding which writes to file of temp dir
+func writeEntries(t *testing.T, encoding string) string {
+	dir, _ := ioutil.TempDir("", "rklogger-decode")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	filePath := path.Join(dir, "ut.log")
+
+	raw := []byte(fmt.Sprintf(`---
+level: info
+encoding: %s
+encoderConfig:
+  messageKey: msg
+outputPaths: ["%s"]
+`, encoding, filePath))
+
+	logger, _, err := rklogger.NewZapLoggerWithBytes(raw, rklogger.YAML)
+	assert.Nil(t, err)
+	logger.Info("first")
+	logger.Info("second")
+	logger.Sync()
+
+	return filePath
+}
+
+func TestRun(t *testing.T) {
+	for _, format := range []string{"msgpack", "cbor"} {
+		filePath := writeEntries(t, format)
+
+		out := &bytes.Buffer{}
+		assert.Nil(t, run(format, []string{filePath}, nil, out))
+		assert.Equal(t, "{\"msg\":\"first\"}\n{\"msg\":\"second\"}\n", out.String())
+
+		// With stdin
+		content, _ := ioutil.ReadFile(filePath)
+		out.Reset()
+		assert.Nil(t, run(format, nil, bytes.NewReader(content), out))
+		assert.Equal(t, "{\"msg\":\"first\"}\n{\"msg\":\"second\"}\n", out.String())
+	}
+}
+
+func TestRun_WithError(t *testing.T) {
+	// With unknown format
+	assert.NotNil(t, run("xml", nil, bytes.NewReader(nil), &bytes.Buffer{}))
+
+	// With missing file
+	assert.NotNil(t, run("msgpack", []string{"/non-exist/ut.log"}, nil, &bytes.Buffer{}))
+
+	// With json entries, entries decoded before error are printed
+	out := &bytes.Buffer{}
+	assert.NotNil(t, run("msgpack", nil, bytes.NewReader([]byte{0x80, 0xc1}), out))
+	assert.Equal(t, "{}\n", out.String())
+}
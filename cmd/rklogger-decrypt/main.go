@@ -0,0 +1,79 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Command rklogger-decrypt prints entries of encrypted outputs, like:
+//
+//	rklogger-decrypt -key-file /run/secrets/log-key app.log
+//	cat app.log | rklogger-decrypt -key-env LOG_KEY
+//
+// Entries are read from files of arguments, or stdin if no file is given.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"io"
+	"os"
+)
+
+func main() {
+	keyFile := flag.String("key-file", "", "file of base64 encoded key")
+	keyEnv := flag.String("key-env", "", "environment variable of base64 encoded key")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -key-file file | -key-env name [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	config := &rklogger.EncryptionConfig{KeyFile: *keyFile, KeyEnv: *keyEnv}
+	if err := run(config, flag.Args(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Decrypt entries of files, or stdin if no file is given, and print them to out
+func run(config *rklogger.EncryptionConfig, files []string, stdin io.Reader, out io.Writer) error {
+	key, err := rklogger.LoadEncryptionKey(config)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	if len(files) == 0 {
+		return decryptAll(key, stdin, w)
+	}
+
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+
+		err = decryptAll(key, f, w)
+		f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to decrypt file, file:%s", name)
+		}
+	}
+
+	return nil
+}
+
+// Decrypt records until EOF, entries decrypted before error are printed
+func decryptAll(key []byte, in io.Reader, w io.Writer) error {
+	reader, err := rklogger.NewDecryptReader(in, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, reader)
+	return err
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+var utKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+// Create logger with encrypted output which writes to file of temp dir
+func writeEntries(t *testing.T) string {
+	dir, _ := ioutil.TempDir("", "rklogger-decrypt")
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	filePath := path.Join(dir, "app.log")
+
+	config := rklogger.NewConfigWithOptions(
+		rklogger.WithJSONEncoding(),
+		rklogger.WithOutput(),
+		rklogger.WithEncryptedOutput(filePath, rklogger.EncryptionConfig{Key: utKey}))
+	config.Zap.EncoderConfig.TimeKey, config.Zap.EncoderConfig.LevelKey, config.Zap.EncoderConfig.CallerKey = "", "", ""
+
+	logger, closer, err := rklogger.NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	logger.Info("first")
+	logger.Info("second")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	return filePath
+}
+
+func TestRun(t *testing.T) {
+	filePath := writeEntries(t)
+	os.Setenv("UT_LOG_KEY", utKey)
+	defer os.Unsetenv("UT_LOG_KEY")
+	config := &rklogger.EncryptionConfig{KeyEnv: "UT_LOG_KEY"}
+
+	out := &bytes.Buffer{}
+	assert.Nil(t, run(config, []string{filePath}, nil, out))
+	assert.Equal(t, "{\"msg\":\"first\"}\n{\"msg\":\"second\"}\n", out.String())
+
+	// With stdin
+	content, _ := ioutil.ReadFile(filePath)
+	out.Reset()
+	assert.Nil(t, run(config, nil, bytes.NewReader(content), out))
+	assert.Equal(t, "{\"msg\":\"first\"}\n{\"msg\":\"second\"}\n", out.String())
+}
+
+func TestRun_WithError(t *testing.T) {
+	filePath := writeEntries(t)
+
+	// Without key
+	assert.NotNil(t, run(&rklogger.EncryptionConfig{}, []string{filePath}, nil, &bytes.Buffer{}))
+
+	// With missing file
+	config := &rklogger.EncryptionConfig{Key: utKey}
+	assert.NotNil(t, run(config, []string{"/non-exist/app.log"}, nil, &bytes.Buffer{}))
+
+	// With truncated file, entries decrypted before error are printed
+	content, _ := ioutil.ReadFile(filePath)
+	out := &bytes.Buffer{}
+	assert.NotNil(t, run(config, nil, bytes.NewReader(content[:len(content)-1]), out))
+	assert.Equal(t, "{\"msg\":\"first\"}\n", out.String())
+}
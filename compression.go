@@ -0,0 +1,127 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Algorithms and modes which could be used by CompressionConfig
+const (
+	// CompressionZstd compresses files with zstd into files with .zst extension.
+	CompressionZstd = "zstd"
+	// CompressionGzip compresses files with gzip into files with .gz extension.
+	CompressionGzip = "gzip"
+	// CompressionModeRotate compresses files once they are rotated, before hooks of rotation section.
+	CompressionModeRotate = "rotate"
+	// CompressionModeInline writes compressed entries to the current file, which are flushed while syncing logger.
+	CompressionModeInline = "inline"
+)
+
+// extensions of compressed files keyed by algorithms
+var compressionExts = map[string]string{
+	CompressionZstd: ".zst",
+	CompressionGzip: ".gz",
+}
+
+// CompressionConfig compresses files of rotation section with zstd or gzip, either once they are rotated or inline.
+// Entries compressed inline are flushed while syncing logger and could be read with zstd -dc or zcat, though the
+// frame of current file is incomplete until it is closed. Files compressed inline are not compressed again.
+//
+// Example config file in YAML:
+//
+//	rotation:
+//	  schedule: daily
+//	  compression:
+//	    algorithm: zstd
+//	    level: 19
+//	    mode: rotate
+type CompressionConfig struct {
+	// Algorithm is zstd or gzip.
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	// Level is compression level of algorithm, like 1 to 22 of zstd or 1 to 9 of gzip, default level if zero.
+	Level int `json:"level,omitempty" yaml:"level,omitempty"`
+	// Mode is rotate or inline, rotate by default. Inline compression is not supported by lumberjack driver.
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+// Validate algorithm, level and mode of config
+func (config *CompressionConfig) validate() error {
+	if _, err := newCompressWriter(config.Algorithm, config.Level, ioutil.Discard); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(config.Mode) {
+	case "", CompressionModeRotate, CompressionModeInline:
+		return nil
+	default:
+		return errors.Errorf("invalid mode of compression, mode:%s", config.Mode)
+	}
+}
+
+// Whether files are compressed inline
+func (config *CompressionConfig) inline() bool {
+	return config != nil && strings.ToLower(config.Mode) == CompressionModeInline
+}
+
+// Extension of files compressed with algorithm of config
+func (config *CompressionConfig) ext() string {
+	return compressionExts[strings.ToLower(config.Algorithm)]
+}
+
+// compressWriter compresses bytes written to it, Flush() writes compressed bytes which are pending
+type compressWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// Create writer which compresses bytes with algorithm and level into w
+func newCompressWriter(algorithm string, level int, w io.Writer) (compressWriter, error) {
+	switch strings.ToLower(algorithm) {
+	case CompressionZstd:
+		zstdLevel := zstd.SpeedDefault
+		if level != 0 {
+			zstdLevel = zstd.EncoderLevelFromZstd(level)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstdLevel))
+	case CompressionGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	default:
+		return nil, errors.Errorf("invalid algorithm of compression, algorithm:%s", algorithm)
+	}
+}
+
+// Trim extension of compressed file, like app-2020-09-01.log.zst
+func trimCompressionExt(path string) string {
+	ext := filepath.Ext(path)
+	for _, compressionExt := range compressionExts {
+		if ext == compressionExt {
+			return strings.TrimSuffix(path, ext)
+		}
+	}
+
+	return path
+}
+
+// Glob files matching pattern and their compressed files
+func globWithCompression(pattern string) []string {
+	res, _ := filepath.Glob(pattern)
+	for _, ext := range compressionExts {
+		matches, _ := filepath.Glob(pattern + ext)
+		res = append(res, matches...)
+	}
+
+	return res
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Decompress file compressed with zstd, frame of file which is not closed is incomplete
+func readZstdFileContent(t *testing.T, filePath string) string {
+	file, err := os.Open(filePath)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	reader, err := zstd.NewReader(file)
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(reader)
+	if err != io.ErrUnexpectedEOF {
+		assert.Nil(t, err)
+	}
+	return string(content)
+}
+
+func TestCompressionConfig_Validate(t *testing.T) {
+	assert.Nil(t, (&CompressionConfig{Algorithm: CompressionZstd, Level: 19}).validate())
+	assert.Nil(t, (&CompressionConfig{Algorithm: "GZIP", Level: 9, Mode: CompressionModeInline}).validate())
+
+	assert.NotNil(t, (&CompressionConfig{Algorithm: "lz4"}).validate())
+	assert.NotNil(t, (&CompressionConfig{Algorithm: CompressionGzip, Level: 10}).validate())
+	assert.NotNil(t, (&CompressionConfig{Algorithm: CompressionZstd, Mode: "ut-mode"}).validate())
+}
+
+func TestTimedWriter_WithInlineCompression(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+	config := RotationConfig{
+		Schedule:    ScheduleDaily,
+		Compression: &CompressionConfig{Algorithm: CompressionZstd, Mode: CompressionModeInline},
+	}
+
+	now := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	writer, setNow := newTestTimedWriter(t, filePath, config, now)
+
+	// flushed while syncing
+	writer.Write([]byte("ut-first\n"))
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, "ut-first\n", readZstdFileContent(t, filePath+".zst"))
+	assert.NoFileExists(t, filePath)
+
+	// rotated file keeps extension of algorithm
+	setNow(now.Add(24 * time.Hour))
+	writer.Write([]byte("ut-second\n"))
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, []string{"app-2020-09-01.log.zst", "app.log.zst"}, listFileNames(t, dir))
+	assert.Equal(t, "ut-first\n", readZstdFileContent(t, path.Join(dir, "app-2020-09-01.log.zst")))
+
+	// entries appended after reopening are compressed into a new frame
+	writer, _ = newTestTimedWriter(t, filePath, config, now.Add(24*time.Hour))
+	writer.Write([]byte("ut-third\n"))
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, "ut-second\nut-third\n", readZstdFileContent(t, filePath+".zst"))
+}
+
+func TestTimedWriter_RemoveCompressedBackups(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+	for _, name := range []string{"app-2020-08-30.log.gz", "app-2020-08-31.log.zst", "app-2020-09-01.log"} {
+		assert.Nil(t, ioutil.WriteFile(path.Join(dir, name), []byte("ut"), 0644))
+	}
+
+	now := time.Date(2020, 9, 2, 10, 0, 0, 0, time.UTC)
+	writer, _ := newTestTimedWriter(t, filePath, RotationConfig{Schedule: ScheduleDaily, MaxBackups: 2}, now)
+	writer.Write([]byte("ut\n"))
+	writer.removeBackups(now)
+
+	assert.Equal(t, []string{"app-2020-08-31.log.zst", "app-2020-09-01.log", "app.log"}, listFileNames(t, dir))
+}
+
+func TestNewRotator_WithCompression(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+
+	// compressed once rotated
+	rotator, err := newRotator(filePath, &RotationConfig{
+		Schedule:    ScheduleDaily,
+		Compression: &CompressionConfig{Algorithm: CompressionZstd, Level: 19},
+	}, nil)
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	rotator.Write([]byte("ut-content\n"))
+	assert.Nil(t, rotator.Rotate())
+	assert.Eventually(t, func() bool {
+		matches, _ := filepath.Glob(path.Join(dir, "app-*.log.zst"))
+		return len(matches) == 1 && readZstdFileContent(t, matches[0]) == "ut-content\n"
+	}, time.Second, 10*time.Millisecond)
+
+	// inline compression is not supported by lumberjack
+	rotator, err = newRotator(filePath, &RotationConfig{
+		Driver:      RotationDriverLumberjack,
+		Compression: &CompressionConfig{Algorithm: CompressionGzip, Mode: CompressionModeInline},
+	}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, rotator)
+
+	// invalid algorithm
+	rotator, err = newRotator(filePath, &RotationConfig{
+		Schedule:    ScheduleDaily,
+		Compression: &CompressionConfig{Algorithm: "lz4"},
+	}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, rotator)
+
+	// lumberjack leaves compression to hook
+	rotator, err = newRotator(filePath, &RotationConfig{
+		Compress:    true,
+		Compression: &CompressionConfig{Algorithm: CompressionZstd},
+	}, nil)
+	assert.Nil(t, err)
+	assert.False(t, rotator.(*notifyingLumberjack).Compress)
+	rotator.Close()
+}
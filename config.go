@@ -0,0 +1,633 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"time"
+)
+
+// Config is a combined config of zap and lumberjack with explicit sections.
+//
+// Example config file in YAML:
+//
+//	zap:
+//	  level: info
+//	  encoding: console
+//	  outputPaths: ["stdout", "logs/rk-logger.log"]
+//	lumberjack:
+//	  maxsize: 1024
+//	  maxage: 7
+//
+// Config files without zap section are treated as legacy config files
+// in which zap config and lumberjack config are placed at the root together.
+type Config struct {
+	// Name is name of logger in level registry of Loader, DefaultLoggerName if not provided.
+	// It is ignored by logger map, whose loggers are registered with their names.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Preset is name of preset which config file starts from, like gke, see PresetNames() for all of the presets.
+	// Sections in config file override settings of preset, zap section is optional in that case.
+	Preset string `json:"preset" yaml:"preset"`
+	// Zap is the zap config which is required.
+	Zap *zap.Config `json:"zap" yaml:"zap"`
+	// Lumberjack is the rotation config applied to every file output path.
+	// Logger would be built with zap.Config.Build() if not provided.
+	Lumberjack *lumberjack.Logger `json:"lumberjack" yaml:"lumberjack"`
+	// Rotation rotates every file output path with driver, it takes precedence over lumberjack, see RotationConfig.
+	Rotation *RotationConfig `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+	// Archive uploads rotated files of file outputs to object storage, see ArchiveConfig.
+	Archive *ArchiveConfig `json:"archive,omitempty" yaml:"archive,omitempty"`
+	// Retention caps total size of file outputs and their rotated files, see RetentionConfig.
+	Retention *RetentionConfig `json:"retention,omitempty" yaml:"retention,omitempty"`
+	// Permissions sets permission and owner of files and directories created for file outputs, see PermissionsConfig.
+	Permissions *PermissionsConfig `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	// SyncPolicy controls how often file outputs are synced to disk, see SyncPolicyConfig.
+	SyncPolicy *SyncPolicyConfig `json:"syncPolicy,omitempty" yaml:"syncPolicy,omitempty"`
+	// Shared makes file outputs safe to be written by multiple processes, see SharedFileConfig.
+	Shared *SharedFileConfig `json:"shared,omitempty" yaml:"shared,omitempty"`
+	// Buffer buffers entries of file and network outputs in memory and writes them together, see BufferConfig.
+	Buffer *BufferConfig `json:"buffer,omitempty" yaml:"buffer,omitempty"`
+	// OnWriteError is stderr or drop, entries are written to stderr or dropped while writing to outputs fails,
+	// and failed outputs are retried periodically, see OutputConfig.OnWriteError.
+	OnWriteError string `json:"onWriteError,omitempty" yaml:"onWriteError,omitempty"`
+	// Encryption is key of outputs flagged encrypted, see EncryptionConfig.
+	Encryption *EncryptionConfig `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	// Async writes entries of outputs in background with bounded queue, see AsyncConfig.
+	Async *AsyncConfig `json:"async,omitempty" yaml:"async,omitempty"`
+	// LevelSampling samples entries of every level with its own rate, see LevelSamplingConfig.
+	LevelSampling *LevelSamplingConfig `json:"levelSampling,omitempty" yaml:"levelSampling,omitempty"`
+	// RateLimit throttles floods of entries and logs summaries of suppressed entries, see RateLimitConfig.
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	// Dedup collapses identical consecutive entries into one entry carrying number of repeats, see DedupConfig.
+	Dedup *DedupConfig `json:"dedup,omitempty" yaml:"dedup,omitempty"`
+	// Routes route entries of levels to outputs, like warn and above to error log file, see RouteConfig.
+	Routes []*RouteConfig `json:"routes,omitempty" yaml:"routes,omitempty"`
+	// Filters drop entries with rules before they are sampled or encoded, see FilterConfig.
+	Filters []*FilterConfig `json:"filters,omitempty" yaml:"filters,omitempty"`
+	// Redaction masks, hashes or drops sensitive values before entries are encoded, see RedactionConfig.
+	Redaction *RedactionConfig `json:"redaction,omitempty" yaml:"redaction,omitempty"`
+	// Pseudonymization replaces identifier fields with salted hashes, see PseudonymizationConfig.
+	Pseudonymization *PseudonymizationConfig `json:"pseudonymization,omitempty" yaml:"pseudonymization,omitempty"`
+	// Outputs are output paths which carry their own rotation settings,
+	// they are used along with output paths in zap config.
+	Outputs []*OutputConfig `json:"outputs" yaml:"outputs"`
+	// Levels override level of zap config by logger names given with zap.Logger.Named(),
+	// the most specific name wins, like mycompany/db for logger named mycompany/db.sql.
+	Levels map[string]zapcore.Level `json:"levels" yaml:"levels"`
+	// Limits caps sizes of entries, see LimitsConfig.
+	Limits *LimitsConfig `json:"limits,omitempty" yaml:"limits,omitempty"`
+	// Encoder renames keys and replaces encoders of encoderConfig in zap config, see EncoderOverrides.
+	Encoder *EncoderOverrides `json:"encoder,omitempty" yaml:"encoder,omitempty"`
+	// TimeZone is the location of timestamps rendered by time encoder, like UTC or Asia/Shanghai,
+	// timestamps are in local time of host if not provided.
+	TimeZone string `json:"timeZone,omitempty" yaml:"timeZone,omitempty"`
+	// LevelNames are names of levels used by encoders keyed by names of levels, like warn: WARNING or trace: FINEST,
+	// names are parsed as levels in this config as well. Names are upper-cased by capital level encoders.
+	LevelNames map[string]string `json:"levelNames,omitempty" yaml:"levelNames,omitempty"`
+	// CSV declares columns of csv and tsv encodings, see CSVConfig.
+	CSV *CSVConfig `json:"csv,omitempty" yaml:"csv,omitempty"`
+	// Console themes console encoding, see ConsoleConfig.
+	Console *ConsoleConfig `json:"console,omitempty" yaml:"console,omitempty"`
+	// GRPC is logger of gRPC internals installed by package grpclog, see GRPCConfig.
+	GRPC *GRPCConfig `json:"grpc,omitempty" yaml:"grpc,omitempty"`
+	// Extensions are user defined sections which are ignored by rk-logger.
+	Extensions map[string]interface{} `json:"extensions" yaml:"extensions"`
+}
+
+// MarshalJSON marshals Config, zap config is marshalled as ZapConfigWrap
+// since encoders in zap.Config could not be marshalled.
+func (config *Config) MarshalJSON() ([]byte, error) {
+	type innerConfig struct {
+		Name             string                   `json:"name,omitempty"`
+		Preset           string                   `json:"preset,omitempty"`
+		Zap              *ZapConfigWrap           `json:"zap"`
+		Lumberjack       *lumberjack.Logger       `json:"lumberjack"`
+		Rotation         *RotationConfig          `json:"rotation,omitempty"`
+		Archive          *ArchiveConfig           `json:"archive,omitempty"`
+		Retention        *RetentionConfig         `json:"retention,omitempty"`
+		Permissions      *PermissionsConfig       `json:"permissions,omitempty"`
+		SyncPolicy       *SyncPolicyConfig        `json:"syncPolicy,omitempty"`
+		Shared           *SharedFileConfig        `json:"shared,omitempty"`
+		Buffer           *BufferConfig            `json:"buffer,omitempty"`
+		OnWriteError     string                   `json:"onWriteError,omitempty"`
+		Encryption       *EncryptionConfig        `json:"encryption,omitempty"`
+		Async            *AsyncConfig             `json:"async,omitempty"`
+		LevelSampling    *LevelSamplingConfig     `json:"levelSampling,omitempty"`
+		RateLimit        *RateLimitConfig         `json:"rateLimit,omitempty"`
+		Dedup            *DedupConfig             `json:"dedup,omitempty"`
+		Routes           []*RouteConfig           `json:"routes,omitempty"`
+		Filters          []*FilterConfig          `json:"filters,omitempty"`
+		Redaction        *RedactionConfig         `json:"redaction,omitempty"`
+		Pseudonymization *PseudonymizationConfig  `json:"pseudonymization,omitempty"`
+		Outputs          []*OutputConfig          `json:"outputs"`
+		Levels           map[string]zapcore.Level `json:"levels"`
+		LevelNames       map[string]string        `json:"levelNames,omitempty"`
+		Limits           *LimitsConfig            `json:"limits,omitempty"`
+		Encoder          *EncoderOverrides        `json:"encoder,omitempty"`
+		TimeZone         string                   `json:"timeZone,omitempty"`
+		CSV              *CSVConfig               `json:"csv,omitempty"`
+		Console          *ConsoleConfig           `json:"console,omitempty"`
+		GRPC             *GRPCConfig              `json:"grpc,omitempty"`
+		Extensions       map[string]interface{}   `json:"extensions"`
+	}
+
+	inner := &innerConfig{
+		Name:             config.Name,
+		Preset:           config.Preset,
+		Lumberjack:       config.Lumberjack,
+		Rotation:         config.Rotation,
+		Archive:          config.Archive,
+		Retention:        config.Retention,
+		Permissions:      config.Permissions,
+		SyncPolicy:       config.SyncPolicy,
+		Shared:           config.Shared,
+		Buffer:           config.Buffer,
+		OnWriteError:     config.OnWriteError,
+		Encryption:       config.Encryption,
+		Async:            config.Async,
+		LevelSampling:    config.LevelSampling,
+		RateLimit:        config.RateLimit,
+		Dedup:            config.Dedup,
+		Routes:           config.Routes,
+		Filters:          config.Filters,
+		Redaction:        config.Redaction,
+		Pseudonymization: config.Pseudonymization,
+		Outputs:          config.Outputs,
+		Levels:           config.Levels,
+		LevelNames:       config.LevelNames,
+		Limits:           config.Limits,
+		Encoder:          config.Encoder,
+		TimeZone:         config.TimeZone,
+		CSV:              config.CSV,
+		Console:          config.Console,
+		GRPC:             config.GRPC,
+		Extensions:       config.Extensions,
+	}
+
+	if config.Zap != nil {
+		inner.Zap = TransformToZapConfigWrap(config.Zap)
+	}
+
+	return json.Marshal(inner)
+}
+
+// OutputConfig is an output path with its own rotation settings.
+//
+// Example config file in YAML:
+//
+//	outputs:
+//	  - path: logs/audit.log
+//	    lumberjack:
+//	      maxsize: 100
+//	      maxage: 365
+//	      compress: false
+//	  # entries are written to fallback while tcp output is failing
+//	  - path: tcp://relay.example.com:5170
+//	    fallback:
+//	      path: /var/log/app.log
+//	    healthCheckInterval: 10s
+type OutputConfig struct {
+	// Path is a file path, stdout or stderr.
+	Path string `json:"path" yaml:"path"`
+	// Lumberjack replaces rotation settings in combined config for this output path.
+	// File would not be rotated if neither of them is provided.
+	Lumberjack *lumberjack.Logger `json:"lumberjack" yaml:"lumberjack"`
+	// Rotation replaces rotation with driver in combined config for this output path, see RotationConfig.
+	// It takes precedence over Lumberjack.
+	Rotation *RotationConfig `json:"rotation,omitempty" yaml:"rotation,omitempty"`
+	// Archive replaces archive in combined config for this output path, see ArchiveConfig.
+	Archive *ArchiveConfig `json:"archive,omitempty" yaml:"archive,omitempty"`
+	// Permissions replaces permissions in combined config for this output path, see PermissionsConfig.
+	Permissions *PermissionsConfig `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	// SyncPolicy replaces sync policy in combined config for this output path, see SyncPolicyConfig.
+	SyncPolicy *SyncPolicyConfig `json:"syncPolicy,omitempty" yaml:"syncPolicy,omitempty"`
+	// Shared replaces shared in combined config for this output path, see SharedFileConfig.
+	Shared *SharedFileConfig `json:"shared,omitempty" yaml:"shared,omitempty"`
+	// Buffer replaces buffer in combined config for this output path, see BufferConfig.
+	Buffer *BufferConfig `json:"buffer,omitempty" yaml:"buffer,omitempty"`
+	// Fallback is the output which entries are written to while writing to Path fails,
+	// entries are written to Path again once it recovers, see ListFailoverStats().
+	Fallback *OutputConfig `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	// OnWriteError is stderr or drop, entries are written to stderr or dropped while writing to Path fails,
+	// and Path is retried every HealthCheckInterval. It is ignored if Fallback is provided, see ListFailoverStats().
+	OnWriteError string `json:"onWriteError,omitempty" yaml:"onWriteError,omitempty"`
+	// HealthCheckInterval is the interval of checking Path while it fails or syncing it while it works,
+	// like 30s, DefaultHealthCheckInterval if not provided. It is only used with Fallback or OnWriteError.
+	HealthCheckInterval string `json:"healthCheckInterval,omitempty" yaml:"healthCheckInterval,omitempty"`
+	// AllowFields are keys of the only fields written to Path, all fields are written if not provided.
+	// Fields are matched by top level keys, including fields added with With() and initial fields.
+	AllowFields []string `json:"allowFields,omitempty" yaml:"allowFields,omitempty"`
+	// DenyFields are keys of fields never written to Path, like user_email, which take precedence over AllowFields.
+	DenyFields []string `json:"denyFields,omitempty" yaml:"denyFields,omitempty"`
+	// Audit makes Path a tamper-evident hash chain of entries, see AuditConfig.
+	Audit *AuditConfig `json:"audit,omitempty" yaml:"audit,omitempty"`
+	// Encrypted encrypts entries written to Path with key of Encryption, see EncryptionConfig.
+	Encrypted bool `json:"encrypted,omitempty" yaml:"encrypted,omitempty"`
+	// Encryption replaces encryption in combined config for this output path, see EncryptionConfig.
+	Encryption *EncryptionConfig `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+}
+
+// NewConfig creates combined config from zap config and lumberjack config.
+// lumberjack.Logger could be nil, if not provided,
+// then, we will use default write sync
+func NewConfig(zapConfig *zap.Config, lumber *lumberjack.Logger) *Config {
+	return &Config{
+		Zap:        zapConfig,
+		Lumberjack: lumber,
+	}
+}
+
+// ToZapConfig returns a copy of zap config, nil if zap config is missing.
+// Note that level of the copy is shared with the original one.
+func (config *Config) ToZapConfig() *zap.Config {
+	if config.Zap == nil {
+		return nil
+	}
+
+	res := *config.Zap
+	return &res
+}
+
+// ToLumberjackConfig returns a new lumberjack logger with the same rotation settings,
+// nil if lumberjack config is missing.
+// The new logger never shares opened file with the original one.
+func (config *Config) ToLumberjackConfig() *lumberjack.Logger {
+	if config.Lumberjack == nil {
+		return nil
+	}
+
+	return &lumberjack.Logger{
+		Filename:   config.Lumberjack.Filename,
+		MaxSize:    config.Lumberjack.MaxSize,
+		MaxAge:     config.Lumberjack.MaxAge,
+		MaxBackups: config.Lumberjack.MaxBackups,
+		LocalTime:  config.Lumberjack.LocalTime,
+		Compress:   config.Lumberjack.Compress,
+	}
+}
+
+// NewConfigWithBytes parses combined config with byte array from content of config file
+// Both combined config and legacy config are supported
+func NewConfigWithBytes(raw []byte, fileType FileType) (*Config, error) {
+	return defaultLoader.NewConfigWithBytes(raw, fileType)
+}
+
+// NewConfigWithBytes parses combined config with byte array from content of config file and options of Loader
+func (loader *Loader) NewConfigWithBytes(raw []byte, fileType FileType) (*Config, error) {
+	if raw == nil {
+		return nil, errors.New("input byte array is nil")
+	}
+
+	if len(raw) == 0 {
+		return nil, errors.New("byte array is empty")
+	}
+
+	raw = loader.preprocess(raw)
+
+	if loader.strict {
+		if err := validateKnownKeys(raw, fileType); err != nil {
+			return nil, err
+		}
+	}
+
+	return parseConfig(raw, fileType)
+}
+
+// Parse combined config or legacy config from preprocessed content of config file
+func parseConfig(raw []byte, fileType FileType) (*Config, error) {
+	// sections are only recognized if zap section or preset exists
+	probe := &struct {
+		Preset string      `json:"preset" yaml:"preset"`
+		Zap    interface{} `json:"zap" yaml:"zap"`
+	}{}
+	if err := unmarshalWithFileType(raw, fileType, probe); err != nil {
+		return nil, err
+	}
+
+	// levels like trace are not known by zapcore.Level, they are patched before unmarshalling
+	patch, err := newLevelPatch(raw, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(probe.Preset) > 0 {
+		config, err := NewPresetConfig(probe.Preset)
+		if err != nil {
+			return nil, err
+		}
+
+		// keys in config file override settings of preset
+		if err := unmarshalWithLevelPatch(raw, fileType, patch, config); err != nil {
+			return nil, err
+		}
+
+		return config.applyLevelPatch(patch), nil
+	}
+
+	if probe.Zap != nil {
+		config := &Config{}
+		if err := unmarshalWithLevelPatch(raw, fileType, patch, config); err != nil {
+			return nil, err
+		}
+
+		return config.applyLevelPatch(patch), nil
+	}
+
+	// legacy config, parse the same content into both zap config and lumberjack config
+	config := &Config{
+		Zap:        &zap.Config{},
+		Lumberjack: &lumberjack.Logger{},
+	}
+
+	if err := unmarshalWithLevelPatch(raw, fileType, patch, config.Zap); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalWithLevelPatch(raw, fileType, patch, config.Lumberjack); err != nil {
+		return nil, err
+	}
+
+	return config.applyLevelPatch(patch), nil
+}
+
+// Set levels parsed by patch if exists
+func (config *Config) applyLevelPatch(patch *levelPatch) *Config {
+	if patch == nil {
+		return config
+	}
+
+	if len(patch.levels) > 0 && config.Levels == nil {
+		config.Levels = make(map[string]zapcore.Level)
+	}
+	patch.apply(config.Zap, config.Levels)
+
+	return config
+}
+
+// NewConfigWithConfPath parses combined config with config file path
+func NewConfigWithConfPath(filePath string, fileType FileType) (*Config, error) {
+	return defaultLoader.NewConfigWithConfPath(filePath, fileType)
+}
+
+// NewConfigWithConfPath parses combined config with config file path and options of Loader
+func (loader *Loader) NewConfigWithConfPath(filePath string, fileType FileType) (*Config, error) {
+	raw, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.NewConfigWithBytes(raw, fileType)
+}
+
+// NewZapLoggerWithConfig inits zap logger with combined config
+func NewZapLoggerWithConfig(config *Config, opts ...zap.Option) (*zap.Logger, error) {
+	return defaultLoader.NewZapLoggerWithConfig(config, opts...)
+}
+
+// NewZapLoggerWithConfig inits zap logger with combined config and options of Loader
+// Level and config of logger are registered with Config.Name in level registry of Loader.
+func (loader *Loader) NewZapLoggerWithConfig(config *Config, opts ...zap.Option) (*zap.Logger, error) {
+	logger, err := loader.buildZapLoggerWithConfig(config, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	loader.levels.RegisterConfig(config.loggerName(), config)
+	return logger, nil
+}
+
+// Name of logger in level registry, DefaultLoggerName if name is not provided
+func (config *Config) loggerName() string {
+	if len(config.Name) > 0 {
+		return config.Name
+	}
+
+	return DefaultLoggerName
+}
+
+// Build zap logger with combined config, level is not registered
+func (loader *Loader) buildZapLoggerWithConfig(config *Config, opts ...zap.Option) (*zap.Logger, error) {
+	if config == nil {
+		return nil, errors.New("config is nil")
+	}
+
+	// zap.Config.Build() only knows encoder config, so that encoder configured by sections is built by ourselves
+	if (len(config.Outputs) == 0 && len(config.Levels) == 0 && config.CSV == nil && config.Console == nil &&
+		len(config.LevelNames) == 0 && config.Limits == nil && config.Rotation == nil &&
+		config.Retention == nil && config.Archive == nil && config.Permissions == nil &&
+		config.SyncPolicy == nil && config.Shared == nil && len(config.OnWriteError) == 0 &&
+		config.Buffer == nil && config.Async == nil && config.LevelSampling == nil &&
+		config.RateLimit == nil && config.Dedup == nil && len(config.Routes) == 0 &&
+		len(config.Filters) == 0 && config.Redaction == nil &&
+		config.Pseudonymization == nil) || config.Zap == nil {
+		zapConfig, err := config.overriddenZapConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		return loader.buildZapLoggerWithConf(zapConfig, config.Lumberjack, opts...)
+	}
+
+	outputs, errOutputs := config.toOutputConfigs()
+	return loader.buildZapLoggerWithOutputs(config, outputs, errOutputs, opts...)
+}
+
+// Create encoder of zap config, csv and tsv encodings are configured by csv section and console encoding is themed
+// by console section, levels are encoded with level names
+func (config *Config) newEncoder() (zapcore.Encoder, error) {
+	encoderConfig, err := config.encoderConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	zapConfig := *config.Zap
+	zapConfig.EncoderConfig = encoderConfig
+	encoding := zapConfig.Encoding
+	isConsole := config.Console != nil && (encoding == ConsoleEncoding || len(encoding) == 0)
+
+	levelEncoder := zapConfig.EncoderConfig.EncodeLevel
+	if isConsole {
+		// levels are colorized by console encoder
+		levelEncoder = plainLevelEncoder(levelEncoder)
+	}
+
+	levelEncoder, err = newLevelNameEncoder(levelEncoder, config.LevelNames)
+	if err != nil {
+		return nil, err
+	}
+	zapConfig.EncoderConfig.EncodeLevel = levelEncoder
+
+	if isConsole {
+		consoleConfig := *config.Console
+		if len(consoleConfig.TimeFormat) > 0 {
+			// time format of console section is rendered in time zone as well
+			zapConfig.EncoderConfig.EncodeTime = consoleConfig.timeEncoder()
+			consoleConfig.TimeFormat = ""
+			if err := config.applyTimeZone(&zapConfig.EncoderConfig); err != nil {
+				return nil, err
+			}
+		}
+
+		encoder, err := NewConsoleEncoderWithConfig(consoleConfig)(zapConfig.EncoderConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create encoder, encoding:%s", ConsoleEncoding)
+		}
+		return encoder, nil
+	}
+
+	if config.CSV == nil || (encoding != CSVEncoding && encoding != TSVEncoding) {
+		return generateEncoder(&zapConfig)
+	}
+
+	csvConfig := *config.CSV
+	if len(csvConfig.Delimiter) == 0 && encoding == TSVEncoding {
+		csvConfig.Delimiter = "\t"
+	}
+
+	encoder, err := NewCSVEncoderWithConfig(csvConfig)(zapConfig.EncoderConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create encoder, encoding:%s", encoding)
+	}
+
+	return encoder, nil
+}
+
+// Encoder config of zap config with overrides of encoder section and time zone
+func (config *Config) encoderConfig() (zapcore.EncoderConfig, error) {
+	encoderConfig := config.Zap.EncoderConfig
+	if config.Encoder != nil {
+		var err error
+		if encoderConfig, err = config.Encoder.apply(encoderConfig); err != nil {
+			return encoderConfig, errors.Wrap(err, "failed to override encoder config")
+		}
+	}
+
+	if err := config.applyTimeZone(&encoderConfig); err != nil {
+		return encoderConfig, err
+	}
+
+	return encoderConfig, nil
+}
+
+// Wrap time encoder of encoder config with time zone if provided
+func (config *Config) applyTimeZone(encoderConfig *zapcore.EncoderConfig) error {
+	if len(config.TimeZone) == 0 || encoderConfig.EncodeTime == nil {
+		return nil
+	}
+
+	location, err := time.LoadLocation(config.TimeZone)
+	if err != nil {
+		return errors.Wrapf(err, "invalid time zone, timeZone:%s", config.TimeZone)
+	}
+
+	encodeTime := encoderConfig.EncodeTime
+	encoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		encodeTime(t.In(location), enc)
+	}
+
+	return nil
+}
+
+// Copy of zap config with overrides of encoder section and time zone, zap config itself is returned without them
+func (config *Config) overriddenZapConfig() (*zap.Config, error) {
+	if config.Zap == nil || (config.Encoder == nil && len(config.TimeZone) == 0) {
+		return config.Zap, nil
+	}
+
+	encoderConfig, err := config.encoderConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	res := *config.Zap
+	res.EncoderConfig = encoderConfig
+	return &res, nil
+}
+
+// Collect outputs and error outputs of combined config
+// Output paths in zap config use rotation settings in combined config
+func (config *Config) toOutputConfigs() ([]*OutputConfig, []*OutputConfig) {
+	outputs := config.collectOutputs(config.Zap.OutputPaths, config.Outputs)
+	return outputs, config.withRotation(newOutputConfigs(config.Zap.ErrorOutputPaths, config.Lumberjack))
+}
+
+// Collect output paths with rotation settings of combined config and outputs which inherit them
+func (config *Config) collectOutputs(paths []string, outputs []*OutputConfig) []*OutputConfig {
+	res := config.withRotation(newOutputConfigs(paths, config.Lumberjack))
+	for i := range outputs {
+		output := config.inheritRotation(*outputs[i])
+
+		if output.Fallback != nil {
+			fallback := config.inheritRotation(*output.Fallback)
+			output.Fallback = &fallback
+		}
+
+		res = append(res, &output)
+	}
+
+	return res
+}
+
+// Attach rotation, archive, permissions, sync policy, shared, buffer and write error policy of combined config to outputs
+func (config *Config) withRotation(outputs []*OutputConfig) []*OutputConfig {
+	for i := range outputs {
+		outputs[i].Rotation = config.Rotation
+		outputs[i].Archive = config.Archive
+		outputs[i].Permissions = config.Permissions
+		outputs[i].SyncPolicy = config.SyncPolicy
+		outputs[i].Shared = config.Shared
+		outputs[i].Buffer = config.Buffer
+		outputs[i].OnWriteError = config.OnWriteError
+	}
+
+	return outputs
+}
+
+// Fill rotation settings, archive, permissions, sync policy, shared, buffer, write error policy and encryption of
+// combined config into output without its own ones
+func (config *Config) inheritRotation(output OutputConfig) OutputConfig {
+	if output.Lumberjack == nil && output.Rotation == nil {
+		output.Rotation = config.Rotation
+	}
+
+	if output.Lumberjack == nil {
+		output.Lumberjack = config.Lumberjack
+	}
+
+	if output.Archive == nil {
+		output.Archive = config.Archive
+	}
+
+	if output.Permissions == nil {
+		output.Permissions = config.Permissions
+	}
+
+	if output.SyncPolicy == nil {
+		output.SyncPolicy = config.SyncPolicy
+	}
+
+	if output.Shared == nil {
+		output.Shared = config.Shared
+	}
+
+	if output.Buffer == nil {
+		output.Buffer = config.Buffer
+	}
+
+	if len(output.OnWriteError) == 0 {
+		output.OnWriteError = config.OnWriteError
+	}
+
+	if output.Encryption == nil {
+		output.Encryption = config.Encryption
+	}
+
+	return output
+}
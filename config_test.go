@@ -0,0 +1,360 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestNewConfig(t *testing.T) {
+	zapConfig := NewZapStdoutConfig()
+	lumber := NewLumberjackConfigDefault()
+
+	config := NewConfig(zapConfig, lumber)
+	assert.Equal(t, zapConfig, config.Zap)
+	assert.Equal(t, lumber, config.Lumberjack)
+	assert.Nil(t, config.Extensions)
+}
+
+func TestConfig_ToZapConfig(t *testing.T) {
+	assert.Nil(t, (&Config{}).ToZapConfig())
+
+	origin := NewZapStdoutConfig()
+	config := NewConfig(origin, nil)
+
+	res := config.ToZapConfig()
+	assert.False(t, res == origin)
+	assert.Equal(t, origin.Encoding, res.Encoding)
+
+	// level is shared
+	res.Level.SetLevel(zap.ErrorLevel)
+	assert.Equal(t, zap.ErrorLevel, origin.Level.Level())
+}
+
+func TestConfig_ToLumberjackConfig(t *testing.T) {
+	assert.Nil(t, (&Config{}).ToLumberjackConfig())
+
+	origin := NewLumberjackConfigDefault()
+	origin.Filename = "logs/rk-logger.log"
+	config := NewConfig(nil, origin)
+
+	res := config.ToLumberjackConfig()
+	assert.False(t, res == origin)
+	assert.Equal(t, origin, res)
+}
+
+// With nil byte array
+func TestNewConfigWithBytes_WithNilByteArray(t *testing.T) {
+	config, err := NewConfigWithBytes(nil, YAML)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// With empty byte array
+func TestNewConfigWithBytes_WithEmptyByteArray(t *testing.T) {
+	config, err := NewConfigWithBytes([]byte{}, YAML)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// With unknown file type
+func TestNewConfigWithBytes_WithInvalidType(t *testing.T) {
+	config, err := NewConfigWithBytes([]byte(`{"key":"value"}`), 10)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// With invalid section
+func TestNewConfigWithBytes_WithInvalidSection(t *testing.T) {
+	config, err := NewConfigWithBytes([]byte(`{"zap": {"level": "info"}, "lumberjack": "invalid"}`), JSON)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// With legacy config in which zap config and lumberjack config are placed at the root
+func TestNewConfigWithBytes_WithLegacyConfig(t *testing.T) {
+	config, err := NewConfigWithBytes([]byte(`{"level": "warn", "outputPaths": ["stdout"], "maxsize": 10}`), JSON)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.WarnLevel, config.Zap.Level.Level())
+	assert.Equal(t, []string{"stdout"}, config.Zap.OutputPaths)
+	assert.Equal(t, 10, config.Lumberjack.MaxSize)
+	assert.Nil(t, config.Extensions)
+}
+
+// With combined config
+func TestNewConfigWithBytes_WithSections(t *testing.T) {
+	raw := []byte(`
+zap:
+  level: warn
+  outputPaths: ["stdout"]
+lumberjack:
+  maxsize: 10
+extensions:
+  key: value
+`)
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.WarnLevel, config.Zap.Level.Level())
+	assert.Equal(t, []string{"stdout"}, config.Zap.OutputPaths)
+	assert.Equal(t, 10, config.Lumberjack.MaxSize)
+	assert.Equal(t, "value", config.Extensions["key"])
+}
+
+// Without lumberjack section
+func TestNewConfigWithBytes_WithoutLumberjackSection(t *testing.T) {
+	config, err := NewConfigWithBytes([]byte("[zap]\nlevel = \"info\"\noutputPaths = [\"stdout\"]"), TOML)
+	assert.Nil(t, err)
+	assert.NotNil(t, config.Zap)
+	assert.Nil(t, config.Lumberjack)
+}
+
+// With strict parsing
+func TestLoader_NewConfigWithBytes_WithStrictParsing(t *testing.T) {
+	loader := NewLoader(WithStrictParsing())
+
+	config, err := loader.NewConfigWithBytes([]byte(`{"zap": {"level": "info"}, "lumberjack": {"maxSize": 1}}`), JSON)
+	assert.Nil(t, config)
+	assert.IsType(t, &UnknownKeysError{}, err)
+	assert.Equal(t, []UnknownKey{{Path: "lumberjack.maxSize", Line: 1}}, err.(*UnknownKeysError).Keys)
+
+	// keys at the root are not recognized in combined config
+	config, err = loader.NewConfigWithBytes([]byte(`{"zap": {"level": "info"}, "maxsize": 1}`), JSON)
+	assert.Nil(t, config)
+	assert.IsType(t, &UnknownKeysError{}, err)
+
+	// extensions are never validated
+	config, err = loader.NewConfigWithBytes([]byte(`{"zap": {"level": "info"}, "extensions": {"any": 1}}`), JSON)
+	assert.NotNil(t, config)
+	assert.Nil(t, err)
+}
+
+// With empty file path
+func TestNewConfigWithConfPath_WithEmptyString(t *testing.T) {
+	config, err := NewConfigWithConfPath("", YAML)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// Happy case
+func TestNewConfigWithConfPath_HappyCase(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	config, err := NewConfigWithConfPath(dir+"/assets/config.yaml", YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.DebugLevel, config.Zap.Level.Level())
+	assert.Equal(t, "msg", config.Zap.EncoderConfig.MessageKey)
+	assert.Equal(t, 1024, config.Lumberjack.MaxSize)
+	assert.Equal(t, "rk-logger", config.Extensions["owner"])
+}
+
+// With nil config
+func TestNewZapLoggerWithConfig_WithNilConfig(t *testing.T) {
+	logger, err := NewZapLoggerWithConfig(nil)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+// Without zap section
+func TestNewZapLoggerWithConfig_WithoutZapSection(t *testing.T) {
+	logger, err := NewZapLoggerWithConfig(&Config{Lumberjack: &lumberjack.Logger{}})
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+// Happy case
+func TestNewZapLoggerWithConfig_HappyCase(t *testing.T) {
+	logger, err := NewZapLoggerWithConfig(NewConfig(NewZapStdoutConfig(), nil))
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+
+	logger, err = NewZapLoggerWithConfig(NewConfig(NewZapStdoutConfig(), NewLumberjackConfigDefault()))
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+}
+
+// Combined config is accepted by constructors with config file
+func TestNewZapLoggerWithConfPath_WithSections(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	logger, config, err := NewZapLoggerWithConfPath(dir+"/assets/config.yaml", YAML)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.DebugLevel, config.Level.Level())
+
+	lumber, err := NewLumberjackLoggerWithConfPath(dir+"/assets/config.yaml", YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, 1024, lumber.MaxSize)
+}
+
+// With outputs which carry their own rotation settings
+func TestNewConfigWithBytes_WithOutputs(t *testing.T) {
+	raw := []byte(`
+zap:
+  level: info
+  outputPaths: ["stdout"]
+lumberjack:
+  maxsize: 1024
+outputs:
+  - path: logs/app.log
+  - path: logs/audit.log
+    lumberjack:
+      maxsize: 10
+      compress: true
+`)
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Len(t, config.Outputs, 2)
+	assert.Equal(t, "logs/app.log", config.Outputs[0].Path)
+	assert.Nil(t, config.Outputs[0].Lumberjack)
+	assert.Equal(t, "logs/audit.log", config.Outputs[1].Path)
+	assert.Equal(t, 10, config.Outputs[1].Lumberjack.MaxSize)
+	assert.True(t, config.Outputs[1].Lumberjack.Compress)
+}
+
+// With output which has fallback, fallback inherits rotation settings in combined config
+func TestNewConfigWithBytes_WithFallback(t *testing.T) {
+	raw := []byte(`
+zap:
+  level: info
+  outputPaths: ["stdout"]
+lumberjack:
+  maxsize: 1024
+outputs:
+  - path: tcp://relay.example.com:5170
+    fallback:
+      path: logs/app.log
+    healthCheckInterval: 30s
+`)
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Len(t, config.Outputs, 1)
+	assert.Equal(t, "logs/app.log", config.Outputs[0].Fallback.Path)
+	assert.Equal(t, "30s", config.Outputs[0].HealthCheckInterval)
+
+	outputs, _ := config.toOutputConfigs()
+	assert.Equal(t, 1024, outputs[1].Fallback.Lumberjack.MaxSize)
+	assert.Nil(t, config.Outputs[0].Fallback.Lumberjack)
+}
+
+// With invalid output
+func TestNewZapLoggerWithConfig_WithInvalidOutput(t *testing.T) {
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	config.Outputs = []*OutputConfig{{}}
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+// With outputs
+func TestNewZapLoggerWithConfig_WithOutputs(t *testing.T) {
+	dir := newTempDir(t)
+	appPath, auditPath := path.Join(dir, "app.log"), path.Join(dir, "audit.log")
+
+	config := NewConfig(NewZapStdoutConfig(), NewLumberjackConfigDefault())
+	config.Zap.OutputPaths = []string{appPath}
+	config.Outputs = []*OutputConfig{
+		{Path: auditPath, Lumberjack: &lumberjack.Logger{MaxSize: 1}},
+	}
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+
+	for _, filePath := range []string{appPath, auditPath} {
+		content, err := ioutil.ReadFile(filePath)
+		assert.Nil(t, err)
+		assert.Contains(t, string(content), "ut-message")
+	}
+
+	// outputs in config are not modified
+	assert.Equal(t, 1, config.Outputs[0].Lumberjack.MaxSize)
+}
+
+func TestConfig_MarshalJSON(t *testing.T) {
+	config := NewConfig(NewZapStdoutConfig(), NewLumberjackConfigDefault())
+	config.Outputs = []*OutputConfig{{Path: "logs/audit.log"}}
+	config.Extensions = map[string]interface{}{"owner": "rk-logger"}
+
+	bytes, err := json.Marshal(config)
+	assert.Nil(t, err)
+
+	// marshalled config could be parsed again
+	res, err := NewConfigWithBytes(bytes, JSON)
+	assert.Nil(t, err)
+	assert.Equal(t, config.Zap.Level.Level(), res.Zap.Level.Level())
+	assert.Equal(t, config.Zap.OutputPaths, res.Zap.OutputPaths)
+	assert.Equal(t, config.Lumberjack.MaxSize, res.Lumberjack.MaxSize)
+	assert.Equal(t, "logs/audit.log", res.Outputs[0].Path)
+	assert.Equal(t, "rk-logger", res.Extensions["owner"])
+}
+
+// Without zap config
+func TestConfig_MarshalJSONWithoutZap(t *testing.T) {
+	bytes, err := json.Marshal(&Config{})
+	assert.Nil(t, err)
+	assert.Contains(t, string(bytes), `"zap":null`)
+}
+
+func TestConfig_WithTimeZone(t *testing.T) {
+	dir := newTempDir(t)
+
+	// With build path and lumberjack path
+	for i, section := range []string{"", "lumberjack:\n  maxsize: 1\n"} {
+		filePath := path.Join(dir, fmt.Sprintf("ut-%d.log", i))
+		raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    timeKey: ts
+    timeEncoder: iso8601
+  outputPaths: ["` + filePath + `"]
+timeZone: Asia/Shanghai
+` + section)
+
+		config, err := NewConfigWithBytes(raw, YAML)
+		assert.Nil(t, err)
+
+		logger, err := NewZapLoggerWithConfig(config)
+		assert.Nil(t, err)
+		logger.Info("ut-message")
+		logger.Sync()
+
+		assert.Regexp(t, `^\{"ts":"[0-9T:.-]+\+0800"\}\n$`, readFileContent(filePath))
+	}
+
+	// With time format of console section
+	entry := zapcore.Entry{Time: time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)}
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	config.TimeZone = "Asia/Shanghai"
+	config.Console = &ConsoleConfig{Color: ColorNever, Order: []string{"time"}, TimeFormat: "15:04"}
+	encoder, err := config.newEncoder()
+	assert.Nil(t, err)
+	buf, err := encoder.EncodeEntry(entry, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "18:00\n", buf.String())
+
+	// With invalid time zone
+	config = NewConfigWithOptions(WithTimeZone("Mars/Olympus"))
+	assert.Equal(t, "Mars/Olympus", config.TimeZone)
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.Contains(t, err.Error(), "invalid time zone, timeZone:Mars/Olympus")
+}
@@ -0,0 +1,499 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ConsoleEncoding is the name of the default encoding, which is themed by console section of config,
+	// see ConsoleConfig.
+	ConsoleEncoding = "console"
+
+	// ColorAuto colorizes levels if stdout is a terminal and NO_COLOR is not set.
+	ColorAuto = "auto"
+	// ColorAlways colorizes levels regardless of stdout.
+	ColorAlways = "always"
+	// ColorNever never colorizes levels.
+	ColorNever = "never"
+
+	// FieldsJSON renders fields as a trailing json object, like console encoding of zap.
+	FieldsJSON = "json"
+	// FieldsInline renders fields inline as key=value pairs, like logfmt encoding.
+	FieldsInline = "inline"
+)
+
+// elements of console line which could be ordered by ConsoleConfig.Order
+const (
+	consoleTime     = "time"
+	consoleLevel    = "level"
+	consoleName     = "name"
+	consoleCaller   = "caller"
+	consoleFunction = "function"
+	consoleMessage  = "message"
+	consoleFields   = "fields"
+)
+
+var (
+	// default order of elements, which is the same as console encoding of zap
+	defaultConsoleOrder = []string{
+		consoleTime, consoleLevel, consoleName, consoleCaller, consoleFunction, consoleMessage, consoleFields,
+	}
+
+	// names of colors which could be used by ConsoleConfig.Colors
+	colorNames = map[string]string{
+		"black":   "30",
+		"red":     "31",
+		"green":   "32",
+		"yellow":  "33",
+		"blue":    "34",
+		"magenta": "35",
+		"cyan":    "36",
+		"white":   "37",
+		"gray":    "90",
+	}
+
+	// select graphic rendition parameters like 1;31
+	sgrRegex = regexp.MustCompile(`^[0-9]+(;[0-9]+)*$`)
+)
+
+// ConsoleConfig themes console encoding.
+//
+// Example config file in YAML:
+//
+//	zap:
+//	  encoding: console
+//	  encoderConfig:
+//	    timeKey: ts
+//	    levelKey: level
+//	    messageKey: msg
+//	console:
+//	  color: auto
+//	  colors:
+//	    info: green
+//	    warn: "1;33"
+//	  separator: " | "
+//	  order: [level, time, message, fields]
+//	  timeFormat: "15:04:05.000"
+//	  fields: inline
+type ConsoleConfig struct {
+	// Color is one of auto, always and never, ColorAuto by default.
+	// Levels are colorized only if stdout is a terminal and NO_COLOR is not set with ColorAuto.
+	Color string `json:"color" yaml:"color"`
+	// Colors are colors of levels keyed by level names, like info: green or error: "1;31".
+	// Colors are names like black, red, green, yellow, blue, magenta, cyan, white and gray, or select graphic
+	// rendition parameters. Levels not provided have the same colors as color level encoders of zap.
+	Colors map[string]string `json:"colors" yaml:"colors"`
+	// Separator separates elements of line, consoleSeparator of encoderConfig or tab by default.
+	Separator string `json:"separator" yaml:"separator"`
+	// Order is the order of elements, which are time, level, name, caller, function, message and fields.
+	// Elements not provided are omitted, elements whose keys of encoderConfig are empty are omitted as well.
+	// Elements are in the same order as console encoding of zap by default.
+	Order []string `json:"order" yaml:"order"`
+	// TimeFormat is rfc3339, rfc3339nano, iso8601, epoch, millis, nanos or layout of time.Format(), like 15:04:05.
+	// Time is encoded by timeEncoder of encoderConfig if not provided.
+	TimeFormat string `json:"timeFormat" yaml:"timeFormat"`
+	// Fields is json or inline, FieldsJSON by default.
+	Fields string `json:"fields" yaml:"fields"`
+}
+
+// consoleEncoder renders entries as themed lines, fields are encoded by wrapped json or logfmt encoder
+type consoleEncoder struct {
+	zapcore.Encoder
+	config     *zapcore.EncoderConfig
+	order      []string
+	separator  string
+	colors     map[zapcore.Level]string
+	inline     bool
+	lineEnding string
+}
+
+// NewConsoleEncoderWithConfig returns constructor of console encoder themed by consoleConfig, which could be
+// registered with RegisterEncoder.
+func NewConsoleEncoderWithConfig(consoleConfig ConsoleConfig) EncoderConstructor {
+	return func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		colors, err := consoleConfig.levelColors()
+		if err != nil {
+			return nil, err
+		}
+
+		order := consoleConfig.Order
+		if len(order) == 0 {
+			order = defaultConsoleOrder
+		}
+		for _, element := range order {
+			if !containsString(defaultConsoleOrder, element) {
+				return nil, errors.Errorf("invalid element of console order, element:%s", element)
+			}
+		}
+
+		if len(consoleConfig.TimeFormat) > 0 {
+			config.EncodeTime = consoleConfig.timeEncoder()
+		}
+
+		// levels are colorized by ourselves
+		config.EncodeLevel = plainLevelEncoder(config.EncodeLevel)
+
+		separator := consoleConfig.Separator
+		if len(separator) == 0 {
+			separator = config.ConsoleSeparator
+		}
+		if len(separator) == 0 {
+			separator = "\t"
+		}
+
+		lineEnding := config.LineEnding
+		if len(lineEnding) == 0 {
+			lineEnding = zapcore.DefaultLineEnding
+		}
+
+		// fields encoder encodes fields only
+		fieldsConfig := zapcore.EncoderConfig{
+			EncodeTime:     config.EncodeTime,
+			EncodeDuration: config.EncodeDuration,
+			LineEnding:     "\n",
+		}
+
+		res := &consoleEncoder{
+			config:     &config,
+			order:      append([]string{}, order...),
+			separator:  separator,
+			colors:     colors,
+			lineEnding: lineEnding,
+		}
+
+		switch consoleConfig.Fields {
+		case "", FieldsJSON:
+			res.Encoder = zapcore.NewJSONEncoder(fieldsConfig)
+		case FieldsInline:
+			res.Encoder, _ = NewLogfmtEncoder(fieldsConfig)
+			res.inline = true
+		default:
+			return nil, errors.Errorf("invalid fields of console, fields:%s", consoleConfig.Fields)
+		}
+
+		return res, nil
+	}
+}
+
+// Time encoder of time format, which is either name of time encoder or layout
+func (config *ConsoleConfig) timeEncoder() zapcore.TimeEncoder {
+	if encoder, ok := lookupTimeEncoder(config.TimeFormat); ok {
+		return encoder
+	}
+
+	return zapcore.TimeEncoderOfLayout(config.TimeFormat)
+}
+
+// Colors of levels, nil if levels are not colorized
+func (config *ConsoleConfig) levelColors() (map[zapcore.Level]string, error) {
+	switch config.Color {
+	case "", ColorAuto:
+		if !isColorTerminal() {
+			return nil, nil
+		}
+	case ColorAlways:
+	case ColorNever:
+		return nil, nil
+	default:
+		return nil, errors.Errorf("invalid color of console, color:%s", config.Color)
+	}
+
+	res := make(map[zapcore.Level]string)
+	for level := TraceLevel; level <= zapcore.FatalLevel; level++ {
+		res[level] = levelColor(level)
+	}
+
+	for name, color := range config.Colors {
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid level of console colors, level:%s", name)
+		}
+
+		code, ok := colorNames[strings.ToLower(color)]
+		if !ok {
+			if !sgrRegex.MatchString(color) {
+				return nil, errors.Errorf("invalid color of console colors, color:%s", color)
+			}
+			code = color
+		}
+
+		res[level] = "\x1b[" + code + "m"
+	}
+
+	return res, nil
+}
+
+// Level encoder without colors, since colors of levels are decided by console config
+func plainLevelEncoder(encoder zapcore.LevelEncoder) zapcore.LevelEncoder {
+	if encoder == nil {
+		return nil
+	}
+
+	switch reflect.ValueOf(encoder).Pointer() {
+	case reflect.ValueOf(zapcore.CapitalColorLevelEncoder).Pointer():
+		return zapcore.CapitalLevelEncoder
+	case reflect.ValueOf(zapcore.LowercaseColorLevelEncoder).Pointer():
+		return zapcore.LowercaseLevelEncoder
+	default:
+		return encoder
+	}
+}
+
+// Whether list contains value
+func containsString(list []string, value string) bool {
+	for i := range list {
+		if list[i] == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone implements zapcore.Encoder
+func (enc *consoleEncoder) Clone() zapcore.Encoder {
+	clone := *enc
+	clone.Encoder = enc.Encoder.Clone()
+	return &clone
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *consoleEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := consolePool.Get()
+	arr := consoleArrayPool.Get().(*consoleArrayEncoder)
+	arr.buf = consolePool.Get()
+	defer putConsoleArrayEncoder(arr)
+
+	for _, element := range enc.order {
+		arr.buf.Reset()
+		arr.count = 0
+		if !enc.encodeElement(arr, element, entry, fields) {
+			continue
+		}
+
+		if line.Len() > 0 {
+			line.AppendString(enc.separator)
+		}
+		line.Write(arr.buf.Bytes())
+	}
+
+	if len(entry.Stack) > 0 && len(enc.config.StacktraceKey) > 0 {
+		line.AppendByte('\n')
+		line.AppendString(entry.Stack)
+	}
+
+	line.AppendString(enc.lineEnding)
+	return line, nil
+}
+
+// Encode element of line with arr, false is returned if element is omitted
+func (enc *consoleEncoder) encodeElement(arr *consoleArrayEncoder, element string, entry zapcore.Entry, fields []zapcore.Field) bool {
+	config, value := enc.config, arr.buf
+
+	switch element {
+	case consoleTime:
+		if len(config.TimeKey) == 0 || config.EncodeTime == nil {
+			return false
+		}
+		config.EncodeTime(entry.Time, arr)
+	case consoleLevel:
+		if len(config.LevelKey) == 0 || config.EncodeLevel == nil {
+			return false
+		}
+		color, ok := enc.colors[entry.Level]
+		if ok {
+			value.AppendString(color)
+		}
+		config.EncodeLevel(entry.Level, arr)
+		if ok {
+			value.AppendString(colorReset)
+		}
+	case consoleName:
+		if len(entry.LoggerName) == 0 || len(config.NameKey) == 0 {
+			return false
+		}
+		encodeName := config.EncodeName
+		if encodeName == nil {
+			encodeName = zapcore.FullNameEncoder
+		}
+		encodeName(entry.LoggerName, arr)
+	case consoleCaller:
+		if !entry.Caller.Defined || len(config.CallerKey) == 0 || config.EncodeCaller == nil {
+			return false
+		}
+		config.EncodeCaller(entry.Caller, arr)
+	case consoleFunction:
+		if !entry.Caller.Defined || len(config.FunctionKey) == 0 {
+			return false
+		}
+		value.AppendString(entry.Caller.Function)
+	case consoleMessage:
+		if len(config.MessageKey) == 0 {
+			return false
+		}
+		value.AppendString(entry.Message)
+	default:
+		return enc.encodeFields(value, fields)
+	}
+
+	return true
+}
+
+// Encode fields added with With() and fields of entry into value, false is returned if there is no field
+func (enc *consoleEncoder) encodeFields(value *buffer.Buffer, fields []zapcore.Field) bool {
+	buf, err := enc.Encoder.EncodeEntry(zapcore.Entry{}, fields)
+	if err != nil {
+		return false
+	}
+	defer buf.Free()
+
+	buf.TrimNewline()
+	if buf.Len() == 0 || (buf.Len() == 2 && buf.Bytes()[0] == '{' && buf.Bytes()[1] == '}') {
+		return false
+	}
+
+	value.Write(buf.Bytes())
+	return true
+}
+
+// Put array encoder back to pool with its buffer freed
+func putConsoleArrayEncoder(arr *consoleArrayEncoder) {
+	arr.buf.Free()
+	arr.buf = nil
+	arr.count = 0
+	consoleArrayPool.Put(arr)
+}
+
+// consoleArrayEncoder captures values appended by encoders of config, values are separated by spaces and printed the
+// same way as console encoding of zap, which prints them with fmt.Sprint
+type consoleArrayEncoder struct {
+	buf *buffer.Buffer
+	// number of appended values
+	count int
+}
+
+// Separate values with space
+func (arr *consoleArrayEncoder) separate() {
+	if arr.count > 0 {
+		arr.buf.AppendByte(' ')
+	}
+	arr.count++
+}
+
+// AppendBool implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendBool(value bool) {
+	arr.separate()
+	arr.buf.AppendBool(value)
+}
+
+// AppendByteString implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendByteString(value []byte) {
+	arr.separate()
+	arr.buf.Write(value)
+}
+
+// AppendComplex128 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendComplex128(value complex128) {
+	arr.separate()
+	fmt.Fprint(arr.buf, value)
+}
+
+// AppendComplex64 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendComplex64(value complex64) {
+	arr.separate()
+	fmt.Fprint(arr.buf, value)
+}
+
+// AppendFloat64 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendFloat64(value float64) {
+	arr.separate()
+	arr.appendFloat(value, 64)
+}
+
+// AppendFloat32 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendFloat32(value float32) {
+	arr.separate()
+	arr.appendFloat(float64(value), 32)
+}
+
+// Append float the same way as fmt.Sprint
+func (arr *consoleArrayEncoder) appendFloat(value float64, bitSize int) {
+	var scratch [32]byte
+	arr.buf.Write(strconv.AppendFloat(scratch[:0], value, 'g', -1, bitSize))
+}
+
+// AppendInt implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendInt(value int) { arr.AppendInt64(int64(value)) }
+
+// AppendInt64 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendInt64(value int64) {
+	arr.separate()
+	arr.buf.AppendInt(value)
+}
+
+// AppendInt32 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendInt32(value int32) { arr.AppendInt64(int64(value)) }
+
+// AppendInt16 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendInt16(value int16) { arr.AppendInt64(int64(value)) }
+
+// AppendInt8 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendInt8(value int8) { arr.AppendInt64(int64(value)) }
+
+// AppendString implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendString(value string) {
+	arr.separate()
+	arr.buf.AppendString(value)
+}
+
+// AppendUint implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendUint(value uint) { arr.AppendUint64(uint64(value)) }
+
+// AppendUint64 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendUint64(value uint64) {
+	arr.separate()
+	arr.buf.AppendUint(value)
+}
+
+// AppendUint32 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendUint32(value uint32) { arr.AppendUint64(uint64(value)) }
+
+// AppendUint16 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendUint16(value uint16) { arr.AppendUint64(uint64(value)) }
+
+// AppendUint8 implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendUint8(value uint8) { arr.AppendUint64(uint64(value)) }
+
+// AppendUintptr implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendUintptr(value uintptr) { arr.AppendUint64(uint64(value)) }
+
+// AppendDuration implements zapcore.PrimitiveArrayEncoder
+func (arr *consoleArrayEncoder) AppendDuration(value time.Duration) {
+	arr.AppendString(value.String())
+}
+
+// AppendTime implements zapcore.PrimitiveArrayEncoder, time is printed the same way as json encoding
+func (arr *consoleArrayEncoder) AppendTime(value time.Time) {
+	arr.AppendInt64(value.UnixNano())
+}
+
+// pool of buffers returned by console encoder, and array encoders of elements which are reused
+var (
+	consolePool      = buffer.NewPool()
+	consoleArrayPool = sync.Pool{New: func() interface{} {
+		return &consoleArrayEncoder{}
+	}}
+)
@@ -0,0 +1,165 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// Entry with time, level, caller and message
+func newConsoleEntry() zapcore.Entry {
+	return zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC),
+		LoggerName: "db",
+		Message:    "slow query",
+		Caller:     zapcore.NewEntryCaller(0, "/src/db/conn.go", 42, true),
+	}
+}
+
+func TestConsoleEncoder_EncodeEntry(t *testing.T) {
+	config := newCSVEncoderConfig()
+	config.NameKey = "logger"
+
+	// In the same order as console encoding of zap by default
+	encoder, err := NewConsoleEncoderWithConfig(ConsoleConfig{Color: ColorNever})(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "2020-09-01T10:00:00.000Z\twarn\tdb\tdb/conn.go:42\tslow query\t{\"ms\":250}\n",
+		encodeCSV(t, encoder, newConsoleEntry(), zap.Int("ms", 250)))
+
+	// Empty fields are omitted
+	assert.Equal(t, "2020-09-01T10:00:00.000Z\twarn\tdb\tdb/conn.go:42\tslow query\n",
+		encodeCSV(t, encoder, newConsoleEntry()))
+
+	// With order, separator, time format and inline fields
+	encoder, err = NewConsoleEncoderWithConfig(ConsoleConfig{
+		Color:      ColorNever,
+		Separator:  " | ",
+		Order:      []string{"level", "time", "message", "fields"},
+		TimeFormat: "15:04:05",
+		Fields:     FieldsInline,
+	})(config)
+	assert.Nil(t, err)
+	encoder.AddString("app", "ut")
+	assert.Equal(t, "warn | 10:00:00 | slow query | app=ut ms=250\n",
+		encodeCSV(t, encoder.Clone(), newConsoleEntry(), zap.Int("ms", 250)))
+
+	// With named time format, elements whose keys are empty are omitted
+	config.TimeKey = ""
+	encoder, err = NewConsoleEncoderWithConfig(ConsoleConfig{
+		Color:      ColorNever,
+		Order:      []string{"time", "message"},
+		TimeFormat: "epoch",
+	})(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "slow query\n", encodeCSV(t, encoder, newConsoleEntry()))
+
+	// Stacktrace is on a new line
+	config.StacktraceKey = "stacktrace"
+	entry := newConsoleEntry()
+	entry.Stack = "goroutine 1"
+	encoder, err = NewConsoleEncoderWithConfig(ConsoleConfig{Color: ColorNever, Order: []string{"message"}})(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "slow query\ngoroutine 1\n", encodeCSV(t, encoder, entry))
+}
+
+func TestConsoleEncoder_WithColors(t *testing.T) {
+	config := newCSVEncoderConfig()
+	config.EncodeLevel = zapcore.CapitalColorLevelEncoder
+
+	// Levels are colorized as color level encoders of zap by default
+	encoder, err := NewConsoleEncoderWithConfig(ConsoleConfig{
+		Color: ColorAlways,
+		Order: []string{"level", "message"},
+	})(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[33mWARN\x1b[0m\tslow query\n", encodeCSV(t, encoder, newConsoleEntry()))
+
+	// With colors of levels
+	encoder, err = NewConsoleEncoderWithConfig(ConsoleConfig{
+		Color:  ColorAlways,
+		Colors: map[string]string{"warn": "green", "error": "1;31"},
+		Order:  []string{"level"},
+	})(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "\x1b[32mWARN\x1b[0m\n", encodeCSV(t, encoder, newConsoleEntry()))
+	entry := newConsoleEntry()
+	entry.Level = zapcore.ErrorLevel
+	assert.Equal(t, "\x1b[1;31mERROR\x1b[0m\n", encodeCSV(t, encoder, entry))
+
+	// Color level encoder is not colorized without colors
+	encoder, err = NewConsoleEncoderWithConfig(ConsoleConfig{Color: ColorNever, Order: []string{"level"}})(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "WARN\n", encodeCSV(t, encoder, newConsoleEntry()))
+
+	// NO_COLOR disables colors with auto
+	assert.Nil(t, os.Setenv("NO_COLOR", "1"))
+	defer os.Unsetenv("NO_COLOR")
+	encoder, err = NewConsoleEncoderWithConfig(ConsoleConfig{Order: []string{"level"}})(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "WARN\n", encodeCSV(t, encoder, newConsoleEntry()))
+}
+
+func TestConsoleEncoder_WithInvalidConfig(t *testing.T) {
+	for _, consoleConfig := range []ConsoleConfig{
+		{Color: "sometimes"},
+		{Color: ColorAlways, Colors: map[string]string{"verbose": "red"}},
+		{Color: ColorAlways, Colors: map[string]string{"info": "pink"}},
+		{Order: []string{"message", "thread"}},
+		{Fields: "yaml"},
+	} {
+		encoder, err := NewConsoleEncoderWithConfig(consoleConfig)(newCSVEncoderConfig())
+		assert.Nil(t, encoder)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestConsoleEncoding_WithConfigFile(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: console
+  encoderConfig:
+    levelKey: level
+    messageKey: msg
+    levelEncoder: capitalColor
+  outputPaths: ["` + filePath + `"]
+console:
+  color: never
+  separator: " "
+  order: [level, message, fields]
+  fields: inline
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, FieldsInline, config.Console.Fields)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Warn("ut-message", zap.String("user_id", "u1"))
+	logger.Sync()
+
+	assert.Equal(t, "WARN ut-message user_id=u1\n", readFileContent(filePath))
+
+	// With invalid order
+	config.Console.Order = []string{"thread"}
+	logger, err = NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.Contains(t, err.Error(), "failed to create encoder, encoding:console")
+}
+
+func BenchmarkConsoleEncoder(b *testing.B) {
+	enc, _ := NewConsoleEncoderWithConfig(ConsoleConfig{Color: ColorAlways})(zap.NewDevelopmentEncoderConfig())
+	benchmarkEncoder(b, enc)
+}
@@ -0,0 +1,705 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// Attach the same lumberjack config to every path
+func newOutputConfigs(paths []string, lumber *lumberjack.Logger) []*OutputConfig {
+	res := make([]*OutputConfig, 0, len(paths))
+	for i := range paths {
+		res = append(res, &OutputConfig{
+			Path:       paths[i],
+			Lumberjack: lumber,
+		})
+	}
+
+	return res
+}
+
+// Build zap logger with custom core whose write syncers are created from outputs
+// Output paths in zap config are ignored, use outputs and errOutputs instead
+func (loader *Loader) buildZapLogger(config *zap.Config, outputs, errOutputs []*OutputConfig, opts ...zap.Option) (*zap.Logger, error) {
+	return loader.buildZapLoggerWithOutputs(NewConfig(config, nil), outputs, errOutputs, opts...)
+}
+
+// Build zap logger with custom core of combined config, whose levels are overridden by logger names and encoder is
+// configured by sections of encodings
+func (loader *Loader) buildZapLoggerWithOutputs(config *Config, outputs, errOutputs []*OutputConfig, opts ...zap.Option) (*zap.Logger, error) {
+	logger, _, err := loader.buildClosableZapLogger(config, outputs, errOutputs, opts...)
+	return logger, err
+}
+
+// Build zap logger with custom core, the returned function closes write syncers of outputs and error outputs
+func (loader *Loader) buildClosableZapLogger(config *Config, outputs, errOutputs []*OutputConfig, opts ...zap.Option) (*zap.Logger, func(), error) {
+	core, closeSink, err := loader.newZapCore(config, outputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configOpts, closeErrSink, err := loader.newZapOptions(config.Zap, errOutputs)
+	if err != nil {
+		closeSink()
+		return nil, nil, err
+	}
+
+	closeAll := func() {
+		closeSink()
+		closeErrSink()
+	}
+
+	return zap.New(core, append(configOpts, opts...)...), closeAll, nil
+}
+
+// Build zap core with encoder, level, sampling and initial fields of zap config in combined config
+// Level of zap config is overridden by levels of logger names if provided.
+// The returned function closes write syncers of outputs
+func (loader *Loader) newZapCore(combined *Config, outputs []*OutputConfig) (zapcore.Core, func(), error) {
+	config, levels := combined.Zap, combined.Levels
+	if config.Level == (zap.AtomicLevel{}) {
+		return nil, nil, errors.New("level of zap config is missing")
+	}
+
+	encoder, err := combined.newEncoder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if combined.Limits != nil {
+		encoder = newLimitEncoder(encoder, *combined.Limits)
+	}
+
+	var enabler zapcore.LevelEnabler = config.Level
+	var nameLevels *nameLevelEnabler
+	if len(levels) > 0 {
+		nameLevels = newNameLevelEnabler(config.Level, levels)
+		enabler = nameLevels
+	}
+
+	sampler, err := newLevelSampler(combined.LevelSampling)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limiter, err := newRateLimiter(combined.RateLimit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dedup, err := newDeduplicator(combined.Dedup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter, err := newEntryFilter(combined.Filters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redactor, err := newRedactor(combined.Redaction)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pseudonymizer, err := loader.newPseudonymizer(combined.Pseudonymization)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	routes, err := combined.toRoutes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := validateAuditOutputs(config.Encoding, outputs); err != nil {
+		return nil, nil, err
+	}
+
+	for _, r := range routes {
+		if err := validateAuditOutputs(config.Encoding, r.outputs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var async *batch.Config
+	if combined.Async != nil {
+		asyncConfig, err := combined.Async.batchConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+		async = &asyncConfig
+	}
+
+	cores := make([]zapcore.Core, 0)
+	closeCores := func() {}
+	// outputs of zap config and outputs section are not required if entries are routed
+	if len(outputs) > 0 || len(routes) == 0 {
+		if cores, closeCores, err = loader.openOutputCores(outputs, encoder, enabler, async); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// every route is a tee of cores of its outputs, which only enables levels of route
+	files := append([]*OutputConfig(nil), outputs...)
+	routedCores := make([]*routedCore, 0, len(routes))
+	for _, r := range routes {
+		routeEnabler := &routeLevelEnabler{base: enabler, min: r.min, max: r.max}
+		routeCores, closeRoute, err := loader.openOutputCores(r.outputs, encoder, routeEnabler, async)
+		if err != nil {
+			closeCores()
+			return nil, nil, err
+		}
+
+		routedCores = append(routedCores, &routedCore{route: r, core: zapcore.NewTee(routeCores...)})
+		files = append(files, r.outputs...)
+		closeOutputs := closeCores
+		closeCores = func() {
+			closeOutputs()
+			closeRoute()
+		}
+	}
+
+	stopJanitor := func() {}
+	if combined.Retention != nil {
+		_, files = splitCoreOutputs(files)
+		if stopJanitor, err = loader.startRetentionJanitor(combined.Retention, files); err != nil {
+			closeCores()
+			return nil, nil, err
+		}
+	}
+
+	closeAll := func() {
+		dedup.close()
+		limiter.close()
+		stopJanitor()
+		closeCores()
+	}
+
+	core := zapcore.NewTee(cores...)
+	if len(routedCores) > 0 {
+		core = newRoutingCore(core, routedCores)
+	}
+
+	// sample the same way as zap.Config.Build()
+	if config.Sampling != nil {
+		samplerOpts := make([]zapcore.SamplerOption, 0)
+		if config.Sampling.Hook != nil {
+			samplerOpts = append(samplerOpts, zapcore.SamplerHook(config.Sampling.Hook))
+		}
+
+		core = zapcore.NewSamplerWithOptions(
+			core,
+			time.Second,
+			config.Sampling.Initial,
+			config.Sampling.Thereafter,
+			samplerOpts...)
+	}
+
+	// sample every level with its own rate
+	core = sampler.wrap(core)
+
+	// add initial fields in order of keys
+	keys := make([]string, 0, len(config.InitialFields))
+	for k := range config.InitialFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	initialFields := make([]zap.Field, 0, len(config.InitialFields))
+	for _, k := range keys {
+		initialFields = append(initialFields, zap.Any(k, config.InitialFields[k]))
+	}
+
+	// throttle entries after sampling, summaries carry initial fields which are redacted and pseudonymized
+	summaryCore := core
+	if len(initialFields) > 0 {
+		summaryCore = core.With(redactor.redactWith(pseudonymizer.redactWith(initialFields)))
+	}
+	core = limiter.wrap(core, summaryCore)
+
+	// collapse repeats before they are sampled or throttled
+	core = dedup.wrap(core)
+
+	// redact and pseudonymize entries before they are counted and encoded
+	core = redactor.wrap(core)
+	core = pseudonymizer.wrap(core)
+
+	// initial fields are added outside of redactor and pseudonymizer the same way as fields added with With()
+	if len(initialFields) > 0 {
+		core = core.With(initialFields)
+	}
+
+	// drop noise before it is counted by dedup, sampling and rate limit, rules see values which are not redacted
+	core = filter.wrap(core)
+
+	// filter entries by logger names before sampling
+	if nameLevels != nil {
+		core = &nameLevelCore{Core: core, levels: nameLevels}
+	}
+
+	return core, closeAll, nil
+}
+
+// Open cores of outputs with encoder and enabler, entries are written to write syncers of outputs by one core
+// Outputs of registered cores are not written by write syncer, like systemd journal, and outputs with allowed or
+// denied fields are written by their own cores. The returned function closes all of the cores.
+func (loader *Loader) openOutputCores(outputs []*OutputConfig, encoder zapcore.Encoder, enabler zapcore.LevelEnabler, async *batch.Config) ([]zapcore.Core, func(), error) {
+	coreOutputs, others := splitCoreOutputs(outputs)
+	shared, dedicated := splitSharedOutputs(others)
+
+	cores, closeCores, err := openCores(coreOutputs, enabler)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range coreOutputs {
+		cores[i] = newFieldFilter(coreOutputs[i]).wrap(cores[i])
+	}
+
+	closers := []func(){closeCores}
+	closeAll := func() {
+		for i := range closers {
+			closers[i]()
+		}
+	}
+
+	if len(shared) > 0 || (len(coreOutputs) == 0 && len(dedicated) == 0) {
+		sink, closeSink, err := loader.openSink(shared, async)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+
+		cores = append(cores, zapcore.NewCore(encoder, sink, enabler))
+		closers = append(closers, closeSink)
+	}
+
+	for _, output := range dedicated {
+		sink, closeSink, err := loader.openSink([]*OutputConfig{output}, async)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+
+		core := zapcore.NewCore(encoder, sink, enabler)
+		if output.Audit != nil {
+			// signature entry is written before sink is closed
+			auditCore, seal, err := loader.newAuditCore(output, encoder, sink, enabler)
+			if err != nil {
+				closeSink()
+				closeAll()
+				return nil, nil, err
+			}
+
+			core, closeSink = auditCore, sealThenClose(seal, closeSink)
+		}
+
+		cores = append(cores, newFieldFilter(output).wrap(core))
+		closers = append(closers, closeSink)
+	}
+
+	return cores, closeAll, nil
+}
+
+// Split outputs into outputs sharing one core and outputs with their own cores, which carry allowed or denied fields
+// or are audited
+func splitSharedOutputs(outputs []*OutputConfig) ([]*OutputConfig, []*OutputConfig) {
+	shared := make([]*OutputConfig, 0, len(outputs))
+	dedicated := make([]*OutputConfig, 0)
+	for i := range outputs {
+		if len(outputs[i].AllowFields) > 0 || len(outputs[i].DenyFields) > 0 || outputs[i].Audit != nil {
+			dedicated = append(dedicated, outputs[i])
+		} else {
+			shared = append(shared, outputs[i])
+		}
+	}
+
+	return shared, dedicated
+}
+
+// Combine functions sealing and closing audit output
+func sealThenClose(seal, closeSink func()) func() {
+	return func() {
+		seal()
+		closeSink()
+	}
+}
+
+// Open combined write syncer of outputs, which is written in background if async is provided
+func (loader *Loader) openSink(outputs []*OutputConfig, async *batch.Config) (zapcore.WriteSyncer, func(), error) {
+	sink, closeSink, err := loader.openCombinedWriteSyncer(outputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// entries are encoded by callers and written by background worker
+	if async != nil {
+		asyncWriter := newAsyncWriter(*async, sink)
+		return asyncWriter, func() {
+			asyncWriter.Close()
+			closeSink()
+		}, nil
+	}
+
+	return sink, closeSink, nil
+}
+
+// Build options of zap config with error outputs
+// Options of zap config are applied before options of caller, the same way as zap.Config.Build()
+// The returned function closes write syncers of error outputs
+func (loader *Loader) newZapOptions(config *zap.Config, errOutputs []*OutputConfig) ([]zap.Option, func(), error) {
+	configOpts := make([]zap.Option, 0)
+	closeErrSink := func() {}
+
+	// add error output sync
+	if len(errOutputs) > 0 {
+		errSink, closer, err := loader.openCombinedWriteSyncer(errOutputs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		configOpts = append(configOpts, zap.ErrorOutput(errSink))
+		closeErrSink = closer
+	}
+
+	if config.Development {
+		configOpts = append(configOpts, zap.Development())
+	}
+
+	if !config.DisableCaller {
+		configOpts = append(configOpts, zap.AddCaller())
+	}
+
+	stackLevel := zap.ErrorLevel
+	if config.Development {
+		stackLevel = zap.WarnLevel
+	}
+	if !config.DisableStacktrace {
+		configOpts = append(configOpts, zap.AddStacktrace(stackLevel))
+	}
+
+	return configOpts, closeErrSink, nil
+}
+
+// Create write syncers of outputs and combine them together
+func (loader *Loader) newCombinedWriteSyncer(outputs []*OutputConfig) (zapcore.WriteSyncer, error) {
+	syncer, _, err := loader.openCombinedWriteSyncer(outputs)
+	return syncer, err
+}
+
+// Create write syncers of outputs and combine them together
+// The returned function closes all of the write syncers
+func (loader *Loader) openCombinedWriteSyncer(outputs []*OutputConfig) (zapcore.WriteSyncer, func(), error) {
+	syncers := make([]zapcore.WriteSyncer, 0, len(outputs))
+	closers := make([]func(), 0, len(outputs))
+	closeAll := func() {
+		for i := range closers {
+			closers[i]()
+		}
+	}
+
+	for i := range outputs {
+		syncer, closer, err := loader.openWriteSyncer(outputs[i])
+		if err != nil {
+			// close opened write syncers the same way as zap.Open()
+			closeAll()
+			return nil, nil, err
+		}
+
+		syncers = append(syncers, syncer)
+		closers = append(closers, closer)
+	}
+
+	return zap.CombineWriteSyncers(syncers...), closeAll, nil
+}
+
+// Create write syncer of output
+// File paths are attached to a new lumberjack logger with rotation settings of output,
+// stdout, stderr, sink urls and file paths without rotation settings are opened by zap directly,
+// and urls whose scheme is registered with RegisterWriter are opened by their factories
+func (loader *Loader) newWriteSyncer(output *OutputConfig) (zapcore.WriteSyncer, error) {
+	syncer, _, err := loader.openWriteSyncer(output)
+	return syncer, err
+}
+
+// Create write syncer of output, the returned function closes the write syncer
+// Write syncers of file outputs are shared by loggers in process with the same absolute path,
+// and write syncers of other outputs are shared by outputs with the same path if Loader has a pool of write syncers.
+func (loader *Loader) openWriteSyncer(output *OutputConfig) (zapcore.WriteSyncer, func(), error) {
+	// primary and fallback are shared, while failover writer is not
+	if output.Fallback != nil || hasWriteErrorPolicy(output) {
+		return loader.openFailoverWriter(output)
+	}
+
+	if len(output.Path) == 0 {
+		return loader.openBufferedOutput(output)
+	}
+
+	key, settings := loader.poolKey(output.Path), newWriterSettings(output)
+	open := func() (zapcore.WriteSyncer, func(), error) {
+		if _, ok := toFilePath(output.Path); !ok {
+			return loader.openBufferedOutput(output)
+		}
+
+		shared := sharedWriteSyncers.open
+		if loader.reopen {
+			shared = sharedWriteSyncers.reopen
+		}
+
+		return shared(key, settings, func() (zapcore.WriteSyncer, func(), error) {
+			return loader.openBufferedOutput(output)
+		})
+	}
+
+	if loader.pool == nil {
+		return open()
+	}
+
+	return loader.pool.open(key, settings, open)
+}
+
+// Settings of output applied by openBufferedOutput, stdout and stderr have encryption only, and settings other than
+// encryption and buffer are applied to files only
+func newWriterSettings(output *OutputConfig) writerSettings {
+	res := make(writerSettings)
+	add := func(name string, value interface{}) {
+		raw, _ := json.Marshal(value)
+		res[name] = string(raw)
+	}
+
+	// encryption is inherited by outputs which are not encrypted as well
+	add("encrypted", output.Encrypted)
+	if output.Encrypted {
+		add("encryption", output.Encryption)
+	}
+
+	if output.Path == "stdout" || output.Path == "stderr" {
+		return res
+	}
+
+	add("buffer", output.Buffer)
+	if _, ok := toFilePath(output.Path); !ok {
+		return res
+	}
+
+	// rotation takes precedence over lumberjack
+	if output.Rotation != nil {
+		add("rotation", output.Rotation)
+	} else {
+		add("rotation", output.Lumberjack)
+	}
+	add("archive", output.Archive)
+	add("permissions", output.Permissions)
+	add("syncPolicy", output.SyncPolicy)
+	add("shared", output.Shared)
+
+	return res
+}
+
+// Create write syncer of output without pool and wrap it with encryption and buffer of output, stdout and stderr are
+// never buffered. Buffered entries are encrypted together while buffer is flushed.
+func (loader *Loader) openBufferedOutput(output *OutputConfig) (zapcore.WriteSyncer, func(), error) {
+	buf, err := newWriteBuffer(output.Buffer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := loader.newOutputCipher(output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	syncer, closer, err := loader.openOutput(output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	syncer = wrapEncryption(aead, syncer)
+	if output.Path == "stdout" || output.Path == "stderr" {
+		return syncer, closer, nil
+	}
+
+	syncer, closer = buf.wrap(syncer, closer)
+	return syncer, closer, nil
+}
+
+// Key of output path in pool of write syncers, file paths are resolved into absolute paths
+func (loader *Loader) poolKey(path string) string {
+	if filePath, ok := toFilePath(path); ok {
+		if abs, err := loader.resolvePath(filePath); err == nil {
+			return abs
+		}
+	}
+
+	return path
+}
+
+// Create write syncer of output without pool
+func (loader *Loader) openOutput(output *OutputConfig) (zapcore.WriteSyncer, func(), error) {
+	if len(output.Path) == 0 {
+		return nil, nil, errors.New("output path is empty")
+	}
+
+	// writers registered with RegisterWriter are resolved before sinks of zap
+	if factory, u, ok := lookupWriter(output.Path); ok {
+		return openWriter(factory, u)
+	}
+
+	filePath, ok := toFilePath(output.Path)
+	if !ok {
+		return zap.Open(output.Path)
+	}
+
+	filePath, err := loader.resolvePath(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	perms, err := newFilePermissions(output.Permissions, loader.dirMode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy, err := newSyncPolicy(output.SyncPolicy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// templates like app-%Y%m%d-{pid}.log are expanded at open time, timed rotator expands them at rotate time as well
+	if err := loader.ensureDir(ExpandFilename(filePath, time.Now()), perms); err != nil {
+		return nil, nil, err
+	}
+
+	rotation := output.Rotation
+	if rotation == nil && output.Lumberjack != nil {
+		rotation = newLumberjackRotation(output.Lumberjack)
+	}
+
+	if rotation, err = loader.resolveHookFiles(rotation); err != nil {
+		return nil, nil, err
+	}
+
+	// files shared among processes are appended with a single write syscall per entry
+	if output.Shared != nil {
+		return openSharedFile(filePath, output.Shared, rotation, perms, policy)
+	}
+
+	// open file by ourselves since zap could not recognize file paths with drive letter
+	if rotation == nil {
+		file, err := perms.openFile(ExpandFilename(filePath, time.Now()))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		syncer, closer := policy.wrap(zapcore.Lock(file), func() { file.Close() })
+		return syncer, closer, nil
+	}
+
+	// files opened by rotator while rotating are created with permissions as well
+	if perms != nil {
+		withPerms := *rotation
+		withPerms.permissions = perms
+		rotation = &withPerms
+	}
+
+	rotator, err := newRotator(filePath, rotation, output.Archive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	syncer, closer := policy.wrap(&lockedRotator{rotator: rotator}, func() { rotator.Close() })
+	return syncer, closer, nil
+}
+
+// Create parent directories of file paths among output paths
+func (loader *Loader) ensureDirs(pathLists ...[]string) error {
+	for _, paths := range pathLists {
+		for i := range paths {
+			filePath, ok := toFilePath(paths[i])
+			if !ok {
+				continue
+			}
+
+			filePath, err := loader.resolvePath(filePath)
+			if err != nil {
+				return err
+			}
+
+			if err := loader.ensureDir(filePath, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Create parent directory of file path with permissions if it is enabled in Loader
+func (loader *Loader) ensureDir(filePath string, perms *filePermissions) error {
+	if !loader.createDirs {
+		return nil
+	}
+
+	dir := filepath.Dir(filePath)
+	if err := perms.mkdirAll(dir, loader.dirMode); err != nil {
+		return errors.Wrapf(err, "failed to create directory of output path, dir:%s", dir)
+	}
+
+	return nil
+}
+
+// Convert output path to file path which could be rotated by lumberjack
+// stdout, stderr and urls whose scheme is registered with zap.RegisterSink are not file paths,
+// urls with file scheme are converted to file paths of current OS.
+func toFilePath(outputPath string) (string, bool) {
+	if outputPath == "stdout" || outputPath == "stderr" {
+		return "", false
+	}
+
+	// path with volume name on windows, like C:\logs\rk-logger.log or \\host\share\rk-logger.log
+	if len(filepath.VolumeName(outputPath)) > 0 {
+		return filepath.Clean(outputPath), true
+	}
+
+	u, err := url.Parse(outputPath)
+	if err != nil {
+		// it is not an url, treat it as a file path
+		return filepath.Clean(outputPath), true
+	}
+
+	// single letter scheme is a drive letter, like C:/logs/rk-logger.log
+	if len(u.Scheme) < 2 {
+		return filepath.Clean(outputPath), true
+	}
+
+	if u.Scheme == "file" {
+		return fromFileURLPath(u.Path), true
+	}
+
+	return "", false
+}
+
+// Convert path of file url to file path of current OS
+// Path of file:///C:/logs/rk-logger.log is /C:/logs/rk-logger.log which should be C:\logs\rk-logger.log on windows
+func fromFileURLPath(urlPath string) string {
+	if runtime.GOOS == "windows" && len(urlPath) > 2 && urlPath[0] == '/' && urlPath[2] == ':' {
+		urlPath = urlPath[1:]
+	}
+
+	return filepath.FromSlash(urlPath)
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// CoreFactory creates core with url of output path and level enabler of zap config.
+// Entries of the core are not encoded by encoder of zap config, which fits services with structured entries,
+// like systemd journal. Core would be closed with logger if it implements io.Closer.
+type CoreFactory func(u url.URL, enabler zapcore.LevelEnabler) (zapcore.Core, error)
+
+var (
+	// core factories keyed by lower case scheme
+	coreFactories = make(map[string]CoreFactory)
+	coreMutex     sync.RWMutex
+)
+
+// RegisterCore registers factory of cores for output paths with scheme, like cloudlogging://project-id.
+//
+// Unlike RegisterWriter, output paths with the scheme do not work with zap.Config.Build() since zap only accepts sinks.
+// Error would be returned if the scheme is already registered as core or writer.
+func RegisterCore(scheme string, factory CoreFactory) error {
+	if factory == nil {
+		return errors.Errorf("core factory is nil, scheme:%s", scheme)
+	}
+
+	scheme = strings.ToLower(scheme)
+
+	if isWriterScheme(scheme) {
+		return errors.Errorf("writer is already registered, scheme:%s", scheme)
+	}
+
+	coreMutex.Lock()
+	defer coreMutex.Unlock()
+
+	if _, ok := coreFactories[scheme]; ok {
+		return errors.Errorf("core is already registered, scheme:%s", scheme)
+	}
+
+	coreFactories[scheme] = factory
+	return nil
+}
+
+// Check whether lower case scheme is registered as core
+func isCoreScheme(scheme string) bool {
+	coreMutex.RLock()
+	defer coreMutex.RUnlock()
+
+	_, ok := coreFactories[scheme]
+	return ok
+}
+
+// Find factory of cores registered with scheme of output path
+func lookupCore(outputPath string) (CoreFactory, *url.URL, bool) {
+	u, err := url.Parse(outputPath)
+	if err != nil || len(u.Scheme) < 2 {
+		return nil, nil, false
+	}
+
+	coreMutex.RLock()
+	defer coreMutex.RUnlock()
+
+	factory, ok := coreFactories[strings.ToLower(u.Scheme)]
+	return factory, u, ok
+}
+
+// Check whether any of output paths is written by registered core
+func hasCoreOutput(outputPaths []string) bool {
+	for i := range outputPaths {
+		if _, _, ok := lookupCore(outputPaths[i]); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Split outputs into outputs written by registered cores and the rest of outputs
+func splitCoreOutputs(outputs []*OutputConfig) ([]*OutputConfig, []*OutputConfig) {
+	cores := make([]*OutputConfig, 0)
+	others := make([]*OutputConfig, 0, len(outputs))
+	for i := range outputs {
+		if _, _, ok := lookupCore(outputs[i].Path); ok {
+			cores = append(cores, outputs[i])
+		} else {
+			others = append(others, outputs[i])
+		}
+	}
+
+	return cores, others
+}
+
+// Create core for each output, the returned function closes all of the cores
+func openCores(outputs []*OutputConfig, enabler zapcore.LevelEnabler) ([]zapcore.Core, func(), error) {
+	cores := make([]zapcore.Core, 0, len(outputs)+1)
+	unregisters := make([]func(), 0, len(outputs))
+	closeAll := func() {
+		for i := range unregisters {
+			unregisters[i]()
+		}
+
+		for i := range cores {
+			if closer, ok := cores[i].(io.Closer); ok {
+				closer.Close()
+			}
+		}
+	}
+
+	for i := range outputs {
+		factory, u, _ := lookupCore(outputs[i].Path)
+		if outputs[i].Fallback != nil {
+			closeAll()
+			return nil, nil, errors.Errorf("fallback is not supported by core outputs, path:%s", outputs[i].Path)
+		}
+
+		core, err := factory(*u, enabler)
+		if err != nil {
+			closeAll()
+			return nil, nil, errors.Wrapf(err, "failed to create core, scheme:%s", u.Scheme)
+		}
+
+		if core == nil {
+			closeAll()
+			return nil, nil, errors.Errorf("core is nil, scheme:%s", u.Scheme)
+		}
+
+		cores = append(cores, core)
+		unregisters = append(unregisters, registerDeliveryStats(u, core))
+	}
+
+	return cores, closeAll, nil
+}
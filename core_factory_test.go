@@ -0,0 +1,141 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"net/url"
+	"path"
+	"sync"
+	"testing"
+)
+
+// memoryCore records messages of entries and whether it is closed
+type memoryCore struct {
+	zapcore.LevelEnabler
+	query    url.Values
+	messages []string
+	closed   bool
+	mutex    sync.Mutex
+}
+
+// With implements zapcore.Core
+func (core *memoryCore) With(fields []zapcore.Field) zapcore.Core {
+	return core
+}
+
+// Check implements zapcore.Core
+func (core *memoryCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core
+func (core *memoryCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	core.mutex.Lock()
+	defer core.mutex.Unlock()
+
+	core.messages = append(core.messages, entry.Message)
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (core *memoryCore) Sync() error {
+	return nil
+}
+
+// Close implements io.Closer
+func (core *memoryCore) Close() error {
+	core.closed = true
+	return nil
+}
+
+var utCores = make(map[string]*memoryCore)
+
+func init() {
+	err := RegisterCore("ut-core", func(u url.URL, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+		if u.Query().Get("fail") == "true" {
+			return nil, errors.New("ut-error")
+		}
+
+		core := &memoryCore{LevelEnabler: enabler, query: u.Query()}
+		utCores[u.Query().Get("name")] = core
+		return core, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterCore_HappyCase(t *testing.T) {
+	// With duplicate scheme in different case
+	assert.NotNil(t, RegisterCore("UT-CORE", func(u url.URL, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+		return nil, nil
+	}))
+
+	// With scheme registered as writer
+	assert.NotNil(t, RegisterCore("ut-writer", func(u url.URL, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+		return nil, nil
+	}))
+
+	// With scheme registered as core
+	assert.NotNil(t, RegisterWriter("ut-core", func(u url.URL) (zapcore.WriteSyncer, error) {
+		return nil, nil
+	}))
+}
+
+func TestRegisterCore_WithNilFactory(t *testing.T) {
+	assert.NotNil(t, RegisterCore("ut-nil-core", nil))
+}
+
+func TestHasCoreOutput(t *testing.T) {
+	assert.True(t, hasCoreOutput([]string{"stdout", "journald://"}))
+	assert.True(t, hasCoreOutput([]string{"JOURNALD://"}))
+	assert.True(t, hasCoreOutput([]string{"ut-core://"}))
+	assert.False(t, hasCoreOutput([]string{"stdout", "journald.log", "ut-writer://"}))
+
+	cores, others := splitCoreOutputs(newOutputConfigs([]string{"stdout", "journald://", "ut-core://"}, nil))
+	assert.Len(t, cores, 2)
+	assert.Len(t, others, 1)
+}
+
+// Entries are written to registered core along with other outputs, core is closed with logger
+func TestNewZapLoggerWithCloser_WithCore(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	config := NewConfigWithOptions(WithLevel(zapcore.WarnLevel), WithOutput(filePath, "ut-core://?name=happy"))
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-info")
+	logger.Warn("ut-warn")
+	assert.Equal(t, []string{"ut-warn"}, utCores["happy"].messages)
+	assert.Contains(t, readFileContent(filePath), "ut-warn")
+
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.True(t, utCores["happy"].closed)
+}
+
+// With failure of core factory
+func TestNewZapLoggerWithConfig_WithFailedCore(t *testing.T) {
+	logger, err := NewZapLoggerWithConfig(NewConfigWithOptions(WithOutput("ut-core://?fail=true")))
+	assert.NotNil(t, err)
+	assert.Nil(t, logger)
+	assert.Contains(t, err.Error(), "ut-error")
+
+	// zap config is built with cores as well
+	zapConfig := NewZapStdoutConfig()
+	zapConfig.OutputPaths = []string{"ut-core://?name=zap"}
+	zapLogger, err := NewZapLoggerWithConf(zapConfig, nil)
+	assert.Nil(t, err)
+	zapLogger.Info("ut-info")
+	assert.Equal(t, []string{"ut-info"}, utCores["zap"].messages)
+}
@@ -0,0 +1,539 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Create a temp dir which would be removed after test
+func newTempDir(t testing.TB) string {
+	dir, err := ioutil.TempDir("", "rk-logger")
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	return dir
+}
+
+func TestNewOutputConfigs(t *testing.T) {
+	lumber := NewLumberjackConfigDefault()
+	outputs := newOutputConfigs([]string{"stdout", "ut.log"}, lumber)
+
+	assert.Len(t, outputs, 2)
+	assert.Equal(t, "stdout", outputs[0].Path)
+	assert.Equal(t, "ut.log", outputs[1].Path)
+	assert.Equal(t, lumber, outputs[1].Lumberjack)
+}
+
+func TestNewWriteSyncer_WithEmptyPath(t *testing.T) {
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{})
+	assert.Nil(t, syncer)
+	assert.NotNil(t, err)
+}
+
+func TestNewWriteSyncer_WithStdout(t *testing.T) {
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{Path: "stdout", Lumberjack: NewLumberjackConfigDefault()})
+	assert.NotNil(t, syncer)
+	assert.Nil(t, err)
+}
+
+func TestNewWriteSyncer_WithFileWithoutLumberjack(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{Path: filePath})
+	assert.Nil(t, err)
+	_, err = syncer.Write([]byte("ut"))
+	assert.Nil(t, err)
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut", string(content))
+}
+
+func TestNewWriteSyncer_WithInvalidFile(t *testing.T) {
+	// parent of output path is a file
+	parent := path.Join(newTempDir(t), "parent")
+	assert.Nil(t, ioutil.WriteFile(parent, []byte{}, 0644))
+
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{Path: path.Join(parent, "ut.log")})
+	assert.Nil(t, syncer)
+	assert.NotNil(t, err)
+
+	syncer, err = NewLoader(WithDirCreation(false)).newWriteSyncer(&OutputConfig{Path: path.Join(parent, "ut.log")})
+	assert.Nil(t, syncer)
+	assert.NotNil(t, err)
+}
+
+func TestNewWriteSyncer_WithDirCreation(t *testing.T) {
+	dir := newTempDir(t)
+
+	for _, lumber := range []*lumberjack.Logger{nil, NewLumberjackConfigDefault()} {
+		filePath := path.Join(dir, "nested", "dir", "ut.log")
+		syncer, closer, err := NewLoader(WithDirMode(0700)).openWriteSyncer(&OutputConfig{Path: filePath, Lumberjack: lumber})
+		assert.Nil(t, err)
+		_, err = syncer.Write([]byte("ut"))
+		assert.Nil(t, err)
+		closer()
+
+		info, err := os.Stat(path.Join(dir, "nested", "dir"))
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+		assert.Nil(t, os.RemoveAll(path.Join(dir, "nested")))
+	}
+}
+
+// Write syncers of file outputs with the same absolute path are shared by loggers in process
+func TestOpenWriteSyncer_WithSharedFile(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "ut.log")
+	lumber := NewLumberjackConfigDefault()
+
+	first, closeFirst, err := defaultLoader.openWriteSyncer(&OutputConfig{Path: filePath, Lumberjack: lumber})
+	assert.Nil(t, err)
+	second, closeSecond, err := NewLoader(WithBaseDir(dir)).openWriteSyncer(&OutputConfig{Path: "./nested/../ut.log", Lumberjack: lumber})
+	assert.Nil(t, err)
+	assert.True(t, first == second)
+
+	// write syncer is closed once all of loggers release it
+	closeFirst()
+	closeFirst()
+	assert.Contains(t, sharedWriteSyncers.syncers, filePath)
+	_, err = second.Write([]byte("ut-message\n"))
+	assert.Nil(t, err)
+
+	closeSecond()
+	assert.NotContains(t, sharedWriteSyncers.syncers, filePath)
+	assert.Equal(t, "ut-message\n", readFileContent(filePath))
+
+	// reopened write syncer replaces the current one, which is kept until it is released
+	first, closeFirst, err = defaultLoader.openWriteSyncer(&OutputConfig{Path: filePath, Lumberjack: lumber})
+	assert.Nil(t, err)
+	reopening := *defaultLoader
+	reopening.reopen = true
+	second, closeSecond, err = reopening.openWriteSyncer(&OutputConfig{Path: filePath, Lumberjack: lumber})
+	assert.Nil(t, err)
+	assert.False(t, first == second)
+
+	closeFirst()
+	assert.Contains(t, sharedWriteSyncers.syncers, filePath)
+	closeSecond()
+	assert.NotContains(t, sharedWriteSyncers.syncers, filePath)
+}
+
+// Write syncers of the same path are not shared among outputs with different settings
+func TestOpenWriteSyncer_WithDifferentSettings(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "ut.log")
+
+	_, closer, err := defaultLoader.openWriteSyncer(&OutputConfig{Path: filePath, Lumberjack: NewLumberjackConfigDefault()})
+	assert.Nil(t, err)
+	defer closer()
+
+	for _, output := range []*OutputConfig{
+		{Path: filePath},
+		{Path: filePath, Lumberjack: &lumberjack.Logger{MaxSize: 1}},
+		{Path: filePath, Lumberjack: NewLumberjackConfigDefault(), Buffer: &BufferConfig{Size: "64KB"}},
+		{Path: filePath, Lumberjack: NewLumberjackConfigDefault(), Permissions: &PermissionsConfig{FileMode: "0600"}},
+	} {
+		syncer, _, err := defaultLoader.openWriteSyncer(output)
+		assert.Nil(t, syncer)
+		assert.Contains(t, err.Error(), "output is already opened with different settings, path:"+filePath)
+	}
+
+	// With loader which has a pool of write syncers
+	_, _, err = NewLoader().openCombinedWriteSyncer([]*OutputConfig{
+		{Path: "stdout"},
+		{Path: filePath, Lumberjack: NewLumberjackConfigDefault(), SyncPolicy: &SyncPolicyConfig{Mode: SyncEveryWrite}},
+	})
+	assert.Contains(t, err.Error(), "settings:syncPolicy")
+}
+
+func TestNewWriteSyncer_WithoutDirCreation(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "nested", "ut.log")
+
+	syncer, err := NewLoader(WithDirCreation(false)).newWriteSyncer(&OutputConfig{Path: filePath})
+	assert.Nil(t, syncer)
+	assert.NotNil(t, err)
+}
+
+func TestEnsureDir_WithError(t *testing.T) {
+	parent := path.Join(newTempDir(t), "parent")
+	assert.Nil(t, ioutil.WriteFile(parent, []byte{}, 0644))
+
+	err := defaultLoader.ensureDir(path.Join(parent, "dir", "ut.log"), nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to create directory of output path")
+}
+
+// Parent directories are created before zap.Config.Build() as well
+func TestNewZapLoggerWithConf_WithDirCreation(t *testing.T) {
+	dir := newTempDir(t)
+	config := NewZapStdoutConfig()
+	config.OutputPaths = []string{path.Join(dir, "out", "ut.log")}
+	config.ErrorOutputPaths = []string{"stderr", path.Join(dir, "err", "ut.log")}
+
+	logger, err := NewZapLoggerWithConf(config, nil)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+
+	for _, sub := range []string{"out", "err"} {
+		info, err := os.Stat(path.Join(dir, sub))
+		assert.Nil(t, err)
+		assert.True(t, info.IsDir())
+	}
+
+	// error of creating directory is surfaced
+	parent := path.Join(dir, "parent")
+	assert.Nil(t, ioutil.WriteFile(parent, []byte{}, 0644))
+	config.OutputPaths = []string{path.Join(parent, "ut.log")}
+	logger, err = NewZapLoggerWithConf(config, nil)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestNewWriteSyncer_WithLumberjack(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{Path: filePath, Lumberjack: &lumberjack.Logger{MaxSize: 1}})
+	assert.Nil(t, err)
+	_, err = syncer.Write([]byte("ut"))
+	assert.Nil(t, err)
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut", string(content))
+}
+
+func TestNewCombinedWriteSyncer_WithInvalidOutput(t *testing.T) {
+	syncer, err := defaultLoader.newCombinedWriteSyncer([]*OutputConfig{{Path: "stdout"}, {}})
+	assert.Nil(t, syncer)
+	assert.NotNil(t, err)
+}
+
+func TestBuildZapLogger_WithInvalidOutput(t *testing.T) {
+	logger, err := defaultLoader.buildZapLogger(NewZapStdoutConfig(), []*OutputConfig{{}}, nil)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+
+	logger, err = defaultLoader.buildZapLogger(NewZapStdoutConfig(), nil, []*OutputConfig{{}})
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestBuildZapLogger_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	config := NewZapStdoutConfig()
+	config.InitialFields = map[string]interface{}{"initFieldKey": "fieldValue"}
+
+	logger, err := defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: filePath}}, newOutputConfigs([]string{"stderr"}, nil))
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	assert.Nil(t, logger.Sync())
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "ut-message")
+	assert.Contains(t, string(content), "fieldValue")
+}
+
+func TestBuildZapLogger_WithSampling(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	config := NewZapStdoutConfig()
+
+	sampled, dropped := 0, 0
+	config.Sampling = &zap.SamplingConfig{
+		Initial:    2,
+		Thereafter: 100,
+		Hook: func(entry zapcore.Entry, decision zapcore.SamplingDecision) {
+			if decision == zapcore.LogDropped {
+				dropped++
+			} else {
+				sampled++
+			}
+		},
+	}
+
+	logger, err := defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: filePath}}, nil)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		logger.Info("ut-message")
+	}
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "ut-message"))
+	assert.Equal(t, 2, sampled)
+	assert.Equal(t, 8, dropped)
+}
+
+// Logger with lumberjack samples the same way as logger built with zap.Config.Build()
+func TestNewZapLoggerWithConf_WithSampling(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	config := NewZapStdoutConfig()
+	config.OutputPaths = []string{filePath}
+	config.Sampling = &zap.SamplingConfig{Initial: 3, Thereafter: 100}
+
+	logger, err := NewZapLoggerWithConf(config, NewLumberjackConfigDefault())
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		logger.Info("ut-message")
+	}
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, strings.Count(string(content), "ut-message"))
+}
+
+// Initial fields are redacted and pseudonymized the same way as fields added with With(), summaries of rate limit
+// carry them as well
+func TestNewZapLoggerWithConf_WithRedactedInitialFields(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+  initialFields:
+    password: ut-password
+    card: "4111111111111111"
+    user_id: 42
+redaction:
+  rules:
+    - fields: [password]
+      action: drop
+    - patterns: [creditCard]
+      replacement: "****"
+pseudonymization:
+  fields: [user_id]
+  salt: ut-salt
+rateLimit:
+  perSecond: 0.001
+  burst: 1
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	logger.Info("ut-message")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	pseudonymizer, _ := NewLoader().newPseudonymizer(config.Pseudonymization)
+	fields := `"card":"****","user_id":"` + pseudonymizer.hash("42") + `"`
+	assert.Equal(t, `{"msg":"ut-message",`+fields+"}\n"+
+		`{"msg":"`+rateLimitSummaryMessage+`",`+fields+`,"suppressed":1}`+"\n", readFileContent(path.Join(dir, "app.log")))
+}
+
+func TestBuildZapLogger_WithCaller(t *testing.T) {
+	dir := newTempDir(t)
+	config := NewZapStdoutConfig()
+
+	// caller is added by default
+	filePath := path.Join(dir, "caller.log")
+	logger, err := defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: filePath}}, nil)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "core_test.go")
+
+	config.DisableCaller = true
+	filePath = path.Join(dir, "no-caller.log")
+	logger, err = defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: filePath}}, nil)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	content, err = ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), "core_test.go")
+}
+
+func TestBuildZapLogger_WithStacktrace(t *testing.T) {
+	dir := newTempDir(t)
+	config := NewZapStdoutConfig()
+	config.DisableStacktrace = false
+	config.Development = false
+
+	// stacktrace is added to error logs in production
+	filePath := path.Join(dir, "production.log")
+	logger, err := defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: filePath}}, nil)
+	assert.Nil(t, err)
+	logger.Warn("ut-warn")
+	logger.Error("ut-error")
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "TestBuildZapLogger_WithStacktrace"))
+
+	// stacktrace is added to warn logs in development
+	config.Development = true
+	filePath = path.Join(dir, "development.log")
+	logger, err = defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: filePath}}, nil)
+	assert.Nil(t, err)
+	logger.Warn("ut-warn")
+	logger.Error("ut-error")
+	content, err = ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "TestBuildZapLogger_WithStacktrace"))
+
+	config.DisableStacktrace = true
+	filePath = path.Join(dir, "disabled.log")
+	logger, err = defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: filePath}}, nil)
+	assert.Nil(t, err)
+	logger.Error("ut-error")
+	content, err = ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), "TestBuildZapLogger_WithStacktrace")
+}
+
+func TestBuildZapLogger_WithDevelopment(t *testing.T) {
+	config := NewZapStdoutConfig()
+	config.Development = true
+
+	logger, err := defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: path.Join(newTempDir(t), "ut.log")}}, nil)
+	assert.Nil(t, err)
+	assert.Panics(t, func() {
+		logger.DPanic("ut-message")
+	})
+
+	config.Development = false
+	logger, err = defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: path.Join(newTempDir(t), "ut.log")}}, nil)
+	assert.Nil(t, err)
+	assert.NotPanics(t, func() {
+		logger.DPanic("ut-message")
+	})
+}
+
+// Options of caller override options of zap config
+func TestBuildZapLogger_WithCallerOptions(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	config := NewZapStdoutConfig()
+
+	logger, err := defaultLoader.buildZapLogger(config, []*OutputConfig{{Path: filePath}}, nil, zap.WithCaller(false))
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), "core_test.go")
+}
+
+// Sink which keeps everything in memory, registered as ut-memory://
+type memorySink struct {
+	bytes.Buffer
+	synced bool
+	closed bool
+}
+
+func (sink *memorySink) Sync() error {
+	sink.synced = true
+	return nil
+}
+
+func (sink *memorySink) Close() error {
+	sink.closed = true
+	return nil
+}
+
+var (
+	utMemorySinks = make(map[string]*memorySink)
+	_             = zap.RegisterSink("ut-memory", func(u *url.URL) (zap.Sink, error) {
+		sink := &memorySink{}
+		utMemorySinks[u.Host] = sink
+		return sink, nil
+	})
+)
+
+func TestToFilePath(t *testing.T) {
+	filePath, ok := toFilePath("stdout")
+	assert.False(t, ok)
+
+	filePath, ok = toFilePath("stderr")
+	assert.False(t, ok)
+
+	filePath, ok = toFilePath("ut-memory://ut")
+	assert.False(t, ok)
+
+	filePath, ok = toFilePath("tcp://localhost:5170")
+	assert.False(t, ok)
+
+	filePath, ok = toFilePath("logs/rk-logger.log")
+	assert.True(t, ok)
+	assert.Equal(t, "logs/rk-logger.log", filePath)
+
+	filePath, ok = toFilePath("/var/log/rk-logger.log")
+	assert.True(t, ok)
+	assert.Equal(t, "/var/log/rk-logger.log", filePath)
+
+	filePath, ok = toFilePath("file:///var/log/rk-logger.log")
+	assert.True(t, ok)
+	assert.Equal(t, "/var/log/rk-logger.log", filePath)
+
+	filePath, ok = toFilePath("C:/logs/rk-logger.log")
+	assert.True(t, ok)
+	assert.Equal(t, "C:/logs/rk-logger.log", filePath)
+
+	filePath, ok = toFilePath("C:\\logs\\rk-logger.log")
+	assert.True(t, ok)
+	assert.Equal(t, "C:\\logs\\rk-logger.log", filePath)
+}
+
+func TestNewWriteSyncer_WithStderr(t *testing.T) {
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{Path: "stderr", Lumberjack: NewLumberjackConfigDefault()})
+	assert.NotNil(t, syncer)
+	assert.Nil(t, err)
+}
+
+func TestNewWriteSyncer_WithRegisteredSink(t *testing.T) {
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{Path: "ut-memory://sink", Lumberjack: NewLumberjackConfigDefault()})
+	assert.Nil(t, err)
+	_, err = syncer.Write([]byte("ut"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ut", utMemorySinks["sink"].String())
+}
+
+func TestNewWriteSyncer_WithUnregisteredSink(t *testing.T) {
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{Path: "ut-unknown://sink", Lumberjack: NewLumberjackConfigDefault()})
+	assert.Nil(t, syncer)
+	assert.NotNil(t, err)
+}
+
+func TestNewWriteSyncer_WithFileScheme(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	syncer, err := defaultLoader.newWriteSyncer(&OutputConfig{Path: "file://" + filePath, Lumberjack: NewLumberjackConfigDefault()})
+	assert.Nil(t, err)
+	_, err = syncer.Write([]byte("ut"))
+	assert.Nil(t, err)
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut", string(content))
+}
+
+func TestNewZapLoggerWithConf_WithRegisteredSink(t *testing.T) {
+	config := NewZapStdoutConfig()
+	config.OutputPaths = []string{"stderr", "ut-memory://logger"}
+
+	logger, err := NewZapLoggerWithConf(config, NewLumberjackConfigDefault())
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	assert.Contains(t, utMemorySinks["logger"].String(), "ut-message")
+}
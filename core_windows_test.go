@@ -0,0 +1,71 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+// +build windows
+
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestToFilePath_WithWindowsPath(t *testing.T) {
+	filePath, ok := toFilePath(`C:\logs\rk-logger.log`)
+	assert.True(t, ok)
+	assert.Equal(t, `C:\logs\rk-logger.log`, filePath)
+
+	filePath, ok = toFilePath(`C:/logs/rk-logger.log`)
+	assert.True(t, ok)
+	assert.Equal(t, `C:\logs\rk-logger.log`, filePath)
+
+	filePath, ok = toFilePath(`\\host\share\rk-logger.log`)
+	assert.True(t, ok)
+	assert.Equal(t, `\\host\share\rk-logger.log`, filePath)
+
+	filePath, ok = toFilePath(`logs\rk-logger.log`)
+	assert.True(t, ok)
+	assert.Equal(t, `logs\rk-logger.log`, filePath)
+
+	filePath, ok = toFilePath(`logs/rk-logger.log`)
+	assert.True(t, ok)
+	assert.Equal(t, `logs\rk-logger.log`, filePath)
+}
+
+func TestToFilePath_WithWindowsFileURL(t *testing.T) {
+	filePath, ok := toFilePath("file:///C:/logs/rk-logger.log")
+	assert.True(t, ok)
+	assert.Equal(t, `C:\logs\rk-logger.log`, filePath)
+}
+
+func TestToAbsoluteWorkingDir_WithWindowsPath(t *testing.T) {
+	abs, err := toAbsoluteWorkingDir(`C:\logs\..\rk-logger.log`)
+	assert.Nil(t, err)
+	assert.Equal(t, `C:\rk-logger.log`, abs)
+
+	abs, err = toAbsoluteWorkingDir(`logs\rk-logger.log`)
+	assert.Nil(t, err)
+	assert.True(t, filepath.IsAbs(abs))
+	assert.Equal(t, "rk-logger.log", filepath.Base(abs))
+}
+
+func TestNewWriteSyncer_WithWindowsPath(t *testing.T) {
+	for _, lumber := range []*OutputConfig{{}, {Lumberjack: NewLumberjackConfigDefault()}} {
+		filePath := filepath.Join(newTempDir(t), "ut.log")
+		lumber.Path = filePath
+
+		syncer, err := defaultLoader.newWriteSyncer(lumber)
+		assert.Nil(t, err)
+		_, err = syncer.Write([]byte("ut"))
+		assert.Nil(t, err)
+
+		content, err := ioutil.ReadFile(filePath)
+		assert.Nil(t, err)
+		assert.Equal(t, "ut", string(content))
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of schedules which could be used by RotationConfig.Schedule
+const (
+	// ScheduleDaily rotates files at midnight.
+	ScheduleDaily = "daily"
+	// ScheduleHourly rotates files at the beginning of every hour.
+	ScheduleHourly = "hourly"
+)
+
+// schedule returns the next time of rotation after t
+type schedule interface {
+	next(t time.Time) time.Time
+}
+
+// Parse schedule with name or cron expression, like daily, hourly or 0 */6 * * *
+func parseSchedule(spec string) (schedule, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case ScheduleDaily, "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case ScheduleHourly, "@hourly":
+		spec = "0 * * * *"
+	}
+
+	cron, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return cron, nil
+}
+
+// cronSchedule matches times with minute, hour, day of month, month and day of week
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// whether day of month or day of week is *, days are matched by either of them if neither is *
+	domStar, dowStar bool
+}
+
+// bounds of fields of cron expression
+var cronBounds = []struct{ min, max int }{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week, 0 and 7 are sunday
+}
+
+// Parse cron expression with five fields, fields are *, numbers, ranges like 1-5, lists like 1,15 and steps like */10
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronBounds) {
+		return nil, errors.Errorf("invalid cron expression, five fields are expected, schedule:%s", spec)
+	}
+
+	bits := make([]uint64, len(fields))
+	for i := range fields {
+		var err error
+		if bits[i], err = parseCronField(fields[i], cronBounds[i].min, cronBounds[i].max); err != nil {
+			return nil, errors.Wrapf(err, "invalid cron expression, schedule:%s", spec)
+		}
+	}
+
+	// sunday is either 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+// Parse field of cron expression into bits of matched values
+func parseCronField(field string, min, max int) (uint64, error) {
+	var res uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, errors.Errorf("invalid step, field:%s", field)
+			}
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, errors.Errorf("invalid value, field:%s", field)
+			}
+
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, errors.Errorf("invalid value, field:%s", field)
+				}
+			} else if step > 1 {
+				// like 5/15, which is from 5 to max
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, errors.Errorf("value out of range, field:%s", field)
+		}
+
+		for value := start; value <= end; value += step {
+			res |= 1 << uint(value)
+		}
+	}
+
+	return res, nil
+}
+
+// Whether day of t is matched by day of month and day of week
+func (cron *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := cron.dom&(1<<uint(t.Day())) != 0
+	dowMatch := cron.dow&(1<<uint(t.Weekday())) != 0
+
+	if cron.domStar || cron.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// Next implements schedule, zero time is returned if nothing matches in five years, like 0 0 30 2 *
+func (cron *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if cron.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !cron.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if cron.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if cron.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
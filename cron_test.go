@@ -0,0 +1,52 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_HappyCase(t *testing.T) {
+	now := time.Date(2020, 9, 1, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"daily", time.Date(2020, 9, 2, 0, 0, 0, 0, time.UTC)},
+		{"@midnight", time.Date(2020, 9, 2, 0, 0, 0, 0, time.UTC)},
+		{"hourly", time.Date(2020, 9, 1, 11, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2020, 9, 1, 10, 40, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2020, 9, 2, 2, 30, 0, 0, time.UTC)},
+		// 2020-09-06 is sunday
+		{"0 0 * * 7", time.Date(2020, 9, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week
+		{"0 0 15 * 0", time.Date(2020, 9, 6, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		schedule, err := parseSchedule(test.spec)
+		assert.Nil(t, err, test.spec)
+		assert.Equal(t, test.expected, schedule.next(now), test.spec)
+	}
+}
+
+func TestParseSchedule_WithInvalidSpec(t *testing.T) {
+	for _, spec := range []string{"", "weekly", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		schedule, err := parseSchedule(spec)
+		assert.NotNil(t, err, spec)
+		assert.Nil(t, schedule, spec)
+	}
+}
+
+func TestCronSchedule_WithoutMatch(t *testing.T) {
+	schedule, err := parseSchedule("0 0 30 2 *")
+	assert.Nil(t, err)
+	assert.True(t, schedule.next(time.Now()).IsZero())
+}
@@ -0,0 +1,855 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// CSVEncoding is the name of encoding which encodes entries as rows of comma separated values, like:
+	//
+	//	2020-09-01T10:00:00.000+0800,info,db/conn.go:42,connection is ready,10
+	//
+	// Columns are declared by csv section of config, see CSVConfig.
+	CSVEncoding = "csv"
+	// TSVEncoding is the name of encoding which encodes entries as rows of tab separated values, columns are declared
+	// by csv section of config the same way as csv encoding.
+	TSVEncoding = "tsv"
+
+	// CSVRestColumn is the column of fields which are not declared by other columns, fields are encoded as a json
+	// object.
+	CSVRestColumn = "*"
+)
+
+// pool of buffers returned by csv encoder, and value encoders of columns which are reused
+var (
+	csvPool      = buffer.NewPool()
+	csvValuePool = sync.Pool{New: func() interface{} {
+		return &csvValueEncoder{}
+	}}
+)
+
+func init() {
+	if err := RegisterEncoder(CSVEncoding, NewCSVEncoder); err != nil {
+		panic(err)
+	}
+
+	if err := RegisterEncoder(TSVEncoding, NewTSVEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// CSVConfig declares columns of csv and tsv encodings.
+//
+// Example config file in YAML:
+//
+//	zap:
+//	  encoding: csv
+//	  encoderConfig:
+//	    timeKey: ts
+//	    levelKey: level
+//	    callerKey: caller
+//	    messageKey: msg
+//	    timeEncoder: iso8601
+//	csv:
+//	  columns: [ts, level, caller, msg, user.id, "*"]
+type CSVConfig struct {
+	// Columns are keys of entries and fields in order, keys of entries are keys of encoderConfig, like ts or msg.
+	// Fields of objects and namespaces are referred with keys joined by dot, like user.id, and CSVRestColumn refers
+	// to fields which are not declared by other columns. Fields which are not declared are dropped unless
+	// CSVRestColumn is declared.
+	//
+	// Columns are keys of time, level, name, caller, function, message and stacktrace of encoderConfig followed
+	// by CSVRestColumn by default.
+	Columns []string `json:"columns" yaml:"columns"`
+	// Delimiter is the character which separates columns, comma for csv encoding and tab for tsv encoding
+	// by default.
+	Delimiter string `json:"delimiter" yaml:"delimiter"`
+}
+
+// csvEncoder encodes entries as rows, fields are captured by csvObject and mapped to columns, values of objects and
+// arrays are encoded by wrapped json encoder
+type csvEncoder struct {
+	// fields added with With()
+	*csvObject
+	config *zapcore.EncoderConfig
+	// json encoder of fields only
+	json      zapcore.Encoder
+	columns   []string
+	entryKeys map[string]bool
+	// columns other than CSVRestColumn, fields of which are not encoded by rest column again
+	declared   map[string]bool
+	delimiter  rune
+	lineEnding string
+	// delimiter encoded as string which is appended between columns
+	delimiterString string
+}
+
+// NewCSVEncoder creates encoder of comma separated values with encoder config and default columns,
+// see CSVConfig.Columns for details.
+func NewCSVEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return NewCSVEncoderWithConfig(CSVConfig{})(config)
+}
+
+// NewTSVEncoder creates encoder of tab separated values with encoder config and default columns,
+// see CSVConfig.Columns for details.
+func NewTSVEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return NewCSVEncoderWithConfig(CSVConfig{Delimiter: "\t"})(config)
+}
+
+// NewCSVEncoderWithConfig returns constructor of encoder whose columns and delimiter are declared by csvConfig,
+// which could be registered with RegisterEncoder.
+//
+// Keys of entries and encoders of encoderConfig are applied the same way as json encoding, values of objects
+// and arrays are encoded as json, and values of missing keys are empty.
+func NewCSVEncoderWithConfig(csvConfig CSVConfig) EncoderConstructor {
+	return func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		delimiter := ','
+		if len(csvConfig.Delimiter) > 0 {
+			r, size := utf8.DecodeRuneInString(csvConfig.Delimiter)
+			if size != len(csvConfig.Delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+				return nil, errors.Errorf("invalid delimiter of csv, delimiter:%q", csvConfig.Delimiter)
+			}
+			delimiter = r
+		}
+
+		entryKeys := make(map[string]bool)
+		for _, key := range []string{config.TimeKey, config.LevelKey, config.NameKey, config.CallerKey,
+			config.FunctionKey, config.MessageKey, config.StacktraceKey} {
+			if len(key) > 0 {
+				entryKeys[key] = true
+			}
+		}
+
+		columns := csvConfig.Columns
+		if len(columns) == 0 {
+			for _, key := range []string{config.TimeKey, config.LevelKey, config.NameKey, config.CallerKey,
+				config.FunctionKey, config.MessageKey, config.StacktraceKey} {
+				if entryKeys[key] {
+					columns = append(columns, key)
+				}
+			}
+			columns = append(columns, CSVRestColumn)
+		}
+
+		declared := make(map[string]bool)
+		for _, column := range columns {
+			if column != CSVRestColumn {
+				declared[column] = true
+			}
+		}
+
+		lineEnding := config.LineEnding
+		if len(lineEnding) == 0 {
+			lineEnding = zapcore.DefaultLineEnding
+		}
+
+		// json encoder encodes fields only
+		fieldsConfig := zapcore.EncoderConfig{
+			EncodeTime:     config.EncodeTime,
+			EncodeDuration: config.EncodeDuration,
+			LineEnding:     "\n",
+		}
+
+		return &csvEncoder{
+			csvObject:       &csvObject{},
+			config:          &config,
+			json:            zapcore.NewJSONEncoder(fieldsConfig),
+			columns:         append([]string{}, columns...),
+			entryKeys:       entryKeys,
+			declared:        declared,
+			delimiter:       delimiter,
+			lineEnding:      lineEnding,
+			delimiterString: string(delimiter),
+		}, nil
+	}
+}
+
+// Clone implements zapcore.Encoder
+func (enc *csvEncoder) Clone() zapcore.Encoder {
+	clone := *enc
+	clone.csvObject = enc.csvObject.clone(0)
+	return &clone
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *csvEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	obj := enc.csvObject.clone(len(fields))
+	for i := range fields {
+		fields[i].AddTo(obj)
+	}
+
+	row := csvPool.Get()
+	value := csvValuePool.Get().(*csvValueEncoder)
+	value.buf = csvPool.Get()
+	defer putCSVValueEncoder(value)
+
+	for i, column := range enc.columns {
+		if i > 0 {
+			row.AppendString(enc.delimiterString)
+		}
+
+		value.buf.Reset()
+		switch {
+		case column == CSVRestColumn:
+			enc.encodeRest(value, obj)
+		case enc.entryKeys[column]:
+			enc.encodeEntryColumn(value, column, entry)
+		default:
+			if v, ok := obj.lookup(column); ok {
+				enc.encodeValue(value, v)
+			}
+		}
+
+		enc.appendColumn(row, value.buf.Bytes())
+	}
+
+	row.AppendString(enc.lineEnding)
+	return row, nil
+}
+
+// Encode value of entry whose key is column, the same way as json encoding
+func (enc *csvEncoder) encodeEntryColumn(value *csvValueEncoder, column string, entry zapcore.Entry) {
+	config := enc.config
+	cur := value.buf.Len()
+
+	switch column {
+	case config.TimeKey:
+		enc.encodeTime(value, entry.Time)
+	case config.LevelKey:
+		if config.EncodeLevel != nil {
+			config.EncodeLevel(entry.Level, value)
+		}
+		if cur == value.buf.Len() {
+			value.AppendString(entry.Level.String())
+		}
+	case config.NameKey:
+		if len(entry.LoggerName) == 0 {
+			return
+		}
+		encodeName := config.EncodeName
+		if encodeName == nil {
+			encodeName = zapcore.FullNameEncoder
+		}
+		encodeName(entry.LoggerName, value)
+		if cur == value.buf.Len() {
+			value.AppendString(entry.LoggerName)
+		}
+	case config.CallerKey:
+		if !entry.Caller.Defined {
+			return
+		}
+		if config.EncodeCaller != nil {
+			config.EncodeCaller(entry.Caller, value)
+		}
+		if cur == value.buf.Len() {
+			value.AppendString(entry.Caller.String())
+		}
+	case config.FunctionKey:
+		if entry.Caller.Defined {
+			value.AppendString(entry.Caller.Function)
+		}
+	case config.MessageKey:
+		value.AppendString(entry.Message)
+	case config.StacktraceKey:
+		value.AppendString(entry.Stack)
+	}
+}
+
+// Encode time with time encoder of config, which falls back to nanoseconds since epoch the same way as json encoding
+func (enc *csvEncoder) encodeTime(value *csvValueEncoder, t time.Time) {
+	cur := value.buf.Len()
+	if enc.config.EncodeTime != nil {
+		enc.config.EncodeTime(t, value)
+	}
+	if cur == value.buf.Len() {
+		value.AppendInt64(t.UnixNano())
+	}
+}
+
+// Encode duration with duration encoder of config, which falls back to nanoseconds the same way as json encoding
+func (enc *csvEncoder) encodeDuration(value *csvValueEncoder, d time.Duration) {
+	cur := value.buf.Len()
+	if enc.config.EncodeDuration != nil {
+		enc.config.EncodeDuration(d, value)
+	}
+	if cur == value.buf.Len() {
+		value.AppendInt64(int64(d))
+	}
+}
+
+// Encode value captured by csvObject, objects, arrays and reflected values are encoded as json
+func (enc *csvEncoder) encodeValue(value *csvValueEncoder, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		value.AppendString(v)
+	case bool:
+		value.AppendBool(v)
+	case int64:
+		value.AppendInt64(v)
+	case uint64:
+		value.AppendUint64(v)
+	case float64:
+		value.AppendFloat64(v)
+	case float32:
+		value.AppendFloat32(v)
+	case complex128:
+		value.AppendComplex128(v)
+	case complex64:
+		value.AppendComplex64(v)
+	case time.Duration:
+		enc.encodeDuration(value, v)
+	case time.Time:
+		enc.encodeTime(value, v)
+	case []byte:
+		value.AppendString(base64.StdEncoding.EncodeToString(v))
+	case *csvObject:
+		enc.encodeJSON(value, zap.Object("", v))
+	case *csvArray:
+		enc.encodeJSON(value, zap.Array("", v))
+	case csvReflected:
+		if v.value != nil {
+			enc.encodeJSON(value, zap.Reflect("", v.value))
+		}
+	}
+}
+
+// Encode fields which are not declared by other columns as json object, nothing is encoded if there is no such field
+func (enc *csvEncoder) encodeRest(value *csvValueEncoder, obj *csvObject) {
+	rest := csvRest{enc: enc, obj: obj}
+	for i := range obj.fields {
+		if !rest.skip(obj.fields[i].key) {
+			enc.encodeJSON(value, zap.Object("", rest))
+			return
+		}
+	}
+}
+
+// Encode value of field with json encoder, the field is encoded as {"":value} and the value is sliced from it
+func (enc *csvEncoder) encodeJSON(value *csvValueEncoder, field zapcore.Field) {
+	encoded, err := enc.json.EncodeEntry(zapcore.Entry{}, []zapcore.Field{field})
+	if err != nil {
+		return
+	}
+	defer encoded.Free()
+
+	encoded.TrimNewline()
+	raw := encoded.Bytes()
+	value.buf.Write(raw[len(`{"":`) : len(raw)-1])
+}
+
+// Append column to buffer, it is quoted if it contains delimiter, quotes or line breaks, or starts with space
+func (enc *csvEncoder) appendColumn(buf *buffer.Buffer, column []byte) {
+	if !enc.needsQuotes(column) {
+		buf.Write(column)
+		return
+	}
+
+	buf.AppendByte('"')
+	for {
+		i := bytes.IndexByte(column, '"')
+		if i < 0 {
+			break
+		}
+
+		buf.Write(column[:i+1])
+		buf.AppendByte('"')
+		column = column[i+1:]
+	}
+	buf.Write(column)
+	buf.AppendByte('"')
+}
+
+// Whether column should be quoted, which is the same as csv.Writer
+func (enc *csvEncoder) needsQuotes(column []byte) bool {
+	if len(column) == 0 {
+		return false
+	}
+
+	if string(column) == `\.` || bytes.ContainsRune(column, enc.delimiter) || bytes.ContainsAny(column, "\"\r\n") {
+		return true
+	}
+
+	r, _ := utf8.DecodeRune(column)
+	return unicode.IsSpace(r)
+}
+
+// csvRest marshals fields of object which are not declared by other columns
+type csvRest struct {
+	enc *csvEncoder
+	obj *csvObject
+	// keys of enclosing objects joined by dot, empty for top level fields
+	prefix string
+}
+
+// Whether field of key is declared by other columns, fields at top level whose keys are keys of entries are skipped
+// as well
+func (rest csvRest) skip(key string) bool {
+	if len(rest.prefix) == 0 {
+		return rest.enc.entryKeys[key] || rest.enc.declared[key]
+	}
+	return rest.enc.declared[rest.prefix+key]
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (rest csvRest) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range rest.obj.fields {
+		if rest.skip(field.key) {
+			continue
+		}
+
+		if nested, ok := field.value.(*csvObject); ok {
+			enc.AddObject(field.key, csvRest{enc: rest.enc, obj: nested, prefix: rest.prefix + field.key + "."})
+			continue
+		}
+		addCSVValue(enc, field.key, field.value)
+	}
+	return nil
+}
+
+// Put value encoder back to pool with its buffer freed
+func putCSVValueEncoder(value *csvValueEncoder) {
+	value.buf.Free()
+	value.buf = nil
+	csvValuePool.Put(value)
+}
+
+// csvValueEncoder captures value of column appended by encoders of config, values are printed the same way as json
+// encoding without quotes
+type csvValueEncoder struct {
+	buf *buffer.Buffer
+}
+
+// AppendBool implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendBool(v bool) { value.buf.AppendBool(v) }
+
+// AppendByteString implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendByteString(v []byte) { value.buf.Write(v) }
+
+// AppendComplex128 implements zapcore.PrimitiveArrayEncoder, value is encoded as string like 1+2i
+func (value *csvValueEncoder) AppendComplex128(v complex128) {
+	value.buf.AppendFloat(real(v), 64)
+	value.buf.AppendByte('+')
+	value.buf.AppendFloat(imag(v), 64)
+	value.buf.AppendByte('i')
+}
+
+// AppendComplex64 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendComplex64(v complex64) { value.AppendComplex128(complex128(v)) }
+
+// AppendFloat64 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendFloat64(v float64) { value.appendFloat(v, 64) }
+
+// AppendFloat32 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendFloat32(v float32) { value.appendFloat(float64(v), 32) }
+
+// Append float the same way as json encoding
+func (value *csvValueEncoder) appendFloat(v float64, bitSize int) {
+	switch {
+	case math.IsNaN(v):
+		value.buf.AppendString("NaN")
+	case math.IsInf(v, 1):
+		value.buf.AppendString("+Inf")
+	case math.IsInf(v, -1):
+		value.buf.AppendString("-Inf")
+	default:
+		value.buf.AppendFloat(v, bitSize)
+	}
+}
+
+// AppendInt implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendInt(v int) { value.buf.AppendInt(int64(v)) }
+
+// AppendInt64 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendInt64(v int64) { value.buf.AppendInt(v) }
+
+// AppendInt32 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendInt32(v int32) { value.buf.AppendInt(int64(v)) }
+
+// AppendInt16 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendInt16(v int16) { value.buf.AppendInt(int64(v)) }
+
+// AppendInt8 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendInt8(v int8) { value.buf.AppendInt(int64(v)) }
+
+// AppendString implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendString(v string) { value.buf.AppendString(v) }
+
+// AppendUint implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendUint(v uint) { value.buf.AppendUint(uint64(v)) }
+
+// AppendUint64 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendUint64(v uint64) { value.buf.AppendUint(v) }
+
+// AppendUint32 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendUint32(v uint32) { value.buf.AppendUint(uint64(v)) }
+
+// AppendUint16 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendUint16(v uint16) { value.buf.AppendUint(uint64(v)) }
+
+// AppendUint8 implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendUint8(v uint8) { value.buf.AppendUint(uint64(v)) }
+
+// AppendUintptr implements zapcore.PrimitiveArrayEncoder
+func (value *csvValueEncoder) AppendUintptr(v uintptr) { value.buf.AppendUint(uint64(v)) }
+
+// csvField is field captured by csvObject
+type csvField struct {
+	key   string
+	value interface{}
+}
+
+// csvReflected is reflected value captured by csvObject and csvArray, which is encoded as json
+type csvReflected struct {
+	value interface{}
+}
+
+// csvObject implements zapcore.ObjectEncoder and captures fields in order with their types, values are string,
+// bool, int64, uint64, float64, float32, complex128, complex64, time.Duration, time.Time, []byte of binary,
+// *csvObject of objects and namespaces, *csvArray and csvReflected.
+//
+// It implements zapcore.ObjectMarshaler as well, which adds captured fields to another encoder.
+type csvObject struct {
+	fields []csvField
+	// innermost namespace opened, which is the last field of its enclosing object, nil if no namespace is opened
+	namespace *csvObject
+}
+
+// Add field to innermost namespace opened
+func (obj *csvObject) add(key string, value interface{}) {
+	target := obj
+	if obj.namespace != nil {
+		target = obj.namespace
+	}
+	target.fields = append(target.fields, csvField{key: key, value: value})
+}
+
+// Copy object with capacity of extra fields, namespaces opened are copied as well since fields are added to them,
+// other values are immutable once captured and shared
+func (obj *csvObject) clone(extra int) *csvObject {
+	res := &csvObject{fields: make([]csvField, len(obj.fields), len(obj.fields)+extra)}
+	copy(res.fields, obj.fields)
+	if obj.namespace == nil {
+		return res
+	}
+
+	src, dst := obj, res
+	for src != obj.namespace {
+		last := len(dst.fields) - 1
+		nested := src.fields[last].value.(*csvObject)
+		copied := &csvObject{fields: make([]csvField, len(nested.fields), len(nested.fields)+extra)}
+		copy(copied.fields, nested.fields)
+		dst.fields[last].value = copied
+		src, dst = nested, copied
+	}
+	res.namespace = dst
+	return res
+}
+
+// Find value of key, keys joined by dot refer to fields of objects and namespaces, the last field wins if there are
+// fields of the same key
+func (obj *csvObject) lookup(key string) (interface{}, bool) {
+	if value, ok := obj.find(key); ok {
+		return value, true
+	}
+
+	i := strings.IndexByte(key, '.')
+	if i < 0 {
+		return nil, false
+	}
+
+	if value, ok := obj.find(key[:i]); ok {
+		if nested, ok := value.(*csvObject); ok {
+			return nested.lookup(key[i+1:])
+		}
+	}
+
+	return nil, false
+}
+
+// Find value of field whose key is exactly key
+func (obj *csvObject) find(key string) (interface{}, bool) {
+	for i := len(obj.fields) - 1; i >= 0; i-- {
+		if obj.fields[i].key == key {
+			return obj.fields[i].value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (obj *csvObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range obj.fields {
+		addCSVValue(enc, field.key, field.value)
+	}
+	return nil
+}
+
+// AddArray implements zapcore.ObjectEncoder
+func (obj *csvObject) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := &csvArray{}
+	err := marshaler.MarshalLogArray(arr)
+	obj.add(key, arr)
+	return err
+}
+
+// AddObject implements zapcore.ObjectEncoder
+func (obj *csvObject) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	nested := &csvObject{}
+	err := marshaler.MarshalLogObject(nested)
+	obj.add(key, nested)
+	return err
+}
+
+// AddBinary implements zapcore.ObjectEncoder
+func (obj *csvObject) AddBinary(key string, value []byte) { obj.add(key, value) }
+
+// AddByteString implements zapcore.ObjectEncoder
+func (obj *csvObject) AddByteString(key string, value []byte) { obj.add(key, string(value)) }
+
+// AddBool implements zapcore.ObjectEncoder
+func (obj *csvObject) AddBool(key string, value bool) { obj.add(key, value) }
+
+// AddComplex128 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddComplex128(key string, value complex128) { obj.add(key, value) }
+
+// AddComplex64 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddComplex64(key string, value complex64) { obj.add(key, value) }
+
+// AddDuration implements zapcore.ObjectEncoder
+func (obj *csvObject) AddDuration(key string, value time.Duration) { obj.add(key, value) }
+
+// AddFloat64 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddFloat64(key string, value float64) { obj.add(key, value) }
+
+// AddFloat32 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddFloat32(key string, value float32) { obj.add(key, value) }
+
+// AddInt implements zapcore.ObjectEncoder
+func (obj *csvObject) AddInt(key string, value int) { obj.add(key, int64(value)) }
+
+// AddInt64 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddInt64(key string, value int64) { obj.add(key, value) }
+
+// AddInt32 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddInt32(key string, value int32) { obj.add(key, int64(value)) }
+
+// AddInt16 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddInt16(key string, value int16) { obj.add(key, int64(value)) }
+
+// AddInt8 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddInt8(key string, value int8) { obj.add(key, int64(value)) }
+
+// AddString implements zapcore.ObjectEncoder
+func (obj *csvObject) AddString(key, value string) { obj.add(key, value) }
+
+// AddTime implements zapcore.ObjectEncoder
+func (obj *csvObject) AddTime(key string, value time.Time) { obj.add(key, value) }
+
+// AddUint implements zapcore.ObjectEncoder
+func (obj *csvObject) AddUint(key string, value uint) { obj.add(key, uint64(value)) }
+
+// AddUint64 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddUint64(key string, value uint64) { obj.add(key, value) }
+
+// AddUint32 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddUint32(key string, value uint32) { obj.add(key, uint64(value)) }
+
+// AddUint16 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddUint16(key string, value uint16) { obj.add(key, uint64(value)) }
+
+// AddUint8 implements zapcore.ObjectEncoder
+func (obj *csvObject) AddUint8(key string, value uint8) { obj.add(key, uint64(value)) }
+
+// AddUintptr implements zapcore.ObjectEncoder
+func (obj *csvObject) AddUintptr(key string, value uintptr) { obj.add(key, uint64(value)) }
+
+// AddReflected implements zapcore.ObjectEncoder
+func (obj *csvObject) AddReflected(key string, value interface{}) error {
+	obj.add(key, csvReflected{value: value})
+	return nil
+}
+
+// OpenNamespace implements zapcore.ObjectEncoder, fields added afterwards are captured by the namespace
+func (obj *csvObject) OpenNamespace(key string) {
+	namespace := &csvObject{}
+	obj.add(key, namespace)
+	obj.namespace = namespace
+}
+
+// csvArray implements zapcore.ArrayEncoder and captures elements in order with their types the same way as
+// csvObject, it implements zapcore.ArrayMarshaler as well.
+type csvArray struct {
+	elems []interface{}
+}
+
+// MarshalLogArray implements zapcore.ArrayMarshaler
+func (arr *csvArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, elem := range arr.elems {
+		appendCSVValue(enc, elem)
+	}
+	return nil
+}
+
+// AppendArray implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	nested := &csvArray{}
+	err := marshaler.MarshalLogArray(nested)
+	arr.elems = append(arr.elems, nested)
+	return err
+}
+
+// AppendObject implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	nested := &csvObject{}
+	err := marshaler.MarshalLogObject(nested)
+	arr.elems = append(arr.elems, nested)
+	return err
+}
+
+// AppendReflected implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendReflected(value interface{}) error {
+	arr.elems = append(arr.elems, csvReflected{value: value})
+	return nil
+}
+
+// AppendBool implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendBool(value bool) { arr.elems = append(arr.elems, value) }
+
+// AppendByteString implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendByteString(value []byte) { arr.elems = append(arr.elems, string(value)) }
+
+// AppendComplex128 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendComplex128(value complex128) { arr.elems = append(arr.elems, value) }
+
+// AppendComplex64 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendComplex64(value complex64) { arr.elems = append(arr.elems, value) }
+
+// AppendDuration implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendDuration(value time.Duration) { arr.elems = append(arr.elems, value) }
+
+// AppendFloat64 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendFloat64(value float64) { arr.elems = append(arr.elems, value) }
+
+// AppendFloat32 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendFloat32(value float32) { arr.elems = append(arr.elems, value) }
+
+// AppendInt implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendInt(value int) { arr.elems = append(arr.elems, int64(value)) }
+
+// AppendInt64 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendInt64(value int64) { arr.elems = append(arr.elems, value) }
+
+// AppendInt32 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendInt32(value int32) { arr.elems = append(arr.elems, int64(value)) }
+
+// AppendInt16 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendInt16(value int16) { arr.elems = append(arr.elems, int64(value)) }
+
+// AppendInt8 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendInt8(value int8) { arr.elems = append(arr.elems, int64(value)) }
+
+// AppendString implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendString(value string) { arr.elems = append(arr.elems, value) }
+
+// AppendTime implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendTime(value time.Time) { arr.elems = append(arr.elems, value) }
+
+// AppendUint implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendUint(value uint) { arr.elems = append(arr.elems, uint64(value)) }
+
+// AppendUint64 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendUint64(value uint64) { arr.elems = append(arr.elems, value) }
+
+// AppendUint32 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendUint32(value uint32) { arr.elems = append(arr.elems, uint64(value)) }
+
+// AppendUint16 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendUint16(value uint16) { arr.elems = append(arr.elems, uint64(value)) }
+
+// AppendUint8 implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendUint8(value uint8) { arr.elems = append(arr.elems, uint64(value)) }
+
+// AppendUintptr implements zapcore.ArrayEncoder
+func (arr *csvArray) AppendUintptr(value uintptr) { arr.elems = append(arr.elems, uint64(value)) }
+
+// Add value captured by csvObject to encoder with its type
+func addCSVValue(enc zapcore.ObjectEncoder, key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		enc.AddString(key, v)
+	case bool:
+		enc.AddBool(key, v)
+	case int64:
+		enc.AddInt64(key, v)
+	case uint64:
+		enc.AddUint64(key, v)
+	case float64:
+		enc.AddFloat64(key, v)
+	case float32:
+		enc.AddFloat32(key, v)
+	case complex128:
+		enc.AddComplex128(key, v)
+	case complex64:
+		enc.AddComplex64(key, v)
+	case time.Duration:
+		enc.AddDuration(key, v)
+	case time.Time:
+		enc.AddTime(key, v)
+	case []byte:
+		enc.AddBinary(key, v)
+	case *csvObject:
+		enc.AddObject(key, v)
+	case *csvArray:
+		enc.AddArray(key, v)
+	case csvReflected:
+		enc.AddReflected(key, v.value)
+	}
+}
+
+// Append value captured by csvArray to encoder with its type
+func appendCSVValue(enc zapcore.ArrayEncoder, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		enc.AppendString(v)
+	case bool:
+		enc.AppendBool(v)
+	case int64:
+		enc.AppendInt64(v)
+	case uint64:
+		enc.AppendUint64(v)
+	case float64:
+		enc.AppendFloat64(v)
+	case float32:
+		enc.AppendFloat32(v)
+	case complex128:
+		enc.AppendComplex128(v)
+	case complex64:
+		enc.AppendComplex64(v)
+	case time.Duration:
+		enc.AppendDuration(v)
+	case time.Time:
+		enc.AppendTime(v)
+	case *csvObject:
+		enc.AppendObject(v)
+	case *csvArray:
+		enc.AppendArray(v)
+	case csvReflected:
+		enc.AppendReflected(v.value)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path"
+	"testing"
+	"time"
+)
+
+// Encoder config with keys of time, level, caller and message
+func newCSVEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:      "ts",
+		LevelKey:     "level",
+		CallerKey:    "caller",
+		MessageKey:   "msg",
+		EncodeTime:   zapcore.ISO8601TimeEncoder,
+		EncodeLevel:  zapcore.LowercaseLevelEncoder,
+		EncodeCaller: zapcore.ShortCallerEncoder,
+	}
+}
+
+// Encode entry with encoder
+func encodeCSV(t *testing.T, encoder zapcore.Encoder, entry zapcore.Entry, fields ...zapcore.Field) string {
+	buf, err := encoder.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	defer buf.Free()
+	return buf.String()
+}
+
+func TestCSVEncoder_EncodeEntry(t *testing.T) {
+	entry := zapcore.Entry{
+		Level:   zapcore.InfoLevel,
+		Time:    time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC),
+		Message: "connection is ready, pool is warm",
+		Caller:  zapcore.NewEntryCaller(0, "/src/db/conn.go", 42, true),
+	}
+
+	// With default columns
+	encoder, err := NewCSVEncoder(newCSVEncoderConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, `2020-09-01T10:00:00.000Z,info,db/conn.go:42,"connection is ready, pool is warm",`+
+		`"{""pool"":10,""tls"":true}"`+"\n",
+		encodeCSV(t, encoder, entry, zap.Int("pool", 10), zap.Bool("tls", true)))
+
+	// With declared columns, nested fields and missing fields
+	encoder, err = NewCSVEncoderWithConfig(CSVConfig{
+		Columns: []string{"msg", "user.id", "level", "missing", "tags", CSVRestColumn},
+	})(newCSVEncoderConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, `"connection is ready, pool is warm",1024,info,,"[""a"",""b""]","{""user"":{""name"":""admin""}}"`+"\n",
+		encodeCSV(t, encoder, entry,
+			zap.Object("user", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddInt("id", 1024)
+				enc.AddString("name", "admin")
+				return nil
+			})),
+			zap.Strings("tags", []string{"a", "b"})))
+
+	// Without rest column, undeclared fields are dropped
+	encoder, err = NewCSVEncoderWithConfig(CSVConfig{Columns: []string{"level", "msg"}})(newCSVEncoderConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, "info,\"connection is ready, pool is warm\"\n", encodeCSV(t, encoder, entry, zap.Int("pool", 10)))
+}
+
+func TestCSVEncoder_WithDelimiter(t *testing.T) {
+	config := newCSVEncoderConfig()
+	config.LineEnding = "\r\n"
+
+	encoder, err := NewTSVEncoder(config)
+	assert.Nil(t, err)
+
+	// fields added with With() are encoded along with fields of entry
+	encoder.AddString("app", "ut")
+	assert.Equal(t, "0001-01-01T00:00:00.000Z\tinfo\t\ta,b\t\"{\"\"app\"\":\"\"ut\"\",\"\"quote\"\":\"\"\\\"\"\"\"}\"\r\n",
+		encodeCSV(t, encoder.Clone(), zapcore.Entry{Message: "a,b"}, zap.String("quote", `"`)))
+
+	encoder, err = NewCSVEncoderWithConfig(CSVConfig{Delimiter: "|", Columns: []string{"msg", "app"}})(config)
+	assert.Nil(t, err)
+	assert.Equal(t, "a,b|\r\n", encodeCSV(t, encoder, zapcore.Entry{Message: "a,b"}))
+
+	// With invalid delimiters
+	for _, delimiter := range []string{`"`, "\n", "||"} {
+		encoder, err = NewCSVEncoderWithConfig(CSVConfig{Delimiter: delimiter})(config)
+		assert.Nil(t, encoder)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestCSVEncoder_WithFieldTypes(t *testing.T) {
+	config := newCSVEncoderConfig()
+	config.EncodeDuration = zapcore.StringDurationEncoder
+
+	encoder, err := NewCSVEncoderWithConfig(CSVConfig{
+		Columns: []string{"msg", "req.elapsed", "req.at", "req.ratio", "req.id", CSVRestColumn},
+	})(config)
+	assert.Nil(t, err)
+
+	// fields keep their order and types, and fields after namespace opened by With() are captured by it
+	encoder.AddString("zone", "z1")
+	encoder.OpenNamespace("req")
+	encoder.AddString("id", "r1")
+	assert.Equal(t, `ut-message,1.5s,2020-09-01T10:00:00.000Z,0.25,r1,`+
+		`"{""zone"":""z1"",""req"":{""method"":""GET"",""retries"":[1,2],""b"":true}}"`+"\n",
+		encodeCSV(t, encoder.Clone(), zapcore.Entry{Message: "ut-message"},
+			zap.Duration("elapsed", 1500*time.Millisecond),
+			zap.Time("at", time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)),
+			zap.String("method", "GET"),
+			zap.Ints("retries", []int{1, 2}),
+			zap.Float64("ratio", 0.25),
+			zap.Bool("b", true)))
+}
+
+func TestCSVEncoding_WithConfigFile(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: tsv
+  encoderConfig:
+    levelKey: level
+    messageKey: msg
+    levelEncoder: lowercase
+  outputPaths: ["` + filePath + `"]
+csv:
+  columns: [msg, user_id, level]
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"msg", "user_id", "level"}, config.CSV.Columns)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Warn("ut-message", zap.String("user_id", "u1"), zap.Int("dropped", 1))
+	logger.Sync()
+
+	assert.Equal(t, "ut-message\tu1\twarn\n", readFileContent(filePath))
+
+	// With invalid delimiter
+	config.CSV.Delimiter = "||"
+	logger, err = NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.Contains(t, err.Error(), "failed to create encoder, encoding:tsv")
+}
+
+func TestWithCSVEncoding(t *testing.T) {
+	config := NewConfigWithOptions(WithCSVEncoding("ts", "msg"))
+	assert.Equal(t, CSVEncoding, config.Zap.Encoding)
+	assert.Equal(t, []string{"ts", "msg"}, config.CSV.Columns)
+}
+
+func BenchmarkCSVEncoder(b *testing.B) {
+	enc, _ := NewCSVEncoder(zap.NewProductionEncoderConfig())
+	benchmarkEncoder(b, enc)
+}
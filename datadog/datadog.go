@@ -0,0 +1,287 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package datadog registers datadog scheme with rklogger.RegisterWriter, so that entries are sent to
+// HTTP logs intake of Datadog without agent by output paths like:
+//
+//	outputPaths:
+//	  - datadog://?apiKey=${DD_API_KEY}&service=api&source=go&tags=env:prod,team:payments
+//	  # intake of other sites
+//	  - datadog://?apiKey=${DD_API_KEY}&site=datadoghq.eu
+//
+// Import the package for side effects in order to enable the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/datadog"
+//
+// Host of url overrides host of intake, which is http-intake.logs.<site> by default.
+//
+// Query parameters:
+//
+//	apiKey:  API key of Datadog, which is required
+//	site:    site of Datadog, like datadoghq.com, datadoghq.eu or us3.datadoghq.com, datadoghq.com by default
+//	service: service of entries
+//	source:  source of entries, which decides integration pipeline
+//	tags:    tags of entries, like env:prod,team:payments
+//	host:    hostname of entries, hostname by default
+//	gzip:    compresses requests with gzip, true by default
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//	tls, tlsCA, tlsCert, tlsKey, tlsServerName and tlsSkipVerify of TLS, https is used unless tls=false
+//
+// Entries encoded as json are sent as attributes of Datadog entries, the rest of entries are sent as message.
+// Batches are limited to 1000 entries as intake requires. Sync the logger before serverless functions return,
+// otherwise queued entries would be lost.
+package datadog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/rookie-ninja/rk-logger/internal/tlsconfig"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+const (
+	// Scheme is the scheme of output paths which are sent to Datadog.
+	Scheme = "datadog"
+	// DefaultSite is the default site of Datadog.
+	DefaultSite = "datadoghq.com"
+	// MessageKey is the key of entries which holds entries not encoded as json.
+	MessageKey = "message"
+
+	// path of logs intake v2
+	intakePath = "/api/v2/logs"
+	// max number of entries in a request
+	maxEntries = 1000
+)
+
+func init() {
+	if err := rklogger.RegisterWriter(Scheme, newWriterWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// intake sends entries to logs intake, it is only used by the flushing goroutine of batch.Writer
+type intake struct {
+	endpoint string
+	apiKey   string
+	// reserved attributes added to every entry
+	attributes map[string]string
+	gzip       bool
+	client     *http.Client
+}
+
+// writer encodes entries with reserved attributes and sends them in batches
+type writer struct {
+	*batch.Writer
+	intake *intake
+}
+
+// Create writer with url of output path
+func newWriterWithURL(u url.URL) (zapcore.WriteSyncer, error) {
+	config, intake, err := parseURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWriter(config, intake), nil
+}
+
+// Create writer which sends batches with intake, size of batch is capped by limit of intake
+func newWriter(config batch.Config, intake *intake) *writer {
+	if config.Size <= 0 || config.Size > maxEntries {
+		config.Size = maxEntries
+	}
+
+	return &writer{
+		Writer: batch.NewWriter(config, intake.send),
+		intake: intake,
+	}
+}
+
+// Write implements zapcore.WriteSyncer, p is encoded as one entry of Datadog
+func (writer *writer) Write(p []byte) (int, error) {
+	if _, err := writer.Writer.Write(writer.intake.encodeEntry(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close flushes queued entries and closes idle connections
+func (writer *writer) Close() error {
+	err := writer.Writer.Close()
+	writer.intake.client.CloseIdleConnections()
+	return err
+}
+
+// Parse batching config and intake from url
+func parseURL(u *url.URL) (batch.Config, *intake, error) {
+	config := batch.DefaultConfig()
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, err
+	}
+
+	// https is used by default, unlike the rest of sinks, since intake only accepts https
+	secure := true
+	if value := query.Get("tls"); len(value) > 0 {
+		if secure, err = strconv.ParseBool(value); err != nil {
+			return config, nil, errors.Wrap(err, "invalid query of datadog url, key:tls")
+		}
+	}
+
+	tlsConfig, query, err := tlsconfig.FromQuery(query)
+	if err != nil {
+		return config, nil, err
+	}
+
+	intake := &intake{
+		attributes: make(map[string]string),
+		gzip:       true,
+	}
+
+	site, hostname := DefaultSite, ""
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		switch key {
+		case "apiKey":
+			intake.apiKey = value
+		case "site":
+			site = value
+		case "service":
+			intake.attributes["service"] = value
+		case "source":
+			intake.attributes["ddsource"] = value
+		case "tags":
+			intake.attributes["ddtags"] = value
+		case "host":
+			hostname = value
+		case "gzip":
+			if intake.gzip, err = strconv.ParseBool(value); err != nil {
+				return config, nil, errors.Wrapf(err, "invalid query of datadog url, key:%s", key)
+			}
+		default:
+			return config, nil, errors.Errorf("unknown query of datadog url, key:%s", key)
+		}
+	}
+
+	if len(intake.apiKey) == 0 {
+		return config, nil, errors.New("apiKey is required in datadog url")
+	}
+
+	if len(hostname) == 0 {
+		hostname, _ = os.Hostname()
+	}
+	intake.attributes["hostname"] = hostname
+
+	host := u.Host
+	if len(host) == 0 {
+		host = "http-intake.logs." + site
+	}
+
+	scheme := "http"
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if secure {
+		scheme = "https"
+		transport.TLSClientConfig = tlsConfig
+	}
+	intake.endpoint = fmt.Sprintf("%s://%s%s", scheme, host, intakePath)
+	intake.client = &http.Client{Transport: transport}
+
+	return config, intake, nil
+}
+
+// Encode entry as json object with reserved attributes, attributes of entry take precedence
+func (intake *intake) encodeEntry(p []byte) []byte {
+	p = bytes.TrimRight(p, "\r\n")
+
+	attributes := make(map[string]json.RawMessage)
+	if len(p) == 0 || p[0] != '{' || json.Unmarshal(p, &attributes) != nil {
+		attributes = make(map[string]json.RawMessage)
+		attributes[MessageKey], _ = json.Marshal(string(p))
+	}
+
+	for k, v := range intake.attributes {
+		if _, ok := attributes[k]; !ok {
+			attributes[k], _ = json.Marshal(v)
+		}
+	}
+
+	res, _ := json.Marshal(attributes)
+	return res
+}
+
+// Send entries as json array in one request
+func (intake *intake) send(ctx context.Context, entries [][]byte) error {
+	body := &bytes.Buffer{}
+	var w io.Writer = body
+	var zw *gzip.Writer
+	if intake.gzip {
+		zw = gzip.NewWriter(body)
+		w = zw
+	}
+
+	w.Write([]byte{'['})
+	for i := range entries {
+		if i > 0 {
+			w.Write([]byte{','})
+		}
+		w.Write(entries[i])
+	}
+	w.Write([]byte{']'})
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return batch.Permanent(err)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, intake.endpoint, body)
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("DD-API-KEY", intake.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	if intake.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := intake.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send entries, endpoint:%s", intake.endpoint)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusBadRequest {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	reply, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = errors.Errorf("entries are not accepted, endpoint:%s, status:%d, body:%s",
+		intake.endpoint, resp.StatusCode, reply)
+
+	// timeouts, throttling and unavailability are retried, the rest of failures would fail again
+	if resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= http.StatusInternalServerError {
+		return err
+	}
+
+	return batch.Permanent(err)
+}
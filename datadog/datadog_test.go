@@ -0,0 +1,184 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package datadog
+
+import (
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// intakeServer records requests of logs intake and replies with status in order
+type intakeServer struct {
+	*httptest.Server
+	bodies  []string
+	headers []http.Header
+	status  []int
+	mutex   sync.Mutex
+}
+
+// Start intake server
+func newIntakeServer(t *testing.T) *intakeServer {
+	server := &intakeServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, _ = gzip.NewReader(r.Body)
+		}
+		body, _ := ioutil.ReadAll(reader)
+		server.bodies = append(server.bodies, r.URL.Path+" "+string(body))
+		server.headers = append(server.headers, r.Header)
+
+		status := http.StatusAccepted
+		if len(server.status) > 0 {
+			status, server.status = server.status[0], server.status[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Create writer which sends entries to server
+func newTestWriter(t *testing.T, server *intakeServer, query string) *writer {
+	u, _ := url.Parse(strings.Replace(server.URL, "http", Scheme, 1) + "?tls=false&apiKey=ut-key&host=ut-host&" + query)
+	config, intake, err := parseURL(u)
+	assert.Nil(t, err)
+
+	return newWriter(config, intake)
+}
+
+func TestParseURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("datadog://?apiKey=key&site=datadoghq.eu&service=api&source=go&tags=env:prod&host=node&gzip=false&batchSize=5000")
+	config, intake, err := parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://http-intake.logs.datadoghq.eu/api/v2/logs", intake.endpoint)
+	assert.Equal(t, "key", intake.apiKey)
+	assert.Equal(t, map[string]string{
+		"service":  "api",
+		"ddsource": "go",
+		"ddtags":   "env:prod",
+		"hostname": "node",
+	}, intake.attributes)
+	assert.False(t, intake.gzip)
+	assert.Equal(t, 5000, config.Size)
+
+	// With defaults and host of intake
+	u, _ = url.Parse("datadog://intake.example.com?apiKey=key")
+	_, intake, err = parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://intake.example.com/api/v2/logs", intake.endpoint)
+	assert.True(t, intake.gzip)
+	assert.NotEmpty(t, intake.attributes["hostname"])
+}
+
+func TestParseURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"datadog://",
+		"datadog://?apiKey=key&gzip=x",
+		"datadog://?apiKey=key&tls=x",
+		"datadog://?apiKey=key&unknown=x",
+		"datadog://?apiKey=key&linger=x",
+	} {
+		u, _ := url.Parse(raw)
+		_, _, err := parseURL(u)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestIntake_EncodeEntry(t *testing.T) {
+	intake := &intake{attributes: map[string]string{"service": "api", "hostname": "node"}}
+
+	assert.Equal(t, `{"hostname":"node","msg":"a","service":"api"}`,
+		string(intake.encodeEntry([]byte(`{"msg":"a"}`+"\n"))))
+
+	// attributes of entry take precedence
+	assert.Equal(t, `{"hostname":"node","service":"web"}`,
+		string(intake.encodeEntry([]byte(`{"service":"web"}`))))
+
+	// With console encoding
+	assert.Equal(t, `{"hostname":"node","message":"INFO\thello","service":"api"}`,
+		string(intake.encodeEntry([]byte("INFO\thello\n"))))
+}
+
+func TestWriter_HappyCase(t *testing.T) {
+	server := newIntakeServer(t)
+	writer := newTestWriter(t, server, "linger=1h")
+
+	writer.Write([]byte(`{"msg":"a"}` + "\n"))
+	writer.Write([]byte(`{"msg":"b"}` + "\n"))
+	assert.Nil(t, writer.Close())
+
+	assert.Equal(t, []string{`/api/v2/logs [{"hostname":"ut-host","msg":"a"},{"hostname":"ut-host","msg":"b"}]`}, server.bodies)
+	assert.Equal(t, "ut-key", server.headers[0].Get("DD-API-KEY"))
+	assert.Equal(t, "gzip", server.headers[0].Get("Content-Encoding"))
+}
+
+func TestWriter_WithoutGzip(t *testing.T) {
+	server := newIntakeServer(t)
+	writer := newTestWriter(t, server, "gzip=false&linger=1h")
+
+	writer.Write([]byte(`{"msg":"a"}`))
+	assert.Nil(t, writer.Close())
+
+	assert.Empty(t, server.headers[0].Get("Content-Encoding"))
+	assert.Equal(t, []string{`/api/v2/logs [{"hostname":"ut-host","msg":"a"}]`}, server.bodies)
+}
+
+func TestWriter_WithCappedBatchSize(t *testing.T) {
+	server := newIntakeServer(t)
+	writer := newTestWriter(t, server, "batchSize=5000&linger=1h&gzip=false")
+
+	for i := 0; i < maxEntries+1; i++ {
+		writer.Write([]byte(`{"msg":"a"}`))
+	}
+	assert.Nil(t, writer.Close())
+
+	assert.Len(t, server.bodies, 2)
+	assert.Equal(t, `/api/v2/logs [{"hostname":"ut-host","msg":"a"}]`, server.bodies[1])
+}
+
+func TestWriter_WithRetries(t *testing.T) {
+	server := newIntakeServer(t)
+	server.status = []int{http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	writer := newTestWriter(t, server, "linger=1h&backoff=1ms")
+	defer writer.Close()
+
+	writer.Write([]byte(`{"msg":"a"}`))
+	assert.Nil(t, writer.Sync())
+	assert.Len(t, server.bodies, 4)
+}
+
+func TestWriter_WithRejectedEntries(t *testing.T) {
+	server := newIntakeServer(t)
+	server.status = []int{http.StatusForbidden}
+	writer := newTestWriter(t, server, "linger=1h&backoff=1ms")
+	defer writer.Close()
+
+	writer.Write([]byte(`{"msg":"a"}`))
+	err := writer.Sync()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status:403")
+	assert.Len(t, server.bodies, 1)
+}
+
+func TestRegister(t *testing.T) {
+	sink, closeFunc, err := zap.Open("datadog://?apiKey=key")
+	assert.Nil(t, err)
+	assert.NotNil(t, sink)
+	closeFunc()
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDedupWindow is the default time window of collapsing identical consecutive entries.
+	DefaultDedupWindow = 10 * time.Second
+	// DefaultRepeatedKey is the key of count of repeated entries if DedupConfig.RepeatedKey is not provided.
+	DefaultRepeatedKey = "repeated"
+)
+
+// DedupConfig collapses identical consecutive entries within time window into one entry, like "last message repeated
+// N times" of syslog. Entries are identical if their levels, logger names, messages and fields are the same. The first
+// entry is logged as it is, and repeats of it are logged as one entry carrying number of repeats once a different entry
+// is logged, the window is passed or logger is synced.
+//
+// Example config file in YAML:
+//
+//	dedup:
+//	  window: 10s
+//	  repeatedKey: repeated
+type DedupConfig struct {
+	// Window is the time window which repeats are collapsed in, like 30s, DefaultDedupWindow if not provided.
+	Window string `json:"window,omitempty" yaml:"window,omitempty"`
+	// RepeatedKey is the key of number of repeats, DefaultRepeatedKey if not provided.
+	RepeatedKey string `json:"repeatedKey,omitempty" yaml:"repeatedKey,omitempty"`
+}
+
+// encoder config of hashing entries, time, caller and stacktrace are ignored
+var dedupEncoderConfig = zapcore.EncoderConfig{
+	LevelKey:       "level",
+	NameKey:        "logger",
+	MessageKey:     "msg",
+	EncodeLevel:    zapcore.LowercaseLevelEncoder,
+	EncodeTime:     zapcore.EpochNanosTimeEncoder,
+	EncodeDuration: zapcore.NanosDurationEncoder,
+}
+
+// deduplicator keeps the last entry and its repeats, which is shared by cores created by With()
+type deduplicator struct {
+	window      time.Duration
+	repeatedKey string
+
+	mutex sync.Mutex
+	last  *dedupEntry
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// dedupEntry is the last entry written by core
+type dedupEntry struct {
+	hash    uint64
+	startAt time.Time
+	entry   zapcore.Entry
+	fields  []zapcore.Field
+	// core which the entry is written to, including fields added with With()
+	core     zapcore.Core
+	repeated int
+}
+
+// Create deduplicator with config, nil is returned if config is nil
+func newDeduplicator(config *DedupConfig) (*deduplicator, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	res := &deduplicator{
+		window:      DefaultDedupWindow,
+		repeatedKey: config.RepeatedKey,
+	}
+
+	if len(res.repeatedKey) == 0 {
+		res.repeatedKey = DefaultRepeatedKey
+	}
+
+	if len(config.Window) > 0 {
+		window, err := time.ParseDuration(config.Window)
+		if err != nil || window <= 0 {
+			return nil, errors.Errorf("invalid window of dedup, window:%s", config.Window)
+		}
+		res.window = window
+	}
+
+	return res, nil
+}
+
+// Wrap core with deduplicator and start flushing repeats of expired windows in background,
+// core is returned as it is if deduplicator is nil
+func (dedup *deduplicator) wrap(core zapcore.Core) zapcore.Core {
+	if dedup == nil {
+		return core
+	}
+
+	dedup.stop = make(chan struct{})
+	dedup.done = make(chan struct{})
+	go dedup.flushOnInterval()
+
+	return &dedupCore{
+		Core:  core,
+		dedup: dedup,
+		enc:   zapcore.NewJSONEncoder(dedupEncoderConfig),
+	}
+}
+
+// Stop flushing in background and flush repeats of the last entry,
+// it is safe to be called on nil deduplicator or deduplicator which is not started
+func (dedup *deduplicator) close() {
+	if dedup == nil || dedup.stop == nil {
+		return
+	}
+
+	dedup.once.Do(func() {
+		close(dedup.stop)
+		<-dedup.done
+
+		dedup.mutex.Lock()
+		defer dedup.mutex.Unlock()
+		dedup.flush()
+	})
+}
+
+// Flush repeats of the last entry if its window is passed, until stopped
+func (dedup *deduplicator) flushOnInterval() {
+	defer close(dedup.done)
+
+	ticker := time.NewTicker(dedup.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-dedup.stop:
+			return
+		case now := <-ticker.C:
+			dedup.mutex.Lock()
+			if dedup.last != nil && now.Sub(dedup.last.startAt) >= dedup.window {
+				dedup.flush()
+			}
+			dedup.mutex.Unlock()
+		}
+	}
+}
+
+// Write repeats of the last entry as one entry and forget it, lock should be held by caller
+func (dedup *deduplicator) flush() {
+	last := dedup.last
+	if last == nil {
+		return
+	}
+
+	dedup.last = nil
+	if last.repeated > 0 {
+		writeChecked(last.core, last.entry, append(last.fields, zap.Int(dedup.repeatedKey, last.repeated)))
+	}
+}
+
+// Write entry to core if it is enabled by core, errors of core are handled by checked entry
+func writeChecked(core zapcore.Core, entry zapcore.Entry, fields []zapcore.Field) {
+	if checked := core.Check(entry, nil); checked != nil {
+		checked.Write(fields...)
+	}
+}
+
+// Hash bytes with FNV-64a without allocations
+func fnv64a(p []byte) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+
+	hash := uint64(offset64)
+	for _, b := range p {
+		hash ^= uint64(b)
+		hash *= prime64
+	}
+
+	return hash
+}
+
+// dedupCore collapses identical consecutive entries, entries are checked by wrapped core while being written,
+// so that repeats are counted before sampling and rate limit
+type dedupCore struct {
+	zapcore.Core
+	dedup *deduplicator
+	// encoder of fields added with With(), which is used for hashing entries
+	enc zapcore.Encoder
+}
+
+// With implements zapcore.Core, the last entry is shared with cores created by With()
+func (core *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := core.enc.Clone()
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	return &dedupCore{
+		Core:  core.Core.With(fields),
+		dedup: core.dedup,
+		enc:   enc,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *dedupCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core
+func (core *dedupCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	hashed := zapcore.Entry{Level: entry.Level, LoggerName: entry.LoggerName, Message: entry.Message}
+	buf, err := core.enc.EncodeEntry(hashed, fields)
+	if err != nil {
+		return err
+	}
+	hash := fnv64a(buf.Bytes())
+	buf.Free()
+
+	dedup := core.dedup
+	dedup.mutex.Lock()
+	defer dedup.mutex.Unlock()
+
+	// entries above error level are never collapsed since logger panics or exits afterwards
+	if last := dedup.last; last != nil && last.hash == hash && entry.Level <= zapcore.ErrorLevel &&
+		entry.Time.Sub(last.startAt) < dedup.window {
+		last.entry = entry
+		last.repeated++
+		return nil
+	}
+
+	dedup.flush()
+	dedup.last = &dedupEntry{
+		hash:    hash,
+		startAt: entry.Time,
+		entry:   entry,
+		fields:  append([]zapcore.Field(nil), fields...),
+		core:    core.Core,
+	}
+
+	writeChecked(core.Core, entry, fields)
+	return nil
+}
+
+// Sync implements zapcore.Core, repeats of the last entry are written before syncing
+func (core *dedupCore) Sync() error {
+	core.dedup.mutex.Lock()
+	core.dedup.flush()
+	core.dedup.mutex.Unlock()
+
+	return core.Core.Sync()
+}
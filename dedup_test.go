@@ -0,0 +1,122 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDeduplicator(t *testing.T) {
+	// nil config
+	dedup, err := newDeduplicator(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, dedup)
+
+	// defaults
+	dedup, err = newDeduplicator(&DedupConfig{})
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultDedupWindow, dedup.window)
+	assert.Equal(t, DefaultRepeatedKey, dedup.repeatedKey)
+
+	// invalid windows
+	for _, window := range []string{"1x", "-1s"} {
+		_, err := newDeduplicator(&DedupConfig{Window: window})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestDedupCore_Write(t *testing.T) {
+	dedup, err := newDeduplicator(&DedupConfig{Window: "1h", RepeatedKey: "ut-repeated"})
+	assert.Nil(t, err)
+	defer dedup.close()
+
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := dedup.wrap(observed)
+	withField := core.With([]zapcore.Field{zap.String("ut-key", "ut-value")})
+
+	write := func(core zapcore.Core, msg string, now time.Time, fields ...zapcore.Field) {
+		entry := zapcore.Entry{Level: zapcore.InfoLevel, Message: msg, Time: now}
+		if checked := core.Check(entry, nil); checked != nil {
+			checked.Write(fields...)
+		}
+	}
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		write(core, "ut-repeat", now.Add(time.Duration(i)*time.Second))
+	}
+	assert.Equal(t, 1, logs.Len())
+
+	// fields of With() and entries make entries different
+	write(withField, "ut-repeat", now)
+	write(core, "ut-repeat", now, zap.Int("ut-field", 1))
+
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 4)
+	assert.Equal(t, map[string]interface{}{"ut-repeated": int64(2)}, entries[1].ContextMap())
+	assert.Equal(t, now.Add(2*time.Second), entries[1].Time)
+	assert.Equal(t, map[string]interface{}{"ut-key": "ut-value"}, entries[2].ContextMap())
+	assert.Equal(t, map[string]interface{}{"ut-field": int64(1)}, entries[3].ContextMap())
+
+	// repeats after window are logged as new entries
+	write(core, "ut-repeat", now, zap.Int("ut-field", 1))
+	write(core, "ut-repeat", now.Add(time.Hour), zap.Int("ut-field", 1))
+	entries = logs.TakeAll()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, map[string]interface{}{"ut-field": int64(1), "ut-repeated": int64(1)}, entries[0].ContextMap())
+
+	// repeats are written on sync
+	write(core, "ut-repeat", now.Add(time.Hour), zap.Int("ut-field", 1))
+	assert.Nil(t, core.Sync())
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestDedup_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+dedup:
+  window: 1h
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		logger.Info("ut-repeat")
+	}
+	logger.Debug("ut-debug")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	content := readFileContent(path.Join(dir, "app.log"))
+	assert.Equal(t, `{"msg":"ut-repeat"}`+"\n"+`{"msg":"ut-repeat","repeated":4}`+"\n", content)
+	assert.False(t, strings.Contains(content, "ut-debug"))
+
+	// invalid window
+	config.Dedup.Window = "ut-window"
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestWithDedup(t *testing.T) {
+	config := NewConfigWithOptions(WithDedup("30s"))
+	assert.Equal(t, &DedupConfig{Window: "30s"}, config.Dedup)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+var (
+	// delivery counters of outputs which are not closed
+	deliveryReporters = make(map[*deliveryReporter]struct{})
+	deliveryMutex     sync.Mutex
+)
+
+// DeliveryStats is the delivery counters of remote output, like kafka or tcp outputs.
+// Batches of entries are considered sent only if they are acknowledged by remote services.
+type DeliveryStats struct {
+	// Output is the output path without user info and query.
+	Output string `json:"output" yaml:"output"`
+	// Sent is the number of batches which were sent.
+	Sent uint64 `json:"sent" yaml:"sent"`
+	// Retried is the number of retries of failed batches.
+	Retried uint64 `json:"retried" yaml:"retried"`
+	// Abandoned is the number of batches which were not sent after retries or rejected by remote services.
+	Abandoned uint64 `json:"abandoned" yaml:"abandoned"`
+	// Dropped is the number of entries dropped by overflow policy.
+	Dropped uint64 `json:"dropped" yaml:"dropped"`
+}
+
+// ListDeliveryStats returns delivery counters of remote outputs of opened loggers in order of outputs,
+// which could be exported as metrics.
+func ListDeliveryStats() []DeliveryStats {
+	deliveryMutex.Lock()
+	res := make([]DeliveryStats, 0, len(deliveryReporters))
+	for reporter := range deliveryReporters {
+		stats := reporter.stats.Stats()
+		res = append(res, DeliveryStats{
+			Output:    reporter.output,
+			Sent:      stats.Sent,
+			Retried:   stats.Retried,
+			Abandoned: stats.Abandoned,
+			Dropped:   stats.Dropped,
+		})
+	}
+	deliveryMutex.Unlock()
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Output < res[j].Output
+	})
+
+	return res
+}
+
+// statser is implemented by write syncers and cores which send entries with batch.Writer
+type statser interface {
+	Stats() batch.Stats
+}
+
+// deliveryReporter reports delivery counters of output
+type deliveryReporter struct {
+	output string
+	stats  statser
+}
+
+// Register delivery counters of output if v implements statser, the returned function unregisters them
+func registerDeliveryStats(u *url.URL, v interface{}) func() {
+	stats, ok := v.(statser)
+	if !ok {
+		return func() {}
+	}
+
+	// user info and query may contain credentials
+	output := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	reporter := &deliveryReporter{output: output.String(), stats: stats}
+
+	deliveryMutex.Lock()
+	deliveryReporters[reporter] = struct{}{}
+	deliveryMutex.Unlock()
+
+	return func() {
+		deliveryMutex.Lock()
+		delete(deliveryReporters, reporter)
+		deliveryMutex.Unlock()
+	}
+}
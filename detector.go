@@ -0,0 +1,152 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// extensions of config files which could be recognized while detecting file type
+	fileTypeExtensions = map[string]FileType{
+		".json": JSON,
+		".yaml": YAML,
+		".yml":  YAML,
+		".toml": TOML,
+		".hcl":  HCL,
+	}
+
+	// utf-8 byte order mark which some editors would prepend to config files
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// FileTypeDetectionError is returned when config file type could not be inferred
+// neither from file extension nor from file content.
+type FileTypeDetectionError struct {
+	FilePath string
+}
+
+// Error implements error interface.
+func (e *FileTypeDetectionError) Error() string {
+	return fmt.Sprintf("failed to detect config file type, filePath:%s", e.FilePath)
+}
+
+// DetectFileType infers config file type from extension of file path,
+// falls back to sniffing content if extension is missing or unknown.
+// FileTypeDetectionError would be returned if detection fails.
+func DetectFileType(filePath string, raw []byte) (FileType, error) {
+	if fileType, ok := fileTypeExtensions[strings.ToLower(filepath.Ext(filePath))]; ok {
+		return fileType, nil
+	}
+
+	if fileType, ok := sniffFileType(raw); ok {
+		return fileType, nil
+	}
+
+	return JSON, &FileTypeDetectionError{FilePath: filePath}
+}
+
+// NewZapLoggerWithConfPathAuto inits zap logger with config file path
+// whose file type is detected by DetectFileType
+func NewZapLoggerWithConfPathAuto(filePath string, opts ...zap.Option) (*zap.Logger, *zap.Config, error) {
+	return defaultLoader.NewZapLoggerWithConfPathAuto(filePath, opts...)
+}
+
+// NewZapLoggerWithConfPathAuto inits zap logger with config file path and options of Loader
+// whose file type is detected by DetectFileType
+func (loader *Loader) NewZapLoggerWithConfPathAuto(filePath string, opts ...zap.Option) (*zap.Logger, *zap.Config, error) {
+	raw, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// sniff the content which would be actually unmarshalled
+	fileType, err := DetectFileType(filePath, loader.preprocess(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return loader.NewZapLoggerWithBytes(raw, fileType, opts...)
+}
+
+// NewLumberjackLoggerWithConfPathAuto inits lumberjack logger with config file path
+// whose file type is detected by DetectFileType
+func NewLumberjackLoggerWithConfPathAuto(filePath string) (*lumberjack.Logger, error) {
+	return defaultLoader.NewLumberjackLoggerWithConfPathAuto(filePath)
+}
+
+// NewLumberjackLoggerWithConfPathAuto inits lumberjack logger with config file path and options of Loader
+// whose file type is detected by DetectFileType
+func (loader *Loader) NewLumberjackLoggerWithConfPathAuto(filePath string) (*lumberjack.Logger, error) {
+	raw, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// sniff the content which would be actually unmarshalled
+	fileType, err := DetectFileType(filePath, loader.preprocess(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.NewLumberjackLoggerWithBytes(raw, fileType)
+}
+
+// Read config file after validating file path
+func readConfigFile(filePath string) ([]byte, error) {
+	if len(filePath) == 0 {
+		return nil, errors.New("file path is empty")
+	}
+
+	if err := validateFilePath(filePath); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(filePath)
+}
+
+// Sniff file type from content
+// Formats are tried from the strictest to the loosest one, since a flat toml file is also a valid hcl file
+// and plenty of content could be parsed as yaml
+func sniffFileType(raw []byte) (FileType, bool) {
+	content := bytes.TrimSpace(bytes.TrimPrefix(raw, utf8BOM))
+	if len(content) == 0 {
+		return JSON, false
+	}
+
+	if (content[0] == '{' || content[0] == '[') && json.Valid(content) {
+		return JSON, true
+	}
+
+	if bytes.HasPrefix(content, []byte("---")) {
+		return YAML, true
+	}
+
+	if _, err := toml.Decode(string(content), &map[string]interface{}{}); err == nil {
+		return TOML, true
+	}
+
+	if err := hcl.Unmarshal(content, &map[string]interface{}{}); err == nil {
+		return HCL, true
+	}
+
+	if err := yaml.Unmarshal(content, &map[string]interface{}{}); err == nil {
+		return YAML, true
+	}
+
+	return JSON, false
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// Write content into a file without extension
+func writeTempConfigFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "rk-logger")
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	filePath := path.Join(dir, "config")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	return filePath
+}
+
+func TestDetectFileType_WithExtension(t *testing.T) {
+	fileType, err := DetectFileType("/tmp/zap.json", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, JSON, fileType)
+
+	fileType, err = DetectFileType("/tmp/zap.yaml", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, YAML, fileType)
+
+	fileType, err = DetectFileType("/tmp/zap.YML", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, YAML, fileType)
+
+	fileType, err = DetectFileType("/tmp/zap.toml", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, TOML, fileType)
+
+	fileType, err = DetectFileType("/tmp/zap.hcl", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, HCL, fileType)
+}
+
+func TestDetectFileType_WithJsonContent(t *testing.T) {
+	fileType, err := DetectFileType("/tmp/zap", []byte("\xEF\xBB\xBF  {\"level\": \"info\"}"))
+	assert.Nil(t, err)
+	assert.Equal(t, JSON, fileType)
+}
+
+func TestDetectFileType_WithYamlContent(t *testing.T) {
+	fileType, err := DetectFileType("/tmp/zap", []byte("---\nlevel: info"))
+	assert.Nil(t, err)
+	assert.Equal(t, YAML, fileType)
+
+	fileType, err = DetectFileType("/tmp/zap", []byte("level: info\noutputPaths:\n  - stdout"))
+	assert.Nil(t, err)
+	assert.Equal(t, YAML, fileType)
+}
+
+func TestDetectFileType_WithTomlContent(t *testing.T) {
+	fileType, err := DetectFileType("/tmp/zap", []byte("level = \"info\"\n[encoderConfig]\nmessageKey = \"msg\""))
+	assert.Nil(t, err)
+	assert.Equal(t, TOML, fileType)
+}
+
+func TestDetectFileType_WithHclContent(t *testing.T) {
+	fileType, err := DetectFileType("/tmp/zap", []byte("level = \"info\"\nencoderConfig {\n  messageKey = \"msg\"\n}"))
+	assert.Nil(t, err)
+	assert.Equal(t, HCL, fileType)
+}
+
+func TestDetectFileType_WithUnknownContent(t *testing.T) {
+	fileType, err := DetectFileType("/tmp/zap.conf", []byte("this is not a config file"))
+	assert.Equal(t, JSON, fileType)
+	assert.NotNil(t, err)
+	assert.IsType(t, &FileTypeDetectionError{}, err)
+	assert.Contains(t, err.Error(), "/tmp/zap.conf")
+}
+
+func TestDetectFileType_WithEmptyContent(t *testing.T) {
+	_, err := DetectFileType("/tmp/zap", []byte("  "))
+	assert.IsType(t, &FileTypeDetectionError{}, err)
+}
+
+// With empty file path
+func TestNewZapLoggerWithConfPathAuto_WithEmptyString(t *testing.T) {
+	logger, config, err := NewZapLoggerWithConfPathAuto("")
+	assert.Nil(t, logger)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// With non exist file path
+func TestNewZapLoggerWithConfPathAuto_WithNonExistFilePath(t *testing.T) {
+	logger, config, err := NewZapLoggerWithConfPathAuto("/NonExistExpected.invalid")
+	assert.Nil(t, logger)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// With undetectable file
+func TestNewZapLoggerWithConfPathAuto_WithUnknownContent(t *testing.T) {
+	logger, config, err := NewZapLoggerWithConfPathAuto(writeTempConfigFile(t, "this is not a config file"))
+	assert.Nil(t, logger)
+	assert.Nil(t, config)
+	assert.IsType(t, &FileTypeDetectionError{}, err)
+}
+
+// Happy case with extensions
+func TestNewZapLoggerWithConfPathAuto_HappyCase(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	for _, name := range []string{"zap.yaml", "zap.toml", "zap.hcl"} {
+		logger, config, err := NewZapLoggerWithConfPathAuto(path.Join(dir, "assets", name))
+		assert.NotNil(t, logger)
+		assert.NotNil(t, config)
+		assert.Nil(t, err)
+		assert.Equal(t, "messagea", config.EncoderConfig.MessageKey)
+	}
+}
+
+// Happy case without extension
+func TestNewZapLoggerWithConfPathAuto_WithoutExtension(t *testing.T) {
+	logger, config, err := NewZapLoggerWithConfPathAuto(writeTempConfigFile(t, `{"level": "warn", "outputPaths": ["stdout"]}`))
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "warn", config.Level.String())
+}
+
+// With undetectable file
+func TestNewLumberjackLoggerWithConfPathAuto_WithUnknownContent(t *testing.T) {
+	logger, err := NewLumberjackLoggerWithConfPathAuto(writeTempConfigFile(t, "this is not a config file"))
+	assert.Nil(t, logger)
+	assert.IsType(t, &FileTypeDetectionError{}, err)
+}
+
+// Happy case
+func TestNewLumberjackLoggerWithConfPathAuto_HappyCase(t *testing.T) {
+	logger, err := NewLumberjackLoggerWithConfPathAuto(writeTempConfigFile(t, "maxsize = 10\nmaxage = 7"))
+	assert.Nil(t, err)
+	assert.Equal(t, 10, logger.MaxSize)
+	assert.Equal(t, 7, logger.MaxAge)
+}
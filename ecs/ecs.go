@@ -0,0 +1,206 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package ecs registers ecs encoding with rklogger.RegisterEncoder, so that entries are encoded as documents of
+// Elastic Common Schema and indexed by Elasticsearch without mutation, by config like:
+//
+//	encoding: ecs
+//	outputPaths:
+//	  - stdout
+//	  - elasticsearch://es1:9200/logs-{yyyy.MM.dd}
+//
+// Import the package for side effects in order to enable the encoding:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/ecs"
+//
+// Entries are encoded like:
+//
+//	{"@timestamp":"2020-09-01T10:00:00.000Z","log.level":"error","message":"failed to query","ecs.version":"1.12.0",
+//	 "log.logger":"db","log.origin.file.name":"db/conn.go","log.origin.file.line":42,
+//	 "error.message":"timeout","error.type":"*errors.fundamental","labels":{"user_id":"1"}}
+//
+// Fields named by ECS field sets, like trace.id or http.request.method, are kept as they are, other fields are
+// nested under labels with dots replaced by underscores, objects and arrays in labels are encoded as json strings.
+package ecs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Encoding is the name of encoding which encodes entries as ECS documents.
+	Encoding = "ecs"
+	// Version is the version of ECS which documents conform to.
+	Version = "1.12.0"
+
+	// layout of @timestamp, which is in UTC with milliseconds
+	timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+	// key of error fields added by zap.Error
+	errorKey = "error"
+)
+
+// ECS field sets which fields with dotted keys are kept at top level for
+var fieldSets = map[string]bool{
+	"agent": true, "client": true, "cloud": true, "container": true, "destination": true, "dns": true,
+	"error": true, "event": true, "file": true, "host": true, "http": true, "log": true, "network": true,
+	"orchestrator": true, "organization": true, "process": true, "server": true, "service": true,
+	"source": true, "span": true, "trace": true, "transaction": true, "url": true, "user": true,
+	"user_agent": true,
+}
+
+// pool of buffers returned by encoder
+var bufferPool = buffer.NewPool()
+
+func init() {
+	if err := rklogger.RegisterEncoder(Encoding, NewEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// encoder encodes entries as ECS documents, fields are encoded by wrapped json encoder and mapped afterwards
+type encoder struct {
+	zapcore.Encoder
+	lineEnding string
+}
+
+// NewEncoder creates encoder of ECS documents with encoder config, only duration, time and line ending settings
+// are applied, keys of message, level, time, logger name, caller and stacktrace are defined by ECS.
+func NewEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	lineEnding := config.LineEnding
+	if len(lineEnding) == 0 {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+
+	// entries are encoded by ECS, wrapped encoder encodes fields only
+	config.MessageKey, config.LevelKey, config.TimeKey = "", "", ""
+	config.NameKey, config.CallerKey, config.StacktraceKey = "", "", ""
+	config.FunctionKey = ""
+	if config.EncodeTime == nil {
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+	if config.EncodeDuration == nil {
+		config.EncodeDuration = zapcore.StringDurationEncoder
+	}
+
+	return &encoder{
+		Encoder:    zapcore.NewJSONEncoder(config),
+		lineEnding: lineEnding,
+	}, nil
+}
+
+// Clone implements zapcore.Encoder
+func (enc *encoder) Clone() zapcore.Encoder {
+	return &encoder{
+		Encoder:    enc.Encoder.Clone(),
+		lineEnding: enc.lineEnding,
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *encoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := enc.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded.Bytes()))
+	decoder.UseNumber()
+	values := make(map[string]interface{})
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	doc := make(map[string]interface{}, len(values)+8)
+	labels := make(map[string]interface{})
+
+	// error field is mapped to error field set, type is only known for fields of entry
+	if msg, ok := values[errorKey].(string); ok {
+		doc["error.message"] = msg
+		delete(values, errorKey)
+	}
+	if stack, ok := values[errorKey+"Verbose"].(string); ok {
+		doc["error.stack_trace"] = stack
+		delete(values, errorKey+"Verbose")
+	}
+	for i := range fields {
+		if fields[i].Key == errorKey && fields[i].Type == zapcore.ErrorType {
+			doc["error.type"] = fmt.Sprintf("%T", fields[i].Interface)
+		}
+	}
+
+	for k, v := range values {
+		if isFieldSetKey(k) {
+			doc[k] = v
+			continue
+		}
+		addLabel(labels, k, v)
+	}
+
+	doc["@timestamp"] = formatTimestamp(entry.Time)
+	doc["log.level"] = rklogger.LevelName(entry.Level)
+	doc["message"] = entry.Message
+	doc["ecs.version"] = Version
+	if len(entry.LoggerName) > 0 {
+		doc["log.logger"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		doc["log.origin.file.name"] = strings.TrimSuffix(entry.Caller.TrimmedPath(), ":"+strconv.Itoa(entry.Caller.Line))
+		doc["log.origin.file.line"] = entry.Caller.Line
+		if len(entry.Caller.Function) > 0 {
+			doc["log.origin.function"] = entry.Caller.Function
+		}
+	}
+	if _, ok := doc["error.stack_trace"]; !ok && len(entry.Stack) > 0 {
+		doc["error.stack_trace"] = entry.Stack
+	}
+	if len(labels) > 0 {
+		doc["labels"] = labels
+	}
+
+	res, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufferPool.Get()
+	buf.Write(res)
+	buf.AppendString(enc.lineEnding)
+	return buf, nil
+}
+
+// Whether key is a dotted field of ECS field set, like trace.id
+func isFieldSetKey(key string) bool {
+	i := strings.IndexByte(key, '.')
+	return i > 0 && i < len(key)-1 && fieldSets[key[:i]]
+}
+
+// Add value as label, dots in name are replaced with underscore since labels are flat in ECS.
+// Values other than strings, numbers and booleans are added as json.
+func addLabel(labels map[string]interface{}, name string, value interface{}) {
+	name = strings.ReplaceAll(name, ".", "_")
+
+	switch v := value.(type) {
+	case nil:
+	case string, json.Number, bool:
+		labels[name] = v
+	default:
+		raw, _ := json.Marshal(v)
+		labels[name] = string(raw)
+	}
+}
+
+// Format time as @timestamp of ECS, which is UTC with milliseconds
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
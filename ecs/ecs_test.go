@@ -0,0 +1,129 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Encode entry with ecs encoder and decode the document
+func encodeDocument(t *testing.T, enc zapcore.Encoder, entry zapcore.Entry, fields ...zapcore.Field) map[string]interface{} {
+	buf, err := enc.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	defer buf.Free()
+
+	assert.Equal(t, byte('\n'), buf.Bytes()[buf.Len()-1])
+
+	doc := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &doc))
+	return doc
+}
+
+func TestEncoder_EncodeEntry(t *testing.T) {
+	enc, err := NewEncoder(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2020, 9, 1, 18, 0, 0, 123456789, time.FixedZone("ut", 8*3600)),
+		LoggerName: "db",
+		Message:    "ut-message",
+		Caller:     zapcore.NewEntryCaller(0, "/src/db/conn.go", 42, true),
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"@timestamp":           "2020-09-01T10:00:00.123Z",
+		"log.level":            "warn",
+		"message":              "ut-message",
+		"ecs.version":          Version,
+		"log.logger":           "db",
+		"log.origin.file.name": "db/conn.go",
+		"log.origin.file.line": float64(42),
+		"trace.id":             "ut-trace",
+		"user.id":              "ut-user",
+		"labels": map[string]interface{}{
+			"customer_id": "ut-customer",
+			"attempt":     float64(2),
+			"enabled":     true,
+			"request":     `{"path":"/"}`,
+		},
+	}, encodeDocument(t, enc, entry,
+		zap.String("trace.id", "ut-trace"),
+		zap.String("user.id", "ut-user"),
+		zap.String("customer.id", "ut-customer"),
+		zap.Int("attempt", 2),
+		zap.Bool("enabled", true),
+		zap.Any("request", map[string]string{"path": "/"}),
+	))
+}
+
+func TestEncoder_WithError(t *testing.T) {
+	enc, err := NewEncoder(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	doc := encodeDocument(t, enc, zapcore.Entry{Message: "ut-message"}, zap.Error(errors.New("ut-error")))
+	assert.Equal(t, "ut-error", doc["error.message"])
+	assert.Equal(t, "*errors.fundamental", doc["error.type"])
+	assert.True(t, strings.HasPrefix(doc["error.stack_trace"].(string), "ut-error\n"))
+	assert.NotContains(t, doc, "labels")
+
+	// With stacktrace of entry
+	doc = encodeDocument(t, enc, zapcore.Entry{Message: "ut-message", Stack: "ut-stack"}, zap.Error(fmt.Errorf("ut-error")))
+	assert.Equal(t, "*errors.errorString", doc["error.type"])
+	assert.Equal(t, "ut-stack", doc["error.stack_trace"])
+}
+
+// Fields added with With() are mapped as well
+func TestEncoder_Clone(t *testing.T) {
+	enc, err := NewEncoder(zapcore.EncoderConfig{LineEnding: "\r\n"})
+	assert.Nil(t, err)
+	enc.AddString("service.name", "ut-service")
+
+	clone := enc.Clone()
+	clone.AddString("app", "ut")
+
+	buf, err := clone.EncodeEntry(zapcore.Entry{Message: "ut-message"}, nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(buf.String(), "\r\n"))
+
+	doc := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "ut-service", doc["service.name"])
+	assert.Equal(t, map[string]interface{}{"app": "ut"}, doc["labels"])
+}
+
+// Encoding is registered with rklogger
+func TestRegister(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "ecs")
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "ut.log")
+
+	raw := []byte(fmt.Sprintf(`---
+level: info
+encoding: ecs
+outputPaths: ["%s"]
+`, filePath))
+
+	logger, _, err := rklogger.NewZapLoggerWithBytes(raw, rklogger.YAML)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `"ecs.version":"`+Version+`"`)
+	assert.Contains(t, string(content), `"message":"ut-message"`)
+}
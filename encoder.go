@@ -0,0 +1,60 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+)
+
+// EncoderConstructor creates encoder with encoder config of zap config.
+type EncoderConstructor func(config zapcore.EncoderConfig) (zapcore.Encoder, error)
+
+var (
+	// encoder constructors keyed by encoding name
+	encoderConstructors = make(map[string]EncoderConstructor)
+	encoderMutex        sync.RWMutex
+)
+
+// RegisterEncoder registers constructor of encoder with name, so that config files could refer to it
+// with encoding, like encoding: mycompany-encoder.
+//
+// The encoder is registered with zap.RegisterEncoder as well, so that it works with zap.Config.Build() too.
+// Error would be returned if the name is already registered, including json and console.
+func RegisterEncoder(name string, constructor EncoderConstructor) error {
+	if len(name) == 0 {
+		return errors.New("encoder name is empty")
+	}
+
+	if constructor == nil {
+		return errors.Errorf("encoder constructor is nil, name:%s", name)
+	}
+
+	encoderMutex.Lock()
+	defer encoderMutex.Unlock()
+
+	if _, ok := encoderConstructors[name]; ok {
+		return errors.Errorf("encoder is already registered, name:%s", name)
+	}
+
+	if err := zap.RegisterEncoder(name, constructor); err != nil {
+		return errors.Wrapf(err, "failed to register encoder, name:%s", name)
+	}
+
+	encoderConstructors[name] = constructor
+	return nil
+}
+
+// Find constructor of encoder registered with name
+func lookupEncoder(name string) (EncoderConstructor, bool) {
+	encoderMutex.RLock()
+	defer encoderMutex.RUnlock()
+
+	constructor, ok := encoderConstructors[name]
+	return constructor, ok
+}
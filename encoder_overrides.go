@@ -0,0 +1,191 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"strconv"
+	"strings"
+)
+
+var (
+	// time encoders keyed by normalized names, see normalizeEncoderName
+	timeEncoders = map[string]zapcore.TimeEncoder{
+		"rfc3339":     zapcore.RFC3339TimeEncoder,
+		"rfc3339nano": zapcore.RFC3339NanoTimeEncoder,
+		"iso8601":     zapcore.ISO8601TimeEncoder,
+		"epoch":       zapcore.EpochTimeEncoder,
+		"seconds":     zapcore.EpochTimeEncoder,
+		"epochmillis": zapcore.EpochMillisTimeEncoder,
+		"millis":      zapcore.EpochMillisTimeEncoder,
+		"epochnanos":  zapcore.EpochNanosTimeEncoder,
+		"nanos":       zapcore.EpochNanosTimeEncoder,
+	}
+
+	// duration encoders keyed by normalized names
+	durationEncoders = map[string]zapcore.DurationEncoder{
+		"string":  zapcore.StringDurationEncoder,
+		"seconds": zapcore.SecondsDurationEncoder,
+		"millis":  zapcore.MillisDurationEncoder,
+		"ms":      zapcore.MillisDurationEncoder,
+		"nanos":   zapcore.NanosDurationEncoder,
+	}
+
+	// level encoders keyed by normalized names
+	levelEncoders = map[string]zapcore.LevelEncoder{
+		"capital":        zapcore.CapitalLevelEncoder,
+		"capitalcolor":   zapcore.CapitalColorLevelEncoder,
+		"lowercase":      zapcore.LowercaseLevelEncoder,
+		"lower":          zapcore.LowercaseLevelEncoder,
+		"lowercasecolor": zapcore.LowercaseColorLevelEncoder,
+		"color":          zapcore.LowercaseColorLevelEncoder,
+	}
+)
+
+// EncoderOverrides renames keys and replaces encoders of encoderConfig in zap config, which is applied to loggers
+// built with or without lumberjack config, so that keys of a preset could be renamed without restating the whole
+// encoderConfig.
+//
+// Example config file in YAML:
+//
+//	preset: production
+//	encoder:
+//	  keys:
+//	    msg: message
+//	    ts: timestamp
+//	    level: severity
+//	  timeEncoder: rfc3339nano
+//	  durationEncoder: millis
+//	  levelEncoder: capital-color
+//	  callerTrimPrefix: github.com/mycompany/app/
+//	  callerSegments: 3
+//	  functionKey: func
+type EncoderOverrides struct {
+	// Keys rename keys of encoderConfig, keyed by current keys, like msg: message.
+	Keys map[string]string `json:"keys" yaml:"keys"`
+	// TimeEncoder is rfc3339, rfc3339nano, iso8601, epoch, epoch-millis or epoch-nanos.
+	TimeEncoder string `json:"timeEncoder" yaml:"timeEncoder"`
+	// DurationEncoder is string, seconds, millis or nanos.
+	DurationEncoder string `json:"durationEncoder" yaml:"durationEncoder"`
+	// LevelEncoder is capital, capital-color, lowercase or lowercase-color.
+	LevelEncoder string `json:"levelEncoder" yaml:"levelEncoder"`
+	// CallerTrimPrefix strips path of caller up to and including prefix, like module path github.com/mycompany/app/.
+	CallerTrimPrefix string `json:"callerTrimPrefix" yaml:"callerTrimPrefix"`
+	// CallerSegments keeps the last segments of path of caller, like 2 for db/conn.go:42, the whole path if zero.
+	CallerSegments int `json:"callerSegments" yaml:"callerSegments"`
+	// FunctionKey is the key of function name of caller, which is emitted as a separate field if provided.
+	FunctionKey string `json:"functionKey" yaml:"functionKey"`
+}
+
+// Apply overrides to encoder config
+func (overrides *EncoderOverrides) apply(config zapcore.EncoderConfig) (zapcore.EncoderConfig, error) {
+	keys := []*string{
+		&config.TimeKey, &config.LevelKey, &config.NameKey, &config.CallerKey,
+		&config.FunctionKey, &config.MessageKey, &config.StacktraceKey,
+	}
+
+	// keys are renamed at once, so that keys could be swapped, like ts: time and time: ts
+	renamed := make(map[*string]string)
+	for from, to := range overrides.Keys {
+		found := false
+		for _, key := range keys {
+			if len(*key) > 0 && *key == from {
+				renamed[key] = to
+				found = true
+			}
+		}
+
+		if !found {
+			return config, errors.Errorf("key is not a key of encoderConfig, key:%s", from)
+		}
+	}
+	for key, to := range renamed {
+		*key = to
+	}
+
+	if len(overrides.TimeEncoder) > 0 {
+		encoder, ok := lookupTimeEncoder(overrides.TimeEncoder)
+		if !ok {
+			return config, errors.Errorf("invalid time encoder, timeEncoder:%s", overrides.TimeEncoder)
+		}
+		config.EncodeTime = encoder
+	}
+
+	if len(overrides.DurationEncoder) > 0 {
+		encoder, ok := durationEncoders[normalizeEncoderName(overrides.DurationEncoder)]
+		if !ok {
+			return config, errors.Errorf("invalid duration encoder, durationEncoder:%s", overrides.DurationEncoder)
+		}
+		config.EncodeDuration = encoder
+	}
+
+	if len(overrides.LevelEncoder) > 0 {
+		encoder, ok := levelEncoders[normalizeEncoderName(overrides.LevelEncoder)]
+		if !ok {
+			return config, errors.Errorf("invalid level encoder, levelEncoder:%s", overrides.LevelEncoder)
+		}
+		config.EncodeLevel = encoder
+	}
+
+	if overrides.CallerSegments < 0 {
+		return config, errors.Errorf("caller segments should not be negative, callerSegments:%d", overrides.CallerSegments)
+	}
+	if overrides.CallerSegments > 0 || len(overrides.CallerTrimPrefix) > 0 {
+		config.EncodeCaller = newCallerEncoder(overrides.CallerTrimPrefix, overrides.CallerSegments)
+	}
+
+	if len(overrides.FunctionKey) > 0 {
+		config.FunctionKey = overrides.FunctionKey
+	}
+
+	return config, nil
+}
+
+// Create caller encoder which strips path of caller up to and including prefix and keeps the last segments of it
+func newCallerEncoder(prefix string, segments int) zapcore.CallerEncoder {
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		if !caller.Defined {
+			enc.AppendString("undefined")
+			return
+		}
+
+		file := caller.File
+		if len(prefix) > 0 {
+			if i := strings.Index(file, prefix); i >= 0 {
+				file = file[i+len(prefix):]
+			}
+		}
+
+		if segments > 0 {
+			end := len(file)
+			for i := 0; i < segments; i++ {
+				end = strings.LastIndexByte(file[:end], '/')
+				if end < 0 {
+					break
+				}
+			}
+
+			if end >= 0 {
+				file = file[end+1:]
+			}
+		}
+
+		enc.AppendString(file + ":" + strconv.Itoa(caller.Line))
+	}
+}
+
+// Find time encoder with name, like rfc3339nano or epoch-millis
+func lookupTimeEncoder(name string) (zapcore.TimeEncoder, bool) {
+	encoder, ok := timeEncoders[normalizeEncoderName(name)]
+	return encoder, ok
+}
+
+// Normalize name of encoder by lower-casing it and removing dashes and underscores,
+// like capital-color, capital_color and capitalColor
+func normalizeEncoderName(name string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Whether encoders are the same function
+func sameEncoder(expected, actual interface{}) bool {
+	return reflect.ValueOf(expected).Pointer() == reflect.ValueOf(actual).Pointer()
+}
+
+func TestEncoderOverrides_Apply(t *testing.T) {
+	overrides := &EncoderOverrides{
+		Keys:            map[string]string{"msg": "message", "ts": "level", "level": "ts"},
+		TimeEncoder:     "rfc3339nano",
+		DurationEncoder: "millis",
+		LevelEncoder:    "capital-color",
+	}
+
+	config, err := overrides.apply(newCSVEncoderConfig())
+	assert.Nil(t, err)
+	assert.Equal(t, "message", config.MessageKey)
+	// keys are swapped
+	assert.Equal(t, "level", config.TimeKey)
+	assert.Equal(t, "ts", config.LevelKey)
+	assert.Equal(t, "caller", config.CallerKey)
+	assert.True(t, sameEncoder(zapcore.RFC3339NanoTimeEncoder, config.EncodeTime))
+	assert.True(t, sameEncoder(zapcore.MillisDurationEncoder, config.EncodeDuration))
+	assert.True(t, sameEncoder(zapcore.CapitalColorLevelEncoder, config.EncodeLevel))
+
+	// names are case insensitive with dashes or underscores
+	for name, expected := range map[string]zapcore.TimeEncoder{
+		"epoch-millis": zapcore.EpochMillisTimeEncoder,
+		"EPOCH_NANOS":  zapcore.EpochNanosTimeEncoder,
+		"ISO8601":      zapcore.ISO8601TimeEncoder,
+	} {
+		config, err = (&EncoderOverrides{TimeEncoder: name}).apply(newCSVEncoderConfig())
+		assert.Nil(t, err)
+		assert.True(t, sameEncoder(expected, config.EncodeTime), name)
+	}
+
+	// With invalid overrides
+	for _, overrides := range []*EncoderOverrides{
+		{Keys: map[string]string{"stacktrace": "stack"}},
+		{TimeEncoder: "unix"},
+		{DurationEncoder: "minutes"},
+		{LevelEncoder: "rainbow"},
+	} {
+		_, err = overrides.apply(newCSVEncoderConfig())
+		assert.NotNil(t, err)
+	}
+}
+
+func TestEncoderOverrides_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+
+	for _, lumberjack := range []bool{false, true} {
+		filePath := path.Join(dir, "build.log")
+		lumberjackSection := ""
+		if lumberjack {
+			filePath = path.Join(dir, "lumberjack.log")
+			lumberjackSection = "lumberjack:\n  maxsize: 1\n"
+		}
+
+		raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    levelKey: level
+    messageKey: msg
+    levelEncoder: lowercase
+  outputPaths: ["` + filePath + `"]
+` + lumberjackSection + `encoder:
+  keys:
+    msg: message
+    level: severity
+  durationEncoder: millis
+  levelEncoder: capital
+`)
+
+		config, err := NewConfigWithBytes(raw, YAML)
+		assert.Nil(t, err)
+
+		logger, err := NewZapLoggerWithConfig(config)
+		assert.Nil(t, err)
+		logger.Info("ut-message", zap.Duration("elapsed", 1500*time.Microsecond))
+		logger.Sync()
+
+		assert.Equal(t, `{"severity":"INFO","message":"ut-message","elapsed":1}`+"\n", readFileContent(filePath))
+		// zap config itself is not changed
+		assert.Equal(t, "msg", config.Zap.EncoderConfig.MessageKey)
+	}
+}
+
+func TestEncoderOverrides_WithInvalidKey(t *testing.T) {
+	config := NewConfig(&zap.Config{
+		Level:         zap.NewAtomicLevel(),
+		Encoding:      "json",
+		EncoderConfig: newCSVEncoderConfig(),
+		OutputPaths:   []string{path.Join(newTempDir(t), "ut.log")},
+	}, nil)
+	config.Encoder = &EncoderOverrides{Keys: map[string]string{"timestamp": "ts"}}
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.Contains(t, err.Error(), "failed to override encoder config")
+
+	// With outputs
+	config.Outputs = []*OutputConfig{{Path: "stdout"}}
+	logger, err = NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.Contains(t, err.Error(), "failed to override encoder config")
+}
+
+func TestNewCallerEncoder(t *testing.T) {
+	encode := func(encoder zapcore.CallerEncoder, caller zapcore.EntryCaller) string {
+		enc := zapcore.NewMapObjectEncoder()
+		enc.AddArray("caller", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			encoder(caller, arr)
+			return nil
+		}))
+		return enc.Fields["caller"].([]interface{})[0].(string)
+	}
+
+	caller := zapcore.NewEntryCaller(0, "/go/src/github.com/mycompany/app/internal/db/conn.go", 42, true)
+	assert.Equal(t, "internal/db/conn.go:42", encode(newCallerEncoder("github.com/mycompany/app/", 0), caller))
+	assert.Equal(t, "db/conn.go:42", encode(newCallerEncoder("", 2), caller))
+	assert.Equal(t, "db/conn.go:42", encode(newCallerEncoder("github.com/mycompany/app/", 2), caller))
+	// fewer segments than required
+	assert.Equal(t, "internal/db/conn.go:42", encode(newCallerEncoder("github.com/mycompany/app/", 5), caller))
+	// prefix not found
+	assert.Equal(t, "/go/src/github.com/mycompany/app/internal/db/conn.go:42", encode(newCallerEncoder("gitlab.com/", 0), caller))
+	assert.Equal(t, "undefined", encode(newCallerEncoder("", 2), zapcore.EntryCaller{}))
+}
+
+func TestEncoderOverrides_WithCallerAndFunction(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+    callerKey: caller
+    callerEncoder: full
+  outputPaths: ["` + filePath + `"]
+encoder:
+  callerSegments: 1
+  functionKey: func
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	logger.Sync()
+
+	assert.Regexp(t, `^\{"caller":"encoder_overrides_test.go:[0-9]+","func":"[^"]+TestEncoderOverrides_WithCallerAndFunction","msg":"ut-message"\}\n$`,
+		readFileContent(filePath))
+
+	// With negative segments
+	config.Encoder.CallerSegments = -1
+	logger, err = NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
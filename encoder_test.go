@@ -0,0 +1,122 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path"
+	"testing"
+)
+
+// Encoder config whose message key is invalid fails ut encoder
+const utInvalidMessageKey = "invalid"
+
+var _ = RegisterEncoder("ut-encoder", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	if config.MessageKey == utInvalidMessageKey {
+		return nil, errors.New("ut-error")
+	}
+
+	encoder := zapcore.NewJSONEncoder(config)
+	encoder.AddString("encoder", "ut")
+	return encoder, nil
+})
+
+func TestRegisterEncoder_WithConfigFile(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	raw := []byte(fmt.Sprintf(`---
+zap:
+  level: info
+  encoding: ut-encoder
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["%s"]
+levels:
+  db: warn
+`, filePath))
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Contains(t, readFileContent(filePath), `{"msg":"ut-message","encoder":"ut"}`)
+}
+
+// Encoders work with zap.Config.Build() as well
+func TestRegisterEncoder_WithZapConfig(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	config := &zap.Config{
+		Level:         zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding:      "ut-encoder",
+		EncoderConfig: zapcore.EncoderConfig{MessageKey: "msg"},
+		OutputPaths:   []string{filePath},
+	}
+
+	logger, err := config.Build()
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Contains(t, readFileContent(filePath), `{"msg":"ut-message","encoder":"ut"}`)
+}
+
+// With error from constructor
+func TestGenerateEncoder_WithConstructorError(t *testing.T) {
+	config := &zap.Config{
+		Encoding:      "ut-encoder",
+		EncoderConfig: zapcore.EncoderConfig{MessageKey: utInvalidMessageKey},
+	}
+
+	encoder, err := generateEncoder(config)
+	assert.Nil(t, encoder)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to create encoder, encoding:ut-encoder")
+
+	logger, err := NewZapLoggerWithConfig(&Config{Zap: &zap.Config{
+		Level:         zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding:      "ut-encoder",
+		EncoderConfig: zapcore.EncoderConfig{MessageKey: utInvalidMessageKey},
+	}, Levels: map[string]zapcore.Level{"db": zap.WarnLevel}})
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+// Unknown encoding is treated as console encoding
+func TestGenerateEncoder_WithUnknownEncoding(t *testing.T) {
+	encoder, err := generateEncoder(&zap.Config{Encoding: "unknown"})
+	assert.Nil(t, err)
+	assert.NotNil(t, encoder)
+}
+
+// With registered name
+func TestRegisterEncoder_WithDuplicateName(t *testing.T) {
+	constructor := func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return zapcore.NewJSONEncoder(config), nil
+	}
+
+	err := RegisterEncoder("ut-encoder", constructor)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "encoder is already registered, name:ut-encoder")
+
+	// encoders of zap
+	assert.NotNil(t, RegisterEncoder("json", constructor))
+	assert.NotNil(t, RegisterEncoder("console", constructor))
+}
+
+// With empty name and nil constructor
+func TestRegisterEncoder_WithInvalidArguments(t *testing.T) {
+	assert.NotNil(t, RegisterEncoder("", func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		return zapcore.NewJSONEncoder(config), nil
+	}))
+	assert.NotNil(t, RegisterEncoder("ut-nil", nil))
+
+	_, ok := lookupEncoder("ut-nil")
+	assert.False(t, ok)
+}
@@ -0,0 +1,334 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// version of encrypted records
+	encryptionVersion = 1
+	// size of additional data of encrypted records, which is version, stream and sequence
+	encryptionDataSize = 1 + 8 + 8
+	// size of header of encrypted records, which is additional data, length of sealed entry and nonce
+	encryptionHeaderSize = encryptionDataSize + 4 + 12
+	// max size of sealed entries read from encrypted files
+	maxEncryptedRecordSize = 64 * 1024 * 1024
+)
+
+// KeyProvider returns key of encryption with url of kms, like awskms://alias/logs.
+type KeyProvider func(u url.URL) ([]byte, error)
+
+var (
+	// key providers keyed by lower case scheme
+	keyProviders     = make(map[string]KeyProvider)
+	keyProviderMutex sync.RWMutex
+)
+
+// RegisterKeyProvider registers provider of encryption keys for kms urls with scheme, like a provider decrypting
+// data key with AWS KMS for awskms://alias/logs. Error would be returned if the scheme is already registered.
+func RegisterKeyProvider(scheme string, provider KeyProvider) error {
+	if provider == nil {
+		return errors.Errorf("key provider is nil, scheme:%s", scheme)
+	}
+
+	scheme = strings.ToLower(scheme)
+
+	keyProviderMutex.Lock()
+	defer keyProviderMutex.Unlock()
+
+	if _, ok := keyProviders[scheme]; ok {
+		return errors.Errorf("key provider is already registered, scheme:%s", scheme)
+	}
+
+	keyProviders[scheme] = provider
+	return nil
+}
+
+// EncryptionConfig is key of outputs flagged encrypted, which makes them unreadable on shared hosts. Every write of
+// output, which is an entry unless it is buffered, is sealed with AES-GCM and a random nonce into a record, so that
+// encrypted files could be appended, rotated and decrypted by NewDecryptReader record by record. Records are numbered
+// in order within stream of writer, which is random per opened output, and the numbers are authenticated, so that
+// records reordered, duplicated or dropped are detected, except records dropped from the start or the end of a file.
+//
+// Key is AES-128, AES-192 or AES-256 key encoded with standard base64, it is read from KeyFile, KeyEnv, KMS or Key
+// in order.
+//
+// Example config file in YAML:
+//
+//	encryption:
+//	  keyFile: /run/secrets/log-key
+//	outputs:
+//	  - path: /var/log/app.log
+//	    encrypted: true
+type EncryptionConfig struct {
+	// Key is base64 encoded key, prefer KeyFile, KeyEnv or KMS to keep it out of config file.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// KeyEnv is name of environment variable of base64 encoded key.
+	KeyEnv string `json:"keyEnv,omitempty" yaml:"keyEnv,omitempty"`
+	// KeyFile is path of file whose content is base64 encoded key, leading and trailing spaces are trimmed.
+	// Relative path is resolved against base directory of Loader.
+	KeyFile string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	// KMS is url of key whose scheme is registered with RegisterKeyProvider, like awskms://alias/logs.
+	KMS string `json:"kms,omitempty" yaml:"kms,omitempty"`
+}
+
+// LoadEncryptionKey reads key of config from file, environment variable, kms or config in order,
+// relative key file is resolved against working directory.
+func LoadEncryptionKey(config *EncryptionConfig) ([]byte, error) {
+	return NewLoader().encryptionKey(config)
+}
+
+// Read key of encryption from file, environment variable, kms or config in order
+func (loader *Loader) encryptionKey(config *EncryptionConfig) ([]byte, error) {
+	if config == nil {
+		return nil, errors.New("encryption config is missing")
+	}
+
+	var key []byte
+	switch {
+	case len(config.KeyFile) > 0:
+		filePath, err := loader.resolvePath(config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read key file of encryption, keyFile:%s", config.KeyFile)
+		}
+
+		if key, err = decodeEncryptionKey(string(raw)); err != nil {
+			return nil, errors.Wrapf(err, "invalid key of encryption, keyFile:%s", config.KeyFile)
+		}
+	case len(config.KeyEnv) > 0:
+		var err error
+		if key, err = decodeEncryptionKey(os.Getenv(config.KeyEnv)); err != nil {
+			return nil, errors.Wrapf(err, "invalid key of encryption, keyEnv:%s", config.KeyEnv)
+		}
+	case len(config.KMS) > 0:
+		var err error
+		if key, err = loadKMSKey(config.KMS); err != nil {
+			return nil, err
+		}
+	case len(config.Key) > 0:
+		var err error
+		if key, err = decodeEncryptionKey(config.Key); err != nil {
+			return nil, errors.Wrap(err, "invalid key of encryption")
+		}
+	default:
+		return nil, errors.New("key of encryption is missing, provide key, keyEnv, keyFile or kms")
+	}
+
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, errors.Errorf("size of encryption key should be 16, 24 or 32 bytes, size:%d", len(key))
+	}
+
+	return key, nil
+}
+
+// Decode base64 encoded key, leading and trailing spaces are trimmed
+func decodeEncryptionKey(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return nil, errors.New("key is empty")
+	}
+
+	return base64.StdEncoding.DecodeString(text)
+}
+
+// Load key with provider registered with scheme of kms url
+func loadKMSKey(kms string) ([]byte, error) {
+	u, err := url.Parse(kms)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid kms of encryption, kms:%s", kms)
+	}
+
+	keyProviderMutex.RLock()
+	provider, ok := keyProviders[strings.ToLower(u.Scheme)]
+	keyProviderMutex.RUnlock()
+
+	if !ok {
+		return nil, errors.Errorf("key provider is not registered, scheme:%s", u.Scheme)
+	}
+
+	key, err := provider(*u)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load key of encryption, kms:%s", kms)
+	}
+
+	return key, nil
+}
+
+// Create AES-GCM cipher of key
+func newEncryptionCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher of encryption")
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Create cipher of output flagged encrypted, nil is returned if output is not encrypted
+func (loader *Loader) newOutputCipher(output *OutputConfig) (cipher.AEAD, error) {
+	if !output.Encrypted {
+		return nil, nil
+	}
+
+	key, err := loader.encryptionKey(output.Encryption)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load key of encrypted output, path:%s", output.Path)
+	}
+
+	return newEncryptionCipher(key)
+}
+
+// Wrap write syncer with encryption, write syncer is returned as it is if cipher is nil
+func wrapEncryption(aead cipher.AEAD, syncer zapcore.WriteSyncer) zapcore.WriteSyncer {
+	if aead == nil {
+		return syncer
+	}
+
+	return &encryptedWriter{WriteSyncer: syncer, aead: aead}
+}
+
+// encryptedWriter seals every write into a record written to wrapped write syncer with a single write, records are
+// numbered in order within random stream of writer
+type encryptedWriter struct {
+	zapcore.WriteSyncer
+	aead cipher.AEAD
+	// stream is generated by the first write, records are sealed and written in order of sequence with lock held
+	stream   []byte
+	sequence uint64
+	mutex    sync.Mutex
+}
+
+// Write implements io.Writer, record is version, stream, sequence, length of sealed entry, nonce and sealed entry,
+// version, stream and sequence are authenticated as additional data
+func (writer *encryptedWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.stream == nil {
+		stream := make([]byte, 8)
+		if _, err := rand.Read(stream); err != nil {
+			return 0, errors.Wrap(err, "failed to generate stream of encryption")
+		}
+		writer.stream = stream
+	}
+
+	record := make([]byte, encryptionHeaderSize, encryptionHeaderSize+len(p)+writer.aead.Overhead())
+	record[0] = encryptionVersion
+	copy(record[1:9], writer.stream)
+	binary.BigEndian.PutUint64(record[9:encryptionDataSize], writer.sequence)
+	nonce := record[encryptionDataSize+4 : encryptionHeaderSize]
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, errors.Wrap(err, "failed to generate nonce of encryption")
+	}
+
+	record = writer.aead.Seal(record, nonce, p, record[:encryptionDataSize])
+	binary.BigEndian.PutUint32(record[encryptionDataSize:encryptionDataSize+4], uint32(len(record)-encryptionHeaderSize))
+
+	if _, err := writer.WriteSyncer.Write(record); err != nil {
+		return 0, err
+	}
+
+	writer.sequence++
+	return len(p), nil
+}
+
+// NewDecryptReader creates reader of plain entries of encrypted output read from reader, an error is returned by
+// Read if any record is modified, truncated, reordered, duplicated or dropped. Records dropped from the start or the
+// end of reader are not detected, since files start with records of streams opened before they were rotated.
+func NewDecryptReader(reader io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newEncryptionCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{reader: bufio.NewReader(reader), aead: aead, sequences: make(map[string]uint64)}, nil
+}
+
+// decryptReader opens records one by one
+type decryptReader struct {
+	reader *bufio.Reader
+	aead   cipher.AEAD
+	// plain bytes of current record which are not read yet
+	plain  []byte
+	record int
+	// sequences of the next records keyed by streams
+	sequences map[string]uint64
+}
+
+// Read implements io.Reader
+func (reader *decryptReader) Read(p []byte) (int, error) {
+	for len(reader.plain) == 0 {
+		if err := reader.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, reader.plain)
+	reader.plain = reader.plain[n:]
+	return n, nil
+}
+
+// Open next record, io.EOF is returned if there is no more record
+func (reader *decryptReader) next() error {
+	header := make([]byte, encryptionHeaderSize)
+	if _, err := io.ReadFull(reader.reader, header); err != nil {
+		if err == io.EOF {
+			return err
+		}
+		return errors.Wrapf(err, "failed to read encrypted record, record:%d", reader.record)
+	}
+
+	if header[0] != encryptionVersion {
+		return errors.Errorf("unknown version of encrypted record, record:%d, version:%d", reader.record, header[0])
+	}
+
+	size := binary.BigEndian.Uint32(header[encryptionDataSize : encryptionDataSize+4])
+	if size > maxEncryptedRecordSize {
+		return errors.Errorf("encrypted record is too large, record:%d, size:%d", reader.record, size)
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(reader.reader, sealed); err != nil {
+		return errors.Wrapf(err, "encrypted record is truncated, record:%d", reader.record)
+	}
+
+	plain, err := reader.aead.Open(sealed[:0], header[encryptionDataSize+4:], sealed, header[:encryptionDataSize])
+	if err != nil {
+		return errors.Wrapf(err, "failed to decrypt record, record:%d", reader.record)
+	}
+
+	// the first record of stream could be any one, since files are rotated
+	stream, sequence := string(header[1:9]), binary.BigEndian.Uint64(header[9:encryptionDataSize])
+	if next, ok := reader.sequences[stream]; ok && sequence != next {
+		return errors.Errorf("encrypted record is out of order, record:%d, sequence:%d, expected:%d",
+			reader.record, sequence, next)
+	}
+	reader.sequences[stream] = sequence + 1
+
+	reader.plain = plain
+	reader.record++
+	return nil
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+)
+
+var (
+	utEncryptionKey  = bytes.Repeat([]byte{1}, 32)
+	utEncryptionText = base64.StdEncoding.EncodeToString(utEncryptionKey)
+)
+
+// Decrypt content of file with key
+func decryptFile(t *testing.T, filePath string, key []byte) (string, error) {
+	raw, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+
+	reader, err := NewDecryptReader(bytes.NewReader(raw), key)
+	assert.Nil(t, err)
+
+	plain, err := ioutil.ReadAll(reader)
+	return string(plain), err
+}
+
+func TestLoader_EncryptionKey(t *testing.T) {
+	loader := NewLoader()
+
+	// With key
+	key, err := loader.encryptionKey(&EncryptionConfig{Key: utEncryptionText})
+	assert.Nil(t, err)
+	assert.Equal(t, utEncryptionKey, key)
+
+	// key file takes precedence over environment variable, relative path is resolved against base directory
+	dir := newTempDir(t)
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "key"), []byte(utEncryptionText+"\n"), 0600))
+	os.Setenv("UT_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	defer os.Unsetenv("UT_ENCRYPTION_KEY")
+
+	key, err = NewLoader(WithBaseDir(dir)).encryptionKey(&EncryptionConfig{KeyFile: "key", KeyEnv: "UT_ENCRYPTION_KEY"})
+	assert.Nil(t, err)
+	assert.Equal(t, utEncryptionKey, key)
+
+	key, err = loader.encryptionKey(&EncryptionConfig{KeyEnv: "UT_ENCRYPTION_KEY"})
+	assert.Nil(t, err)
+	assert.Equal(t, make([]byte, 16), key)
+
+	// With kms
+	assert.Nil(t, RegisterKeyProvider("utkms", func(u url.URL) ([]byte, error) {
+		if u.Host != "logs" {
+			return nil, errors.New("ut-error")
+		}
+		return utEncryptionKey, nil
+	}))
+	assert.NotNil(t, RegisterKeyProvider("utkms", func(u url.URL) ([]byte, error) { return nil, nil }))
+	assert.NotNil(t, RegisterKeyProvider("utnil", nil))
+
+	key, err = loader.encryptionKey(&EncryptionConfig{KMS: "utkms://logs"})
+	assert.Nil(t, err)
+	assert.Equal(t, utEncryptionKey, key)
+
+	// invalid configs
+	for _, config := range []*EncryptionConfig{
+		nil,
+		{},
+		{Key: "not-base64"},
+		{Key: base64.StdEncoding.EncodeToString(make([]byte, 8))},
+		{KeyEnv: "UT_MISSING_KEY"},
+		{KeyFile: path.Join(dir, "missing")},
+		{KMS: "utkms://other"},
+		{KMS: "utmissing://logs"},
+	} {
+		_, err := loader.encryptionKey(config)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestEncryptedOutput_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "app.log")
+	config := NewConfigWithOptions(
+		WithJSONEncoding(),
+		WithOutput(),
+		WithEncryptedOutput(filePath, EncryptionConfig{Key: utEncryptionText}))
+	config.Zap.EncoderConfig.TimeKey = ""
+	config.Zap.EncoderConfig.LevelKey = ""
+	config.Zap.EncoderConfig.CallerKey = ""
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	logger.Info("ut-first", zap.String("user", "ut-user"))
+	logger.Info("ut-second")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	assert.NotContains(t, readFileContent(filePath), "ut-first")
+
+	plain, err := decryptFile(t, filePath, utEncryptionKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"msg\":\"ut-first\",\"user\":\"ut-user\"}\n{\"msg\":\"ut-second\"}\n", plain)
+
+	// With another key
+	_, err = decryptFile(t, filePath, make([]byte, 32))
+	assert.NotNil(t, err)
+}
+
+// Write syncer of plain output is never shared with encrypted output of the same path
+func TestEncryptedOutput_WithPlainOutput(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "app.log")
+
+	_, closer, err := defaultLoader.openWriteSyncer(&OutputConfig{Path: filePath})
+	assert.Nil(t, err)
+	defer closer()
+
+	syncer, _, err := defaultLoader.openWriteSyncer(&OutputConfig{Path: filePath, Encrypted: true, Encryption: &EncryptionConfig{Key: utEncryptionText}})
+	assert.Nil(t, syncer)
+	assert.Contains(t, err.Error(), "settings:encrypted,encryption")
+
+	// encryption of outputs which are not encrypted is ignored
+	_, closeSecond, err := defaultLoader.openWriteSyncer(&OutputConfig{Path: filePath, Encryption: &EncryptionConfig{Key: utEncryptionText}})
+	assert.Nil(t, err)
+	closeSecond()
+}
+
+func TestEncryptedOutput_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "plain.log") + `"]
+encryption:
+  key: ` + utEncryptionText + `
+buffer:
+  size: 1KB
+  flushInterval: 1s
+outputs:
+  - path: ` + path.Join(dir, "app.log") + `
+    encrypted: true
+    audit: {}
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	// buffered entries are encrypted together, audit chain continues from encrypted file
+	for i := 0; i < 2; i++ {
+		logger, closer, err := NewZapLoggerWithCloser(config)
+		assert.Nil(t, err)
+		logger.Info("ut-first")
+		logger.Info("ut-second")
+		assert.Nil(t, closer.Shutdown(context.Background()))
+	}
+
+	assert.Contains(t, readFileContent(path.Join(dir, "plain.log")), "ut-first")
+	assert.NotContains(t, readFileContent(path.Join(dir, "app.log")), "ut-first")
+
+	plain, err := decryptFile(t, path.Join(dir, "app.log"), utEncryptionKey)
+	assert.Nil(t, err)
+
+	report, err := VerifyAuditLog(bytes.NewReader([]byte(plain)), "", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, report.Entries)
+
+	// Without key
+	config.Encryption = nil
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestNewDecryptReader_WithInvalidRecords(t *testing.T) {
+	// With invalid key
+	_, err := NewDecryptReader(bytes.NewReader(nil), make([]byte, 8))
+	assert.NotNil(t, err)
+
+	aead, _ := newEncryptionCipher(utEncryptionKey)
+	out := &bytes.Buffer{}
+	writer := wrapEncryption(aead, zapcore.AddSync(out))
+	writer.Write([]byte("ut-entry\n"))
+	record := out.Bytes()
+
+	for name, raw := range map[string][]byte{
+		"truncated header": record[:4],
+		"truncated entry":  record[:len(record)-1],
+		"modified":         append(append([]byte(nil), record[:len(record)-1]...), record[len(record)-1]^1),
+		"version":          append([]byte{2}, record[1:]...),
+		"sequence":         append(append(append([]byte(nil), record[:16]...), 1), record[17:]...),
+	} {
+		reader, _ := NewDecryptReader(bytes.NewReader(raw), utEncryptionKey)
+		_, err := ioutil.ReadAll(reader)
+		assert.NotNil(t, err, name)
+	}
+
+	// Without records
+	reader, _ := NewDecryptReader(bytes.NewReader(nil), utEncryptionKey)
+	plain, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Empty(t, plain)
+}
+
+func TestNewDecryptReader_WithReorderedRecords(t *testing.T) {
+	aead, _ := newEncryptionCipher(utEncryptionKey)
+	records := make([][]byte, 0)
+	out := &bytes.Buffer{}
+	writer := wrapEncryption(aead, zapcore.AddSync(out))
+	for _, entry := range []string{"ut-0\n", "ut-1\n", "ut-2\n"} {
+		writer.Write([]byte(entry))
+		records = append(records, append([]byte(nil), out.Bytes()...))
+		out.Reset()
+	}
+
+	// records of another stream, like the one of process which appends to the same file after restart
+	other := wrapEncryption(aead, zapcore.AddSync(out))
+	other.Write([]byte("ut-other\n"))
+	otherRecord := out.Bytes()
+
+	decrypt := func(indexes ...int) (string, error) {
+		raw := make([]byte, 0)
+		for _, i := range indexes {
+			if i < 0 {
+				raw = append(raw, otherRecord...)
+				continue
+			}
+			raw = append(raw, records[i]...)
+		}
+
+		reader, _ := NewDecryptReader(bytes.NewReader(raw), utEncryptionKey)
+		plain, err := ioutil.ReadAll(reader)
+		return string(plain), err
+	}
+
+	// records dropped from the start or the end are not detected, since files are rotated
+	for expected, indexes := range map[string][]int{
+		"ut-0\nut-1\nut-2\n":           {0, 1, 2},
+		"ut-1\nut-2\n":                 {1, 2},
+		"ut-0\nut-1\n":                 {0, 1},
+		"ut-0\nut-other\nut-1\nut-2\n": {0, -1, 1, 2},
+	} {
+		plain, err := decrypt(indexes...)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, plain)
+	}
+
+	for name, indexes := range map[string][]int{
+		"reordered":  {0, 2, 1},
+		"duplicated": {0, 1, 1, 2},
+		"dropped":    {0, 2},
+	} {
+		_, err := decrypt(indexes...)
+		assert.Contains(t, err.Error(), "encrypted record is out of order", name)
+	}
+}
+
+func TestWithEncryptedOutput(t *testing.T) {
+	config := NewConfigWithOptions(WithEncryptedOutput("ut-app.log", EncryptionConfig{KeyEnv: "LOG_KEY"}))
+	assert.Equal(t, []*OutputConfig{
+		{Path: "ut-app.log", Encrypted: true, Encryption: &EncryptionConfig{KeyEnv: "LOG_KEY"}},
+	}, config.Outputs)
+}
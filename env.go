@@ -0,0 +1,34 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"os"
+	"regexp"
+)
+
+// Matches escaped $$ or ${ENV_VAR} with optional :-default suffix
+var envPattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces ${ENV_VAR} and ${ENV_VAR:-default} in raw content with environment variables.
+//
+// Following shell semantics, the default value is used if the variable is unset or empty,
+// a variable without default value which is unset would be replaced with empty string.
+// Use $$ for a literal $, so that $${ENV_VAR} would be kept as ${ENV_VAR}.
+func ExpandEnv(raw []byte) []byte {
+	return envPattern.ReplaceAllFunc(raw, func(match []byte) []byte {
+		if string(match) == "$$" {
+			return []byte("$")
+		}
+
+		groups := envPattern.FindSubmatch(match)
+		if value := os.Getenv(string(groups[1])); len(value) > 0 {
+			return []byte(value)
+		}
+
+		return groups[3]
+	})
+}
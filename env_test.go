@@ -0,0 +1,44 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestExpandEnv_WithSetVariable(t *testing.T) {
+	assert.Nil(t, os.Setenv("RK_LOGGER_UT_LEVEL", "debug"))
+	defer os.Unsetenv("RK_LOGGER_UT_LEVEL")
+
+	assert.Equal(t, "level: debug", string(ExpandEnv([]byte("level: ${RK_LOGGER_UT_LEVEL}"))))
+	assert.Equal(t, "level: debug", string(ExpandEnv([]byte("level: ${RK_LOGGER_UT_LEVEL:-info}"))))
+}
+
+func TestExpandEnv_WithUnsetVariable(t *testing.T) {
+	assert.Equal(t, "level: ", string(ExpandEnv([]byte("level: ${RK_LOGGER_UT_NON_EXIST}"))))
+	assert.Equal(t, "level: info", string(ExpandEnv([]byte("level: ${RK_LOGGER_UT_NON_EXIST:-info}"))))
+	assert.Equal(t, "path: /var/log/app.log", string(ExpandEnv([]byte("path: ${RK_LOGGER_UT_NON_EXIST:-/var/log/app.log}"))))
+}
+
+func TestExpandEnv_WithEmptyVariable(t *testing.T) {
+	assert.Nil(t, os.Setenv("RK_LOGGER_UT_EMPTY", ""))
+	defer os.Unsetenv("RK_LOGGER_UT_EMPTY")
+
+	assert.Equal(t, "level: info", string(ExpandEnv([]byte("level: ${RK_LOGGER_UT_EMPTY:-info}"))))
+}
+
+func TestExpandEnv_WithEscape(t *testing.T) {
+	assert.Nil(t, os.Setenv("RK_LOGGER_UT_LEVEL", "debug"))
+	defer os.Unsetenv("RK_LOGGER_UT_LEVEL")
+
+	assert.Equal(t, "level: ${RK_LOGGER_UT_LEVEL}", string(ExpandEnv([]byte("level: $${RK_LOGGER_UT_LEVEL}"))))
+	assert.Equal(t, "cost: $5", string(ExpandEnv([]byte("cost: $$5"))))
+}
+
+func TestExpandEnv_WithoutBraces(t *testing.T) {
+	assert.Equal(t, "level: $HOME", string(ExpandEnv([]byte("level: $HOME"))))
+}
@@ -0,0 +1,389 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LoggerNameKey is the key of field which carries logger name in loggers of LoggerFactory.
+const LoggerNameKey = "logger"
+
+// LoggerFactory owns parsed configs of logger map and hands out loggers by name.
+//
+// Loggers of the same config are children of one root logger, and write syncers are shared by path
+// among all of the loggers, so that a file is written by a single lumberjack logger.
+// If outputs with the same path carry different rotation settings, the first one wins.
+type LoggerFactory struct {
+	loader  *Loader
+	configs map[string]*Config
+	opts    []zap.Option
+	// root loggers keyed by name of config
+	roots map[string]*zap.Logger
+	// loggers handed out keyed by logger name
+	loggers map[string]*zap.Logger
+	closed  bool
+	mutex   sync.Mutex
+	// signals relayed to RotateAll and done channel of relaying
+	signals    chan os.Signal
+	signalDone chan struct{}
+}
+
+// NewLoggerFactory creates LoggerFactory with a map of logger names to configs.
+func NewLoggerFactory(configs map[string]*Config, opts ...zap.Option) *LoggerFactory {
+	return defaultLoader.NewLoggerFactory(configs, opts...)
+}
+
+// NewLoggerFactory creates LoggerFactory with a map of logger names to configs and options of Loader
+func (loader *Loader) NewLoggerFactory(configs map[string]*Config, opts ...zap.Option) *LoggerFactory {
+	// loader of factory shares write syncers among loggers
+	pooled := *loader
+	pooled.pool = newWriteSyncerPool()
+
+	return &LoggerFactory{
+		loader:  &pooled,
+		configs: configs,
+		opts:    opts,
+		roots:   make(map[string]*zap.Logger),
+		loggers: make(map[string]*zap.Logger),
+	}
+}
+
+// NewLoggerFactoryWithBytes creates LoggerFactory with byte array from content of config file
+// See NewConfigMapWithBytes for details of config file
+func NewLoggerFactoryWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (*LoggerFactory, error) {
+	return defaultLoader.NewLoggerFactoryWithBytes(raw, fileType, opts...)
+}
+
+// NewLoggerFactoryWithBytes creates LoggerFactory with byte array from content of config file and options of Loader
+func (loader *Loader) NewLoggerFactoryWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (*LoggerFactory, error) {
+	configs, err := loader.NewConfigMapWithBytes(raw, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.NewLoggerFactory(configs, opts...), nil
+}
+
+// NewLoggerFactoryWithConfPath creates LoggerFactory with config file path
+// See NewConfigMapWithBytes for details of config file
+func NewLoggerFactoryWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (*LoggerFactory, error) {
+	return defaultLoader.NewLoggerFactoryWithConfPath(filePath, fileType, opts...)
+}
+
+// NewLoggerFactoryWithConfPath creates LoggerFactory with config file path and options of Loader
+func (loader *Loader) NewLoggerFactoryWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (*LoggerFactory, error) {
+	configs, err := loader.NewConfigMapWithConfPath(filePath, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.NewLoggerFactory(configs, opts...), nil
+}
+
+// GetLogger returns logger with name which carries a field of LoggerNameKey.
+// Logger is built with config of the same name, or config of DefaultLoggerName if there is no such config.
+// The same logger is returned for the same name.
+func (factory *LoggerFactory) GetLogger(name string) (*zap.Logger, error) {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+
+	if factory.closed {
+		return nil, errors.New("logger factory is closed")
+	}
+
+	if logger, ok := factory.loggers[name]; ok {
+		return logger, nil
+	}
+
+	root, err := factory.root(factory.configName(name))
+	if err != nil {
+		return nil, err
+	}
+
+	logger := root.With(zap.String(LoggerNameKey, name))
+	factory.loggers[name] = logger
+
+	return logger, nil
+}
+
+// Close syncs loggers and closes shared write syncers, loggers should not be used after Close.
+func (factory *LoggerFactory) Close() error {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+
+	if factory.closed {
+		return nil
+	}
+
+	factory.closed = true
+	factory.stopSignals()
+	for _, root := range factory.roots {
+		root.Sync()
+	}
+	factory.loader.pool.close()
+
+	return nil
+}
+
+// Rotate rotates file outputs of logger with name immediately, including outputs of routes and error outputs.
+// Outputs are shared by path, so loggers writing to the same files are rotated as well.
+// Nothing is rotated if logger of config is not built yet since its outputs are not opened.
+func (factory *LoggerFactory) Rotate(name string) error {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+
+	if factory.closed {
+		return errors.New("logger factory is closed")
+	}
+
+	configName := factory.configName(name)
+	if _, ok := factory.roots[configName]; !ok {
+		return nil
+	}
+
+	config := factory.configs[configName]
+	outputs, errOutputs := config.toOutputConfigs()
+	for _, route := range config.Routes {
+		outputs = append(outputs, config.collectOutputs(route.OutputPaths, route.Outputs)...)
+	}
+
+	keys := make([]string, 0, len(outputs)+len(errOutputs))
+	for _, output := range append(outputs, errOutputs...) {
+		for ; output != nil; output = output.Fallback {
+			if len(output.Path) > 0 {
+				keys = append(keys, factory.loader.poolKey(output.Path))
+			}
+		}
+	}
+
+	return factory.loader.pool.rotate(keys)
+}
+
+// RotateAll rotates file outputs of all loggers immediately
+func (factory *LoggerFactory) RotateAll() error {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+
+	if factory.closed {
+		return errors.New("logger factory is closed")
+	}
+
+	return factory.loader.pool.rotate(nil)
+}
+
+// Name of config which logger with name is built with, DefaultLoggerName if there is no config with name
+func (factory *LoggerFactory) configName(name string) string {
+	if _, ok := factory.configs[name]; !ok {
+		return DefaultLoggerName
+	}
+
+	return name
+}
+
+// Get or build root logger of config with name
+func (factory *LoggerFactory) root(configName string) (*zap.Logger, error) {
+	if root, ok := factory.roots[configName]; ok {
+		return root, nil
+	}
+
+	config, ok := factory.configs[configName]
+	if !ok || config == nil || config.Zap == nil {
+		return nil, errors.Errorf("logger config is missing, name:%s", configName)
+	}
+
+	// files are always opened by ourselves so that write syncers could be shared
+	outputs, errOutputs := config.toOutputConfigs()
+	root, err := factory.loader.buildZapLoggerWithOutputs(config, outputs, errOutputs, factory.opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build logger, name:%s", configName)
+	}
+
+	factory.loader.levels.RegisterConfig(configName, config)
+	factory.roots[configName] = root
+
+	return root, nil
+}
+
+// sharedWriteSyncers shares write syncers of file outputs with the same absolute path among loggers in process,
+// so that loggers writing to the same file never race on rotation
+var sharedWriteSyncers = newWriteSyncerPool()
+
+// writeSyncerPool shares write syncers among outputs with the same key
+type writeSyncerPool struct {
+	syncers map[string]*pooledWriteSyncer
+	mutex   sync.Mutex
+}
+
+// pooledWriteSyncer is write syncer in pool with number of its references
+type pooledWriteSyncer struct {
+	zapcore.WriteSyncer
+	closer   func()
+	settings writerSettings
+	refs     int
+}
+
+// writerSettings are settings of output applied while its write syncer is opened, like rotation and buffer, which
+// are encoded as json and keyed by names. Write syncers are shared only among outputs with the same settings.
+type writerSettings map[string]string
+
+// Names of settings which are different from others, in order
+func (settings writerSettings) diff(others writerSettings) []string {
+	res := make([]string, 0)
+	for name, value := range settings {
+		if others[name] != value {
+			res = append(res, name)
+		}
+	}
+
+	for name := range others {
+		if _, ok := settings[name]; !ok {
+			res = append(res, name)
+		}
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// Create an empty writeSyncerPool
+func newWriteSyncerPool() *writeSyncerPool {
+	return &writeSyncerPool{
+		syncers: make(map[string]*pooledWriteSyncer),
+	}
+}
+
+// Get write syncer with key or open a new one, the returned function releases the reference,
+// and write syncer is closed once all of its references are released or pool is closed.
+// Error would be returned if write syncer with key is opened with different settings.
+func (pool *writeSyncerPool) open(key string, settings writerSettings, open func() (zapcore.WriteSyncer, func(), error)) (zapcore.WriteSyncer, func(), error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if pooled, ok := pool.syncers[key]; ok {
+		if diff := pooled.settings.diff(settings); len(diff) > 0 {
+			return nil, nil, errors.Errorf("output is already opened with different settings, path:%s, settings:%s",
+				key, strings.Join(diff, ","))
+		}
+
+		return pool.acquire(key, pooled)
+	}
+
+	return pool.replace(key, settings, open)
+}
+
+// Open a new write syncer with key which replaces the current one for later opens, like files moved by logrotate,
+// the current one is closed once all of its references are released
+func (pool *writeSyncerPool) reopen(key string, settings writerSettings, open func() (zapcore.WriteSyncer, func(), error)) (zapcore.WriteSyncer, func(), error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	return pool.replace(key, settings, open)
+}
+
+// Open write syncer and store it with key, it is called with lock held
+func (pool *writeSyncerPool) replace(key string, settings writerSettings, open func() (zapcore.WriteSyncer, func(), error)) (zapcore.WriteSyncer, func(), error) {
+	syncer, closer, err := open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pooled := &pooledWriteSyncer{WriteSyncer: syncer, closer: closer, settings: settings}
+	pool.syncers[key] = pooled
+
+	return pool.acquire(key, pooled)
+}
+
+// Add reference of write syncer with key, it is called with lock held
+func (pool *writeSyncerPool) acquire(key string, pooled *pooledWriteSyncer) (zapcore.WriteSyncer, func(), error) {
+	pooled.refs++
+	var once sync.Once
+
+	return pooled.WriteSyncer, func() {
+		once.Do(func() {
+			pool.release(key, pooled)
+		})
+	}, nil
+}
+
+// Release reference of write syncer with key, it is closed if there are no more references
+func (pool *writeSyncerPool) release(key string, pooled *pooledWriteSyncer) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	// write syncer is closed by pool already
+	if pooled.refs <= 0 {
+		return
+	}
+
+	if pooled.refs--; pooled.refs > 0 {
+		return
+	}
+
+	// write syncer may be replaced by a reopened one
+	if pool.syncers[key] == pooled {
+		delete(pool.syncers, key)
+	}
+	pooled.closer()
+}
+
+// Rotate write syncers of rotators with keys once, all of them are rotated if keys are nil
+func (pool *writeSyncerPool) rotate(keys []string) error {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if keys == nil {
+		for key := range pool.syncers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	var err error
+	rotated := make(map[string]bool)
+	for _, key := range keys {
+		pooled, ok := pool.syncers[key]
+		if !ok || rotated[key] {
+			continue
+		}
+
+		rotator, ok := pooled.WriteSyncer.(interface{ Rotate() error })
+		if !ok {
+			continue
+		}
+
+		rotated[key] = true
+		err = multierr.Append(err, errors.Wrapf(rotator.Rotate(), "failed to rotate file, path:%s", key))
+	}
+
+	return err
+}
+
+// Close all of the write syncers in pool
+func (pool *writeSyncerPool) close() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	keys := make([]string, 0, len(pool.syncers))
+	for key := range pool.syncers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		pool.syncers[key].refs = 0
+		pool.syncers[key].closer()
+	}
+
+	pool.syncers = make(map[string]*pooledWriteSyncer)
+}
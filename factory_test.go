@@ -0,0 +1,213 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// Config of logger map whose loggers write to the same file with rotation
+func newFactoryConfig(filePath string) []byte {
+	return []byte(fmt.Sprintf(`---
+default:
+  zap:
+    level: info
+    encoding: json
+    encoderConfig:
+      messageKey: msg
+    outputPaths: ["%s"]
+  lumberjack:
+    maxsize: 1
+db:
+  zap:
+    level: debug
+`, filePath))
+}
+
+func TestNewLoggerFactoryWithBytes_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	factory, err := NewLoggerFactoryWithBytes(newFactoryConfig(filePath), YAML)
+	assert.Nil(t, err)
+	defer factory.Close()
+
+	app, err := factory.GetLogger("app")
+	assert.Nil(t, err)
+	db, err := factory.GetLogger("db")
+	assert.Nil(t, err)
+
+	app.Info("app-message")
+	db.Debug("db-message")
+
+	content := readFileContent(filePath)
+	assert.Contains(t, content, `{"msg":"app-message","logger":"app"}`)
+	assert.Contains(t, content, `{"msg":"db-message","logger":"db"}`)
+}
+
+// With invalid config file
+func TestNewLoggerFactoryWithBytes_WithInvalidConfig(t *testing.T) {
+	factory, err := NewLoggerFactoryWithBytes([]byte(`default: [`), YAML)
+	assert.Nil(t, factory)
+	assert.NotNil(t, err)
+}
+
+func TestNewLoggerFactoryWithConfPath_HappyCase(t *testing.T) {
+	dir, _ := os.Getwd()
+	factory, err := NewLoggerFactoryWithConfPath(dir+"/assets/loggers.yaml", YAML)
+	assert.Nil(t, err)
+	defer factory.Close()
+
+	logger, err := factory.GetLogger("db")
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+}
+
+// With non exist file
+func TestNewLoggerFactoryWithConfPath_WithNonExistFile(t *testing.T) {
+	factory, err := NewLoggerFactoryWithConfPath(path.Join(newTempDir(t), "loggers.yaml"), YAML)
+	assert.Nil(t, factory)
+	assert.NotNil(t, err)
+}
+
+// Write syncers of the same path are shared among loggers
+func TestLoggerFactory_WithSharedWriteSyncers(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	factory, err := NewLoggerFactoryWithBytes(newFactoryConfig(filePath), YAML)
+	assert.Nil(t, err)
+	defer factory.Close()
+
+	_, err = factory.GetLogger("app")
+	assert.Nil(t, err)
+	_, err = factory.GetLogger("db")
+	assert.Nil(t, err)
+
+	assert.Len(t, factory.loader.pool.syncers, 1)
+	assert.Contains(t, factory.loader.pool.syncers, filePath)
+	assert.Len(t, factory.roots, 2)
+}
+
+// The same logger is returned for the same name, unknown names use default config
+func TestLoggerFactory_GetLogger(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	factory, err := NewLoggerFactoryWithBytes(newFactoryConfig(filePath), YAML)
+	assert.Nil(t, err)
+	defer factory.Close()
+
+	first, err := factory.GetLogger("app")
+	assert.Nil(t, err)
+	second, err := factory.GetLogger("app")
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	other, err := factory.GetLogger("other")
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, other)
+	assert.Len(t, factory.roots, 1)
+}
+
+// Without default config
+func TestLoggerFactory_GetLoggerWithoutDefault(t *testing.T) {
+	factory := NewLoggerFactory(map[string]*Config{"db": NewConfig(NewZapStdoutConfig(), nil)})
+	defer factory.Close()
+
+	logger, err := factory.GetLogger("db")
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+
+	logger, err = factory.GetLogger("app")
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "logger config is missing")
+}
+
+// With invalid config of logger
+func TestLoggerFactory_GetLoggerWithInvalidConfig(t *testing.T) {
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	config.Zap.OutputPaths = []string{"invalid://ut"}
+	factory := NewLoggerFactory(map[string]*Config{DefaultLoggerName: config})
+	defer factory.Close()
+
+	logger, err := factory.GetLogger("app")
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestLoggerFactory_Close(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	factory, err := NewLoggerFactoryWithBytes(newFactoryConfig(filePath), YAML)
+	assert.Nil(t, err)
+
+	logger, err := factory.GetLogger("app")
+	assert.Nil(t, err)
+	logger.Info("before-close")
+
+	assert.Nil(t, factory.Close())
+	// close twice
+	assert.Nil(t, factory.Close())
+	assert.Empty(t, factory.loader.pool.syncers)
+	assert.NotContains(t, sharedWriteSyncers.syncers, filePath)
+
+	logger, err = factory.GetLogger("app")
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+	assert.Contains(t, readFileContent(filePath), "before-close")
+}
+
+// Loggers of factory with default loader do not share write syncers with default loader
+func TestNewLoggerFactory_WithDefaultLoader(t *testing.T) {
+	factory := NewLoggerFactory(map[string]*Config{})
+	assert.NotNil(t, factory.loader.pool)
+	assert.Nil(t, defaultLoader.pool)
+}
+
+// Config of logger map whose loggers write to files of their own with rotation
+func newRotationFactoryConfig(dir string) []byte {
+	return []byte(fmt.Sprintf(`---
+default:
+  zap:
+    level: info
+    encoding: json
+    encoderConfig:
+      messageKey: msg
+    outputPaths: ["%s"]
+  lumberjack:
+    maxsize: 1
+db:
+  zap:
+    outputPaths: ["%s"]
+`, path.Join(dir, "app.log"), path.Join(dir, "db.log")))
+}
+
+func TestLoggerFactory_Rotate(t *testing.T) {
+	dir := newTempDir(t)
+	factory, err := NewLoggerFactoryWithBytes(newRotationFactoryConfig(dir), YAML)
+	assert.Nil(t, err)
+
+	// outputs of loggers which are not built are not opened
+	assert.Nil(t, factory.Rotate("app"))
+
+	app, _ := factory.GetLogger("app")
+	db, _ := factory.GetLogger("db")
+	app.Info("app-message")
+	db.Info("db-message")
+
+	assert.Nil(t, factory.Rotate("app"))
+	assert.Len(t, listFileNames(t, dir), 3)
+	assert.Empty(t, readFileContent(path.Join(dir, "app.log")))
+	assert.Contains(t, readFileContent(path.Join(dir, "db.log")), "db-message")
+
+	assert.Nil(t, factory.RotateAll())
+	matches, _ := filepath.Glob(path.Join(dir, "db-*.log"))
+	assert.Len(t, matches, 1)
+	assert.Empty(t, readFileContent(path.Join(dir, "db.log")))
+
+	assert.Nil(t, factory.Close())
+	assert.NotNil(t, factory.Rotate("app"))
+	assert.NotNil(t, factory.RotateAll())
+}
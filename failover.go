@@ -0,0 +1,306 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHealthCheckInterval is the default interval of checking primary outputs of failover outputs.
+const DefaultHealthCheckInterval = 10 * time.Second
+
+// Policies which could be used by OutputConfig.OnWriteError
+const (
+	// WriteErrorStderr writes entries to stderr while writing to output fails.
+	WriteErrorStderr = "stderr"
+	// WriteErrorDrop drops entries while writing to output fails, they are counted in FailoverStats.Dropped.
+	WriteErrorDrop = "drop"
+)
+
+var (
+	// failover writers which are not closed
+	failoverWriters = make(map[*failoverWriter]struct{})
+	failoverMutex   sync.Mutex
+)
+
+// HealthChecker is implemented by write syncers which could check whether their destination is available,
+// like tcp outputs. Failed primary outputs of failover outputs are recovered once the check succeeds,
+// primary outputs which do not implement it are recovered once they are synced successfully.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// FailoverStats is the status of output with fallback.
+type FailoverStats struct {
+	// Primary is the path of primary output.
+	Primary string `json:"primary" yaml:"primary"`
+	// Fallback is the path of fallback output, or drop if entries are dropped while primary fails.
+	Fallback string `json:"fallback" yaml:"fallback"`
+	// Failed is true while entries are written to fallback output.
+	Failed bool `json:"failed" yaml:"failed"`
+	// Failovers is the number of times entries were switched to fallback output.
+	Failovers uint64 `json:"failovers" yaml:"failovers"`
+	// Recoveries is the number of times entries were switched back to primary output.
+	Recoveries uint64 `json:"recoveries" yaml:"recoveries"`
+	// Fallbacks is the number of entries written to fallback output.
+	Fallbacks uint64 `json:"fallbacks" yaml:"fallbacks"`
+	// Dropped is the number of entries written to neither primary nor fallback output,
+	// including entries dropped while primary fails.
+	Dropped uint64 `json:"dropped" yaml:"dropped"`
+	// LastError is the error of primary output which caused the last failover.
+	LastError string `json:"lastError" yaml:"lastError"`
+	// LastFailover is the time of the last failover.
+	LastFailover time.Time `json:"lastFailover" yaml:"lastFailover"`
+}
+
+// ListFailoverStats returns status of outputs with fallback of opened loggers in order of primary paths,
+// which could be exported as metrics.
+func ListFailoverStats() []FailoverStats {
+	failoverMutex.Lock()
+	res := make([]FailoverStats, 0, len(failoverWriters))
+	for writer := range failoverWriters {
+		res = append(res, writer.stats())
+	}
+	failoverMutex.Unlock()
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Primary < res[j].Primary
+	})
+
+	return res
+}
+
+// failoverWriter writes entries to primary write syncer, and to fallback write syncer while primary fails
+type failoverWriter struct {
+	primary  zapcore.WriteSyncer
+	fallback zapcore.WriteSyncer
+	interval time.Duration
+	// whether entries are dropped instead of being written to fallback
+	drop   bool
+	status FailoverStats
+	mutex  sync.RWMutex
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Open primary and fallback write syncers of output, the returned function stops health check and closes both
+func (loader *Loader) openFailoverWriter(output *OutputConfig) (zapcore.WriteSyncer, func(), error) {
+	interval := DefaultHealthCheckInterval
+	if len(output.HealthCheckInterval) > 0 {
+		var err error
+		if interval, err = time.ParseDuration(output.HealthCheckInterval); err != nil || interval <= 0 {
+			return nil, nil, errors.Errorf("invalid health check interval of output, path:%s, interval:%s",
+				output.Path, output.HealthCheckInterval)
+		}
+	}
+
+	// fallback of write error policy
+	fallbackOutput, drop := output.Fallback, false
+	if fallbackOutput == nil {
+		switch strings.ToLower(output.OnWriteError) {
+		case WriteErrorStderr:
+			fallbackOutput = &OutputConfig{Path: "stderr"}
+		case WriteErrorDrop:
+			drop = true
+		default:
+			return nil, nil, errors.Errorf("invalid write error policy of output, path:%s, onWriteError:%s",
+				output.Path, output.OnWriteError)
+		}
+	}
+
+	if fallbackOutput != nil {
+		if _, _, ok := lookupCore(fallbackOutput.Path); ok {
+			return nil, nil, errors.Errorf("fallback is not supported by core outputs, path:%s", fallbackOutput.Path)
+		}
+	}
+
+	primaryOutput := *output
+	primaryOutput.Fallback, primaryOutput.OnWriteError = nil, ""
+	primary, closePrimary, err := loader.openWriteSyncer(&primaryOutput)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fallback, closeFallback := zapcore.AddSync(ioutil.Discard), func() {}
+	if fallbackOutput != nil {
+		if fallback, closeFallback, err = loader.openWriteSyncer(fallbackOutput); err != nil {
+			closePrimary()
+			return nil, nil, err
+		}
+	}
+
+	writer := newFailoverWriter(primary, fallback, interval)
+	writer.drop = drop
+	writer.status.Primary, writer.status.Fallback = output.Path, WriteErrorDrop
+	if fallbackOutput != nil {
+		writer.status.Fallback = fallbackOutput.Path
+	}
+
+	return writer, func() {
+		writer.close()
+		closePrimary()
+		closeFallback()
+	}, nil
+}
+
+// Whether write error policy is applied to output, stdout and stderr are written without it
+func hasWriteErrorPolicy(output *OutputConfig) bool {
+	return len(output.OnWriteError) > 0 && output.Path != "stdout" && output.Path != "stderr"
+}
+
+// Create failover writer and start health check in background
+func newFailoverWriter(primary, fallback zapcore.WriteSyncer, interval time.Duration) *failoverWriter {
+	writer := &failoverWriter{
+		primary:  primary,
+		fallback: fallback,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+
+	failoverMutex.Lock()
+	failoverWriters[writer] = struct{}{}
+	failoverMutex.Unlock()
+
+	go writer.run()
+	return writer
+}
+
+// Write implements zapcore.WriteSyncer, p is written to fallback if writing to primary fails
+func (writer *failoverWriter) Write(p []byte) (int, error) {
+	if !writer.failed() {
+		n, err := writer.primary.Write(p)
+		if err == nil {
+			return n, nil
+		}
+		writer.fail(err)
+	}
+
+	if writer.drop {
+		writer.count(&writer.status.Dropped)
+		return len(p), nil
+	}
+
+	n, err := writer.fallback.Write(p)
+	if err != nil {
+		writer.count(&writer.status.Dropped)
+		return n, err
+	}
+
+	writer.count(&writer.status.Fallbacks)
+	return n, nil
+}
+
+// Increase counter in status
+func (writer *failoverWriter) count(counter *uint64) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	*counter++
+}
+
+// Sync implements zapcore.WriteSyncer, errors of primary are handled by failing over and recorded in status
+func (writer *failoverWriter) Sync() error {
+	if !writer.failed() {
+		if err := writer.primary.Sync(); err != nil {
+			writer.fail(err)
+		}
+	}
+
+	return writer.fallback.Sync()
+}
+
+// Check primary periodically, primary is synced while it works so that failures of buffered writers are found
+func (writer *failoverWriter) run() {
+	ticker := time.NewTicker(writer.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !writer.failed() {
+				if err := writer.primary.Sync(); err != nil {
+					writer.fail(err)
+				}
+			} else if writer.check() == nil {
+				writer.recover()
+			}
+		case <-writer.done:
+			return
+		}
+	}
+}
+
+// Check whether primary is available with health check or sync
+func (writer *failoverWriter) check() error {
+	checker, ok := writer.primary.(HealthChecker)
+	if !ok {
+		return writer.primary.Sync()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writer.interval)
+	defer cancel()
+	return checker.HealthCheck(ctx)
+}
+
+// Whether entries are written to fallback
+func (writer *failoverWriter) failed() bool {
+	writer.mutex.RLock()
+	defer writer.mutex.RUnlock()
+
+	return writer.status.Failed
+}
+
+// Switch to fallback with error of primary
+func (writer *failoverWriter) fail(err error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.status.Failed {
+		return
+	}
+
+	writer.status.Failed = true
+	writer.status.Failovers++
+	writer.status.LastError = err.Error()
+	writer.status.LastFailover = time.Now()
+}
+
+// Switch back to primary
+func (writer *failoverWriter) recover() {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.status.Failed {
+		writer.status.Failed = false
+		writer.status.Recoveries++
+	}
+}
+
+// Copy status of writer
+func (writer *failoverWriter) stats() FailoverStats {
+	writer.mutex.RLock()
+	defer writer.mutex.RUnlock()
+
+	return writer.status
+}
+
+// Stop health check and remove writer from status of ListFailoverStats()
+func (writer *failoverWriter) close() {
+	writer.once.Do(func() {
+		close(writer.done)
+
+		failoverMutex.Lock()
+		delete(failoverWriters, writer)
+		failoverMutex.Unlock()
+	})
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	utFailoverWriters = make(map[string]*failingWriter)
+	_                 = RegisterWriter("ut-failover", func(u url.URL) (zapcore.WriteSyncer, error) {
+		writer := &failingWriter{}
+		utFailoverWriters[u.Host] = writer
+		return writer, nil
+	})
+)
+
+// failingWriter fails to write, sync and check health while err is set
+type failingWriter struct {
+	mutex  sync.Mutex
+	buf    bytes.Buffer
+	err    error
+	checks int
+}
+
+func (writer *failingWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.err != nil {
+		return 0, writer.err
+	}
+	return writer.buf.Write(p)
+}
+
+func (writer *failingWriter) Sync() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.err
+}
+
+func (writer *failingWriter) setError(err error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.err = err
+}
+
+func (writer *failingWriter) String() string {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.buf.String()
+}
+
+// healthCheckedWriter is checked by HealthCheck instead of Sync
+type healthCheckedWriter struct {
+	*failingWriter
+	healthy bool
+}
+
+func (writer *healthCheckedWriter) HealthCheck(ctx context.Context) error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.checks++
+	if !writer.healthy {
+		return errors.New("ut-unhealthy")
+	}
+	return nil
+}
+
+// Entries are written to fallback while primary fails, and to primary again once it is synced successfully
+func TestFailoverWriter_HappyCase(t *testing.T) {
+	primary, fallback := &failingWriter{}, &memorySink{}
+	writer := newFailoverWriter(primary, zapcore.Lock(fallback), 10*time.Millisecond)
+	defer writer.close()
+
+	_, err := writer.Write([]byte("ut-1\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-1\n", primary.String())
+
+	primary.setError(errors.New("ut-error"))
+	_, err = writer.Write([]byte("ut-2\n"))
+	assert.Nil(t, err)
+
+	stats := writer.stats()
+	assert.True(t, stats.Failed)
+	assert.Equal(t, uint64(1), stats.Failovers)
+	assert.Equal(t, "ut-error", stats.LastError)
+	assert.False(t, stats.LastFailover.IsZero())
+
+	// primary is not written while it fails
+	primary.setError(nil)
+	_, err = writer.Write([]byte("ut-3\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-2\nut-3\n", fallback.String())
+
+	assert.Eventually(t, func() bool {
+		return !writer.failed()
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint64(1), writer.stats().Recoveries)
+
+	_, err = writer.Write([]byte("ut-4\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-1\nut-4\n", primary.String())
+}
+
+// With primary which fails to sync
+func TestFailoverWriter_WithSyncError(t *testing.T) {
+	primary, fallback := &failingWriter{}, &memorySink{}
+	writer := newFailoverWriter(primary, zapcore.Lock(fallback), time.Hour)
+	defer writer.close()
+
+	primary.setError(errors.New("ut-error"))
+	assert.Nil(t, writer.Sync())
+	assert.True(t, writer.failed())
+	assert.True(t, fallback.synced)
+}
+
+// With primary which fails to sync in background
+func TestFailoverWriter_WithBackgroundSyncError(t *testing.T) {
+	primary := &failingWriter{}
+	writer := newFailoverWriter(primary, zapcore.AddSync(ioutil.Discard), 10*time.Millisecond)
+	defer writer.close()
+
+	primary.setError(errors.New("ut-error"))
+	assert.Eventually(t, func() bool {
+		return writer.failed()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// With primary which implements HealthChecker
+func TestFailoverWriter_WithHealthChecker(t *testing.T) {
+	primary := &healthCheckedWriter{failingWriter: &failingWriter{}}
+	writer := newFailoverWriter(primary, zapcore.AddSync(ioutil.Discard), 10*time.Millisecond)
+	defer writer.close()
+
+	writer.fail(errors.New("ut-error"))
+
+	// primary is not recovered by sync while health check fails
+	assert.Eventually(t, func() bool {
+		primary.mutex.Lock()
+		defer primary.mutex.Unlock()
+		return primary.checks > 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, writer.failed())
+
+	primary.mutex.Lock()
+	primary.healthy = true
+	primary.mutex.Unlock()
+
+	assert.Eventually(t, func() bool {
+		return !writer.failed()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Failover writers are listed until they are closed
+func TestListFailoverStats(t *testing.T) {
+	writer := newFailoverWriter(&failingWriter{}, zapcore.AddSync(ioutil.Discard), time.Hour)
+	writer.status.Primary, writer.status.Fallback = "ut-primary", "ut-fallback"
+
+	assert.Contains(t, ListFailoverStats(), FailoverStats{Primary: "ut-primary", Fallback: "ut-fallback"})
+
+	writer.close()
+	writer.close()
+	assert.NotContains(t, ListFailoverStats(), FailoverStats{Primary: "ut-primary", Fallback: "ut-fallback"})
+}
+
+// With output which has fallback file
+func TestNewZapLoggerWithConfig_WithFallback(t *testing.T) {
+	fallbackPath := path.Join(newTempDir(t), "fallback.log")
+
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	config.Zap.OutputPaths = []string{}
+	config.Outputs = []*OutputConfig{
+		{Path: "ut-failover://logger", Fallback: &OutputConfig{Path: fallbackPath}, HealthCheckInterval: "1h"},
+	}
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-message-1")
+	utFailoverWriters["logger"].setError(errors.New("ut-error"))
+	logger.Info("ut-message-2")
+
+	assert.Contains(t, utFailoverWriters["logger"].String(), "ut-message-1")
+	content, err := ioutil.ReadFile(fallbackPath)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), "ut-message-1")
+	assert.Contains(t, string(content), "ut-message-2")
+
+	var stats *FailoverStats
+	for _, s := range ListFailoverStats() {
+		if s.Primary == "ut-failover://logger" {
+			stats = &s
+		}
+	}
+	assert.NotNil(t, stats)
+	assert.Equal(t, fallbackPath, stats.Fallback)
+	assert.True(t, stats.Failed)
+}
+
+// With invalid fallback configs
+func TestLoader_OpenFailoverWriter_WithInvalidConfig(t *testing.T) {
+	// With invalid health check interval
+	_, _, err := defaultLoader.openWriteSyncer(&OutputConfig{
+		Path: "stdout", Fallback: &OutputConfig{Path: "stderr"}, HealthCheckInterval: "invalid",
+	})
+	assert.NotNil(t, err)
+
+	// With core as fallback
+	_, _, err = defaultLoader.openWriteSyncer(&OutputConfig{
+		Path: "stdout", Fallback: &OutputConfig{Path: "journald://"},
+	})
+	assert.NotNil(t, err)
+
+	// With invalid fallback
+	_, _, err = defaultLoader.openWriteSyncer(&OutputConfig{
+		Path: "stdout", Fallback: &OutputConfig{},
+	})
+	assert.NotNil(t, err)
+
+	// With core as primary
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	config.Outputs = []*OutputConfig{{Path: "journald://", Fallback: &OutputConfig{Path: "stderr"}}}
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+// Entries written to fallback and entries lost are counted
+func TestFailoverWriter_WithCounters(t *testing.T) {
+	primary, fallback := &failingWriter{}, &failingWriter{}
+	writer := newFailoverWriter(primary, fallback, time.Hour)
+	defer writer.close()
+
+	primary.setError(errors.New("ut-error"))
+	_, err := writer.Write([]byte("ut-message"))
+	assert.Nil(t, err)
+
+	fallback.setError(errors.New("ut-error"))
+	_, err = writer.Write([]byte("ut-message"))
+	assert.NotNil(t, err)
+
+	assert.Equal(t, uint64(1), writer.stats().Fallbacks)
+	assert.Equal(t, uint64(1), writer.stats().Dropped)
+}
+
+// Entries are dropped while primary fails
+func TestFailoverWriter_WithDrop(t *testing.T) {
+	primary := &failingWriter{}
+	writer := newFailoverWriter(primary, zapcore.AddSync(ioutil.Discard), 10*time.Millisecond)
+	writer.drop = true
+	defer writer.close()
+
+	primary.setError(errors.New("ut-error"))
+	n, err := writer.Write([]byte("ut-message-1"))
+	assert.Nil(t, err)
+	assert.Equal(t, len("ut-message-1"), n)
+	assert.Equal(t, uint64(1), writer.stats().Dropped)
+
+	// primary is retried periodically
+	primary.setError(nil)
+	assert.Eventually(t, func() bool {
+		return !writer.failed()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writer.Write([]byte("ut-message-2"))
+	assert.Equal(t, "ut-message-2", primary.String())
+	assert.Equal(t, uint64(0), writer.stats().Fallbacks)
+}
+
+// With write error policy of combined config
+func TestNewZapLoggerWithConfig_WithOnWriteError(t *testing.T) {
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	config.Zap.OutputPaths = []string{"stdout", "ut-failover://write-error-stderr"}
+	config.Outputs = []*OutputConfig{{Path: "ut-failover://write-error-drop", OnWriteError: WriteErrorDrop}}
+	config.OnWriteError = WriteErrorStderr
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+
+	utFailoverWriters["write-error-drop"].setError(errors.New("ut-error"))
+	logger.Info("ut-message")
+
+	fallbacks := make(map[string]FailoverStats)
+	for _, stats := range ListFailoverStats() {
+		fallbacks[stats.Primary] = stats
+	}
+
+	// stdout is written without policy
+	assert.NotContains(t, fallbacks, "stdout")
+	assert.Equal(t, WriteErrorStderr, fallbacks["ut-failover://write-error-stderr"].Fallback)
+	assert.Equal(t, WriteErrorDrop, fallbacks["ut-failover://write-error-drop"].Fallback)
+	assert.Equal(t, uint64(1), fallbacks["ut-failover://write-error-drop"].Dropped)
+
+	// With invalid policy
+	_, _, err = defaultLoader.openWriteSyncer(&OutputConfig{Path: "ut-failover://invalid", OnWriteError: "ut-policy"})
+	assert.NotNil(t, err)
+}
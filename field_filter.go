@@ -0,0 +1,115 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldFilter removes fields which are not allowed or denied by output, fields are matched by top level keys
+type fieldFilter struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+// Create filter of allowed and denied fields of output, nil is returned if neither of them is provided
+func newFieldFilter(output *OutputConfig) *fieldFilter {
+	if len(output.AllowFields) == 0 && len(output.DenyFields) == 0 {
+		return nil
+	}
+
+	res := &fieldFilter{}
+	if len(output.AllowFields) > 0 {
+		res.allow = make(map[string]struct{}, len(output.AllowFields))
+		for _, key := range output.AllowFields {
+			res.allow[key] = struct{}{}
+		}
+	}
+
+	res.deny = make(map[string]struct{}, len(output.DenyFields))
+	for _, key := range output.DenyFields {
+		res.deny[key] = struct{}{}
+	}
+
+	return res
+}
+
+// Wrap core with filter, core is returned as it is if filter is nil
+func (filter *fieldFilter) wrap(core zapcore.Core) zapcore.Core {
+	if filter == nil {
+		return core
+	}
+
+	return &fieldFilterCore{Core: core, filter: filter}
+}
+
+// Whether field with key is written
+func (filter *fieldFilter) keep(key string) bool {
+	if _, ok := filter.deny[key]; ok {
+		return false
+	}
+
+	if filter.allow != nil {
+		_, ok := filter.allow[key]
+		return ok
+	}
+
+	return true
+}
+
+// Remove fields which are not written, fields are copied only if any of them is removed
+// Fields following removed namespace are removed as well since they are nested in it.
+func (filter *fieldFilter) filterFields(fields []zapcore.Field) []zapcore.Field {
+	for i := range fields {
+		if filter.keep(fields[i].Key) {
+			continue
+		}
+
+		res := append(make([]zapcore.Field, 0, len(fields)), fields[:i]...)
+		for j := i; j < len(fields); j++ {
+			if !filter.keep(fields[j].Key) {
+				if fields[j].Type == zapcore.NamespaceType {
+					break
+				}
+				continue
+			}
+
+			res = append(res, fields[j])
+		}
+
+		return res
+	}
+
+	return fields
+}
+
+// fieldFilterCore removes fields which are not allowed or denied before entries are written to wrapped core
+type fieldFilterCore struct {
+	zapcore.Core
+	filter *fieldFilter
+}
+
+// With implements zapcore.Core
+func (core *fieldFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &fieldFilterCore{
+		Core:   core.Core.With(core.filter.filterFields(fields)),
+		filter: core.filter,
+	}
+}
+
+// Check implements zapcore.Core, entries are written to wrapped core directly so that errors of it are reported
+func (core *fieldFilterCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core
+func (core *fieldFilterCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return core.Core.Write(entry, core.filter.filterFields(fields))
+}
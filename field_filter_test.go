@@ -0,0 +1,93 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"path"
+	"testing"
+)
+
+func TestNewFieldFilter(t *testing.T) {
+	assert.Nil(t, newFieldFilter(&OutputConfig{}))
+
+	filter := newFieldFilter(&OutputConfig{DenyFields: []string{"user_email"}})
+	assert.Nil(t, filter.allow)
+	assert.False(t, filter.keep("user_email"))
+	assert.True(t, filter.keep("ut-key"))
+
+	// denied fields take precedence over allowed fields
+	filter = newFieldFilter(&OutputConfig{AllowFields: []string{"status", "user_email"}, DenyFields: []string{"user_email"}})
+	assert.True(t, filter.keep("status"))
+	assert.False(t, filter.keep("user_email"))
+	assert.False(t, filter.keep("ut-key"))
+}
+
+func TestFieldFilter_FilterFields(t *testing.T) {
+	filter := newFieldFilter(&OutputConfig{DenyFields: []string{"user_email", "request"}})
+
+	// fields are not copied if nothing is removed
+	fields := []zapcore.Field{zap.String("ut-key", "ut-value")}
+	assert.Equal(t, &fields[0], &filter.filterFields(fields)[0])
+
+	// fields nested in removed namespace are removed as well
+	res := filter.filterFields([]zapcore.Field{
+		zap.String("ut-key", "ut-value"),
+		zap.String("user_email", "ut@example.com"),
+		zap.Int("status", 200),
+		zap.Namespace("request"),
+		zap.String("body", "ut-body"),
+	})
+	assert.Equal(t, []zapcore.Field{zap.String("ut-key", "ut-value"), zap.Int("status", 200)}, res)
+}
+
+func TestFieldFilterCore_Write(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(newFieldFilter(&OutputConfig{AllowFields: []string{"status"}}).wrap(observed))
+
+	logger.With(zap.String("ut-key", "ut-value"), zap.Int("status", 200)).Info("ut-message", zap.String("ut-other", "ut-value"))
+	logger.Debug("ut-debug")
+
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, map[string]interface{}{"status": int64(200)}, entries[0].ContextMap())
+}
+
+func TestFieldFilter_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+  initialFields:
+    service: ut-service
+outputs:
+  - path: ` + path.Join(dir, "saas.log") + `
+    denyFields: [user_email, request_body]
+  - path: ` + path.Join(dir, "audit.log") + `
+    allowFields: [user_email]
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-message", zap.String("user_email", "ut@example.com"), zap.String("request_body", "ut-body"))
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	assert.Equal(t, `{"msg":"ut-message","service":"ut-service","user_email":"ut@example.com","request_body":"ut-body"}`+"\n",
+		readFileContent(path.Join(dir, "app.log")))
+	assert.Equal(t, `{"msg":"ut-message","service":"ut-service"}`+"\n", readFileContent(path.Join(dir, "saas.log")))
+	assert.Equal(t, `{"msg":"ut-message","user_email":"ut@example.com"}`+"\n", readFileContent(path.Join(dir, "audit.log")))
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var (
+	// tokens of filename templates, like %Y and {hostname}
+	filenameTokenPattern = regexp.MustCompile(`%[%YymdjHMS]|\{(hostname|pid)\}`)
+	// layouts of time tokens
+	filenameTimeLayouts = map[string]string{
+		"%Y": "2006",
+		"%y": "06",
+		"%m": "01",
+		"%d": "02",
+		"%H": "15",
+		"%M": "04",
+		"%S": "05",
+	}
+)
+
+// ExpandFilename expands tokens of filename template with time t, host and process, so that multiple instances on
+// one host never write to the same file, like /var/log/app-%Y%m%d-{hostname}-{pid}.log.
+//
+// Tokens are %Y, %y, %m, %d, %j (day of year), %H, %M, %S, %% (percent sign), {hostname} and {pid},
+// other characters are kept as they are.
+func ExpandFilename(template string, t time.Time) string {
+	return filenameTokenPattern.ReplaceAllStringFunc(template, func(token string) string {
+		switch token {
+		case "%%":
+			return "%"
+		case "%j":
+			return fmt.Sprintf("%03d", t.YearDay())
+		case "{hostname}":
+			hostname, err := os.Hostname()
+			if err != nil {
+				return "localhost"
+			}
+			return hostname
+		case "{pid}":
+			return strconv.Itoa(os.Getpid())
+		default:
+			return t.Format(filenameTimeLayouts[token])
+		}
+	})
+}
+
+// Whether file path contains tokens of filename template
+func isFilenameTemplate(filePath string) bool {
+	return filenameTokenPattern.MatchString(filePath)
+}
+
+// Whether any of output paths is a file path with tokens of filename template
+func hasFilenameTemplate(pathLists ...[]string) bool {
+	for _, paths := range pathLists {
+		for i := range paths {
+			if filePath, ok := toFilePath(paths[i]); ok && isFilenameTemplate(filePath) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Whether filename template contains tokens which vary with time
+func isFilenameTimeTemplate(template string) bool {
+	for _, token := range filenameTokenPattern.FindAllString(template, -1) {
+		if isFilenameTimeToken(token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Whether token of filename template varies with time
+func isFilenameTimeToken(token string) bool {
+	_, ok := filenameTimeLayouts[token]
+	return ok || token == "%j"
+}
+
+// Glob pattern of files expanded from filename template at any time
+func filenameGlob(template string) string {
+	return filenameTokenPattern.ReplaceAllStringFunc(template, func(token string) string {
+		if isFilenameTimeToken(token) {
+			return "*"
+		}
+
+		return ExpandFilename(token, time.Time{})
+	})
+}
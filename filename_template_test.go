@@ -0,0 +1,89 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpandFilename(t *testing.T) {
+	hostname, err := os.Hostname()
+	assert.Nil(t, err)
+	now := time.Date(2020, 9, 1, 8, 5, 3, 0, time.UTC)
+
+	assert.Equal(t, "/var/log/app-20200901-"+hostname+"-"+strconv.Itoa(os.Getpid())+".log",
+		ExpandFilename("/var/log/app-%Y%m%d-{hostname}-{pid}.log", now))
+	assert.Equal(t, "20-080503-245-%Y-%q-{user}", ExpandFilename("%y-%H%M%S-%j-%%Y-%q-{user}", now))
+	assert.Equal(t, "/var/log/app.log", ExpandFilename("/var/log/app.log", now))
+}
+
+func TestIsFilenameTemplate(t *testing.T) {
+	assert.True(t, isFilenameTemplate("app-{pid}.log"))
+	assert.True(t, isFilenameTemplate("app-%Y.log"))
+	assert.False(t, isFilenameTemplate("app-{user}.log"))
+
+	assert.True(t, isFilenameTimeTemplate("app-%d.log"))
+	assert.False(t, isFilenameTimeTemplate("app-%%d-{pid}.log"))
+
+	assert.True(t, hasFilenameTemplate([]string{"stdout", "logs/app-%Y%m%d.log"}))
+	assert.False(t, hasFilenameTemplate([]string{"stdout", "logs/app.log"}))
+}
+
+func TestFilenameGlob(t *testing.T) {
+	assert.Equal(t, "logs/*/app-**-%d-"+strconv.Itoa(os.Getpid())+".log", filenameGlob("logs/%Y/app-%m%d-%%d-{pid}.log"))
+}
+
+func TestTimedWriter_WithFilenameTemplate(t *testing.T) {
+	dir := newTempDir(t)
+	template := path.Join(dir, "%Y%m", "app-%d-{pid}.log")
+	pid := strconv.Itoa(os.Getpid())
+
+	writer, setNow := newTestTimedWriter(t, template, RotationConfig{Schedule: ScheduleDaily, MaxBackups: 1},
+		time.Date(2020, 9, 30, 10, 0, 0, 0, time.UTC))
+
+	writer.Write([]byte("day-1\n"))
+	setNow(time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC))
+	writer.Write([]byte("day-2\n"))
+
+	// files are not renamed
+	assert.Equal(t, "day-1\n", readFileContent(path.Join(dir, "202009", "app-30-"+pid+".log")))
+	assert.Equal(t, "day-2\n", readFileContent(path.Join(dir, "202010", "app-01-"+pid+".log")))
+
+	// the oldest file is removed
+	modTime := time.Now().Add(-time.Hour)
+	assert.Nil(t, os.Chtimes(path.Join(dir, "202009", "app-30-"+pid+".log"), modTime, modTime))
+	setNow(time.Date(2020, 10, 2, 10, 0, 0, 0, time.UTC))
+	writer.Write([]byte("day-3\n"))
+	assert.Empty(t, listFileNames(t, path.Join(dir, "202009")))
+	assert.Equal(t, []string{"app-01-" + pid + ".log", "app-02-" + pid + ".log"}, listFileNames(t, path.Join(dir, "202010")))
+}
+
+func TestFilenameTemplate_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app-{pid}.log") + `"]
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	logger.Sync()
+
+	assert.Equal(t, `{"msg":"ut-message"}`+"\n", readFileContent(path.Join(dir, "app-"+strconv.Itoa(os.Getpid())+".log")))
+}
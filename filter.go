@@ -0,0 +1,522 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FilterConfig is a rule of filters section, which drops entries matching Drop or keeps only entries matching Keep.
+// Exactly one of them should be provided. Rules are evaluated in order before entries are sampled or encoded,
+// and an entry is dropped once any of rules drops it.
+//
+// Expressions compare level, logger, msg and fields.<key> with literals, and are combined with &&, || and !
+// as well as parentheses. Operators are ==, !=, <, <=, >, >= and =~ which matches regular expression.
+// Levels are compared by severity, and values of fields are compared as numbers if literals are numbers.
+// Comparisons of missing fields are false except !=.
+//
+// Example config file in YAML:
+//
+//	filters:
+//	  - drop: logger == "health" && level < warn
+//	  - keep: fields.env == "prod" || level >= error
+//	  - drop: msg =~ "^GET /metrics"
+type FilterConfig struct {
+	// Drop is expression of entries which are dropped.
+	Drop string `json:"drop,omitempty" yaml:"drop,omitempty"`
+	// Keep is expression of entries which are kept, the rest of entries are dropped.
+	Keep string `json:"keep,omitempty" yaml:"keep,omitempty"`
+}
+
+// entryFilter evaluates rules of filters section
+type entryFilter struct {
+	rules []*filterRule
+	// keys of fields referenced by rules, entries are filtered while being written if any
+	fieldKeys map[string]struct{}
+}
+
+// filterRule is parsed FilterConfig
+type filterRule struct {
+	drop bool
+	expr filterExpr
+}
+
+// Create filter with configs, nil is returned if configs are empty
+func newEntryFilter(configs []*FilterConfig) (*entryFilter, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	res := &entryFilter{
+		rules:     make([]*filterRule, 0, len(configs)),
+		fieldKeys: make(map[string]struct{}),
+	}
+
+	for i, config := range configs {
+		if (len(config.Drop) > 0) == (len(config.Keep) > 0) {
+			return nil, errors.Errorf("exactly one of drop and keep of filter should be provided, index:%d", i)
+		}
+
+		rule := &filterRule{drop: len(config.Drop) > 0}
+		text := config.Keep
+		if rule.drop {
+			text = config.Drop
+		}
+
+		parser := &filterParser{text: text, fieldKeys: res.fieldKeys}
+		expr, err := parser.parse()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid expression of filter, index:%d, expression:%s", i, text)
+		}
+
+		rule.expr = expr
+		res.rules = append(res.rules, rule)
+	}
+
+	return res, nil
+}
+
+// Wrap core with filter, core is returned as it is if filter is nil
+func (filter *entryFilter) wrap(core zapcore.Core) zapcore.Core {
+	if filter == nil {
+		return core
+	}
+
+	return &filterCore{Core: core, filter: filter}
+}
+
+// Whether entry is kept by all of rules
+func (filter *entryFilter) allow(ctx *filterContext) bool {
+	for _, rule := range filter.rules {
+		if rule.expr.eval(ctx) == rule.drop {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterCore drops entries with rules, entries are filtered while being checked unless fields are referenced by rules
+type filterCore struct {
+	zapcore.Core
+	filter *entryFilter
+	// fields added with With() which are referenced by rules
+	context []zapcore.Field
+}
+
+// With implements zapcore.Core
+func (core *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	context := core.context
+	for i := range fields {
+		if _, ok := core.filter.fieldKeys[fields[i].Key]; ok {
+			context = append(context[:len(context):len(context)], fields[i])
+		}
+	}
+
+	return &filterCore{
+		Core:    core.Core.With(fields),
+		filter:  core.filter,
+		context: context,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *filterCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !core.Enabled(entry.Level) {
+		return checked
+	}
+
+	if len(core.filter.fieldKeys) > 0 {
+		return checked.AddCore(entry, core)
+	}
+
+	if !core.filter.allow(&filterContext{entry: entry}) {
+		return checked
+	}
+
+	return core.Core.Check(entry, checked)
+}
+
+// Write implements zapcore.Core, it is only called if fields are referenced by rules
+func (core *filterCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if core.filter.allow(&filterContext{entry: entry, context: core.context, fields: fields}) {
+		writeChecked(core.Core, entry, fields)
+	}
+
+	return nil
+}
+
+// filterContext is entry and fields evaluated by expressions
+type filterContext struct {
+	entry   zapcore.Entry
+	context []zapcore.Field
+	fields  []zapcore.Field
+}
+
+// Value of field with key, fields of entry take precedence over fields added with With()
+func (ctx *filterContext) field(key string) (interface{}, bool) {
+	for _, fields := range [][]zapcore.Field{ctx.fields, ctx.context} {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if fields[i].Key == key {
+				enc := zapcore.NewMapObjectEncoder()
+				fields[i].AddTo(enc)
+				value, ok := enc.Fields[key]
+				return value, ok
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// filterExpr is node of parsed expression
+type filterExpr interface {
+	eval(ctx *filterContext) bool
+}
+
+// filterAnd is true if both of operands are true
+type filterAnd struct {
+	left, right filterExpr
+}
+
+func (expr *filterAnd) eval(ctx *filterContext) bool {
+	return expr.left.eval(ctx) && expr.right.eval(ctx)
+}
+
+// filterOr is true if either of operands is true
+type filterOr struct {
+	left, right filterExpr
+}
+
+func (expr *filterOr) eval(ctx *filterContext) bool {
+	return expr.left.eval(ctx) || expr.right.eval(ctx)
+}
+
+// filterNot negates operand
+type filterNot struct {
+	expr filterExpr
+}
+
+func (expr *filterNot) eval(ctx *filterContext) bool {
+	return !expr.expr.eval(ctx)
+}
+
+// filterCompare compares variable of entry with literal
+type filterCompare struct {
+	// level, logger, msg or field
+	variable string
+	// key of field
+	key      string
+	op       string
+	literal  string
+	number   float64
+	isNumber bool
+	level    zapcore.Level
+	pattern  *regexp.Regexp
+}
+
+func (expr *filterCompare) eval(ctx *filterContext) bool {
+	switch expr.variable {
+	case "level":
+		return compareOrdered(expr.op, float64(ctx.entry.Level), float64(expr.level))
+	case "logger":
+		return expr.compareString(ctx.entry.LoggerName)
+	case "msg":
+		return expr.compareString(ctx.entry.Message)
+	}
+
+	value, ok := ctx.field(expr.key)
+	if !ok {
+		return expr.op == "!="
+	}
+
+	if expr.isNumber && expr.pattern == nil {
+		if number, ok := toFloat(value); ok {
+			return compareOrdered(expr.op, number, expr.number)
+		}
+	}
+
+	if text, ok := value.(string); ok {
+		return expr.compareString(text)
+	}
+
+	return expr.compareString(fmt.Sprint(value))
+}
+
+// Compare text with literal
+func (expr *filterCompare) compareString(text string) bool {
+	if expr.pattern != nil {
+		return expr.pattern.MatchString(text)
+	}
+
+	return compareOrdered(expr.op, float64(strings.Compare(text, expr.literal)), 0)
+}
+
+// Compare numbers with operator
+func compareOrdered(op string, left, right float64) bool {
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	case ">":
+		return left > right
+	default:
+		return left >= right
+	}
+}
+
+// Convert numeric value of field to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uintptr:
+		return float64(v), true
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+
+	return 0, false
+}
+
+// filterParser parses expression with recursive descent
+//
+//	or         := and ("||" and)*
+//	and        := unary ("&&" unary)*
+//	unary      := "!" unary | "(" or ")" | comparison
+//	comparison := variable operator literal
+type filterParser struct {
+	text      string
+	pos       int
+	fieldKeys map[string]struct{}
+}
+
+// Parse the whole text
+func (parser *filterParser) parse() (filterExpr, error) {
+	expr, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.skipSpaces(); parser.pos < len(parser.text) {
+		return nil, parser.errorf("unexpected %q", parser.text[parser.pos:])
+	}
+
+	return expr, nil
+}
+
+func (parser *filterParser) parseOr() (filterExpr, error) {
+	left, err := parser.parseAnd()
+	for err == nil && parser.consume("||") {
+		var right filterExpr
+		if right, err = parser.parseAnd(); err == nil {
+			left = &filterOr{left: left, right: right}
+		}
+	}
+
+	return left, err
+}
+
+func (parser *filterParser) parseAnd() (filterExpr, error) {
+	left, err := parser.parseUnary()
+	for err == nil && parser.consume("&&") {
+		var right filterExpr
+		if right, err = parser.parseUnary(); err == nil {
+			left = &filterAnd{left: left, right: right}
+		}
+	}
+
+	return left, err
+}
+
+func (parser *filterParser) parseUnary() (filterExpr, error) {
+	if parser.consume("!") {
+		expr, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &filterNot{expr: expr}, nil
+	}
+
+	if parser.consume("(") {
+		expr, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !parser.consume(")") {
+			return nil, parser.errorf("missing )")
+		}
+
+		return expr, nil
+	}
+
+	return parser.parseComparison()
+}
+
+func (parser *filterParser) parseComparison() (filterExpr, error) {
+	expr := &filterCompare{}
+
+	name := parser.word()
+	switch {
+	case name == "level" || name == "logger" || name == "msg":
+		expr.variable = name
+	case strings.HasPrefix(name, "fields.") && len(name) > len("fields."):
+		expr.variable, expr.key = "field", strings.TrimPrefix(name, "fields.")
+		parser.fieldKeys[expr.key] = struct{}{}
+	case len(name) == 0:
+		return nil, parser.errorf("missing level, logger, msg or fields.<key>")
+	default:
+		return nil, parser.errorf("unknown variable %s, expecting level, logger, msg or fields.<key>", name)
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "=~", "<", ">"} {
+		if parser.consume(op) {
+			expr.op = op
+			break
+		}
+	}
+
+	if len(expr.op) == 0 {
+		return nil, parser.errorf("missing operator after %s", name)
+	}
+
+	literal, quoted, err := parser.literal()
+	if err != nil {
+		return nil, err
+	}
+	expr.literal = literal
+
+	if expr.op == "=~" {
+		if expr.variable == "level" {
+			return nil, parser.errorf("level could not be matched with =~")
+		}
+
+		if expr.pattern, err = regexp.Compile(literal); err != nil {
+			return nil, parser.errorf("invalid regular expression %q", literal)
+		}
+
+		return expr, nil
+	}
+
+	if expr.variable == "level" {
+		if expr.level, err = ParseLevel(literal); err != nil {
+			return nil, parser.errorf("unrecognized level %s", literal)
+		}
+
+		return expr, nil
+	}
+
+	if !quoted {
+		if number, err := strconv.ParseFloat(literal, 64); err == nil {
+			expr.number, expr.isNumber = number, true
+		}
+	}
+
+	return expr, nil
+}
+
+// Parse quoted string or bare word as literal
+func (parser *filterParser) literal() (string, bool, error) {
+	parser.skipSpaces()
+	if parser.pos >= len(parser.text) {
+		return "", false, parser.errorf("missing literal")
+	}
+
+	if parser.text[parser.pos] != '"' {
+		word := parser.word()
+		if len(word) == 0 {
+			return "", false, parser.errorf("missing literal")
+		}
+
+		return word, false, nil
+	}
+
+	// find the closing quote which is not escaped
+	end := parser.pos + 1
+	for ; end < len(parser.text) && parser.text[end] != '"'; end++ {
+		if parser.text[end] == '\\' {
+			end++
+		}
+	}
+
+	if end >= len(parser.text) {
+		return "", false, parser.errorf("unterminated string")
+	}
+
+	literal, err := strconv.Unquote(parser.text[parser.pos : end+1])
+	if err != nil {
+		return "", false, parser.errorf("invalid string %s", parser.text[parser.pos:end+1])
+	}
+
+	parser.pos = end + 1
+	return literal, true, nil
+}
+
+// Parse identifier, number or bare word
+func (parser *filterParser) word() string {
+	parser.skipSpaces()
+	start := parser.pos
+	for parser.pos < len(parser.text) {
+		r := rune(parser.text[parser.pos])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_.-+", r) {
+			break
+		}
+		parser.pos++
+	}
+
+	return parser.text[start:parser.pos]
+}
+
+// Consume token if text continues with it
+func (parser *filterParser) consume(token string) bool {
+	parser.skipSpaces()
+	if strings.HasPrefix(parser.text[parser.pos:], token) {
+		parser.pos += len(token)
+		return true
+	}
+
+	return false
+}
+
+func (parser *filterParser) skipSpaces() {
+	for parser.pos < len(parser.text) && unicode.IsSpace(rune(parser.text[parser.pos])) {
+		parser.pos++
+	}
+}
+
+// Error with position of parser
+func (parser *filterParser) errorf(format string, args ...interface{}) error {
+	return errors.Errorf("%s at %d", fmt.Sprintf(format, args...), parser.pos)
+}
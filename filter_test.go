@@ -0,0 +1,155 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"path"
+	"testing"
+)
+
+func TestNewEntryFilter(t *testing.T) {
+	// empty configs
+	filter, err := newEntryFilter(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, filter)
+
+	filter, err = newEntryFilter([]*FilterConfig{
+		{Drop: `logger == "health" && level < warn`},
+		{Keep: `fields.env == prod || level >= error`},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, filter.rules, 2)
+	assert.Equal(t, map[string]struct{}{"env": {}}, filter.fieldKeys)
+
+	// invalid configs
+	for _, config := range []*FilterConfig{
+		{},
+		{Drop: "level > info", Keep: "level > info"},
+		{Drop: "level"},
+		{Drop: "level >"},
+		{Drop: "level > ut-level"},
+		{Drop: "level =~ info"},
+		{Drop: "ut-variable == 1"},
+		{Drop: "fields. == 1"},
+		{Drop: `msg =~ "("`},
+		{Drop: `msg == "ut-message`},
+		{Drop: "(level > info"},
+		{Drop: "level > info info"},
+		{Drop: "level > info &&"},
+	} {
+		_, err := newEntryFilter([]*FilterConfig{config})
+		assert.NotNil(t, err, config)
+	}
+}
+
+func TestEntryFilter_Allow(t *testing.T) {
+	for expression, cases := range map[string]map[bool]*filterContext{
+		`logger == "health" && level < warn`: {
+			true:  {entry: zapcore.Entry{LoggerName: "health", Level: zapcore.InfoLevel}},
+			false: {entry: zapcore.Entry{LoggerName: "health", Level: zapcore.WarnLevel}},
+		},
+		`!(logger != health) || msg =~ "^GET /metrics"`: {
+			true:  {entry: zapcore.Entry{Message: "GET /metrics 200"}},
+			false: {entry: zapcore.Entry{Message: "GET /api 200"}},
+		},
+		`fields.status >= 500`: {
+			true:  {fields: []zapcore.Field{zap.Int("status", 502)}},
+			false: {fields: []zapcore.Field{zap.Int("status", 200)}},
+		},
+		`fields.env == "prod"`: {
+			true:  {context: []zapcore.Field{zap.String("env", "prod")}},
+			false: {context: []zapcore.Field{zap.String("env", "prod")}, fields: []zapcore.Field{zap.String("env", "dev")}},
+		},
+		`fields.ok == true`: {
+			true:  {fields: []zapcore.Field{zap.Bool("ok", true)}},
+			false: {fields: []zapcore.Field{zap.Bool("ok", false)}},
+		},
+		`fields.missing != 1`: {
+			true:  {},
+			false: {fields: []zapcore.Field{zap.Int("missing", 1)}},
+		},
+	} {
+		filter, err := newEntryFilter([]*FilterConfig{{Drop: expression}})
+		assert.Nil(t, err)
+
+		for dropped, ctx := range cases {
+			assert.Equal(t, !dropped, filter.allow(ctx), expression)
+		}
+	}
+}
+
+func TestFilterCore_Check(t *testing.T) {
+	filter, err := newEntryFilter([]*FilterConfig{{Drop: `logger == health`}})
+	assert.Nil(t, err)
+
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(filter.wrap(observed))
+
+	logger.Named("health").Info("ut-health")
+	logger.Named("api").Info("ut-api")
+	logger.Named("api").Debug("ut-debug")
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, "ut-api", logs.All()[0].Message)
+
+	// fields added with With() are filtered as well
+	filter, err = newEntryFilter([]*FilterConfig{{Keep: `fields.env == prod`}})
+	assert.Nil(t, err)
+
+	observed, logs = observer.New(zapcore.InfoLevel)
+	logger = zap.New(filter.wrap(observed))
+
+	logger.With(zap.String("env", "prod"), zap.String("ut-key", "ut-value")).Info("ut-prod")
+	logger.With(zap.String("env", "prod")).Info("ut-dev", zap.String("env", "dev"))
+	logger.Info("ut-missing")
+	logger.Info("ut-field", zap.String("env", "prod"))
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, "ut-prod", logs.All()[0].Message)
+	assert.Equal(t, "ut-field", logs.All()[1].Message)
+}
+
+func TestFilters_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+filters:
+  - drop: logger == "health" && level < warn
+  - drop: fields.path =~ "^/metrics"
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Named("health").Info("ut-health")
+	logger.Named("health").Warn("ut-unhealthy")
+	logger.Info("ut-metrics", zap.String("path", "/metrics"))
+	logger.Info("ut-api", zap.String("path", "/api"))
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	assert.Equal(t, `{"msg":"ut-unhealthy"}`+"\n"+`{"msg":"ut-api","path":"/api"}`+"\n",
+		readFileContent(path.Join(dir, "app.log")))
+
+	// invalid expression
+	config.Filters[0].Drop = "ut-expression"
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestWithFilter(t *testing.T) {
+	config := NewConfigWithOptions(WithFilter(FilterConfig{Drop: "level < warn"}), WithFilter(FilterConfig{Keep: "msg == ut"}))
+	assert.Equal(t, []*FilterConfig{{Drop: "level < warn"}, {Keep: "msg == ut"}}, config.Filters)
+}
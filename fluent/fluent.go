@@ -0,0 +1,294 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package fluent registers fluent scheme with rklogger.RegisterWriter, so that entries are forwarded to
+// Fluentd or Fluent Bit with forward protocol by output paths like:
+//
+//	outputPaths:
+//	  # forward over tcp
+//	  - fluent://fluentd.example.com:24224?tag=app.access
+//	  # forward over unix socket
+//	  - fluent:///var/run/fluent/fluent.sock?tag=app.access
+//
+// Import the package for side effects in order to enable the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/fluent"
+//
+// Query parameters:
+//
+//	tag: tag of events, name of executable by default
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//	tls, tlsCA, tlsCert, tlsKey, tlsServerName and tlsSkipVerify of TLS
+//
+// Entries encoded as json are forwarded as records with the same keys, the rest of entries are forwarded
+// as records with message key. Entries are buffered in bounded memory while fluentd is unavailable, connection is
+// re-established with exponential backoff before each retry of failed batches.
+package fluent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/rookie-ninja/rk-logger/internal/tlsconfig"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Scheme is the scheme of output paths which are forwarded to fluentd.
+const Scheme = "fluent"
+
+// MessageKey is the key of record which holds entries not encoded as json.
+const MessageKey = "message"
+
+func init() {
+	if err := rklogger.RegisterWriter(Scheme, newWriterWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// forwarder sends batches of events to fluentd, it is only used by the flushing goroutine of batch.Writer
+type forwarder struct {
+	network   string
+	address   string
+	tag       string
+	tlsConfig *tls.Config
+	conn      net.Conn
+}
+
+// writer encodes entries as events of forward protocol and forwards them in batches
+type writer struct {
+	*batch.Writer
+	forwarder *forwarder
+}
+
+// Create writer with url of output path
+func newWriterWithURL(u url.URL) (zapcore.WriteSyncer, error) {
+	config, forwarder, err := parseURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWriter(config, forwarder), nil
+}
+
+// Create writer which forwards batches with forwarder
+func newWriter(config batch.Config, forwarder *forwarder) *writer {
+	return &writer{
+		Writer:    batch.NewWriter(config, forwarder.forward),
+		forwarder: forwarder,
+	}
+}
+
+// Write implements zapcore.WriteSyncer, p is encoded as one event with current time
+func (writer *writer) Write(p []byte) (int, error) {
+	event, err := encodeEvent(time.Now(), p)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := writer.Writer.Write(event); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close flushes queued entries and closes connection to fluentd
+func (writer *writer) Close() error {
+	return multierr.Append(writer.Writer.Close(), writer.forwarder.close())
+}
+
+// Parse batching config and forwarder from url
+func parseURL(u *url.URL) (batch.Config, *forwarder, error) {
+	config := batch.DefaultConfig()
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, err
+	}
+
+	tlsConfig, query, err := tlsconfig.FromQuery(query)
+	if err != nil {
+		return config, nil, err
+	}
+
+	for key := range query {
+		if key != "tag" {
+			return config, nil, errors.Errorf("unknown query of fluent url, key:%s", key)
+		}
+	}
+
+	forwarder := &forwarder{
+		tag:       query.Get("tag"),
+		tlsConfig: tlsConfig,
+	}
+
+	if len(u.Host) > 0 {
+		forwarder.network, forwarder.address = "tcp", u.Host
+	} else if len(u.Path) > 0 {
+		forwarder.network, forwarder.address = "unix", u.Path
+	} else {
+		return config, nil, errors.Errorf("address is required in fluent url, url:%s", u.String())
+	}
+
+	if len(forwarder.tag) == 0 {
+		forwarder.tag = filepath.Base(os.Args[0])
+	}
+
+	return config, forwarder, nil
+}
+
+// Connect to fluentd, deadline of ctx applies to TLS handshake as well
+func (forwarder *forwarder) connect(ctx context.Context) error {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, forwarder.network, forwarder.address)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to fluentd, address:%s", forwarder.address)
+	}
+
+	if forwarder.tlsConfig != nil {
+		config := forwarder.tlsConfig.Clone()
+		if len(config.ServerName) == 0 {
+			config.ServerName, _, _ = net.SplitHostPort(forwarder.address)
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return errors.Wrapf(err, "failed to handshake with fluentd, address:%s", forwarder.address)
+		}
+		conn = tlsConn
+	}
+
+	forwarder.conn = conn
+	return nil
+}
+
+// Forward events in PackedForward mode, connection is closed on failure and re-established by retries
+func (forwarder *forwarder) forward(ctx context.Context, events [][]byte) error {
+	if forwarder.conn == nil {
+		if err := forwarder.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	payload, err := encodeMessage(forwarder.tag, events)
+	if err != nil {
+		return batch.Permanent(err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		forwarder.conn.SetWriteDeadline(deadline)
+	}
+
+	if _, err := forwarder.conn.Write(payload); err != nil {
+		forwarder.close()
+		return errors.Wrapf(err, "failed to forward events, address:%s", forwarder.address)
+	}
+
+	return nil
+}
+
+// Close connection to fluentd
+func (forwarder *forwarder) close() error {
+	if forwarder.conn == nil {
+		return nil
+	}
+
+	err := forwarder.conn.Close()
+	forwarder.conn = nil
+	return err
+}
+
+// Encode [tag, entries, option] of PackedForward mode, entries are concatenated events
+func encodeMessage(tag string, events [][]byte) ([]byte, error) {
+	entries := bytes.Join(events, nil)
+
+	buf := &bytes.Buffer{}
+	enc := msgpack.NewEncoder(buf)
+	err := multierr.Combine(
+		enc.EncodeArrayLen(3),
+		enc.EncodeString(tag),
+		enc.EncodeBytes(entries),
+		enc.EncodeMapLen(1),
+		enc.EncodeString("size"),
+		enc.EncodeInt(int64(len(events))),
+	)
+
+	return buf.Bytes(), err
+}
+
+// Encode [time, record] of an entry, time is encoded as EventTime with nanoseconds
+func encodeEvent(now time.Time, p []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(0x92)
+
+	// EventTime is ext type 0 with seconds and nanoseconds in big endian
+	buf.Write([]byte{0xd7, 0x00})
+	binary.Write(buf, binary.BigEndian, uint32(now.Unix()))
+	binary.Write(buf, binary.BigEndian, uint32(now.Nanosecond()))
+
+	enc := msgpack.NewEncoder(buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(toRecord(p)); err != nil {
+		return nil, errors.Wrap(err, "failed to encode fluent event")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Convert entry to record, entries not encoded as json object are placed with message key
+func toRecord(p []byte) map[string]interface{} {
+	p = bytes.TrimRight(p, "\r\n")
+
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+
+	var record map[string]interface{}
+	if err := dec.Decode(&record); err != nil || dec.More() || record == nil {
+		return map[string]interface{}{MessageKey: string(p)}
+	}
+
+	return toMsgpackValue(record).(map[string]interface{})
+}
+
+// Convert numbers decoded from json into integers if possible, so that they are encoded as msgpack integers
+func toMsgpackValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = toMsgpackValue(v[k])
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = toMsgpackValue(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package fluent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// eventTime decodes EventTime ext type in tests
+type eventTime struct {
+	time.Time
+}
+
+// MarshalMsgpack implements msgpack.Marshaler
+func (t *eventTime) MarshalMsgpack() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return b, nil
+}
+
+// UnmarshalMsgpack implements msgpack.Unmarshaler
+func (t *eventTime) UnmarshalMsgpack(b []byte) error {
+	t.Time = time.Unix(int64(binary.BigEndian.Uint32(b)), int64(binary.BigEndian.Uint32(b[4:])))
+	return nil
+}
+
+func init() {
+	msgpack.RegisterExt(0, (*eventTime)(nil))
+}
+
+// message is message of PackedForward mode
+type message struct {
+	tag     string
+	records []map[string]interface{}
+	times   []time.Time
+	size    int
+}
+
+// Start tcp server and return its address along with channel of received messages
+func newFluentServer(t *testing.T) (string, chan *message) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	messages := make(chan *message, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				dec := msgpack.NewDecoder(conn)
+				for {
+					var raw []interface{}
+					if err := dec.Decode(&raw); err != nil {
+						return
+					}
+
+					msg := &message{tag: raw[0].(string)}
+					msg.size = int(raw[2].(map[string]interface{})["size"].(int8))
+
+					entries := msgpack.NewDecoder(bytes.NewReader(raw[1].([]byte)))
+					for {
+						var event []interface{}
+						if err := entries.Decode(&event); err != nil {
+							break
+						}
+						msg.times = append(msg.times, event[0].(*eventTime).Time)
+						msg.records = append(msg.records, event[1].(map[string]interface{}))
+					}
+					messages <- msg
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+// Wait for message from channel
+func receiveMessage(t *testing.T, messages chan *message) *message {
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "fluent message is not received")
+		return &message{}
+	}
+}
+
+func TestParseURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("fluent://fluentd.example.com:24224?tag=app.access&batchSize=10&tlsSkipVerify=true")
+	config, forwarder, err := parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", forwarder.network)
+	assert.Equal(t, "fluentd.example.com:24224", forwarder.address)
+	assert.Equal(t, "app.access", forwarder.tag)
+	assert.NotNil(t, forwarder.tlsConfig)
+	assert.Equal(t, 10, config.Size)
+
+	// With unix socket and default tag
+	u, _ = url.Parse("fluent:///var/run/fluent.sock")
+	_, forwarder, err = parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "unix", forwarder.network)
+	assert.Equal(t, "/var/run/fluent.sock", forwarder.address)
+	assert.NotEmpty(t, forwarder.tag)
+	assert.Nil(t, forwarder.tlsConfig)
+}
+
+func TestParseURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"fluent://",
+		"fluent://localhost:24224?unknown=x",
+		"fluent://localhost:24224?linger=x",
+		"fluent://localhost:24224?tls=x",
+	} {
+		u, _ := url.Parse(raw)
+		_, _, err := parseURL(u)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestToRecord(t *testing.T) {
+	record := toRecord([]byte(`{"level":"info","count":1,"ratio":0.5,"nested":{"id":2},"list":[3]}` + "\n"))
+	assert.Equal(t, map[string]interface{}{
+		"level":  "info",
+		"count":  int64(1),
+		"ratio":  0.5,
+		"nested": map[string]interface{}{"id": int64(2)},
+		"list":   []interface{}{int64(3)},
+	}, record)
+
+	// With console encoding
+	record = toRecord([]byte("2020-01-01T00:00:00.000Z\tINFO\thello\n"))
+	assert.Equal(t, map[string]interface{}{MessageKey: "2020-01-01T00:00:00.000Z\tINFO\thello"}, record)
+
+	// With json array
+	record = toRecord([]byte(`[1]`))
+	assert.Equal(t, map[string]interface{}{MessageKey: "[1]"}, record)
+}
+
+func TestWriter_HappyCase(t *testing.T) {
+	address, messages := newFluentServer(t)
+	u, _ := url.Parse("fluent://" + address + "?tag=ut&linger=1h")
+	config, forwarder, err := parseURL(u)
+	assert.Nil(t, err)
+
+	writer := newWriter(config, forwarder)
+	before := time.Now()
+	writer.Write([]byte(`{"msg":"a"}` + "\n"))
+	writer.Write([]byte(`{"msg":"b"}` + "\n"))
+	assert.Nil(t, writer.Sync())
+
+	msg := receiveMessage(t, messages)
+	assert.Equal(t, "ut", msg.tag)
+	assert.Equal(t, 2, msg.size)
+	assert.Equal(t, []map[string]interface{}{{"msg": "a"}, {"msg": "b"}}, msg.records)
+	assert.False(t, msg.times[0].Before(before.Truncate(time.Second)))
+
+	assert.Nil(t, writer.Close())
+}
+
+func TestWriter_WithReconnect(t *testing.T) {
+	address, messages := newFluentServer(t)
+	u, _ := url.Parse("fluent://" + address + "?tag=ut&linger=1h&backoff=1ms")
+	config, forwarder, err := parseURL(u)
+	assert.Nil(t, err)
+
+	writer := newWriter(config, forwarder)
+	defer writer.Close()
+
+	writer.Write([]byte(`{"msg":"a"}`))
+	assert.Nil(t, writer.Sync())
+	receiveMessage(t, messages)
+
+	// connection is re-established after it is broken
+	forwarder.conn.Close()
+	writer.Write([]byte(`{"msg":"b"}`))
+	assert.Nil(t, writer.Sync())
+	msg := receiveMessage(t, messages)
+	assert.Equal(t, []map[string]interface{}{{"msg": "b"}}, msg.records)
+}
+
+func TestWriter_WithUnavailableFluentd(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	listener.Close()
+
+	writer := newWriter(batch.Config{Linger: time.Hour, Retries: 1, Backoff: time.Millisecond}, &forwarder{
+		network: "tcp",
+		address: address,
+		tag:     "ut",
+	})
+
+	writer.Write([]byte(`{"msg":"a"}`))
+	assert.NotNil(t, writer.Sync())
+	assert.Nil(t, writer.Close())
+}
+
+func TestRegister(t *testing.T) {
+	// connection is established once entries are flushed
+	sink, closeFunc, err := zap.Open("fluent://localhost:24224?tag=ut")
+	assert.Nil(t, err)
+	assert.NotNil(t, sink)
+	closeFunc()
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encoding is the name of encoding which encodes entries as GELF messages.
+const Encoding = "gelf"
+
+// pool of buffers returned by encoder
+var bufferPool = buffer.NewPool()
+
+// encoder encodes entries as GELF 1.1 messages, fields are encoded by wrapped json encoder and
+// added as additional fields with underscore prefix
+type encoder struct {
+	zapcore.Encoder
+	host       string
+	lineEnding string
+}
+
+// NewEncoder creates encoder of GELF 1.1 messages with encoder config, only duration, time and line ending
+// settings are applied, keys of message, level, time, logger name, caller and stacktrace are defined by GELF.
+func NewEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	lineEnding := config.LineEnding
+	if len(lineEnding) == 0 {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+
+	// entries are encoded by GELF, wrapped encoder encodes fields only
+	config.MessageKey, config.LevelKey, config.TimeKey = "", "", ""
+	config.NameKey, config.CallerKey, config.StacktraceKey = "", "", ""
+	if config.EncodeTime == nil {
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+	if config.EncodeDuration == nil {
+		config.EncodeDuration = zapcore.StringDurationEncoder
+	}
+
+	host, _ := os.Hostname()
+	return &encoder{
+		Encoder:    zapcore.NewJSONEncoder(config),
+		host:       host,
+		lineEnding: lineEnding,
+	}, nil
+}
+
+// Clone implements zapcore.Encoder
+func (enc *encoder) Clone() zapcore.Encoder {
+	return &encoder{
+		Encoder:    enc.Encoder.Clone(),
+		host:       enc.host,
+		lineEnding: enc.lineEnding,
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *encoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := enc.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded.Bytes()))
+	decoder.UseNumber()
+	values := make(map[string]interface{})
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	msg := make(map[string]interface{}, len(values)+8)
+	for k, v := range values {
+		addField(msg, k, v)
+	}
+
+	msg["version"] = "1.1"
+	msg["host"] = enc.host
+	msg["short_message"] = entry.Message
+	msg["timestamp"] = json.Number(formatTimestamp(entry.Time))
+	msg["level"] = severity(entry.Level)
+	if len(entry.LoggerName) > 0 {
+		msg["_logger"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		msg["_caller"] = entry.Caller.TrimmedPath()
+	}
+	if len(entry.Stack) > 0 {
+		msg["full_message"] = entry.Message + "\n" + entry.Stack
+	}
+
+	res, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufferPool.Get()
+	buf.Write(res)
+	buf.AppendString(enc.lineEnding)
+	return buf, nil
+}
+
+// Map level of zap to severity of syslog, fatal is mapped to crit like journal and trace is mapped to debug
+func severity(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return 7
+	case level == zapcore.InfoLevel:
+		return 6
+	case level == zapcore.WarnLevel:
+		return 4
+	case level == zapcore.ErrorLevel:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// Format time as unix seconds with milliseconds, like 1600000000.123
+func formatTimestamp(t time.Time) string {
+	millis := t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+	return strconv.FormatFloat(float64(millis)/1000, 'f', 3, 64)
+}
+
+// Add value as additional field of message, name is prefixed with underscore and sanitized.
+// Values other than strings and numbers are added as json since GELF does not support them.
+func addField(msg map[string]interface{}, name string, value interface{}) {
+	name = "_" + sanitizeName(name)
+	// _id is reserved by GELF
+	if name == "_id" {
+		name = "__id"
+	}
+
+	switch v := value.(type) {
+	case nil:
+	case string, json.Number:
+		msg[name] = v
+	default:
+		raw, _ := json.Marshal(v)
+		msg[name] = string(raw)
+	}
+}
+
+// Replace characters which are not allowed in names of additional fields with underscore
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
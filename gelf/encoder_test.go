@@ -0,0 +1,104 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package gelf
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"testing"
+	"time"
+)
+
+// Encode entry with gelf encoder and decode the message
+func encodeMessage(t *testing.T, enc zapcore.Encoder, entry zapcore.Entry, fields ...zapcore.Field) map[string]interface{} {
+	buf, err := enc.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	defer buf.Free()
+
+	assert.Equal(t, byte('\n'), buf.Bytes()[buf.Len()-1])
+
+	msg := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &msg))
+	return msg
+}
+
+func TestEncoder_EncodeEntry(t *testing.T) {
+	enc, err := NewEncoder(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Unix(1600000000, 123456789),
+		LoggerName: "ut",
+		Message:    "ut-message",
+		Caller:     zapcore.NewEntryCaller(0, "/src/pkg/file.go", 10, true),
+	}
+	hostname, _ := os.Hostname()
+
+	assert.Equal(t, map[string]interface{}{
+		"version":       "1.1",
+		"host":          hostname,
+		"short_message": "ut-message",
+		"timestamp":     1600000000.123,
+		"level":         float64(4),
+		"_logger":       "ut",
+		"_caller":       "pkg/file.go:10",
+		"_user":         "ut-user",
+		"_attempt":      float64(2),
+		"_enabled":      "true",
+		"_request":      `{"path":"/"}`,
+		"__id":          "ut-id",
+		"_user_name":    "ut-name",
+	}, encodeMessage(t, enc, entry,
+		zap.String("user", "ut-user"),
+		zap.Int("attempt", 2),
+		zap.Bool("enabled", true),
+		zap.Any("request", map[string]string{"path": "/"}),
+		zap.String("id", "ut-id"),
+		zap.String("user name", "ut-name")))
+}
+
+func TestEncoder_WithStacktrace(t *testing.T) {
+	enc, _ := NewEncoder(zapcore.EncoderConfig{LineEnding: "\n"})
+
+	msg := encodeMessage(t, enc, zapcore.Entry{Level: zapcore.ErrorLevel, Message: "ut-message", Stack: "ut-stack"})
+	assert.Equal(t, "ut-message\nut-stack", msg["full_message"])
+	assert.Equal(t, float64(3), msg["level"])
+	assert.NotContains(t, msg, "_logger")
+	assert.NotContains(t, msg, "_caller")
+}
+
+func TestEncoder_Clone(t *testing.T) {
+	enc, _ := NewEncoder(zap.NewProductionEncoderConfig())
+	enc.AddString("service", "api")
+
+	clone := enc.Clone()
+	clone.AddString("request", "1")
+
+	msg := encodeMessage(t, clone, zapcore.Entry{})
+	assert.Equal(t, "api", msg["_service"])
+	assert.Equal(t, "1", msg["_request"])
+
+	// fields of clone are not added to original encoder
+	assert.NotContains(t, encodeMessage(t, enc, zapcore.Entry{}), "_request")
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, 7, severity(zapcore.DebugLevel))
+	assert.Equal(t, 6, severity(zapcore.InfoLevel))
+	assert.Equal(t, 4, severity(zapcore.WarnLevel))
+	assert.Equal(t, 3, severity(zapcore.ErrorLevel))
+	assert.Equal(t, 2, severity(zapcore.DPanicLevel))
+	assert.Equal(t, 2, severity(zapcore.FatalLevel))
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	assert.Equal(t, "1600000000.123", formatTimestamp(time.Unix(1600000000, 123456789)))
+	assert.Equal(t, "1600000000.005", formatTimestamp(time.Unix(1600000000, 5000000)))
+	assert.Equal(t, "-62135596800.000", formatTimestamp(time.Time{}))
+}
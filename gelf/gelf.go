@@ -0,0 +1,401 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package gelf registers gelf encoding with rklogger.RegisterEncoder and gelf scheme with rklogger.RegisterWriter,
+// so that entries are sent to Graylog as GELF messages by config like:
+//
+//	encoding: gelf
+//	outputPaths:
+//	  # chunked udp with gzip compression
+//	  - gelf://graylog.example.com:12201?fields=env:prod,team:payments
+//	  # null byte delimited tcp
+//	  - gelf://graylog.example.com:12201?network=tcp&tls=true
+//
+// Import the package for side effects in order to enable the encoding and the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/gelf"
+//
+// Query parameters:
+//
+//	network:     udp or tcp, udp by default
+//	compression: gzip, zlib or none of udp messages, gzip by default
+//	chunkSize:   max size of udp datagrams, 1420 by default which fits WAN
+//	fields:      additional fields of every message, like key1:value1,key2:value2
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//	tls, tlsCA, tlsCert, tlsKey, tlsServerName and tlsSkipVerify of TLS, which is only supported by tcp
+//
+// Entries of other encodings are sent as short messages. Messages over udp exceeding chunk size are split into
+// up to 128 chunks, larger messages are dropped. Messages over tcp are not compressed as Graylog requires.
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/rookie-ninja/rk-logger/internal/tlsconfig"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Scheme is the scheme of output paths which are sent to Graylog.
+	Scheme = "gelf"
+
+	// CompressionGzip compresses udp messages with gzip.
+	CompressionGzip = "gzip"
+	// CompressionZlib compresses udp messages with zlib.
+	CompressionZlib = "zlib"
+	// CompressionNone sends udp messages without compression.
+	CompressionNone = "none"
+
+	// default max size of udp datagrams
+	defaultChunkSize = 1420
+	// size of header of chunks, which is magic bytes, message id, sequence number and count
+	chunkHeaderSize = 12
+	// max number of chunks of a message
+	maxChunks = 128
+)
+
+// magic bytes of chunked messages
+var chunkMagic = []byte{0x1e, 0x0f}
+
+func init() {
+	if err := rklogger.RegisterEncoder(Encoding, NewEncoder); err != nil {
+		panic(err)
+	}
+
+	if err := rklogger.RegisterWriter(Scheme, newWriterWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// writer sends batches of GELF messages
+type writer struct {
+	*batch.Writer
+	sender *sender
+}
+
+// sender sends GELF messages over udp or tcp, it is only used by the flushing goroutine of batch.Writer
+type sender struct {
+	network     string
+	address     string
+	compression string
+	chunkSize   int
+	host        string
+	// additional fields with underscore prefix
+	fields    map[string]interface{}
+	tlsConfig *tls.Config
+	conn      net.Conn
+	// first message of batch being sent and number of its messages which were sent,
+	// so that retries of batch do not send datagrams again
+	current *[]byte
+	sent    int
+}
+
+// Create writer with url of output path
+func newWriterWithURL(u url.URL) (zapcore.WriteSyncer, error) {
+	config, sender, err := parseURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &writer{
+		Writer: batch.NewWriter(config, sender.send),
+		sender: sender,
+	}, nil
+}
+
+// Write implements zapcore.WriteSyncer, p is converted to GELF message with additional fields
+func (writer *writer) Write(p []byte) (int, error) {
+	if _, err := writer.Writer.Write(writer.sender.toMessage(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close flushes queued messages and closes connection to Graylog
+func (writer *writer) Close() error {
+	return multierr.Append(writer.Writer.Close(), writer.sender.close())
+}
+
+// Parse batching config and sender from url
+func parseURL(u *url.URL) (batch.Config, *sender, error) {
+	config := batch.DefaultConfig()
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, err
+	}
+
+	tlsConfig, query, err := tlsconfig.FromQuery(query)
+	if err != nil {
+		return config, nil, err
+	}
+
+	sender := &sender{
+		network:     "udp",
+		address:     u.Host,
+		compression: CompressionGzip,
+		chunkSize:   defaultChunkSize,
+		fields:      make(map[string]interface{}),
+		tlsConfig:   tlsConfig,
+	}
+	sender.host, _ = os.Hostname()
+
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		switch key {
+		case "network":
+			sender.network = value
+		case "compression":
+			sender.compression = value
+		case "chunkSize":
+			sender.chunkSize, err = strconv.Atoi(value)
+			if err == nil && sender.chunkSize <= chunkHeaderSize {
+				err = errors.Errorf("chunk size must be larger than %d", chunkHeaderSize)
+			}
+		case "fields":
+			for _, pair := range strings.Split(value, ",") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) != 2 || len(kv[0]) == 0 {
+					return config, nil, errors.Errorf("invalid field in gelf url, field:%s", pair)
+				}
+				addField(sender.fields, kv[0], kv[1])
+			}
+		default:
+			return config, nil, errors.Errorf("unknown query of gelf url, key:%s", key)
+		}
+
+		if err != nil {
+			return config, nil, errors.Wrapf(err, "invalid query of gelf url, key:%s", key)
+		}
+	}
+
+	if len(sender.address) == 0 {
+		return config, nil, errors.New("address is missing in gelf url")
+	}
+
+	switch sender.network {
+	case "udp", "tcp":
+	default:
+		return config, nil, errors.Errorf("network is not supported in gelf url, network:%s", sender.network)
+	}
+
+	switch sender.compression {
+	case CompressionGzip, CompressionZlib, CompressionNone:
+	default:
+		return config, nil, errors.Errorf("compression is not supported in gelf url, compression:%s", sender.compression)
+	}
+
+	if sender.network == "udp" && sender.tlsConfig != nil {
+		return config, nil, errors.New("tls is not supported by udp in gelf url")
+	}
+
+	return config, sender, nil
+}
+
+// Convert entry to GELF message with additional fields, entries which are not json objects are sent as short messages
+func (sender *sender) toMessage(p []byte) []byte {
+	p = bytes.TrimRight(p, "\r\n")
+
+	msg := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	if len(p) == 0 || p[0] != '{' || decoder.Decode(&msg) != nil {
+		msg = map[string]interface{}{
+			"version":       "1.1",
+			"host":          sender.host,
+			"short_message": string(p),
+			"timestamp":     json.Number(formatTimestamp(time.Now())),
+			"level":         severity(zapcore.InfoLevel),
+		}
+	} else if len(sender.fields) == 0 {
+		return p
+	}
+
+	for k, v := range sender.fields {
+		if _, ok := msg[k]; !ok {
+			msg[k] = v
+		}
+	}
+
+	res, _ := json.Marshal(msg)
+	return res
+}
+
+// Connect to Graylog, deadline of ctx applies to TLS handshake as well
+func (sender *sender) connect(ctx context.Context) error {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, sender.network, sender.address)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to graylog, address:%s", sender.address)
+	}
+
+	if sender.tlsConfig != nil {
+		config := sender.tlsConfig.Clone()
+		if len(config.ServerName) == 0 {
+			config.ServerName, _, _ = net.SplitHostPort(sender.address)
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return errors.Wrapf(err, "failed to handshake with graylog, address:%s", sender.address)
+		}
+		conn = tlsConn
+	}
+
+	sender.conn = conn
+	return nil
+}
+
+// Send messages of batch, connection is closed on failure and re-established by retries
+func (sender *sender) send(ctx context.Context, messages [][]byte) error {
+	if sender.current != &messages[0] {
+		sender.current, sender.sent = &messages[0], 0
+	}
+
+	if sender.conn == nil {
+		if err := sender.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		sender.conn.SetWriteDeadline(deadline)
+	}
+
+	if sender.network == "tcp" {
+		// messages are delimited by null byte
+		buf := &bytes.Buffer{}
+		for _, msg := range messages[sender.sent:] {
+			buf.Write(msg)
+			buf.WriteByte(0)
+		}
+
+		if _, err := sender.conn.Write(buf.Bytes()); err != nil {
+			sender.close()
+			return errors.Wrapf(err, "failed to send messages, address:%s", sender.address)
+		}
+
+		sender.sent = len(messages)
+		return nil
+	}
+
+	var dropped error
+	for sender.sent < len(messages) {
+		datagrams, err := sender.chunk(messages[sender.sent])
+		if err != nil {
+			// message is too large, the rest of batch is sent
+			dropped = err
+			sender.sent++
+			continue
+		}
+
+		for i := range datagrams {
+			if _, err := sender.conn.Write(datagrams[i]); err != nil {
+				sender.close()
+				return errors.Wrapf(err, "failed to send messages, address:%s", sender.address)
+			}
+		}
+		sender.sent++
+	}
+
+	return batch.Permanent(dropped)
+}
+
+// Compress message and split it into chunks if message exceeds chunk size
+func (sender *sender) chunk(msg []byte) ([][]byte, error) {
+	payload, err := compress(sender.compression, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) <= sender.chunkSize {
+		return [][]byte{payload}, nil
+	}
+
+	size := sender.chunkSize - chunkHeaderSize
+	count := (len(payload) + size - 1) / size
+	if count > maxChunks {
+		return nil, errors.Errorf("message is too large for gelf udp, size:%d, chunks:%d", len(payload), count)
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	res := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+
+		chunk := make([]byte, 0, chunkHeaderSize+end-i*size)
+		chunk = append(chunk, chunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, payload[i*size:end]...)
+		res = append(res, chunk)
+	}
+
+	return res, nil
+}
+
+// Compress message with compression
+func compress(compression string, msg []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	var w io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(buf)
+	case CompressionZlib:
+		w = zlib.NewWriter(buf)
+	default:
+		return msg, nil
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Close connection to Graylog
+func (sender *sender) close() error {
+	if sender.conn == nil {
+		return nil
+	}
+
+	err := sender.conn.Close()
+	sender.conn = nil
+	return err
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Start udp server and return its address along with channel of received datagrams
+func newUDPServer(t *testing.T) (string, chan []byte) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	datagrams := make(chan []byte, 100)
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			datagrams <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+
+	return conn.LocalAddr().String(), datagrams
+}
+
+// Wait for datagram from channel
+func receiveDatagram(t *testing.T, datagrams chan []byte) []byte {
+	select {
+	case datagram := <-datagrams:
+		return datagram
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "datagram is not received")
+		return nil
+	}
+}
+
+// Decompress payload with gzip or zlib
+func decompress(t *testing.T, payload []byte) map[string]interface{} {
+	var reader io.Reader
+	var err error
+	if payload[0] == 0x1f {
+		reader, err = gzip.NewReader(bytes.NewReader(payload))
+	} else {
+		reader, err = zlib.NewReader(bytes.NewReader(payload))
+	}
+	assert.Nil(t, err)
+
+	content, _ := ioutil.ReadAll(reader)
+	msg := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(content, &msg))
+	return msg
+}
+
+func TestParseURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("gelf://graylog:12201?network=tcp&compression=none&chunkSize=8154&fields=env:prod,team:a&tlsSkipVerify=true&batchSize=10")
+	config, sender, err := parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, config.Size)
+	assert.Equal(t, "tcp", sender.network)
+	assert.Equal(t, "graylog:12201", sender.address)
+	assert.Equal(t, CompressionNone, sender.compression)
+	assert.Equal(t, 8154, sender.chunkSize)
+	assert.Equal(t, map[string]interface{}{"_env": "prod", "_team": "a"}, sender.fields)
+	assert.NotNil(t, sender.tlsConfig)
+
+	// With defaults
+	u, _ = url.Parse("gelf://graylog:12201")
+	_, sender, err = parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "udp", sender.network)
+	assert.Equal(t, CompressionGzip, sender.compression)
+	assert.Equal(t, defaultChunkSize, sender.chunkSize)
+	assert.Nil(t, sender.tlsConfig)
+}
+
+func TestParseURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"gelf://",
+		"gelf://graylog:12201?network=unix",
+		"gelf://graylog:12201?compression=x",
+		"gelf://graylog:12201?chunkSize=x",
+		"gelf://graylog:12201?chunkSize=12",
+		"gelf://graylog:12201?fields=x",
+		"gelf://graylog:12201?tls=true",
+		"gelf://graylog:12201?tls=x",
+		"gelf://graylog:12201?linger=x",
+		"gelf://graylog:12201?unknown=x",
+	} {
+		u, _ := url.Parse(raw)
+		_, _, err := parseURL(u)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestSender_ToMessage(t *testing.T) {
+	sender := &sender{host: "ut-host", fields: map[string]interface{}{}}
+
+	// gelf messages are sent as they are
+	assert.Equal(t, `{"short_message":"a"}`, string(sender.toMessage([]byte(`{"short_message":"a"}`+"\n"))))
+
+	// With additional fields, fields of entry take precedence
+	sender.fields = map[string]interface{}{"_env": "prod", "_team": "a"}
+	assert.Equal(t, `{"_env":"prod","_team":"b","short_message":"a"}`,
+		string(sender.toMessage([]byte(`{"short_message":"a","_team":"b"}`))))
+
+	// With console encoding
+	msg := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(sender.toMessage([]byte("INFO\tut-message\n")), &msg))
+	assert.Equal(t, "1.1", msg["version"])
+	assert.Equal(t, "ut-host", msg["host"])
+	assert.Equal(t, "INFO\tut-message", msg["short_message"])
+	assert.Equal(t, float64(6), msg["level"])
+	assert.Equal(t, "prod", msg["_env"])
+}
+
+func TestSender_Chunk(t *testing.T) {
+	sender := &sender{compression: CompressionNone, chunkSize: 20}
+
+	// message within chunk size is not chunked
+	datagrams, err := sender.chunk([]byte("0123456789"))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("0123456789")}, datagrams)
+
+	datagrams, err = sender.chunk([]byte("0123456789abcdefghijk"))
+	assert.Nil(t, err)
+	assert.Len(t, datagrams, 3)
+
+	payload := make([]byte, 0)
+	for i := range datagrams {
+		assert.Equal(t, chunkMagic, datagrams[i][:2])
+		assert.Equal(t, datagrams[0][2:10], datagrams[i][2:10])
+		assert.Equal(t, []byte{byte(i), 3}, datagrams[i][10:12])
+		payload = append(payload, datagrams[i][12:]...)
+	}
+	assert.Equal(t, "0123456789abcdefghijk", string(payload))
+
+	// With too many chunks
+	_, err = sender.chunk(make([]byte, 8*maxChunks+1))
+	assert.NotNil(t, err)
+}
+
+func TestWriter_WithUDP(t *testing.T) {
+	address, datagrams := newUDPServer(t)
+	u, _ := url.Parse(fmt.Sprintf("gelf://%s?linger=1h&fields=env:prod", address))
+	syncer, err := newWriterWithURL(*u)
+	assert.Nil(t, err)
+	defer syncer.(*writer).Close()
+
+	syncer.Write([]byte(`{"version":"1.1","short_message":"ut-message"}` + "\n"))
+	assert.Nil(t, syncer.Sync())
+
+	msg := decompress(t, receiveDatagram(t, datagrams))
+	assert.Equal(t, "ut-message", msg["short_message"])
+	assert.Equal(t, "prod", msg["_env"])
+}
+
+func TestWriter_WithChunkedUDP(t *testing.T) {
+	address, datagrams := newUDPServer(t)
+	u, _ := url.Parse(fmt.Sprintf("gelf://%s?linger=1h&compression=zlib&chunkSize=64", address))
+	syncer, err := newWriterWithURL(*u)
+	assert.Nil(t, err)
+	defer syncer.(*writer).Close()
+
+	// random message which is not compressed well
+	message := strings.Repeat("0123456789abcdef", 4) + fmt.Sprint(time.Now().UnixNano())
+	syncer.Write([]byte(`{"version":"1.1","short_message":"` + fmt.Sprintf("%x", message) + `"}`))
+	assert.Nil(t, syncer.Sync())
+
+	first := receiveDatagram(t, datagrams)
+	assert.Equal(t, chunkMagic, first[:2])
+
+	chunks := make([][]byte, first[11])
+	chunks[first[10]] = first[12:]
+	for i := 1; i < len(chunks); i++ {
+		datagram := receiveDatagram(t, datagrams)
+		chunks[datagram[10]] = datagram[12:]
+	}
+
+	msg := decompress(t, bytes.Join(chunks, nil))
+	assert.Equal(t, fmt.Sprintf("%x", message), msg["short_message"])
+}
+
+func TestWriter_WithTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			msg, err := reader.ReadString(0)
+			if err != nil {
+				return
+			}
+			received <- msg
+		}
+	}()
+
+	u, _ := url.Parse(fmt.Sprintf("gelf://%s?network=tcp&linger=1h", listener.Addr().String()))
+	syncer, err := newWriterWithURL(*u)
+	assert.Nil(t, err)
+
+	syncer.Write([]byte(`{"short_message":"a"}` + "\n"))
+	syncer.Write([]byte(`{"short_message":"b"}` + "\n"))
+	assert.Nil(t, syncer.(*writer).Close())
+
+	for _, expected := range []string{`{"short_message":"a"}` + "\x00", `{"short_message":"b"}` + "\x00"} {
+		select {
+		case msg := <-received:
+			assert.Equal(t, expected, msg)
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "message is not received")
+		}
+	}
+}
+
+func TestSender_SendWithUnreachableAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	sender := &sender{network: "tcp", address: address}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NotNil(t, sender.send(ctx, [][]byte{[]byte("{}")}))
+}
+
+func TestRegister(t *testing.T) {
+	address, datagrams := newUDPServer(t)
+
+	config := rklogger.NewConfigWithOptions(rklogger.WithOutput(fmt.Sprintf("gelf://%s?linger=1h", address)))
+	config.Zap.Encoding = Encoding
+	logger, closer, err := rklogger.NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	msg := decompress(t, receiveDatagram(t, datagrams))
+	assert.Equal(t, "ut-message", msg["short_message"])
+	assert.Equal(t, float64(6), msg["level"])
+}
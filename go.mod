@@ -3,9 +3,28 @@ module github.com/rookie-ninja/rk-logger
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/aws/aws-sdk-go v1.44.100
+	github.com/eclipse/paho.mqtt.golang v1.3.5
+	github.com/fsnotify/fsnotify v1.5.4
+	github.com/go-logr/logr v1.2.4
+	github.com/hashicorp/hcl v1.0.0
+	github.com/klauspost/compress v1.15.9
+	github.com/lib/pq v1.10.9
+	github.com/nats-io/nats.go v1.20.0
+	github.com/nats-io/nuid v1.0.1
 	github.com/pkg/errors v0.9.1
-	github.com/stretchr/testify v1.6.1
+	github.com/segmentio/kafka-go v0.4.47
+	github.com/sirupsen/logrus v1.9.3
+	github.com/stretchr/testify v1.8.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	go.opentelemetry.io/proto/otlp v0.19.0
+	go.uber.org/multierr v1.5.0
 	go.uber.org/zap v1.16.0
+	golang.org/x/oauth2 v0.0.0-20211104180415-d3ed0bb246c8
+	google.golang.org/grpc v1.47.0
+	google.golang.org/protobuf v1.28.1
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
-	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
+	gopkg.in/yaml.v3 v3.0.1
+	k8s.io/klog/v2 v2.100.1
 )
@@ -0,0 +1,26 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+// DefaultGRPCLoggerName is the name of logger of gRPC internals, so that its level could be set in levels of config.
+const DefaultGRPCLoggerName = "grpc"
+
+// GRPCConfig is logger of gRPC internals installed by SetAsGRPCLoggerWithConfig of package grpclog, which logs
+// through the same encoders and sinks as the application. It is ignored unless package grpclog is used.
+//
+// Example config file in YAML:
+//
+//	grpc:
+//	  verbosity: 2
+//	levels:
+//	  grpc: warn
+type GRPCConfig struct {
+	// Verbosity is verbosity level of gRPC, verbose logs of gRPC whose levels are above it are dropped,
+	// 0 by default like GRPC_GO_LOG_VERBOSITY_LEVEL of gRPC.
+	Verbosity int `json:"verbosity" yaml:"verbosity"`
+	// Name is name of logger, grpc by default.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package grpclog installs zap logger as grpclog.LoggerV2, so that gRPC internals log through the same encoders
+// and sinks as the application:
+//
+//	logger, err := grpclog.SetAsGRPCLoggerWithConfig(config)
+//
+// Verbosity of gRPC and name of logger are read from grpc section of config:
+//
+//	grpc:
+//	  verbosity: 2
+//	levels:
+//	  grpc: warn
+//
+// Info, warning, error and fatal logs of gRPC are logged with levels of the same names, fatal logs exit like zap.
+// Callers of entries are the callers in gRPC, instead of the ones in this package.
+package grpclog
+
+import (
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/grpclog"
+	"strings"
+)
+
+// frames of Logger and grpclog between callers in gRPC and zap logger
+const callerSkip = 3
+
+// Logger implements grpclog.LoggerV2 and grpclog.DepthLoggerV2 with zap logger.
+type Logger struct {
+	logger    *zap.Logger
+	verbosity int
+}
+
+// NewLoggerV2 creates Logger which logs with zap logger, V(l) of gRPC is true if l is not above verbosity.
+func NewLoggerV2(logger *zap.Logger, verbosity int) *Logger {
+	return &Logger{
+		logger:    logger.WithOptions(zap.AddCallerSkip(callerSkip)),
+		verbosity: verbosity,
+	}
+}
+
+// SetAsGRPCLogger installs zap logger as logger of gRPC with verbosity, see NewLoggerV2.
+// It should be called before any gRPC functions are called, since logger of gRPC is not mutex protected.
+func SetAsGRPCLogger(logger *zap.Logger, verbosity int) {
+	grpclog.SetLoggerV2(NewLoggerV2(logger, verbosity))
+}
+
+// SetAsGRPCLoggerWithConfig builds zap logger with config and installs it as logger of gRPC with verbosity and
+// name in grpc section of config, logger is returned so that it could be synced before exiting.
+func SetAsGRPCLoggerWithConfig(config *rklogger.Config, opts ...zap.Option) (*zap.Logger, error) {
+	logger, err := rklogger.NewZapLoggerWithConfig(config, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	verbosity, name := 0, rklogger.DefaultGRPCLoggerName
+	if config.GRPC != nil {
+		verbosity = config.GRPC.Verbosity
+		if len(config.GRPC.Name) > 0 {
+			name = config.GRPC.Name
+		}
+	}
+
+	logger = logger.Named(name)
+	SetAsGRPCLogger(logger, verbosity)
+	return logger, nil
+}
+
+// Info implements grpclog.LoggerV2
+func (l *Logger) Info(args ...interface{}) {
+	l.log(zapcore.InfoLevel, 0, fmt.Sprint, args)
+}
+
+// Infoln implements grpclog.LoggerV2
+func (l *Logger) Infoln(args ...interface{}) {
+	l.log(zapcore.InfoLevel, 0, sprintln, args)
+}
+
+// Infof implements grpclog.LoggerV2
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(zapcore.InfoLevel, format, args)
+}
+
+// InfoDepth implements grpclog.DepthLoggerV2
+func (l *Logger) InfoDepth(depth int, args ...interface{}) {
+	l.log(zapcore.InfoLevel, depth, sprintln, args)
+}
+
+// Warning implements grpclog.LoggerV2
+func (l *Logger) Warning(args ...interface{}) {
+	l.log(zapcore.WarnLevel, 0, fmt.Sprint, args)
+}
+
+// Warningln implements grpclog.LoggerV2
+func (l *Logger) Warningln(args ...interface{}) {
+	l.log(zapcore.WarnLevel, 0, sprintln, args)
+}
+
+// Warningf implements grpclog.LoggerV2
+func (l *Logger) Warningf(format string, args ...interface{}) {
+	l.logf(zapcore.WarnLevel, format, args)
+}
+
+// WarningDepth implements grpclog.DepthLoggerV2
+func (l *Logger) WarningDepth(depth int, args ...interface{}) {
+	l.log(zapcore.WarnLevel, depth, sprintln, args)
+}
+
+// Error implements grpclog.LoggerV2
+func (l *Logger) Error(args ...interface{}) {
+	l.log(zapcore.ErrorLevel, 0, fmt.Sprint, args)
+}
+
+// Errorln implements grpclog.LoggerV2
+func (l *Logger) Errorln(args ...interface{}) {
+	l.log(zapcore.ErrorLevel, 0, sprintln, args)
+}
+
+// Errorf implements grpclog.LoggerV2
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(zapcore.ErrorLevel, format, args)
+}
+
+// ErrorDepth implements grpclog.DepthLoggerV2
+func (l *Logger) ErrorDepth(depth int, args ...interface{}) {
+	l.log(zapcore.ErrorLevel, depth, sprintln, args)
+}
+
+// Fatal implements grpclog.LoggerV2
+func (l *Logger) Fatal(args ...interface{}) {
+	l.log(zapcore.FatalLevel, 0, fmt.Sprint, args)
+}
+
+// Fatalln implements grpclog.LoggerV2
+func (l *Logger) Fatalln(args ...interface{}) {
+	l.log(zapcore.FatalLevel, 0, sprintln, args)
+}
+
+// Fatalf implements grpclog.LoggerV2
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.logf(zapcore.FatalLevel, format, args)
+}
+
+// FatalDepth implements grpclog.DepthLoggerV2
+func (l *Logger) FatalDepth(depth int, args ...interface{}) {
+	l.log(zapcore.FatalLevel, depth, sprintln, args)
+}
+
+// V implements grpclog.LoggerV2
+func (l *Logger) V(level int) bool {
+	return level <= l.verbosity
+}
+
+// Log message of args formatted with format at level, depth is number of frames skipped by caller in addition
+func (l *Logger) log(level zapcore.Level, depth int, format func(...interface{}) string, args []interface{}) {
+	if !l.logger.Core().Enabled(level) {
+		return
+	}
+
+	logger := l.logger
+	if depth > 0 {
+		logger = logger.WithOptions(zap.AddCallerSkip(depth))
+	}
+
+	if checked := logger.Check(level, format(args...)); checked != nil {
+		checked.Write()
+	}
+}
+
+// Log message formatted like fmt.Sprintf at level
+func (l *Logger) logf(level zapcore.Level, format string, args []interface{}) {
+	if !l.logger.Core().Enabled(level) {
+		return
+	}
+
+	if checked := l.logger.Check(level, fmt.Sprintf(format, args...)); checked != nil {
+		checked.Write()
+	}
+}
+
+// Format args like fmt.Sprintln without trailing newline
+func sprintln(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package grpclog
+
+import (
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc/grpclog"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogger_HappyCase(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	SetAsGRPCLogger(zap.New(observed, zap.AddCaller()), 1)
+
+	grpclog.Info("ut-info", 1)
+	grpclog.Infoln("ut-infoln", 1)
+	grpclog.Infof("ut-infof %d", 1)
+	grpclog.Warning("ut-warning")
+	grpclog.Warningf("ut-warningf %s", "ut")
+	grpclog.Error("ut-error")
+	grpclog.Errorln("ut-errorln")
+	grpclog.Component("ut").Info("ut-component")
+	grpclog.Component("ut").Errorf("ut-component-%s", "errorf")
+
+	expected := []observer.LoggedEntry{
+		{Entry: zapcore.Entry{Level: zapcore.InfoLevel, Message: "ut-info1"}},
+		{Entry: zapcore.Entry{Level: zapcore.InfoLevel, Message: "ut-infoln 1"}},
+		{Entry: zapcore.Entry{Level: zapcore.InfoLevel, Message: "ut-infof 1"}},
+		{Entry: zapcore.Entry{Level: zapcore.WarnLevel, Message: "ut-warning"}},
+		{Entry: zapcore.Entry{Level: zapcore.WarnLevel, Message: "ut-warningf ut"}},
+		{Entry: zapcore.Entry{Level: zapcore.ErrorLevel, Message: "ut-error"}},
+		{Entry: zapcore.Entry{Level: zapcore.ErrorLevel, Message: "ut-errorln"}},
+		{Entry: zapcore.Entry{Level: zapcore.InfoLevel, Message: "[ut] ut-component"}},
+		{Entry: zapcore.Entry{Level: zapcore.ErrorLevel, Message: "[ut] ut-component-errorf"}},
+	}
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, len(expected))
+	for i := range entries {
+		assert.Equal(t, expected[i].Level, entries[i].Level)
+		assert.Equal(t, expected[i].Message, entries[i].Message)
+		// callers are the ones calling grpclog
+		assert.Equal(t, "grpclog_test.go", filepath.Base(entries[i].Caller.File), entries[i].Message)
+	}
+
+	// verbosity
+	assert.True(t, grpclog.V(1))
+	assert.False(t, grpclog.V(2))
+}
+
+func TestLogger_WithDisabledLevel(t *testing.T) {
+	observed, logs := observer.New(zapcore.WarnLevel)
+	logger := NewLoggerV2(zap.New(observed), 0)
+
+	logger.Info("ut-info")
+	logger.InfoDepth(1, "ut-info")
+	logger.Infof("ut-info")
+	logger.Warningln("ut-warningln")
+	logger.WarningDepth(1, "ut-warning-depth")
+	logger.ErrorDepth(1, "ut-error-depth")
+
+	assert.Equal(t, 3, logs.Len())
+	assert.False(t, logger.V(1))
+}
+
+func TestLogger_WithFatal(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger := NewLoggerV2(zap.New(observed, zap.OnFatal(zapcore.WriteThenPanic)), 0)
+
+	assert.Panics(t, func() { logger.Fatal("ut-fatal") })
+	assert.Panics(t, func() { logger.Fatalln("ut-fatalln") })
+	assert.Panics(t, func() { logger.Fatalf("ut-fatalf") })
+	assert.Panics(t, func() { logger.FatalDepth(1, "ut-fatal-depth") })
+	assert.Equal(t, 4, logs.Len())
+	assert.Equal(t, zapcore.FatalLevel, logs.All()[0].Level)
+}
+
+func TestSetAsGRPCLoggerWithConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rk-logger-grpclog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+    nameKey: logger
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+grpc:
+  verbosity: 2
+levels:
+  grpc: warn
+`)
+
+	config, err := rklogger.NewConfigWithBytes(raw, rklogger.YAML)
+	assert.Nil(t, err)
+
+	logger, err := SetAsGRPCLoggerWithConfig(config)
+	assert.Nil(t, err)
+
+	grpclog.Info("ut-ignored")
+	grpclog.Warning("ut-warning")
+	assert.True(t, grpclog.V(2))
+	assert.Nil(t, logger.Sync())
+
+	content, err := ioutil.ReadFile(path.Join(dir, "app.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"logger":"grpc","msg":"ut-warning"}`+"\n", string(content))
+
+	// With custom name
+	config.GRPC = &rklogger.GRPCConfig{Name: "ut-grpc"}
+	logger, err = SetAsGRPCLoggerWithConfig(config)
+	assert.Nil(t, err)
+	assert.False(t, grpclog.V(1))
+	grpclog.Info("ut-info")
+	assert.Nil(t, logger.Sync())
+
+	content, err = ioutil.ReadFile(path.Join(dir, "app.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `{"logger":"ut-grpc","msg":"ut-info"}`)
+
+	// With invalid config
+	_, err = SetAsGRPCLoggerWithConfig(&rklogger.Config{})
+	assert.NotNil(t, err)
+}
@@ -8,6 +8,8 @@ package rklogger
 
 import (
 	"encoding/json"
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/hcl"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -15,7 +17,7 @@ import (
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"os"
-	"path"
+	"path/filepath"
 	"reflect"
 )
 
@@ -73,7 +75,7 @@ var (
 	LumberjackConfig = NewLumberjackConfigDefault()
 )
 
-// FileType is a config file type which support json and yaml currently.
+// FileType is a config file type which support json, yaml, toml and hcl currently.
 type FileType int
 
 const (
@@ -81,14 +83,18 @@ const (
 	JSON FileType = 0
 	// YAML https://yaml.org/
 	YAML FileType = 1
+	// TOML https://toml.io/
+	TOML FileType = 2
+	// HCL https://github.com/hashicorp/hcl
+	HCL FileType = 3
 )
 
 // Stringfy above config file types.
 func (fileType FileType) String() string {
-	names := [...]string{"JSON", "YAML"}
+	names := [...]string{"JSON", "YAML", "TOML", "HCL"}
 
 	// Please do not forget to change the boundary while adding a new config file types
-	if fileType < JSON || fileType > YAML {
+	if fileType < JSON || fileType > HCL {
 		return "UNKNOWN"
 	}
 
@@ -146,54 +152,24 @@ func NewZapStdoutConfig() *zap.Config {
 // lumberjack.Logger could be empty, if not provided,
 // then, we will use default write sync
 func NewZapLoggerWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (*zap.Logger, *zap.Config, error) {
-	if raw == nil {
-		return nil, nil, errors.New("input byte array is nil")
-	}
+	return defaultLoader.NewZapLoggerWithBytes(raw, fileType, opts...)
+}
 
-	if len(raw) == 0 {
-		return nil, nil, errors.New("byte array is empty")
+// NewZapLoggerWithBytes inits zap logger with byte array from content of config file and options of Loader
+func (loader *Loader) NewZapLoggerWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (*zap.Logger, *zap.Config, error) {
+	config, err := loader.NewConfigWithBytes(raw, fileType)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Initialize zap logger from config file
-	var logger *zap.Logger
-	var err error
-	zapConfig := &zap.Config{}
-	lumberConfig := &lumberjack.Logger{}
-
-	if fileType == JSON {
-		// parse zap json file
-		if err := json.Unmarshal(raw, zapConfig); err != nil {
-			return nil, nil, err
-		}
-
-		// parse lumberjack json file
-		if err := json.Unmarshal(raw, lumberConfig); err != nil {
-			return nil, nil, err
-		}
-
-		logger, err = NewZapLoggerWithConf(zapConfig, lumberConfig, opts...)
-	} else if fileType == YAML {
-		// parse zap yaml file
-		if err := yaml.Unmarshal(raw, zapConfig); err != nil {
-			return nil, nil, err
-		}
-
-		// parse lumberjack yaml file
-		if err := yaml.Unmarshal(raw, lumberConfig); err != nil {
-			return nil, nil, err
-		}
-
-		logger, err = NewZapLoggerWithConf(zapConfig, lumberConfig, opts...)
-	} else {
-		logger, err = nil, errors.New("invalid config file")
-	}
+	logger, err := loader.NewZapLoggerWithConfig(config, opts...)
 
 	// make sure we return nil for logger and logger config
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return logger, zapConfig, err
+	return logger, config.Zap, nil
 }
 
 // NewZapLoggerWithConfPath init zap logger with config file path
@@ -201,6 +177,11 @@ func NewZapLoggerWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (*
 // lumberjack.Logger could be empty, if not provided,
 // then, we will use default write sync
 func NewZapLoggerWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (*zap.Logger, *zap.Config, error) {
+	return defaultLoader.NewZapLoggerWithConfPath(filePath, fileType, opts...)
+}
+
+// NewZapLoggerWithConfPath inits zap logger with config file path and options of Loader
+func (loader *Loader) NewZapLoggerWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (*zap.Logger, *zap.Config, error) {
 	if len(filePath) == 0 {
 		return nil, nil, errors.New("file path is empty")
 	}
@@ -218,7 +199,7 @@ func NewZapLoggerWithConfPath(filePath string, fileType FileType, opts ...zap.Op
 			return logger, config, readErr
 		}
 
-		logger, config, err = NewZapLoggerWithBytes(bytes, fileType, opts...)
+		logger, config, err = loader.NewZapLoggerWithBytes(bytes, fileType, opts...)
 	}
 
 	return logger, config, err
@@ -229,114 +210,84 @@ func NewZapLoggerWithConfPath(filePath string, fileType FileType, opts ...zap.Op
 // lumberjack.Logger could be empty, if not provided,
 // then, we will use default write sync
 func NewZapLoggerWithConf(config *zap.Config, lumber *lumberjack.Logger, opts ...zap.Option) (*zap.Logger, error) {
+	return defaultLoader.NewZapLoggerWithConf(config, lumber, opts...)
+}
+
+// NewZapLoggerWithConf inits zap logger with config and options of Loader
+// Level and config of logger are registered with DefaultLoggerName in level registry of Loader.
+func (loader *Loader) NewZapLoggerWithConf(config *zap.Config, lumber *lumberjack.Logger, opts ...zap.Option) (*zap.Logger, error) {
+	logger, err := loader.buildZapLoggerWithConf(config, lumber, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	loader.levels.RegisterConfig(DefaultLoggerName, NewConfig(config, lumber))
+	return logger, nil
+}
+
+// Build zap logger with zap config and lumberjack config, level is not registered
+func (loader *Loader) buildZapLoggerWithConf(config *zap.Config, lumber *lumberjack.Logger, opts ...zap.Option) (*zap.Logger, error) {
 	// Validate parameters
 	if config == nil {
 		return nil, errors.New("zap config is nil")
 	}
 
-	if lumber == nil {
-		return config.Build(opts...)
+	// zap resolves relative paths against current working directory, open files by ourselves with base directory,
+	// outputs of registered cores are not sinks of zap either, nor does zap know filename templates
+	if lumber == nil && (len(loader.baseDir) > 0 || hasCoreOutput(config.OutputPaths) ||
+		hasFilenameTemplate(config.OutputPaths, config.ErrorOutputPaths)) {
+		return loader.buildZapLogger(config, newOutputConfigs(config.OutputPaths, nil), newOutputConfigs(config.ErrorOutputPaths, nil), opts...)
 	}
 
-	sync := make([]zapcore.WriteSyncer, 0, 0)
-	// Iterate output path and attach to lumberjack
-	// Remember, each logger will use same lumberjack logger configuration
-	for i := range config.OutputPaths {
-		if config.OutputPaths[i] != "stdout" && config.OutputPaths[i] != "stderr" {
-			lumberNew := &lumberjack.Logger{
-				Filename:   config.OutputPaths[i],
-				MaxAge:     lumber.MaxAge,
-				MaxBackups: lumber.MaxBackups,
-				MaxSize:    lumber.MaxSize,
-				Compress:   lumber.Compress,
-				LocalTime:  lumber.LocalTime,
-			}
-
-			sync = append(sync, zapcore.AddSync(lumberNew))
-		} else {
-			stdout, close, err := zap.Open(config.OutputPaths[i])
-			// just close the syncer if err occurs
-			if err != nil {
-				close()
-			} else {
-				sync = append(sync, stdout)
-			}
+	if lumber == nil {
+		// files are opened by zap, make sure parent directories exist
+		if err := loader.ensureDirs(config.OutputPaths, config.ErrorOutputPaths); err != nil {
+			return nil, err
 		}
-	}
 
-	core := zapcore.NewCore(
-		generateEncoder(config),
-		zap.CombineWriteSyncers(sync...),
-		config.Level)
-
-	// add initial fields
-	initialFields := make([]zap.Field, 0, 0)
-	for k, v := range config.InitialFields {
-		initialFields = append(initialFields, zap.Any(k, v))
-	}
-
-	// add error output sync
-	errSink := make([]zapcore.WriteSyncer, 0, 0)
-	if len(config.ErrorOutputPaths) > 0 {
-		for i := range config.ErrorOutputPaths {
-			if config.ErrorOutputPaths[i] != "stdout" && config.ErrorOutputPaths[i] != "stderr" {
-				lumberNew := &lumberjack.Logger{
-					Filename:   config.ErrorOutputPaths[i],
-					MaxAge:     lumber.MaxAge,
-					MaxBackups: lumber.MaxBackups,
-					MaxSize:    lumber.MaxSize,
-					Compress:   lumber.Compress,
-					LocalTime:  lumber.LocalTime,
-				}
-
-				errSink = append(errSink, zapcore.AddSync(lumberNew))
-			} else {
-				stdout, close, err := zap.Open(config.ErrorOutputPaths[i])
-				// just close the syncer if err occurs
-				if err != nil {
-					close()
-				} else {
-					errSink = append(errSink, stdout)
-				}
-			}
+		// levels like trace are encoded with registered names
+		levelEncoder, err := newLevelNameEncoder(config.EncoderConfig.EncodeLevel, nil)
+		if err != nil {
+			return nil, err
 		}
 
-		opts = append(opts, zap.ErrorOutput(zap.CombineWriteSyncers(errSink...)))
+		built := *config
+		built.EncoderConfig.EncodeLevel = levelEncoder
+		return built.Build(opts...)
 	}
 
-	return zap.New(core, opts...).With(initialFields...), nil
+	// Remember, each logger will use same lumberjack logger configuration
+	return loader.buildZapLogger(config, newOutputConfigs(config.OutputPaths, lumber), newOutputConfigs(config.ErrorOutputPaths, lumber), opts...)
 }
 
 // NewLumberjackLoggerWithBytes inits lumberjack logger as write sync with raw byte array of config file
 func NewLumberjackLoggerWithBytes(raw []byte, fileType FileType) (*lumberjack.Logger, error) {
-	if raw == nil {
-		return nil, errors.New("input byte array is nil")
-	}
+	return defaultLoader.NewLumberjackLoggerWithBytes(raw, fileType)
+}
 
-	if len(raw) == 0 {
-		return nil, errors.New("byte array is empty")
+// NewLumberjackLoggerWithBytes inits lumberjack logger with raw byte array of config file and options of Loader
+func (loader *Loader) NewLumberjackLoggerWithBytes(raw []byte, fileType FileType) (*lumberjack.Logger, error) {
+	config, err := loader.NewConfigWithBytes(raw, fileType)
+	if err != nil {
+		return nil, err
 	}
 
-	logger := &lumberjack.Logger{}
-	// unmarshal as yaml
-	if fileType == YAML {
-		if err := yaml.Unmarshal(raw, logger); err != nil {
-			return nil, err
-		}
-	} else if fileType == JSON {
-		if err := json.Unmarshal(raw, logger); err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, errors.New("unknown type")
+	// lumberjack section is optional in combined config
+	if config.Lumberjack == nil {
+		return &lumberjack.Logger{}, nil
 	}
 
-	return logger, nil
+	return config.Lumberjack, nil
 }
 
 // NewLumberjackLoggerWithConfPath inits lumberjack logger as write sync with lumberjack config file path
 // File path needs to be absolute path
 func NewLumberjackLoggerWithConfPath(filePath string, fileType FileType) (*lumberjack.Logger, error) {
+	return defaultLoader.NewLumberjackLoggerWithConfPath(filePath, fileType)
+}
+
+// NewLumberjackLoggerWithConfPath inits lumberjack logger with lumberjack config file path and options of Loader
+func (loader *Loader) NewLumberjackLoggerWithConfPath(filePath string, fileType FileType) (*lumberjack.Logger, error) {
 	if len(filePath) == 0 {
 		return nil, errors.New("file path is empty")
 	}
@@ -350,7 +301,7 @@ func NewLumberjackLoggerWithConfPath(filePath string, fileType FileType) (*lumbe
 		bytes, readErr := ioutil.ReadFile(filePath)
 
 		if readErr == nil {
-			logger, err = NewLumberjackLoggerWithBytes(bytes, fileType)
+			logger, err = loader.NewLumberjackLoggerWithBytes(bytes, fileType)
 		} else {
 			err = readErr
 		}
@@ -360,7 +311,7 @@ func NewLumberjackLoggerWithConfPath(filePath string, fileType FileType) (*lumbe
 }
 
 func validateFilePath(filePath string) error {
-	_, err := os.Stat(filePath)
+	_, err := os.Stat(filepath.Clean(filePath))
 
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -373,20 +324,143 @@ func validateFilePath(filePath string) error {
 	return err
 }
 
+// Unmarshal content of config file into target with file type
+func unmarshalWithFileType(raw []byte, fileType FileType, target interface{}) error {
+	if fileType == JSON {
+		return json.Unmarshal(raw, target)
+	} else if fileType == YAML {
+		return yaml.Unmarshal(raw, target)
+	} else if fileType == TOML {
+		return unmarshalTOML(raw, target)
+	} else if fileType == HCL {
+		return unmarshalHCL(raw, target)
+	}
+
+	return errors.New("invalid config file type")
+}
+
+// Decode content of config file into generic map with file type
+func decodeWithFileType(raw []byte, fileType FileType) (map[string]interface{}, error) {
+	if fileType == TOML {
+		return decodeTOML(raw)
+	} else if fileType == HCL {
+		return decodeHCL(raw)
+	}
+
+	content := make(map[string]interface{})
+	if err := unmarshalWithFileType(raw, fileType, &content); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// Unmarshal toml content into target
+// Neither zap.Config nor lumberjack.Logger carries toml tags, so we decode toml into a generic map
+// and convert it to json in order to reuse the json tags of the target
+func unmarshalTOML(raw []byte, target interface{}) error {
+	content, err := decodeTOML(raw)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalMapWithJSON(content, target)
+}
+
+// Decode toml content into generic map
+func decodeTOML(raw []byte) (map[string]interface{}, error) {
+	content := make(map[string]interface{})
+	if _, err := toml.Decode(string(raw), &content); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+// Unmarshal hcl content into target
+// hcl decodes every block as a list of objects, so single element lists are flattened
+// before converting to json, the same way as toml
+func unmarshalHCL(raw []byte, target interface{}) error {
+	content, err := decodeHCL(raw)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalMapWithJSON(content, target)
+}
+
+// Decode hcl content into generic map with flattened blocks
+func decodeHCL(raw []byte) (map[string]interface{}, error) {
+	content := make(map[string]interface{})
+	if err := hcl.Unmarshal(raw, &content); err != nil {
+		return nil, err
+	}
+
+	return flattenHCLBlocks(content).(map[string]interface{}), nil
+}
+
+// Flatten single element block lists produced by hcl decoder recursively
+func flattenHCLBlocks(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		if len(v) == 1 {
+			return flattenHCLBlocks(v[0])
+		}
+
+		res := make([]interface{}, 0, len(v))
+		for i := range v {
+			res = append(res, flattenHCLBlocks(v[i]))
+		}
+		return res
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = flattenHCLBlocks(v[key])
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = flattenHCLBlocks(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Marshal generic map into json and unmarshal it into target with json tags
+func unmarshalMapWithJSON(content map[string]interface{}, target interface{}) error {
+	bytes, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(bytes, target)
+}
+
 // Generate zap encoder from zap config
-func generateEncoder(config *zap.Config) zapcore.Encoder {
+// Encoders registered with RegisterEncoder are resolved by encoding name
+func generateEncoder(config *zap.Config) (zapcore.Encoder, error) {
 	if config.Encoding == "json" {
-		return zapcore.NewJSONEncoder(config.EncoderConfig)
+		return zapcore.NewJSONEncoder(config.EncoderConfig), nil
+	}
+
+	if constructor, ok := lookupEncoder(config.Encoding); ok {
+		encoder, err := constructor(config.EncoderConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create encoder, encoding:%s", config.Encoding)
+		}
+
+		return encoder, nil
 	}
 
 	// default is console encoding
-	return zapcore.NewConsoleEncoder(config.EncoderConfig)
+	return zapcore.NewConsoleEncoder(config.EncoderConfig), nil
 }
 
 // Parse relative path, convert it to current working directory
 func toAbsoluteWorkingDir(filePath string) (string, error) {
-	if path.IsAbs(filePath) {
-		return filePath, nil
+	if filepath.IsAbs(filePath) {
+		return filepath.Clean(filePath), nil
 	}
 
 	dir, err := os.Getwd()
@@ -395,7 +469,7 @@ func toAbsoluteWorkingDir(filePath string) (string, error) {
 	}
 
 	// relative path, add current working directory
-	return path.Clean(path.Join(dir, filePath)), nil
+	return filepath.Join(dir, filePath), nil
 }
 
 // TransformToZapConfig transforms wrapped zap config into zap.Config
@@ -406,8 +480,8 @@ func TransformToZapConfig(wrap *ZapConfigWrap) *zap.Config {
 
 	level := zap.NewAtomicLevel()
 
-	if err := level.UnmarshalText([]byte(wrap.Level)); err != nil {
-		level.SetLevel(zapcore.InfoLevel)
+	if parsed, err := ParseLevel(wrap.Level); err == nil {
+		level.SetLevel(parsed)
 	}
 
 	config := &zap.Config{
@@ -526,9 +600,9 @@ type ZapConfigWrap struct {
 	DisableStacktrace bool `json:"disableStacktrace" yaml:"disableStacktrace"`
 	// Sampling sets a sampling policy. A nil SamplingConfig disables sampling.
 	Sampling *zap.SamplingConfig `json:"sampling" yaml:"sampling"`
-	// Encoding sets the logger's encoding. Valid values are "json" and
-	// "console", as well as any third-party encodings registered via
-	// RegisterEncoder.
+	// Encoding sets the logger's encoding. Valid values are "json",
+	// "console" and "logfmt", as well as any third-party encodings registered
+	// via RegisterEncoder.
 	Encoding string `json:"encoding" yaml:"encoding"`
 	// EncoderConfig sets options for the chosen encoder. See
 	// zapcore.EncoderConfig for details.
@@ -5,6 +5,9 @@
 package rklogger
 
 import (
+	"bytes"
+	"encoding/json"
+	"github.com/BurntSushi/toml"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -16,11 +19,15 @@ import (
 func TestConfigFileType_Indexing(t *testing.T) {
 	assert.Equal(t, FileType(0), JSON)
 	assert.Equal(t, FileType(1), YAML)
+	assert.Equal(t, FileType(2), TOML)
+	assert.Equal(t, FileType(3), HCL)
 }
 
 func TestConfigFileType_String_HappyCase(t *testing.T) {
 	assert.Equal(t, "JSON", JSON.String())
 	assert.Equal(t, "YAML", YAML.String())
+	assert.Equal(t, "TOML", TOML.String())
+	assert.Equal(t, "HCL", HCL.String())
 }
 
 func TestConfigFileType_String_Overflow_LeftBoundary(t *testing.T) {
@@ -133,6 +140,167 @@ func TestNewZapLoggerWithBytes_HappyCase(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// With invalid toml
+func TestNewZapLoggerWithBytes_WithInvalidToml(t *testing.T) {
+	invalidToml := `key = `
+	logger, config, err := NewZapLoggerWithBytes([]byte(invalidToml), TOML)
+	assert.Nil(t, logger)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// Happy case with toml
+func TestNewZapLoggerWithBytes_WithToml(t *testing.T) {
+	bytes := []byte(`
+level = "debug"
+development = true
+disableCaller = true
+disableStacktrace = true
+encoding = "json"
+outputPaths = ["stdout"]
+errorOutputPaths = ["stderr"]
+
+[sampling]
+initial = 3
+thereafter = 10
+
+[initialFields]
+initFieldKey = "fieldValue"
+
+[encoderConfig]
+messageKey = "message"
+levelKey = "level"
+nameKey = "logger"
+timeKey = "time"
+callerKey = "caller"
+functionKey = "func"
+stacktraceKey = "stacktrace"
+lineEnding = "\n"
+timeEncoder = "iso8601"
+levelEncoder = "capital"
+durationEncoder = "string"
+callerEncoder = "full"
+nameEncoder = "full"
+consoleSeparator = " "
+`)
+	logger, config, err := NewZapLoggerWithBytes(bytes, TOML)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+
+	assert.Equal(t, zap.DebugLevel, config.Level.Level())
+	assert.True(t, config.Development)
+	assert.True(t, config.DisableCaller)
+	assert.True(t, config.DisableStacktrace)
+	assert.Equal(t, "json", config.Encoding)
+	assert.Equal(t, []string{"stdout"}, config.OutputPaths)
+	assert.Equal(t, []string{"stderr"}, config.ErrorOutputPaths)
+	assert.Equal(t, &zap.SamplingConfig{Initial: 3, Thereafter: 10}, config.Sampling)
+	assert.Equal(t, map[string]interface{}{"initFieldKey": "fieldValue"}, config.InitialFields)
+
+	encoder := config.EncoderConfig
+	assert.Equal(t, "message", encoder.MessageKey)
+	assert.Equal(t, "level", encoder.LevelKey)
+	assert.Equal(t, "logger", encoder.NameKey)
+	assert.Equal(t, "time", encoder.TimeKey)
+	assert.Equal(t, "caller", encoder.CallerKey)
+	assert.Equal(t, "func", encoder.FunctionKey)
+	assert.Equal(t, "stacktrace", encoder.StacktraceKey)
+	assert.Equal(t, "\n", encoder.LineEnding)
+	assert.Equal(t, " ", encoder.ConsoleSeparator)
+	assert.Equal(t, "ISO8601", marshalZapTimeEncoder(encoder.EncodeTime))
+	assert.Equal(t, "capital", marshalZapLevelEncoder(encoder.EncodeLevel))
+	assert.Equal(t, "string", marshalZapDurationEncoder(encoder.EncodeDuration))
+	assert.Equal(t, "full", marshalZapCallerEncoder(encoder.EncodeCaller))
+}
+
+// Round trip with toml
+func TestNewZapLoggerWithBytes_TomlRoundTrip(t *testing.T) {
+	origin := NewZapStdoutConfig()
+	origin.InitialFields = map[string]interface{}{"initFieldKey": "fieldValue"}
+	origin.Sampling = &zap.SamplingConfig{Initial: 3, Thereafter: 10}
+
+	// zap.Config could not be encoded directly, marshal it with ZapConfigWrap first
+	jsonBytes, err := TransformToZapConfigWrap(origin).MarshalJSON()
+	assert.Nil(t, err)
+	content := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(jsonBytes, &content))
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, toml.NewEncoder(buf).Encode(content))
+
+	logger, config, err := NewZapLoggerWithBytes(buf.Bytes(), TOML)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, origin.Level.String(), config.Level.String())
+	assert.Equal(t, origin.Development, config.Development)
+	assert.Equal(t, origin.DisableStacktrace, config.DisableStacktrace)
+	assert.Equal(t, origin.Sampling, config.Sampling)
+	assert.Equal(t, origin.Encoding, config.Encoding)
+	assert.Equal(t, origin.OutputPaths, config.OutputPaths)
+	assert.Equal(t, origin.ErrorOutputPaths, config.ErrorOutputPaths)
+	assert.Equal(t, origin.InitialFields, config.InitialFields)
+	assert.Equal(t, origin.EncoderConfig.MessageKey, config.EncoderConfig.MessageKey)
+	assert.Equal(t, origin.EncoderConfig.TimeKey, config.EncoderConfig.TimeKey)
+	assert.Equal(t,
+		marshalZapTimeEncoder(origin.EncoderConfig.EncodeTime),
+		marshalZapTimeEncoder(config.EncoderConfig.EncodeTime))
+	assert.Equal(t,
+		marshalZapLevelEncoder(origin.EncoderConfig.EncodeLevel),
+		marshalZapLevelEncoder(config.EncoderConfig.EncodeLevel))
+}
+
+// With invalid hcl
+func TestNewZapLoggerWithBytes_WithInvalidHcl(t *testing.T) {
+	invalidHcl := `level = [`
+	logger, config, err := NewZapLoggerWithBytes([]byte(invalidHcl), HCL)
+	assert.Nil(t, logger)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// Happy case with hcl
+func TestNewZapLoggerWithBytes_WithHcl(t *testing.T) {
+	bytes := []byte(`
+level = "warn"
+encoding = "json"
+outputPaths = ["stdout"]
+errorOutputPaths = ["stderr"]
+
+sampling {
+  initial = 3
+  thereafter = 10
+}
+
+initialFields {
+  initFieldKey = "fieldValue"
+}
+
+encoderConfig {
+  messageKey = "message"
+  timeKey = "time"
+  timeEncoder = "rfc3339"
+  levelEncoder = "capital"
+}
+
+maxsize = 1
+maxage = 7
+`)
+	logger, config, err := NewZapLoggerWithBytes(bytes, HCL)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+
+	assert.Equal(t, zap.WarnLevel, config.Level.Level())
+	assert.Equal(t, "json", config.Encoding)
+	assert.Equal(t, []string{"stdout"}, config.OutputPaths)
+	assert.Equal(t, []string{"stderr"}, config.ErrorOutputPaths)
+	assert.Equal(t, &zap.SamplingConfig{Initial: 3, Thereafter: 10}, config.Sampling)
+	assert.Equal(t, map[string]interface{}{"initFieldKey": "fieldValue"}, config.InitialFields)
+	assert.Equal(t, "message", config.EncoderConfig.MessageKey)
+	assert.Equal(t, "time", config.EncoderConfig.TimeKey)
+	assert.Equal(t, "RFC3339", marshalZapTimeEncoder(config.EncoderConfig.EncodeTime))
+	assert.Equal(t, "capital", marshalZapLevelEncoder(config.EncoderConfig.EncodeLevel))
+}
+
 // With empty file path
 func TestNewZapLoggerWithConfPath_WithEmptyString(t *testing.T) {
 	logger, config, err := NewZapLoggerWithConfPath("", YAML)
@@ -169,6 +337,30 @@ func TestNewZapLoggerWithConfPath_HappyCase(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// Happy case with toml
+func TestNewZapLoggerWithConfPath_WithToml(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	logger, config, err := NewZapLoggerWithConfPath(dir+"/assets/zap.toml", TOML)
+	assert.NotNil(t, logger)
+	assert.NotNil(t, config)
+	assert.Nil(t, err)
+}
+
+// Happy case with hcl
+func TestNewZapLoggerWithConfPath_WithHcl(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	logger, config, err := NewZapLoggerWithConfPath(dir+"/assets/zap.hcl", HCL)
+	assert.NotNil(t, logger)
+	assert.NotNil(t, config)
+	assert.Nil(t, err)
+}
+
 // With nil config
 func TestNewZapLoggerWithConf_WithNilConfig(t *testing.T) {
 	logger, err := NewZapLoggerWithConf(nil, nil)
@@ -247,9 +439,84 @@ func TestNewLumberjackLoggerWithBytes_WithInvalidJson(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+// With invalid toml
+func TestNewLumberjackLoggerWithBytes_WithInvalidToml(t *testing.T) {
+	logger, err := NewLumberjackLoggerWithBytes([]byte("maxsize = "), TOML)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+// Happy case with toml
+func TestNewLumberjackLoggerWithBytes_WithToml(t *testing.T) {
+	bytes := []byte(`
+filename = "logs/rk-logger.log"
+maxsize = 1
+maxage = 7
+maxbackups = 3
+localtime = true
+compress = true
+`)
+
+	logger, err := NewLumberjackLoggerWithBytes(bytes, TOML)
+	assert.Nil(t, err)
+	assert.Equal(t, "logs/rk-logger.log", logger.Filename)
+	assert.Equal(t, 1, logger.MaxSize)
+	assert.Equal(t, 7, logger.MaxAge)
+	assert.Equal(t, 3, logger.MaxBackups)
+	assert.True(t, logger.LocalTime)
+	assert.True(t, logger.Compress)
+}
+
+// Round trip with toml
+func TestNewLumberjackLoggerWithBytes_TomlRoundTrip(t *testing.T) {
+	origin := NewLumberjackConfigDefault()
+	origin.Filename = "logs/rk-logger.log"
+
+	// lumberjack.Logger carries no toml tags, encode it through its json tags
+	jsonBytes, err := json.Marshal(origin)
+	assert.Nil(t, err)
+	content := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(jsonBytes, &content))
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, toml.NewEncoder(buf).Encode(content))
+
+	logger, err := NewLumberjackLoggerWithBytes(buf.Bytes(), TOML)
+	assert.Nil(t, err)
+	assert.Equal(t, origin, logger)
+}
+
+// With invalid hcl
+func TestNewLumberjackLoggerWithBytes_WithInvalidHcl(t *testing.T) {
+	logger, err := NewLumberjackLoggerWithBytes([]byte("maxsize = ["), HCL)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+// Happy case with hcl
+func TestNewLumberjackLoggerWithBytes_WithHcl(t *testing.T) {
+	bytes := []byte(`
+filename = "logs/rk-logger.log"
+maxsize = 1
+maxage = 7
+maxbackups = 3
+localtime = true
+compress = true
+`)
+
+	logger, err := NewLumberjackLoggerWithBytes(bytes, HCL)
+	assert.Nil(t, err)
+	assert.Equal(t, "logs/rk-logger.log", logger.Filename)
+	assert.Equal(t, 1, logger.MaxSize)
+	assert.Equal(t, 7, logger.MaxAge)
+	assert.Equal(t, 3, logger.MaxBackups)
+	assert.True(t, logger.LocalTime)
+	assert.True(t, logger.Compress)
+}
+
 // With unknown file type
 func TestNewLumberjackLoggerWithBytes_WithUnkownFileType(t *testing.T) {
-	logger, err := NewLumberjackLoggerWithBytes([]byte(`{"key":"value"}`), 2)
+	logger, err := NewLumberjackLoggerWithBytes([]byte(`{"key":"value"}`), 10)
 	assert.Nil(t, logger)
 	assert.NotNil(t, err)
 }
@@ -349,6 +616,28 @@ func TestNewLumberjackLoggerWithConfPath_HappyCase(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+// Happy case with toml
+func TestNewLumberjackLoggerWithConfPath_WithToml(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	logger, err := NewLumberjackLoggerWithConfPath(dir+"/assets/lumberjack.toml", TOML)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+}
+
+// Happy case with hcl
+func TestNewLumberjackLoggerWithConfPath_WithHcl(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	logger, err := NewLumberjackLoggerWithConfPath(dir+"/assets/lumberjack.hcl", HCL)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+}
+
 // With invalid file path
 func TestValidateFilePath_WithInvalidFilePath(t *testing.T) {
 	assert.NotNil(t, validateFilePath("///invalid"))
@@ -369,13 +658,17 @@ func TestValidateFilePath_HappyCase(t *testing.T) {
 // With json encoder
 func TestGenerateEncoder_WithJsonEncoder(t *testing.T) {
 	config := &zap.Config{Encoding: "json"}
-	assert.NotNil(t, generateEncoder(config))
+	encoder, err := generateEncoder(config)
+	assert.NotNil(t, encoder)
+	assert.Nil(t, err)
 }
 
 // With console encoder
 func TestGenerateEncoder_WithConsoleEncoder(t *testing.T) {
 	config := &zap.Config{Encoding: "console"}
-	assert.NotNil(t, generateEncoder(config))
+	encoder, err := generateEncoder(config)
+	assert.NotNil(t, encoder)
+	assert.Nil(t, err)
 }
 
 // Absolute path
@@ -442,3 +735,18 @@ func TestZapConfigWrap_UnmarshalJSON(t *testing.T) {
 	// unmarshal is not supported yet!
 	assert.Nil(t, wrap.UnmarshalJSON([]byte{}))
 }
+
+func TestFlattenHCLBlocks(t *testing.T) {
+	content := map[string]interface{}{
+		"single": []map[string]interface{}{{"key": "value"}},
+		"multi":  []map[string]interface{}{{"key": "value"}, {"key": "value"}},
+		"list":   []interface{}{"stdout"},
+		"plain":  "value",
+	}
+
+	res := flattenHCLBlocks(content).(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"key": "value"}, res["single"])
+	assert.Len(t, res["multi"], 2)
+	assert.Equal(t, []interface{}{"stdout"}, res["list"])
+	assert.Equal(t, "value", res["plain"])
+}
@@ -0,0 +1,544 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package batch buffers encoded entries in a bounded queue and flushes them in batches,
+// it is shared by sinks which ship entries to remote services.
+package batch
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// max delay between retries of batches in spool or with unlimited retries
+const maxBackoff = time.Minute
+
+// OverflowPolicy decides what happens to entries written while queue is full.
+type OverflowPolicy string
+
+const (
+	// Block blocks writing until there is room in queue, which applies backpressure to logger.
+	Block OverflowPolicy = "block"
+	// DropNewest drops entries which are being written.
+	DropNewest OverflowPolicy = "dropNewest"
+	// DropOldest drops the oldest entries in queue in favor of entries which are being written.
+	DropOldest OverflowPolicy = "dropOldest"
+)
+
+// Config is the batching config of Writer.
+type Config struct {
+	// Size is the max number of entries in a batch.
+	Size int
+	// Bytes is the max total size of entries in a batch, a single entry larger than it is sent alone.
+	Bytes int
+	// Linger is the max time an entry waits in a batch which is not full.
+	Linger time.Duration
+	// QueueSize is the max number of entries waiting to be batched.
+	QueueSize int
+	// Overflow is the policy applied once queue is full.
+	Overflow OverflowPolicy
+	// Retries is the max number of retries of a failed batch, negative for unlimited retries until Writer is closed.
+	Retries int
+	// Backoff is the delay before the first retry, it doubles on every retry.
+	Backoff time.Duration
+	// Timeout is the timeout of each attempt of flushing a batch.
+	Timeout time.Duration
+	// SpoolDir is the directory which batches are persisted in before they are flushed, so that they survive
+	// restarts and outages. Batches are kept in memory only if not provided.
+	SpoolDir string
+	// SpoolSize is the max total size of batches persisted in SpoolDir, overflow applies once it is exceeded.
+	SpoolSize int64
+}
+
+// DefaultConfig returns batching config with default values.
+func DefaultConfig() Config {
+	return Config{
+		Size:      100,
+		Bytes:     1 << 20,
+		Linger:    time.Second,
+		QueueSize: 10000,
+		Overflow:  Block,
+		Retries:   3,
+		Backoff:   100 * time.Millisecond,
+		Timeout:   10 * time.Second,
+		SpoolSize: 1 << 30,
+	}
+}
+
+// ParseQuery overrides config with query of output url, like batchSize=100&linger=1s&overflow=dropNewest.
+// Keys which are not related to batching are returned. Spool directory is created if it is provided.
+func (config *Config) ParseQuery(query url.Values) (url.Values, error) {
+	rest := make(url.Values)
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		var err error
+		switch key {
+		case "batchSize":
+			config.Size, err = parsePositive(value)
+		case "batchBytes":
+			config.Bytes, err = parsePositive(value)
+		case "linger":
+			config.Linger, err = time.ParseDuration(value)
+		case "queueSize":
+			config.QueueSize, err = parsePositive(value)
+		case "overflow":
+			config.Overflow = OverflowPolicy(value)
+			if config.Overflow != Block && config.Overflow != DropNewest && config.Overflow != DropOldest {
+				err = errors.New("unknown overflow policy")
+			}
+		case "retries":
+			config.Retries, err = strconv.Atoi(value)
+		case "backoff":
+			config.Backoff, err = time.ParseDuration(value)
+		case "timeout":
+			config.Timeout, err = time.ParseDuration(value)
+		case "spoolDir":
+			config.SpoolDir = value
+		case "spoolSize":
+			var size int
+			size, err = parsePositive(value)
+			config.SpoolSize = int64(size)
+		default:
+			rest[key] = values
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid query of batching, key:%s", key)
+		}
+	}
+
+	return rest, nil
+}
+
+// Parse positive integer
+func parsePositive(value string) (int, error) {
+	res, err := strconv.Atoi(value)
+	if err == nil && res <= 0 {
+		err = errors.New("value must be positive")
+	}
+
+	return res, err
+}
+
+// FlushFunc sends a batch of encoded entries, entries should not be retained after it returns.
+type FlushFunc func(ctx context.Context, entries [][]byte) error
+
+// permanentError is an error which should not be retried
+type permanentError struct {
+	error
+}
+
+// Unwrap returns the wrapped error
+func (err *permanentError) Unwrap() error {
+	return err.error
+}
+
+// Permanent wraps error returned by FlushFunc so that the batch is dropped without retries,
+// like rejections of malformed requests.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{error: err}
+}
+
+// Stats is the delivery counters of Writer.
+type Stats struct {
+	// Sent is the number of batches which were flushed.
+	Sent uint64
+	// Retried is the number of retries of failed batches.
+	Retried uint64
+	// Abandoned is the number of batches which were not flushed after retries or failed with permanent errors.
+	Abandoned uint64
+	// Dropped is the number of entries dropped by overflow policy.
+	Dropped uint64
+}
+
+// Writer implements zapcore.WriteSyncer which queues entries and flushes them in batches in background.
+// Write never returns error of flushing, which is returned by Sync and Close instead.
+//
+// With SpoolDir, batches are persisted in spool and flushed from it in order by another goroutine, failed batches
+// are retried until they are flushed, and batches left in spool are flushed once Writer is created again.
+// Sync persists queued entries and returns errors of flushing since last Sync.
+type Writer struct {
+	// counters placed first for 64-bit alignment of atomic operations
+	dropped   uint64
+	sent      uint64
+	retried   uint64
+	abandoned uint64
+	config    Config
+	flush     FlushFunc
+	queue     chan []byte
+	syncs     chan chan error
+	closing   chan struct{}
+	done      chan struct{}
+	once      sync.Once
+	err       error
+	// spool and errors of flushing batches in spool since last Sync
+	spool     *spool
+	delivered chan struct{}
+	spoolErrs error
+	spoolMu   sync.Mutex
+}
+
+// NewWriter creates Writer and starts flushing in background, zero values of config are replaced by defaults.
+func NewWriter(config Config, flush FlushFunc) *Writer {
+	defaults := DefaultConfig()
+	if config.Size <= 0 {
+		config.Size = defaults.Size
+	}
+	if config.Bytes <= 0 {
+		config.Bytes = defaults.Bytes
+	}
+	if config.Linger <= 0 {
+		config.Linger = defaults.Linger
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if len(config.Overflow) == 0 {
+		config.Overflow = defaults.Overflow
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.SpoolSize <= 0 {
+		config.SpoolSize = defaults.SpoolSize
+	}
+
+	writer := &Writer{
+		config:  config,
+		flush:   flush,
+		queue:   make(chan []byte, config.QueueSize),
+		syncs:   make(chan chan error),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if len(config.SpoolDir) > 0 {
+		// entries are kept in memory if spool could not be opened, the error is returned by Sync
+		spool, err := openSpool(config.SpoolDir, config.SpoolSize, config.Overflow, &writer.dropped)
+		if err != nil {
+			writer.spoolErrs = err
+		} else {
+			writer.spool = spool
+			writer.delivered = make(chan struct{})
+			go writer.deliver()
+		}
+	}
+	go writer.run()
+
+	return writer
+}
+
+// Write implements zapcore.WriteSyncer, p is copied and queued as one entry
+func (writer *Writer) Write(p []byte) (int, error) {
+	select {
+	case <-writer.closing:
+		return 0, errors.New("batch writer is closed")
+	default:
+	}
+
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	switch writer.config.Overflow {
+	case DropNewest:
+		select {
+		case writer.queue <- entry:
+		default:
+			atomic.AddUint64(&writer.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case writer.queue <- entry:
+				return len(p), nil
+			default:
+			}
+
+			select {
+			case <-writer.queue:
+				atomic.AddUint64(&writer.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case writer.queue <- entry:
+		case <-writer.closing:
+			return 0, errors.New("batch writer is closed")
+		}
+	}
+
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer, it flushes queued entries and returns errors of flushing since last Sync
+func (writer *Writer) Sync() error {
+	res := make(chan error, 1)
+	select {
+	case writer.syncs <- res:
+		return <-res
+	case <-writer.done:
+		return nil
+	}
+}
+
+// Close flushes queued entries and stops flushing in background, entries written afterwards are rejected
+func (writer *Writer) Close() error {
+	writer.once.Do(func() {
+		close(writer.closing)
+		if writer.spool != nil {
+			writer.spool.interrupt()
+		}
+	})
+
+	<-writer.done
+	return writer.err
+}
+
+// Dropped returns number of entries dropped by overflow policy.
+func (writer *Writer) Dropped() uint64 {
+	return atomic.LoadUint64(&writer.dropped)
+}
+
+// Stats returns delivery counters.
+func (writer *Writer) Stats() Stats {
+	return Stats{
+		Sent:      atomic.LoadUint64(&writer.sent),
+		Retried:   atomic.LoadUint64(&writer.retried),
+		Abandoned: atomic.LoadUint64(&writer.abandoned),
+		Dropped:   atomic.LoadUint64(&writer.dropped),
+	}
+}
+
+// Batch entries and flush them once batch is full, linger expires, Sync or Close is called
+func (writer *Writer) run() {
+	defer close(writer.done)
+
+	batch := make([][]byte, 0, writer.config.Size)
+	size := 0
+	var errs error
+	var timer *time.Timer
+	var linger <-chan time.Time
+
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, linger = nil, nil
+		}
+
+		if len(batch) > 0 && writer.spool != nil {
+			errs = multierr.Append(errs, writer.spool.append(batch))
+			batch = make([][]byte, 0, writer.config.Size)
+			size = 0
+		} else if len(batch) > 0 {
+			if _, err := writer.flushWithRetry(batch); err != nil {
+				atomic.AddUint64(&writer.abandoned, 1)
+				errs = multierr.Append(errs, err)
+			}
+			batch = make([][]byte, 0, writer.config.Size)
+			size = 0
+		}
+	}
+
+	add := func(entry []byte) {
+		if len(batch) > 0 && size+len(entry) > writer.config.Bytes {
+			flush()
+		}
+
+		batch = append(batch, entry)
+		size += len(entry)
+
+		if len(batch) >= writer.config.Size || size >= writer.config.Bytes {
+			flush()
+		} else if timer == nil {
+			timer = time.NewTimer(writer.config.Linger)
+			linger = timer.C
+		}
+	}
+
+	drain := func() {
+		for {
+			select {
+			case entry := <-writer.queue:
+				add(entry)
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case entry := <-writer.queue:
+			add(entry)
+		case <-linger:
+			timer, linger = nil, nil
+			flush()
+		case res := <-writer.syncs:
+			drain()
+			res <- multierr.Append(errs, writer.takeSpoolErrors())
+			errs = nil
+		case <-writer.closing:
+			drain()
+			if writer.spool != nil {
+				// batches which are not flushed are kept in spool
+				writer.spool.stop()
+				<-writer.delivered
+				errs = multierr.Append(errs, writer.spool.close())
+			}
+			writer.err = multierr.Append(errs, writer.takeSpoolErrors())
+			return
+		}
+	}
+}
+
+// Flush batch with retries and exponential backoff, true is returned if batch failed with permanent error.
+// Unlimited retries stop once Writer is closed.
+func (writer *Writer) flushWithRetry(batch [][]byte) (bool, error) {
+	backoff := writer.config.Backoff
+	unlimited := writer.config.Retries < 0
+	if unlimited && backoff <= 0 {
+		backoff = DefaultConfig().Backoff
+	}
+
+	var err error
+	for attempt := 0; unlimited || attempt <= writer.config.Retries; attempt++ {
+		if attempt > 0 {
+			if unlimited && writer.isClosing() {
+				break
+			}
+
+			atomic.AddUint64(&writer.retried, 1)
+			if unlimited {
+				// a last attempt is made once Writer is closed
+				select {
+				case <-time.After(backoff):
+				case <-writer.closing:
+				}
+			} else {
+				time.Sleep(backoff)
+			}
+			if backoff *= 2; unlimited && backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), writer.config.Timeout)
+		err = writer.flush(ctx, batch)
+		cancel()
+
+		if err == nil {
+			atomic.AddUint64(&writer.sent, 1)
+			return false, nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return true, permanent.error
+		}
+	}
+
+	return false, errors.Wrapf(err, "failed to flush batch, entries:%d", len(batch))
+}
+
+// Flush batches in spool in order, failed batches are retried with doubling backoff up to maxBackoff.
+// It returns once spool is stopped and all batches are flushed, or a batch fails after spool is stopped.
+func (writer *Writer) deliver() {
+	defer close(writer.delivered)
+
+	initial := writer.config.Backoff
+	if initial <= 0 {
+		initial = DefaultConfig().Backoff
+	}
+
+	backoff := initial
+	var entries [][]byte
+	var id uint64
+	var end int64
+	for {
+		// failed batch is retried with the same entries, so that FlushFunc could track its progress
+		if entries == nil {
+			var ok bool
+			if entries, id, end, ok = writer.spool.next(); !ok {
+				return
+			}
+		}
+
+		permanent, err := writer.flushWithRetry(entries)
+		if err == nil || permanent {
+			if err == nil {
+				backoff = initial
+			} else {
+				atomic.AddUint64(&writer.abandoned, 1)
+			}
+			err = multierr.Append(err, writer.spool.ack(id, end, len(entries)))
+			writer.addSpoolError(err)
+			entries = nil
+			continue
+		}
+		writer.addSpoolError(errors.Wrap(err, "batch is kept in spool"))
+
+		select {
+		case <-writer.closing:
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Whether Close is called
+func (writer *Writer) isClosing() bool {
+	select {
+	case <-writer.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// Record error of flushing batch in spool
+func (writer *Writer) addSpoolError(err error) {
+	if err == nil {
+		return
+	}
+
+	writer.spoolMu.Lock()
+	defer writer.spoolMu.Unlock()
+
+	writer.spoolErrs = multierr.Append(writer.spoolErrs, err)
+}
+
+// Return and clear errors of flushing batches in spool
+func (writer *Writer) takeSpoolErrors() error {
+	writer.spoolMu.Lock()
+	defer writer.spoolMu.Unlock()
+
+	err := writer.spoolErrs
+	writer.spoolErrs = nil
+	return err
+}
+
+// Add number of dropped entries
+func addDropped(dropped *uint64, n int) {
+	if n > 0 {
+		atomic.AddUint64(dropped, uint64(n))
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package batch
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder records flushed batches
+type recorder struct {
+	batches [][]string
+	calls   int
+	errs    []error
+	block   chan struct{}
+	mutex   sync.Mutex
+}
+
+// Flush implements FlushFunc
+func (rec *recorder) flush(ctx context.Context, entries [][]byte) error {
+	if rec.block != nil {
+		<-rec.block
+	}
+
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	rec.calls++
+	if len(rec.errs) > 0 {
+		err := rec.errs[0]
+		rec.errs = rec.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+
+	batch := make([]string, 0, len(entries))
+	for i := range entries {
+		batch = append(batch, string(entries[i]))
+	}
+	rec.batches = append(rec.batches, batch)
+	return nil
+}
+
+// Return flushed batches
+func (rec *recorder) flushed() [][]string {
+	rec.mutex.Lock()
+	defer rec.mutex.Unlock()
+
+	return append([][]string{}, rec.batches...)
+}
+
+func TestConfig_ParseQuery_HappyCase(t *testing.T) {
+	config := DefaultConfig()
+	dir := filepath.Join(newSpoolDir(t), "spool")
+	query, _ := url.ParseQuery("batchSize=10&batchBytes=2048&linger=5ms&queueSize=20&overflow=dropOldest&retries=0&backoff=1ms&timeout=1s&topic=app")
+	query.Set("spoolDir", dir)
+	query.Set("spoolSize", "4096")
+	rest, err := config.ParseQuery(query)
+	assert.Nil(t, err)
+	assert.Equal(t, Config{
+		Size:      10,
+		Bytes:     2048,
+		Linger:    5 * time.Millisecond,
+		QueueSize: 20,
+		Overflow:  DropOldest,
+		Retries:   0,
+		Backoff:   time.Millisecond,
+		Timeout:   time.Second,
+		SpoolDir:  dir,
+		SpoolSize: 4096,
+	}, config)
+	assert.Equal(t, url.Values{"topic": {"app"}}, rest)
+	// spool directory is created by writer only
+	assert.NoDirExists(t, dir)
+
+	writer := NewWriter(config, (&recorder{}).flush)
+	assert.DirExists(t, dir)
+	assert.Nil(t, writer.Close())
+}
+
+func TestConfig_ParseQuery_WithInvalidValue(t *testing.T) {
+	for _, query := range []string{"batchSize=0", "batchBytes=x", "linger=1", "overflow=drop", "retries=x", "spoolSize=0"} {
+		config := DefaultConfig()
+		values, _ := url.ParseQuery(query)
+		_, err := config.ParseQuery(values)
+		assert.NotNil(t, err, query)
+	}
+}
+
+func TestWriter_WithBatchSize(t *testing.T) {
+	rec := &recorder{}
+	writer := NewWriter(Config{Size: 2, Linger: time.Hour}, rec.flush)
+
+	writer.Write([]byte("a"))
+	writer.Write([]byte("b"))
+	writer.Write([]byte("c"))
+
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, rec.flushed())
+}
+
+func TestWriter_WithBatchBytes(t *testing.T) {
+	rec := &recorder{}
+	writer := NewWriter(Config{Bytes: 4, Linger: time.Hour}, rec.flush)
+
+	writer.Write([]byte("ab"))
+	writer.Write([]byte("cde"))
+	writer.Write([]byte("fghij"))
+
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, [][]string{{"ab"}, {"cde"}, {"fghij"}}, rec.flushed())
+}
+
+func TestWriter_WithLinger(t *testing.T) {
+	rec := &recorder{}
+	writer := NewWriter(Config{Linger: 10 * time.Millisecond}, rec.flush)
+	defer writer.Close()
+
+	writer.Write([]byte("a"))
+
+	assert.Eventually(t, func() bool {
+		return len(rec.flushed()) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+}
+
+func TestWriter_Sync(t *testing.T) {
+	rec := &recorder{}
+	writer := NewWriter(Config{Linger: time.Hour}, rec.flush)
+	defer writer.Close()
+
+	p := []byte("a")
+	writer.Write(p)
+	// written bytes are copied
+	p[0] = 'b'
+
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, [][]string{{"a"}}, rec.flushed())
+}
+
+func TestWriter_WithRetries(t *testing.T) {
+	rec := &recorder{errs: []error{errors.New("unavailable"), errors.New("unavailable")}}
+	writer := NewWriter(Config{Linger: time.Hour, Retries: 2, Backoff: time.Millisecond}, rec.flush)
+	defer writer.Close()
+
+	writer.Write([]byte("a"))
+
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, 3, rec.calls)
+	assert.Equal(t, [][]string{{"a"}}, rec.flushed())
+}
+
+func TestWriter_WithRetriesExhausted(t *testing.T) {
+	rec := &recorder{errs: []error{errors.New("unavailable"), errors.New("unavailable")}}
+	writer := NewWriter(Config{Linger: time.Hour, Retries: 1, Backoff: time.Millisecond}, rec.flush)
+	defer writer.Close()
+
+	writer.Write([]byte("a"))
+
+	assert.NotNil(t, writer.Sync())
+	assert.Equal(t, 2, rec.calls)
+	// errors are returned once
+	assert.Nil(t, writer.Sync())
+}
+
+func TestWriter_WithPermanentError(t *testing.T) {
+	rec := &recorder{errs: []error{Permanent(errors.New("bad request"))}}
+	writer := NewWriter(Config{Linger: time.Hour, Retries: 3, Backoff: time.Millisecond}, rec.flush)
+	defer writer.Close()
+
+	writer.Write([]byte("a"))
+
+	err := writer.Sync()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "bad request")
+	assert.Equal(t, 1, rec.calls)
+	assert.Nil(t, Permanent(nil))
+}
+
+func TestWriter_WithDropNewest(t *testing.T) {
+	rec := &recorder{block: make(chan struct{})}
+	writer := NewWriter(Config{Size: 1, QueueSize: 1, Overflow: DropNewest}, rec.flush)
+
+	// first entry is being flushed, second one is queued and third one is dropped
+	writer.Write([]byte("a"))
+	assert.Eventually(t, func() bool {
+		return len(writer.queue) == 0
+	}, 5*time.Second, time.Millisecond)
+	writer.Write([]byte("b"))
+	writer.Write([]byte("c"))
+
+	close(rec.block)
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, uint64(1), writer.Dropped())
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, rec.flushed())
+}
+
+func TestWriter_WithDropOldest(t *testing.T) {
+	rec := &recorder{block: make(chan struct{})}
+	writer := NewWriter(Config{Size: 1, QueueSize: 1, Overflow: DropOldest}, rec.flush)
+
+	writer.Write([]byte("a"))
+	assert.Eventually(t, func() bool {
+		return len(writer.queue) == 0
+	}, 5*time.Second, time.Millisecond)
+	writer.Write([]byte("b"))
+	writer.Write([]byte("c"))
+
+	close(rec.block)
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, uint64(1), writer.Dropped())
+	assert.Equal(t, [][]string{{"a"}, {"c"}}, rec.flushed())
+}
+
+func TestWriter_WithBlock(t *testing.T) {
+	rec := &recorder{block: make(chan struct{})}
+	writer := NewWriter(Config{Size: 1, QueueSize: 1}, rec.flush)
+
+	writer.Write([]byte("a"))
+	assert.Eventually(t, func() bool {
+		return len(writer.queue) == 0
+	}, 5*time.Second, time.Millisecond)
+	writer.Write([]byte("b"))
+
+	written := make(chan struct{})
+	go func() {
+		writer.Write([]byte("c"))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		assert.Fail(t, "write is not blocked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(rec.block)
+	<-written
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, uint64(0), writer.Dropped())
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"c"}}, rec.flushed())
+}
+
+func TestWriter_Close(t *testing.T) {
+	rec := &recorder{}
+	writer := NewWriter(Config{Linger: time.Hour}, rec.flush)
+
+	writer.Write([]byte("a"))
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, [][]string{{"a"}}, rec.flushed())
+
+	// With write after close
+	_, err := writer.Write([]byte("b"))
+	assert.NotNil(t, err)
+
+	// With sync and close after close
+	assert.Nil(t, writer.Sync())
+	assert.Nil(t, writer.Close())
+}
+
+func TestWriter_Stats(t *testing.T) {
+	rec := &recorder{errs: []error{
+		errors.New("unavailable"), nil,
+		errors.New("unavailable"), errors.New("unavailable"),
+		Permanent(errors.New("bad request")),
+	}}
+	writer := NewWriter(Config{Size: 1, Retries: 1, Backoff: time.Millisecond}, rec.flush)
+
+	// sent after retry, abandoned after retries and abandoned with permanent error
+	for _, entry := range []string{"a", "b", "c"} {
+		writer.Write([]byte(entry))
+		writer.Sync()
+	}
+	writer.Close()
+
+	assert.Equal(t, Stats{Sent: 1, Retried: 2, Abandoned: 2}, writer.Stats())
+}
+
+func TestWriter_WithUnlimitedRetries(t *testing.T) {
+	errs := make([]error, 5)
+	for i := range errs {
+		errs[i] = errors.New("unavailable")
+	}
+	rec := &recorder{errs: errs}
+	writer := NewWriter(Config{Linger: time.Hour, Retries: -1, Backoff: time.Millisecond}, rec.flush)
+
+	writer.Write([]byte("a"))
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, [][]string{{"a"}}, rec.flushed())
+	assert.Equal(t, uint64(5), writer.Stats().Retried)
+
+	// retries stop once writer is closed
+	rec.errs = []error{errors.New("unavailable"), errors.New("unavailable"), errors.New("unavailable")}
+	writer.Write([]byte("b"))
+	assert.NotNil(t, writer.Close())
+	assert.Equal(t, uint64(1), writer.Stats().Abandoned)
+}
@@ -0,0 +1,468 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"github.com/pkg/errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// extension of segment files in spool directory
+	segmentExt = ".spool"
+	// file which records position of the first entry not yet flushed
+	cursorFile = "cursor"
+	// max size of a segment file
+	maxSegmentSize = 8 << 20
+	// size of length and checksum before every record
+	recordHeaderSize = 8
+)
+
+// segment is a file of records, every record is a batch of entries
+type segment struct {
+	id      uint64
+	size    int64
+	entries int
+}
+
+// spool persists batches in segment files under dir, batches are read in order and removed once they are acked.
+// Segments are scanned on open so that batches which were not flushed before exit are replayed.
+type spool struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+	overflow    OverflowPolicy
+	// number of dropped entries of Writer
+	dropped *uint64
+
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	segments []*segment
+	size     int64
+	file     *os.File
+	reader   *os.File
+	// offset and number of entries of records acked in the oldest segment
+	offset  int64
+	acked   int
+	closing bool
+	closed  bool
+}
+
+// Open spool in dir, existing segments are scanned and truncated after the last valid record
+func openSpool(dir string, maxSize int64, overflow OverflowPolicy, dropped *uint64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create spool directory, dir:%s", dir)
+	}
+
+	spool := &spool{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: maxSize / 8,
+		overflow:    overflow,
+		dropped:     dropped,
+	}
+	spool.cond = sync.NewCond(&spool.mutex)
+	if spool.segmentSize > maxSegmentSize {
+		spool.segmentSize = maxSegmentSize
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+
+	// ids of segments are not reused, since segments before cursor are removed
+	cursorID, cursorOffset := spool.readCursor()
+	lastID := cursorID
+	for _, name := range names {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		if id > lastID {
+			lastID = id
+		}
+
+		seg, err := scanSegment(name, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if seg.size == 0 || id < cursorID {
+			os.Remove(name)
+			continue
+		}
+		spool.segments = append(spool.segments, seg)
+		spool.size += seg.size
+	}
+
+	sort.Slice(spool.segments, func(i, j int) bool {
+		return spool.segments[i].id < spool.segments[j].id
+	})
+
+	if len(spool.segments) > 0 && spool.segments[0].id == cursorID && cursorOffset <= spool.segments[0].size {
+		spool.offset = cursorOffset
+	}
+
+	// batches are always appended to a new segment
+	if err := spool.createSegment(lastID + 1); err != nil {
+		return nil, err
+	}
+
+	return spool, nil
+}
+
+// Scan records of segment file, the file is truncated after the last valid record
+func scanSegment(name string, id uint64) (*segment, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open spool segment, file:%s", name)
+	}
+
+	seg := &segment{id: id}
+	reader := bufio.NewReader(file)
+	for {
+		payload, err := readRecord(reader)
+		if err != nil {
+			break
+		}
+
+		seg.size += int64(recordHeaderSize + len(payload))
+		seg.entries += int(binary.BigEndian.Uint32(payload))
+	}
+	file.Close()
+
+	if info, err := os.Stat(name); err == nil && info.Size() > seg.size {
+		// partial record written before exit
+		if err := os.Truncate(name, seg.size); err != nil {
+			return nil, errors.Wrapf(err, "failed to truncate spool segment, file:%s", name)
+		}
+	}
+
+	return seg, nil
+}
+
+// Read id of segment and offset in it from cursor file
+func (spool *spool) readCursor() (uint64, int64) {
+	content, err := ioutil.ReadFile(filepath.Join(spool.dir, cursorFile))
+	if err != nil {
+		return 0, 0
+	}
+
+	var id uint64
+	var offset int64
+	if _, err := fmt.Sscanf(string(content), "%d %d", &id, &offset); err != nil {
+		return 0, 0
+	}
+
+	return id, offset
+}
+
+// Name of segment file
+func (spool *spool) segmentPath(id uint64) string {
+	return filepath.Join(spool.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// Create segment which batches are appended to
+func (spool *spool) createSegment(id uint64) error {
+	file, err := os.OpenFile(spool.segmentPath(id), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create spool segment, dir:%s", spool.dir)
+	}
+
+	if spool.file != nil {
+		spool.file.Close()
+	}
+	spool.file = file
+	spool.segments = append(spool.segments, &segment{id: id})
+	return nil
+}
+
+// Whether segment is the one batches are appended to
+func (spool *spool) isActive(seg *segment) bool {
+	return seg == spool.segments[len(spool.segments)-1]
+}
+
+// Remove the oldest segment, a new segment is created first if it is active
+func (spool *spool) removeOldest() error {
+	oldest := spool.segments[0]
+	if spool.isActive(oldest) {
+		if err := spool.createSegment(oldest.id + 1); err != nil {
+			return err
+		}
+	}
+
+	if spool.reader != nil {
+		spool.reader.Close()
+		spool.reader = nil
+	}
+
+	spool.segments = spool.segments[1:]
+	spool.size -= oldest.size
+	spool.offset, spool.acked = 0, 0
+	spool.cond.Broadcast()
+
+	if err := os.Remove(spool.segmentPath(oldest.id)); err != nil {
+		return errors.Wrapf(err, "failed to remove spool segment, dir:%s", spool.dir)
+	}
+
+	return nil
+}
+
+// Append batch as a record, overflow policy applies once spool is full
+func (spool *spool) append(entries [][]byte) error {
+	record := encodeRecord(entries)
+	size := int64(len(record))
+
+	spool.mutex.Lock()
+	defer spool.mutex.Unlock()
+
+	if size > spool.maxSize {
+		addDropped(spool.dropped, len(entries))
+		return errors.Errorf("batch exceeds spool size, size:%d, spoolSize:%d", size, spool.maxSize)
+	}
+
+	for spool.size+size > spool.maxSize {
+		oldest := spool.segments[0]
+		switch {
+		case spool.offset >= oldest.size && oldest.size > 0:
+			// the oldest segment is flushed already
+			if err := spool.removeOldest(); err != nil {
+				return err
+			}
+		case spool.overflow == DropOldest && oldest.size > 0:
+			addDropped(spool.dropped, oldest.entries-spool.acked)
+			if err := spool.removeOldest(); err != nil {
+				return err
+			}
+		case spool.overflow == Block && !spool.closing:
+			spool.cond.Wait()
+		default:
+			addDropped(spool.dropped, len(entries))
+			return nil
+		}
+	}
+
+	active := spool.segments[len(spool.segments)-1]
+	if active.size > 0 && active.size+size > spool.segmentSize {
+		if err := spool.createSegment(active.id + 1); err != nil {
+			return err
+		}
+		active = spool.segments[len(spool.segments)-1]
+	}
+
+	if _, err := spool.file.Write(record); err != nil {
+		return errors.Wrapf(err, "failed to write spool segment, dir:%s", spool.dir)
+	}
+	if err := spool.file.Sync(); err != nil {
+		return errors.Wrapf(err, "failed to sync spool segment, dir:%s", spool.dir)
+	}
+
+	active.size += size
+	active.entries += len(entries)
+	spool.size += size
+	spool.cond.Broadcast()
+
+	return nil
+}
+
+// Read the oldest batch which is not acked, it blocks until a batch is appended.
+// Position of batch is returned for ack, false is returned once spool is closed and all batches are read.
+func (spool *spool) next() ([][]byte, uint64, int64, bool) {
+	spool.mutex.Lock()
+	defer spool.mutex.Unlock()
+
+	for {
+		oldest := spool.segments[0]
+		if spool.offset < oldest.size {
+			entries, end, err := spool.read(oldest)
+			if err == nil {
+				return entries, oldest.id, end, true
+			}
+
+			// the rest of corrupted segment is dropped
+			addDropped(spool.dropped, oldest.entries-spool.acked)
+			if spool.removeOldest() == nil {
+				continue
+			}
+			spool.offset = oldest.size
+		}
+
+		if !spool.isActive(oldest) {
+			spool.removeOldest()
+			continue
+		}
+
+		if spool.closed {
+			return nil, 0, 0, false
+		}
+		spool.cond.Wait()
+	}
+}
+
+// Read record at offset of segment
+func (spool *spool) read(seg *segment) ([][]byte, int64, error) {
+	if spool.reader == nil {
+		reader, err := os.Open(spool.segmentPath(seg.id))
+		if err != nil {
+			return nil, 0, err
+		}
+		spool.reader = reader
+	}
+
+	payload, err := readRecord(io.NewSectionReader(spool.reader, spool.offset, seg.size-spool.offset))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, err := decodeRecord(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entries, spool.offset + int64(recordHeaderSize+len(payload)), nil
+}
+
+// Ack batch returned by next, position is recorded in cursor file so that it is not replayed after restart
+func (spool *spool) ack(id uint64, end int64, entries int) error {
+	spool.mutex.Lock()
+	defer spool.mutex.Unlock()
+
+	oldest := spool.segments[0]
+	if oldest.id != id || end <= spool.offset {
+		// segment was dropped by overflow policy
+		return nil
+	}
+
+	spool.offset = end
+	spool.acked += entries
+	spool.cond.Broadcast()
+
+	if spool.offset >= oldest.size && !spool.isActive(oldest) {
+		return spool.removeOldest()
+	}
+
+	cursor := fmt.Sprintf("%d %d\n", id, end)
+	return ioutil.WriteFile(filepath.Join(spool.dir, cursorFile), []byte(cursor), 0644)
+}
+
+// Stop blocking appends, batches are dropped instead once spool is full
+func (spool *spool) interrupt() {
+	spool.mutex.Lock()
+	defer spool.mutex.Unlock()
+
+	spool.closing = true
+	spool.cond.Broadcast()
+}
+
+// Stop appending, next returns false once all batches are read
+func (spool *spool) stop() {
+	spool.mutex.Lock()
+	defer spool.mutex.Unlock()
+
+	spool.closed = true
+	spool.cond.Broadcast()
+}
+
+// Close segment files, batches which are not acked are kept for replay
+func (spool *spool) close() error {
+	spool.mutex.Lock()
+	defer spool.mutex.Unlock()
+
+	if spool.reader != nil {
+		spool.reader.Close()
+		spool.reader = nil
+	}
+
+	// segment is kept only if it has batches which are not acked
+	active := spool.segments[len(spool.segments)-1]
+	err := spool.file.Close()
+	if active.size == 0 || (len(spool.segments) == 1 && spool.offset >= active.size) {
+		os.Remove(spool.segmentPath(active.id))
+	}
+
+	return err
+}
+
+// Encode batch as record, which is length and crc32 of payload followed by payload.
+// Payload is number of entries followed by length and content of every entry.
+func encodeRecord(entries [][]byte) []byte {
+	size := recordHeaderSize + 4
+	for i := range entries {
+		size += 4 + len(entries[i])
+	}
+
+	record := make([]byte, recordHeaderSize, size)
+	record = appendUint32(record, uint32(len(entries)))
+	for i := range entries {
+		record = appendUint32(record, uint32(len(entries[i])))
+		record = append(record, entries[i]...)
+	}
+
+	payload := record[recordHeaderSize:]
+	binary.BigEndian.PutUint32(record[0:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(payload))
+	return record
+}
+
+// Read payload of record and verify its checksum
+func readRecord(reader io.Reader) ([]byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+
+	if len(payload) < 4 || crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errors.New("invalid checksum of spool record")
+	}
+
+	return payload, nil
+}
+
+// Decode entries of batch from payload of record
+func decodeRecord(payload []byte) ([][]byte, error) {
+	count := binary.BigEndian.Uint32(payload)
+	payload = payload[4:]
+
+	entries := make([][]byte, 0, count)
+	for i := uint32(0); i < count; i++ {
+		if len(payload) < 4 {
+			return nil, errors.New("invalid entry of spool record")
+		}
+
+		size := binary.BigEndian.Uint32(payload)
+		payload = payload[4:]
+		if uint32(len(payload)) < size {
+			return nil, errors.New("invalid entry of spool record")
+		}
+
+		entries = append(entries, payload[:size])
+		payload = payload[size:]
+	}
+
+	return entries, nil
+}
+
+// Append uint32 in big endian
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package batch
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Create a temp dir of spool which would be removed after test
+func newSpoolDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "spool")
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	return dir
+}
+
+// List names of segment files in spool
+func listSegments(t *testing.T, dir string) []string {
+	names, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	assert.Nil(t, err)
+	return names
+}
+
+func TestRecord_EncodeAndDecode(t *testing.T) {
+	record := encodeRecord([][]byte{[]byte("a"), {}, []byte("bc")})
+
+	payload, err := readRecord(bytes.NewReader(record))
+	assert.Nil(t, err)
+	entries, err := decodeRecord(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("a"), {}, []byte("bc")}, entries)
+
+	// With corrupted record
+	record[len(record)-1] = 'x'
+	_, err = readRecord(bytes.NewReader(record))
+	assert.NotNil(t, err)
+
+	// With truncated record
+	_, err = readRecord(bytes.NewReader(record[:len(record)-1]))
+	assert.NotNil(t, err)
+}
+
+// Batches are flushed from spool and removed once they are flushed
+func TestWriter_WithSpool(t *testing.T) {
+	dir := newSpoolDir(t)
+	rec := &recorder{}
+	writer := NewWriter(Config{Size: 1, SpoolDir: dir}, rec.flush)
+
+	writer.Write([]byte("a"))
+	writer.Write([]byte("b"))
+	assert.Nil(t, writer.Sync())
+
+	assert.Eventually(t, func() bool {
+		return len(rec.flushed()) == 2
+	}, 5*time.Second, time.Millisecond)
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, rec.flushed())
+
+	// segment without batches to flush is removed on close
+	assert.Empty(t, listSegments(t, dir))
+}
+
+// Batches which are not flushed before close are flushed by writer created with the same spool
+func TestWriter_WithSpoolReplay(t *testing.T) {
+	dir := newSpoolDir(t)
+	rec := &recorder{errs: []error{errors.New("unavailable")}}
+	writer := NewWriter(Config{Size: 1, Retries: 0, Backoff: time.Hour, SpoolDir: dir}, rec.flush)
+
+	writer.Write([]byte("a"))
+	assert.Eventually(t, func() bool {
+		rec.mutex.Lock()
+		defer rec.mutex.Unlock()
+		return rec.calls == 1
+	}, 5*time.Second, time.Millisecond)
+	writer.Write([]byte("b"))
+
+	err := writer.Close()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "batch is kept in spool")
+	assert.Empty(t, rec.flushed())
+	assert.NotEmpty(t, listSegments(t, dir))
+
+	writer = NewWriter(Config{Size: 1, SpoolDir: dir}, rec.flush)
+	writer.Write([]byte("c"))
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, [][]string{{"a"}, {"b"}, {"c"}}, rec.flushed())
+	assert.Empty(t, listSegments(t, dir))
+}
+
+// Failed batches are retried until they are flushed
+func TestWriter_WithSpoolRetries(t *testing.T) {
+	rec := &recorder{errs: []error{errors.New("unavailable"), errors.New("unavailable"), errors.New("unavailable")}}
+	writer := NewWriter(Config{Size: 1, Retries: 0, Backoff: time.Millisecond, SpoolDir: newSpoolDir(t)}, rec.flush)
+	defer writer.Close()
+
+	writer.Write([]byte("a"))
+	writer.Write([]byte("b"))
+
+	assert.Eventually(t, func() bool {
+		return len(rec.flushed()) == 2
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, rec.flushed())
+
+	// errors of retries are returned by sync
+	assert.NotNil(t, writer.Sync())
+	assert.Nil(t, writer.Sync())
+}
+
+// Batches with permanent errors are removed from spool
+func TestWriter_WithSpoolPermanentError(t *testing.T) {
+	rec := &recorder{errs: []error{Permanent(errors.New("bad request"))}}
+	writer := NewWriter(Config{Size: 1, SpoolDir: newSpoolDir(t)}, rec.flush)
+
+	writer.Write([]byte("a"))
+	writer.Write([]byte("b"))
+
+	err := writer.Close()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "bad request")
+	assert.Equal(t, [][]string{{"b"}}, rec.flushed())
+}
+
+// Acked batches in segment are not replayed after restart
+func TestSpool_WithCursor(t *testing.T) {
+	dir := newSpoolDir(t)
+	var dropped uint64
+	spool, err := openSpool(dir, 1<<20, Block, &dropped)
+	assert.Nil(t, err)
+
+	assert.Nil(t, spool.append([][]byte{[]byte("a")}))
+	assert.Nil(t, spool.append([][]byte{[]byte("b")}))
+
+	entries, id, end, ok := spool.next()
+	assert.True(t, ok)
+	assert.Equal(t, [][]byte{[]byte("a")}, entries)
+	assert.Nil(t, spool.ack(id, end, len(entries)))
+	assert.Nil(t, spool.close())
+
+	spool, err = openSpool(dir, 1<<20, Block, &dropped)
+	assert.Nil(t, err)
+	entries, _, _, ok = spool.next()
+	assert.True(t, ok)
+	assert.Equal(t, [][]byte{[]byte("b")}, entries)
+	assert.Nil(t, spool.close())
+}
+
+// Partial record written before exit is truncated
+func TestSpool_WithPartialRecord(t *testing.T) {
+	dir := newSpoolDir(t)
+	var dropped uint64
+	spool, err := openSpool(dir, 1<<20, Block, &dropped)
+	assert.Nil(t, err)
+	assert.Nil(t, spool.append([][]byte{[]byte("a")}))
+	assert.Nil(t, spool.close())
+
+	names := listSegments(t, dir)
+	assert.Len(t, names, 1)
+	file, err := os.OpenFile(names[0], os.O_WRONLY|os.O_APPEND, 0644)
+	assert.Nil(t, err)
+	file.Write(encodeRecord([][]byte{[]byte("b")})[:5])
+	file.Close()
+
+	spool, err = openSpool(dir, 1<<20, Block, &dropped)
+	assert.Nil(t, err)
+	assert.Nil(t, spool.append([][]byte{[]byte("c")}))
+
+	entries, id, end, _ := spool.next()
+	assert.Equal(t, [][]byte{[]byte("a")}, entries)
+	assert.Nil(t, spool.ack(id, end, len(entries)))
+	entries, _, _, _ = spool.next()
+	assert.Equal(t, [][]byte{[]byte("c")}, entries)
+	assert.Nil(t, spool.close())
+}
+
+// With spool which is full
+func TestSpool_WithOverflow(t *testing.T) {
+	record := int64(len(encodeRecord([][]byte{[]byte("a")})))
+
+	// With dropNewest
+	var dropped uint64
+	spool, err := openSpool(newSpoolDir(t), 2*record, DropNewest, &dropped)
+	assert.Nil(t, err)
+	for _, entry := range []string{"a", "b", "c"} {
+		assert.Nil(t, spool.append([][]byte{[]byte(entry)}))
+	}
+	assert.Equal(t, uint64(1), dropped)
+	entries, _, _, _ := spool.next()
+	assert.Equal(t, [][]byte{[]byte("a")}, entries)
+	assert.Nil(t, spool.close())
+
+	// With dropOldest, segments are smaller than spool
+	dropped = 0
+	spool, err = openSpool(newSpoolDir(t), 8*record, DropOldest, &dropped)
+	assert.Nil(t, err)
+	for _, entry := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"} {
+		assert.Nil(t, spool.append([][]byte{[]byte(entry)}))
+	}
+	assert.Equal(t, uint64(1), dropped)
+	entries, _, _, _ = spool.next()
+	assert.Equal(t, [][]byte{[]byte("b")}, entries)
+	assert.Nil(t, spool.close())
+
+	// With block, append waits until batches are acked
+	spool, err = openSpool(newSpoolDir(t), 2*record, Block, &dropped)
+	assert.Nil(t, err)
+	assert.Nil(t, spool.append([][]byte{[]byte("a")}))
+	assert.Nil(t, spool.append([][]byte{[]byte("b")}))
+
+	appended := make(chan struct{})
+	go func() {
+		spool.append([][]byte{[]byte("c")})
+		close(appended)
+	}()
+
+	select {
+	case <-appended:
+		assert.Fail(t, "append is not blocked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	entries, id, end, _ := spool.next()
+	assert.Nil(t, spool.ack(id, end, len(entries)))
+	<-appended
+	assert.Nil(t, spool.close())
+
+	// With batch larger than spool
+	assert.NotNil(t, spool.append([][]byte{make([]byte, 2*record)}))
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package columns maps json entries to columns of tables, it is shared by sinks which insert entries into databases.
+package columns
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"regexp"
+	"strings"
+)
+
+// Entry is the key which maps the whole entry to column.
+const Entry = "*"
+
+// names of columns which could be used without quoting, like created_at
+var nameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Column maps key of entry to column of table.
+type Column struct {
+	Name string
+	Key  string
+}
+
+// Mapping is the ordered columns of table.
+type Mapping []Column
+
+// Parse mapping like ts:time,level:level,message:msg,entry:*, where column name comes before key of entry.
+// Column name is used as key if key is omitted.
+func Parse(value string) (Mapping, error) {
+	res := make(Mapping, 0)
+	seen := make(map[string]bool)
+
+	for _, pair := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		column := Column{Name: kv[0], Key: kv[0]}
+		if len(kv) == 2 {
+			column.Key = kv[1]
+		}
+
+		if !nameRegex.MatchString(column.Name) || len(column.Key) == 0 {
+			return nil, errors.Errorf("invalid column, column:%s", pair)
+		}
+
+		if seen[column.Name] {
+			return nil, errors.Errorf("duplicated column, column:%s", column.Name)
+		}
+		seen[column.Name] = true
+
+		res = append(res, column)
+	}
+
+	return res, nil
+}
+
+// Names returns names of columns in order.
+func (mapping Mapping) Names() []string {
+	res := make([]string, 0, len(mapping))
+	for i := range mapping {
+		res = append(res, mapping[i].Name)
+	}
+
+	return res
+}
+
+// Values returns values of columns from entry encoded as json object, strings, json.Number and booleans are returned
+// as they are, objects and arrays are returned as json strings and missing keys are returned as nil.
+func (mapping Mapping) Values(entry []byte) ([]interface{}, error) {
+	entry = bytes.TrimRight(entry, "\r\n")
+
+	decoder := json.NewDecoder(bytes.NewReader(entry))
+	decoder.UseNumber()
+	fields := make(map[string]interface{})
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, errors.Wrap(err, "entry is not a json object")
+	}
+
+	res := make([]interface{}, 0, len(mapping))
+	for i := range mapping {
+		if mapping[i].Key == Entry {
+			res = append(res, string(entry))
+			continue
+		}
+
+		switch v := fields[mapping[i].Key].(type) {
+		case map[string]interface{}, []interface{}:
+			raw, _ := json.Marshal(v)
+			res = append(res, string(raw))
+		default:
+			res = append(res, v)
+		}
+	}
+
+	return res, nil
+}
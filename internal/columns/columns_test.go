@@ -0,0 +1,43 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package columns
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse_HappyCase(t *testing.T) {
+	mapping, err := Parse("ts:time, level ,message:msg,entry:*")
+	assert.Nil(t, err)
+	assert.Equal(t, Mapping{
+		{Name: "ts", Key: "time"},
+		{Name: "level", Key: "level"},
+		{Name: "message", Key: "msg"},
+		{Name: "entry", Key: Entry},
+	}, mapping)
+	assert.Equal(t, []string{"ts", "level", "message", "entry"}, mapping.Names())
+}
+
+func TestParse_WithInvalidColumns(t *testing.T) {
+	for _, value := range []string{"", "ts:", "1ts:time", "drop table:msg", "ts:a,ts:b"} {
+		_, err := Parse(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestMapping_Values(t *testing.T) {
+	mapping, _ := Parse("message:msg,status:status,ok:ok,user:user,tags:tags,missing,entry:*")
+
+	entry := `{"msg":"a","status":404,"ok":true,"user":{"id":1},"tags":["x"]}`
+	values, err := mapping.Values([]byte(entry + "\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", json.Number("404"), true, `{"id":1}`, `["x"]`, nil, entry}, values)
+
+	// With entry which is not json
+	_, err = mapping.Values([]byte("plain\n"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package extension flattens fields of json entries to key value pairs, it is shared by encoders of SIEM formats
+// whose extensions are flat, like CEF and LEEF.
+package extension
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Pair is a key value pair of extension.
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// Decode json object encoded by zap json encoder, numbers are kept as they are encoded.
+func Decode(p []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	values := make(map[string]interface{})
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Flatten values to pairs sorted by key, keys of nested objects are joined with dot, arrays are encoded as json.
+// Characters of keys other than letters, digits, dot and underscore are replaced with underscore.
+func Flatten(values map[string]interface{}) []Pair {
+	res := make([]Pair, 0, len(values))
+	flatten(&res, "", values)
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+// Flatten values with prefix of keys
+func flatten(res *[]Pair, prefix string, values map[string]interface{}) {
+	for k, v := range values {
+		key := prefix + sanitizeKey(k)
+
+		switch value := v.(type) {
+		case nil:
+		case map[string]interface{}:
+			flatten(res, key+".", value)
+		case string:
+			*res = append(*res, Pair{Key: key, Value: value})
+		case json.Number:
+			*res = append(*res, Pair{Key: key, Value: value.String()})
+		case bool:
+			if value {
+				*res = append(*res, Pair{Key: key, Value: "true"})
+			} else {
+				*res = append(*res, Pair{Key: key, Value: "false"})
+			}
+		default:
+			raw, _ := json.Marshal(value)
+			*res = append(*res, Pair{Key: key, Value: string(raw)})
+		}
+	}
+}
+
+// Replace characters which are not allowed in keys of extensions with underscore
+func sanitizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
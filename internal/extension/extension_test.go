@@ -0,0 +1,33 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package extension
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	values, err := Decode([]byte(`{"id":12345678901234567890,"ok":true}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "12345678901234567890", Flatten(values)[0].Value)
+
+	// With invalid json
+	_, err = Decode([]byte(`not-json`))
+	assert.NotNil(t, err)
+}
+
+func TestFlatten(t *testing.T) {
+	values, err := Decode([]byte(`{"user":{"name":"ut","id":1},"tags":["a","b"],"ok":false,"empty":null,"bad key=":"v"}`))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []Pair{
+		{Key: "bad_key_", Value: "v"},
+		{Key: "ok", Value: "false"},
+		{Key: "tags", Value: `["a","b"]`},
+		{Key: "user.id", Value: "1"},
+		{Key: "user.name", Value: "ut"},
+	}, Flatten(values))
+}
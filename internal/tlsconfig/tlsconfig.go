@@ -0,0 +1,103 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package tlsconfig builds tls.Config from query of output url, it is shared by sinks which connect to remote services.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+)
+
+// FromQuery builds tls.Config from query of output url, nil is returned if TLS is not enabled.
+//
+// Query parameters:
+//
+//	tls:           enables or disables TLS, it is enabled by the rest of parameters if not provided
+//	tlsCA:         path of PEM encoded CA certificates which replace system CA certificates
+//	tlsCert:       path of PEM encoded client certificate
+//	tlsKey:        path of PEM encoded private key of client certificate
+//	tlsServerName: server name which overrides host name of url
+//	tlsSkipVerify: skips verification of server certificate, which should only be used in tests
+//
+// Keys which are not related to TLS are returned.
+func FromQuery(query url.Values) (*tls.Config, url.Values, error) {
+	rest := make(url.Values)
+	implied := false
+	explicit := ""
+	config := &tls.Config{}
+	var certFile, keyFile string
+
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		var err error
+		switch key {
+		case "tls":
+			explicit = value
+			_, err = strconv.ParseBool(value)
+		case "tlsCA":
+			config.RootCAs, err = loadCertPool(value)
+		case "tlsCert":
+			certFile = value
+		case "tlsKey":
+			keyFile = value
+		case "tlsServerName":
+			config.ServerName = value
+		case "tlsSkipVerify":
+			config.InsecureSkipVerify, err = strconv.ParseBool(value)
+		default:
+			rest[key] = values
+			continue
+		}
+
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid query of tls, key:%s", key)
+		}
+
+		if key != "tls" {
+			implied = true
+		}
+	}
+
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to load client certificate")
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	enabled := implied
+	if len(explicit) > 0 {
+		enabled, _ = strconv.ParseBool(explicit)
+	}
+
+	if !enabled {
+		return nil, rest, nil
+	}
+
+	return config, rest, nil
+}
+
+// Load PEM encoded certificates from file
+func loadCertPool(filePath string) (*x509.CertPool, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, errors.Errorf("no certificate found in file, path:%s", filePath)
+	}
+
+	return pool, nil
+}
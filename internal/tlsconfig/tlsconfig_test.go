@@ -0,0 +1,109 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"path"
+	"testing"
+	"time"
+)
+
+// Write self signed certificate and its private key into dir
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ut"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certFile, keyFile := path.Join(dir, "cert.pem"), path.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return certFile, keyFile
+}
+
+func TestFromQuery_WithoutTLS(t *testing.T) {
+	query, _ := url.ParseQuery("topic=app")
+	config, rest, err := FromQuery(query)
+	assert.Nil(t, err)
+	assert.Nil(t, config)
+	assert.Equal(t, url.Values{"topic": {"app"}}, rest)
+}
+
+func TestFromQuery_WithExplicitTLS(t *testing.T) {
+	query, _ := url.ParseQuery("tls=true")
+	config, rest, err := FromQuery(query)
+	assert.Nil(t, err)
+	assert.NotNil(t, config)
+	assert.Empty(t, rest)
+
+	// With TLS disabled explicitly
+	query, _ = url.ParseQuery("tls=false&tlsServerName=example.com")
+	config, _, err = FromQuery(query)
+	assert.Nil(t, err)
+	assert.Nil(t, config)
+}
+
+func TestFromQuery_WithImpliedTLS(t *testing.T) {
+	query, _ := url.ParseQuery("tlsServerName=example.com&tlsSkipVerify=true")
+	config, _, err := FromQuery(query)
+	assert.Nil(t, err)
+	assert.NotNil(t, config)
+	assert.Equal(t, "example.com", config.ServerName)
+	assert.True(t, config.InsecureSkipVerify)
+}
+
+func TestFromQuery_WithCertificates(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir())
+
+	query := url.Values{
+		"tlsCA":   {certFile},
+		"tlsCert": {certFile},
+		"tlsKey":  {keyFile},
+	}
+	config, _, err := FromQuery(query)
+	assert.Nil(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+}
+
+func TestFromQuery_WithInvalidQuery(t *testing.T) {
+	dir := t.TempDir()
+	invalid := path.Join(dir, "invalid.pem")
+	assert.Nil(t, ioutil.WriteFile(invalid, []byte("invalid"), 0600))
+
+	for _, query := range []url.Values{
+		{"tls": {"x"}},
+		{"tlsSkipVerify": {"x"}},
+		{"tlsCA": {path.Join(dir, "not-exist.pem")}},
+		{"tlsCA": {invalid}},
+		{"tlsCert": {invalid}},
+	} {
+		_, _, err := FromQuery(query)
+		assert.NotNil(t, err, query)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JournaldScheme is the scheme of output path which writes to systemd journal with native protocol, like:
+//
+//	outputPaths:
+//	  - journald://
+//	  # socket of journald and SYSLOG_IDENTIFIER of entries, name of executable by default
+//	  - journald:///run/systemd/journal/socket?identifier=app
+//
+// Entries are not encoded by encoder of zap config, levels are mapped to journal priorities
+// and fields are sent as journal fields whose names are converted to upper case, like USER_ID for userId.
+const JournaldScheme = "journald"
+
+// JournaldSocket is the default socket of systemd journal.
+const JournaldSocket = "/run/systemd/journal/socket"
+
+func init() {
+	if err := RegisterCore(JournaldScheme, newJournalCoreWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// Connect to systemd journal with url of output path and create journal core
+func newJournalCoreWithURL(u url.URL, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	journal, err := newJournalConn(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return newJournalCore(enabler, journal), nil
+}
+
+// journalConn sends entries to systemd journal, it is shared by cores derived with With()
+type journalConn struct {
+	conn       net.Conn
+	identifier string
+	mutex      sync.Mutex
+}
+
+// Connect to systemd journal with url of output path
+func newJournalConn(outputPath string) (*journalConn, error) {
+	u, err := url.Parse(outputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	query := u.Query()
+	for key := range query {
+		if key != "identifier" {
+			return nil, errors.Errorf("unknown query of journald url, key:%s", key)
+		}
+	}
+
+	socket := JournaldSocket
+	if len(u.Path) > 0 {
+		socket = fromFileURLPath(u.Path)
+	}
+
+	identifier := query.Get("identifier")
+	if len(identifier) == 0 {
+		identifier = filepath.Base(os.Args[0])
+	}
+
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to journald, socket:%s", socket)
+	}
+
+	return &journalConn{
+		conn:       conn,
+		identifier: identifier,
+	}, nil
+}
+
+// Send fields as one journal entry
+func (journal *journalConn) send(payload []byte) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	_, err := journal.conn.Write(payload)
+	return err
+}
+
+// Close connection to systemd journal
+func (journal *journalConn) close() error {
+	return journal.conn.Close()
+}
+
+// journalCore implements zapcore.Core which sends entries to systemd journal
+type journalCore struct {
+	zapcore.LevelEnabler
+	journal *journalConn
+	fields  []zapcore.Field
+}
+
+// Create journal core with level enabler of zap config
+func newJournalCore(enabler zapcore.LevelEnabler, journal *journalConn) *journalCore {
+	return &journalCore{
+		LevelEnabler: enabler,
+		journal:      journal,
+	}
+}
+
+// With implements zapcore.Core
+func (core *journalCore) With(fields []zapcore.Field) zapcore.Core {
+	res := make([]zapcore.Field, 0, len(core.fields)+len(fields))
+	res = append(res, core.fields...)
+	res = append(res, fields...)
+
+	return &journalCore{
+		LevelEnabler: core.LevelEnabler,
+		journal:      core.journal,
+		fields:       res,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *journalCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core
+func (core *journalCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range core.fields {
+		core.fields[i].AddTo(enc)
+	}
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	payload := &bytes.Buffer{}
+	writeJournalField(payload, "MESSAGE", entry.Message)
+	writeJournalField(payload, "PRIORITY", strconv.Itoa(journalPriority(entry.Level)))
+	writeJournalField(payload, "SYSLOG_IDENTIFIER", core.journal.identifier)
+
+	if len(entry.LoggerName) > 0 {
+		writeJournalField(payload, "LOGGER_NAME", entry.LoggerName)
+	}
+
+	if entry.Caller.Defined {
+		writeJournalField(payload, "CODE_FILE", entry.Caller.File)
+		writeJournalField(payload, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+		if len(entry.Caller.Function) > 0 {
+			writeJournalField(payload, "CODE_FUNC", entry.Caller.Function)
+		}
+	}
+
+	if len(entry.Stack) > 0 {
+		writeJournalField(payload, "STACKTRACE", entry.Stack)
+	}
+
+	// fields in order of keys, so that entries are stable
+	keys := make([]string, 0, len(enc.Fields))
+	for k := range enc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		writeJournalField(payload, toJournalFieldName(k), toJournalFieldValue(enc.Fields[k]))
+	}
+
+	return core.journal.send(payload.Bytes())
+}
+
+// Sync implements zapcore.Core, entries are not buffered
+func (core *journalCore) Sync() error {
+	return nil
+}
+
+// Close implements io.Closer, connection is shared by cores derived with With()
+func (core *journalCore) Close() error {
+	return core.journal.close()
+}
+
+// Map level of zap to priority of journal, which is the same as severity of syslog
+// Fatal is mapped to crit instead of emerg which is broadcast to all of the terminals, trace is mapped to debug
+func journalPriority(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return 7
+	case level == zapcore.InfoLevel:
+		return 6
+	case level == zapcore.WarnLevel:
+		return 4
+	case level == zapcore.ErrorLevel:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// Write field with native protocol of journal, values with newline are serialized in binary format
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.ContainsRune(value, '\n') {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// Convert key of field to name of journal field which consists of upper case letters, digits and underscores,
+// camel case keys are separated with underscores, like USER_ID for userId.
+// Names could not start with underscore which is reserved for trusted fields.
+func toJournalFieldName(key string) string {
+	var buf strings.Builder
+	for i, r := range key {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			if i > 0 && buf.Len() > 0 && key[i-1] >= 'a' && key[i-1] <= 'z' {
+				buf.WriteByte('_')
+			}
+			buf.WriteRune(r)
+		case r >= 'a' && r <= 'z':
+			buf.WriteRune(r - 'a' + 'A')
+		case r >= '0' && r <= '9':
+			buf.WriteRune(r)
+		default:
+			buf.WriteByte('_')
+		}
+	}
+
+	name := strings.TrimLeft(buf.String(), "_")
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		name = "FIELD_" + name
+	}
+
+	// names are limited to 64 characters
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// Convert value of field to string, values other than strings are marshalled as json
+func toJournalFieldValue(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+
+	if bytes, err := json.Marshal(value); err == nil {
+		return string(bytes)
+	}
+
+	return fmt.Sprint(value)
+}
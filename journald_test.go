@@ -0,0 +1,162 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Listen on unixgram socket as systemd journal
+func newJournalServer(t *testing.T) (string, net.PacketConn) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unixgram is not supported on windows")
+	}
+
+	socketPath := path.Join(newTempDir(t), "journal.sock")
+	conn, err := net.ListenPacket("unixgram", socketPath)
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return socketPath, conn
+}
+
+// Receive an entry and parse fields with native protocol of journal
+func receiveJournalEntry(t *testing.T, conn net.PacketConn) map[string]string {
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+
+	return parseJournalFields(buf[:n])
+}
+
+// Parse fields with native protocol of journal
+func parseJournalFields(payload []byte) map[string]string {
+	res := make(map[string]string)
+	for len(payload) > 0 {
+		end := bytes.IndexByte(payload, '\n')
+		line := payload[:end]
+		payload = payload[end+1:]
+
+		if i := bytes.IndexByte(line, '='); i >= 0 {
+			res[string(line[:i])] = string(line[i+1:])
+			continue
+		}
+
+		// binary format
+		size := binary.LittleEndian.Uint64(payload[:8])
+		res[string(line)] = string(payload[8 : 8+size])
+		payload = payload[8+size+1:]
+	}
+
+	return res
+}
+
+func TestJournalCore_HappyCase(t *testing.T) {
+	socketPath, conn := newJournalServer(t)
+	config := NewConfigWithOptions(
+		WithOutput("journald://"+socketPath+"?identifier=ut"),
+		WithFields(map[string]interface{}{"app": "ut"}))
+
+	logger, closer, err := NewZapLoggerWithCloser(config, zap.AddCaller())
+	assert.Nil(t, err)
+	defer closer.Shutdown(context.Background())
+
+	logger.Named("db").Warn("ut-message", zap.Int("userId", 1), zap.String("query", "select\n1"))
+
+	fields := receiveJournalEntry(t, conn)
+	assert.Equal(t, "ut-message", fields["MESSAGE"])
+	assert.Equal(t, "4", fields["PRIORITY"])
+	assert.Equal(t, "ut", fields["SYSLOG_IDENTIFIER"])
+	assert.Equal(t, "db", fields["LOGGER_NAME"])
+	assert.Equal(t, "ut", fields["APP"])
+	assert.Equal(t, "1", fields["USER_ID"])
+	assert.Equal(t, "select\n1", fields["QUERY"])
+	assert.Contains(t, fields["CODE_FILE"], "journald_test.go")
+	assert.NotEmpty(t, fields["CODE_LINE"])
+}
+
+// Journal is written along with other outputs, levels are respected
+func TestJournalCore_WithOtherOutputs(t *testing.T) {
+	socketPath, conn := newJournalServer(t)
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	zapConfig := NewZapStdoutConfig()
+	zapConfig.OutputPaths = []string{filePath, "journald://" + socketPath}
+	logger, err := NewZapLoggerWithConf(zapConfig, nil)
+	assert.Nil(t, err)
+
+	logger.Debug("ut-debug")
+	logger.Info("ut-info")
+
+	assert.Equal(t, "ut-info", receiveJournalEntry(t, conn)["MESSAGE"])
+	content := readFileContent(filePath)
+	assert.Contains(t, content, "ut-info")
+	assert.NotContains(t, content, "ut-debug")
+}
+
+// With unreachable journal
+func TestJournalCore_WithUnreachableJournal(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	config := NewConfigWithOptions(WithOutput(filePath, "journald://"+path.Join(newTempDir(t), "journal.sock")))
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to connect to journald")
+
+	_, err = newJournalConn("journald://?unknown=1")
+	assert.NotNil(t, err)
+}
+
+func TestJournalPriority(t *testing.T) {
+	assert.Equal(t, 7, journalPriority(zap.DebugLevel))
+	assert.Equal(t, 6, journalPriority(zap.InfoLevel))
+	assert.Equal(t, 4, journalPriority(zap.WarnLevel))
+	assert.Equal(t, 3, journalPriority(zap.ErrorLevel))
+	assert.Equal(t, 2, journalPriority(zap.DPanicLevel))
+	assert.Equal(t, 2, journalPriority(zap.PanicLevel))
+	assert.Equal(t, 2, journalPriority(zap.FatalLevel))
+}
+
+func TestToJournalFieldName(t *testing.T) {
+	assert.Equal(t, "USER_ID", toJournalFieldName("userId"))
+	assert.Equal(t, "USER_ID", toJournalFieldName("user_id"))
+	assert.Equal(t, "HTTP_STATUS", toJournalFieldName("http.status"))
+	assert.Equal(t, "URL", toJournalFieldName("URL"))
+	assert.Equal(t, "TRUSTED", toJournalFieldName("_trusted"))
+	assert.Equal(t, "FIELD_1ST", toJournalFieldName("1st"))
+	assert.Equal(t, "FIELD_", toJournalFieldName("_"))
+	assert.Len(t, toJournalFieldName(strings.Repeat("a", 100)), 64)
+}
+
+func TestToJournalFieldValue(t *testing.T) {
+	assert.Equal(t, "value", toJournalFieldValue("value"))
+	assert.Equal(t, "1", toJournalFieldValue(1))
+	assert.Equal(t, `{"key":"value"}`, toJournalFieldValue(map[string]interface{}{"key": "value"}))
+	assert.Equal(t, "(0+1i)", toJournalFieldValue(complex(0, 1)))
+}
+
+// Fields added with With are kept in derived cores
+func TestJournalCore_With(t *testing.T) {
+	core := newJournalCore(zap.InfoLevel, nil)
+	derived := core.With([]zapcore.Field{zap.String("key", "value")}).(*journalCore)
+	assert.Len(t, derived.fields, 1)
+	assert.Empty(t, core.fields)
+	assert.Nil(t, derived.Sync())
+}
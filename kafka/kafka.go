@@ -0,0 +1,204 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package kafka registers kafka scheme with rklogger.RegisterWriter, so that entries are produced to kafka topic
+// by output paths like:
+//
+//	outputPaths:
+//	  - kafka://broker1:9092,broker2:9092/app-logs?compression=snappy&batchSize=500&linger=100ms
+//
+// Import the package for side effects in order to enable the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/kafka"
+//
+// Query parameters:
+//
+//	compression: none, gzip, snappy, lz4 or zstd, none by default
+//	acks:        none, one or all, all by default
+//	sasl:        plain, scram-sha-256 or scram-sha-512 along with username and password
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//	tls, tlsCA, tlsCert, tlsKey, tlsServerName and tlsSkipVerify of TLS
+//
+// Entries are queued in bounded memory and produced in batches in background, overflow decides whether
+// loggers are blocked, which is the default, or entries are dropped once the queue is full.
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/rookie-ninja/rk-logger/internal/tlsconfig"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Scheme is the scheme of output paths which are produced to kafka.
+const Scheme = "kafka"
+
+func init() {
+	if err := rklogger.RegisterWriter(Scheme, newWriterWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// producer produces messages to kafka, which is implemented by kafka.Writer
+type producer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// writer queues entries and produces them in batches
+type writer struct {
+	*batch.Writer
+	producer producer
+}
+
+// Create writer with url of output path
+func newWriterWithURL(u url.URL) (zapcore.WriteSyncer, error) {
+	config, producer, err := parseURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWriter(config, producer), nil
+}
+
+// Create writer which produces batches with producer
+func newWriter(config batch.Config, producer producer) *writer {
+	return &writer{
+		Writer: batch.NewWriter(config, func(ctx context.Context, entries [][]byte) error {
+			msgs := make([]kafka.Message, 0, len(entries))
+			for i := range entries {
+				msgs = append(msgs, kafka.Message{Value: bytes.TrimRight(entries[i], "\r\n")})
+			}
+
+			return producer.WriteMessages(ctx, msgs...)
+		}),
+		producer: producer,
+	}
+}
+
+// Close flushes queued entries and closes producer
+func (writer *writer) Close() error {
+	return multierr.Append(writer.Writer.Close(), writer.producer.Close())
+}
+
+// Parse batching config and kafka producer from url
+func parseURL(u *url.URL) (batch.Config, *kafka.Writer, error) {
+	config := batch.DefaultConfig()
+
+	brokers := strings.Split(u.Host, ",")
+	topic := strings.Trim(u.Path, "/")
+	if len(u.Host) == 0 || len(topic) == 0 {
+		return config, nil, errors.Errorf("brokers and topic are required in kafka url, host:%s, path:%s", u.Host, u.Path)
+	}
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, err
+	}
+
+	tlsConfig, query, err := tlsconfig.FromQuery(query)
+	if err != nil {
+		return config, nil, err
+	}
+
+	producer := &kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+		// batches are retried by batch.Writer
+		MaxAttempts:  1,
+		BatchSize:    config.Size,
+		BatchBytes:   int64(config.Bytes),
+		BatchTimeout: time.Millisecond,
+		RequiredAcks: kafka.RequireAll,
+	}
+
+	var mechanism sasl.Mechanism
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		switch key {
+		case "compression":
+			producer.Compression, err = parseCompression(value)
+		case "acks":
+			producer.RequiredAcks, err = parseAcks(value)
+		case "sasl":
+			mechanism, err = parseSASL(value, query.Get("username"), query.Get("password"))
+		case "username", "password":
+		default:
+			err = errors.New("unknown query")
+		}
+
+		if err != nil {
+			return config, nil, errors.Wrapf(err, "invalid query of kafka url, key:%s", key)
+		}
+	}
+
+	if tlsConfig != nil || mechanism != nil {
+		producer.Transport = &kafka.Transport{
+			TLS:  tlsConfig,
+			SASL: mechanism,
+		}
+	}
+
+	return config, producer, nil
+}
+
+// Parse compression codec
+func parseCompression(value string) (kafka.Compression, error) {
+	switch value {
+	case "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	default:
+		return 0, errors.Errorf("compression is not supported, compression:%s", value)
+	}
+}
+
+// Parse required acks
+func parseAcks(value string) (kafka.RequiredAcks, error) {
+	switch value {
+	case "none":
+		return kafka.RequireNone, nil
+	case "one":
+		return kafka.RequireOne, nil
+	case "all":
+		return kafka.RequireAll, nil
+	default:
+		return 0, errors.Errorf("acks is not supported, acks:%s", value)
+	}
+}
+
+// Parse SASL mechanism with credentials
+func parseSASL(value, username, password string) (sasl.Mechanism, error) {
+	switch value {
+	case "plain":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	default:
+		return nil, errors.Errorf("sasl mechanism is not supported, sasl:%s", value)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package kafka
+
+import (
+	"context"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProducer records produced messages
+type fakeProducer struct {
+	values []string
+	closed bool
+	mutex  sync.Mutex
+}
+
+// WriteMessages implements producer
+func (producer *fakeProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+
+	for i := range msgs {
+		producer.values = append(producer.values, string(msgs[i].Value))
+	}
+	return nil
+}
+
+// Close implements producer
+func (producer *fakeProducer) Close() error {
+	producer.closed = true
+	return nil
+}
+
+func TestParseURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("kafka://broker1:9092,broker2:9092/app-logs?compression=snappy&acks=one&batchSize=500&linger=100ms&overflow=dropNewest")
+	config, producer, err := parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "broker1:9092,broker2:9092", producer.Addr.String())
+	assert.Equal(t, "app-logs", producer.Topic)
+	assert.Equal(t, kafka.Snappy, producer.Compression)
+	assert.Equal(t, kafka.RequireOne, producer.RequiredAcks)
+	assert.Equal(t, 500, producer.BatchSize)
+	assert.Equal(t, 500, config.Size)
+	assert.Equal(t, 100*time.Millisecond, config.Linger)
+	assert.Nil(t, producer.Transport)
+}
+
+func TestParseURL_WithDefaults(t *testing.T) {
+	u, _ := url.Parse("kafka://localhost:9092/app-logs")
+	_, producer, err := parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, kafka.RequireAll, producer.RequiredAcks)
+	assert.Equal(t, kafka.Compression(0), producer.Compression)
+}
+
+func TestParseURL_WithTLSAndSASL(t *testing.T) {
+	u, _ := url.Parse("kafka://localhost:9093/app-logs?tls=true&sasl=scram-sha-512&username=user&password=pass")
+	_, producer, err := parseURL(u)
+	assert.Nil(t, err)
+
+	transport := producer.Transport.(*kafka.Transport)
+	assert.NotNil(t, transport.TLS)
+	assert.Equal(t, "SCRAM-SHA-512", transport.SASL.Name())
+
+	// With plain
+	u, _ = url.Parse("kafka://localhost:9093/app-logs?sasl=plain&username=user&password=pass")
+	_, producer, err = parseURL(u)
+	assert.Nil(t, err)
+	transport = producer.Transport.(*kafka.Transport)
+	assert.Nil(t, transport.TLS)
+	assert.Equal(t, "PLAIN", transport.SASL.Name())
+}
+
+func TestParseURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"kafka:///app-logs",
+		"kafka://localhost:9092",
+		"kafka://localhost:9092/app-logs?compression=brotli",
+		"kafka://localhost:9092/app-logs?acks=two",
+		"kafka://localhost:9092/app-logs?sasl=gssapi",
+		"kafka://localhost:9092/app-logs?batchSize=0",
+		"kafka://localhost:9092/app-logs?tls=x",
+		"kafka://localhost:9092/app-logs?unknown=x",
+	} {
+		u, _ := url.Parse(raw)
+		_, _, err := parseURL(u)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestWriter_HappyCase(t *testing.T) {
+	producer := &fakeProducer{}
+	writer := newWriter(batch.Config{Linger: time.Hour}, producer)
+
+	writer.Write([]byte("{\"msg\":\"a\"}\n"))
+	writer.Write([]byte("{\"msg\":\"b\"}\n"))
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, []string{`{"msg":"a"}`, `{"msg":"b"}`}, producer.values)
+
+	assert.Nil(t, writer.Close())
+	assert.True(t, producer.closed)
+}
+
+func TestRegister(t *testing.T) {
+	// producer does not connect to brokers until entries are flushed
+	sink, closeFunc, err := zap.Open("kafka://localhost:9092/app-logs")
+	assert.Nil(t, err)
+	assert.NotNil(t, sink)
+	closeFunc()
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package klog redirects klog, which is used by Kubernetes client-go, to zap logger, so that operators do not
+// write raw klog lines to stderr:
+//
+//	logger, _, _ := rklogger.NewZapLoggerWithConfPath("/etc/app/zap.yaml", rklogger.YAML)
+//	klog.Redirect(logger)
+//	defer klog.Flush()
+//
+// Entries are logged by logr adapter of package logr, which maps V(0) to info, V(1) to debug and V(n) to zap level
+// -n. Verbosity of klog is set with flags of klog internally, which is the max V-level enabled by zap logger unless
+// WithVerbosity is provided. glog could not be redirected since it provides no API of output, migrate to klog first.
+package klog
+
+import (
+	"flag"
+	"github.com/go-logr/logr"
+	rklogr "github.com/rookie-ninja/rk-logger/logr"
+	"go.uber.org/zap"
+	"k8s.io/klog/v2"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Option is the option of Redirect.
+type Option func(*options)
+
+// options of Redirect
+type options struct {
+	verbosity int
+}
+
+// WithVerbosity sets verbosity of klog, which is -v flag of klog, V-levels above it are dropped by klog.
+func WithVerbosity(verbosity int) Option {
+	return func(opts *options) {
+		opts.verbosity = verbosity
+	}
+}
+
+// Redirect redirects klog to zap logger, which is flushed by klog.Flush. It should be called during initialization
+// before goroutines are started, since logger of klog is not mutex protected.
+func Redirect(logger *zap.Logger, opts ...Option) error {
+	options := &options{verbosity: Verbosity(logger)}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	if err := flags.Set("v", strconv.Itoa(options.verbosity)); err != nil {
+		return err
+	}
+
+	// frame of trimSink is skipped by callers
+	inner := rklogr.ToLogr(logger).WithCallDepth(1).GetSink()
+	klog.SetLoggerWithOptions(logr.New(&trimSink{LogSink: inner}),
+		klog.ContextualLogger(true),
+		klog.FlushLogger(func() { logger.Sync() }))
+	return nil
+}
+
+// Verbosity returns the max V-level of klog enabled by zap logger with rklogr.DefaultLevelMapper,
+// like 0 for info and 1 for debug.
+func Verbosity(logger *zap.Logger) int {
+	core := logger.Core()
+	res := 0
+	for v := 1; v <= -math.MinInt8; v++ {
+		if !core.Enabled(rklogr.DefaultLevelMapper(v)) {
+			break
+		}
+		res = v
+	}
+
+	return res
+}
+
+// trimSink wraps logr.LogSink of rklogr and trims trailing newline of messages, which klog appends to formatted ones
+type trimSink struct {
+	logr.LogSink
+}
+
+// Init implements logr.LogSink, wrapped sink is initialized by rklogr.ToLogr already
+func (sink *trimSink) Init(info logr.RuntimeInfo) {}
+
+// Info implements logr.LogSink
+func (sink *trimSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	sink.LogSink.Info(level, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+// Error implements logr.LogSink
+func (sink *trimSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	sink.LogSink.Error(err, strings.TrimSuffix(msg, "\n"), keysAndValues...)
+}
+
+// WithValues implements logr.LogSink
+func (sink *trimSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &trimSink{LogSink: sink.LogSink.WithValues(keysAndValues...)}
+}
+
+// WithName implements logr.LogSink
+func (sink *trimSink) WithName(name string) logr.LogSink {
+	return &trimSink{LogSink: sink.LogSink.WithName(name)}
+}
+
+// WithCallDepth implements logr.CallDepthLogSink
+func (sink *trimSink) WithCallDepth(depth int) logr.LogSink {
+	if withCallDepth, ok := sink.LogSink.(logr.CallDepthLogSink); ok {
+		return &trimSink{LogSink: withCallDepth.WithCallDepth(depth)}
+	}
+
+	return sink
+}
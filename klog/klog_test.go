@@ -0,0 +1,73 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package klog
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"k8s.io/klog/v2"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerbosity(t *testing.T) {
+	observed, _ := observer.New(zapcore.InfoLevel)
+	assert.Equal(t, 0, Verbosity(zap.New(observed)))
+
+	observed, _ = observer.New(zapcore.WarnLevel)
+	assert.Equal(t, 0, Verbosity(zap.New(observed)))
+
+	observed, _ = observer.New(zapcore.DebugLevel)
+	assert.Equal(t, 1, Verbosity(zap.New(observed)))
+
+	observed, _ = observer.New(zapcore.Level(-4))
+	assert.Equal(t, 4, Verbosity(zap.New(observed)))
+}
+
+func TestRedirect_HappyCase(t *testing.T) {
+	defer klog.ClearLogger()
+
+	observed, logs := observer.New(zapcore.DebugLevel)
+	assert.Nil(t, Redirect(zap.New(observed, zap.AddCaller())))
+
+	klog.Info("ut-info")
+	klog.V(1).Infof("ut-debug-%d", 1)
+	klog.V(2).Info("ut-ignored")
+	klog.InfoS("ut-structured", "key", "value")
+	klog.ErrorS(errors.New("ut-error"), "ut-failed")
+	klog.Infof("ut-newline\n")
+	klog.Flush()
+
+	assert.False(t, klog.V(2).Enabled())
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 5)
+	assert.Equal(t, "ut-info", entries[0].Message)
+	assert.Equal(t, zapcore.DebugLevel, entries[1].Level)
+	assert.Equal(t, "ut-debug-1", entries[1].Message)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, entries[2].ContextMap())
+	assert.Equal(t, zapcore.ErrorLevel, entries[3].Level)
+	assert.Equal(t, map[string]interface{}{"error": "ut-error"}, entries[3].ContextMap())
+	assert.Equal(t, "ut-newline", entries[4].Message)
+
+	// callers are the ones calling klog
+	for _, entry := range entries {
+		assert.Equal(t, "klog_test.go", filepath.Base(entry.Caller.File), entry.Message)
+	}
+}
+
+func TestRedirect_WithVerbosity(t *testing.T) {
+	defer klog.ClearLogger()
+
+	observed, logs := observer.New(zapcore.DebugLevel)
+	assert.Nil(t, Redirect(zap.New(observed), WithVerbosity(0)))
+
+	klog.V(1).Info("ut-ignored")
+	assert.False(t, klog.V(1).Enabled())
+	assert.Equal(t, 0, logs.Len())
+}
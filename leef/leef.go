@@ -0,0 +1,210 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package leef registers leef encoding with rklogger.RegisterEncoder, so that entries are encoded as events of
+// Log Event Extended Format 1.0 and collected by QRadar by config like:
+//
+//	encoding: leef
+//	outputPaths:
+//	  - syslog://qradar.example.com:514?network=tcp
+//
+// Import the package for side effects in order to enable the encoding:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/leef"
+//
+// Entries are encoded like, attributes are separated by tab:
+//
+//	LEEF:1.0|rookie-ninja|app|1.0|login failed|devTime=Sep 01 2020 10:00:00.000 UTC	sev=6	msg=login failed	src=10.0.0.1	usrName=admin
+//
+// Event ID is the value of eventId field, message is used if it is missing. Fields are flattened to attributes
+// with keys joined by dot, so that fields named by keys of LEEF, like src or usrName, are recognized by QRadar.
+// Fields named devTime, sev, cat, msg, caller or stacktrace are overwritten by entries.
+// Encoders with other vendor, product and version of header are created by NewEncoderWithHeader.
+package leef
+
+import (
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/extension"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Encoding is the name of encoding which encodes entries as LEEF events.
+	Encoding = "leef"
+	// Version is the version of LEEF which events conform to.
+	Version = "1.0"
+
+	// key of field which is used as event id of header
+	eventIDKey = "eventId"
+	// layout of devTime, which is the default devTimeFormat of LEEF in UTC
+	devTimeLayout = "Jan 02 2006 15:04:05.000 MST"
+)
+
+// pool of buffers returned by encoder
+var bufferPool = buffer.NewPool()
+
+// keys of attributes which are defined by entries, fields with the same keys are overwritten
+var entryKeys = []string{"devTime", "sev", "cat", "msg", "caller", "stacktrace"}
+
+// escapes header values and attribute values, tab is the delimiter of attributes
+var (
+	headerEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ", "\t", " ")
+	attributeEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\r", `\r`, "\n", `\n`)
+)
+
+func init() {
+	if err := rklogger.RegisterEncoder(Encoding, NewEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// Header is the device of LEEF header which events are reported by.
+type Header struct {
+	Vendor  string
+	Product string
+	Version string
+}
+
+// DefaultHeader returns header with vendor rookie-ninja, name of executable as product and version 1.0.
+func DefaultHeader() Header {
+	return Header{
+		Vendor:  "rookie-ninja",
+		Product: filepath.Base(os.Args[0]),
+		Version: "1.0",
+	}
+}
+
+// encoder encodes entries as LEEF events, fields are encoded by wrapped json encoder and flattened to attributes
+type encoder struct {
+	zapcore.Encoder
+	header     string
+	lineEnding string
+}
+
+// NewEncoder creates encoder of LEEF events with DefaultHeader and encoder config, only duration, time and
+// line ending settings are applied, keys of message, level, time, logger name, caller and stacktrace are
+// defined by LEEF.
+func NewEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return NewEncoderWithHeader(DefaultHeader())(config)
+}
+
+// NewEncoderWithHeader returns constructor of LEEF encoder with header, which could be registered with
+// rklogger.RegisterEncoder by another name.
+func NewEncoderWithHeader(header Header) rklogger.EncoderConstructor {
+	prefix := "LEEF:" + Version + "|" + headerEscaper.Replace(header.Vendor) + "|" +
+		headerEscaper.Replace(header.Product) + "|" + headerEscaper.Replace(header.Version) + "|"
+
+	return func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		lineEnding := config.LineEnding
+		if len(lineEnding) == 0 {
+			lineEnding = zapcore.DefaultLineEnding
+		}
+
+		// entries are encoded by LEEF, wrapped encoder encodes fields only
+		config.MessageKey, config.LevelKey, config.TimeKey = "", "", ""
+		config.NameKey, config.CallerKey, config.StacktraceKey = "", "", ""
+		config.FunctionKey = ""
+		if config.EncodeTime == nil {
+			config.EncodeTime = zapcore.ISO8601TimeEncoder
+		}
+		if config.EncodeDuration == nil {
+			config.EncodeDuration = zapcore.StringDurationEncoder
+		}
+
+		return &encoder{
+			Encoder:    zapcore.NewJSONEncoder(config),
+			header:     prefix,
+			lineEnding: lineEnding,
+		}, nil
+	}
+}
+
+// Clone implements zapcore.Encoder
+func (enc *encoder) Clone() zapcore.Encoder {
+	return &encoder{
+		Encoder:    enc.Encoder.Clone(),
+		header:     enc.header,
+		lineEnding: enc.lineEnding,
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *encoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := enc.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	values, err := extension.Decode(encoded.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	eventID := entry.Message
+	if id, ok := values[eventIDKey].(string); ok && len(id) > 0 {
+		eventID = id
+	}
+	delete(values, eventIDKey)
+	for _, key := range entryKeys {
+		delete(values, key)
+	}
+
+	buf := bufferPool.Get()
+	buf.AppendString(enc.header)
+	buf.AppendString(headerEscaper.Replace(eventID))
+	buf.AppendByte('|')
+
+	buf.AppendString("devTime=")
+	buf.AppendString(entry.Time.UTC().Format(devTimeLayout))
+	buf.AppendString("\tsev=")
+	buf.AppendInt(int64(severity(entry.Level)))
+	if len(entry.LoggerName) > 0 {
+		appendAttribute(buf, "cat", entry.LoggerName)
+	}
+	appendAttribute(buf, "msg", entry.Message)
+	if entry.Caller.Defined {
+		appendAttribute(buf, "caller", entry.Caller.TrimmedPath())
+	}
+	for _, pair := range extension.Flatten(values) {
+		appendAttribute(buf, pair.Key, pair.Value)
+	}
+	if len(entry.Stack) > 0 {
+		appendAttribute(buf, "stacktrace", entry.Stack)
+	}
+
+	buf.AppendString(enc.lineEnding)
+	return buf, nil
+}
+
+// Append attribute, attributes are separated by tab
+func appendAttribute(buf *buffer.Buffer, key, value string) {
+	buf.AppendByte('\t')
+	buf.AppendString(key)
+	buf.AppendByte('=')
+	buf.AppendString(attributeEscaper.Replace(value))
+}
+
+// Severity of LEEF from 1 to 10
+func severity(level zapcore.Level) int {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return 1
+	case level == zapcore.InfoLevel:
+		return 3
+	case level == zapcore.WarnLevel:
+		return 6
+	case level == zapcore.ErrorLevel:
+		return 8
+	case level < zapcore.FatalLevel:
+		return 9
+	default:
+		return 10
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package leef
+
+import (
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Encode entry with encoder
+func encode(t *testing.T, enc zapcore.Encoder, entry zapcore.Entry, fields ...zapcore.Field) string {
+	buf, err := enc.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	defer buf.Free()
+	return buf.String()
+}
+
+func TestEncoder_EncodeEntry(t *testing.T) {
+	enc, err := NewEncoderWithHeader(Header{Vendor: "ut|vendor", Product: "ut-product", Version: "2.0"})(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	entry := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       time.Date(2020, 9, 1, 18, 0, 0, 123456789, time.FixedZone("ut", 8*3600)),
+		LoggerName: "auth",
+		Message:    "login failed",
+		Caller:     zapcore.NewEntryCaller(0, "/src/auth/login.go", 42, true),
+		Stack:      "ut-stack\nline",
+	}
+
+	assert.Equal(t, "LEEF:1.0|ut\\|vendor|ut-product|2.0|100|devTime=Sep 01 2020 10:00:00.123 UTC\tsev=8\tcat=auth"+
+		"\tmsg=login failed\tcaller=auth/login.go:42\tquery=a\\tb\tsrc=10.0.0.1\tusrName=admin\tstacktrace=ut-stack\\nline\n",
+		encode(t, enc, entry,
+			zap.String("usrName", "admin"),
+			zap.String("src", "10.0.0.1"),
+			zap.String("eventId", "100"),
+			zap.String("query", "a\tb"),
+			zap.String("sev", "ut-overwritten"),
+		))
+}
+
+// Event id is message if eventId field is missing
+func TestEncoder_WithoutEventID(t *testing.T) {
+	enc, err := NewEncoder(zapcore.EncoderConfig{LineEnding: "\r\n"})
+	assert.Nil(t, err)
+
+	res := encode(t, enc.Clone(), zapcore.Entry{Message: "ut-message", Time: time.Unix(1, 0)})
+	assert.Equal(t, "LEEF:1.0|rookie-ninja|"+DefaultHeader().Product+"|1.0|ut-message|"+
+		"devTime=Jan 01 1970 00:00:01.000 UTC\tsev=3\tmsg=ut-message\r\n", res)
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, 1, severity(zapcore.DebugLevel))
+	assert.Equal(t, 3, severity(zapcore.InfoLevel))
+	assert.Equal(t, 6, severity(zapcore.WarnLevel))
+	assert.Equal(t, 8, severity(zapcore.ErrorLevel))
+	assert.Equal(t, 9, severity(zapcore.PanicLevel))
+	assert.Equal(t, 10, severity(zapcore.FatalLevel))
+}
+
+// Encoding is registered with rklogger
+func TestRegister(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "leef")
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "ut.log")
+
+	raw := []byte(fmt.Sprintf(`---
+level: info
+encoding: leef
+outputPaths: ["%s"]
+`, filePath))
+
+	logger, _, err := rklogger.NewZapLoggerWithBytes(raw, rklogger.YAML)
+	assert.Nil(t, err)
+	logger.Info("ut-message", zap.String("usrName", "ut"))
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "LEEF:1.0|rookie-ninja|"))
+	assert.Contains(t, string(content), "|ut-message|devTime=")
+	assert.Contains(t, string(content), "\tusrName=ut")
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultLevelRegistry is used by package level functions and Loaders without WithLevelRegistry
+var defaultLevelRegistry = NewLevelRegistry()
+
+// LevelRegistry keeps zap.AtomicLevel and effective config of constructed loggers keyed by logger name,
+// so that verbosity of a live process could be changed and inspected at runtime.
+//
+// Loggers constructed with a single config are registered with Config.Name, or DefaultLoggerName if it is empty,
+// loggers constructed with logger map are registered with their names.
+type LevelRegistry struct {
+	entries map[string]*levelEntry
+	mutex   sync.RWMutex
+}
+
+// Level and config of registered logger, config is nil if only level is registered
+type levelEntry struct {
+	level  zap.AtomicLevel
+	config *Config
+	// pending revert of level set with SetLevelFor
+	expiry *levelExpiry
+}
+
+// Revert of temporary level after timer fired
+type levelExpiry struct {
+	timer    *time.Timer
+	original zapcore.Level
+}
+
+// Cancel pending revert of level
+func (entry *levelEntry) cancelExpiry() {
+	if entry.expiry != nil {
+		entry.expiry.timer.Stop()
+		entry.expiry = nil
+	}
+}
+
+// NewLevelRegistry creates an empty LevelRegistry.
+func NewLevelRegistry() *LevelRegistry {
+	return &LevelRegistry{
+		entries: make(map[string]*levelEntry),
+	}
+}
+
+// Register level with logger name, previous level with the same name would be replaced.
+func (registry *LevelRegistry) Register(name string, level zap.AtomicLevel) {
+	registry.register(name, &levelEntry{level: level})
+}
+
+// RegisterConfig registers level of zap config and config itself with logger name.
+func (registry *LevelRegistry) RegisterConfig(name string, config *Config) {
+	if config == nil || config.Zap == nil {
+		return
+	}
+
+	registry.register(name, &levelEntry{level: config.Zap.Level, config: config})
+}
+
+// Register entry with logger name if level is not empty
+func (registry *LevelRegistry) register(name string, entry *levelEntry) {
+	if entry.level == (zap.AtomicLevel{}) {
+		return
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if prev, ok := registry.entries[name]; ok {
+		prev.cancelExpiry()
+	}
+
+	registry.entries[name] = entry
+}
+
+// SetLevel changes level of logger with name, loggers derived from it are affected as well.
+// Pending revert of level set with SetLevelFor is cancelled.
+func (registry *LevelRegistry) SetLevel(name string, level zapcore.Level) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	entry, ok := registry.entries[name]
+	if !ok {
+		return errors.Errorf("logger is not registered, name:%s", name)
+	}
+
+	entry.cancelExpiry()
+	entry.level.SetLevel(level)
+	return nil
+}
+
+// SetLevelFor changes level of logger with name and reverts it after duration.
+// Calling it again before reverting extends duration and keeps the level before the first call as the one to revert to.
+func (registry *LevelRegistry) SetLevelFor(name string, level zapcore.Level, duration time.Duration) error {
+	if duration <= 0 {
+		return errors.Errorf("duration of level should be positive, duration:%s", duration)
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	entry, ok := registry.entries[name]
+	if !ok {
+		return errors.Errorf("logger is not registered, name:%s", name)
+	}
+
+	original := entry.level.Level()
+	if entry.expiry != nil {
+		original = entry.expiry.original
+		entry.cancelExpiry()
+	}
+
+	expiry := &levelExpiry{original: original}
+	expiry.timer = time.AfterFunc(duration, func() {
+		registry.revert(entry, expiry)
+	})
+	entry.expiry = expiry
+	entry.level.SetLevel(level)
+
+	return nil
+}
+
+// Revert level of entry if expiry is still pending
+func (registry *LevelRegistry) revert(entry *levelEntry, expiry *levelExpiry) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	// level was set again or entry was replaced after timer fired
+	if entry.expiry != expiry {
+		return
+	}
+
+	entry.expiry = nil
+	entry.level.SetLevel(expiry.original)
+}
+
+// GetLevel returns current level of logger with name.
+func (registry *LevelRegistry) GetLevel(name string) (zapcore.Level, error) {
+	atomicLevel, err := registry.AtomicLevel(name)
+	if err != nil {
+		return zapcore.InfoLevel, err
+	}
+
+	return atomicLevel.Level(), nil
+}
+
+// AtomicLevel returns zap.AtomicLevel of logger with name.
+func (registry *LevelRegistry) AtomicLevel(name string) (zap.AtomicLevel, error) {
+	entry, err := registry.entry(name)
+	if err != nil {
+		return zap.AtomicLevel{}, err
+	}
+
+	return entry.level, nil
+}
+
+// Config returns effective config of logger with name, level of zap config is the current level.
+func (registry *LevelRegistry) Config(name string) (*Config, error) {
+	entry, err := registry.entry(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.config == nil {
+		return nil, errors.Errorf("config of logger is not registered, name:%s", name)
+	}
+
+	return entry.config, nil
+}
+
+// Get entry of logger with name
+func (registry *LevelRegistry) entry(name string) (*levelEntry, error) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	entry, ok := registry.entries[name]
+	if !ok {
+		return nil, errors.Errorf("logger is not registered, name:%s", name)
+	}
+
+	return entry, nil
+}
+
+// Names returns names of registered loggers in ascending order.
+func (registry *LevelRegistry) Names() []string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	res := make([]string, 0, len(registry.entries))
+	for name := range registry.entries {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// DefaultLevelRegistry returns level registry used by package level functions and Loaders without WithLevelRegistry
+func DefaultLevelRegistry() *LevelRegistry {
+	return defaultLevelRegistry
+}
+
+// RegisterLevel registers level with logger name in default level registry
+func RegisterLevel(name string, level zap.AtomicLevel) {
+	defaultLevelRegistry.Register(name, level)
+}
+
+// SetLevel changes level of logger with name in default level registry
+func SetLevel(name string, level zapcore.Level) error {
+	return defaultLevelRegistry.SetLevel(name, level)
+}
+
+// SetLevelFor changes level of logger with name in default level registry and reverts it after duration
+func SetLevelFor(name string, level zapcore.Level, duration time.Duration) error {
+	return defaultLevelRegistry.SetLevelFor(name, level, duration)
+}
+
+// GetLevel returns level of logger with name in default level registry
+func GetLevel(name string) (zapcore.Level, error) {
+	return defaultLevelRegistry.GetLevel(name)
+}
+
+// LoggerNames returns names of loggers registered in default level registry
+func LoggerNames() []string {
+	return defaultLevelRegistry.Names()
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Query parameter of logger name in requests of level handler
+const levelHandlerNameParam = "name"
+
+// LoggerLevel is a logger name with its current level in response of level handler.
+type LoggerLevel struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+}
+
+// levelHandler serves levels of loggers registered in LevelRegistry
+type levelHandler struct {
+	registry *LevelRegistry
+}
+
+// NewLevelHandler creates http.Handler of levels in registry which could be mounted under path like /admin/log.
+//
+// GET without name lists registered loggers:
+//
+//	GET /admin/log => {"loggers": [{"name": "default", "level": "info"}]}
+//
+// Requests with name get or set level of the logger, levels are parsed and rendered with registered names like
+// trace, and setting level cancels pending revert of SetLevelFor:
+//
+//	GET /admin/log?name=db => {"level": "info"}
+//	PUT /admin/log?name=db with body {"level": "debug"} => {"level": "debug"}
+func NewLevelHandler(registry *LevelRegistry) http.Handler {
+	return &levelHandler{registry: registry}
+}
+
+// LevelHandler creates http.Handler of levels in default level registry, see NewLevelHandler for details.
+func LevelHandler() http.Handler {
+	return NewLevelHandler(defaultLevelRegistry)
+}
+
+// ServeHTTP implements http.Handler
+func (handler *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get(levelHandlerNameParam)
+	if len(name) > 0 {
+		handler.serveLevel(w, r, name)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeLevelHandlerError(w, http.StatusMethodNotAllowed, fmt.Sprintf("only GET is supported without logger name, method:%s", r.Method))
+		return
+	}
+
+	loggers := make([]LoggerLevel, 0)
+	for _, name := range handler.registry.Names() {
+		if level, err := handler.registry.GetLevel(name); err == nil {
+			loggers = append(loggers, LoggerLevel{Name: name, Level: LevelName(level)})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"loggers": loggers})
+}
+
+// Get or set level of logger with name
+func (handler *levelHandler) serveLevel(w http.ResponseWriter, r *http.Request, name string) {
+	level, err := handler.registry.GetLevel(name)
+	if err != nil {
+		writeLevelHandlerError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Level *string `json:"level"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeLevelHandlerError(w, http.StatusBadRequest, fmt.Sprintf("invalid body of request, err:%v", err))
+			return
+		}
+
+		if req.Level == nil {
+			writeLevelHandlerError(w, http.StatusBadRequest, "level is missing in body of request")
+			return
+		}
+
+		if level, err = ParseLevel(*req.Level); err != nil {
+			writeLevelHandlerError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := handler.registry.SetLevel(name, level); err != nil {
+			writeLevelHandlerError(w, http.StatusNotFound, err.Error())
+			return
+		}
+	default:
+		writeLevelHandlerError(w, http.StatusMethodNotAllowed, fmt.Sprintf("only GET and PUT are supported, method:%s", r.Method))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"level": LevelName(level)})
+}
+
+// Write error in the same format as zap.AtomicLevel
+func writeLevelHandlerError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
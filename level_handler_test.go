@@ -0,0 +1,131 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Serve request with level handler of registry
+func serveLevelHandler(registry *LevelRegistry, method, target, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	NewLevelHandler(registry).ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return recorder
+}
+
+func TestLevelHandler_List(t *testing.T) {
+	registry := NewLevelRegistry()
+	registry.Register("db", zap.NewAtomicLevelAt(zap.WarnLevel))
+	registry.Register("app", zap.NewAtomicLevelAt(zap.DebugLevel))
+
+	recorder := serveLevelHandler(registry, http.MethodGet, "/admin/log", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"loggers": [{"name": "app", "level": "debug"}, {"name": "db", "level": "warn"}]}`, recorder.Body.String())
+}
+
+// With empty registry
+func TestLevelHandler_ListWithoutLoggers(t *testing.T) {
+	recorder := serveLevelHandler(NewLevelRegistry(), http.MethodGet, "/admin/log", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"loggers": []}`, recorder.Body.String())
+}
+
+// With method other than GET and without name
+func TestLevelHandler_ListWithInvalidMethod(t *testing.T) {
+	recorder := serveLevelHandler(NewLevelRegistry(), http.MethodPut, "/admin/log", `{"level": "debug"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "error")
+}
+
+func TestLevelHandler_GetAndPut(t *testing.T) {
+	registry := NewLevelRegistry()
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	registry.Register("db", level)
+
+	recorder := serveLevelHandler(registry, http.MethodGet, "/admin/log?name=db", "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"level": "info"}`, recorder.Body.String())
+
+	recorder = serveLevelHandler(registry, http.MethodPut, "/admin/log?name=db", `{"level": "debug"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"level": "debug"}`, recorder.Body.String())
+	assert.Equal(t, zap.DebugLevel, level.Level())
+}
+
+// With logger which is not registered
+func TestLevelHandler_WithUnknownName(t *testing.T) {
+	recorder := serveLevelHandler(NewLevelRegistry(), http.MethodGet, "/admin/log?name=db", "")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "logger is not registered")
+}
+
+// With invalid level in body
+func TestLevelHandler_WithInvalidLevel(t *testing.T) {
+	registry := NewLevelRegistry()
+	registry.Register("db", zap.NewAtomicLevelAt(zap.InfoLevel))
+
+	recorder := serveLevelHandler(registry, http.MethodPut, "/admin/log?name=db", `{"level": "invalid"}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// without level
+	recorder = serveLevelHandler(registry, http.MethodPut, "/admin/log?name=db", `{}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// with invalid body
+	recorder = serveLevelHandler(registry, http.MethodPut, "/admin/log?name=db", `level=debug`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// with invalid method
+	recorder = serveLevelHandler(registry, http.MethodPost, "/admin/log?name=db", `{"level": "debug"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+// Levels are parsed and rendered with registered names
+func TestLevelHandler_WithLevelNames(t *testing.T) {
+	registry := NewLevelRegistry()
+	level := zap.NewAtomicLevelAt(TraceLevel)
+	registry.Register("db", level)
+
+	recorder := serveLevelHandler(registry, http.MethodGet, "/admin/log?name=db", "")
+	assert.JSONEq(t, `{"level": "trace"}`, recorder.Body.String())
+
+	recorder = serveLevelHandler(registry, http.MethodPut, "/admin/log?name=db", `{"level": "info"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = serveLevelHandler(registry, http.MethodPut, "/admin/log?name=db", `{"level": "TRACE"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"level": "trace"}`, recorder.Body.String())
+	assert.Equal(t, TraceLevel, level.Level())
+}
+
+// Level set through handler cancels pending revert of SetLevelFor
+func TestLevelHandler_WithPendingExpiry(t *testing.T) {
+	registry := NewLevelRegistry()
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	registry.Register("db", level)
+
+	assert.Nil(t, registry.SetLevelFor("db", zap.DebugLevel, 50*time.Millisecond))
+	recorder := serveLevelHandler(registry, http.MethodPut, "/admin/log?name=db", `{"level": "error"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, zap.ErrorLevel, level.Level())
+}
+
+func TestLevelHandler_WithDefaultRegistry(t *testing.T) {
+	RegisterLevel("ut-handler", zap.NewAtomicLevelAt(zap.InfoLevel))
+
+	recorder := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/log", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "ut-handler")
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TraceLevel logs are more verbose than debug logs, which are usually disabled even in development.
+// Use Trace() to log at TraceLevel since zap.Logger has no method of it.
+const TraceLevel = zapcore.DebugLevel - 1
+
+// Names of levels which are registered by default
+const (
+	// TraceLevelName is the name of TraceLevel.
+	TraceLevelName = "trace"
+	// NoticeLevelName is an alias of zapcore.InfoLevel rather than a level of its own, since zap levels are
+	// consecutive and there is no value between InfoLevel and WarnLevel. Level configured as notice is exactly
+	// info level, which enables info logs as well, and entries are encoded as info.
+	NoticeLevelName = "notice"
+)
+
+// levelNameRegistry keeps names of levels which are not known by zapcore.Level
+type levelNameRegistry struct {
+	// levels keyed by lowercase names and aliases
+	levels map[string]zapcore.Level
+	// names of levels which are used by encoders
+	names map[zapcore.Level]string
+	mutex sync.RWMutex
+}
+
+// defaultLevelNames is used by ParseLevel and LevelName
+var defaultLevelNames = &levelNameRegistry{
+	levels: map[string]zapcore.Level{
+		TraceLevelName:  TraceLevel,
+		NoticeLevelName: zapcore.InfoLevel,
+	},
+	names: map[zapcore.Level]string{
+		TraceLevel: TraceLevelName,
+	},
+}
+
+// RegisterLevelName registers name of level which is parsed by ParseLevel and config files. Name of level which
+// has no name yet, like zapcore.DebugLevel-2, is used by level encoders as well, otherwise the name is an alias.
+// Names are case insensitive, and could not be registered with different levels.
+func RegisterLevelName(name string, level zapcore.Level) error {
+	key := strings.ToLower(name)
+	if len(key) == 0 {
+		return errors.New("name of level is empty")
+	}
+
+	var known zapcore.Level
+	if err := known.UnmarshalText([]byte(key)); err == nil && known != level {
+		return errors.Errorf("name of level is registered by zap, name:%s", name)
+	}
+
+	defaultLevelNames.mutex.Lock()
+	defer defaultLevelNames.mutex.Unlock()
+
+	if prev, ok := defaultLevelNames.levels[key]; ok && prev != level {
+		return errors.Errorf("name of level is already registered, name:%s, level:%s", name, defaultLevelNames.name(prev))
+	}
+
+	defaultLevelNames.levels[key] = level
+	if _, ok := defaultLevelNames.names[level]; !ok && !isZapLevel(level) {
+		defaultLevelNames.names[level] = key
+	}
+
+	return nil
+}
+
+// ParseLevel parses level with names of zap and registered names, case insensitive, like TRACE or notice.
+// Aliases are parsed as levels they refer to, like notice is parsed as zapcore.InfoLevel.
+func ParseLevel(text string) (zapcore.Level, error) {
+	defaultLevelNames.mutex.RLock()
+	level, ok := defaultLevelNames.levels[strings.ToLower(text)]
+	defaultLevelNames.mutex.RUnlock()
+	if ok {
+		return level, nil
+	}
+
+	if err := level.UnmarshalText([]byte(text)); err != nil {
+		return level, errors.Errorf("unrecognized level, level:%s", text)
+	}
+
+	return level, nil
+}
+
+// LevelName returns lowercase name of level, registered names are used for levels which are not known by zap.
+func LevelName(level zapcore.Level) string {
+	defaultLevelNames.mutex.RLock()
+	defer defaultLevelNames.mutex.RUnlock()
+
+	return defaultLevelNames.name(level)
+}
+
+// Name of level, lock should be held by caller
+func (registry *levelNameRegistry) name(level zapcore.Level) string {
+	if name, ok := registry.names[level]; ok {
+		return name
+	}
+
+	return level.String()
+}
+
+// Copy names of levels which are used by encoders
+func (registry *levelNameRegistry) copyNames() map[zapcore.Level]string {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	res := make(map[zapcore.Level]string, len(registry.names))
+	for level, name := range registry.names {
+		res[level] = name
+	}
+
+	return res
+}
+
+// Whether level is one of levels defined by zap
+func isZapLevel(level zapcore.Level) bool {
+	return level >= zapcore.DebugLevel && level <= zapcore.FatalLevel
+}
+
+// Trace logs a message at TraceLevel, caller of Trace() is annotated as caller of entry.
+func Trace(logger *zap.Logger, msg string, fields ...zap.Field) {
+	if !logger.Core().Enabled(TraceLevel) {
+		return
+	}
+
+	if ce := logger.WithOptions(zap.AddCallerSkip(1)).Check(TraceLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// Create level encoder which encodes levels with names, names are keyed by names of levels, like warn: WARNING,
+// registered names are used for levels without names. Names are upper-cased by capital encoders of zap and
+// colorized by color encoders of zap, levels without names are encoded by encoder.
+func newLevelNameEncoder(encoder zapcore.LevelEncoder, names map[string]string) (zapcore.LevelEncoder, error) {
+	if encoder == nil {
+		return nil, nil
+	}
+
+	levelNames := defaultLevelNames.copyNames()
+	for key, name := range names {
+		level, err := ParseLevel(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid level of level names, level:%s", key)
+		}
+		levelNames[level] = name
+	}
+
+	if len(levelNames) == 0 {
+		return encoder, nil
+	}
+
+	capital, color := false, false
+	switch reflect.ValueOf(encoder).Pointer() {
+	case reflect.ValueOf(zapcore.CapitalLevelEncoder).Pointer():
+		capital = true
+	case reflect.ValueOf(zapcore.CapitalColorLevelEncoder).Pointer():
+		capital, color = true, true
+	case reflect.ValueOf(zapcore.LowercaseColorLevelEncoder).Pointer():
+		color = true
+	}
+
+	return func(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		name, ok := levelNames[level]
+		if !ok {
+			encoder(level, enc)
+			return
+		}
+
+		if capital {
+			name = strings.ToUpper(name)
+		}
+
+		if color {
+			name = levelColor(level) + name + colorReset
+		}
+
+		enc.AppendString(name)
+	}, nil
+}
+
+// Parse levels of config with names of levels in config, names are keyed by names of levels, like warn: WARNING
+func parseLevelWithNames(text string, names map[string]string) (zapcore.Level, error) {
+	for key, name := range names {
+		if strings.EqualFold(name, text) {
+			return ParseLevel(key)
+		}
+	}
+
+	return ParseLevel(text)
+}
+
+// levelPatch replaces levels of config file which zapcore.Level could not parse, like trace, with info in decoded
+// content, so that content could be unmarshalled into zap config, parsed levels are set by apply() afterwards.
+type levelPatch struct {
+	content  map[string]interface{}
+	zapLevel *zapcore.Level
+	levels   map[string]zapcore.Level
+}
+
+// Create levelPatch with content of config file, nil if every level could be parsed by zapcore.Level
+func newLevelPatch(raw []byte, fileType FileType) (*levelPatch, error) {
+	content, err := decodeWithFileType(raw, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	if section, ok := content["levelNames"].(map[string]interface{}); ok {
+		for key, value := range section {
+			if name, ok := value.(string); ok {
+				names[key] = name
+			}
+		}
+	}
+
+	patch := &levelPatch{
+		content: content,
+		levels:  make(map[string]zapcore.Level),
+	}
+
+	// level is in zap section of combined config and at top level of legacy config
+	section := content
+	if zapSection, ok := content["zap"].(map[string]interface{}); ok {
+		section = zapSection
+	}
+	if patch.zapLevel, err = patchLevel(section, "level", names); err != nil {
+		return nil, err
+	}
+
+	if levels, ok := content["levels"].(map[string]interface{}); ok {
+		for name := range levels {
+			level, err := patchLevel(levels, name, names)
+			if err != nil {
+				return nil, err
+			}
+
+			if level != nil {
+				patch.levels[name] = *level
+			}
+		}
+	}
+
+	if patch.zapLevel == nil && len(patch.levels) == 0 {
+		return nil, nil
+	}
+
+	return patch, nil
+}
+
+// Replace level of key in section with info if zapcore.Level could not parse it, the parsed level is returned
+func patchLevel(section map[string]interface{}, key string, names map[string]string) (*zapcore.Level, error) {
+	text, ok := section[key].(string)
+	if !ok {
+		return nil, nil
+	}
+
+	var known zapcore.Level
+	if err := known.UnmarshalText([]byte(text)); err == nil {
+		return nil, nil
+	}
+
+	level, err := parseLevelWithNames(text, names)
+	if err != nil {
+		return nil, err
+	}
+
+	section[key] = zapcore.InfoLevel.String()
+	return &level, nil
+}
+
+// Unmarshal content of config file into target, patched content is unmarshalled if patch exists
+func unmarshalWithLevelPatch(raw []byte, fileType FileType, patch *levelPatch, target interface{}) error {
+	if patch == nil {
+		return unmarshalWithFileType(raw, fileType, target)
+	}
+
+	return unmarshalMapWithJSON(patch.content, target)
+}
+
+// Set parsed levels to zap config and levels of names
+func (patch *levelPatch) apply(zapConfig *zap.Config, levels map[string]zapcore.Level) {
+	if patch.zapLevel != nil && zapConfig != nil {
+		if zapConfig.Level == (zap.AtomicLevel{}) {
+			zapConfig.Level = zap.NewAtomicLevel()
+		}
+		zapConfig.Level.SetLevel(*patch.zapLevel)
+	}
+
+	for name, level := range patch.levels {
+		levels[name] = level
+	}
+}
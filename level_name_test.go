@@ -0,0 +1,167 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for text, expected := range map[string]zapcore.Level{
+		"trace":  TraceLevel,
+		"TRACE":  TraceLevel,
+		"debug":  zapcore.DebugLevel,
+		"notice": zapcore.InfoLevel,
+		"WARN":   zapcore.WarnLevel,
+		"fatal":  zapcore.FatalLevel,
+	} {
+		level, err := ParseLevel(text)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, level, text)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.NotNil(t, err)
+}
+
+func TestRegisterLevelName(t *testing.T) {
+	finest := zapcore.DebugLevel - 2
+	defer func() {
+		defaultLevelNames.mutex.Lock()
+		delete(defaultLevelNames.levels, "finest")
+		delete(defaultLevelNames.levels, "verbose")
+		delete(defaultLevelNames.names, finest)
+		defaultLevelNames.mutex.Unlock()
+	}()
+
+	assert.Nil(t, RegisterLevelName("FINEST", finest))
+	assert.Nil(t, RegisterLevelName("verbose", finest))
+
+	// the first name is the name of level
+	level, err := ParseLevel("verbose")
+	assert.Nil(t, err)
+	assert.Equal(t, finest, level)
+	assert.Equal(t, "finest", LevelName(finest))
+
+	// alias of zap level does not rename it, notice is info and could not be registered as another level
+	assert.Nil(t, RegisterLevelName("notice", zapcore.InfoLevel))
+	assert.NotNil(t, RegisterLevelName("notice", zapcore.WarnLevel))
+	assert.Equal(t, "info", LevelName(zapcore.InfoLevel))
+	assert.Equal(t, "trace", LevelName(TraceLevel))
+
+	// With registered names
+	assert.NotNil(t, RegisterLevelName("", finest))
+	assert.NotNil(t, RegisterLevelName("warn", finest))
+	assert.NotNil(t, RegisterLevelName("trace", finest))
+}
+
+func TestNewLevelNameEncoder(t *testing.T) {
+	encode := func(encoder zapcore.LevelEncoder, level zapcore.Level) string {
+		enc := zapcore.NewMapObjectEncoder()
+		enc.AddArray("level", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			encoder(level, arr)
+			return nil
+		}))
+		return enc.Fields["level"].([]interface{})[0].(string)
+	}
+
+	// registered names are used without level names
+	encoder, err := newLevelNameEncoder(zapcore.CapitalLevelEncoder, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "TRACE", encode(encoder, TraceLevel))
+	assert.Equal(t, "INFO", encode(encoder, zapcore.InfoLevel))
+
+	// With level names
+	encoder, err = newLevelNameEncoder(zapcore.LowercaseLevelEncoder, map[string]string{"warn": "warning"})
+	assert.Nil(t, err)
+	assert.Equal(t, "warning", encode(encoder, zapcore.WarnLevel))
+	assert.Equal(t, "trace", encode(encoder, TraceLevel))
+
+	// With color level encoder
+	encoder, err = newLevelNameEncoder(zapcore.CapitalColorLevelEncoder, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, colorMagenta+"TRACE"+colorReset, encode(encoder, TraceLevel))
+
+	// With invalid level
+	encoder, err = newLevelNameEncoder(zapcore.CapitalLevelEncoder, map[string]string{"verbose": "VERBOSE"})
+	assert.Nil(t, encoder)
+	assert.NotNil(t, err)
+
+	// Without level encoder
+	encoder, err = newLevelNameEncoder(nil, nil)
+	assert.Nil(t, encoder)
+	assert.Nil(t, err)
+}
+
+func TestLevelNames_WithConfigFile(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	raw := []byte(`---
+zap:
+  level: trace
+  encoding: json
+  encoderConfig:
+    levelKey: level
+    messageKey: msg
+    callerKey: caller
+    levelEncoder: capital
+    callerEncoder: short
+  outputPaths: ["` + filePath + `"]
+levels:
+  db: warning
+levelNames:
+  warn: WARNING
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, TraceLevel, config.Zap.Level.Level())
+	assert.Equal(t, zapcore.WarnLevel, config.Levels["db"])
+	assert.Equal(t, "capital", marshalZapLevelEncoder(config.Zap.EncoderConfig.EncodeLevel))
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	Trace(logger, "trace-message")
+	logger.Warn("warn-message")
+	logger.Named("db").Info("dropped")
+	logger.Sync()
+
+	lines := strings.Split(strings.TrimSpace(readFileContent(filePath)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"level":"TRACE"`)
+	assert.Contains(t, lines[0], `/level_name_test.go:`)
+	assert.Contains(t, lines[1], `"level":"WARNING"`)
+
+	// With invalid level
+	_, err = NewConfigWithBytes([]byte("zap:\n  level: verbose\n"), YAML)
+	assert.NotNil(t, err)
+}
+
+func TestTrace(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	logger, err := NewZapLoggerWithConf(&zap.Config{
+		Level:    zap.NewAtomicLevelAt(zapcore.DebugLevel),
+		Encoding: "json",
+		EncoderConfig: zapcore.EncoderConfig{
+			LevelKey:    "level",
+			MessageKey:  "msg",
+			EncodeLevel: zapcore.LowercaseLevelEncoder,
+		},
+		OutputPaths: []string{filePath},
+	}, nil)
+	assert.Nil(t, err)
+
+	// disabled by debug level
+	Trace(logger, "dropped")
+	logger.Debug("debug-message")
+	logger.Sync()
+	assert.Equal(t, `{"level":"debug","msg":"debug-message"}`+"\n", readFileContent(filePath))
+}
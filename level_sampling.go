@@ -0,0 +1,242 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Names of presets which could be used by LevelSamplingConfig.Preset
+const (
+	// SamplingPresetProduction samples debug and info levels the same way as production config of zap,
+	// the first 100 entries with the same message per second are logged and every 100th entry afterwards.
+	SamplingPresetProduction = "production"
+	// SamplingPresetHighTraffic samples debug level at 1/1000 after 10 entries, info level at 1/100 after 50 entries
+	// and warn level at 1/10 after 100 entries with the same message per second.
+	SamplingPresetHighTraffic = "highTraffic"
+)
+
+// DefaultSamplingTick is the default interval of counting entries by LevelSamplingConfig.
+const DefaultSamplingTick = time.Second
+
+// levels sampled by presets keyed by names of presets
+var samplingPresets = map[string]map[zapcore.Level]LevelSampling{
+	SamplingPresetProduction: {
+		zapcore.DebugLevel: {Initial: 100, Thereafter: 100},
+		zapcore.InfoLevel:  {Initial: 100, Thereafter: 100},
+	},
+	SamplingPresetHighTraffic: {
+		zapcore.DebugLevel: {Initial: 10, Thereafter: 1000},
+		zapcore.InfoLevel:  {Initial: 50, Thereafter: 100},
+		zapcore.WarnLevel:  {Initial: 100, Thereafter: 10},
+	},
+}
+
+// LevelSamplingConfig samples entries of every level with its own rate, while sampling of zap config samples all of
+// levels with the same rate. Entries with the same level and message are counted every tick, the first Initial
+// entries are logged and every Thereafter entry is logged afterwards. Levels which are neither sampled by preset
+// nor declared by Levels are never sampled, like error level.
+//
+// Example config file in YAML:
+//
+//	levelSampling:
+//	  preset: production
+//	  tick: 1s
+//	  levels:
+//	    info:
+//	      initial: 50
+//	      thereafter: 100
+//	    debug:
+//	      initial: 1
+//	      thereafter: 0
+type LevelSamplingConfig struct {
+	// Preset is production or highTraffic, levels sampled by preset are overridden by Levels.
+	Preset string `json:"preset,omitempty" yaml:"preset,omitempty"`
+	// Tick is the interval of counting entries, like 1s, DefaultSamplingTick if not provided.
+	Tick string `json:"tick,omitempty" yaml:"tick,omitempty"`
+	// Levels are sampling rates keyed by level names, including names of custom levels.
+	Levels map[string]LevelSampling `json:"levels,omitempty" yaml:"levels,omitempty"`
+}
+
+// LevelSampling is sampling rate of level.
+type LevelSampling struct {
+	// Initial is the number of entries with the same message logged every tick before sampling.
+	Initial int `json:"initial" yaml:"initial"`
+	// Thereafter is the rate of entries logged after Initial entries, 0 drops all of them and 1 logs all of them,
+	// which disables sampling of level declared by preset.
+	Thereafter int `json:"thereafter" yaml:"thereafter"`
+}
+
+// number of counters of every sampled level, entries are counted by hash of messages
+const levelSamplerCounters = 4096
+
+// levelSampler counts entries of sampled levels
+type levelSampler struct {
+	tick   time.Duration
+	levels map[zapcore.Level]*sampledLevel
+}
+
+// sampledLevel is sampling rate and counters of level
+type sampledLevel struct {
+	LevelSampling
+	counters [levelSamplerCounters]samplingCounter
+}
+
+// samplingCounter counts entries until resetAt, which is the same as counter of zap sampler
+type samplingCounter struct {
+	resetAt int64
+	count   uint64
+}
+
+// Create sampler with config, nil is returned if config is nil
+func newLevelSampler(config *LevelSamplingConfig) (*levelSampler, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	res := &levelSampler{
+		tick:   DefaultSamplingTick,
+		levels: make(map[zapcore.Level]*sampledLevel),
+	}
+
+	if len(config.Tick) > 0 {
+		tick, err := time.ParseDuration(config.Tick)
+		if err != nil || tick <= 0 {
+			return nil, errors.Errorf("invalid tick of level sampling, tick:%s", config.Tick)
+		}
+		res.tick = tick
+	}
+
+	if len(config.Preset) > 0 {
+		preset, ok := samplingPresets[config.Preset]
+		if !ok {
+			return nil, errors.Errorf("invalid preset of level sampling, preset:%s, presets:[%s]",
+				config.Preset, strings.Join(samplingPresetNames(), ", "))
+		}
+
+		for level, sampling := range preset {
+			res.levels[level] = &sampledLevel{LevelSampling: sampling}
+		}
+	}
+
+	for name, sampling := range config.Levels {
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid level of level sampling, level:%s", name)
+		}
+
+		if sampling.Initial < 0 || sampling.Thereafter < 0 {
+			return nil, errors.Errorf("initial and thereafter of level sampling should not be negative, level:%s", name)
+		}
+
+		// every entry is logged
+		if sampling.Thereafter == 1 {
+			delete(res.levels, level)
+			continue
+		}
+
+		res.levels[level] = &sampledLevel{LevelSampling: sampling}
+	}
+
+	return res, nil
+}
+
+// Names of sampling presets in ascending order
+func samplingPresetNames() []string {
+	res := make([]string, 0, len(samplingPresets))
+	for name := range samplingPresets {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// Wrap core with sampler, core is returned as it is if sampler is nil or no level is sampled
+func (sampler *levelSampler) wrap(core zapcore.Core) zapcore.Core {
+	if sampler == nil || len(sampler.levels) == 0 {
+		return core
+	}
+
+	return &levelSamplerCore{Core: core, sampler: sampler}
+}
+
+// Whether entry is sampled out
+func (sampler *levelSampler) drop(entry zapcore.Entry) bool {
+	level, ok := sampler.levels[entry.Level]
+	if !ok {
+		return false
+	}
+
+	counter := &level.counters[fnv32a(entry.Message)%levelSamplerCounters]
+	n := counter.inc(entry.Time, sampler.tick)
+	if n <= uint64(level.Initial) {
+		return false
+	}
+
+	return level.Thereafter == 0 || (n-uint64(level.Initial))%uint64(level.Thereafter) != 0
+}
+
+// Increase counter, it is reset if tick of time is passed
+func (counter *samplingCounter) inc(t time.Time, tick time.Duration) uint64 {
+	now := t.UnixNano()
+	resetAt := atomic.LoadInt64(&counter.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&counter.count, 1)
+	}
+
+	atomic.StoreUint64(&counter.count, 1)
+	if !atomic.CompareAndSwapInt64(&counter.resetAt, resetAt, now+tick.Nanoseconds()) {
+		// counter is reset by another goroutine
+		return atomic.AddUint64(&counter.count, 1)
+	}
+
+	return 1
+}
+
+// Hash message with FNV-32a without allocations
+func fnv32a(text string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+
+	hash := uint32(offset32)
+	for i := 0; i < len(text); i++ {
+		hash ^= uint32(text[i])
+		hash *= prime32
+	}
+
+	return hash
+}
+
+// levelSamplerCore drops entries sampled out by their levels
+type levelSamplerCore struct {
+	zapcore.Core
+	sampler *levelSampler
+}
+
+// With implements zapcore.Core, counters are shared with cores created by With()
+func (core *levelSamplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelSamplerCore{
+		Core:    core.Core.With(fields),
+		sampler: core.sampler,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *levelSamplerCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !core.Enabled(entry.Level) || core.sampler.drop(entry) {
+		return checked
+	}
+
+	return core.Core.Check(entry, checked)
+}
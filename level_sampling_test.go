@@ -0,0 +1,151 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewLevelSampler(t *testing.T) {
+	// nil config
+	sampler, err := newLevelSampler(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, sampler)
+
+	// levels override preset
+	sampler, err = newLevelSampler(&LevelSamplingConfig{
+		Preset: SamplingPresetHighTraffic,
+		Tick:   "10s",
+		Levels: map[string]LevelSampling{
+			"INFO":  {Initial: 1, Thereafter: 0},
+			"warn":  {Initial: 1, Thereafter: 1},
+			"error": {Initial: 5, Thereafter: 10},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, sampler.tick)
+	assert.Len(t, sampler.levels, 3)
+	assert.Equal(t, LevelSampling{Initial: 10, Thereafter: 1000}, sampler.levels[zapcore.DebugLevel].LevelSampling)
+	assert.Equal(t, LevelSampling{Initial: 1, Thereafter: 0}, sampler.levels[zapcore.InfoLevel].LevelSampling)
+	assert.Equal(t, LevelSampling{Initial: 5, Thereafter: 10}, sampler.levels[zapcore.ErrorLevel].LevelSampling)
+
+	// invalid configs
+	for _, config := range []*LevelSamplingConfig{
+		{Preset: "ut-preset"},
+		{Tick: "1x"},
+		{Tick: "-1s"},
+		{Levels: map[string]LevelSampling{"ut-level": {Initial: 1}}},
+		{Levels: map[string]LevelSampling{"info": {Initial: -1}}},
+	} {
+		_, err := newLevelSampler(config)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestLevelSamplerCore_Check(t *testing.T) {
+	sampler, err := newLevelSampler(&LevelSamplingConfig{
+		Levels: map[string]LevelSampling{
+			"info":  {Initial: 2, Thereafter: 3},
+			"debug": {Initial: 1, Thereafter: 0},
+		},
+	})
+	assert.Nil(t, err)
+
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := sampler.wrap(observed).With([]zapcore.Field{})
+
+	write := func(level zapcore.Level, msg string, now time.Time) {
+		entry := zapcore.Entry{Level: level, Message: msg, Time: now}
+		if checked := core.Check(entry, nil); checked != nil {
+			checked.Write()
+		}
+	}
+
+	now := time.Now()
+	for i := 0; i < 8; i++ {
+		write(zapcore.InfoLevel, "ut-info", now)
+		write(zapcore.DebugLevel, "ut-debug", now)
+		write(zapcore.ErrorLevel, "ut-error", now)
+	}
+	// counters are separated by messages
+	write(zapcore.InfoLevel, "ut-other", now)
+
+	// info: 1, 2, 5, 8; debug: 1; error is never sampled
+	counts := map[string]int{}
+	for _, entry := range logs.TakeAll() {
+		counts[entry.Message]++
+	}
+	assert.Equal(t, map[string]int{"ut-info": 4, "ut-debug": 1, "ut-error": 8, "ut-other": 1}, counts)
+
+	// counters are reset after tick
+	write(zapcore.DebugLevel, "ut-debug", now.Add(DefaultSamplingTick))
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestLevelSampler_Wrap(t *testing.T) {
+	observed, _ := observer.New(zapcore.DebugLevel)
+
+	// nil sampler
+	var sampler *levelSampler
+	assert.Equal(t, observed, sampler.wrap(observed))
+
+	// no sampled levels
+	sampler, err := newLevelSampler(&LevelSamplingConfig{
+		Levels: map[string]LevelSampling{"info": {Initial: 1, Thereafter: 1}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, observed, sampler.wrap(observed))
+}
+
+func TestLevelSampling_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+levelSampling:
+  preset: production
+  levels:
+    info:
+      initial: 1
+      thereafter: 0
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("ut-info")
+		logger.Error("ut-error")
+	}
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	content := readFileContent(path.Join(dir, "app.log"))
+	assert.Equal(t, 1, strings.Count(content, "ut-info"))
+	assert.Equal(t, 3, strings.Count(content, "ut-error"))
+
+	// invalid preset
+	config.LevelSampling.Preset = "ut-preset"
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestWithLevelSampling(t *testing.T) {
+	config := NewConfigWithOptions(WithLevelSampling(LevelSamplingConfig{Preset: SamplingPresetHighTraffic}))
+	assert.Equal(t, &LevelSamplingConfig{Preset: SamplingPresetHighTraffic}, config.LevelSampling)
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLevelRegistry_HappyCase(t *testing.T) {
+	registry := NewLevelRegistry()
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	registry.Register("ut", level)
+
+	res, err := registry.GetLevel("ut")
+	assert.Nil(t, err)
+	assert.Equal(t, zap.InfoLevel, res)
+
+	assert.Nil(t, registry.SetLevel("ut", zap.DebugLevel))
+	assert.Equal(t, zap.DebugLevel, level.Level())
+
+	atomicLevel, err := registry.AtomicLevel("ut")
+	assert.Nil(t, err)
+	assert.Equal(t, level, atomicLevel)
+}
+
+// With logger which is not registered
+func TestLevelRegistry_WithUnknownName(t *testing.T) {
+	registry := NewLevelRegistry()
+
+	_, err := registry.GetLevel("ut")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "logger is not registered")
+	assert.NotNil(t, registry.SetLevel("ut", zap.DebugLevel))
+}
+
+// Empty atomic level is ignored
+func TestLevelRegistry_WithEmptyLevel(t *testing.T) {
+	registry := NewLevelRegistry()
+	registry.Register("ut", zap.AtomicLevel{})
+	assert.Empty(t, registry.Names())
+}
+
+func TestLevelRegistry_Names(t *testing.T) {
+	registry := NewLevelRegistry()
+	registry.Register("db", zap.NewAtomicLevel())
+	registry.Register("app", zap.NewAtomicLevel())
+	registry.Register("db", zap.NewAtomicLevel())
+
+	assert.Equal(t, []string{"app", "db"}, registry.Names())
+}
+
+// Level of logger constructed with default loader is registered in default registry
+func TestSetLevel_WithDefaultLoader(t *testing.T) {
+	logger, _, err := NewZapLoggerWithBytes([]byte(`{"level": "info", "outputPaths": ["stdout"]}`), JSON)
+	assert.Nil(t, err)
+	assert.Contains(t, LoggerNames(), DefaultLoggerName)
+
+	assert.Nil(t, SetLevel(DefaultLoggerName, zap.DebugLevel))
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	level, err := GetLevel(DefaultLoggerName)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.DebugLevel, level)
+
+	RegisterLevel("ut", zap.NewAtomicLevel())
+	assert.Contains(t, LoggerNames(), "ut")
+}
+
+func TestNewZapLoggerWithConf_WithLevelRegistry(t *testing.T) {
+	registry := NewLevelRegistry()
+	loader := NewLoader(WithLevelRegistry(registry))
+
+	logger, err := loader.NewZapLoggerWithConf(NewZapStdoutConfig(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{DefaultLoggerName}, registry.Names())
+
+	assert.Nil(t, registry.SetLevel(DefaultLoggerName, zap.ErrorLevel))
+	assert.False(t, logger.Core().Enabled(zapcore.WarnLevel))
+
+	// with combined config
+	logger, err = loader.NewZapLoggerWithConfig(NewConfig(NewZapStdoutConfig(), nil))
+	assert.Nil(t, err)
+	assert.Nil(t, registry.SetLevel(DefaultLoggerName, zap.ErrorLevel))
+	assert.False(t, logger.Core().Enabled(zapcore.WarnLevel))
+}
+
+// Loggers with name of config are registered with the name
+func TestNewZapLoggerWithConfig_WithName(t *testing.T) {
+	registry := NewLevelRegistry()
+	loader := NewLoader(WithLevelRegistry(registry))
+
+	config, err := loader.NewConfigWithBytes([]byte(`{"name": "payments", "zap": {"level": "info", "encoding": "json", "outputPaths": ["stdout"]}}`), JSON)
+	assert.Nil(t, err)
+	assert.Equal(t, "payments", config.Name)
+
+	logger, err := loader.NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"payments"}, registry.Names())
+
+	assert.Nil(t, registry.SetLevel("payments", zap.DebugLevel))
+	assert.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	// With closer
+	config.Name = "audit"
+	_, closer, err := loader.NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	defer closer.Shutdown(context.Background())
+	assert.Equal(t, []string{"audit", "payments"}, registry.Names())
+}
+
+// Loggers in logger map are registered with their names
+func TestNewZapLoggerMapWithBytes_WithLevelRegistry(t *testing.T) {
+	registry := NewLevelRegistry()
+	loader := NewLoader(WithLevelRegistry(registry))
+
+	bytes := []byte(`{"default": {"zap": {"level": "info", "encoding": "json", "outputPaths": ["stdout"]}}, "db": {"zap": {"level": "warn"}}}`)
+	loggers, err := loader.NewZapLoggerMapWithBytes(bytes, JSON)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db", DefaultLoggerName}, registry.Names())
+
+	assert.Nil(t, registry.SetLevel("db", zap.DebugLevel))
+	assert.True(t, loggers["db"].Core().Enabled(zapcore.DebugLevel))
+	assert.False(t, loggers[DefaultLoggerName].Core().Enabled(zapcore.DebugLevel))
+}
+
+// Failure of building logger map does not register any level
+func TestNewZapLoggerMapWithBytes_WithLevelRegistryAndInvalidLogger(t *testing.T) {
+	registry := NewLevelRegistry()
+	loader := NewLoader(WithLevelRegistry(registry))
+
+	bytes := []byte(`{"default": {"zap": {"level": "info", "encoding": "json", "outputPaths": ["stdout"]}}, "db": {"zap": {"encoding": "invalid"}}}`)
+	_, err := loader.NewZapLoggerMapWithBytes(bytes, JSON)
+	assert.NotNil(t, err)
+	assert.Empty(t, registry.Names())
+}
+
+func TestLevelRegistry_RegisterConfig(t *testing.T) {
+	registry := NewLevelRegistry()
+	config := NewConfig(NewZapStdoutConfig(), nil)
+	registry.RegisterConfig("ut", config)
+
+	res, err := registry.Config("ut")
+	assert.Nil(t, err)
+	assert.Equal(t, config, res)
+
+	assert.Nil(t, registry.SetLevel("ut", zap.WarnLevel))
+	assert.Equal(t, zap.WarnLevel, res.Zap.Level.Level())
+
+	// nil config is ignored
+	registry.RegisterConfig("nil", nil)
+	registry.RegisterConfig("nil", &Config{})
+	assert.Equal(t, []string{"ut"}, registry.Names())
+}
+
+// With logger registered without config
+func TestLevelRegistry_ConfigWithoutConfig(t *testing.T) {
+	registry := NewLevelRegistry()
+	registry.Register("ut", zap.NewAtomicLevel())
+
+	config, err := registry.Config("ut")
+	assert.Nil(t, config)
+	assert.Contains(t, err.Error(), "config of logger is not registered")
+
+	_, err = registry.Config("unknown")
+	assert.Contains(t, err.Error(), "logger is not registered")
+}
+
+func TestDefaultLevelRegistry(t *testing.T) {
+	assert.Equal(t, defaultLevelRegistry, DefaultLevelRegistry())
+}
+
+func TestLevelRegistry_SetLevelFor(t *testing.T) {
+	registry := NewLevelRegistry()
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	registry.Register("ut", level)
+
+	assert.Nil(t, registry.SetLevelFor("ut", zap.DebugLevel, 50*time.Millisecond))
+	assert.Equal(t, zap.DebugLevel, level.Level())
+
+	assert.Eventually(t, func() bool {
+		return level.Level() == zap.InfoLevel
+	}, time.Second, 10*time.Millisecond)
+}
+
+// Calling again extends duration and reverts to the level before the first call
+func TestLevelRegistry_SetLevelForTwice(t *testing.T) {
+	registry := NewLevelRegistry()
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	registry.Register("ut", level)
+
+	assert.Nil(t, registry.SetLevelFor("ut", zap.DebugLevel, 50*time.Millisecond))
+	assert.Nil(t, registry.SetLevelFor("ut", zap.WarnLevel, time.Hour))
+	assert.Equal(t, zap.WarnLevel, level.Level())
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, zap.WarnLevel, level.Level())
+
+	assert.Nil(t, registry.SetLevelFor("ut", zap.ErrorLevel, 10*time.Millisecond))
+	assert.Eventually(t, func() bool {
+		return level.Level() == zap.InfoLevel
+	}, time.Second, 10*time.Millisecond)
+}
+
+// SetLevel cancels pending revert
+func TestLevelRegistry_SetLevelForWithSetLevel(t *testing.T) {
+	registry := NewLevelRegistry()
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	registry.Register("ut", level)
+
+	assert.Nil(t, registry.SetLevelFor("ut", zap.DebugLevel, 20*time.Millisecond))
+	assert.Nil(t, registry.SetLevel("ut", zap.WarnLevel))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, zap.WarnLevel, level.Level())
+}
+
+// Registering the same name again cancels pending revert of previous level
+func TestLevelRegistry_SetLevelForWithRegister(t *testing.T) {
+	registry := NewLevelRegistry()
+	prev := zap.NewAtomicLevelAt(zap.InfoLevel)
+	registry.Register("ut", prev)
+	assert.Nil(t, registry.SetLevelFor("ut", zap.DebugLevel, 20*time.Millisecond))
+
+	curr := zap.NewAtomicLevelAt(zap.ErrorLevel)
+	registry.Register("ut", curr)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, zap.DebugLevel, prev.Level())
+	assert.Equal(t, zap.ErrorLevel, curr.Level())
+}
+
+func TestLevelRegistry_SetLevelForWithInvalidArgs(t *testing.T) {
+	registry := NewLevelRegistry()
+	registry.Register("ut", zap.NewAtomicLevel())
+
+	assert.NotNil(t, registry.SetLevelFor("unknown", zap.DebugLevel, time.Minute))
+	assert.NotNil(t, registry.SetLevelFor("ut", zap.DebugLevel, 0))
+}
+
+// Concurrent calls are race free, run with -race
+func TestLevelRegistry_SetLevelForConcurrently(t *testing.T) {
+	registry := NewLevelRegistry()
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	registry.Register("ut", level)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.SetLevelFor("ut", zap.DebugLevel, time.Millisecond)
+			registry.GetLevel("ut")
+		}()
+	}
+	wg.Wait()
+
+	assert.Eventually(t, func() bool {
+		return level.Level() == zap.InfoLevel
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSetLevelFor_WithDefaultRegistry(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	RegisterLevel("ut-expiry", level)
+
+	assert.Nil(t, SetLevelFor("ut-expiry", zap.DebugLevel, time.Hour))
+	assert.Equal(t, zap.DebugLevel, level.Level())
+	assert.Nil(t, SetLevel("ut-expiry", zap.InfoLevel))
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"unicode/utf8"
+)
+
+// DefaultTruncatedKey is the key of marker added to truncated entries if LimitsConfig.TruncatedKey is not provided.
+const DefaultTruncatedKey = "truncated"
+
+// LimitsConfig caps sizes of entries in bytes, so that downstream pipelines are protected from huge messages and
+// stack dumps. Truncated entries are marked with "truncated":true, zero means no limit.
+//
+// Example config file in YAML:
+//
+//	limits:
+//	  maxMessageSize: 4096
+//	  maxFieldSize: 8192
+//	  maxEntrySize: 65536
+type LimitsConfig struct {
+	// MaxMessageSize caps message of entries.
+	MaxMessageSize int `json:"maxMessageSize" yaml:"maxMessageSize"`
+	// MaxFieldSize caps stacktrace of entries and values of string, byte string and error fields,
+	// including fields added with With().
+	MaxFieldSize int `json:"maxFieldSize" yaml:"maxFieldSize"`
+	// MaxEntrySize caps encoded entries. Stacktrace and fields of oversized entries are dropped and message is
+	// truncated afterwards if it is still oversized, fields added with With() are kept.
+	MaxEntrySize int `json:"maxEntrySize" yaml:"maxEntrySize"`
+	// TruncatedKey is the key of marker, DefaultTruncatedKey by default.
+	TruncatedKey string `json:"truncatedKey" yaml:"truncatedKey"`
+}
+
+// limitEncoder truncates entries and fields of wrapped encoder
+type limitEncoder struct {
+	zapcore.Encoder
+	limits       LimitsConfig
+	truncatedKey string
+	// whether fields added with With() are truncated
+	truncated bool
+}
+
+// Wrap encoder with limits
+func newLimitEncoder(encoder zapcore.Encoder, limits LimitsConfig) zapcore.Encoder {
+	truncatedKey := limits.TruncatedKey
+	if len(truncatedKey) == 0 {
+		truncatedKey = DefaultTruncatedKey
+	}
+
+	return &limitEncoder{
+		Encoder:      encoder,
+		limits:       limits,
+		truncatedKey: truncatedKey,
+	}
+}
+
+// Clone implements zapcore.Encoder
+func (enc *limitEncoder) Clone() zapcore.Encoder {
+	return &limitEncoder{
+		Encoder:      enc.Encoder.Clone(),
+		limits:       enc.limits,
+		truncatedKey: enc.truncatedKey,
+		truncated:    enc.truncated,
+	}
+}
+
+// AddString implements zapcore.ObjectEncoder
+func (enc *limitEncoder) AddString(key, value string) {
+	if max := enc.limits.MaxFieldSize; max > 0 && len(value) > max {
+		value, enc.truncated = truncateString(value, max), true
+	}
+
+	enc.Encoder.AddString(key, value)
+}
+
+// AddByteString implements zapcore.ObjectEncoder
+func (enc *limitEncoder) AddByteString(key string, value []byte) {
+	if max := enc.limits.MaxFieldSize; max > 0 && len(value) > max {
+		value, enc.truncated = []byte(truncateString(string(value), max)), true
+	}
+
+	enc.Encoder.AddByteString(key, value)
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *limitEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	truncated := enc.truncated
+	if max := enc.limits.MaxMessageSize; max > 0 && len(entry.Message) > max {
+		entry.Message, truncated = truncateString(entry.Message, max), true
+	}
+
+	if max := enc.limits.MaxFieldSize; max > 0 {
+		if len(entry.Stack) > max {
+			entry.Stack, truncated = truncateString(entry.Stack, max), true
+		}
+
+		var truncatedFields bool
+		fields, truncatedFields = truncateFields(fields, max)
+		truncated = truncated || truncatedFields
+	}
+
+	buf, err := enc.encode(entry, fields, truncated)
+	max := enc.limits.MaxEntrySize
+	if err != nil || max <= 0 || buf.Len() <= max {
+		return buf, err
+	}
+
+	// drop stacktrace and fields
+	buf.Free()
+	entry.Stack = ""
+	if buf, err = enc.encode(entry, nil, true); err != nil || buf.Len() <= max {
+		return buf, err
+	}
+
+	// truncate message with the size of overflow
+	overflow := buf.Len() - max
+	buf.Free()
+	entry.Message = truncateString(entry.Message, len(entry.Message)-overflow)
+	return enc.encode(entry, nil, true)
+}
+
+// Encode entry with wrapped encoder, marker is added if truncated
+func (enc *limitEncoder) encode(entry zapcore.Entry, fields []zapcore.Field, truncated bool) (*buffer.Buffer, error) {
+	if truncated {
+		fields = append(fields[:len(fields):len(fields)], zap.Bool(enc.truncatedKey, true))
+	}
+
+	return enc.Encoder.EncodeEntry(entry, fields)
+}
+
+// Truncate values of string, byte string, error and stringer fields, fields are copied if any of them is truncated
+func truncateFields(fields []zapcore.Field, max int) ([]zapcore.Field, bool) {
+	var res []zapcore.Field
+	for i := range fields {
+		field := fields[i]
+
+		var value string
+		switch field.Type {
+		case zapcore.StringType:
+			value = field.String
+		case zapcore.ByteStringType:
+			value = string(field.Interface.([]byte))
+		case zapcore.ErrorType:
+			value = field.Interface.(error).Error()
+		case zapcore.StringerType:
+			value = fmt.Sprint(field.Interface)
+		default:
+			continue
+		}
+
+		if len(value) <= max {
+			continue
+		}
+
+		if res == nil {
+			res = append([]zapcore.Field{}, fields...)
+		}
+		res[i] = zap.String(field.Key, truncateString(value, max))
+	}
+
+	if res == nil {
+		return fields, false
+	}
+
+	return res, true
+}
+
+// Truncate string to at most max bytes without splitting runes
+func truncateString(value string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+
+	if len(value) <= max {
+		return value
+	}
+
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+
+	return value[:max]
+}
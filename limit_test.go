@@ -0,0 +1,91 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Encoder of json with message key only
+func newLimitTestEncoder(limits LimitsConfig) zapcore.Encoder {
+	return newLimitEncoder(zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:    "msg",
+		StacktraceKey: "stack",
+	}), limits)
+}
+
+func TestLimitEncoder_EncodeEntry(t *testing.T) {
+	encoder := newLimitTestEncoder(LimitsConfig{MaxMessageSize: 5, MaxFieldSize: 3})
+
+	// Within limits
+	assert.Equal(t, `{"msg":"hello","k":"abc"}`+"\n",
+		encodeCSV(t, encoder, zapcore.Entry{Message: "hello"}, zap.String("k", "abc")))
+
+	// With message, stacktrace and fields over limits
+	assert.Equal(t, `{"msg":"hello","s":"abc","b":"xyz","error":"err","n":12345,"truncated":true,"stack":"gor"}`+"\n",
+		encodeCSV(t, encoder, zapcore.Entry{Message: "hello world", Stack: "goroutine 1"},
+			zap.String("s", "abcdef"),
+			zap.ByteString("b", []byte("xyzxyz")),
+			zap.Error(errors.New("error")),
+			zap.Int("n", 12345)))
+
+	// Runes are not split
+	assert.Equal(t, `{"msg":"你","truncated":true}`+"\n", encodeCSV(t, encoder, zapcore.Entry{Message: "你好"}))
+
+	// Fields added with With() are truncated and marked in every entry
+	clone := encoder.Clone()
+	clone.AddString("ctx", "abcdef")
+	assert.Equal(t, `{"msg":"hi","ctx":"abc","truncated":true}`+"\n", encodeCSV(t, clone, zapcore.Entry{Message: "hi"}))
+	assert.Equal(t, `{"msg":"hi"}`+"\n", encodeCSV(t, encoder, zapcore.Entry{Message: "hi"}))
+}
+
+func TestLimitEncoder_WithMaxEntrySize(t *testing.T) {
+	encoder := newLimitTestEncoder(LimitsConfig{MaxEntrySize: 40, TruncatedKey: "cut"})
+
+	// Stacktrace and fields are dropped
+	assert.Equal(t, `{"msg":"hello","cut":true}`+"\n",
+		encodeCSV(t, encoder, zapcore.Entry{Message: "hello", Stack: strings.Repeat("s", 100)}, zap.String("k", "v")))
+
+	// Message is truncated afterwards
+	res := encodeCSV(t, encoder, zapcore.Entry{Message: strings.Repeat("m", 100)})
+	assert.Equal(t, `{"msg":"`+strings.Repeat("m", 18)+`","cut":true}`+"\n", res)
+	assert.Len(t, res, 40)
+}
+
+func TestLimits_WithConfigFile(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + filePath + `"]
+limits:
+  maxMessageSize: 4
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	logger.Sync()
+
+	assert.Equal(t, `{"msg":"ut-m","truncated":true}`+"\n", readFileContent(filePath))
+}
+
+func TestWithLimits(t *testing.T) {
+	config := NewConfigWithOptions(WithLimits(LimitsConfig{MaxEntrySize: 1024}))
+	assert.Equal(t, 1024, config.Limits.MaxEntrySize)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// defaultLoader is used by package level functions with default options
+var defaultLoader = NewLoader()
+
+// DefaultDirMode is the permission of parent directories of file output paths created by Loader.
+const DefaultDirMode os.FileMode = 0755
+
+// Loader loads config files and builds loggers with options.
+// Package level functions like NewZapLoggerWithBytes use a Loader with default options,
+// create a new Loader with NewLoader in order to enable optional behaviors.
+type Loader struct {
+	expandEnv  bool
+	strict     bool
+	createDirs bool
+	dirMode    os.FileMode
+	baseDir    string
+	levels     *LevelRegistry
+	// write syncers shared by loggers of LoggerFactory
+	pool *writeSyncerPool
+	// file outputs are reopened instead of shared with other loggers, which is used while reloading
+	reopen bool
+}
+
+// LoaderOption is used while creating Loader.
+type LoaderOption func(*Loader)
+
+// WithEnvExpansion enables ${ENV_VAR} and ${ENV_VAR:-default} interpolation in raw config content,
+// see ExpandEnv for details.
+func WithEnvExpansion() LoaderOption {
+	return func(loader *Loader) {
+		loader.expandEnv = true
+	}
+}
+
+// WithStrictParsing rejects config files which contain unrecognized keys, like outputPath instead of outputPaths.
+// UnknownKeysError which lists all of the unrecognized keys would be returned.
+func WithStrictParsing() LoaderOption {
+	return func(loader *Loader) {
+		loader.strict = true
+	}
+}
+
+// WithDirCreation enables or disables creating parent directories of file output paths
+// before building loggers, which is enabled by default.
+func WithDirCreation(enabled bool) LoaderOption {
+	return func(loader *Loader) {
+		loader.createDirs = enabled
+	}
+}
+
+// WithDirMode sets permission of parent directories created for file output paths, DefaultDirMode by default.
+func WithDirMode(mode os.FileMode) LoaderOption {
+	return func(loader *Loader) {
+		loader.dirMode = mode
+	}
+}
+
+// WithBaseDir resolves relative file output paths against dir instead of current working directory.
+// A relative dir is resolved against current working directory.
+func WithBaseDir(dir string) LoaderOption {
+	return func(loader *Loader) {
+		loader.baseDir = dir
+	}
+}
+
+// WithLevelRegistry registers levels of constructed loggers in registry instead of the default one
+// which is used by package level functions like SetLevel.
+func WithLevelRegistry(registry *LevelRegistry) LoaderOption {
+	return func(loader *Loader) {
+		loader.levels = registry
+	}
+}
+
+// NewLoader creates a new Loader with options.
+func NewLoader(opts ...LoaderOption) *Loader {
+	loader := &Loader{
+		createDirs: true,
+		dirMode:    DefaultDirMode,
+		levels:     defaultLevelRegistry,
+	}
+
+	for i := range opts {
+		opts[i](loader)
+	}
+
+	return loader
+}
+
+// Preprocess raw content of config file before unmarshalling
+func (loader *Loader) preprocess(raw []byte) []byte {
+	if loader.expandEnv {
+		raw = ExpandEnv(raw)
+	}
+
+	return raw
+}
+
+// Resolve file path against base directory, current working directory would be used if base directory is not set
+func (loader *Loader) resolvePath(filePath string) (string, error) {
+	if len(loader.baseDir) == 0 || filepath.IsAbs(filePath) {
+		return toAbsoluteWorkingDir(filePath)
+	}
+
+	dir, err := toAbsoluteWorkingDir(loader.baseDir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, filePath), nil
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewLoader_WithoutOptions(t *testing.T) {
+	loader := NewLoader()
+	assert.NotNil(t, loader)
+	assert.False(t, loader.expandEnv)
+	assert.False(t, loader.strict)
+	assert.True(t, loader.createDirs)
+	assert.Equal(t, DefaultDirMode, loader.dirMode)
+	assert.Empty(t, loader.baseDir)
+	assert.Equal(t, defaultLevelRegistry, loader.levels)
+}
+
+func TestNewLoader_WithDirCreation(t *testing.T) {
+	loader := NewLoader(WithDirCreation(false), WithDirMode(0700))
+	assert.False(t, loader.createDirs)
+	assert.Equal(t, os.FileMode(0700), loader.dirMode)
+}
+
+func TestNewLoader_WithEnvExpansion(t *testing.T) {
+	loader := NewLoader(WithEnvExpansion())
+	assert.True(t, loader.expandEnv)
+}
+
+// Variables would not be expanded with default loader
+func TestNewZapLoggerWithBytes_WithoutEnvExpansion(t *testing.T) {
+	assert.Nil(t, os.Setenv("RK_LOGGER_UT_KEY", "value"))
+	defer os.Unsetenv("RK_LOGGER_UT_KEY")
+
+	bytes := []byte(`{"level": "info", "outputPaths": ["stdout"], "initialFields": {"key": "${RK_LOGGER_UT_KEY}"}}`)
+	logger, config, err := NewZapLoggerWithBytes(bytes, JSON)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, "${RK_LOGGER_UT_KEY}", config.InitialFields["key"])
+}
+
+func TestLoader_NewZapLoggerWithBytes_WithEnvExpansion(t *testing.T) {
+	assert.Nil(t, os.Setenv("RK_LOGGER_UT_LEVEL", "warn"))
+	defer os.Unsetenv("RK_LOGGER_UT_LEVEL")
+
+	bytes := []byte(`
+level: ${RK_LOGGER_UT_LEVEL:-info}
+encoding: ${RK_LOGGER_UT_ENCODING:-json}
+outputPaths: ["${RK_LOGGER_UT_OUTPUT:-stdout}"]
+initialFields:
+  env: ${RK_LOGGER_UT_ENV:-dev}
+`)
+	logger, config, err := NewLoader(WithEnvExpansion()).NewZapLoggerWithBytes(bytes, YAML)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.WarnLevel, config.Level.Level())
+	assert.Equal(t, "json", config.Encoding)
+	assert.Equal(t, []string{"stdout"}, config.OutputPaths)
+	assert.Equal(t, "dev", config.InitialFields["env"])
+}
+
+func TestLoader_NewZapLoggerWithConfPath_WithEnvExpansion(t *testing.T) {
+	logger, config, err := NewLoader(WithEnvExpansion()).NewZapLoggerWithConfPath(
+		writeTempConfigFile(t, `{"level": "${RK_LOGGER_UT_LEVEL:-error}", "outputPaths": ["stdout"]}`), JSON)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.ErrorLevel, config.Level.Level())
+}
+
+func TestLoader_NewLumberjackLoggerWithBytes_WithEnvExpansion(t *testing.T) {
+	assert.Nil(t, os.Setenv("RK_LOGGER_UT_FILENAME", "logs/ut.log"))
+	defer os.Unsetenv("RK_LOGGER_UT_FILENAME")
+
+	logger, err := NewLoader(WithEnvExpansion()).NewLumberjackLoggerWithBytes(
+		[]byte("filename: ${RK_LOGGER_UT_FILENAME}\nmaxsize: ${RK_LOGGER_UT_MAX_SIZE:-10}"), YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, "logs/ut.log", logger.Filename)
+	assert.Equal(t, 10, logger.MaxSize)
+}
+
+func TestLoader_NewLumberjackLoggerWithConfPathAuto_WithEnvExpansion(t *testing.T) {
+	logger, err := NewLoader(WithEnvExpansion()).NewLumberjackLoggerWithConfPathAuto(
+		writeTempConfigFile(t, "maxage = ${RK_LOGGER_UT_MAX_AGE:-3}"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, logger.MaxAge)
+}
+
+func TestNewLoader_WithStrictParsing(t *testing.T) {
+	loader := NewLoader(WithStrictParsing())
+	assert.True(t, loader.strict)
+}
+
+func TestNewLoader_WithBaseDir(t *testing.T) {
+	loader := NewLoader(WithBaseDir("/var/log"))
+	assert.Equal(t, "/var/log", loader.baseDir)
+}
+
+// Without base dir, relative path is resolved against working directory
+func TestResolvePath_WithoutBaseDir(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+
+	res, err := NewLoader().resolvePath(filepath.Join("logs", "ut.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(wd, "logs", "ut.log"), res)
+}
+
+func TestResolvePath_WithBaseDir(t *testing.T) {
+	dir := newTempDir(t)
+
+	res, err := NewLoader(WithBaseDir(dir)).resolvePath(filepath.Join("logs", "ut.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "logs", "ut.log"), res)
+
+	// absolute path is not affected
+	abs := filepath.Join(newTempDir(t), "ut.log")
+	res, err = NewLoader(WithBaseDir(dir)).resolvePath(abs)
+	assert.Nil(t, err)
+	assert.Equal(t, abs, res)
+}
+
+// Relative base dir is resolved against working directory
+func TestResolvePath_WithRelativeBaseDir(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+
+	res, err := NewLoader(WithBaseDir("base")).resolvePath("ut.log")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(wd, "base", "ut.log"), res)
+}
+
+// Relative output paths in config file are written to base dir
+func TestNewZapLoggerWithBytes_WithBaseDir(t *testing.T) {
+	dir := newTempDir(t)
+	loader := NewLoader(WithBaseDir(dir))
+
+	bytes := []byte(`{"level": "info", "encoding": "json", "encoderConfig": {"messageKey": "msg"}, "outputPaths": ["logs/ut.log"], "errorOutputPaths": ["stderr"]}`)
+	logger, _, err := loader.NewZapLoggerWithBytes(bytes, JSON)
+	assert.Nil(t, err)
+	logger.Info("base dir")
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "logs", "ut.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "base dir")
+
+	// with lumberjack
+	lumber := NewLumberjackConfigDefault()
+	logger, err = loader.NewZapLoggerWithConf(&zap.Config{
+		Level:         zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding:      "json",
+		EncoderConfig: zap.NewProductionEncoderConfig(),
+		OutputPaths:   []string{"lumber/ut.log"},
+	}, lumber)
+	assert.Nil(t, err)
+	logger.Info("base dir with lumberjack")
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, "lumber", "ut.log"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), "base dir with lumberjack")
+}
@@ -0,0 +1,587 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LogfmtEncoding is the name of encoding which encodes entries as logfmt, like:
+//
+//	ts=2020-09-01T10:00:00.000+0800 level=info logger=db caller=db/conn.go:42 msg="connection is ready" pool.size=10
+//
+// Values with spaces, quotes, equal signs or control characters are quoted, fields of objects are flattened
+// with keys joined by dot, and arrays and reflected values are encoded as json.
+const LogfmtEncoding = "logfmt"
+
+// pool of buffers returned by logfmt encoder, and encoders of entries which are reused
+var (
+	logfmtPool        = buffer.NewPool()
+	logfmtEncoderPool = sync.Pool{New: func() interface{} {
+		return &logfmtEncoder{}
+	}}
+)
+
+func init() {
+	if err := RegisterEncoder(LogfmtEncoding, NewLogfmtEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// logfmtEncoder implements zapcore.Encoder and zapcore.PrimitiveArrayEncoder which encoders of config append to
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf *buffer.Buffer
+	// keys of open namespaces and objects, which prefix keys of fields
+	namespaces []string
+	// scratch space of quoted strings
+	scratch []byte
+}
+
+// NewLogfmtEncoder creates encoder of logfmt with encoder config, keys of entries and encoders of time, level,
+// duration, caller and name are applied the same way as json encoding.
+func NewLogfmtEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return &logfmtEncoder{
+		EncoderConfig: &config,
+		buf:           logfmtPool.Get(),
+	}, nil
+}
+
+// Clone implements zapcore.Encoder
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	return enc.clone()
+}
+
+// Copy encoder with encoded fields
+func (enc *logfmtEncoder) clone() *logfmtEncoder {
+	clone := &logfmtEncoder{
+		EncoderConfig: enc.EncoderConfig,
+		buf:           logfmtPool.Get(),
+		namespaces:    append([]string{}, enc.namespaces...),
+	}
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *logfmtEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := logfmtEncoderPool.Get().(*logfmtEncoder)
+	final.EncoderConfig = enc.EncoderConfig
+	final.buf = logfmtPool.Get()
+	defer putLogfmtEncoder(final)
+
+	if len(final.TimeKey) > 0 {
+		final.AddTime(final.TimeKey, entry.Time)
+	}
+
+	if len(final.LevelKey) > 0 {
+		final.addKey(final.LevelKey)
+		cur := final.buf.Len()
+		if final.EncodeLevel != nil {
+			final.EncodeLevel(entry.Level, final)
+		}
+		if cur == final.buf.Len() {
+			final.AppendString(entry.Level.String())
+		}
+	}
+
+	if len(entry.LoggerName) > 0 && len(final.NameKey) > 0 {
+		final.addKey(final.NameKey)
+		cur := final.buf.Len()
+		if final.EncodeName != nil {
+			final.EncodeName(entry.LoggerName, final)
+		}
+		if cur == final.buf.Len() {
+			final.AppendString(entry.LoggerName)
+		}
+	}
+
+	if entry.Caller.Defined {
+		if len(final.CallerKey) > 0 {
+			final.addKey(final.CallerKey)
+			cur := final.buf.Len()
+			if final.EncodeCaller != nil {
+				final.EncodeCaller(entry.Caller, final)
+			}
+			if cur == final.buf.Len() {
+				final.AppendString(entry.Caller.String())
+			}
+		}
+
+		if len(final.FunctionKey) > 0 {
+			final.AddString(final.FunctionKey, entry.Caller.Function)
+		}
+	}
+
+	if len(final.MessageKey) > 0 {
+		final.AddString(final.MessageKey, entry.Message)
+	}
+
+	// fields added with With() and fields of entry are prefixed with namespaces opened by them
+	if enc.buf.Len() > 0 {
+		final.separate()
+		final.buf.Write(enc.buf.Bytes())
+	}
+
+	final.namespaces = append(final.namespaces[:0], enc.namespaces...)
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.namespaces = final.namespaces[:0]
+
+	if len(entry.Stack) > 0 && len(final.StacktraceKey) > 0 {
+		final.AddString(final.StacktraceKey, entry.Stack)
+	}
+
+	if len(final.LineEnding) > 0 {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+
+	return final.buf, nil
+}
+
+// Put encoder of entry back to pool, its buffer is returned to caller
+func putLogfmtEncoder(enc *logfmtEncoder) {
+	enc.EncoderConfig = nil
+	enc.buf = nil
+	enc.namespaces = enc.namespaces[:0]
+	enc.scratch = enc.scratch[:0]
+	logfmtEncoderPool.Put(enc)
+}
+
+// Append space between key value pairs
+func (enc *logfmtEncoder) separate() {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+}
+
+// Append key prefixed with namespaces and equal sign, characters which break logfmt are replaced with underscore
+func (enc *logfmtEncoder) addKey(key string) {
+	enc.separate()
+	for i := range enc.namespaces {
+		enc.buf.AppendString(sanitizeLogfmtKey(enc.namespaces[i]))
+		enc.buf.AppendByte('.')
+	}
+	enc.buf.AppendString(sanitizeLogfmtKey(key))
+	enc.buf.AppendByte('=')
+}
+
+// Replace spaces, quotes, equal signs and control characters in key with underscore
+func sanitizeLogfmtKey(key string) string {
+	if len(key) == 0 {
+		return "_"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// Whether value should be quoted
+func needsLogfmtQuote(value string) bool {
+	if len(value) == 0 {
+		return true
+	}
+
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || unicode.IsControl(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddArray implements zapcore.ObjectEncoder, array is encoded as json
+func (enc *logfmtEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := &logfmtArrayEncoder{config: enc.EncoderConfig, elems: []interface{}{}}
+	err := marshaler.MarshalLogArray(arr)
+
+	raw, jsonErr := json.Marshal(arr.elems)
+	if err == nil {
+		err = jsonErr
+	}
+
+	enc.AddByteString(key, raw)
+	return err
+}
+
+// AddObject implements zapcore.ObjectEncoder, fields of object are flattened with keys prefixed by key
+func (enc *logfmtEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	enc.namespaces = append(enc.namespaces, key)
+	err := marshaler.MarshalLogObject(enc)
+	enc.namespaces = enc.namespaces[:len(enc.namespaces)-1]
+	return err
+}
+
+// AddBinary implements zapcore.ObjectEncoder, value is encoded as base64
+func (enc *logfmtEncoder) AddBinary(key string, value []byte) {
+	enc.AddString(key, base64.StdEncoding.EncodeToString(value))
+}
+
+// AddByteString implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddByteString(key string, value []byte) {
+	enc.addKey(key)
+	enc.AppendByteString(value)
+}
+
+// AddBool implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddBool(key string, value bool) {
+	enc.addKey(key)
+	enc.AppendBool(value)
+}
+
+// AddComplex128 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddComplex128(key string, value complex128) {
+	enc.addKey(key)
+	enc.AppendComplex128(value)
+}
+
+// AddComplex64 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddComplex64(key string, value complex64) {
+	enc.AddComplex128(key, complex128(value))
+}
+
+// AddDuration implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddDuration(key string, value time.Duration) {
+	enc.addKey(key)
+	enc.AppendDuration(value)
+}
+
+// AddFloat64 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddFloat64(key string, value float64) {
+	enc.addKey(key)
+	enc.AppendFloat64(value)
+}
+
+// AddFloat32 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddFloat32(key string, value float32) {
+	enc.addKey(key)
+	enc.AppendFloat32(value)
+}
+
+// AddInt implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddInt(key string, value int) { enc.AddInt64(key, int64(value)) }
+
+// AddInt64 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddInt64(key string, value int64) {
+	enc.addKey(key)
+	enc.AppendInt64(value)
+}
+
+// AddInt32 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddInt32(key string, value int32) { enc.AddInt64(key, int64(value)) }
+
+// AddInt16 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddInt16(key string, value int16) { enc.AddInt64(key, int64(value)) }
+
+// AddInt8 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddInt8(key string, value int8) { enc.AddInt64(key, int64(value)) }
+
+// AddString implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddString(key, value string) {
+	enc.addKey(key)
+	enc.AppendString(value)
+}
+
+// AddTime implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddTime(key string, value time.Time) {
+	enc.addKey(key)
+	enc.AppendTime(value)
+}
+
+// AddUint implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddUint(key string, value uint) { enc.AddUint64(key, uint64(value)) }
+
+// AddUint64 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddUint64(key string, value uint64) {
+	enc.addKey(key)
+	enc.AppendUint64(value)
+}
+
+// AddUint32 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddUint32(key string, value uint32) { enc.AddUint64(key, uint64(value)) }
+
+// AddUint16 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddUint16(key string, value uint16) { enc.AddUint64(key, uint64(value)) }
+
+// AddUint8 implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddUint8(key string, value uint8) { enc.AddUint64(key, uint64(value)) }
+
+// AddUintptr implements zapcore.ObjectEncoder
+func (enc *logfmtEncoder) AddUintptr(key string, value uintptr) { enc.AddUint64(key, uint64(value)) }
+
+// AddReflected implements zapcore.ObjectEncoder, value is encoded as json and strings are not quoted twice
+func (enc *logfmtEncoder) AddReflected(key string, value interface{}) error {
+	if str, ok := value.(string); ok {
+		enc.AddString(key, str)
+		return nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	enc.AddByteString(key, raw)
+	return nil
+}
+
+// OpenNamespace implements zapcore.ObjectEncoder, keys of fields added afterwards are prefixed by key
+func (enc *logfmtEncoder) OpenNamespace(key string) {
+	enc.namespaces = append(enc.namespaces, key)
+}
+
+// AppendBool implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendBool(value bool) {
+	enc.buf.AppendBool(value)
+}
+
+// AppendByteString implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendByteString(value []byte) {
+	enc.AppendString(string(value))
+}
+
+// AppendComplex128 implements zapcore.PrimitiveArrayEncoder, like 1+2i
+func (enc *logfmtEncoder) AppendComplex128(value complex128) {
+	re, im := real(value), imag(value)
+	enc.buf.AppendFloat(re, 64)
+	if (im >= 0 && !math.IsInf(im, 1)) || math.IsNaN(im) {
+		enc.buf.AppendByte('+')
+	}
+	enc.buf.AppendFloat(im, 64)
+	enc.buf.AppendByte('i')
+}
+
+// AppendComplex64 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendComplex64(value complex64) { enc.AppendComplex128(complex128(value)) }
+
+// AppendDuration implements zapcore.PrimitiveArrayEncoder, nanoseconds are appended without duration encoder
+func (enc *logfmtEncoder) AppendDuration(value time.Duration) {
+	cur := enc.buf.Len()
+	if enc.EncodeDuration != nil {
+		enc.EncodeDuration(value, enc)
+	}
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(int64(value))
+	}
+}
+
+// AppendFloat64 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendFloat64(value float64) {
+	enc.buf.AppendFloat(value, 64)
+}
+
+// AppendFloat32 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendFloat32(value float32) {
+	enc.buf.AppendFloat(float64(value), 32)
+}
+
+// AppendInt implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendInt(value int) { enc.AppendInt64(int64(value)) }
+
+// AppendInt64 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendInt64(value int64) {
+	enc.buf.AppendInt(value)
+}
+
+// AppendInt32 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendInt32(value int32) { enc.AppendInt64(int64(value)) }
+
+// AppendInt16 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendInt16(value int16) { enc.AppendInt64(int64(value)) }
+
+// AppendInt8 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendInt8(value int8) { enc.AppendInt64(int64(value)) }
+
+// AppendString implements zapcore.PrimitiveArrayEncoder, value is quoted if necessary
+func (enc *logfmtEncoder) AppendString(value string) {
+	if needsLogfmtQuote(value) {
+		enc.scratch = strconv.AppendQuote(enc.scratch[:0], value)
+		enc.buf.Write(enc.scratch)
+		return
+	}
+
+	enc.buf.AppendString(value)
+}
+
+// AppendTime implements zapcore.PrimitiveArrayEncoder, nanoseconds since epoch are appended without time encoder
+func (enc *logfmtEncoder) AppendTime(value time.Time) {
+	cur := enc.buf.Len()
+	if enc.EncodeTime != nil {
+		enc.EncodeTime(value, enc)
+	}
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(value.UnixNano())
+	}
+}
+
+// AppendUint implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendUint(value uint) { enc.AppendUint64(uint64(value)) }
+
+// AppendUint64 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendUint64(value uint64) {
+	enc.buf.AppendUint(value)
+}
+
+// AppendUint32 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendUint32(value uint32) { enc.AppendUint64(uint64(value)) }
+
+// AppendUint16 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendUint16(value uint16) { enc.AppendUint64(uint64(value)) }
+
+// AppendUint8 implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendUint8(value uint8) { enc.AppendUint64(uint64(value)) }
+
+// AppendUintptr implements zapcore.PrimitiveArrayEncoder
+func (enc *logfmtEncoder) AppendUintptr(value uintptr) { enc.AppendUint64(uint64(value)) }
+
+// Format float like strconv, NaN and infinities are spelled out, floats of logfmtEncoder are appended the same way
+func formatLogfmtFloat(value float64, bitSize int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(value, 'f', -1, bitSize)
+}
+
+// logfmtArrayEncoder collects elements of arrays, which are encoded as json
+type logfmtArrayEncoder struct {
+	config *zapcore.EncoderConfig
+	elems  []interface{}
+}
+
+// AppendArray implements zapcore.ArrayEncoder
+func (arr *logfmtArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	nested := &logfmtArrayEncoder{config: arr.config, elems: []interface{}{}}
+	err := marshaler.MarshalLogArray(nested)
+	arr.elems = append(arr.elems, nested.elems)
+	return err
+}
+
+// AppendObject implements zapcore.ArrayEncoder
+func (arr *logfmtArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	obj := zapcore.NewMapObjectEncoder()
+	err := marshaler.MarshalLogObject(obj)
+	arr.elems = append(arr.elems, obj.Fields)
+	return err
+}
+
+// AppendReflected implements zapcore.ArrayEncoder
+func (arr *logfmtArrayEncoder) AppendReflected(value interface{}) error {
+	arr.elems = append(arr.elems, value)
+	return nil
+}
+
+// AppendBool implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendBool(value bool) { arr.elems = append(arr.elems, value) }
+
+// AppendByteString implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendByteString(value []byte) {
+	arr.elems = append(arr.elems, string(value))
+}
+
+// AppendComplex128 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendComplex128(value complex128) {
+	arr.elems = append(arr.elems, fmt.Sprint(value))
+}
+
+// AppendComplex64 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendComplex64(value complex64) {
+	arr.AppendComplex128(complex128(value))
+}
+
+// AppendDuration implements zapcore.PrimitiveArrayEncoder, duration encoder appends to array
+func (arr *logfmtArrayEncoder) AppendDuration(value time.Duration) {
+	cur := len(arr.elems)
+	if arr.config.EncodeDuration != nil {
+		arr.config.EncodeDuration(value, arr)
+	}
+	if cur == len(arr.elems) {
+		arr.elems = append(arr.elems, int64(value))
+	}
+}
+
+// AppendFloat64 implements zapcore.PrimitiveArrayEncoder, NaN and infinities are appended as strings
+func (arr *logfmtArrayEncoder) AppendFloat64(value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		arr.elems = append(arr.elems, formatLogfmtFloat(value, 64))
+		return
+	}
+	arr.elems = append(arr.elems, value)
+}
+
+// AppendFloat32 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendFloat32(value float32) { arr.AppendFloat64(float64(value)) }
+
+// AppendInt implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendInt(value int) { arr.elems = append(arr.elems, value) }
+
+// AppendInt64 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendInt64(value int64) { arr.elems = append(arr.elems, value) }
+
+// AppendInt32 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendInt32(value int32) { arr.elems = append(arr.elems, value) }
+
+// AppendInt16 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendInt16(value int16) { arr.elems = append(arr.elems, value) }
+
+// AppendInt8 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendInt8(value int8) { arr.elems = append(arr.elems, value) }
+
+// AppendString implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendString(value string) { arr.elems = append(arr.elems, value) }
+
+// AppendTime implements zapcore.PrimitiveArrayEncoder, time encoder appends to array
+func (arr *logfmtArrayEncoder) AppendTime(value time.Time) {
+	cur := len(arr.elems)
+	if arr.config.EncodeTime != nil {
+		arr.config.EncodeTime(value, arr)
+	}
+	if cur == len(arr.elems) {
+		arr.elems = append(arr.elems, value.UnixNano())
+	}
+}
+
+// AppendUint implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendUint(value uint) { arr.elems = append(arr.elems, value) }
+
+// AppendUint64 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendUint64(value uint64) { arr.elems = append(arr.elems, value) }
+
+// AppendUint32 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendUint32(value uint32) { arr.elems = append(arr.elems, value) }
+
+// AppendUint16 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendUint16(value uint16) { arr.elems = append(arr.elems, value) }
+
+// AppendUint8 implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendUint8(value uint8) { arr.elems = append(arr.elems, value) }
+
+// AppendUintptr implements zapcore.PrimitiveArrayEncoder
+func (arr *logfmtArrayEncoder) AppendUintptr(value uintptr) { arr.elems = append(arr.elems, value) }
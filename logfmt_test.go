@@ -0,0 +1,146 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"path"
+	"testing"
+	"time"
+)
+
+// utLogfmtObject is an object with nested array
+type utLogfmtObject struct{}
+
+func (utLogfmtObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", "ut")
+	enc.AddInt("size", 10)
+	return enc.AddArray("tags", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		arr.AppendString("a b")
+		arr.AppendInt(1)
+		return nil
+	}))
+}
+
+// Encode entry with encoder config of stdout config
+func encodeLogfmt(t *testing.T, entry zapcore.Entry, fields ...zapcore.Field) string {
+	encoder, err := NewLogfmtEncoder(*NewZapStdoutEncoderConfig())
+	assert.Nil(t, err)
+
+	buf, err := encoder.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	return buf.String()
+}
+
+func TestLogfmtEncoder_EncodeEntry(t *testing.T) {
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC),
+		LoggerName: "db",
+		Message:    "connection is slow",
+		Caller:     zapcore.NewEntryCaller(0, "/src/db/conn.go", 42, true),
+		Stack:      "goroutine 1\n\tmain.go:10",
+	}
+
+	assert.Equal(t, `ts=2020-09-01T10:00:00.000Z level=WARN logger=db caller=db/conn.go:42 msg="connection is slow" `+
+		`elapsed=1.5s stacktrace="goroutine 1\n\tmain.go:10"`+"\n",
+		encodeLogfmt(t, entry, zap.Duration("elapsed", 1500*time.Millisecond)))
+}
+
+func TestLogfmtEncoder_WithFields(t *testing.T) {
+	output := encodeLogfmt(t, zapcore.Entry{Message: "ut"},
+		zap.String("empty", ""),
+		zap.String("quote", `say "hi"`),
+		zap.String("equal", "a=b"),
+		zap.String("unicode", "中文"),
+		zap.String("bad key", "v"),
+		zap.Bool("ok", true),
+		zap.Int64("int", -1),
+		zap.Uint8("uint", 8),
+		zap.Float64("float", 1.5),
+		zap.Float64("nan", math.NaN()),
+		zap.Complex128("complex", complex(1, -2)),
+		zap.Binary("binary", []byte("ut")),
+		zap.ByteString("bytes", []byte("ut")),
+		zap.Strings("strings", []string{"a", "b"}),
+		zap.Reflect("map", map[string]int{"a": 1}),
+		zap.Reflect("str", "a b"),
+		zap.Error(errors.New("failed to connect")),
+		zap.Object("object", utLogfmtObject{}),
+	)
+
+	for _, expected := range []string{
+		`msg=ut`,
+		`empty=""`,
+		`quote="say \"hi\""`,
+		`equal="a=b"`,
+		`unicode=中文`,
+		`bad_key=v`,
+		`ok=true`,
+		`int=-1`,
+		`uint=8`,
+		`float=1.5`,
+		`nan=NaN`,
+		`complex=1-2i`,
+		`binary="dXQ="`,
+		`bytes=ut`,
+		`strings="[\"a\",\"b\"]"`,
+		`map="{\"a\":1}"`,
+		`str="a b"`,
+		`error="failed to connect"`,
+		`object.name=ut object.size=10 object.tags="[\"a b\",1]"`,
+	} {
+		assert.Contains(t, output, expected)
+	}
+}
+
+// Fields added with With() and namespaces are kept by clones only
+func TestLogfmtEncoder_WithNamespace(t *testing.T) {
+	encoder, err := NewLogfmtEncoder(zapcore.EncoderConfig{MessageKey: "msg"})
+	assert.Nil(t, err)
+	encoder.AddString("app", "ut")
+
+	clone := encoder.Clone()
+	clone.OpenNamespace("req")
+	clone.AddInt("id", 1)
+
+	buf, err := clone.EncodeEntry(zapcore.Entry{Message: "ut"}, []zapcore.Field{zap.Int("status", 200)})
+	assert.Nil(t, err)
+	assert.Equal(t, "msg=ut app=ut req.id=1 req.status=200\n", buf.String())
+
+	buf, err = encoder.EncodeEntry(zapcore.Entry{Message: "ut"}, []zapcore.Field{zap.Int("status", 200)})
+	assert.Nil(t, err)
+	assert.Equal(t, "msg=ut app=ut status=200\n", buf.String())
+}
+
+// With encoding in config file
+func TestNewZapLoggerWithBytes_WithLogfmt(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	raw := []byte(fmt.Sprintf(`---
+level: info
+encoding: logfmt
+encoderConfig:
+  messageKey: msg
+  levelKey: level
+  levelEncoder: lowercase
+outputPaths: ["%s"]
+`, filePath))
+
+	logger, _, err := NewZapLoggerWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger.With(zap.String("app", "ut")).Info("ut message", zap.Int("count", 1))
+	assert.Equal(t, "level=info msg=\"ut message\" app=ut count=1\n", readFileContent(filePath))
+}
+
+func BenchmarkLogfmtEncoder(b *testing.B) {
+	enc, _ := NewLogfmtEncoder(zap.NewProductionEncoderConfig())
+	benchmarkEncoder(b, enc)
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"sort"
+)
+
+// DefaultLoggerName is the name of section in config file of logger map
+// which is inherited by other loggers.
+const DefaultLoggerName = "default"
+
+// NewConfigMapWithBytes parses a map of logger names to combined configs with byte array from content of config file
+//
+// Each section at the root of config file is a config of logger either in combined format or legacy format,
+// every section inherits from the section named default, nested maps are merged and the rest of values are overridden.
+// Example in YAML:
+//
+//	default:
+//	  zap:
+//	    level: info
+//	    outputPaths: ["stdout"]
+//	db:
+//	  zap:
+//	    level: debug
+func NewConfigMapWithBytes(raw []byte, fileType FileType) (map[string]*Config, error) {
+	return defaultLoader.NewConfigMapWithBytes(raw, fileType)
+}
+
+// NewConfigMapWithBytes parses a map of logger names to combined configs with options of Loader
+func (loader *Loader) NewConfigMapWithBytes(raw []byte, fileType FileType) (map[string]*Config, error) {
+	if raw == nil {
+		return nil, errors.New("input byte array is nil")
+	}
+
+	if len(raw) == 0 {
+		return nil, errors.New("byte array is empty")
+	}
+
+	raw = loader.preprocess(raw)
+
+	if loader.strict {
+		if err := validateKnownKeysOfMap(raw, fileType); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := decodeWithFileType(raw, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	base := make(map[string]interface{})
+	if value, ok := content[DefaultLoggerName]; ok {
+		if base, ok = value.(map[string]interface{}); !ok {
+			return nil, errors.Errorf("invalid logger config, name:%s", DefaultLoggerName)
+		}
+	}
+
+	res := make(map[string]*Config)
+	for _, name := range sortedKeys(content) {
+		section, ok := content[name].(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("invalid logger config, name:%s", name)
+		}
+
+		// merged content is parsed as json which is shared by all of the file types
+		bytes, err := json.Marshal(mergeConfigMaps(base, section))
+		if err != nil {
+			return nil, err
+		}
+
+		config, err := parseConfig(bytes, JSON)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse logger config, name:%s", name)
+		}
+
+		res[name] = config
+	}
+
+	return res, nil
+}
+
+// NewConfigMapWithConfPath parses a map of logger names to combined configs with config file path
+func NewConfigMapWithConfPath(filePath string, fileType FileType) (map[string]*Config, error) {
+	return defaultLoader.NewConfigMapWithConfPath(filePath, fileType)
+}
+
+// NewConfigMapWithConfPath parses a map of logger names to combined configs with config file path and options of Loader
+func (loader *Loader) NewConfigMapWithConfPath(filePath string, fileType FileType) (map[string]*Config, error) {
+	raw, err := readConfigFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.NewConfigMapWithBytes(raw, fileType)
+}
+
+// NewZapLoggerMapWithBytes inits a map of logger names to zap loggers with byte array from content of config file
+// See NewConfigMapWithBytes for details of config file
+func NewZapLoggerMapWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (map[string]*zap.Logger, error) {
+	return defaultLoader.NewZapLoggerMapWithBytes(raw, fileType, opts...)
+}
+
+// NewZapLoggerMapWithBytes inits a map of logger names to zap loggers with options of Loader
+func (loader *Loader) NewZapLoggerMapWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (map[string]*zap.Logger, error) {
+	configs, err := loader.NewConfigMapWithBytes(raw, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.newZapLoggerMap(configs, opts...)
+}
+
+// NewZapLoggerMapWithConfPath inits a map of logger names to zap loggers with config file path
+// See NewConfigMapWithBytes for details of config file
+func NewZapLoggerMapWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (map[string]*zap.Logger, error) {
+	return defaultLoader.NewZapLoggerMapWithConfPath(filePath, fileType, opts...)
+}
+
+// NewZapLoggerMapWithConfPath inits a map of logger names to zap loggers with config file path and options of Loader
+func (loader *Loader) NewZapLoggerMapWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (map[string]*zap.Logger, error) {
+	configs, err := loader.NewConfigMapWithConfPath(filePath, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	return loader.newZapLoggerMap(configs, opts...)
+}
+
+// Build zap loggers in ascending order of names, so that the same error would be returned every time
+func (loader *Loader) newZapLoggerMap(configs map[string]*Config, opts ...zap.Option) (map[string]*zap.Logger, error) {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	res := make(map[string]*zap.Logger, len(configs))
+	for _, name := range names {
+		logger, err := loader.buildZapLoggerWithConfig(configs[name], opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to build logger, name:%s", name)
+		}
+
+		res[name] = logger
+	}
+
+	// register levels once all of the loggers are built
+	for _, name := range names {
+		loader.levels.RegisterConfig(name, configs[name])
+	}
+
+	return res, nil
+}
+
+// Keys of map in ascending order
+func sortedKeys(content map[string]interface{}) []string {
+	res := make([]string, 0, len(content))
+	for k := range content {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// Merge override into a copy of base recursively, nested maps are merged and the rest of values are replaced
+func mergeConfigMaps(base, override map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		res[k] = v
+	}
+
+	for k, v := range override {
+		baseMap, baseOk := res[k].(map[string]interface{})
+		overrideMap, overrideOk := v.(map[string]interface{})
+
+		if baseOk && overrideOk {
+			res[k] = mergeConfigMaps(baseMap, overrideMap)
+		} else {
+			res[k] = v
+		}
+	}
+
+	return res
+}
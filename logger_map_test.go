@@ -0,0 +1,203 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"os"
+	"testing"
+)
+
+// With nil byte array
+func TestNewConfigMapWithBytes_WithNilByteArray(t *testing.T) {
+	configs, err := NewConfigMapWithBytes(nil, YAML)
+	assert.Nil(t, configs)
+	assert.NotNil(t, err)
+}
+
+// With empty byte array
+func TestNewConfigMapWithBytes_WithEmptyByteArray(t *testing.T) {
+	configs, err := NewConfigMapWithBytes([]byte{}, YAML)
+	assert.Nil(t, configs)
+	assert.NotNil(t, err)
+}
+
+// With invalid content
+func TestNewConfigMapWithBytes_WithInvalidContent(t *testing.T) {
+	configs, err := NewConfigMapWithBytes([]byte(`{"app":`), JSON)
+	assert.Nil(t, configs)
+	assert.NotNil(t, err)
+}
+
+// With invalid default section
+func TestNewConfigMapWithBytes_WithInvalidDefaultSection(t *testing.T) {
+	configs, err := NewConfigMapWithBytes([]byte(`{"default": "invalid"}`), JSON)
+	assert.Nil(t, configs)
+	assert.NotNil(t, err)
+}
+
+// With invalid logger section
+func TestNewConfigMapWithBytes_WithInvalidSection(t *testing.T) {
+	configs, err := NewConfigMapWithBytes([]byte(`{"app": ["invalid"]}`), JSON)
+	assert.Nil(t, configs)
+	assert.NotNil(t, err)
+
+	configs, err = NewConfigMapWithBytes([]byte(`{"app": {"zap": {"level": "invalid"}}}`), JSON)
+	assert.Nil(t, configs)
+	assert.Contains(t, err.Error(), "name:app")
+}
+
+// Without default section
+func TestNewConfigMapWithBytes_WithoutDefaultSection(t *testing.T) {
+	configs, err := NewConfigMapWithBytes([]byte(`{"app": {"level": "warn"}}`), JSON)
+	assert.Nil(t, err)
+	assert.Len(t, configs, 1)
+	assert.Equal(t, zap.WarnLevel, configs["app"].Zap.Level.Level())
+}
+
+// With legacy format of sections
+func TestNewConfigMapWithBytes_WithLegacySections(t *testing.T) {
+	raw := []byte(`
+default {
+  level = "info"
+  outputPaths = ["stdout"]
+  maxsize = 10
+}
+
+app {
+  level = "error"
+}
+`)
+	configs, err := NewConfigMapWithBytes(raw, HCL)
+	assert.Nil(t, err)
+	assert.Len(t, configs, 2)
+	assert.Equal(t, zap.ErrorLevel, configs["app"].Zap.Level.Level())
+	assert.Equal(t, []string{"stdout"}, configs["app"].Zap.OutputPaths)
+	assert.Equal(t, 10, configs["app"].Lumberjack.MaxSize)
+}
+
+// Happy case
+func TestNewConfigMapWithConfPath_HappyCase(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	configs, err := NewConfigMapWithConfPath(dir+"/assets/loggers.yaml", YAML)
+	assert.Nil(t, err)
+	assert.Len(t, configs, 3)
+
+	// default section is a logger as well
+	assert.Equal(t, zap.InfoLevel, configs["default"].Zap.Level.Level())
+	assert.Equal(t, "msg", configs["default"].Zap.EncoderConfig.MessageKey)
+
+	// inherited from default section
+	assert.Equal(t, zap.InfoLevel, configs["app"].Zap.Level.Level())
+	assert.Equal(t, []string{"stdout"}, configs["app"].Zap.OutputPaths)
+	assert.Equal(t, "app", configs["app"].Zap.InitialFields["logger"])
+	assert.Equal(t, 1024, configs["app"].Lumberjack.MaxSize)
+
+	// overridden
+	assert.Equal(t, zap.DebugLevel, configs["db"].Zap.Level.Level())
+	assert.Equal(t, "message", configs["db"].Zap.EncoderConfig.MessageKey)
+	assert.Equal(t, "level", configs["db"].Zap.EncoderConfig.LevelKey)
+	assert.Equal(t, 7, configs["db"].Lumberjack.MaxAge)
+}
+
+// With empty file path
+func TestNewConfigMapWithConfPath_WithEmptyString(t *testing.T) {
+	configs, err := NewConfigMapWithConfPath("", YAML)
+	assert.Nil(t, configs)
+	assert.NotNil(t, err)
+}
+
+// With strict parsing
+func TestLoader_NewConfigMapWithBytes_WithStrictParsing(t *testing.T) {
+	raw := []byte(`
+default:
+  zap:
+    level: info
+app:
+  zap:
+    outputPath: ["stdout"]
+db:
+  lvl: debug
+`)
+	configs, err := NewLoader(WithStrictParsing()).NewConfigMapWithBytes(raw, YAML)
+	assert.Nil(t, configs)
+	assert.Equal(t, []UnknownKey{
+		{Path: "app.zap.outputPath", Line: 7},
+		{Path: "db.lvl", Line: 9},
+	}, err.(*UnknownKeysError).Keys)
+}
+
+// With env expansion
+func TestLoader_NewConfigMapWithBytes_WithEnvExpansion(t *testing.T) {
+	raw := []byte(`{"default": {"zap": {"level": "${RK_LOGGER_UT_LEVEL:-warn}"}}}`)
+	configs, err := NewLoader(WithEnvExpansion()).NewConfigMapWithBytes(raw, JSON)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.WarnLevel, configs["default"].Zap.Level.Level())
+}
+
+// With invalid logger config
+func TestNewZapLoggerMapWithBytes_WithInvalidSection(t *testing.T) {
+	loggers, err := NewZapLoggerMapWithBytes([]byte(`{"app": {"zap": {"level": "info"}}, "db": {"lumberjack": {}}}`), JSON)
+	assert.Nil(t, loggers)
+	assert.NotNil(t, err)
+}
+
+// Happy case
+func TestNewZapLoggerMapWithBytes_HappyCase(t *testing.T) {
+	loggers, err := NewZapLoggerMapWithBytes([]byte(`{"default": {"level": "info", "outputPaths": ["stdout"]}, "app": {}}`), JSON)
+	assert.Nil(t, err)
+	assert.Len(t, loggers, 2)
+	assert.NotNil(t, loggers["default"])
+	assert.NotNil(t, loggers["app"])
+}
+
+// With non exist file path
+func TestNewZapLoggerMapWithConfPath_WithNonExistFilePath(t *testing.T) {
+	loggers, err := NewZapLoggerMapWithConfPath("/NonExistExpected.invalid", YAML)
+	assert.Nil(t, loggers)
+	assert.NotNil(t, err)
+}
+
+// Happy case
+func TestNewZapLoggerMapWithConfPath_HappyCase(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	loggers, err := NewZapLoggerMapWithConfPath(dir+"/assets/loggers.yaml", YAML)
+	assert.Nil(t, err)
+	assert.Len(t, loggers, 3)
+	assert.True(t, loggers["db"].Core().Enabled(zap.DebugLevel))
+	assert.False(t, loggers["app"].Core().Enabled(zap.DebugLevel))
+}
+
+func TestMergeConfigMaps(t *testing.T) {
+	base := map[string]interface{}{
+		"level":       "info",
+		"outputPaths": []interface{}{"stdout"},
+		"encoderConfig": map[string]interface{}{
+			"messageKey": "msg",
+			"levelKey":   "level",
+		},
+	}
+	override := map[string]interface{}{
+		"outputPaths": []interface{}{"stderr"},
+		"encoderConfig": map[string]interface{}{
+			"messageKey": "message",
+		},
+	}
+
+	res := mergeConfigMaps(base, override)
+	assert.Equal(t, "info", res["level"])
+	assert.Equal(t, []interface{}{"stderr"}, res["outputPaths"])
+	assert.Equal(t, map[string]interface{}{"messageKey": "message", "levelKey": "level"}, res["encoderConfig"])
+
+	// base is not modified
+	assert.Equal(t, "msg", base["encoderConfig"].(map[string]interface{})["messageKey"])
+}
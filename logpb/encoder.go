@@ -0,0 +1,265 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package logpb registers protobuf encoding with rklogger.RegisterEncoder, so that entries are encoded as LogEntry
+// messages of logentry.proto and parsed by downstream consumers with generated code, by config like:
+//
+//	encoding: protobuf
+//	outputPaths: ["kafka://broker:9092/app-logs"]
+//
+// Import the package for side effects in order to enable the encoding:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/logpb"
+//
+// Each entry is written with a varint length prefix, which is the same as writeDelimitedTo() of Java, and read
+// back with ReadEntry(). Schema of LogEntry is fixed, so keys and encoders of encoderConfig are not applied.
+//
+// Run protoc in the root of repository after logentry.proto is changed:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative logpb/logentry.proto
+package logpb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Encoding is the name of encoding which encodes entries as length-prefixed LogEntry messages.
+const Encoding = "protobuf"
+
+var (
+	// pool of buffers returned by encoder
+	bufferPool = buffer.NewPool()
+	// keys of maps are sorted so that the same entry is always encoded the same way
+	marshalOptions = proto.MarshalOptions{Deterministic: true}
+)
+
+func init() {
+	if err := rklogger.RegisterEncoder(Encoding, NewEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// encoder encodes entries as LogEntry messages, fields are collected by wrapped map encoder and converted afterwards
+type encoder struct {
+	*zapcore.MapObjectEncoder
+	// keys of open namespaces, which fields are added to
+	namespaces []string
+}
+
+// NewEncoder creates encoder of LogEntry messages, encoder config is ignored since schema of LogEntry is fixed.
+func NewEncoder(zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return &encoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+	}, nil
+}
+
+// OpenNamespace implements zapcore.ObjectEncoder
+func (enc *encoder) OpenNamespace(key string) {
+	enc.MapObjectEncoder.OpenNamespace(key)
+	enc.namespaces = append(enc.namespaces, key)
+}
+
+// Clone implements zapcore.Encoder
+func (enc *encoder) Clone() zapcore.Encoder {
+	return enc.clone()
+}
+
+// Copy encoder with fields, maps of open namespaces are copied since fields are added to them, maps of objects
+// are shared since they are never changed
+func (enc *encoder) clone() *encoder {
+	clone := &encoder{
+		MapObjectEncoder: zapcore.NewMapObjectEncoder(),
+		namespaces:       append([]string{}, enc.namespaces...),
+	}
+
+	src, dst := enc.Fields, clone.Fields
+	for _, ns := range enc.namespaces {
+		for k, v := range src {
+			if k != ns {
+				dst[k] = v
+			}
+		}
+
+		clone.MapObjectEncoder.OpenNamespace(ns)
+		src, dst = src[ns].(map[string]interface{}), dst[ns].(map[string]interface{})
+	}
+
+	for k, v := range src {
+		dst[k] = v
+	}
+
+	return clone
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *encoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+
+	msg := &LogEntry{
+		TimeUnixNano: entry.Time.UnixNano(),
+		Level:        rklogger.LevelName(entry.Level),
+		LoggerName:   entry.LoggerName,
+		Message:      entry.Message,
+		Stacktrace:   entry.Stack,
+		Fields:       toFields(final.Fields),
+	}
+	if entry.Caller.Defined {
+		msg.Caller = entry.Caller.TrimmedPath()
+		msg.Function = entry.Caller.Function
+	}
+
+	raw := protowire.AppendVarint(nil, uint64(marshalOptions.Size(msg)))
+	raw, err := marshalOptions.MarshalAppend(raw, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufferPool.Get()
+	buf.Write(raw)
+	return buf, nil
+}
+
+// ReadEntry reads a length-prefixed LogEntry written by protobuf encoding, io.EOF is returned if there is no more
+// entry.
+func ReadEntry(r *bufio.Reader) (*LogEntry, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// buffer grows with data read so that corrupted length does not allocate at once
+	raw := &bytes.Buffer{}
+	if _, err := io.CopyN(raw, r, int64(size)); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	res := &LogEntry{}
+	if err := proto.Unmarshal(raw.Bytes(), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Convert fields encoded by zapcore.MapObjectEncoder to fields of LogEntry
+func toFields(fields map[string]interface{}) map[string]*Value {
+	res := make(map[string]*Value, len(fields))
+	for k, v := range fields {
+		res[k] = toValue(v)
+	}
+	return res
+}
+
+// Convert value encoded by zapcore.MapObjectEncoder to value of field, reflected values are converted with json
+// so that they are encoded the same way as json encoding
+func toValue(value interface{}) *Value {
+	switch v := value.(type) {
+	case nil:
+		return &Value{}
+	case string:
+		return &Value{Kind: &Value_StringValue{StringValue: v}}
+	case bool:
+		return &Value{Kind: &Value_BoolValue{BoolValue: v}}
+	case int:
+		return &Value{Kind: &Value_IntValue{IntValue: int64(v)}}
+	case int8:
+		return &Value{Kind: &Value_IntValue{IntValue: int64(v)}}
+	case int16:
+		return &Value{Kind: &Value_IntValue{IntValue: int64(v)}}
+	case int32:
+		return &Value{Kind: &Value_IntValue{IntValue: int64(v)}}
+	case int64:
+		return &Value{Kind: &Value_IntValue{IntValue: v}}
+	case uint:
+		return &Value{Kind: &Value_UintValue{UintValue: uint64(v)}}
+	case uint8:
+		return &Value{Kind: &Value_UintValue{UintValue: uint64(v)}}
+	case uint16:
+		return &Value{Kind: &Value_UintValue{UintValue: uint64(v)}}
+	case uint32:
+		return &Value{Kind: &Value_UintValue{UintValue: uint64(v)}}
+	case uint64:
+		return &Value{Kind: &Value_UintValue{UintValue: v}}
+	case uintptr:
+		return &Value{Kind: &Value_UintValue{UintValue: uint64(v)}}
+	case float32:
+		return &Value{Kind: &Value_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &Value{Kind: &Value_DoubleValue{DoubleValue: v}}
+	case complex64:
+		return &Value{Kind: &Value_StringValue{StringValue: formatComplex(complex128(v))}}
+	case complex128:
+		return &Value{Kind: &Value_StringValue{StringValue: formatComplex(v)}}
+	case []byte:
+		return &Value{Kind: &Value_BytesValue{BytesValue: v}}
+	case time.Time:
+		return &Value{Kind: &Value_TimeUnixNano{TimeUnixNano: v.UnixNano()}}
+	case time.Duration:
+		return &Value{Kind: &Value_DurationNano{DurationNano: int64(v)}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &Value{Kind: &Value_IntValue{IntValue: i}}
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return &Value{Kind: &Value_UintValue{UintValue: u}}
+		}
+		f, _ := v.Float64()
+		return &Value{Kind: &Value_DoubleValue{DoubleValue: f}}
+	case map[string]interface{}:
+		return &Value{Kind: &Value_ObjectValue{ObjectValue: &ObjectValue{Fields: toFields(v)}}}
+	case []interface{}:
+		values := make([]*Value, 0, len(v))
+		for i := range v {
+			values = append(values, toValue(v[i]))
+		}
+		return &Value{Kind: &Value_ArrayValue{ArrayValue: &ArrayValue{Values: values}}}
+	default:
+		return toReflectedValue(v)
+	}
+}
+
+// Convert reflected value with json, value which could not be encoded with json is formatted as string
+func toReflectedValue(value interface{}) *Value {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return &Value{Kind: &Value_StringValue{StringValue: fmt.Sprint(value)}}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return &Value{Kind: &Value_StringValue{StringValue: fmt.Sprint(value)}}
+	}
+
+	return toValue(decoded)
+}
+
+// Format complex number like 1+2i, which is the same as json encoding
+func formatComplex(value complex128) string {
+	r, i := real(value), imag(value)
+	res := strconv.FormatFloat(r, 'g', -1, 64)
+	if i >= 0 {
+		res += "+"
+	}
+	return res + strconv.FormatFloat(i, 'g', -1, 64) + "i"
+}
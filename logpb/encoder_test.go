@@ -0,0 +1,158 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package logpb
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// Encode entry with encoder and read it back
+func encodeEntry(t *testing.T, enc zapcore.Encoder, entry zapcore.Entry, fields ...zapcore.Field) *LogEntry {
+	buf, err := enc.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	defer buf.Free()
+
+	r := bufio.NewReader(bytes.NewReader(buf.Bytes()))
+	res, err := ReadEntry(r)
+	assert.Nil(t, err)
+
+	_, err = ReadEntry(r)
+	assert.Equal(t, io.EOF, err)
+	return res
+}
+
+func TestEncoder_EncodeEntry(t *testing.T) {
+	enc, err := NewEncoder(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	res := encodeEntry(t, enc, zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       time.Unix(1, 500),
+		LoggerName: "db",
+		Message:    "failed to query",
+		Caller:     zapcore.EntryCaller{Defined: true, File: "/src/db/conn.go", Line: 42, Function: "db.Query"},
+		Stack:      "stack",
+	},
+		zap.Error(errors.New("timeout")),
+		zap.Int("retries", -3),
+		zap.Uint8("attempt", 2),
+		zap.Float32("ratio", 0.5),
+		zap.Bool("ok", false),
+		zap.Binary("raw", []byte{0x01}),
+		zap.Complex128("c", complex(1, -2)),
+		zap.Time("deadline", time.Unix(2, 0)),
+		zap.Duration("elapsed", time.Second),
+		zap.Strings("tags", []string{"a"}),
+		zap.Any("meta", map[string]interface{}{"k": nil, "n": 1}),
+		zap.Reflect("ch", make(chan int)),
+	)
+
+	assert.Equal(t, int64(1000000500), res.TimeUnixNano)
+	assert.Equal(t, "error", res.Level)
+	assert.Equal(t, "db", res.LoggerName)
+	assert.Equal(t, "db/conn.go:42", res.Caller)
+	assert.Equal(t, "db.Query", res.Function)
+	assert.Equal(t, "failed to query", res.Message)
+	assert.Equal(t, "stack", res.Stacktrace)
+
+	// reflected value which could not be encoded with json is formatted
+	assert.NotEmpty(t, res.Fields["ch"].GetStringValue())
+	delete(res.Fields, "ch")
+
+	assert.True(t, proto.Equal(&LogEntry{Fields: map[string]*Value{
+		"error":    {Kind: &Value_StringValue{StringValue: "timeout"}},
+		"retries":  {Kind: &Value_IntValue{IntValue: -3}},
+		"attempt":  {Kind: &Value_UintValue{UintValue: 2}},
+		"ratio":    {Kind: &Value_DoubleValue{DoubleValue: 0.5}},
+		"ok":       {Kind: &Value_BoolValue{BoolValue: false}},
+		"raw":      {Kind: &Value_BytesValue{BytesValue: []byte{0x01}}},
+		"c":        {Kind: &Value_StringValue{StringValue: "1-2i"}},
+		"deadline": {Kind: &Value_TimeUnixNano{TimeUnixNano: 2000000000}},
+		"elapsed":  {Kind: &Value_DurationNano{DurationNano: 1000000000}},
+		"tags": {Kind: &Value_ArrayValue{ArrayValue: &ArrayValue{Values: []*Value{
+			{Kind: &Value_StringValue{StringValue: "a"}},
+		}}}},
+		"meta": {Kind: &Value_ObjectValue{ObjectValue: &ObjectValue{Fields: map[string]*Value{
+			"k": {},
+			"n": {Kind: &Value_IntValue{IntValue: 1}},
+		}}}},
+	}}, &LogEntry{Fields: res.Fields}))
+}
+
+func TestEncoder_WithNamespace(t *testing.T) {
+	enc, _ := NewEncoder(zapcore.EncoderConfig{})
+	enc.AddString("app", "ut")
+	enc.OpenNamespace("ctx")
+	enc.AddInt("a", 1)
+
+	clone := enc.Clone()
+	clone.AddInt("b", 2)
+
+	// fields added to clone are not added to encoder
+	res := encodeEntry(t, enc, zapcore.Entry{}, zap.Int("c", 3))
+	assert.Equal(t, "ut", res.Fields["app"].GetStringValue())
+	ctx := res.Fields["ctx"].GetObjectValue().GetFields()
+	assert.Len(t, ctx, 2)
+	assert.Equal(t, int64(1), ctx["a"].GetIntValue())
+	assert.Equal(t, int64(3), ctx["c"].GetIntValue())
+
+	res = encodeEntry(t, clone, zapcore.Entry{})
+	ctx = res.Fields["ctx"].GetObjectValue().GetFields()
+	assert.Len(t, ctx, 2)
+	assert.Equal(t, int64(2), ctx["b"].GetIntValue())
+
+	// entries are not added to encoder
+	assert.Len(t, enc.(*encoder).Fields["ctx"], 1)
+}
+
+func TestReadEntry_WithError(t *testing.T) {
+	// With truncated message
+	_, err := ReadEntry(bufio.NewReader(bytes.NewReader([]byte{0x05, 0x08})))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// With invalid message
+	_, err = ReadEntry(bufio.NewReader(bytes.NewReader([]byte{0x02, 0xff, 0xff})))
+	assert.NotNil(t, err)
+}
+
+func TestEncoding_WithConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "logpb")
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "ut.log")
+
+	logger, _, err := rklogger.NewZapLoggerWithBytes([]byte(`---
+level: info
+encoding: protobuf
+outputPaths: ["`+filePath+`"]
+`), rklogger.YAML)
+	assert.Nil(t, err)
+	logger.Info("first", zap.String("k", "v"))
+	logger.Info("second")
+	logger.Sync()
+
+	content, _ := ioutil.ReadFile(filePath)
+	r := bufio.NewReader(bytes.NewReader(content))
+	for _, msg := range []string{"first", "second"} {
+		res, err := ReadEntry(r)
+		assert.Nil(t, err)
+		assert.Equal(t, msg, res.Message)
+		assert.Equal(t, "info", res.Level)
+	}
+	_, err = ReadEntry(r)
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package logr adapts zap logger to logr.Logger, so that Kubernetes operators built on controller-runtime could be
+// configured through rk-logger config files:
+//
+//	logger, _, _ := rklogger.NewZapLoggerWithConfPath("/etc/app/zap.yaml", rklogger.YAML)
+//	ctrl.SetLogger(logr.ToLogr(logger))
+//
+// V-levels of logr are mapped to zap levels by LevelMapper, V(0) is info, V(1) is debug and V(n) is zap level -n
+// by default, so that entries above V(1) are written only if level of zap config is set to a negative number.
+// Key and value pairs are mapped to fields and values implementing logr.Marshaler are logged with MarshalLog.
+package logr
+
+import (
+	"fmt"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+)
+
+const (
+	// DefaultErrorKey is the key of field of errors logged by Error.
+	DefaultErrorKey = "error"
+	// key of field of invalid key and value pairs
+	invalidKey = "ignored"
+)
+
+// LevelMapper maps V-level of logr, which is zero or positive, to zap level.
+type LevelMapper func(v int) zapcore.Level
+
+// Option is the option of ToLogr.
+type Option func(*sink)
+
+// WithLevelMapper maps V-levels with mapper instead of DefaultLevelMapper.
+func WithLevelMapper(mapper LevelMapper) Option {
+	return func(sink *sink) {
+		if mapper != nil {
+			sink.mapper = mapper
+		}
+	}
+}
+
+// WithErrorKey overrides key of field of errors logged by Error, which is error by default.
+func WithErrorKey(key string) Option {
+	return func(sink *sink) {
+		if len(key) > 0 {
+			sink.errorKey = key
+		}
+	}
+}
+
+// DefaultLevelMapper maps V(n) to zap level -n, like V(0) to info and V(1) to debug.
+func DefaultLevelMapper(v int) zapcore.Level {
+	if v > -math.MinInt8 {
+		return zapcore.Level(math.MinInt8)
+	}
+
+	return zapcore.Level(-v)
+}
+
+// LevelsByVerbosity maps V(n) to levels[n], V-levels beyond levels are mapped to the last one, like
+// LevelsByVerbosity(zapcore.InfoLevel, zapcore.DebugLevel) maps V(0) to info and the others to debug.
+// DefaultLevelMapper is returned if levels are empty.
+func LevelsByVerbosity(levels ...zapcore.Level) LevelMapper {
+	if len(levels) == 0 {
+		return DefaultLevelMapper
+	}
+
+	res := make([]zapcore.Level, len(levels))
+	copy(res, levels)
+	return func(v int) zapcore.Level {
+		if v >= len(res) {
+			return res[len(res)-1]
+		}
+
+		if v < 0 {
+			return res[0]
+		}
+
+		return res[v]
+	}
+}
+
+// ToLogr creates logr.Logger which writes entries to zap logger, names of logr are appended to name of logger.
+func ToLogr(logger *zap.Logger, opts ...Option) logr.Logger {
+	sink := &sink{
+		logger:   logger,
+		mapper:   DefaultLevelMapper,
+		errorKey: DefaultErrorKey,
+	}
+
+	for _, opt := range opts {
+		opt(sink)
+	}
+
+	return logr.New(sink)
+}
+
+// sink implements logr.LogSink and logr.CallDepthLogSink with zap logger
+type sink struct {
+	logger   *zap.Logger
+	mapper   LevelMapper
+	errorKey string
+}
+
+// Init implements logr.LogSink, frames of logr.Logger and sink are skipped by callers
+func (sink *sink) Init(info logr.RuntimeInfo) {
+	sink.logger = sink.logger.WithOptions(zap.AddCallerSkip(info.CallDepth + 1))
+}
+
+// Enabled implements logr.LogSink
+func (sink *sink) Enabled(level int) bool {
+	return sink.logger.Core().Enabled(sink.mapper(level))
+}
+
+// Info implements logr.LogSink
+func (sink *sink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if checked := sink.logger.Check(sink.mapper(level), msg); checked != nil {
+		checked.Write(toFields(keysAndValues)...)
+	}
+}
+
+// Error implements logr.LogSink, entries are logged with error level regardless of V-level
+func (sink *sink) Error(err error, msg string, keysAndValues ...interface{}) {
+	checked := sink.logger.Check(zapcore.ErrorLevel, msg)
+	if checked == nil {
+		return
+	}
+
+	fields := toFields(keysAndValues)
+	if err != nil {
+		fields = append([]zapcore.Field{zap.NamedError(sink.errorKey, err)}, fields...)
+	}
+
+	checked.Write(fields...)
+}
+
+// WithValues implements logr.LogSink
+func (sink *sink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	res := *sink
+	res.logger = sink.logger.With(toFields(keysAndValues)...)
+	return &res
+}
+
+// WithName implements logr.LogSink
+func (sink *sink) WithName(name string) logr.LogSink {
+	res := *sink
+	res.logger = sink.logger.Named(name)
+	return &res
+}
+
+// WithCallDepth implements logr.CallDepthLogSink
+func (sink *sink) WithCallDepth(depth int) logr.LogSink {
+	res := *sink
+	res.logger = sink.logger.WithOptions(zap.AddCallerSkip(depth))
+	return &res
+}
+
+// Convert key and value pairs to fields, pairs with non-string keys and the last key without value are logged
+// with ignored key instead of being dropped silently
+func toFields(keysAndValues []interface{}) []zapcore.Field {
+	if len(keysAndValues) == 0 {
+		return nil
+	}
+
+	fields := make([]zapcore.Field, 0, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i == len(keysAndValues)-1 {
+			fields = append(fields, zap.Any(invalidKey, keysAndValues[i]))
+			break
+		}
+
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			fields = append(fields, zap.String(invalidKey, fmt.Sprintf("%v=%v", keysAndValues[i], keysAndValues[i+1])))
+			continue
+		}
+
+		value := keysAndValues[i+1]
+		if marshaler, ok := value.(logr.Marshaler); ok {
+			value = marshaler.MarshalLog()
+		}
+
+		if err, ok := value.(error); ok {
+			fields = append(fields, zap.NamedError(key, err))
+		} else {
+			fields = append(fields, zap.Any(key, value))
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package logr
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"strings"
+	"testing"
+)
+
+// Create zap logger writing entries of level and above to buffer with json encoding
+func newTestLogger(level zapcore.Level, opts ...zap.Option) (*zap.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	encoderConfig := zapcore.EncoderConfig{
+		MessageKey:   "msg",
+		LevelKey:     "level",
+		NameKey:      "logger",
+		CallerKey:    "caller",
+		EncodeLevel:  zapcore.LowercaseLevelEncoder,
+		EncodeCaller: zapcore.ShortCallerEncoder,
+	}
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(buf), level)
+	return zap.New(core, opts...), buf
+}
+
+// utMarshaler implements logr.Marshaler
+type utMarshaler struct{}
+
+func (utMarshaler) MarshalLog() interface{} {
+	return "ut-marshaled"
+}
+
+func TestDefaultLevelMapper(t *testing.T) {
+	assert.Equal(t, zapcore.InfoLevel, DefaultLevelMapper(0))
+	assert.Equal(t, zapcore.DebugLevel, DefaultLevelMapper(1))
+	assert.Equal(t, zapcore.Level(-4), DefaultLevelMapper(4))
+	assert.Equal(t, zapcore.Level(math.MinInt8), DefaultLevelMapper(1000))
+}
+
+func TestLevelsByVerbosity(t *testing.T) {
+	mapper := LevelsByVerbosity(zapcore.WarnLevel, zapcore.InfoLevel)
+	assert.Equal(t, zapcore.WarnLevel, mapper(0))
+	assert.Equal(t, zapcore.InfoLevel, mapper(1))
+	assert.Equal(t, zapcore.InfoLevel, mapper(5))
+	assert.Equal(t, zapcore.WarnLevel, mapper(-1))
+
+	// With empty levels
+	assert.Equal(t, zapcore.DebugLevel, LevelsByVerbosity()(1))
+}
+
+func TestToLogr_HappyCase(t *testing.T) {
+	logger, buf := newTestLogger(zapcore.DebugLevel)
+	logrLogger := ToLogr(logger).WithName("ut").WithValues("app", "ut-app")
+
+	assert.True(t, logrLogger.V(1).Enabled())
+	assert.False(t, logrLogger.V(2).Enabled())
+
+	logrLogger.Info("ut-info", "count", 1, "marshaler", utMarshaler{}, "cause", errors.New("ut-cause"))
+	logrLogger.V(1).Info("ut-debug")
+	logrLogger.V(2).Info("ut-ignored")
+	logrLogger.Error(errors.New("ut-error"), "ut-failed", "key", "value")
+	logrLogger.V(2).Error(nil, "ut-nil-error")
+	logrLogger.Info("ut-invalid", 1, "one", "dangling")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		`{"level":"info","logger":"ut","msg":"ut-info","app":"ut-app","count":1,"marshaler":"ut-marshaled","cause":"ut-cause"}`,
+		`{"level":"debug","logger":"ut","msg":"ut-debug","app":"ut-app"}`,
+		`{"level":"error","logger":"ut","msg":"ut-failed","app":"ut-app","error":"ut-error","key":"value"}`,
+		`{"level":"error","logger":"ut","msg":"ut-nil-error","app":"ut-app"}`,
+		`{"level":"info","logger":"ut","msg":"ut-invalid","app":"ut-app","ignored":"1=one","ignored":"dangling"}`,
+	}, lines)
+}
+
+func TestToLogr_WithOptions(t *testing.T) {
+	logger, buf := newTestLogger(zapcore.InfoLevel)
+	logrLogger := ToLogr(logger,
+		WithLevelMapper(LevelsByVerbosity(zapcore.WarnLevel, zapcore.InfoLevel, zapcore.DebugLevel)),
+		WithErrorKey("err"))
+
+	logrLogger.Info("ut-warn")
+	logrLogger.V(1).Info("ut-info")
+	logrLogger.V(2).Info("ut-ignored")
+	logrLogger.Error(errors.New("ut-error"), "ut-failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		`{"level":"warn","msg":"ut-warn"}`,
+		`{"level":"info","msg":"ut-info"}`,
+		`{"level":"error","msg":"ut-failed","err":"ut-error"}`,
+	}, lines)
+}
+
+func TestToLogr_WithCaller(t *testing.T) {
+	logger, buf := newTestLogger(zapcore.InfoLevel, zap.AddCaller())
+	logrLogger := ToLogr(logger)
+
+	logrLogger.Info("ut-caller")
+	assert.Contains(t, buf.String(), `"caller":"logr/logr_test.go:`)
+	assert.NotContains(t, buf.String(), "logr.go")
+
+	buf.Reset()
+	logrLogger.WithCallDepth(1).Info("ut-helper")
+	assert.Contains(t, buf.String(), `"caller":"testing/testing.go:`)
+}
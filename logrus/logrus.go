@@ -0,0 +1,179 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package logrus forwards entries of logrus to zap logger with fields and levels, so that code bases using logrus
+// could migrate to rk-logger sink first, without rewriting call sites.
+//
+// Hook forwards entries along with outputs of logrus, while Redirect replaces outputs of logrus with zap logger:
+//
+//	logger, _, _ := rklogger.NewZapLoggerWithConfPath("/etc/app/zap.yaml", rklogger.YAML)
+//	logrus.Redirect(logrus.StandardLogger(), logger)
+//
+// Trace level of logrus is mapped to rklogger.TraceLevel and the others are mapped to levels of the same names.
+// Panic and fatal entries are written without panicking or exiting, which are left to logrus. Callers of entries
+// are the ones reported by logrus, or the first frames out of logrus if logrus does not report callers.
+package logrus
+
+import (
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	// package of logrus whose frames are skipped by callers
+	logrusPackage = "github.com/sirupsen/logrus."
+	// frames of runtime.Callers, caller, forward and Fire or Format
+	callerSkip = 4
+	// max number of frames searched for callers
+	maxCallerDepth = 25
+)
+
+// Hook is logrus.Hook which forwards entries to zap logger.
+type Hook struct {
+	logger *zap.Logger
+	levels []logrus.Level
+}
+
+// NewHook creates Hook which forwards entries of levels to zap logger, entries of all levels are forwarded
+// if levels are empty. Add it to logrus logger with AddHook.
+func NewHook(logger *zap.Logger, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+
+	return &Hook{logger: logger, levels: levels}
+}
+
+// Levels implements logrus.Hook
+func (hook *Hook) Levels() []logrus.Level {
+	return hook.levels
+}
+
+// Fire implements logrus.Hook
+func (hook *Hook) Fire(entry *logrus.Entry) error {
+	forward(hook.logger, entry)
+	return nil
+}
+
+// Formatter is logrus.Formatter which forwards entries to zap logger and formats them as nothing, so that outputs
+// of logrus are replaced with zap logger.
+type Formatter struct {
+	logger *zap.Logger
+}
+
+// NewFormatter creates Formatter which forwards entries to zap logger.
+func NewFormatter(logger *zap.Logger) *Formatter {
+	return &Formatter{logger: logger}
+}
+
+// Format implements logrus.Formatter
+func (formatter *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	forward(formatter.logger, entry)
+	return nil, nil
+}
+
+// Redirect replaces formatter of target with Formatter and discards output of it, level of target is set to trace
+// so that levels are decided by zap logger.
+func Redirect(target *logrus.Logger, logger *zap.Logger) {
+	target.SetFormatter(NewFormatter(logger))
+	target.SetOutput(ioutil.Discard)
+	target.SetLevel(logrus.TraceLevel)
+}
+
+// ToZapLevel maps level of logrus to zap level.
+func ToZapLevel(level logrus.Level) zapcore.Level {
+	switch level {
+	case logrus.PanicLevel:
+		return zapcore.PanicLevel
+	case logrus.FatalLevel:
+		return zapcore.FatalLevel
+	case logrus.ErrorLevel:
+		return zapcore.ErrorLevel
+	case logrus.WarnLevel:
+		return zapcore.WarnLevel
+	case logrus.InfoLevel:
+		return zapcore.InfoLevel
+	case logrus.DebugLevel:
+		return zapcore.DebugLevel
+	default:
+		return rklogger.TraceLevel
+	}
+}
+
+// Write entry of logrus with zap logger
+func forward(logger *zap.Logger, entry *logrus.Entry) {
+	level := ToZapLevel(entry.Level)
+	checked := logger.Check(level, entry.Message)
+	if checked == nil {
+		return
+	}
+
+	// write panic and fatal entries with core, so that zap logger would neither panic nor exit
+	if level > zapcore.ErrorLevel {
+		if checked = logger.Core().Check(checked.Entry, nil); checked == nil {
+			return
+		}
+	}
+
+	if !entry.Time.IsZero() {
+		checked.Entry.Time = entry.Time
+	}
+
+	if checked.Entry.Caller.Defined {
+		checked.Entry.Caller = caller(entry)
+	}
+
+	checked.Write(toFields(entry.Data)...)
+}
+
+// Caller reported by logrus, or the first frame out of logrus
+func caller(entry *logrus.Entry) zapcore.EntryCaller {
+	if entry.HasCaller() {
+		return zapcore.NewEntryCaller(entry.Caller.PC, entry.Caller.File, entry.Caller.Line, true)
+	}
+
+	pcs := make([]uintptr, maxCallerDepth)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(callerSkip, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, logrusPackage) {
+			return zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, frame.PC != 0)
+		}
+
+		if !more {
+			return zapcore.EntryCaller{}
+		}
+	}
+}
+
+// Convert data of entry to fields sorted by keys, errors are logged as errors
+func toFields(data logrus.Fields) []zapcore.Field {
+	if len(data) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]zapcore.Field, 0, len(keys))
+	for _, key := range keys {
+		if err, ok := data[key].(error); ok {
+			fields = append(fields, zap.NamedError(key, err))
+		} else {
+			fields = append(fields, zap.Any(key, data[key]))
+		}
+	}
+
+	return fields
+}
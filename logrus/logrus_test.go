@@ -0,0 +1,117 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package logrus
+
+import (
+	"bytes"
+	"errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Create logrus logger writing to buffer with text formatter
+func newLogrusLogger() (*logrus.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	return logger, buf
+}
+
+func TestToZapLevel(t *testing.T) {
+	assert.Equal(t, zapcore.PanicLevel, ToZapLevel(logrus.PanicLevel))
+	assert.Equal(t, zapcore.FatalLevel, ToZapLevel(logrus.FatalLevel))
+	assert.Equal(t, zapcore.ErrorLevel, ToZapLevel(logrus.ErrorLevel))
+	assert.Equal(t, zapcore.WarnLevel, ToZapLevel(logrus.WarnLevel))
+	assert.Equal(t, zapcore.InfoLevel, ToZapLevel(logrus.InfoLevel))
+	assert.Equal(t, zapcore.DebugLevel, ToZapLevel(logrus.DebugLevel))
+	assert.Equal(t, rklogger.TraceLevel, ToZapLevel(logrus.TraceLevel))
+}
+
+func TestHook_HappyCase(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger, buf := newLogrusLogger()
+	logger.AddHook(NewHook(zap.New(observed, zap.AddCaller()).Named("ut")))
+
+	at := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	logger.WithFields(logrus.Fields{"user": "ut-user", "count": 1}).WithTime(at).Warn("ut-warn")
+	logger.WithError(errors.New("ut-error")).Error("ut-failed")
+	logger.Debug("ut-ignored")
+
+	// logrus writes entries as well
+	assert.Contains(t, buf.String(), "ut-warn")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, "ut", entries[0].LoggerName)
+	assert.Equal(t, "ut-warn", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"user": "ut-user", "count": int64(1)}, entries[0].ContextMap())
+	assert.Equal(t, "logrus_test.go", filepath.Base(entries[0].Caller.File))
+	assert.Equal(t, map[string]interface{}{"error": "ut-error"}, entries[1].ContextMap())
+	assert.Equal(t, at, logs.All()[0].Time)
+}
+
+func TestHook_WithLevels(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger, _ := newLogrusLogger()
+	hook := NewHook(zap.New(observed), logrus.ErrorLevel)
+	logger.AddHook(hook)
+
+	assert.Equal(t, []logrus.Level{logrus.ErrorLevel}, hook.Levels())
+	assert.Equal(t, logrus.AllLevels, NewHook(zap.NewNop()).Levels())
+
+	logger.Warn("ut-warn")
+	logger.Error("ut-error")
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, "ut-error", logs.All()[0].Message)
+}
+
+func TestHook_WithPanic(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger, _ := newLogrusLogger()
+	logger.AddHook(NewHook(zap.New(observed)))
+
+	// panic is raised by logrus with entry instead of zap with message
+	func() {
+		defer func() {
+			_, ok := recover().(*logrus.Entry)
+			assert.True(t, ok)
+		}()
+		logger.Panic("ut-panic")
+	}()
+
+	assert.Equal(t, 1, logs.Len())
+	assert.Equal(t, zapcore.PanicLevel, logs.All()[0].Level)
+}
+
+func TestRedirect(t *testing.T) {
+	observed, logs := observer.New(rklogger.TraceLevel)
+	logger, buf := newLogrusLogger()
+	logger.SetReportCaller(true)
+	Redirect(logger, zap.New(observed, zap.AddCaller()))
+
+	logger.WithField("key", "value").Trace("ut-trace")
+	logger.Info("ut-info")
+
+	assert.Empty(t, buf.String())
+	assert.Equal(t, 2, logs.Len())
+	assert.Equal(t, rklogger.TraceLevel, logs.All()[0].Level)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, logs.All()[0].ContextMap())
+	assert.Equal(t, "logrus_test.go", filepath.Base(logs.All()[1].Caller.File))
+
+	// With disabled level
+	observed, logs = observer.New(zapcore.InfoLevel)
+	Redirect(logger, zap.New(observed))
+	logger.Debug("ut-ignored")
+	assert.Equal(t, 0, logs.Len())
+}
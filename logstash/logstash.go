@@ -0,0 +1,159 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package logstash registers logstash encoding with rklogger.RegisterEncoder, so that entries are encoded as
+// Logstash v1 json events, which is the format of logstash-logback-encoder, by config like:
+//
+//	encoding: logstash
+//	outputPaths:
+//	  - tcp://logstash.example.com:5000
+//
+// Import the package for side effects in order to enable the encoding:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/logstash"
+//
+// Entries are encoded like:
+//
+//	{"@timestamp":"2020-09-01T18:00:00.000+08:00","@version":"1","message":"failed to query","logger_name":"db",
+//	 "level":"ERROR","level_value":40000,"caller_file_name":"db/conn.go","caller_line_number":42,
+//	 "stack_trace":"...","user_id":"1"}
+//
+// Fields are kept at top level, fields named by keys of events are overwritten by entries.
+package logstash
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/rookie-ninja/rk-logger"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Encoding is the name of encoding which encodes entries as Logstash v1 events.
+	Encoding = "logstash"
+	// Version is the value of @version of events.
+	Version = "1"
+
+	// layout of @timestamp, which is in time zone of entries with milliseconds
+	timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// pool of buffers returned by encoder
+var bufferPool = buffer.NewPool()
+
+func init() {
+	if err := rklogger.RegisterEncoder(Encoding, NewEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// encoder encodes entries as Logstash v1 events, fields are encoded by wrapped json encoder and added afterwards
+type encoder struct {
+	zapcore.Encoder
+	lineEnding string
+}
+
+// NewEncoder creates encoder of Logstash v1 events with encoder config, only duration, time and line ending
+// settings are applied, keys of message, level, time, logger name, caller and stacktrace are defined by Logstash.
+func NewEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	lineEnding := config.LineEnding
+	if len(lineEnding) == 0 {
+		lineEnding = zapcore.DefaultLineEnding
+	}
+
+	// entries are encoded by Logstash, wrapped encoder encodes fields only
+	config.MessageKey, config.LevelKey, config.TimeKey = "", "", ""
+	config.NameKey, config.CallerKey, config.StacktraceKey = "", "", ""
+	config.FunctionKey = ""
+	if config.EncodeTime == nil {
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+	}
+	if config.EncodeDuration == nil {
+		config.EncodeDuration = zapcore.StringDurationEncoder
+	}
+
+	return &encoder{
+		Encoder:    zapcore.NewJSONEncoder(config),
+		lineEnding: lineEnding,
+	}, nil
+}
+
+// Clone implements zapcore.Encoder
+func (enc *encoder) Clone() zapcore.Encoder {
+	return &encoder{
+		Encoder:    enc.Encoder.Clone(),
+		lineEnding: enc.lineEnding,
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *encoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := enc.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded.Bytes()))
+	decoder.UseNumber()
+	event := make(map[string]interface{})
+	if err := decoder.Decode(&event); err != nil {
+		return nil, err
+	}
+
+	event["@timestamp"] = formatTimestamp(entry.Time)
+	event["@version"] = Version
+	event["message"] = entry.Message
+	event["level"] = strings.ToUpper(rklogger.LevelName(entry.Level))
+	event["level_value"] = levelValue(entry.Level)
+	if len(entry.LoggerName) > 0 {
+		event["logger_name"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		event["caller_file_name"] = strings.TrimSuffix(entry.Caller.TrimmedPath(), ":"+strconv.Itoa(entry.Caller.Line))
+		event["caller_line_number"] = entry.Caller.Line
+		if len(entry.Caller.Function) > 0 {
+			event["caller_method_name"] = entry.Caller.Function
+		}
+	}
+	if len(entry.Stack) > 0 {
+		event["stack_trace"] = entry.Stack
+	}
+
+	res, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufferPool.Get()
+	buf.Write(res)
+	buf.AppendString(enc.lineEnding)
+	return buf, nil
+}
+
+// Value of level of logback, levels above error are mapped to error which is the highest level of logback
+func levelValue(level zapcore.Level) int {
+	switch {
+	case level < zapcore.DebugLevel:
+		return 5000
+	case level == zapcore.DebugLevel:
+		return 10000
+	case level == zapcore.InfoLevel:
+		return 20000
+	case level == zapcore.WarnLevel:
+		return 30000
+	default:
+		return 40000
+	}
+}
+
+// Format time as @timestamp of Logstash, which is ISO8601 with milliseconds
+func formatTimestamp(t time.Time) string {
+	return t.Format(timestampLayout)
+}
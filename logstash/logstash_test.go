@@ -0,0 +1,114 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package logstash
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Encode entry with logstash encoder and decode the event
+func encodeEvent(t *testing.T, enc zapcore.Encoder, entry zapcore.Entry, fields ...zapcore.Field) map[string]interface{} {
+	buf, err := enc.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+	defer buf.Free()
+
+	assert.Equal(t, byte('\n'), buf.Bytes()[buf.Len()-1])
+
+	event := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &event))
+	return event
+}
+
+func TestEncoder_EncodeEntry(t *testing.T) {
+	enc, err := NewEncoder(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	entry := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       time.Date(2020, 9, 1, 18, 0, 0, 123456789, time.FixedZone("ut", 8*3600)),
+		LoggerName: "db",
+		Message:    "ut-message",
+		Caller:     zapcore.NewEntryCaller(0, "/src/db/conn.go", 42, true),
+		Stack:      "ut-stack",
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"@timestamp":         "2020-09-01T18:00:00.123+08:00",
+		"@version":           Version,
+		"message":            "ut-message",
+		"level":              "ERROR",
+		"level_value":        float64(40000),
+		"logger_name":        "db",
+		"caller_file_name":   "db/conn.go",
+		"caller_line_number": float64(42),
+		"stack_trace":        "ut-stack",
+		"user_id":            "ut-user",
+		"attempt":            float64(2),
+		"request":            map[string]interface{}{"path": "/"},
+	}, encodeEvent(t, enc, entry,
+		zap.String("user_id", "ut-user"),
+		zap.Int("attempt", 2),
+		zap.Any("request", map[string]string{"path": "/"}),
+		zap.String("message", "ut-overwritten"),
+	))
+}
+
+func TestLevelValue(t *testing.T) {
+	assert.Equal(t, 10000, levelValue(zapcore.DebugLevel))
+	assert.Equal(t, 20000, levelValue(zapcore.InfoLevel))
+	assert.Equal(t, 30000, levelValue(zapcore.WarnLevel))
+	assert.Equal(t, 40000, levelValue(zapcore.ErrorLevel))
+	assert.Equal(t, 40000, levelValue(zapcore.FatalLevel))
+}
+
+// Fields added with With() are encoded as well
+func TestEncoder_Clone(t *testing.T) {
+	enc, err := NewEncoder(zapcore.EncoderConfig{LineEnding: "\r\n"})
+	assert.Nil(t, err)
+	enc.AddString("app", "ut")
+
+	buf, err := enc.Clone().EncodeEntry(zapcore.Entry{Message: "ut-message"}, nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(buf.String(), "\r\n"))
+
+	event := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "ut", event["app"])
+	assert.Equal(t, "INFO", event["level"])
+	assert.NotContains(t, event, "logger_name")
+}
+
+// Encoding is registered with rklogger
+func TestRegister(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "logstash")
+	defer os.RemoveAll(dir)
+	filePath := path.Join(dir, "ut.log")
+
+	raw := []byte(fmt.Sprintf(`---
+level: info
+encoding: logstash
+outputPaths: ["%s"]
+`, filePath))
+
+	logger, _, err := rklogger.NewZapLoggerWithBytes(raw, rklogger.YAML)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `"@version":"1"`)
+	assert.Contains(t, string(content), `"message":"ut-message"`)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"net/url"
+	"strconv"
+)
+
+// LumberjackScheme is the scheme of zap sink which writes to file rotated by lumberjack.
+//
+// The sink is registered with zap.RegisterSink, so that it works with zap.Config.Build() as well, like:
+//
+//	outputPaths: ["lumberjack:///var/log/app.log?maxSize=100&maxAge=7&maxBackups=3&localTime=true&compress=true"]
+//
+// Path of url must be absolute. Query parameters are the same as fields of lumberjack.Logger,
+// size is in megabytes and age is in days.
+const LumberjackScheme = "lumberjack"
+
+func init() {
+	if err := zap.RegisterSink(LumberjackScheme, newLumberjackSink); err != nil {
+		panic(err)
+	}
+}
+
+// lumberjackSink implements zap.Sink with lumberjack.Logger
+type lumberjackSink struct {
+	*lumberjack.Logger
+}
+
+// Sync implements zap.Sink, lumberjack.Logger writes to file without buffering
+func (sink *lumberjackSink) Sync() error {
+	return nil
+}
+
+// Create lumberjack sink with url
+func newLumberjackSink(u *url.URL) (zap.Sink, error) {
+	lumber, err := parseLumberjackURL(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lumberjackSink{Logger: lumber}, nil
+}
+
+// Parse rotation settings of lumberjack from url
+func parseLumberjackURL(u *url.URL) (*lumberjack.Logger, error) {
+	if len(u.Host) > 0 && u.Host != "localhost" {
+		return nil, errors.Errorf("host is not supported in lumberjack url, url:%s", u.String())
+	}
+
+	if len(u.Path) == 0 {
+		return nil, errors.Errorf("path is missing in lumberjack url, url:%s", u.String())
+	}
+
+	lumber := &lumberjack.Logger{
+		Filename: fromFileURLPath(u.Path),
+	}
+
+	for key, values := range u.Query() {
+		value := values[len(values)-1]
+
+		var err error
+		switch key {
+		case "maxSize":
+			lumber.MaxSize, err = strconv.Atoi(value)
+		case "maxAge":
+			lumber.MaxAge, err = strconv.Atoi(value)
+		case "maxBackups":
+			lumber.MaxBackups, err = strconv.Atoi(value)
+		case "localTime":
+			lumber.LocalTime, err = strconv.ParseBool(value)
+		case "compress":
+			lumber.Compress, err = strconv.ParseBool(value)
+		default:
+			return nil, errors.Errorf("unknown query of lumberjack url, key:%s", key)
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid query of lumberjack url, key:%s", key)
+		}
+	}
+
+	return lumber, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Convert file path to lumberjack url with query
+func toLumberjackURL(filePath, query string) string {
+	res := LumberjackScheme + ":///" + strings.TrimPrefix(filepath.ToSlash(filePath), "/")
+	if len(query) > 0 {
+		res += "?" + query
+	}
+
+	return res
+}
+
+func TestParseLumberjackURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("lumberjack:///var/log/app.log?maxSize=100&maxAge=7&maxBackups=3&localTime=true&compress=true")
+	lumber, err := parseLumberjackURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.FromSlash("/var/log/app.log"), lumber.Filename)
+	assert.Equal(t, 100, lumber.MaxSize)
+	assert.Equal(t, 7, lumber.MaxAge)
+	assert.Equal(t, 3, lumber.MaxBackups)
+	assert.True(t, lumber.LocalTime)
+	assert.True(t, lumber.Compress)
+}
+
+// Without query
+func TestParseLumberjackURL_WithoutQuery(t *testing.T) {
+	u, _ := url.Parse("lumberjack://localhost/var/log/app.log")
+	lumber, err := parseLumberjackURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.FromSlash("/var/log/app.log"), lumber.Filename)
+	assert.Zero(t, lumber.MaxSize)
+	assert.False(t, lumber.Compress)
+}
+
+// With invalid url
+func TestParseLumberjackURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"lumberjack://host/app.log",
+		"lumberjack://",
+		"lumberjack:///app.log?maxSize=large",
+		"lumberjack:///app.log?compress=maybe",
+		"lumberjack:///app.log?unknown=1",
+	} {
+		u, _ := url.Parse(raw)
+		lumber, err := parseLumberjackURL(u)
+		assert.Nil(t, lumber, raw)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+// Lumberjack sink works with vanilla zap config
+func TestLumberjackSink_WithZapConfig(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "logs", "ut.log")
+	config := NewZapStdoutConfig()
+	config.OutputPaths = []string{toLumberjackURL(filePath, "maxSize=1&compress=true")}
+
+	logger, err := config.Build()
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Nil(t, logger.Sync())
+	assert.Contains(t, readFileContent(filePath), "ut-message")
+}
+
+// Lumberjack sink works with combined config as well
+func TestLumberjackSink_WithCombinedConfig(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, closer, err := NewZapLoggerWithCloser(NewConfigWithOptions(WithOutput(toLumberjackURL(filePath, ""))))
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.Contains(t, readFileContent(filePath), "ut-message")
+}
+
+// With invalid query in output path
+func TestLumberjackSink_WithInvalidQuery(t *testing.T) {
+	config := NewZapStdoutConfig()
+	config.OutputPaths = []string{"lumberjack:///ut.log?maxSize=large"}
+
+	logger, err := config.Build()
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
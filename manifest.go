@@ -0,0 +1,276 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestExt is extension of manifests written by sign hook of rotation, like app-2020-09-01.log.zst.manifest.
+const ManifestExt = ".manifest"
+
+// Algorithms of keys signing manifests
+const (
+	ManifestKeyEd25519 = "ed25519"
+	ManifestKeyECDSA   = "ecdsa"
+	ManifestKeyRSA     = "rsa"
+)
+
+// algorithms of checksums in manifests, md5 and sha1 are not collision resistant and could not be signed
+var manifestAlgorithms = map[string]bool{
+	"sha256": true,
+	"sha512": true,
+}
+
+// RotationManifest is a detached manifest of rotated file written by sign hook of rotation in json, so that
+// integrity of archived files could be verified with public key by VerifyRotationManifest.
+//
+// Signature is signed over json of manifest without signature, with ed25519, or ECDSA and RSA PKCS#1 v1.5 over
+// its SHA-256 digest.
+type RotationManifest struct {
+	// File is name of rotated file in the same directory as manifest.
+	File string `json:"file"`
+	// Size is size of file in bytes.
+	Size int64 `json:"size"`
+	// Algorithm is sha256 or sha512 of checksum.
+	Algorithm string `json:"algorithm"`
+	// Checksum is checksum of file in hex.
+	Checksum string `json:"checksum"`
+	// SignedAt is time when manifest is signed.
+	SignedAt time.Time `json:"signedAt"`
+	// KeyAlgorithm is ed25519, ecdsa or rsa.
+	KeyAlgorithm string `json:"keyAlgorithm"`
+	// Signature is base64 encoded signature of manifest.
+	Signature string `json:"signature"`
+}
+
+// Create hook which writes manifest of file signed with private key into file with .manifest extension
+func newSignRotationHook(config *RotationHookConfig) (RotationHook, error) {
+	algorithm := strings.ToLower(config.Algorithm)
+	if len(algorithm) == 0 {
+		algorithm = "sha256"
+	}
+
+	if !manifestAlgorithms[algorithm] {
+		return nil, errors.Errorf("invalid algorithm of sign, algorithm:%s", config.Algorithm)
+	}
+
+	if len(config.KeyFile) == 0 {
+		return nil, errors.New("key file of sign is empty")
+	}
+
+	raw, err := ioutil.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read key file of sign, keyFile:%s", config.KeyFile)
+	}
+
+	signer, err := parseManifestSigner(raw)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key file of sign, keyFile:%s", config.KeyFile)
+	}
+
+	return func(ctx context.Context, path string) (string, error) {
+		manifest, err := newRotationManifest(path, algorithm)
+		if err != nil {
+			return "", err
+		}
+
+		if err := manifest.sign(signer); err != nil {
+			return "", err
+		}
+
+		content, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return "", err
+		}
+
+		return path, ioutil.WriteFile(path+ManifestExt, append(content, '\n'), 0644)
+	}, nil
+}
+
+// Parse PEM encoded private key in PKCS#8, SEC 1 or PKCS#1
+func parseManifestSigner(raw []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM block is found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case ed25519.PrivateKey, *ecdsa.PrivateKey, *rsa.PrivateKey:
+		return key.(crypto.Signer), nil
+	default:
+		return nil, errors.Errorf("type of private key is not supported, type:%T", key)
+	}
+}
+
+// Create manifest of file with checksum of algorithm
+func newRotationManifest(path, algorithm string) (*RotationManifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	h := checksumHashes[algorithm]()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RotationManifest{
+		File:      filepath.Base(path),
+		Size:      size,
+		Algorithm: algorithm,
+		Checksum:  hex.EncodeToString(h.Sum(nil)),
+		SignedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// Json of manifest without signature, which is signed
+func (manifest *RotationManifest) payload() ([]byte, error) {
+	unsigned := *manifest
+	unsigned.Signature = ""
+	return json.Marshal(&unsigned)
+}
+
+// Sign manifest with private key
+func (manifest *RotationManifest) sign(signer crypto.Signer) error {
+	switch signer.(type) {
+	case ed25519.PrivateKey:
+		manifest.KeyAlgorithm = ManifestKeyEd25519
+	case *ecdsa.PrivateKey:
+		manifest.KeyAlgorithm = ManifestKeyECDSA
+	default:
+		manifest.KeyAlgorithm = ManifestKeyRSA
+	}
+
+	payload, err := manifest.payload()
+	if err != nil {
+		return err
+	}
+
+	var signature []byte
+	if manifest.KeyAlgorithm == ManifestKeyEd25519 {
+		signature, err = signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(payload)
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to sign manifest")
+	}
+
+	manifest.Signature = base64.StdEncoding.EncodeToString(signature)
+	return nil
+}
+
+// VerifyRotationManifest verifies signature of manifest written by sign hook of rotation with PEM encoded public key
+// in PKIX, and checksum and size of file named in manifest, which is in the same directory as manifest.
+// Manifest is returned if both of them are valid.
+func VerifyRotationManifest(manifestPath string, publicKeyPEM []byte) (*RotationManifest, error) {
+	raw, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read manifest, path:%s", manifestPath)
+	}
+
+	manifest := &RotationManifest{}
+	if err := json.Unmarshal(raw, manifest); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse manifest, path:%s", manifestPath)
+	}
+
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM block of public key is found")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse public key")
+	}
+
+	if err := manifest.verify(publicKey); err != nil {
+		return nil, errors.Wrapf(err, "invalid signature of manifest, path:%s", manifestPath)
+	}
+
+	if !manifestAlgorithms[manifest.Algorithm] {
+		return nil, errors.Errorf("invalid algorithm of manifest, path:%s, algorithm:%s", manifestPath, manifest.Algorithm)
+	}
+
+	actual, err := newRotationManifest(filepath.Join(filepath.Dir(manifestPath), manifest.File), manifest.Algorithm)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read file of manifest, path:%s", manifestPath)
+	}
+
+	if actual.Size != manifest.Size || actual.Checksum != manifest.Checksum {
+		return nil, errors.Errorf("file does not match manifest, path:%s, file:%s", manifestPath, manifest.File)
+	}
+
+	return manifest, nil
+}
+
+// Verify signature of manifest with public key
+func (manifest *RotationManifest) verify(publicKey crypto.PublicKey) error {
+	signature, err := base64.StdEncoding.DecodeString(manifest.Signature)
+	if err != nil {
+		return err
+	}
+
+	payload, err := manifest.payload()
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(payload)
+	valid := false
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		valid = manifest.KeyAlgorithm == ManifestKeyEd25519 && ed25519.Verify(key, payload, signature)
+	case *ecdsa.PublicKey:
+		valid = manifest.KeyAlgorithm == ManifestKeyECDSA && ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		valid = manifest.KeyAlgorithm == ManifestKeyRSA && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return errors.Errorf("type of public key is not supported, type:%T", publicKey)
+	}
+
+	if !valid {
+		return errors.New("signature does not match public key")
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Write PEM encoded private key into temp directory, PEM encoded public key is returned
+func writeSigningKey(t *testing.T, dir, name string, key crypto.Signer) []byte {
+	var block *pem.Block
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		assert.Nil(t, err)
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+	case *rsa.PrivateKey:
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		assert.Nil(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, name), pem.EncodeToMemory(block), 0600))
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestRotationHook_WithSign(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	for algorithm, key := range map[string]crypto.Signer{
+		ManifestKeyEd25519: edKey,
+		ManifestKeyECDSA:   ecKey,
+		ManifestKeyRSA:     rsaKey,
+	} {
+		filePath := writeRotatedFile(t, "ut-content")
+		publicKey := writeSigningKey(t, path.Dir(filePath), "key.pem", key)
+
+		hook := newTestRotationHook(t, &RotationHookConfig{Action: RotationHookSign, KeyFile: path.Join(path.Dir(filePath), "key.pem")})
+		res, err := hook(context.Background(), filePath)
+		assert.Nil(t, err)
+		assert.Equal(t, filePath, res)
+
+		manifest, err := VerifyRotationManifest(filePath+ManifestExt, publicKey)
+		assert.Nil(t, err, algorithm)
+		assert.Equal(t, "app-2020-09-01.log", manifest.File)
+		assert.Equal(t, int64(10), manifest.Size)
+		assert.Equal(t, "sha256", manifest.Algorithm)
+		assert.Equal(t, "d5f61cac53d5aa50ed92e73e761c9e9b38b7a750b40b9d61aadb65179eae7bf4", manifest.Checksum)
+		assert.Equal(t, algorithm, manifest.KeyAlgorithm)
+
+		// With another key
+		_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+		_, err = VerifyRotationManifest(filePath+ManifestExt, writeSigningKey(t, path.Dir(filePath), "other.pem", otherKey))
+		assert.NotNil(t, err)
+	}
+}
+
+func TestVerifyRotationManifest_WithTampering(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	filePath := writeRotatedFile(t, "ut-content")
+	publicKey := writeSigningKey(t, path.Dir(filePath), "key.pem", key)
+
+	hook := newTestRotationHook(t, &RotationHookConfig{Action: RotationHookSign, Algorithm: "SHA512", KeyFile: path.Join(path.Dir(filePath), "key.pem")})
+	_, err := hook(context.Background(), filePath)
+	assert.Nil(t, err)
+
+	manifestPath := filePath + ManifestExt
+	content := readFileContent(manifestPath)
+
+	// modified manifest
+	manifest := &RotationManifest{}
+	assert.Nil(t, json.Unmarshal([]byte(content), manifest))
+	manifest.Size = 11
+	raw, _ := json.Marshal(manifest)
+	assert.Nil(t, ioutil.WriteFile(manifestPath, raw, 0644))
+	_, err = VerifyRotationManifest(manifestPath, publicKey)
+	assert.NotNil(t, err)
+
+	// modified file
+	assert.Nil(t, ioutil.WriteFile(manifestPath, []byte(content), 0644))
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte("ut-forged!"), 0644))
+	_, err = VerifyRotationManifest(manifestPath, publicKey)
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "does not match manifest"))
+
+	// invalid inputs
+	_, err = VerifyRotationManifest(manifestPath, []byte("ut-invalid"))
+	assert.NotNil(t, err)
+	_, err = VerifyRotationManifest(path.Join(path.Dir(filePath), "missing"), publicKey)
+	assert.NotNil(t, err)
+	assert.Nil(t, ioutil.WriteFile(manifestPath, []byte("{"), 0644))
+	_, err = VerifyRotationManifest(manifestPath, publicKey)
+	assert.NotNil(t, err)
+}
+
+func TestNewSignRotationHook_WithInvalidConfigs(t *testing.T) {
+	dir := newTempDir(t)
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	writeSigningKey(t, dir, "key.pem", key)
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "invalid.pem"), []byte("ut-invalid"), 0600))
+
+	for _, config := range []*RotationHookConfig{
+		{Action: RotationHookSign},
+		{Action: RotationHookSign, KeyFile: path.Join(dir, "missing.pem")},
+		{Action: RotationHookSign, KeyFile: path.Join(dir, "invalid.pem")},
+		{Action: RotationHookSign, KeyFile: path.Join(dir, "key.pem"), Algorithm: "crc"},
+		{Action: RotationHookSign, KeyFile: path.Join(dir, "key.pem"), Algorithm: "md5"},
+		{Action: RotationHookSign, KeyFile: path.Join(dir, "key.pem"), Algorithm: "sha1"},
+	} {
+		_, err := newSignRotationHook(config)
+		assert.NotNil(t, err)
+	}
+
+	// missing file
+	hook, err := newSignRotationHook(&RotationHookConfig{Action: RotationHookSign, KeyFile: path.Join(dir, "key.pem")})
+	assert.Nil(t, err)
+	_, err = hook(context.Background(), path.Join(dir, "missing.log"))
+	assert.NotNil(t, err)
+}
+
+func TestLoader_ResolveHookFiles(t *testing.T) {
+	dir := newTempDir(t)
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	writeSigningKey(t, dir, "key.pem", key)
+	loader := NewLoader(WithBaseDir(dir))
+
+	rotation := &RotationConfig{Hooks: []*RotationHookConfig{
+		{Action: RotationHookGzip},
+		{Action: RotationHookSign, KeyFile: "key.pem"},
+	}}
+	resolved, err := loader.resolveHookFiles(rotation)
+	assert.Nil(t, err)
+	assert.Equal(t, path.Join(dir, "key.pem"), resolved.Hooks[1].KeyFile)
+	assert.Equal(t, rotation.Hooks[0], resolved.Hooks[0])
+	// config is left untouched
+	assert.Equal(t, "key.pem", rotation.Hooks[1].KeyFile)
+
+	_, err = newSignRotationHook(resolved.Hooks[1])
+	assert.Nil(t, err)
+
+	// without key files
+	rotation = &RotationConfig{Hooks: []*RotationHookConfig{{Action: RotationHookGzip}}}
+	resolved, err = loader.resolveHookFiles(rotation)
+	assert.Nil(t, err)
+	assert.True(t, rotation == resolved)
+
+	resolved, err = loader.resolveHookFiles(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, resolved)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"encoding/binary"
+	"go.uber.org/zap/zapcore"
+	"math"
+)
+
+// MsgpackEncoding is the name of encoding which encodes entries as MessagePack maps.
+//
+// Keys of entries and encoders of encoderConfig are applied the same way as json encoding, entries are not
+// followed by line ending since MessagePack values are self-delimiting. Binary fields are encoded as bin
+// instead of base64 strings.
+const MsgpackEncoding = "msgpack"
+
+func init() {
+	if err := RegisterEncoder(MsgpackEncoding, NewMsgpackEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// NewMsgpackEncoder creates encoder of MessagePack with encoder config.
+func NewMsgpackEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return newBinaryEncoder(&config, msgpackFormat{}), nil
+}
+
+// msgpackFormat implements binaryFormat with MessagePack
+type msgpackFormat struct{}
+
+func (msgpackFormat) appendMapHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(buf, 0xde), uint16(n))
+	default:
+		return appendUint32(append(buf, 0xdf), uint32(n))
+	}
+}
+
+func (msgpackFormat) appendArrayHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(buf, 0xdc), uint16(n))
+	default:
+		return appendUint32(append(buf, 0xdd), uint32(n))
+	}
+}
+
+func (msgpackFormat) appendString(buf []byte, value string) []byte {
+	switch n := len(value); {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = appendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = appendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, value...)
+}
+
+func (msgpackFormat) appendBytes(buf []byte, value []byte) []byte {
+	switch n := len(value); {
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		buf = appendUint16(append(buf, 0xc5), uint16(n))
+	default:
+		buf = appendUint32(append(buf, 0xc6), uint32(n))
+	}
+	return append(buf, value...)
+}
+
+func (f msgpackFormat) appendInt(buf []byte, value int64) []byte {
+	switch {
+	case value >= 0:
+		return f.appendUint(buf, uint64(value))
+	case value >= -32:
+		return append(buf, byte(value))
+	case value >= math.MinInt8:
+		return append(buf, 0xd0, byte(value))
+	case value >= math.MinInt16:
+		return appendUint16(append(buf, 0xd1), uint16(value))
+	case value >= math.MinInt32:
+		return appendUint32(append(buf, 0xd2), uint32(value))
+	default:
+		return appendUint64(append(buf, 0xd3), uint64(value))
+	}
+}
+
+func (msgpackFormat) appendUint(buf []byte, value uint64) []byte {
+	switch {
+	case value < 128:
+		return append(buf, byte(value))
+	case value <= math.MaxUint8:
+		return append(buf, 0xcc, byte(value))
+	case value <= math.MaxUint16:
+		return appendUint16(append(buf, 0xcd), uint16(value))
+	case value <= math.MaxUint32:
+		return appendUint32(append(buf, 0xce), uint32(value))
+	default:
+		return appendUint64(append(buf, 0xcf), value)
+	}
+}
+
+func (msgpackFormat) appendFloat64(buf []byte, value float64) []byte {
+	return appendUint64(append(buf, 0xcb), math.Float64bits(value))
+}
+
+func (msgpackFormat) appendFloat32(buf []byte, value float32) []byte {
+	return appendUint32(append(buf, 0xca), math.Float32bits(value))
+}
+
+func (msgpackFormat) appendBool(buf []byte, value bool) []byte {
+	if value {
+		return append(buf, 0xc3)
+	}
+	return append(buf, 0xc2)
+}
+
+func (msgpackFormat) appendNil(buf []byte) []byte {
+	return append(buf, 0xc0)
+}
+
+// Append big endian integers which are shared by binary formats
+func appendUint16(buf []byte, value uint16) []byte {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], value)
+	return append(buf, b[:]...)
+}
+
+func appendUint32(buf []byte, value uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], value)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, value uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], value)
+	return append(buf, b[:]...)
+}
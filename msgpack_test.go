@@ -0,0 +1,115 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNewMsgpackEncoder(t *testing.T) {
+	enc, err := NewMsgpackEncoder(zap.NewProductionEncoderConfig())
+	assert.Nil(t, err)
+
+	entry, fields := sampleEntry()
+	fields = append(fields, zap.Binary("raw", []byte{0x01, 0x02}), zap.Any("meta", map[string]int{"retries": 3}))
+
+	buf, err := enc.EncodeEntry(entry, fields)
+	assert.Nil(t, err)
+
+	res := make(map[string]interface{})
+	assert.Nil(t, msgpack.Unmarshal(buf.Bytes(), &res))
+	assert.Equal(t, map[string]interface{}{
+		"level":   "info",
+		"ts":      1598954400.0,
+		"logger":  "db",
+		"caller":  "db/conn.go:42",
+		"msg":     "connection is ready",
+		"host":    "10.0.0.1",
+		"port":    uint16(5432),
+		"pool":    int8(10),
+		"elapsed": 1.5,
+		"tls":     true,
+		"ratio":   0.75,
+		"tags":    []interface{}{"primary", "eu-west-1"},
+		"user":    map[string]interface{}{"name": "admin", "id": uint16(1024)},
+		"raw":     []byte{0x01, 0x02},
+		"meta":    map[string]interface{}{"retries": int8(3)},
+	}, res)
+}
+
+func TestMsgpackFormat(t *testing.T) {
+	format := msgpackFormat{}
+
+	for _, value := range []int64{0, 127, 128, 255, 256, 65535, 65536, math.MaxUint32 + 1, -1, -32, -33, -128, -129,
+		-32768, -32769, math.MinInt32 - 1, math.MinInt64, math.MaxInt64} {
+		var res int64
+		assert.Nil(t, msgpack.Unmarshal(format.appendInt(nil, value), &res))
+		assert.Equal(t, value, res)
+	}
+
+	var u uint64
+	assert.Nil(t, msgpack.Unmarshal(format.appendUint(nil, math.MaxUint64), &u))
+	assert.Equal(t, uint64(math.MaxUint64), u)
+
+	for _, n := range []int{0, 31, 32, 255, 256, 65536} {
+		value := strings.Repeat("a", n)
+		var res string
+		assert.Nil(t, msgpack.Unmarshal(format.appendString(nil, value), &res))
+		assert.Equal(t, value, res)
+
+		var raw []byte
+		assert.Nil(t, msgpack.Unmarshal(format.appendBytes(nil, []byte(value)), &raw))
+		assert.Equal(t, []byte(value), append([]byte{}, raw...))
+	}
+
+	for _, n := range []int{15, 16, 65536} {
+		arr := format.appendArrayHeader(nil, n)
+		m := format.appendMapHeader(nil, n)
+		for i := 0; i < n; i++ {
+			arr = format.appendNil(arr)
+			m = format.appendBool(format.appendInt(m, int64(i)), i%2 == 0)
+		}
+
+		var resArr []interface{}
+		assert.Nil(t, msgpack.Unmarshal(arr, &resArr))
+		assert.Len(t, resArr, n)
+
+		resMap := make(map[int]bool)
+		assert.Nil(t, msgpack.Unmarshal(m, &resMap))
+		assert.Len(t, resMap, n)
+		assert.True(t, resMap[0])
+	}
+
+	var f32 float32
+	assert.Nil(t, msgpack.Unmarshal(format.appendFloat32(nil, 1.5), &f32))
+	assert.Equal(t, float32(1.5), f32)
+}
+
+// Entries are concatenated without line ending
+func TestNewMsgpackEncoder_WithStream(t *testing.T) {
+	enc, _ := NewMsgpackEncoder(zapcore.EncoderConfig{MessageKey: "msg", LineEnding: "\n"})
+
+	stream := &bytes.Buffer{}
+	for _, msg := range []string{"a", "b"} {
+		buf, err := enc.EncodeEntry(zapcore.Entry{Message: msg}, nil)
+		assert.Nil(t, err)
+		stream.Write(buf.Bytes())
+	}
+
+	dec := msgpack.NewDecoder(stream)
+	for _, msg := range []string{"a", "b"} {
+		res := make(map[string]interface{})
+		assert.Nil(t, dec.Decode(&res))
+		assert.Equal(t, msg, res["msg"])
+	}
+	assert.Equal(t, 0, stream.Len())
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"go.uber.org/zap/zapcore"
+	"sort"
+	"strings"
+)
+
+// Separator of nested logger names joined by zap.Logger.Named()
+const loggerNameSeparator = "."
+
+// nameLevelEnabler enables levels by logger names with fallback of base level
+// As a zapcore.LevelEnabler, it enables a level once base level or any level of names enables it,
+// so that entries are filtered by names in nameLevelCore afterwards.
+type nameLevelEnabler struct {
+	base zapcore.LevelEnabler
+	// names in descending order of length, the most specific name is matched first
+	names  []string
+	levels map[string]zapcore.Level
+}
+
+// Create nameLevelEnabler with base level and levels of names
+func newNameLevelEnabler(base zapcore.LevelEnabler, levels map[string]zapcore.Level) *nameLevelEnabler {
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+
+		return names[i] < names[j]
+	})
+
+	return &nameLevelEnabler{
+		base:   base,
+		names:  names,
+		levels: levels,
+	}
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (enabler *nameLevelEnabler) Enabled(level zapcore.Level) bool {
+	if enabler.base.Enabled(level) {
+		return true
+	}
+
+	for _, name := range enabler.names {
+		if enabler.levels[name].Enabled(level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check whether level is enabled for logger name
+func (enabler *nameLevelEnabler) enabledFor(loggerName string, level zapcore.Level) bool {
+	for _, name := range enabler.names {
+		if matchLoggerName(loggerName, name) {
+			return enabler.levels[name].Enabled(level)
+		}
+	}
+
+	return enabler.base.Enabled(level)
+}
+
+// Check whether logger name is name or nested in it, like db.sql in db
+func matchLoggerName(loggerName, name string) bool {
+	// name is not joined with separator since it allocates on every entry
+	return strings.HasPrefix(loggerName, name) &&
+		(len(loggerName) == len(name) || strings.HasPrefix(loggerName[len(name):], loggerNameSeparator))
+}
+
+// nameLevelCore drops entries which are not enabled for their logger names
+type nameLevelCore struct {
+	zapcore.Core
+	levels *nameLevelEnabler
+}
+
+// With implements zapcore.Core
+func (core *nameLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &nameLevelCore{
+		Core:   core.Core.With(fields),
+		levels: core.levels,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *nameLevelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !core.levels.enabledFor(entry.LoggerName, entry.Level) {
+		return checked
+	}
+
+	return core.Core.Check(entry, checked)
+}
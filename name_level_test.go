@@ -0,0 +1,144 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestNameLevelEnabler_Enabled(t *testing.T) {
+	enabler := newNameLevelEnabler(zap.NewAtomicLevelAt(zap.InfoLevel), map[string]zapcore.Level{
+		"mycompany/db":   zap.DebugLevel,
+		"mycompany/http": zap.WarnLevel,
+	})
+
+	// enabled by level of name
+	assert.True(t, enabler.Enabled(zap.DebugLevel))
+	assert.True(t, enabler.Enabled(zap.InfoLevel))
+	assert.Equal(t, []string{"mycompany/http", "mycompany/db"}, enabler.names)
+}
+
+func TestNameLevelEnabler_EnabledFor(t *testing.T) {
+	enabler := newNameLevelEnabler(zap.NewAtomicLevelAt(zap.InfoLevel), map[string]zapcore.Level{
+		"mycompany":        zap.ErrorLevel,
+		"mycompany/db":     zap.DebugLevel,
+		"mycompany/db.sql": zap.WarnLevel,
+	})
+
+	// exact name
+	assert.True(t, enabler.enabledFor("mycompany/db", zap.DebugLevel))
+	// the most specific name wins
+	assert.False(t, enabler.enabledFor("mycompany/db.sql", zap.InfoLevel))
+	assert.True(t, enabler.enabledFor("mycompany/db.sql.tx", zap.WarnLevel))
+	assert.True(t, enabler.enabledFor("mycompany/db.conn", zap.DebugLevel))
+	assert.False(t, enabler.enabledFor("mycompany.http", zap.WarnLevel))
+	// name which is only prefix of string is not matched
+	assert.True(t, enabler.enabledFor("mycompany/dbx", zap.InfoLevel))
+	// fallback to base level
+	assert.True(t, enabler.enabledFor("", zap.InfoLevel))
+	assert.False(t, enabler.enabledFor("other", zap.DebugLevel))
+}
+
+// Base level is still dynamic
+func TestNameLevelEnabler_WithAtomicBaseLevel(t *testing.T) {
+	base := zap.NewAtomicLevelAt(zap.InfoLevel)
+	enabler := newNameLevelEnabler(base, map[string]zapcore.Level{"db": zap.WarnLevel})
+	assert.False(t, enabler.Enabled(zap.DebugLevel))
+
+	base.SetLevel(zap.DebugLevel)
+	assert.True(t, enabler.Enabled(zap.DebugLevel))
+	assert.True(t, enabler.enabledFor("other", zap.DebugLevel))
+	assert.False(t, enabler.enabledFor("db", zap.DebugLevel))
+}
+
+// Config file with levels of names
+func newNameLevelConfig(filePath string) []byte {
+	return []byte(fmt.Sprintf(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+    nameKey: logger
+  outputPaths: ["%s"]
+levels:
+  mycompany/db: debug
+  mycompany/http: warn
+`, filePath))
+}
+
+func TestNewZapLoggerWithConfig_WithLevels(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	config, err := NewConfigWithBytes(newNameLevelConfig(filePath), YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, zap.DebugLevel, config.Levels["mycompany/db"])
+	assert.Equal(t, zap.WarnLevel, config.Levels["mycompany/http"])
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+
+	logger.Debug("root-debug")
+	logger.Info("root-info")
+	logger.Named("mycompany/db").Debug("db-debug")
+	logger.Named("mycompany/db").Named("sql").With(zap.String("key", "value")).Debug("sql-debug")
+	logger.Named("mycompany/http").Info("http-info")
+	logger.Named("mycompany/http").Warn("http-warn")
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(content), "root-debug")
+	assert.Contains(t, string(content), "root-info")
+	assert.Contains(t, string(content), "db-debug")
+	assert.Contains(t, string(content), "sql-debug")
+	assert.NotContains(t, string(content), "http-info")
+	assert.Contains(t, string(content), "http-warn")
+}
+
+// Entries dropped by levels of names do not consume sampling
+func TestNewZapLoggerWithConfig_WithLevelsAndSampling(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	zapConfig := NewZapStdoutConfig()
+	zapConfig.Sampling = &zap.SamplingConfig{Initial: 2, Thereafter: 100}
+
+	config := NewConfig(zapConfig, nil)
+	config.Outputs = []*OutputConfig{{Path: filePath}}
+	config.Levels = map[string]zapcore.Level{"db": zap.ErrorLevel}
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		logger.Named("db").Info("ut-message")
+	}
+	logger.Info("ut-message")
+	logger.Info("ut-message")
+
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(content), "ut-message"))
+}
+
+// Levels is a known key in strict mode
+func TestNewConfigWithBytes_WithLevelsAndStrictParsing(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	config, err := NewLoader(WithStrictParsing()).NewConfigWithBytes(newNameLevelConfig(filePath), YAML)
+	assert.Nil(t, err)
+	assert.Len(t, config.Levels, 2)
+}
+
+// With invalid level of name
+func TestNewConfigWithBytes_WithInvalidLevels(t *testing.T) {
+	config, err := NewConfigWithBytes([]byte(`{"zap": {"level": "info"}, "levels": {"db": "invalid"}}`), JSON)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
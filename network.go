@@ -0,0 +1,248 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/rookie-ninja/rk-logger/internal/tlsconfig"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"net"
+	"net/url"
+)
+
+// TCPScheme, UDPScheme and UnixScheme are schemes of output paths which write raw entries to network, like:
+//
+//	outputPaths:
+//	  - tcp://relay.example.com:5170?tls=true&overflow=dropNewest
+//	  - udp://relay.example.com:5170
+//	  - unix:///var/run/relay.sock
+//
+// Query parameters:
+//
+//	framing: newline or none, entries over tcp and unix socket are terminated with newline by default
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//	tls, tlsCA, tlsCert, tlsKey, tlsServerName and tlsSkipVerify of TLS, which is not supported by udp
+//
+// Entries are queued in bounded memory and written in background, overflow=block blocks logger while queue is full
+// and overflow=dropNewest or dropOldest drops entries instead. Connection is re-established with exponential backoff
+// before each retry of failed batches. Every entry over udp is sent as one datagram without trailing newline.
+const (
+	TCPScheme  = "tcp"
+	UDPScheme  = "udp"
+	UnixScheme = "unix"
+)
+
+const (
+	// NetFramingNewline terminates every entry with newline.
+	NetFramingNewline = "newline"
+	// NetFramingNone writes entries as they are encoded.
+	NetFramingNone = "none"
+)
+
+func init() {
+	for _, scheme := range []string{TCPScheme, UDPScheme, UnixScheme} {
+		if err := RegisterWriter(scheme, newNetWriterWithURL); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// netWriter writes batches of entries to network connection
+type netWriter struct {
+	*batch.Writer
+	conn *netConn
+}
+
+// netConn writes entries to connection, it is only used by the flushing goroutine of batch.Writer
+type netConn struct {
+	network   string
+	address   string
+	framing   string
+	tlsConfig *tls.Config
+	conn      net.Conn
+	// first entry of batch being written and number of its entries which were written,
+	// so that retries of batch do not write datagrams again
+	current *[]byte
+	sent    int
+}
+
+// Create network writer with url of output path
+func newNetWriterWithURL(u url.URL) (zapcore.WriteSyncer, error) {
+	config, conn, err := parseNetURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &netWriter{
+		Writer: batch.NewWriter(config, conn.write),
+		conn:   conn,
+	}, nil
+}
+
+// Close flushes queued entries and closes connection
+func (writer *netWriter) Close() error {
+	return multierr.Append(writer.Writer.Close(), writer.conn.close())
+}
+
+// HealthCheck implements HealthChecker, a new connection is established and closed
+func (writer *netWriter) HealthCheck(ctx context.Context) error {
+	conn := &netConn{network: writer.conn.network, address: writer.conn.address, tlsConfig: writer.conn.tlsConfig}
+	if err := conn.connect(ctx); err != nil {
+		return err
+	}
+
+	return conn.close()
+}
+
+// Parse batching config and connection settings from url
+func parseNetURL(u *url.URL) (batch.Config, *netConn, error) {
+	config := batch.DefaultConfig()
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, err
+	}
+
+	tlsConfig, query, err := tlsconfig.FromQuery(query)
+	if err != nil {
+		return config, nil, err
+	}
+
+	for key := range query {
+		if key != "framing" {
+			return config, nil, errors.Errorf("unknown query of %s url, key:%s", u.Scheme, key)
+		}
+	}
+
+	conn := &netConn{
+		network:   u.Scheme,
+		address:   u.Host,
+		framing:   query.Get("framing"),
+		tlsConfig: tlsConfig,
+	}
+
+	if conn.network == UnixScheme {
+		conn.address = fromFileURLPath(u.Path)
+	}
+
+	if len(conn.address) == 0 {
+		return config, nil, errors.Errorf("address is missing in %s url", u.Scheme)
+	}
+
+	if len(conn.framing) == 0 {
+		conn.framing = NetFramingNewline
+	}
+	if conn.framing != NetFramingNewline && conn.framing != NetFramingNone {
+		return config, nil, errors.Errorf("framing is not supported in %s url, framing:%s", u.Scheme, conn.framing)
+	}
+
+	if conn.network == UDPScheme && conn.tlsConfig != nil {
+		return config, nil, errors.New("tls is not supported in udp url")
+	}
+
+	return config, conn, nil
+}
+
+// Connect to address, deadline of ctx applies to TLS handshake as well
+func (conn *netConn) connect(ctx context.Context) error {
+	dialer := &net.Dialer{}
+	raw, err := dialer.DialContext(ctx, conn.network, conn.address)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect, network:%s, address:%s", conn.network, conn.address)
+	}
+
+	if conn.tlsConfig != nil {
+		config := conn.tlsConfig.Clone()
+		if len(config.ServerName) == 0 {
+			config.ServerName, _, _ = net.SplitHostPort(conn.address)
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			raw.SetDeadline(deadline)
+		}
+
+		tlsConn := tls.Client(raw, config)
+		if err := tlsConn.Handshake(); err != nil {
+			raw.Close()
+			return errors.Wrapf(err, "failed to handshake, network:%s, address:%s", conn.network, conn.address)
+		}
+		raw = tlsConn
+	}
+
+	conn.conn = raw
+	return nil
+}
+
+// Write entries of batch, connection is closed on failure and re-established by retries
+func (conn *netConn) write(ctx context.Context, entries [][]byte) error {
+	if conn.current != &entries[0] {
+		conn.current, conn.sent = &entries[0], 0
+	}
+
+	if conn.conn == nil {
+		if err := conn.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.conn.SetWriteDeadline(deadline)
+	}
+
+	for conn.sent < len(entries) {
+		var err error
+		if conn.network == UDPScheme {
+			// every entry is a datagram
+			_, err = conn.conn.Write(bytes.TrimRight(entries[conn.sent], "\r\n"))
+			if err == nil {
+				conn.sent++
+			}
+		} else {
+			_, err = conn.conn.Write(conn.frame(entries[conn.sent:]))
+			if err == nil {
+				conn.sent = len(entries)
+			}
+		}
+
+		if err != nil {
+			conn.close()
+			return errors.Wrapf(err, "failed to write entries, network:%s, address:%s", conn.network, conn.address)
+		}
+	}
+
+	return nil
+}
+
+// Concatenate entries with framing of stream
+func (conn *netConn) frame(entries [][]byte) []byte {
+	if conn.framing == NetFramingNone {
+		return bytes.Join(entries, nil)
+	}
+
+	buf := &bytes.Buffer{}
+	for i := range entries {
+		buf.Write(bytes.TrimRight(entries[i], "\r\n"))
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes()
+}
+
+// Close connection
+func (conn *netConn) close() error {
+	if conn.conn == nil {
+		return nil
+	}
+
+	err := conn.conn.Close()
+	conn.conn = nil
+	return err
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/url"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Start stream server and return channel of received lines, connections are closed after first line if asked
+func newNetStreamServer(t *testing.T, listener net.Listener, closeAfterLine bool) chan string {
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					lines <- line
+
+					if closeAfterLine {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return lines
+}
+
+// Wait for line from channel
+func receiveNetLine(t *testing.T, lines chan string) string {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "line is not received")
+		return ""
+	}
+}
+
+func TestParseNetURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("tcp://relay.example.com:5170?framing=none&tlsSkipVerify=true&batchSize=10")
+	config, conn, err := parseNetURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, config.Size)
+	assert.Equal(t, "tcp", conn.network)
+	assert.Equal(t, "relay.example.com:5170", conn.address)
+	assert.Equal(t, NetFramingNone, conn.framing)
+	assert.NotNil(t, conn.tlsConfig)
+
+	// With defaults
+	u, _ = url.Parse("udp://relay.example.com:5170")
+	_, conn, err = parseNetURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "udp", conn.network)
+	assert.Equal(t, NetFramingNewline, conn.framing)
+	assert.Nil(t, conn.tlsConfig)
+
+	// With unix socket
+	u, _ = url.Parse("unix:///var/run/relay.sock")
+	_, conn, err = parseNetURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "unix", conn.network)
+	assert.Equal(t, fromFileURLPath("/var/run/relay.sock"), conn.address)
+}
+
+func TestParseNetURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"tcp://",
+		"unix://",
+		"tcp://relay.example.com:5170?framing=x",
+		"tcp://relay.example.com:5170?unknown=x",
+		"tcp://relay.example.com:5170?tls=x",
+		"tcp://relay.example.com:5170?linger=x",
+		"udp://relay.example.com:5170?tls=true",
+	} {
+		u, _ := url.Parse(raw)
+		_, _, err := parseNetURL(u)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestNetWriter_WithTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	lines := newNetStreamServer(t, listener, false)
+
+	u, _ := url.Parse(fmt.Sprintf("tcp://%s?linger=1h", listener.Addr().String()))
+	writer, err := newNetWriterWithURL(*u)
+	assert.Nil(t, err)
+
+	writer.Write([]byte("ut-message-1\n"))
+	writer.Write([]byte("ut-message-2"))
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, "ut-message-1\n", receiveNetLine(t, lines))
+	assert.Equal(t, "ut-message-2\n", receiveNetLine(t, lines))
+
+	assert.Nil(t, writer.(*netWriter).Close())
+}
+
+// Writer reconnects once connection is closed by server
+func TestNetWriter_Reconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	lines := newNetStreamServer(t, listener, true)
+
+	u, _ := url.Parse(fmt.Sprintf("tcp://%s?linger=1h&backoff=1ms&retries=10", listener.Addr().String()))
+	writer, err := newNetWriterWithURL(*u)
+	assert.Nil(t, err)
+	defer writer.(*netWriter).Close()
+
+	writer.Write([]byte("ut-message-1\n"))
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, "ut-message-1\n", receiveNetLine(t, lines))
+
+	// writes to closed connection fail eventually, entries are written again after reconnecting
+	for i := 0; i < 100; i++ {
+		writer.Write([]byte("ut-message-2\n"))
+		assert.Nil(t, writer.Sync())
+
+		select {
+		case line := <-lines:
+			assert.Equal(t, "ut-message-2\n", line)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	assert.Fail(t, "writer is not reconnected")
+}
+
+func TestNetWriter_WithUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	u, _ := url.Parse(fmt.Sprintf("udp://%s?linger=1h", conn.LocalAddr().String()))
+	writer, err := newNetWriterWithURL(*u)
+	assert.Nil(t, err)
+	defer writer.(*netWriter).Close()
+
+	writer.Write([]byte("ut-message-1\n"))
+	writer.Write([]byte("ut-message-2\n"))
+	assert.Nil(t, writer.Sync())
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, expected := range []string{"ut-message-1", "ut-message-2"} {
+		n, _, err := conn.ReadFrom(buf)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(buf[:n]))
+	}
+}
+
+func TestNetWriter_WithUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket is not supported on windows")
+	}
+
+	socketPath := path.Join(newTempDir(t), "relay.sock")
+	listener, err := net.Listen("unix", socketPath)
+	assert.Nil(t, err)
+	lines := newNetStreamServer(t, listener, false)
+
+	logger, closer, err := NewZapLoggerWithCloser(NewConfigWithOptions(WithOutput("unix://" + socketPath + "?linger=1h")))
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.Contains(t, receiveNetLine(t, lines), "ut-message")
+}
+
+func TestNetWriter_WithUnreachableAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	u, _ := url.Parse(fmt.Sprintf("tcp://%s?linger=1h&retries=1&backoff=1ms", address))
+	writer, err := newNetWriterWithURL(*u)
+	assert.Nil(t, err)
+	defer writer.(*netWriter).Close()
+
+	writer.Write([]byte("ut-message"))
+	assert.NotNil(t, writer.Sync())
+}
+
+func TestNetConn_Frame(t *testing.T) {
+	conn := &netConn{framing: NetFramingNewline}
+	assert.Equal(t, "a\nb\n", string(conn.frame([][]byte{[]byte("a\r\n"), []byte("b")})))
+
+	// With no framing
+	conn.framing = NetFramingNone
+	assert.Equal(t, "a\r\nb", string(conn.frame([][]byte{[]byte("a\r\n"), []byte("b")})))
+}
+
+func TestNetConn_Write(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	lines := newNetStreamServer(t, listener, false)
+
+	conn := &netConn{network: "tcp", address: listener.Addr().String(), framing: NetFramingNewline}
+	defer conn.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, conn.write(ctx, [][]byte{[]byte("ut-message")}))
+	assert.Equal(t, "ut-message\n", receiveNetLine(t, lines))
+}
+
+// Health check connects to address
+func TestNetWriter_HealthCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	newNetStreamServer(t, listener, false)
+
+	u, _ := url.Parse("tcp://" + listener.Addr().String())
+	syncer, closer, err := openWriter(newNetWriterWithURL, u)
+	assert.Nil(t, err)
+	defer closer()
+
+	checker, ok := syncer.(HealthChecker)
+	assert.True(t, ok)
+	assert.Nil(t, checker.HealthCheck(context.Background()))
+
+	listener.Close()
+	assert.NotNil(t, checker.HealthCheck(context.Background()))
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// codes of gRPC which are retried as OTLP specifies
+var retriedCodes = map[codes.Code]bool{
+	codes.Canceled:          true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+	codes.OutOfRange:        true,
+	codes.Unavailable:       true,
+	codes.DataLoss:          true,
+}
+
+// grpcExporter exports requests with OTLP/gRPC
+type grpcExporter struct {
+	target   string
+	conn     *grpc.ClientConn
+	client   collogs.LogsServiceClient
+	metadata metadata.MD
+	options  []grpc.CallOption
+}
+
+// httpExporter exports requests with OTLP/HTTP and protobuf payload
+type httpExporter struct {
+	endpoint string
+	header   map[string]string
+	gzip     bool
+	client   *http.Client
+}
+
+// Create gRPC exporter, connection is established in background
+func newGRPCExporter(target string, header map[string]string, gzip bool, tlsConfig *tls.Config) (*grpcExporter, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial otlp collector, target:%s", target)
+	}
+
+	res := &grpcExporter{
+		target:   target,
+		conn:     conn,
+		client:   collogs.NewLogsServiceClient(conn),
+		metadata: metadata.New(header),
+	}
+	if gzip {
+		res.options = append(res.options, grpc.UseCompressor(grpcgzip.Name))
+	}
+
+	return res, nil
+}
+
+// Export request with gRPC, status codes other than transient ones are permanent
+func (exporter *grpcExporter) export(ctx context.Context, req *collogs.ExportLogsServiceRequest) error {
+	ctx = metadata.NewOutgoingContext(ctx, exporter.metadata)
+
+	resp, err := exporter.client.Export(ctx, req, exporter.options...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to export log records, target:%s", exporter.target)
+		if retriedCodes[status.Code(errors.Cause(err))] {
+			return err
+		}
+		return batch.Permanent(err)
+	}
+
+	return fromPartialSuccess(resp)
+}
+
+// Close connection to collector
+func (exporter *grpcExporter) close() error {
+	return exporter.conn.Close()
+}
+
+// Create HTTP exporter, https is used if TLS is enabled
+func newHTTPExporter(host, path string, header map[string]string, gzip bool, tlsConfig *tls.Config) *httpExporter {
+	scheme := "http"
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		scheme = "https"
+		httpTransport.TLSClientConfig = tlsConfig
+	}
+
+	return &httpExporter{
+		endpoint: fmt.Sprintf("%s://%s%s", scheme, host, path),
+		header:   header,
+		gzip:     gzip,
+		client:   &http.Client{Transport: httpTransport},
+	}
+}
+
+// Export request with protobuf payload, status 429 and 5xx are retried
+func (exporter *httpExporter) export(ctx context.Context, req *collogs.ExportLogsServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return batch.Permanent(err)
+	}
+
+	if exporter.gzip {
+		buf := &bytes.Buffer{}
+		zipper := gzip.NewWriter(buf)
+		zipper.Write(body)
+		zipper.Close()
+		body = buf.Bytes()
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, exporter.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	if exporter.gzip {
+		httpReq.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range exporter.header {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := exporter.client.Do(httpReq)
+	if err != nil {
+		return errors.Wrapf(err, "failed to export log records, endpoint:%s", exporter.endpoint)
+	}
+	defer resp.Body.Close()
+
+	reply, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < http.StatusBadRequest {
+		res := &collogs.ExportLogsServiceResponse{}
+		if len(reply) > 0 && proto.Unmarshal(reply, res) != nil {
+			return nil
+		}
+		return fromPartialSuccess(res)
+	}
+
+	err = errors.Errorf("log records are not accepted, endpoint:%s, status:%d", exporter.endpoint, resp.StatusCode)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return err
+	}
+
+	return batch.Permanent(err)
+}
+
+// Close idle connections to collector
+func (exporter *httpExporter) close() error {
+	exporter.client.CloseIdleConnections()
+	return nil
+}
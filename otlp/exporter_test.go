@@ -0,0 +1,204 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"github.com/stretchr/testify/assert"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collectorServer records requests of OTLP/gRPC and OTLP/HTTP and replies with errors or status in order
+type collectorServer struct {
+	collogs.UnimplementedLogsServiceServer
+	requests []*collogs.ExportLogsServiceRequest
+	headers  []map[string]string
+	errs     []error
+	status   []int
+	partial  *collogs.ExportLogsPartialSuccess
+	mutex    sync.Mutex
+}
+
+// Export implements collogs.LogsServiceServer
+func (server *collectorServer) Export(ctx context.Context, req *collogs.ExportLogsServiceRequest) (*collogs.ExportLogsServiceResponse, error) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(map[string]string)
+	for k, v := range md {
+		header[k] = v[0]
+	}
+	server.headers = append(server.headers, header)
+
+	if len(server.errs) > 0 {
+		err := server.errs[0]
+		server.errs = server.errs[1:]
+		return nil, err
+	}
+
+	server.requests = append(server.requests, req)
+	return &collogs.ExportLogsServiceResponse{PartialSuccess: server.partial}, nil
+}
+
+// ServeHTTP implements http.Handler of OTLP/HTTP
+func (server *collectorServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.headers = append(server.headers, map[string]string{
+		"path":             r.URL.Path,
+		"content-type":     r.Header.Get("Content-Type"),
+		"content-encoding": r.Header.Get("Content-Encoding"),
+		"authorization":    r.Header.Get("Authorization"),
+	})
+
+	if len(server.status) > 0 {
+		status := server.status[0]
+		server.status = server.status[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	var reader io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		reader, _ = gzip.NewReader(r.Body)
+	}
+	body, _ := ioutil.ReadAll(reader)
+
+	req := &collogs.ExportLogsServiceRequest{}
+	proto.Unmarshal(body, req)
+	server.requests = append(server.requests, req)
+
+	reply, _ := proto.Marshal(&collogs.ExportLogsServiceResponse{PartialSuccess: server.partial})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(reply)
+}
+
+// Start gRPC server of collector and return its address
+func startGRPCServer(t *testing.T, collector *collectorServer) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	server := grpc.NewServer()
+	collogs.RegisterLogsServiceServer(server, collector)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+// Request with one log record
+func newTestRequest() *collogs.ExportLogsServiceRequest {
+	return &collogs.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			ScopeLogs: []*logspb.ScopeLogs{{
+				LogRecords: []*logspb.LogRecord{{SeverityText: "INFO"}},
+			}},
+		}},
+	}
+}
+
+func TestGRPCExporter_HappyCase(t *testing.T) {
+	collector := &collectorServer{}
+	exporter, err := newGRPCExporter(startGRPCServer(t, collector), map[string]string{"authorization": "abc"}, true, nil)
+	assert.Nil(t, err)
+	defer exporter.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.Nil(t, exporter.export(ctx, newTestRequest()))
+	assert.Len(t, collector.requests, 1)
+	assert.True(t, proto.Equal(newTestRequest(), collector.requests[0]))
+	assert.Equal(t, "abc", collector.headers[0]["authorization"])
+	assert.Len(t, exporter.options, 1)
+}
+
+func TestGRPCExporter_WithErrors(t *testing.T) {
+	collector := &collectorServer{
+		errs: []error{status.Error(codes.Unavailable, "ut-unavailable"), status.Error(codes.InvalidArgument, "ut-invalid")},
+	}
+	exporter, err := newGRPCExporter(startGRPCServer(t, collector), nil, false, nil)
+	assert.Nil(t, err)
+	defer exporter.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// transient error
+	err = exporter.export(ctx, newTestRequest())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ut-unavailable")
+
+	// permanent error
+	err = exporter.export(ctx, newTestRequest())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ut-invalid")
+
+	// partial success
+	collector.partial = &collogs.ExportLogsPartialSuccess{RejectedLogRecords: 1, ErrorMessage: "ut-rejected"}
+	err = exporter.export(ctx, newTestRequest())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ut-rejected")
+}
+
+func TestHTTPExporter_HappyCase(t *testing.T) {
+	collector := &collectorServer{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	exporter := newHTTPExporter(server.Listener.Addr().String(), LogsPath, map[string]string{"Authorization": "abc"}, true, nil)
+	defer exporter.close()
+
+	assert.Nil(t, exporter.export(context.Background(), newTestRequest()))
+	assert.Len(t, collector.requests, 1)
+	assert.True(t, proto.Equal(newTestRequest(), collector.requests[0]))
+	assert.Equal(t, map[string]string{
+		"path":             LogsPath,
+		"content-type":     "application/x-protobuf",
+		"content-encoding": "gzip",
+		"authorization":    "abc",
+	}, collector.headers[0])
+
+	// partial success
+	collector.partial = &collogs.ExportLogsPartialSuccess{RejectedLogRecords: 1, ErrorMessage: "ut-rejected"}
+	err := exporter.export(context.Background(), newTestRequest())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ut-rejected")
+}
+
+func TestHTTPExporter_WithErrors(t *testing.T) {
+	collector := &collectorServer{status: []int{http.StatusServiceUnavailable, http.StatusBadRequest}}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	exporter := newHTTPExporter(server.Listener.Addr().String(), LogsPath, nil, false, nil)
+	defer exporter.close()
+
+	err := exporter.export(context.Background(), newTestRequest())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status:503")
+
+	err = exporter.export(context.Background(), newTestRequest())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status:400")
+	assert.Empty(t, collector.requests)
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package otlp registers otlp scheme with rklogger.RegisterCore, so that entries are exported to OpenTelemetry
+// collector as log records with OTLP/gRPC or OTLP/HTTP by output paths like:
+//
+//	outputPaths:
+//	  - stdout
+//	  - otlp://otel-collector:4317?resource=service.name:api,deployment.environment:prod
+//	  - otlp://otel-collector:4318?protocol=http&tls=true&header=Authorization:Bearer%20${TOKEN}
+//
+// Import the package for side effects in order to enable the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/otlp"
+//
+// Path of url is the path of OTLP/HTTP endpoint, /v1/logs by default.
+//
+// Query parameters:
+//
+//	protocol: grpc or http, grpc by default
+//	resource: attributes of resource like key1:value1,key2:value2
+//	header:   header or metadata of requests like key:value, which could be repeated
+//	gzip:     compresses requests with gzip, false by default
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//	tls, tlsCA, tlsCert, tlsKey, tlsServerName and tlsSkipVerify of TLS, plaintext is used unless TLS is enabled
+//
+// Attributes of resource are merged from service.name and host.name detected, OTEL_SERVICE_NAME,
+// OTEL_RESOURCE_ATTRIBUTES and query in order. Entries are not encoded by encoder of zap config, fields are
+// converted to attributes of log records, and fields with TraceIDKey, SpanIDKey and TraceFlagsKey are mapped to
+// trace context of log records.
+package otlp
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/rookie-ninja/rk-logger/internal/tlsconfig"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/proto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Scheme is the scheme of output paths which are exported to OpenTelemetry collector.
+	Scheme = "otlp"
+	// ProtocolGRPC exports log records with OTLP/gRPC.
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTP exports log records with OTLP/HTTP and protobuf payload.
+	ProtocolHTTP = "http"
+	// LogsPath is the default path of OTLP/HTTP endpoint.
+	LogsPath = "/v1/logs"
+
+	// name of instrumentation scope of log records
+	scopeName = "github.com/rookie-ninja/rk-logger"
+)
+
+func init() {
+	if err := rklogger.RegisterCore(Scheme, newCoreWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// exporter exports requests to collector, it is only used by the flushing goroutine of batch.Writer
+type exporter interface {
+	export(ctx context.Context, req *collogs.ExportLogsServiceRequest) error
+	close() error
+}
+
+// client exports batches of log records with resource
+type client struct {
+	resource *resourcepb.Resource
+	exporter exporter
+}
+
+// otlpCore implements zapcore.Core which exports entries as log records
+type otlpCore struct {
+	zapcore.LevelEnabler
+	writer *batch.Writer
+	client *client
+	fields []zapcore.Field
+}
+
+// Create core with url of output path and level enabler of zap config
+func newCoreWithURL(u url.URL, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	config, client, err := parseURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCore(enabler, config, client), nil
+}
+
+// Create core which exports batches of log records with client
+func newCore(enabler zapcore.LevelEnabler, config batch.Config, client *client) *otlpCore {
+	return &otlpCore{
+		LevelEnabler: enabler,
+		writer:       batch.NewWriter(config, client.export),
+		client:       client,
+	}
+}
+
+// Parse batching config and client from url
+func parseURL(u *url.URL) (batch.Config, *client, error) {
+	config := batch.DefaultConfig()
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, err
+	}
+
+	tlsConfig, query, err := tlsconfig.FromQuery(query)
+	if err != nil {
+		return config, nil, err
+	}
+
+	if len(u.Host) == 0 {
+		return config, nil, errors.New("host is missing in otlp url")
+	}
+
+	attributes := defaultAttributes()
+	protocol, gzip := ProtocolGRPC, false
+	header := make(map[string]string)
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		switch key {
+		case "protocol":
+			protocol = value
+		case "resource":
+			for _, pair := range strings.Split(value, ",") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) != 2 || len(kv[0]) == 0 {
+					return config, nil, errors.Errorf("invalid resource attribute in otlp url, attribute:%s", pair)
+				}
+				attributes[kv[0]] = kv[1]
+			}
+		case "header":
+			for i := range values {
+				kv := strings.SplitN(values[i], ":", 2)
+				if len(kv) != 2 || len(strings.TrimSpace(kv[0])) == 0 {
+					return config, nil, errors.Errorf("invalid header in otlp url, header:%s", values[i])
+				}
+				header[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+		case "gzip":
+			if gzip, err = strconv.ParseBool(value); err != nil {
+				return config, nil, errors.Wrapf(err, "invalid query of otlp url, key:%s", key)
+			}
+		default:
+			return config, nil, errors.Errorf("unknown query of otlp url, key:%s", key)
+		}
+	}
+
+	client := &client{resource: toResource(attributes)}
+	switch protocol {
+	case ProtocolGRPC:
+		client.exporter, err = newGRPCExporter(u.Host, header, gzip, tlsConfig)
+	case ProtocolHTTP:
+		path := u.Path
+		if len(path) == 0 || path == "/" {
+			path = LogsPath
+		}
+		client.exporter = newHTTPExporter(u.Host, path, header, gzip, tlsConfig)
+	default:
+		return config, nil, errors.Errorf("protocol is not supported in otlp url, protocol:%s", protocol)
+	}
+
+	if err != nil {
+		return config, nil, err
+	}
+
+	return config, client, nil
+}
+
+// Default attributes of resource merged with OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+func defaultAttributes() map[string]string {
+	res := map[string]string{
+		"service.name": filepath.Base(os.Args[0]),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		res["host.name"] = hostname
+	}
+
+	for _, pair := range strings.Split(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"), ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || len(strings.TrimSpace(kv[0])) == 0 {
+			continue
+		}
+
+		value, err := url.QueryUnescape(strings.TrimSpace(kv[1]))
+		if err != nil {
+			continue
+		}
+		res[strings.TrimSpace(kv[0])] = value
+	}
+
+	if name := os.Getenv("OTEL_SERVICE_NAME"); len(name) > 0 {
+		res["service.name"] = name
+	}
+
+	return res
+}
+
+// Convert attributes to resource, attributes are sorted by keys
+func toResource(attributes map[string]string) *resourcepb.Resource {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := &resourcepb.Resource{}
+	for _, k := range keys {
+		res.Attributes = append(res.Attributes, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: attributes[k]}},
+		})
+	}
+
+	return res
+}
+
+// With implements zapcore.Core
+func (core *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	res := make([]zapcore.Field, 0, len(core.fields)+len(fields))
+	res = append(res, core.fields...)
+	res = append(res, fields...)
+
+	return &otlpCore{
+		LevelEnabler: core.LevelEnabler,
+		writer:       core.writer,
+		client:       core.client,
+		fields:       res,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *otlpCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core, entry is queued as log record and exported in background
+func (core *otlpCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	payload, err := proto.Marshal(toRecord(entry, core.fields, fields))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode log record")
+	}
+
+	if _, err = core.writer.Write(payload); err != nil {
+		return err
+	}
+
+	// zap panics or exits after writing entries above error level
+	if entry.Level > zapcore.ErrorLevel {
+		return core.writer.Sync()
+	}
+
+	return nil
+}
+
+// Sync implements zapcore.Core, queued log records are exported
+func (core *otlpCore) Sync() error {
+	return core.writer.Sync()
+}
+
+// Close implements io.Closer, queued log records are exported before closing connection
+func (core *otlpCore) Close() error {
+	return multierr.Append(core.writer.Close(), core.client.exporter.close())
+}
+
+// Stats returns delivery counters of batches which are exported, which are listed by rklogger.ListDeliveryStats()
+func (core *otlpCore) Stats() batch.Stats {
+	return core.writer.Stats()
+}
+
+// Export log records in one request, log records rejected by collector are not retried
+func (client *client) export(ctx context.Context, records [][]byte) error {
+	scope := &logspb.ScopeLogs{
+		Scope:      &commonpb.InstrumentationScope{Name: scopeName},
+		LogRecords: make([]*logspb.LogRecord, 0, len(records)),
+	}
+
+	for i := range records {
+		record := &logspb.LogRecord{}
+		if err := proto.Unmarshal(records[i], record); err != nil {
+			return batch.Permanent(err)
+		}
+		scope.LogRecords = append(scope.LogRecords, record)
+	}
+
+	return client.exporter.export(ctx, &collogs.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource:  client.resource,
+			ScopeLogs: []*logspb.ScopeLogs{scope},
+		}},
+	})
+}
+
+// Convert partial success of response to permanent error
+func fromPartialSuccess(resp *collogs.ExportLogsServiceResponse) error {
+	partial := resp.GetPartialSuccess()
+	if partial == nil || partial.RejectedLogRecords == 0 {
+		return nil
+	}
+
+	return batch.Permanent(errors.Errorf("log records are rejected by collector, rejected:%d, message:%s",
+		partial.RejectedLogRecords, partial.ErrorMessage))
+}
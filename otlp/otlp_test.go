@@ -0,0 +1,152 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package otlp
+
+import (
+	"context"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/url"
+	"os"
+	"testing"
+)
+
+// Attributes of resource as map
+func resourceAttributes(client *client) map[string]string {
+	res := make(map[string]string)
+	for _, kv := range client.resource.Attributes {
+		res[kv.Key] = kv.Value.GetStringValue()
+	}
+
+	return res
+}
+
+func TestParseURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("otlp://localhost:4317?resource=service.name:api,env:prod&header=authorization:abc&gzip=true&batchSize=10")
+	config, client, err := parseURL(u)
+	assert.Nil(t, err)
+	defer client.exporter.close()
+	assert.Equal(t, 10, config.Size)
+	assert.Equal(t, "api", resourceAttributes(client)["service.name"])
+	assert.Equal(t, "prod", resourceAttributes(client)["env"])
+
+	exporter := client.exporter.(*grpcExporter)
+	assert.Equal(t, "localhost:4317", exporter.target)
+	assert.Equal(t, []string{"abc"}, exporter.metadata.Get("authorization"))
+	assert.Len(t, exporter.options, 1)
+
+	// With http
+	u, _ = url.Parse("otlp://localhost:4318?protocol=http&tls=true")
+	_, client, err = parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://localhost:4318/v1/logs", client.exporter.(*httpExporter).endpoint)
+
+	u, _ = url.Parse("otlp://localhost:4318/custom/logs?protocol=http")
+	_, client, err = parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:4318/custom/logs", client.exporter.(*httpExporter).endpoint)
+}
+
+func TestParseURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"otlp:///logs",
+		"otlp://localhost:4317?protocol=ws",
+		"otlp://localhost:4317?resource=env",
+		"otlp://localhost:4317?header=abc",
+		"otlp://localhost:4317?gzip=x",
+		"otlp://localhost:4317?tls=x",
+		"otlp://localhost:4317?linger=x",
+		"otlp://localhost:4317?unknown=x",
+	} {
+		u, _ := url.Parse(raw)
+		_, _, err := parseURL(u)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestDefaultAttributes(t *testing.T) {
+	hostname, _ := os.Hostname()
+
+	os.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.name=ut-env,deployment.environment=prod%20eu,invalid")
+	os.Setenv("OTEL_SERVICE_NAME", "ut-service")
+	defer os.Unsetenv("OTEL_RESOURCE_ATTRIBUTES")
+	defer os.Unsetenv("OTEL_SERVICE_NAME")
+
+	assert.Equal(t, map[string]string{
+		"service.name":           "ut-service",
+		"host.name":              hostname,
+		"deployment.environment": "prod eu",
+	}, defaultAttributes())
+}
+
+func TestCore_HappyCase(t *testing.T) {
+	collector := &collectorServer{}
+	u, _ := url.Parse("otlp://" + startGRPCServer(t, collector) + "?linger=1h&resource=service.name:ut")
+	config, client, err := parseURL(u)
+	assert.Nil(t, err)
+
+	core := newCore(zapcore.DebugLevel, config, client)
+	logger := zap.New(core).With(zap.String("a", "b"))
+	logger.Info("ut-message", zap.Int("status", 200))
+	logger.Debug("ut-debug")
+	assert.Nil(t, core.Sync())
+	assert.Nil(t, core.Close())
+
+	assert.Len(t, collector.requests, 1)
+	resourceLogs := collector.requests[0].ResourceLogs[0]
+	assert.Equal(t, "ut", resourceAttributes(client)["service.name"])
+	assert.Equal(t, client.resource.Attributes[0].Key, resourceLogs.Resource.Attributes[0].Key)
+	assert.Equal(t, scopeName, resourceLogs.ScopeLogs[0].Scope.Name)
+
+	records := resourceLogs.ScopeLogs[0].LogRecords
+	assert.Len(t, records, 2)
+	assert.Equal(t, "ut-message", records[0].Body.GetStringValue())
+	assert.Equal(t, "INFO", records[0].SeverityText)
+	assert.Equal(t, "a", records[0].Attributes[0].Key)
+	assert.Equal(t, "status", records[0].Attributes[1].Key)
+	assert.Equal(t, "DEBUG", records[1].SeverityText)
+}
+
+// Transient errors are retried, the rest are not
+func TestCore_WithRetries(t *testing.T) {
+	collector := &collectorServer{
+		errs: []error{status.Error(codes.Unavailable, "ut-unavailable"), status.Error(codes.ResourceExhausted, "ut-exhausted")},
+	}
+	u, _ := url.Parse("otlp://" + startGRPCServer(t, collector) + "?linger=1h&backoff=1ms")
+	config, client, err := parseURL(u)
+	assert.Nil(t, err)
+
+	core := newCore(zapcore.DebugLevel, config, client)
+	defer core.Close()
+
+	zap.New(core).Info("ut-message")
+	assert.Nil(t, core.Sync())
+	assert.Len(t, collector.headers, 3)
+	assert.Len(t, collector.requests, 1)
+
+	collector.errs = []error{status.Error(codes.InvalidArgument, "ut-invalid")}
+	zap.New(core).Info("ut-message")
+	err = core.Sync()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ut-invalid")
+	assert.Len(t, collector.headers, 4)
+}
+
+func TestRegister(t *testing.T) {
+	collector := &collectorServer{}
+	address := startGRPCServer(t, collector)
+
+	logger, closer, err := rklogger.NewZapLoggerWithCloser(rklogger.NewConfigWithOptions(
+		rklogger.WithOutput("otlp://" + address + "?linger=1h")))
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.Len(t, collector.requests, 1)
+}
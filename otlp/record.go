@@ -0,0 +1,209 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package otlp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/rookie-ninja/rk-logger"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TraceIDKey is the key of field whose hex value is mapped to trace id of log record, see TraceID.
+	TraceIDKey = "trace_id"
+	// SpanIDKey is the key of field whose hex value is mapped to span id of log record, see SpanID.
+	SpanIDKey = "span_id"
+	// TraceFlagsKey is the key of field whose hex value is mapped to trace flags of log record, like 01 of sampled.
+	TraceFlagsKey = "trace_flags"
+)
+
+// TraceID returns field of hex encoded trace id which correlates entries with traces.
+func TraceID(traceID string) zap.Field {
+	return zap.String(TraceIDKey, traceID)
+}
+
+// SpanID returns field of hex encoded span id which correlates entries with spans.
+func SpanID(spanID string) zap.Field {
+	return zap.String(SpanIDKey, spanID)
+}
+
+// Convert entry and fields to log record, trace context is taken from fields
+func toRecord(entry zapcore.Entry, fieldLists ...[]zapcore.Field) *logspb.LogRecord {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fields := range fieldLists {
+		for i := range fields {
+			fields[i].AddTo(enc)
+		}
+	}
+
+	res := &logspb.LogRecord{
+		TimeUnixNano:         uint64(entry.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       severity(entry.Level),
+		SeverityText:         strings.ToUpper(rklogger.LevelName(entry.Level)),
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: entry.Message}},
+	}
+
+	if id, ok := decodeID(enc.Fields[TraceIDKey], 16); ok {
+		res.TraceId = id
+		delete(enc.Fields, TraceIDKey)
+	}
+
+	if id, ok := decodeID(enc.Fields[SpanIDKey], 8); ok {
+		res.SpanId = id
+		delete(enc.Fields, SpanIDKey)
+	}
+
+	if flags, ok := enc.Fields[TraceFlagsKey].(string); ok {
+		if value, err := strconv.ParseUint(flags, 16, 8); err == nil {
+			res.Flags = uint32(value)
+			delete(enc.Fields, TraceFlagsKey)
+		}
+	}
+
+	// attributes of semantic conventions
+	if len(entry.LoggerName) > 0 {
+		enc.Fields["logger.name"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		enc.Fields["code.filepath"] = entry.Caller.File
+		enc.Fields["code.lineno"] = int64(entry.Caller.Line)
+		if len(entry.Caller.Function) > 0 {
+			enc.Fields["code.function"] = entry.Caller.Function
+		}
+	}
+	if len(entry.Stack) > 0 {
+		enc.Fields["exception.stacktrace"] = entry.Stack
+	}
+
+	res.Attributes = toKeyValues(enc.Fields)
+	return res
+}
+
+// Map level of zap to severity number, which is the same as OpenTelemetry bridge of zap
+func severity(level zapcore.Level) logspb.SeverityNumber {
+	switch level {
+	case rklogger.TraceLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_TRACE
+	case zapcore.DebugLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case zapcore.InfoLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case zapcore.WarnLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case zapcore.ErrorLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case zapcore.DPanicLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2
+	case zapcore.PanicLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR3
+	case zapcore.FatalLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	}
+}
+
+// Decode hex encoded id with size, ids of all zeros are invalid
+func decodeID(value interface{}, size int) ([]byte, bool) {
+	str, ok := value.(string)
+	if !ok || len(str) != size*2 {
+		return nil, false
+	}
+
+	id, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, false
+	}
+
+	for i := range id {
+		if id[i] != 0 {
+			return id, true
+		}
+	}
+
+	return nil, false
+}
+
+// Convert fields to attributes sorted by keys
+func toKeyValues(fields map[string]interface{}) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, &commonpb.KeyValue{Key: k, Value: toAnyValue(fields[k])})
+	}
+
+	return res
+}
+
+// Convert value encoded by zapcore.MapObjectEncoder to any value of attribute
+func toAnyValue(value interface{}) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int8:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int16:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+	case uint:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint8:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint16:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case time.Time:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Format(time.RFC3339Nano)}}
+	case time.Duration:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.String()}}
+	case map[string]interface{}:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{
+			KvlistValue: &commonpb.KeyValueList{Values: toKeyValues(v)},
+		}}
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, 0, len(v))
+		for i := range v {
+			values = append(values, toAnyValue(v[i]))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{
+			ArrayValue: &commonpb.ArrayValue{Values: values},
+		}}
+	case nil:
+		return &commonpb.AnyValue{}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package otlp
+
+import (
+	"github.com/stretchr/testify/assert"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/proto"
+	"testing"
+	"time"
+)
+
+// Any value of string
+func stringValue(value string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}
+}
+
+// Any value of int
+func intValue(value int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}
+}
+
+func TestToRecord_HappyCase(t *testing.T) {
+	now := time.Unix(1600000000, 123)
+	entry := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       now,
+		LoggerName: "ut-logger",
+		Message:    "ut-message",
+		Caller:     zapcore.NewEntryCaller(0, "/src/main.go", 10, true),
+		Stack:      "ut-stack",
+	}
+
+	record := toRecord(entry,
+		[]zapcore.Field{
+			TraceID("0102030405060708090a0b0c0d0e0f10"),
+			SpanID("0102030405060708"),
+			zap.String(TraceFlagsKey, "01"),
+		},
+		[]zapcore.Field{zap.Int("status", 404), zap.Object("user", zapcore.ObjectMarshalerFunc(
+			func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("id", "u1")
+				return nil
+			}))})
+
+	assert.True(t, proto.Equal(&logspb.LogRecord{
+		TimeUnixNano:         uint64(now.UnixNano()),
+		ObservedTimeUnixNano: record.ObservedTimeUnixNano,
+		SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+		SeverityText:         "WARN",
+		Body:                 stringValue("ut-message"),
+		TraceId:              []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanId:               []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		Flags:                1,
+		Attributes: []*commonpb.KeyValue{
+			{Key: "code.filepath", Value: stringValue("/src/main.go")},
+			{Key: "code.lineno", Value: intValue(10)},
+			{Key: "exception.stacktrace", Value: stringValue("ut-stack")},
+			{Key: "logger.name", Value: stringValue("ut-logger")},
+			{Key: "status", Value: intValue(404)},
+			{Key: "user", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{
+				KvlistValue: &commonpb.KeyValueList{Values: []*commonpb.KeyValue{{Key: "id", Value: stringValue("u1")}}},
+			}}},
+		},
+	}, record), record.String())
+}
+
+// Invalid trace context is kept as attributes
+func TestToRecord_WithInvalidTraceContext(t *testing.T) {
+	record := toRecord(zapcore.Entry{Level: zapcore.InfoLevel}, []zapcore.Field{
+		TraceID("00000000000000000000000000000000"),
+		SpanID("xyz"),
+		zap.String(TraceFlagsKey, "zz"),
+	})
+
+	assert.Empty(t, record.TraceId)
+	assert.Empty(t, record.SpanId)
+	assert.Zero(t, record.Flags)
+	assert.Len(t, record.Attributes, 3)
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, severity(zapcore.DebugLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, severity(zapcore.InfoLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, severity(zapcore.WarnLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, severity(zapcore.ErrorLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR2, severity(zapcore.DPanicLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR3, severity(zapcore.PanicLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, severity(zapcore.FatalLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, severity(zapcore.Level(100)))
+}
+
+func TestToAnyValue(t *testing.T) {
+	assert.True(t, proto.Equal(stringValue("a"), toAnyValue("a")))
+	assert.True(t, proto.Equal(intValue(1), toAnyValue(uint8(1))))
+	assert.True(t, proto.Equal(&commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}, toAnyValue(true)))
+	assert.True(t, proto.Equal(&commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 1.5}}, toAnyValue(1.5)))
+	assert.True(t, proto.Equal(stringValue("1s"), toAnyValue(time.Second)))
+	assert.True(t, proto.Equal(stringValue("(1+2i)"), toAnyValue(complex(1, 2))))
+	assert.True(t, proto.Equal(&commonpb.AnyValue{}, toAnyValue(nil)))
+	assert.True(t, proto.Equal(&commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{
+		ArrayValue: &commonpb.ArrayValue{Values: []*commonpb.AnyValue{stringValue("a"), intValue(1)}},
+	}}, toAnyValue([]interface{}{"a", 1})))
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// DefaultFileMode is the permission of files created for file outputs.
+const DefaultFileMode os.FileMode = 0644
+
+// PermissionsConfig sets permission and owner of files and parent directories created for file outputs, including
+// files opened by rotation. Permissions are applied regardless of umask, and owner is changed only if process runs
+// as root. Existing files and directories are left untouched.
+//
+// Example config file in YAML:
+//
+//	permissions:
+//	  fileMode: "0640"
+//	  dirMode: "0750"
+//	  owner: app
+//	  group: adm
+type PermissionsConfig struct {
+	// FileMode is octal permission of files, DefaultFileMode if not provided.
+	FileMode string `json:"fileMode,omitempty" yaml:"fileMode,omitempty"`
+	// DirMode is octal permission of parent directories, permission set with WithDirMode() if not provided.
+	DirMode string `json:"dirMode,omitempty" yaml:"dirMode,omitempty"`
+	// Owner is name or uid of user who owns files and directories.
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	// Group is name or gid of group which owns files and directories.
+	Group string `json:"group,omitempty" yaml:"group,omitempty"`
+}
+
+// filePermissions is parsed PermissionsConfig, nil applies permissions of os.OpenFile and os.MkdirAll with umask
+type filePermissions struct {
+	fileMode os.FileMode
+	dirMode  os.FileMode
+	// owner of files and directories, -1 keeps it unchanged
+	uid, gid int
+}
+
+// Parse permissions of config, dir mode is used if config does not provide one, nil is returned if config is nil
+func newFilePermissions(config *PermissionsConfig, dirMode os.FileMode) (*filePermissions, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	perms := &filePermissions{
+		fileMode: DefaultFileMode,
+		dirMode:  dirMode,
+		uid:      -1,
+		gid:      -1,
+	}
+
+	var err error
+	if len(config.FileMode) > 0 {
+		if perms.fileMode, err = parseFileMode(config.FileMode); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.DirMode) > 0 {
+		if perms.dirMode, err = parseFileMode(config.DirMode); err != nil {
+			return nil, err
+		}
+	}
+
+	// owner could be changed by root only
+	if os.Geteuid() != 0 {
+		return perms, nil
+	}
+
+	if len(config.Owner) > 0 {
+		if perms.uid, err = lookupUID(config.Owner); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.Group) > 0 {
+		if perms.gid, err = lookupGID(config.Group); err != nil {
+			return nil, err
+		}
+	}
+
+	return perms, nil
+}
+
+// Parse octal permission, like 0640
+func parseFileMode(text string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(text, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, errors.Errorf("invalid file mode, mode:%s", text)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// Look up uid of user with name or uid
+func lookupUID(owner string) (int, error) {
+	if uid, err := strconv.Atoi(owner); err == nil {
+		return uid, nil
+	}
+
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to look up owner, owner:%s", owner)
+	}
+
+	return strconv.Atoi(u.Uid)
+}
+
+// Look up gid of group with name or gid
+func lookupGID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to look up group, group:%s", group)
+	}
+
+	return strconv.Atoi(g.Gid)
+}
+
+// Open file for appending, permissions are applied if the file is created
+func (perms *filePermissions) openFile(filePath string) (*os.File, error) {
+	if perms == nil {
+		return os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, DefaultFileMode)
+	}
+
+	_, statErr := os.Stat(filePath)
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perms.fileMode)
+	if err != nil || !os.IsNotExist(statErr) {
+		return file, err
+	}
+
+	if err := perms.apply(file.Name(), perms.fileMode); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// Create directory along with missing parents, permissions are applied to the created ones, mode is used if
+// permissions are nil
+func (perms *filePermissions) mkdirAll(dir string, mode os.FileMode) error {
+	if perms == nil {
+		return os.MkdirAll(dir, mode)
+	}
+
+	// missing directories from the deepest one
+	missing := make([]string, 0)
+	for current := filepath.Clean(dir); ; current = filepath.Dir(current) {
+		if _, err := os.Stat(current); err == nil || filepath.Dir(current) == current {
+			break
+		}
+		missing = append(missing, current)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], perms.dirMode); err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			return err
+		}
+
+		if err := perms.apply(missing[i], perms.dirMode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Apply mode regardless of umask and change owner of created file or directory
+func (perms *filePermissions) apply(path string, mode os.FileMode) error {
+	if err := os.Chmod(path, mode); err != nil {
+		return err
+	}
+
+	if perms.uid >= 0 || perms.gid >= 0 {
+		if err := os.Chown(path, perms.uid, perms.gid); err != nil {
+			return errors.Wrapf(err, "failed to change owner, path:%s", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Permission of file
+func fileMode(t *testing.T, filePath string) os.FileMode {
+	info, err := os.Stat(filePath)
+	assert.Nil(t, err)
+	return info.Mode().Perm()
+}
+
+// Skip tests of permissions which are not supported on windows
+func skipPermissionsOnWindows(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not supported on windows")
+	}
+}
+
+func TestParseFileMode(t *testing.T) {
+	mode, err := parseFileMode("0640")
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), mode)
+
+	mode, err = parseFileMode("750")
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0750), mode)
+
+	_, err = parseFileMode("0648")
+	assert.NotNil(t, err)
+	_, err = parseFileMode("01777")
+	assert.NotNil(t, err)
+}
+
+func TestNewFilePermissions(t *testing.T) {
+	perms, err := newFilePermissions(nil, DefaultDirMode)
+	assert.Nil(t, err)
+	assert.Nil(t, perms)
+
+	perms, err = newFilePermissions(&PermissionsConfig{FileMode: "0600"}, 0700)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), perms.fileMode)
+	assert.Equal(t, os.FileMode(0700), perms.dirMode)
+
+	_, err = newFilePermissions(&PermissionsConfig{DirMode: "ut-mode"}, DefaultDirMode)
+	assert.NotNil(t, err)
+
+	// owner is changed by root only
+	perms, err = newFilePermissions(&PermissionsConfig{Owner: "ut-unknown-user", Group: "0"}, DefaultDirMode)
+	if os.Geteuid() == 0 {
+		assert.NotNil(t, err)
+	} else {
+		assert.Nil(t, err)
+		assert.Equal(t, -1, perms.uid)
+		assert.Equal(t, -1, perms.gid)
+	}
+}
+
+func TestFilePermissions_OpenFile(t *testing.T) {
+	skipPermissionsOnWindows(t)
+
+	dir := newTempDir(t)
+	perms, err := newFilePermissions(&PermissionsConfig{FileMode: "0666", DirMode: "0750"}, DefaultDirMode)
+	assert.Nil(t, err)
+
+	// created regardless of umask
+	assert.Nil(t, perms.mkdirAll(path.Join(dir, "a", "b"), DefaultDirMode))
+	assert.Equal(t, os.FileMode(0750), fileMode(t, path.Join(dir, "a")))
+	assert.Equal(t, os.FileMode(0750), fileMode(t, path.Join(dir, "a", "b")))
+
+	filePath := path.Join(dir, "a", "b", "ut.log")
+	file, err := perms.openFile(filePath)
+	assert.Nil(t, err)
+	file.Close()
+	assert.Equal(t, os.FileMode(0666), fileMode(t, filePath))
+
+	// existing files are left untouched
+	assert.Nil(t, os.Chmod(filePath, 0600))
+	file, err = perms.openFile(filePath)
+	assert.Nil(t, err)
+	file.Close()
+	assert.Equal(t, os.FileMode(0600), fileMode(t, filePath))
+}
+
+func TestPermissions_WithConfigFile(t *testing.T) {
+	skipPermissionsOnWindows(t)
+
+	dir := path.Join(newTempDir(t), "logs")
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+outputs:
+  - path: ` + path.Join(dir, "audit.log") + `
+    lumberjack:
+      maxsize: 1
+permissions:
+  fileMode: "0640"
+  dirMode: "0700"
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	logger.Sync()
+
+	assert.Equal(t, os.FileMode(0700), fileMode(t, dir))
+	assert.Equal(t, os.FileMode(0640), fileMode(t, path.Join(dir, "app.log")))
+	assert.Equal(t, os.FileMode(0640), fileMode(t, path.Join(dir, "audit.log")))
+}
+
+func TestTimedWriter_WithPermissions(t *testing.T) {
+	skipPermissionsOnWindows(t)
+
+	dir := newTempDir(t)
+	perms, err := newFilePermissions(&PermissionsConfig{FileMode: "0600"}, DefaultDirMode)
+	assert.Nil(t, err)
+
+	now := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	writer, setNow := newTestTimedWriter(t, path.Join(dir, "app-%Y%m%d.log"),
+		RotationConfig{Schedule: ScheduleDaily, permissions: perms}, now)
+
+	writer.Write([]byte("ut-first\n"))
+	setNow(now.Add(24 * time.Hour))
+	writer.Write([]byte("ut-second\n"))
+
+	assert.Equal(t, os.FileMode(0600), fileMode(t, path.Join(dir, "app-20200901.log")))
+	assert.Equal(t, os.FileMode(0600), fileMode(t, path.Join(dir, "app-20200902.log")))
+}
+
+func TestWithPermissions(t *testing.T) {
+	config := NewConfigWithOptions(WithPermissions(PermissionsConfig{FileMode: "0640"}))
+	assert.Equal(t, &PermissionsConfig{FileMode: "0640"}, config.Permissions)
+}
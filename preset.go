@@ -0,0 +1,154 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sort"
+)
+
+// Names of presets which could be selected with preset key in config file.
+const (
+	PresetProduction  = "production"
+	PresetDevelopment = "development"
+	PresetContainer   = "container"
+	PresetMinimal     = "minimal"
+	PresetGKE         = "gke"
+	PresetCloudRun    = "cloudrun"
+	PresetLambda      = "lambda"
+	PresetECS         = "ecs"
+)
+
+// presets creates combined config of preset by name
+var presets = map[string]func() *Config{
+	PresetProduction:  NewProductionConfig,
+	PresetDevelopment: NewDevelopmentConfig,
+	PresetContainer:   NewContainerConfig,
+	PresetMinimal:     NewMinimalConfig,
+	PresetGKE:         NewGKEConfig,
+	PresetCloudRun:    NewCloudRunConfig,
+	PresetLambda:      NewLambdaConfig,
+	PresetECS:         NewECSConfig,
+}
+
+// PresetNames returns names of presets in ascending order.
+func PresetNames() []string {
+	res := make([]string, 0, len(presets))
+	for name := range presets {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+// NewPresetConfig creates combined config of preset with name, like PresetGKE.
+func NewPresetConfig(name string) (*Config, error) {
+	newConfig, ok := presets[name]
+	if !ok {
+		return nil, errors.Errorf("preset is not supported, name:%s", name)
+	}
+
+	config := newConfig()
+	config.Preset = name
+
+	return config, nil
+}
+
+// NewPresetLogger builds zap logger with combined config of preset with name overridden by options.
+func NewPresetLogger(name string, opts ...Option) (*zap.Logger, error) {
+	config, err := NewPresetConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWithConfig(config, opts...)
+}
+
+// NewProductionConfig creates combined config for production which logs info level and above as json
+// with sampling, files in output paths are rotated with NewLumberjackConfigDefault().
+func NewProductionConfig() *Config {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	return NewConfig(&zap.Config{
+		Level:    zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding: "json",
+		Sampling: &zap.SamplingConfig{
+			Initial:    100,
+			Thereafter: 100,
+		},
+		EncoderConfig:    encoderConfig,
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stderr"},
+	}, NewLumberjackConfigDefault())
+}
+
+// NewDevelopmentConfig creates combined config for development which logs debug level and above
+// as colored console text without sampling, stacktraces are added to warn level and above.
+func NewDevelopmentConfig() *Config {
+	encoderConfig := *NewZapStdoutEncoderConfig()
+	encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+
+	return NewConfig(&zap.Config{
+		Level:            zap.NewAtomicLevelAt(zap.DebugLevel),
+		Development:      true,
+		Encoding:         "console",
+		EncoderConfig:    encoderConfig,
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stderr"},
+	}, nil)
+}
+
+// NewContainerConfig creates combined config for containers which logs info level and above as json
+// to stdout with sampling, files are not rotated since logs are collected by container runtime.
+func NewContainerConfig() *Config {
+	config := NewProductionConfig()
+	config.Lumberjack = nil
+
+	return config
+}
+
+// NewMinimalConfig creates combined config which only logs level and message of info level and above
+// as console text without caller and stacktrace.
+func NewMinimalConfig() *Config {
+	return NewConfig(&zap.Config{
+		Level:             zap.NewAtomicLevelAt(zap.InfoLevel),
+		Encoding:          "console",
+		DisableCaller:     true,
+		DisableStacktrace: true,
+		EncoderConfig: zapcore.EncoderConfig{
+			LevelKey:    "level",
+			MessageKey:  "msg",
+			LineEnding:  zapcore.DefaultLineEnding,
+			EncodeLevel: zapcore.CapitalLevelEncoder,
+		},
+		OutputPaths:      []string{"stdout"},
+		ErrorOutputPaths: []string{"stderr"},
+	}, nil)
+}
+
+// NewProductionLogger builds zap logger with NewProductionConfig() overridden by options.
+func NewProductionLogger(opts ...Option) (*zap.Logger, error) {
+	return newWithConfig(NewProductionConfig(), opts...)
+}
+
+// NewDevelopmentLogger builds zap logger with NewDevelopmentConfig() overridden by options.
+func NewDevelopmentLogger(opts ...Option) (*zap.Logger, error) {
+	return newWithConfig(NewDevelopmentConfig(), opts...)
+}
+
+// NewContainerLogger builds zap logger with NewContainerConfig() overridden by options.
+func NewContainerLogger(opts ...Option) (*zap.Logger, error) {
+	return newWithConfig(NewContainerConfig(), opts...)
+}
+
+// NewMinimalLogger builds zap logger with NewMinimalConfig() overridden by options.
+func NewMinimalLogger(opts ...Option) (*zap.Logger, error) {
+	return newWithConfig(NewMinimalConfig(), opts...)
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestNewProductionConfig(t *testing.T) {
+	config := NewProductionConfig()
+	assert.Equal(t, zap.InfoLevel, config.Zap.Level.Level())
+	assert.Equal(t, "json", config.Zap.Encoding)
+	assert.NotNil(t, config.Zap.Sampling)
+	assert.NotNil(t, config.Lumberjack)
+	assert.False(t, config.Zap.Development)
+}
+
+func TestNewDevelopmentConfig(t *testing.T) {
+	config := NewDevelopmentConfig()
+	assert.Equal(t, zap.DebugLevel, config.Zap.Level.Level())
+	assert.Equal(t, "console", config.Zap.Encoding)
+	assert.Nil(t, config.Zap.Sampling)
+	assert.Nil(t, config.Lumberjack)
+	assert.True(t, config.Zap.Development)
+}
+
+func TestNewContainerConfig(t *testing.T) {
+	config := NewContainerConfig()
+	assert.Equal(t, "json", config.Zap.Encoding)
+	assert.Equal(t, []string{"stdout"}, config.Zap.OutputPaths)
+	assert.Nil(t, config.Lumberjack)
+}
+
+func TestNewMinimalConfig(t *testing.T) {
+	config := NewMinimalConfig()
+	assert.True(t, config.Zap.DisableCaller)
+	assert.True(t, config.Zap.DisableStacktrace)
+	assert.Empty(t, config.Zap.EncoderConfig.TimeKey)
+}
+
+func TestNewProductionLogger_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := NewProductionLogger(WithOutput(filePath))
+	assert.Nil(t, err)
+
+	logger.Debug("ut-debug")
+	logger.Info("ut-info")
+
+	content := readFileContent(filePath)
+	assert.NotContains(t, content, "ut-debug")
+	assert.Contains(t, content, `"msg":"ut-info"`)
+	assert.Contains(t, content, `"level":"info"`)
+}
+
+func TestNewDevelopmentLogger_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := NewDevelopmentLogger(WithOutput(filePath))
+	assert.Nil(t, err)
+
+	logger.Debug("ut-debug")
+	assert.Contains(t, readFileContent(filePath), "ut-debug")
+}
+
+// With overridden level
+func TestNewContainerLogger_WithLevel(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := NewContainerLogger(WithLevel(zap.WarnLevel), WithOutput(filePath))
+	assert.Nil(t, err)
+
+	logger.Info("ut-info")
+	logger.Warn("ut-warn")
+
+	content := readFileContent(filePath)
+	assert.NotContains(t, content, "ut-info")
+	assert.Contains(t, content, `"msg":"ut-warn"`)
+}
+
+func TestNewMinimalLogger_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := NewMinimalLogger(WithOutput(filePath))
+	assert.Nil(t, err)
+
+	logger.Info("ut-info")
+	assert.Equal(t, "INFO\tut-info", strings.TrimSpace(readFileContent(filePath)))
+}
+
+// With invalid output
+func TestNewProductionLogger_WithInvalidOutput(t *testing.T) {
+	logger, err := NewProductionLogger(WithOutput("invalid://ut"))
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestPresetNames(t *testing.T) {
+	assert.Equal(t, []string{
+		PresetCloudRun,
+		PresetContainer,
+		PresetDevelopment,
+		PresetECS,
+		PresetGKE,
+		PresetLambda,
+		PresetMinimal,
+		PresetProduction,
+	}, PresetNames())
+}
+
+func TestNewPresetConfig_HappyCase(t *testing.T) {
+	for _, name := range PresetNames() {
+		config, err := NewPresetConfig(name)
+		assert.Nil(t, err)
+		assert.Equal(t, name, config.Preset)
+		assert.NotNil(t, config.Zap)
+	}
+}
+
+// With unknown preset
+func TestNewPresetConfig_WithUnknownPreset(t *testing.T) {
+	config, err := NewPresetConfig("unknown")
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "preset is not supported, name:unknown")
+
+	logger, err := NewPresetLogger("unknown")
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestNewPresetLogger_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := NewPresetLogger(PresetMinimal, WithOutput(filePath))
+	assert.Nil(t, err)
+
+	logger.Info("ut-info")
+	assert.Equal(t, "INFO\tut-info", strings.TrimSpace(readFileContent(filePath)))
+}
+
+// Preset selected in config file is overridden by keys in config file
+func TestNewConfigWithBytes_WithPreset(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	raw := []byte(fmt.Sprintf(`---
+preset: minimal
+zap:
+  level: warn
+  outputPaths: ["%s"]
+  encoderConfig:
+    messageKey: message
+`, filePath))
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, PresetMinimal, config.Preset)
+	assert.Equal(t, zap.WarnLevel, config.Zap.Level.Level())
+	assert.Equal(t, "message", config.Zap.EncoderConfig.MessageKey)
+	// settings of preset are kept
+	assert.Equal(t, "level", config.Zap.EncoderConfig.LevelKey)
+	assert.True(t, config.Zap.DisableCaller)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Info("ut-info")
+	logger.Warn("ut-warn")
+	assert.Equal(t, "WARN\tut-warn", strings.TrimSpace(readFileContent(filePath)))
+}
+
+// Preset alone without zap section
+func TestNewConfigWithBytes_WithPresetOnly(t *testing.T) {
+	config, err := NewConfigWithBytes([]byte(`{"preset": "gke"}`), JSON)
+	assert.Nil(t, err)
+	assert.Equal(t, "severity", config.Zap.EncoderConfig.LevelKey)
+
+	config, err = NewConfigWithBytes([]byte(`preset = "ecs"`), TOML)
+	assert.Nil(t, err)
+	assert.Equal(t, "timestamp", config.Zap.EncoderConfig.TimeKey)
+}
+
+// With unknown preset in config file
+func TestNewConfigWithBytes_WithUnknownPreset(t *testing.T) {
+	config, err := NewConfigWithBytes([]byte(`preset: unknown`), YAML)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// Preset is a known key in strict mode
+func TestNewConfigWithBytes_WithPresetAndStrictParsing(t *testing.T) {
+	loader := NewLoader(WithStrictParsing())
+	config, err := loader.NewConfigWithBytes([]byte("preset: lambda\nlevels:\n  db: debug\n"), YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, PresetLambda, config.Preset)
+
+	config, err = loader.NewConfigWithBytes([]byte("preset: lambda\nunknown: true\n"), YAML)
+	assert.Nil(t, config)
+	assert.NotNil(t, err)
+}
+
+// Preset in default section is inherited by every logger
+func TestNewConfigMapWithBytes_WithPreset(t *testing.T) {
+	configs, err := NewConfigMapWithBytes([]byte(`---
+default:
+  preset: production
+db:
+  zap:
+    level: debug
+`), YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, PresetProduction, configs["db"].Preset)
+	assert.Equal(t, zap.DebugLevel, configs["db"].Zap.Level.Level())
+	assert.Equal(t, "json", configs["db"].Zap.Encoding)
+	assert.Equal(t, zap.InfoLevel, configs[DefaultLoggerName].Zap.Level.Level())
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+	"os"
+)
+
+// PrettyJSONEncoding is the name of encoding which encodes entries as indented json for development, like:
+//
+//	{
+//	  "level": "info",
+//	  "msg": "connection is ready",
+//	  "db": {
+//	    "pool": 10
+//	  }
+//	}
+//
+// Keys and levels are colorized if stdout is a terminal and NO_COLOR is not set.
+const PrettyJSONEncoding = "json-pretty"
+
+const (
+	// indent of nested objects and arrays of pretty json
+	prettyJSONIndent = "  "
+
+	// escape sequences of colors
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+)
+
+// pool of buffers returned by pretty json encoder
+var prettyJSONPool = buffer.NewPool()
+
+func init() {
+	if err := RegisterEncoder(PrettyJSONEncoding, NewPrettyJSONEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// prettyJSONEncoder indents entries encoded by wrapped json encoder
+type prettyJSONEncoder struct {
+	zapcore.Encoder
+	levelKey   string
+	lineEnding string
+	color      bool
+}
+
+// NewPrettyJSONEncoder creates encoder of indented json with encoder config, keys of entries and encoders of config
+// are applied the same way as json encoding. Keys and levels are colorized if stdout is a terminal and NO_COLOR
+// is not set, use NewPrettyJSONEncoderWithColor() to decide it explicitly.
+func NewPrettyJSONEncoder(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	return NewPrettyJSONEncoderWithColor(isColorTerminal())(config)
+}
+
+// NewPrettyJSONEncoderWithColor returns constructor of indented json encoder which colorizes keys and levels
+// or not, it could be registered with RegisterEncoder.
+func NewPrettyJSONEncoderWithColor(color bool) EncoderConstructor {
+	return func(config zapcore.EncoderConfig) (zapcore.Encoder, error) {
+		lineEnding := config.LineEnding
+		if len(lineEnding) == 0 {
+			lineEnding = zapcore.DefaultLineEnding
+		}
+		config.LineEnding = "\n"
+
+		return &prettyJSONEncoder{
+			Encoder:    zapcore.NewJSONEncoder(config),
+			levelKey:   config.LevelKey,
+			lineEnding: lineEnding,
+			color:      color,
+		}, nil
+	}
+}
+
+// Whether stdout is a terminal which colors are enabled for, see https://no-color.org
+func isColorTerminal() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Color of level which is the same as color level encoders of zap, levels below debug like trace are magenta as well
+func levelColor(level zapcore.Level) string {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return colorMagenta
+	case level == zapcore.InfoLevel:
+		return colorBlue
+	case level == zapcore.WarnLevel:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+// Clone implements zapcore.Encoder
+func (enc *prettyJSONEncoder) Clone() zapcore.Encoder {
+	return &prettyJSONEncoder{
+		Encoder:    enc.Encoder.Clone(),
+		levelKey:   enc.levelKey,
+		lineEnding: enc.lineEnding,
+		color:      enc.color,
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (enc *prettyJSONEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := enc.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+	encoded.TrimNewline()
+
+	buf := prettyJSONPool.Get()
+	p := &prettyJSONPrinter{
+		buf:        buf,
+		src:        encoded.Bytes(),
+		levelKey:   enc.levelKey,
+		levelColor: levelColor(entry.Level),
+		color:      enc.color,
+	}
+	p.print()
+
+	buf.AppendString(enc.lineEnding)
+	return buf, nil
+}
+
+// prettyJSONPrinter indents compact json produced by json encoder and colorizes keys and value of level
+type prettyJSONPrinter struct {
+	buf *buffer.Buffer
+	src []byte
+	pos int
+	// open objects and arrays, whose size is the depth of indent
+	stack      []byte
+	levelKey   string
+	levelColor string
+	color      bool
+}
+
+// Print the whole source, source is valid json since it is produced by json encoder
+func (p *prettyJSONPrinter) print() {
+	// whether the next string is a key of object, and whether it is the value of level
+	expectKey, isLevel := false, false
+
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch c {
+		case '{', '[':
+			p.buf.AppendByte(c)
+			p.pos++
+			// empty objects and arrays are kept compact
+			if p.pos < len(p.src) && (p.src[p.pos] == '}' || p.src[p.pos] == ']') {
+				p.buf.AppendByte(p.src[p.pos])
+				p.pos++
+				continue
+			}
+			p.stack = append(p.stack, c)
+			p.newline()
+			expectKey = c == '{'
+		case '}', ']':
+			p.stack = p.stack[:len(p.stack)-1]
+			p.newline()
+			p.buf.AppendByte(c)
+			p.pos++
+		case ',':
+			p.buf.AppendByte(c)
+			p.pos++
+			p.newline()
+			expectKey = p.stack[len(p.stack)-1] == '{'
+		case ':':
+			p.buf.AppendString(": ")
+			p.pos++
+		case '"':
+			str := p.scanString()
+			switch {
+			case expectKey:
+				isLevel = len(p.stack) == 1 && string(str) == `"`+p.levelKey+`"`
+				p.colorize(colorCyan, str)
+				expectKey = false
+				continue
+			case isLevel:
+				p.colorize(p.levelColor, str)
+			default:
+				p.buf.Write(str)
+			}
+			isLevel = false
+		default:
+			start := p.pos
+			for p.pos < len(p.src) && !isJSONDelimiter(p.src[p.pos]) {
+				p.pos++
+			}
+			p.buf.Write(p.src[start:p.pos])
+			isLevel = false
+		}
+	}
+}
+
+// Scan string with quotes at current position
+func (p *prettyJSONPrinter) scanString() []byte {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '"':
+			p.pos++
+			return p.src[start:p.pos]
+		}
+		p.pos++
+	}
+	return p.src[start:]
+}
+
+// Write value with color if enabled
+func (p *prettyJSONPrinter) colorize(color string, value []byte) {
+	if !p.color {
+		p.buf.Write(value)
+		return
+	}
+
+	p.buf.AppendString(color)
+	p.buf.Write(value)
+	p.buf.AppendString(colorReset)
+}
+
+// Write line break and indent of current depth
+func (p *prettyJSONPrinter) newline() {
+	p.buf.AppendByte('\n')
+	for range p.stack {
+		p.buf.AppendString(prettyJSONIndent)
+	}
+}
+
+// Whether byte ends a literal of json, like number, true, false or null
+func isJSONDelimiter(c byte) bool {
+	switch c {
+	case ',', ':', '{', '}', '[', ']', '"':
+		return true
+	default:
+		return false
+	}
+}
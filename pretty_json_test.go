@@ -0,0 +1,89 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPrettyJSONEncoder_EncodeEntry(t *testing.T) {
+	encoder, err := NewPrettyJSONEncoderWithColor(false)(zapcore.EncoderConfig{
+		LevelKey:    "level",
+		MessageKey:  "msg",
+		EncodeLevel: zapcore.LowercaseLevelEncoder,
+	})
+	assert.Nil(t, err)
+
+	buf, err := encoder.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: `say "hi", {ok}`}, []zapcore.Field{
+		zap.Object("db", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddInt("pool", 10)
+			return enc.AddArray("hosts", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+				arr.AppendString("a")
+				arr.AppendBool(true)
+				return nil
+			}))
+		})),
+		zap.Strings("empty", []string{}),
+		zap.Any("meta", map[string]interface{}{}),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+  "level": "info",
+  "msg": "say \"hi\", {ok}",
+  "db": {
+    "pool": 10,
+    "hosts": [
+      "a",
+      true
+    ]
+  },
+  "empty": [],
+  "meta": {}
+}
+`, buf.String())
+}
+
+func TestPrettyJSONEncoder_WithColor(t *testing.T) {
+	encoder, err := NewPrettyJSONEncoderWithColor(true)(zapcore.EncoderConfig{
+		LevelKey:    "level",
+		TimeKey:     "ts",
+		LineEnding:  "\r\n",
+		EncodeLevel: zapcore.CapitalLevelEncoder,
+		EncodeTime:  zapcore.EpochTimeEncoder,
+	})
+	assert.Nil(t, err)
+
+	// fields added with With() are encoded along with fields of entry, nested level keys are not colorized
+	encoder.AddString("app", "ut")
+	buf, err := encoder.Clone().EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Unix(1, 0)},
+		[]zapcore.Field{zap.Any("ctx", map[string]string{"level": "x"})})
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n"+
+		"  \x1b[36m\"level\"\x1b[0m: \x1b[33m\"WARN\"\x1b[0m,\n"+
+		"  \x1b[36m\"ts\"\x1b[0m: 1,\n"+
+		"  \x1b[36m\"app\"\x1b[0m: \"ut\",\n"+
+		"  \x1b[36m\"ctx\"\x1b[0m: {\n"+
+		"    \x1b[36m\"level\"\x1b[0m: \"x\"\n"+
+		"  }\n"+
+		"}\r\n", buf.String())
+}
+
+func TestIsColorTerminal(t *testing.T) {
+	os.Setenv("NO_COLOR", "")
+	defer os.Unsetenv("NO_COLOR")
+	assert.False(t, isColorTerminal())
+}
+
+func TestLevelColor(t *testing.T) {
+	assert.Equal(t, colorMagenta, levelColor(zapcore.DebugLevel))
+	assert.Equal(t, colorBlue, levelColor(zapcore.InfoLevel))
+	assert.Equal(t, colorYellow, levelColor(zapcore.WarnLevel))
+	assert.Equal(t, colorRed, levelColor(zapcore.FatalLevel))
+}
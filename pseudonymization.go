@@ -0,0 +1,92 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// PseudonymizationConfig replaces values of identifier fields with salted HMAC-SHA256 hashes, so that logs remain
+// joinable for debugging while identifiers are pseudonymized at rest. The same value is always replaced with the same
+// hash of 16 hex characters as long as salt is not changed. Salt is required, it is read from SaltFile, SaltEnv or Salt
+// in order.
+//
+// Example config file in YAML:
+//
+//	pseudonymization:
+//	  fields: [user_id, ip]
+//	  saltEnv: LOG_PSEUDONYM_SALT
+//	  saltFile: /run/secrets/log-salt
+type PseudonymizationConfig struct {
+	// Fields are names of identifier fields, case insensitive.
+	Fields []string `json:"fields" yaml:"fields"`
+	// Salt is key of HMAC, prefer SaltEnv or SaltFile to keep it out of config file.
+	Salt string `json:"salt,omitempty" yaml:"salt,omitempty"`
+	// SaltEnv is name of environment variable of salt.
+	SaltEnv string `json:"saltEnv,omitempty" yaml:"saltEnv,omitempty"`
+	// SaltFile is path of file whose content is salt, trailing line breaks are trimmed.
+	// Relative path is resolved against base directory of Loader.
+	SaltFile string `json:"saltFile,omitempty" yaml:"saltFile,omitempty"`
+}
+
+// Create redactor which hashes identifier fields with salt, nil is returned if config is nil
+func (loader *Loader) newPseudonymizer(config *PseudonymizationConfig) (*redactor, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	if len(config.Fields) == 0 {
+		return nil, errors.New("fields of pseudonymization are empty")
+	}
+
+	salt, err := loader.pseudonymizationSalt(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRedactor(&RedactionConfig{
+		Rules:   []*RedactionRule{{Fields: config.Fields, Action: RedactionHash}},
+		HashKey: salt,
+	})
+}
+
+// Read salt of pseudonymization from file, environment variable or config in order
+func (loader *Loader) pseudonymizationSalt(config *PseudonymizationConfig) (string, error) {
+	if len(config.SaltFile) > 0 {
+		filePath, err := loader.resolvePath(config.SaltFile)
+		if err != nil {
+			return "", err
+		}
+
+		raw, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read salt file of pseudonymization, saltFile:%s", config.SaltFile)
+		}
+
+		if salt := strings.TrimRight(string(raw), "\r\n"); len(salt) > 0 {
+			return salt, nil
+		}
+
+		return "", errors.Errorf("salt file of pseudonymization is empty, saltFile:%s", config.SaltFile)
+	}
+
+	if len(config.SaltEnv) > 0 {
+		if salt := os.Getenv(config.SaltEnv); len(salt) > 0 {
+			return salt, nil
+		}
+
+		return "", errors.Errorf("environment variable of pseudonymization salt is empty, saltEnv:%s", config.SaltEnv)
+	}
+
+	if len(config.Salt) == 0 {
+		return "", errors.New("salt of pseudonymization is missing, provide salt, saltEnv or saltFile")
+	}
+
+	return config.Salt, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLoader_NewPseudonymizer(t *testing.T) {
+	loader := NewLoader()
+
+	// nil config
+	pseudonymizer, err := loader.newPseudonymizer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, pseudonymizer)
+
+	// salt in config
+	pseudonymizer, err = loader.newPseudonymizer(&PseudonymizationConfig{Fields: []string{"user_id"}, Salt: "ut-salt"})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ut-salt"), pseudonymizer.hashKey)
+	assert.Equal(t, RedactionHash, pseudonymizer.rules[0].action)
+
+	// salt file takes precedence over environment variable, relative path is resolved against base directory
+	dir := newTempDir(t)
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "salt"), []byte("ut-file-salt\n"), 0600))
+	os.Setenv("UT_PSEUDONYM_SALT", "ut-env-salt")
+	defer os.Unsetenv("UT_PSEUDONYM_SALT")
+
+	pseudonymizer, err = NewLoader(WithBaseDir(dir)).newPseudonymizer(&PseudonymizationConfig{
+		Fields:   []string{"user_id"},
+		SaltEnv:  "UT_PSEUDONYM_SALT",
+		SaltFile: "salt",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ut-file-salt"), pseudonymizer.hashKey)
+
+	pseudonymizer, err = loader.newPseudonymizer(&PseudonymizationConfig{Fields: []string{"user_id"}, SaltEnv: "UT_PSEUDONYM_SALT"})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ut-env-salt"), pseudonymizer.hashKey)
+
+	// invalid configs
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "empty"), []byte("\n"), 0600))
+	for _, config := range []*PseudonymizationConfig{
+		{Salt: "ut-salt"},
+		{Fields: []string{"user_id"}},
+		{Fields: []string{"user_id"}, SaltEnv: "UT_MISSING_SALT"},
+		{Fields: []string{"user_id"}, SaltFile: path.Join(dir, "missing")},
+		{Fields: []string{"user_id"}, SaltFile: path.Join(dir, "empty")},
+	} {
+		_, err := loader.newPseudonymizer(config)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestPseudonymization_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+pseudonymization:
+  fields: [user_id, ip]
+  salt: ut-salt
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	pseudonymizer, _ := NewLoader().newPseudonymizer(config.Pseudonymization)
+	expected := `{"msg":"ut-login","user_id":"` + pseudonymizer.hash("42") + `","ip":"` + pseudonymizer.hash("10.0.0.1") + `"}` + "\n"
+
+	// the same values are replaced with the same hashes
+	logger.Info("ut-login", zap.Int("user_id", 42), zap.String("ip", "10.0.0.1"))
+	logger.Info("ut-login", zap.Int("user_id", 42), zap.String("ip", "10.0.0.1"))
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.Equal(t, expected+expected, readFileContent(path.Join(dir, "app.log")))
+
+	// missing salt
+	config.Pseudonymization.Salt = ""
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestWithPseudonymization(t *testing.T) {
+	config := NewConfigWithOptions(WithPseudonymization("LOG_SALT", "user_id", "ip"))
+	assert.Equal(t, &PseudonymizationConfig{Fields: []string{"user_id", "ip"}, SaltEnv: "LOG_SALT"}, config.Pseudonymization)
+}
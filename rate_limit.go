@@ -0,0 +1,300 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Keys of entries which could be used by RateLimitConfig.Key
+const (
+	// RateLimitKeyMessage limits entries with the same message.
+	RateLimitKeyMessage = "msg"
+	// RateLimitKeyLogger limits entries of the same logger name given with zap.Logger.Named().
+	RateLimitKeyLogger = "logger"
+)
+
+const (
+	// DefaultRateLimitSummaryInterval is the default interval of summary entries of suppressed entries.
+	DefaultRateLimitSummaryInterval = 10 * time.Second
+	// DefaultRateLimitMaxKeys is the default number of keys limited separately,
+	// entries of the rest of keys share the same limit.
+	DefaultRateLimitMaxKeys = 10000
+)
+
+// message of summary entries
+const rateLimitSummaryMessage = "log entries suppressed by rate limit"
+
+// RateLimitConfig throttles floods of entries with token bucket, like repeated connection errors. Every key gets
+// perSecond tokens per second up to burst tokens, and entries are suppressed while there is no token. Numbers of
+// suppressed entries are logged with summary entries periodically, at the highest level of suppressed entries.
+//
+// Example config file in YAML:
+//
+//	rateLimit:
+//	  perSecond: 1000
+//	  burst: 200
+//	  key: msg
+//	  summaryInterval: 10s
+type RateLimitConfig struct {
+	// PerSecond is the number of entries allowed per second of every key.
+	PerSecond float64 `json:"perSecond" yaml:"perSecond"`
+	// Burst is the number of entries allowed at once, PerSecond rounded up if not provided.
+	Burst int `json:"burst,omitempty" yaml:"burst,omitempty"`
+	// Key is msg or logger, which limits entries by message or logger name,
+	// all entries of logger share the same limit if not provided.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// SummaryInterval is the interval of summary entries, like 1m, DefaultRateLimitSummaryInterval if not provided.
+	SummaryInterval string `json:"summaryInterval,omitempty" yaml:"summaryInterval,omitempty"`
+	// MaxKeys is the number of keys limited separately, DefaultRateLimitMaxKeys if not provided.
+	MaxKeys int `json:"maxKeys,omitempty" yaml:"maxKeys,omitempty"`
+}
+
+// rateLimiter keeps token buckets of keys and logs summary entries of suppressed entries
+type rateLimiter struct {
+	perSecond float64
+	burst     float64
+	keyName   string
+	key       func(entry zapcore.Entry) string
+	interval  time.Duration
+	maxKeys   int
+
+	mutex   sync.Mutex
+	buckets map[string]*rateLimitBucket
+	// core which summary entries are written to
+	core zapcore.Core
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// rateLimitBucket is token bucket of key
+type rateLimitBucket struct {
+	tokens     float64
+	updatedAt  time.Time
+	suppressed uint64
+	// the highest level of suppressed entries
+	level zapcore.Level
+}
+
+// Create rate limiter with config, nil is returned if config is nil
+func newRateLimiter(config *RateLimitConfig) (*rateLimiter, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	if config.PerSecond <= 0 {
+		return nil, errors.Errorf("perSecond of rate limit should be positive, perSecond:%v", config.PerSecond)
+	}
+
+	if config.Burst < 0 || config.MaxKeys < 0 {
+		return nil, errors.Errorf("burst and maxKeys of rate limit should not be negative, burst:%d, maxKeys:%d",
+			config.Burst, config.MaxKeys)
+	}
+
+	res := &rateLimiter{
+		perSecond: config.PerSecond,
+		burst:     float64(config.Burst),
+		keyName:   config.Key,
+		interval:  DefaultRateLimitSummaryInterval,
+		maxKeys:   config.MaxKeys,
+		buckets:   make(map[string]*rateLimitBucket),
+	}
+
+	if res.burst == 0 {
+		res.burst = math.Ceil(config.PerSecond)
+	}
+
+	if res.maxKeys == 0 {
+		res.maxKeys = DefaultRateLimitMaxKeys
+	}
+
+	switch config.Key {
+	case "":
+		res.key = func(zapcore.Entry) string { return "" }
+	case RateLimitKeyMessage:
+		res.key = func(entry zapcore.Entry) string { return entry.Message }
+	case RateLimitKeyLogger:
+		res.key = func(entry zapcore.Entry) string { return entry.LoggerName }
+	default:
+		return nil, errors.Errorf("invalid key of rate limit, key:%s, keys:[%s, %s]",
+			config.Key, RateLimitKeyMessage, RateLimitKeyLogger)
+	}
+
+	if len(config.SummaryInterval) > 0 {
+		interval, err := time.ParseDuration(config.SummaryInterval)
+		if err != nil || interval <= 0 {
+			return nil, errors.Errorf("invalid summaryInterval of rate limit, summaryInterval:%s", config.SummaryInterval)
+		}
+		res.interval = interval
+	}
+
+	return res, nil
+}
+
+// Wrap core with limiter and start logging summary entries to summaryCore in background,
+// core is returned as it is if limiter is nil
+func (limiter *rateLimiter) wrap(core, summaryCore zapcore.Core) zapcore.Core {
+	if limiter == nil {
+		return core
+	}
+
+	limiter.core = summaryCore
+	limiter.stop = make(chan struct{})
+	limiter.done = make(chan struct{})
+	go limiter.summarizeOnInterval()
+
+	return &rateLimitCore{Core: core, limiter: limiter}
+}
+
+// Stop logging summary entries in background and log summary of entries suppressed since the last one,
+// it is safe to be called on nil limiter or limiter which is not started
+func (limiter *rateLimiter) close() {
+	if limiter == nil || limiter.stop == nil {
+		return
+	}
+
+	limiter.once.Do(func() {
+		close(limiter.stop)
+		<-limiter.done
+		limiter.summarize(time.Now())
+	})
+}
+
+// Whether entry is allowed, token of its key is consumed if so
+func (limiter *rateLimiter) allow(entry zapcore.Entry) bool {
+	key := limiter.key(entry)
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	bucket, ok := limiter.buckets[key]
+	if !ok {
+		// entries of the rest of keys share the limit of empty key
+		if len(limiter.buckets) >= limiter.maxKeys {
+			key = ""
+			bucket = limiter.buckets[key]
+		}
+
+		if bucket == nil {
+			bucket = &rateLimitBucket{tokens: limiter.burst, updatedAt: entry.Time}
+			limiter.buckets[key] = bucket
+		}
+	}
+
+	if elapsed := entry.Time.Sub(bucket.updatedAt); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * limiter.perSecond
+		if bucket.tokens > limiter.burst {
+			bucket.tokens = limiter.burst
+		}
+		bucket.updatedAt = entry.Time
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true
+	}
+
+	if bucket.suppressed == 0 || entry.Level > bucket.level {
+		bucket.level = entry.Level
+	}
+	bucket.suppressed++
+
+	return false
+}
+
+// Log summary entries periodically until stopped
+func (limiter *rateLimiter) summarizeOnInterval() {
+	defer close(limiter.done)
+
+	ticker := time.NewTicker(limiter.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-limiter.stop:
+			return
+		case now := <-ticker.C:
+			limiter.summarize(now)
+		}
+	}
+}
+
+// Log summary entries of keys with suppressed entries in order of keys, and remove buckets which are refilled
+func (limiter *rateLimiter) summarize(now time.Time) {
+	type summary struct {
+		key        string
+		suppressed uint64
+		level      zapcore.Level
+	}
+
+	limiter.mutex.Lock()
+	summaries := make([]summary, 0)
+	for key, bucket := range limiter.buckets {
+		if bucket.suppressed > 0 {
+			summaries = append(summaries, summary{key: key, suppressed: bucket.suppressed, level: bucket.level})
+			bucket.suppressed = 0
+			continue
+		}
+
+		if bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*limiter.perSecond >= limiter.burst {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.mutex.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].key < summaries[j].key
+	})
+
+	for _, summary := range summaries {
+		entry := zapcore.Entry{
+			Level:   summary.level,
+			Time:    now,
+			Message: rateLimitSummaryMessage,
+		}
+
+		fields := []zapcore.Field{zap.Uint64("suppressed", summary.suppressed)}
+		if limiter.keyName == RateLimitKeyLogger {
+			entry.LoggerName = summary.key
+		} else if len(summary.key) > 0 {
+			fields = append(fields, zap.String("key", summary.key))
+		}
+
+		if checked := limiter.core.Check(entry, nil); checked != nil {
+			checked.Write(fields...)
+		}
+	}
+}
+
+// rateLimitCore suppresses entries which exceed rate limit
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *rateLimiter
+}
+
+// With implements zapcore.Core, limits are shared with cores created by With()
+func (core *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{
+		Core:    core.Core.With(fields),
+		limiter: core.limiter,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *rateLimitCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !core.Enabled(entry.Level) || !core.limiter.allow(entry) {
+		return checked
+	}
+
+	return core.Core.Check(entry, checked)
+}
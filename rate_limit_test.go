@@ -0,0 +1,148 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	// nil config
+	limiter, err := newRateLimiter(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, limiter)
+
+	// defaults
+	limiter, err = newRateLimiter(&RateLimitConfig{PerSecond: 2.5})
+	assert.Nil(t, err)
+	assert.Equal(t, float64(3), limiter.burst)
+	assert.Equal(t, DefaultRateLimitSummaryInterval, limiter.interval)
+	assert.Equal(t, DefaultRateLimitMaxKeys, limiter.maxKeys)
+
+	// invalid configs
+	for _, config := range []*RateLimitConfig{
+		{},
+		{PerSecond: 1, Burst: -1},
+		{PerSecond: 1, MaxKeys: -1},
+		{PerSecond: 1, Key: "ut-key"},
+		{PerSecond: 1, SummaryInterval: "1x"},
+		{PerSecond: 1, SummaryInterval: "-1s"},
+	} {
+		_, err := newRateLimiter(config)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestRateLimitCore_Check(t *testing.T) {
+	limiter, err := newRateLimiter(&RateLimitConfig{PerSecond: 1, Burst: 2, Key: RateLimitKeyMessage, SummaryInterval: "1h"})
+	assert.Nil(t, err)
+
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := limiter.wrap(observed, observed).With([]zapcore.Field{})
+
+	write := func(level zapcore.Level, msg string, now time.Time) {
+		entry := zapcore.Entry{Level: level, Message: msg, Time: now}
+		if checked := core.Check(entry, nil); checked != nil {
+			checked.Write()
+		}
+	}
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		write(zapcore.InfoLevel, "ut-flood", now)
+	}
+	write(zapcore.ErrorLevel, "ut-flood", now)
+	write(zapcore.InfoLevel, "ut-other", now)
+	assert.Equal(t, 3, logs.Len())
+
+	// tokens are refilled over time
+	write(zapcore.InfoLevel, "ut-flood", now.Add(time.Second))
+	write(zapcore.InfoLevel, "ut-flood", now.Add(time.Second))
+	assert.Equal(t, 4, logs.Len())
+	logs.TakeAll()
+
+	// summary is logged at the highest level of suppressed entries on close
+	limiter.close()
+	summaries := logs.TakeAll()
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, rateLimitSummaryMessage, summaries[0].Message)
+	assert.Equal(t, zapcore.ErrorLevel, summaries[0].Level)
+	assert.Equal(t, map[string]interface{}{"suppressed": uint64(5), "key": "ut-flood"}, summaries[0].ContextMap())
+
+	// closed twice
+	limiter.close()
+}
+
+func TestRateLimiter_WithMaxKeys(t *testing.T) {
+	limiter, err := newRateLimiter(&RateLimitConfig{PerSecond: 1, Key: RateLimitKeyLogger, MaxKeys: 1})
+	assert.Nil(t, err)
+
+	now := time.Now()
+	assert.True(t, limiter.allow(zapcore.Entry{LoggerName: "ut-first", Time: now}))
+	assert.False(t, limiter.allow(zapcore.Entry{LoggerName: "ut-first", Time: now}))
+
+	// the rest of keys share the same limit
+	assert.True(t, limiter.allow(zapcore.Entry{LoggerName: "ut-second", Time: now}))
+	assert.False(t, limiter.allow(zapcore.Entry{LoggerName: "ut-third", Time: now}))
+	assert.Len(t, limiter.buckets, 2)
+
+	// refilled buckets without suppressed entries are removed by summary
+	limiter.core, _ = observer.New(zapcore.DebugLevel)
+	limiter.summarize(now.Add(time.Second))
+	assert.Len(t, limiter.buckets, 2)
+	limiter.summarize(now.Add(time.Second))
+	assert.Empty(t, limiter.buckets)
+}
+
+func TestRateLimit_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+    nameKey: logger
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+  initialFields:
+    service: ut-service
+rateLimit:
+  perSecond: 0.001
+  burst: 2
+  key: logger
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	for i := 0; i < 5; i++ {
+		logger.Named("ut-db").Warn("ut-connection-refused")
+	}
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	content := readFileContent(path.Join(dir, "app.log"))
+	assert.Equal(t, 2, strings.Count(content, "ut-connection-refused"))
+	assert.Contains(t, content, `{"logger":"ut-db","msg":"`+rateLimitSummaryMessage+`","service":"ut-service","suppressed":3}`)
+
+	// invalid key
+	config.RateLimit.Key = "ut-key"
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestWithRateLimit(t *testing.T) {
+	config := NewConfigWithOptions(WithRateLimit(1000, 200, RateLimitKeyMessage))
+	assert.Equal(t, &RateLimitConfig{PerSecond: 1000, Burst: 200, Key: RateLimitKeyMessage}, config.RateLimit)
+}
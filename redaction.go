@@ -0,0 +1,401 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"hash"
+	"regexp"
+	"strings"
+)
+
+// Actions of redaction rules which could be used by RedactionRule.Action
+const (
+	// RedactionMask replaces values with replacement of rule.
+	RedactionMask = "mask"
+	// RedactionHash replaces values with the first 16 hex characters of their SHA-256 hashes, or HMAC-SHA256 hashes
+	// if hash key is provided, so that entries of the same value could still be correlated.
+	RedactionHash = "hash"
+	// RedactionDrop removes fields, it masks values in messages since messages could not be removed.
+	RedactionDrop = "drop"
+)
+
+// Names of built-in patterns which could be used by RedactionRule.Patterns
+const (
+	// RedactionPatternCreditCard matches credit card numbers of 13 to 19 digits passing Luhn check,
+	// digits could be separated by spaces or dashes.
+	RedactionPatternCreditCard = "creditCard"
+	// RedactionPatternEmail matches email addresses.
+	RedactionPatternEmail = "email"
+	// RedactionPatternBearerToken matches bearer tokens of authorization headers, like Bearer eyJhbGciOi...
+	RedactionPatternBearerToken = "bearerToken"
+)
+
+// DefaultRedactionReplacement is the default replacement of masked values.
+const DefaultRedactionReplacement = "[REDACTED]"
+
+// built-in patterns keyed by names
+var redactionPatterns = map[string]*redactionPattern{
+	RedactionPatternCreditCard: {
+		regexp:   regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		validate: luhnValid,
+	},
+	RedactionPatternEmail: {
+		regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	RedactionPatternBearerToken: {
+		regexp: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+	},
+}
+
+// RedactionConfig masks, hashes or drops sensitive values before entries are encoded, so that entries written to
+// every output satisfy requirements like GDPR and PCI without wrapping call sites. Rules match fields by names or
+// string values of fields and messages by patterns, and are applied in order.
+//
+// Example config file in YAML:
+//
+//	redaction:
+//	  hashKey: ${REDACTION_HASH_KEY}
+//	  rules:
+//	    - fields: [password, secret]
+//	      action: drop
+//	    - fields: [userId]
+//	      action: hash
+//	    - patterns: [creditCard, email, bearerToken, '\d{3}-\d{2}-\d{4}']
+//	      action: mask
+//	      replacement: "***"
+type RedactionConfig struct {
+	// Rules are applied in order, see RedactionRule.
+	Rules []*RedactionRule `json:"rules" yaml:"rules"`
+	// HashKey is key of HMAC-SHA256 used by hash action, plain SHA-256 is used if not provided.
+	HashKey string `json:"hashKey,omitempty" yaml:"hashKey,omitempty"`
+	// DryRun writes entries as they are and records fields and patterns which would be redacted,
+	// see ListRedactionMatches().
+	DryRun bool `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+}
+
+// RedactionRule redacts fields whose names are Fields and parts of string values and messages matching Patterns.
+type RedactionRule struct {
+	// Fields are names of fields whose values are redacted whatever types they are, case insensitive.
+	Fields []string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	// Patterns are names of built-in patterns or regular expressions, parts of values of string, byte string,
+	// stringer and error fields as well as messages matching them are redacted.
+	Patterns []string `json:"patterns,omitempty" yaml:"patterns,omitempty"`
+	// Action is mask, hash or drop, mask by default.
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+	// Replacement replaces masked values, DefaultRedactionReplacement if not provided.
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+}
+
+// redactionPattern is a regular expression whose matches are redacted if they are valid
+type redactionPattern struct {
+	// name of built-in pattern or regular expression
+	name     string
+	regexp   *regexp.Regexp
+	validate func(match string) bool
+}
+
+// redactionRule is parsed RedactionRule
+type redactionRule struct {
+	fields      []string
+	patterns    []*redactionPattern
+	action      string
+	replacement string
+}
+
+// redactor applies rules to fields and messages
+type redactor struct {
+	rules   []*redactionRule
+	hashKey []byte
+	dryRun  bool
+}
+
+// redactionRecorder is called with rule, key of field and pattern matched by rule, key is empty for messages and
+// pattern is empty for fields matched by names
+type redactionRecorder func(rule *redactionRule, key, pattern string)
+
+// Create redactor with config, nil is returned if config is nil or without rules
+func newRedactor(config *RedactionConfig) (*redactor, error) {
+	if config == nil || len(config.Rules) == 0 {
+		return nil, nil
+	}
+
+	res := &redactor{
+		rules:   make([]*redactionRule, 0, len(config.Rules)),
+		hashKey: []byte(config.HashKey),
+		dryRun:  config.DryRun,
+	}
+
+	for i, ruleConfig := range config.Rules {
+		rule := &redactionRule{
+			fields:      ruleConfig.Fields,
+			action:      strings.ToLower(ruleConfig.Action),
+			replacement: ruleConfig.Replacement,
+		}
+
+		if len(rule.action) == 0 {
+			rule.action = RedactionMask
+		}
+
+		if rule.action != RedactionMask && rule.action != RedactionHash && rule.action != RedactionDrop {
+			return nil, errors.Errorf("invalid action of redaction rule, index:%d, action:%s, actions:[%s, %s, %s]",
+				i, ruleConfig.Action, RedactionMask, RedactionHash, RedactionDrop)
+		}
+
+		if len(rule.replacement) == 0 {
+			rule.replacement = DefaultRedactionReplacement
+		}
+
+		if len(ruleConfig.Fields) == 0 && len(ruleConfig.Patterns) == 0 {
+			return nil, errors.Errorf("fields and patterns of redaction rule are empty, index:%d", i)
+		}
+
+		for _, pattern := range ruleConfig.Patterns {
+			if builtIn, ok := redactionPatterns[pattern]; ok {
+				rule.patterns = append(rule.patterns, &redactionPattern{
+					name:     pattern,
+					regexp:   builtIn.regexp,
+					validate: builtIn.validate,
+				})
+				continue
+			}
+
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid pattern of redaction rule, index:%d, pattern:%s", i, pattern)
+			}
+			rule.patterns = append(rule.patterns, &redactionPattern{name: pattern, regexp: compiled})
+		}
+
+		res.rules = append(res.rules, rule)
+	}
+
+	return res, nil
+}
+
+// Wrap core with redactor, core is returned as it is if redactor is nil
+func (redactor *redactor) wrap(core zapcore.Core) zapcore.Core {
+	if redactor == nil {
+		return core
+	}
+
+	return &redactionCore{Core: core, redactor: redactor}
+}
+
+// Redact fields the same way as With() without recording matches, fields are returned as they are if redactor is nil
+// or in dry run mode
+func (redactor *redactor) redactWith(fields []zapcore.Field) []zapcore.Field {
+	if redactor == nil || redactor.dryRun {
+		return fields
+	}
+
+	return redactor.redactFields(fields, nil)
+}
+
+// Redact fields, fields are copied only if any of them is redacted, matches are recorded if record is not nil
+func (redactor *redactor) redactFields(fields []zapcore.Field, record redactionRecorder) []zapcore.Field {
+	res := fields
+	copied := false
+	for i := range fields {
+		field, redacted, keep := redactor.redactField(fields[i], record)
+		if !redacted {
+			if copied {
+				res = append(res, field)
+			}
+			continue
+		}
+
+		if !copied {
+			res = append(make([]zapcore.Field, 0, len(fields)), fields[:i]...)
+			copied = true
+		}
+
+		if keep {
+			res = append(res, field)
+		}
+	}
+
+	return res
+}
+
+// Redact field, whether field is redacted and whether it is kept are returned
+func (redactor *redactor) redactField(field zapcore.Field, record redactionRecorder) (zapcore.Field, bool, bool) {
+	if field.Type == zapcore.NamespaceType || field.Type == zapcore.SkipType {
+		return field, false, true
+	}
+
+	for _, rule := range redactor.rules {
+		for _, name := range rule.fields {
+			if !strings.EqualFold(name, field.Key) {
+				continue
+			}
+
+			if record != nil {
+				record(rule, field.Key, "")
+			}
+
+			switch rule.action {
+			case RedactionDrop:
+				return field, true, false
+			case RedactionHash:
+				return zap.String(field.Key, redactor.hash(fieldString(field))), true, true
+			default:
+				return zap.String(field.Key, rule.replacement), true, true
+			}
+		}
+	}
+
+	var text string
+	switch field.Type {
+	case zapcore.StringType:
+		text = field.String
+	case zapcore.ByteStringType:
+		text = string(field.Interface.([]byte))
+	case zapcore.StringerType, zapcore.ErrorType:
+		text = fieldString(field)
+	default:
+		return field, false, true
+	}
+
+	redacted, drop := redactor.redactString(text, field.Key, record)
+	if drop {
+		return field, true, false
+	}
+
+	if redacted == text {
+		return field, false, true
+	}
+
+	return zap.String(field.Key, redacted), true, true
+}
+
+// Redact parts of text of field with key matching patterns, true is returned if text should be dropped
+func (redactor *redactor) redactString(text, key string, record redactionRecorder) (string, bool) {
+	for _, rule := range redactor.rules {
+		for _, pattern := range rule.patterns {
+			matched := false
+			text = pattern.regexp.ReplaceAllStringFunc(text, func(match string) string {
+				if pattern.validate != nil && !pattern.validate(match) {
+					return match
+				}
+
+				if !matched && record != nil {
+					record(rule, key, pattern.name)
+				}
+
+				matched = true
+				if rule.action == RedactionHash {
+					return redactor.hash(match)
+				}
+
+				return rule.replacement
+			})
+
+			if matched && rule.action == RedactionDrop {
+				return text, true
+			}
+		}
+	}
+
+	return text, false
+}
+
+// The first 16 hex characters of SHA-256 or HMAC-SHA256 of value
+func (redactor *redactor) hash(value string) string {
+	var h hash.Hash
+	if len(redactor.hashKey) > 0 {
+		h = hmac.New(sha256.New, redactor.hashKey)
+	} else {
+		h = sha256.New()
+	}
+
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Value of field as string
+func fieldString(field zapcore.Field) string {
+	enc := zapcore.NewMapObjectEncoder()
+	field.AddTo(enc)
+
+	if text, ok := enc.Fields[field.Key].(string); ok {
+		return text
+	}
+
+	return fmt.Sprint(enc.Fields[field.Key])
+}
+
+// Check whether digits in text pass Luhn check
+func luhnValid(text string) bool {
+	sum, n := 0, 0
+	for i := len(text) - 1; i >= 0; i-- {
+		c := text[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		digit := int(c - '0')
+		if n%2 == 1 {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		n++
+	}
+
+	return n > 0 && sum%10 == 0
+}
+
+// redactionCore redacts messages and fields of entries before they are written to wrapped core
+type redactionCore struct {
+	zapcore.Core
+	redactor *redactor
+}
+
+// With implements zapcore.Core, fields are recorded without logger name in dry run mode
+func (core *redactionCore) With(fields []zapcore.Field) zapcore.Core {
+	if core.redactor.dryRun {
+		core.redactor.redactFields(fields, newRedactionMatchRecorder(""))
+		return &redactionCore{Core: core.Core.With(fields), redactor: core.redactor}
+	}
+
+	return &redactionCore{
+		Core:     core.Core.With(core.redactor.redactFields(fields, nil)),
+		redactor: core.redactor,
+	}
+}
+
+// Check implements zapcore.Core, entries are checked by wrapped core after being redacted
+func (core *redactionCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core, entries are written as they are in dry run mode
+func (core *redactionCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if core.redactor.dryRun {
+		record := newRedactionMatchRecorder(entry.LoggerName)
+		core.redactor.redactString(entry.Message, "", record)
+		core.redactor.redactFields(fields, record)
+		writeChecked(core.Core, entry, fields)
+		return nil
+	}
+
+	entry.Message, _ = core.redactor.redactString(entry.Message, "", nil)
+	writeChecked(core.Core, entry, core.redactor.redactFields(fields, nil))
+	return nil
+}
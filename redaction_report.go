@@ -0,0 +1,102 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// max number of distinct matches recorded in dry run mode, matches beyond it are not recorded
+const maxRedactionMatches = 4096
+
+var (
+	// matches of redaction rules in dry run mode keyed by logger name, field and pattern
+	redactionMatches    = make(map[redactionMatchKey]*RedactionMatch)
+	redactionMatchMutex sync.Mutex
+)
+
+// RedactionMatch counts values which would be redacted by rules of redaction section in dry run mode, so that
+// logging hygiene could be audited before redaction is enforced.
+type RedactionMatch struct {
+	// Logger is name of logger whose entries are matched, empty for fields added with With().
+	Logger string `json:"logger" yaml:"logger"`
+	// Field is key of matched field, empty for messages.
+	Field string `json:"field" yaml:"field"`
+	// Pattern is name of built-in pattern or regular expression which matches value, empty if field is matched by name.
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Action is action of matched rule.
+	Action string `json:"action" yaml:"action"`
+	// Count is the number of matched values.
+	Count uint64 `json:"count" yaml:"count"`
+	// LastMatched is the time of the last match.
+	LastMatched time.Time `json:"lastMatched" yaml:"lastMatched"`
+}
+
+// redactionMatchKey identifies RedactionMatch
+type redactionMatchKey struct {
+	logger, field, pattern, action string
+}
+
+// ListRedactionMatches returns matches of redaction rules of loggers in dry run mode since process started or matches
+// were reset, in order of logger names, fields and patterns.
+func ListRedactionMatches() []RedactionMatch {
+	redactionMatchMutex.Lock()
+	res := make([]RedactionMatch, 0, len(redactionMatches))
+	for _, match := range redactionMatches {
+		res = append(res, *match)
+	}
+	redactionMatchMutex.Unlock()
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Logger != res[j].Logger {
+			return res[i].Logger < res[j].Logger
+		}
+
+		if res[i].Field != res[j].Field {
+			return res[i].Field < res[j].Field
+		}
+
+		if res[i].Pattern != res[j].Pattern {
+			return res[i].Pattern < res[j].Pattern
+		}
+
+		return res[i].Action < res[j].Action
+	})
+
+	return res
+}
+
+// ResetRedactionMatches removes all of the recorded matches of redaction rules.
+func ResetRedactionMatches() {
+	redactionMatchMutex.Lock()
+	redactionMatches = make(map[redactionMatchKey]*RedactionMatch)
+	redactionMatchMutex.Unlock()
+}
+
+// Create recorder which records matches of entries of logger
+func newRedactionMatchRecorder(logger string) redactionRecorder {
+	return func(rule *redactionRule, field, pattern string) {
+		key := redactionMatchKey{logger: logger, field: field, pattern: pattern, action: rule.action}
+
+		redactionMatchMutex.Lock()
+		defer redactionMatchMutex.Unlock()
+
+		match, ok := redactionMatches[key]
+		if !ok {
+			if len(redactionMatches) >= maxRedactionMatches {
+				return
+			}
+
+			match = &RedactionMatch{Logger: logger, Field: field, Pattern: pattern, Action: rule.action}
+			redactionMatches[key] = match
+		}
+
+		match.Count++
+		match.LastMatched = time.Now()
+	}
+}
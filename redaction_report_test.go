@@ -0,0 +1,68 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"path"
+	"testing"
+)
+
+func TestRedaction_WithDryRun(t *testing.T) {
+	ResetRedactionMatches()
+	defer ResetRedactionMatches()
+
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+    nameKey: logger
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+redaction:
+  dryRun: true
+  rules:
+    - fields: [password]
+      action: drop
+    - patterns: [creditCard, email]
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Named("auth").Info("ut-login of ut@example.com", zap.String("password", "ut-password"))
+	logger.Named("auth").Info("ut-login", zap.String("password", "ut-password"), zap.String("card", "4111111111111111"))
+	logger.With(zap.String("contact", "ut@example.com")).Info("ut-contact")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	// entries are written as they are
+	assert.Equal(t, `{"logger":"auth","msg":"ut-login of ut@example.com","password":"ut-password"}`+"\n"+
+		`{"logger":"auth","msg":"ut-login","password":"ut-password","card":"4111111111111111"}`+"\n"+
+		`{"msg":"ut-contact","contact":"ut@example.com"}`+"\n", readFileContent(path.Join(dir, "app.log")))
+
+	matches := ListRedactionMatches()
+	for i := range matches {
+		assert.False(t, matches[i].LastMatched.IsZero())
+		matches[i].LastMatched = matches[0].LastMatched
+	}
+	lastMatched := matches[0].LastMatched
+
+	assert.Equal(t, []RedactionMatch{
+		{Field: "contact", Pattern: RedactionPatternEmail, Action: RedactionMask, Count: 1, LastMatched: lastMatched},
+		{Logger: "auth", Pattern: RedactionPatternEmail, Action: RedactionMask, Count: 1, LastMatched: lastMatched},
+		{Logger: "auth", Field: "card", Pattern: RedactionPatternCreditCard, Action: RedactionMask, Count: 1, LastMatched: lastMatched},
+		{Logger: "auth", Field: "password", Action: RedactionDrop, Count: 2, LastMatched: lastMatched},
+	}, matches)
+
+	ResetRedactionMatches()
+	assert.Empty(t, ListRedactionMatches())
+}
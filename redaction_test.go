@@ -0,0 +1,165 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"path"
+	"testing"
+)
+
+func TestNewRedactor(t *testing.T) {
+	// empty config
+	redactor, err := newRedactor(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, redactor)
+
+	redactor, err = newRedactor(&RedactionConfig{Rules: []*RedactionRule{{Fields: []string{"password"}}}})
+	assert.Nil(t, err)
+	assert.Equal(t, RedactionMask, redactor.rules[0].action)
+	assert.Equal(t, DefaultRedactionReplacement, redactor.rules[0].replacement)
+
+	// invalid rules
+	for _, rule := range []*RedactionRule{
+		{},
+		{Fields: []string{"password"}, Action: "ut-action"},
+		{Patterns: []string{"("}},
+	} {
+		_, err := newRedactor(&RedactionConfig{Rules: []*RedactionRule{rule}})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestRedactor_RedactString(t *testing.T) {
+	redactor, err := newRedactor(&RedactionConfig{Rules: []*RedactionRule{
+		{Patterns: []string{RedactionPatternCreditCard, RedactionPatternEmail, RedactionPatternBearerToken}},
+		{Patterns: []string{`\d{3}-\d{2}-\d{4}`}, Action: RedactionHash},
+	}})
+	assert.Nil(t, err)
+
+	for text, expected := range map[string]string{
+		"card 4111 1111 1111 1111 paid":   "card [REDACTED] paid",
+		"card 4111-1111-1111-1111":        "card [REDACTED]",
+		"order 4111111111111112 failed":   "order 4111111111111112 failed",
+		"mail to ut@example.com now":      "mail to [REDACTED] now",
+		"Authorization: Bearer abc.def-1": "Authorization: [REDACTED]",
+		"ssn 123-45-6789":                 "ssn " + redactor.hash("123-45-6789"),
+	} {
+		res, drop := redactor.redactString(text, "", nil)
+		assert.False(t, drop)
+		assert.Equal(t, expected, res)
+	}
+}
+
+func TestRedactor_RedactFields(t *testing.T) {
+	redactor, err := newRedactor(&RedactionConfig{
+		HashKey: "ut-key",
+		Rules: []*RedactionRule{
+			{Fields: []string{"password"}, Action: RedactionDrop},
+			{Fields: []string{"userId"}, Action: RedactionHash},
+			{Fields: []string{"token"}, Replacement: "***"},
+			{Patterns: []string{RedactionPatternEmail}},
+			{Patterns: []string{"ut-secret"}, Action: RedactionDrop},
+		},
+	})
+	assert.Nil(t, err)
+
+	// fields are not copied if nothing is redacted
+	fields := []zapcore.Field{zap.String("ut-key", "ut-value"), zap.Int("count", 1)}
+	assert.Equal(t, &fields[0], &redactor.redactFields(fields, nil)[0])
+
+	res := redactor.redactFields([]zapcore.Field{
+		zap.String("ut-key", "ut-value"),
+		zap.String("PASSWORD", "ut-password"),
+		zap.Int("userId", 42),
+		zap.String("token", "ut-token"),
+		zap.ByteString("mail", []byte("ut@example.com")),
+		zap.Error(errors.New("failed to notify ut@example.com")),
+		zap.String("note", "ut-secret"),
+		zap.Namespace("token"),
+	}, nil)
+
+	enc := zapcore.NewMapObjectEncoder()
+	for i := range res {
+		res[i].AddTo(enc)
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"ut-key": "ut-value",
+		"userId": redactor.hash("42"),
+		"token":  map[string]interface{}{},
+		"mail":   "[REDACTED]",
+		"error":  "failed to notify [REDACTED]",
+	}, enc.Fields)
+	assert.Len(t, redactor.hash("42"), 16)
+	// hash without key
+	plain, err := newRedactor(&RedactionConfig{Rules: []*RedactionRule{{Fields: []string{"userId"}}}})
+	assert.Nil(t, err)
+	assert.NotEqual(t, plain.hash("42"), redactor.hash("42"))
+}
+
+func TestRedactionCore_Write(t *testing.T) {
+	redactor, err := newRedactor(&RedactionConfig{Rules: []*RedactionRule{
+		{Fields: []string{"password"}},
+		{Patterns: []string{RedactionPatternEmail}},
+	}})
+	assert.Nil(t, err)
+
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(redactor.wrap(observed))
+
+	logger.With(zap.String("password", "ut-password")).Info("ut@example.com signed in", zap.String("email", "ut@example.com"))
+	logger.Debug("ut-debug")
+
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "[REDACTED] signed in", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"password": "[REDACTED]", "email": "[REDACTED]"}, entries[0].ContextMap())
+}
+
+func TestRedaction_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+redaction:
+  rules:
+    - fields: [password]
+      action: drop
+    - patterns: [creditCard]
+      replacement: "****"
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-login", zap.String("password", "ut-password"), zap.String("card", "4111111111111111"))
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	assert.Equal(t, `{"msg":"ut-login","card":"****"}`+"\n", readFileContent(path.Join(dir, "app.log")))
+
+	// invalid action
+	config.Redaction.Rules[0].Action = "ut-action"
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestWithRedaction(t *testing.T) {
+	redaction := RedactionConfig{Rules: []*RedactionRule{{Fields: []string{"password"}}}}
+	config := NewConfigWithOptions(WithRedaction(redaction))
+	assert.Equal(t, &redaction, config.Redaction)
+}
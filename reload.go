@@ -0,0 +1,415 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// max time waiting for in-flight writes to the previous core before it is closed by reloading
+	reloadDrainTimeout = 10 * time.Second
+	// interval of checking in-flight writes to the previous core
+	reloadDrainInterval = 10 * time.Millisecond
+)
+
+// ReloadableLogger is a zap logger whose core could be rebuilt from config file at runtime.
+// Level, encoding, sampling, initial fields and outputs are reloaded, while options like caller,
+// stacktrace, development mode and error outputs are fixed when ReloadableLogger is created.
+type ReloadableLogger struct {
+	loader    *Loader
+	filePath  string
+	fileType  FileType
+	zapLogger *zap.Logger
+	holder    *coreHolder
+	closeSink func()
+	// error outputs are not reloaded
+	closeErrSink func()
+	watcher      *fsnotify.Watcher
+	done         chan struct{}
+	signals      chan os.Signal
+	signalDone   chan struct{}
+	// previous cores which are closed once their in-flight writes are drained
+	retiring sync.WaitGroup
+	closed   bool
+	mutex    sync.Mutex
+}
+
+// NewReloadableZapLogger inits reloadable zap logger with config file path,
+// call Watch in order to reload it automatically once config file changed.
+func NewReloadableZapLogger(filePath string, fileType FileType, opts ...zap.Option) (*ReloadableLogger, error) {
+	return defaultLoader.NewReloadableZapLogger(filePath, fileType, opts...)
+}
+
+// NewReloadableZapLogger inits reloadable zap logger with config file path and options of Loader
+func (loader *Loader) NewReloadableZapLogger(filePath string, fileType FileType, opts ...zap.Option) (*ReloadableLogger, error) {
+	filePath, err := toAbsoluteWorkingDir(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := loader.NewConfigWithConfPath(filePath, fileType)
+	if err != nil {
+		return nil, err
+	}
+
+	core, closeSink, err := loader.newZapCoreWithConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	_, errOutputs := config.toOutputConfigs()
+	configOpts, closeErrSink, err := loader.newZapOptions(config.Zap, errOutputs)
+	if err != nil {
+		closeSink()
+		return nil, err
+	}
+
+	holder := &coreHolder{}
+	holder.store(core)
+	loader.levels.RegisterConfig(config.loggerName(), config)
+
+	return &ReloadableLogger{
+		loader:       loader,
+		filePath:     filePath,
+		fileType:     fileType,
+		zapLogger:    zap.New(newProxyCore(holder), append(configOpts, opts...)...),
+		holder:       holder,
+		closeSink:    closeSink,
+		closeErrSink: closeErrSink,
+	}, nil
+}
+
+// Logger returns zap logger, loggers derived from it follow reloading as well.
+func (logger *ReloadableLogger) Logger() *zap.Logger {
+	return logger.zapLogger
+}
+
+// Reload re-parses config file and swaps core of logger,
+// logger keeps the previous core if config file is invalid.
+// Reloaded config is registered again, levels set at runtime are discarded.
+// Outputs of the previous core are closed in background once entries being written to them are written,
+// so that no entries are lost during reloading.
+func (logger *ReloadableLogger) Reload() error {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.closed {
+		return errors.New("reloadable logger is closed")
+	}
+
+	config, err := logger.loader.NewConfigWithConfPath(logger.filePath, logger.fileType)
+	if err != nil {
+		return err
+	}
+
+	// files are reopened in case they are moved by logrotate or their settings are changed
+	reloading := *logger.loader
+	reloading.reopen = true
+	core, closeSink, err := reloading.newZapCoreWithConfig(config)
+	if err != nil {
+		return err
+	}
+
+	prev, closePrev := logger.holder.swap(core), logger.closeSink
+	logger.closeSink = closeSink
+	logger.loader.levels.RegisterConfig(config.loggerName(), config)
+
+	logger.retiring.Add(1)
+	go func() {
+		defer logger.retiring.Done()
+		prev.drain(reloadDrainTimeout)
+		prev.core.Sync()
+		closePrev()
+	}()
+
+	return nil
+}
+
+// Watch reloads logger in background once config file was written or replaced.
+// Failures of reloading are logged with logger itself.
+func (logger *ReloadableLogger) Watch() error {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.closed {
+		return errors.New("reloadable logger is closed")
+	}
+
+	if logger.watcher != nil {
+		return errors.New("config file is already watched")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// watch directory instead of file since editors and kubernetes config maps replace config file
+	if err := watcher.Add(filepath.Dir(logger.filePath)); err != nil {
+		watcher.Close()
+		return errors.Wrapf(err, "failed to watch config file, filePath:%s", logger.filePath)
+	}
+
+	logger.watcher = watcher
+	logger.done = make(chan struct{})
+	go logger.watch(watcher, logger.done)
+
+	return nil
+}
+
+// Close stops watching config file and handling signals, then closes outputs.
+// Logger should not be used after Close.
+func (logger *ReloadableLogger) Close() error {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	var err error
+	if logger.watcher != nil {
+		close(logger.done)
+		err = logger.watcher.Close()
+		logger.watcher = nil
+	}
+
+	logger.stopSignals()
+
+	if !logger.closed {
+		logger.closed = true
+		logger.retiring.Wait()
+		logger.zapLogger.Sync()
+		logger.closeSink()
+		logger.closeErrSink()
+	}
+
+	return err
+}
+
+// Reload logger with events of watcher until done is closed
+func (logger *ReloadableLogger) watch(watcher *fsnotify.Watcher, done chan struct{}) {
+	realPath, _ := filepath.EvalSymlinks(logger.filePath)
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			// config file itself is written or created, or target of symlink changed
+			currPath, _ := filepath.EvalSymlinks(logger.filePath)
+			written := filepath.Clean(event.Name) == logger.filePath && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+			if !written && (len(currPath) < 1 || currPath == realPath) {
+				continue
+			}
+
+			realPath = currPath
+			if err := logger.Reload(); err != nil {
+				logger.zapLogger.Error("failed to reload config file", zap.String("filePath", logger.filePath), zap.Error(err))
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			logger.zapLogger.Error("failed to watch config file", zap.String("filePath", logger.filePath), zap.Error(err))
+		}
+	}
+}
+
+// Build zap core with combined config
+func (loader *Loader) newZapCoreWithConfig(config *Config) (zapcore.Core, func(), error) {
+	if config.Zap == nil {
+		return nil, nil, errors.New("zap config is nil")
+	}
+
+	outputs, _ := config.toOutputConfigs()
+	return loader.newZapCore(config, outputs)
+}
+
+// Generation of core stored in coreHolder
+type coreGeneration struct {
+	// number of in-flight writes, keep it as the first word for 64-bit atomic operations on 32-bit platforms
+	refs int64
+	id   uint64
+	core zapcore.Core
+}
+
+// Release generation pinned by coreHolder.pin
+func (gen *coreGeneration) release() {
+	atomic.AddInt64(&gen.refs, -1)
+}
+
+// Wait until in-flight writes are done or timeout, generation should not be current any more
+func (gen *coreGeneration) drain(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&gen.refs) > 0 && time.Now().Before(deadline) {
+		time.Sleep(reloadDrainInterval)
+	}
+}
+
+// Hold current core which could be swapped atomically
+type coreHolder struct {
+	// keep id as the first word for 64-bit atomic operations on 32-bit platforms
+	id    uint64
+	value atomic.Value
+}
+
+// Store core as a new generation
+func (holder *coreHolder) store(core zapcore.Core) {
+	holder.value.Store(&coreGeneration{
+		id:   atomic.AddUint64(&holder.id, 1),
+		core: core,
+	})
+}
+
+// Store core as a new generation and return the previous generation
+func (holder *coreHolder) swap(core zapcore.Core) *coreGeneration {
+	prev := holder.load()
+	holder.store(core)
+	return prev
+}
+
+// Pin current generation until it is released, so that it is not closed while entries are being written.
+// Generation is pinned again if it is swapped meanwhile, since the previous one may be drained already.
+func (holder *coreHolder) pin() *coreGeneration {
+	for {
+		gen := holder.load()
+		atomic.AddInt64(&gen.refs, 1)
+		if holder.load() == gen {
+			return gen
+		}
+		gen.release()
+	}
+}
+
+// Load current generation
+func (holder *coreHolder) load() *coreGeneration {
+	return holder.value.Load().(*coreGeneration)
+}
+
+// proxyCore delegates to current core of coreHolder,
+// fields added with With() are applied to core of every generation.
+type proxyCore struct {
+	holder *coreHolder
+	fields []zapcore.Field
+	// core of current generation with fields
+	cache atomic.Value
+}
+
+// Create proxy core of holder
+func newProxyCore(holder *coreHolder) *proxyCore {
+	return &proxyCore{holder: holder}
+}
+
+// Get core of current generation with fields
+func (core *proxyCore) current() zapcore.Core {
+	return core.coreOf(core.holder.load())
+}
+
+// Get core of generation with fields
+func (core *proxyCore) coreOf(gen *coreGeneration) zapcore.Core {
+	if len(core.fields) < 1 {
+		return gen.core
+	}
+
+	if cached, ok := core.cache.Load().(*coreGeneration); ok && cached.id == gen.id {
+		return cached.core
+	}
+
+	cached := &coreGeneration{
+		id:   gen.id,
+		core: gen.core.With(core.fields),
+	}
+	core.cache.Store(cached)
+
+	return cached.core
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (core *proxyCore) Enabled(level zapcore.Level) bool {
+	return core.current().Enabled(level)
+}
+
+// With implements zapcore.Core
+func (core *proxyCore) With(fields []zapcore.Field) zapcore.Core {
+	res := &proxyCore{
+		holder: core.holder,
+		fields: make([]zapcore.Field, 0, len(core.fields)+len(fields)),
+	}
+	res.fields = append(res.fields, core.fields...)
+	res.fields = append(res.fields, fields...)
+
+	return res
+}
+
+// Check implements zapcore.Core, entry is added with core of current generation,
+// which is pinned until entry is written
+func (core *proxyCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	gen := core.holder.pin()
+	res := core.coreOf(gen).Check(entry, checked)
+	if res == nil {
+		gen.release()
+		return nil
+	}
+
+	// cores are written in order, so generation is released after its cores
+	return res.AddCore(entry, &generationReleaser{gen: gen})
+}
+
+// Write implements zapcore.Core
+func (core *proxyCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	gen := core.holder.pin()
+	defer gen.release()
+
+	return core.coreOf(gen).Write(entry, fields)
+}
+
+// Sync implements zapcore.Core
+func (core *proxyCore) Sync() error {
+	return core.current().Sync()
+}
+
+// generationReleaser is added to checked entries after cores of generation, it releases generation once
+// entry is written
+type generationReleaser struct {
+	gen *coreGeneration
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (core *generationReleaser) Enabled(zapcore.Level) bool {
+	return true
+}
+
+// With implements zapcore.Core
+func (core *generationReleaser) With([]zapcore.Field) zapcore.Core {
+	return core
+}
+
+// Check implements zapcore.Core
+func (core *generationReleaser) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked
+}
+
+// Write implements zapcore.Core
+func (core *generationReleaser) Write(zapcore.Entry, []zapcore.Field) error {
+	core.gen.release()
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (core *generationReleaser) Sync() error {
+	return nil
+}
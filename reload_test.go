@@ -0,0 +1,264 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Write yaml config file of level and output path
+func writeReloadConfig(t *testing.T, filePath, level, outputPath string) {
+	content := fmt.Sprintf(`---
+level: %s
+encoding: json
+encoderConfig:
+  messageKey: msg
+outputPaths: ["%s"]
+errorOutputPaths: ["stderr"]
+`, level, outputPath)
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+}
+
+// Read file content, empty string would be returned if file is missing
+func readFileContent(filePath string) string {
+	content, _ := ioutil.ReadFile(filePath)
+	return string(content)
+}
+
+func TestNewReloadableZapLogger_HappyCase(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	defer logger.Close()
+
+	assert.NotNil(t, logger.Logger())
+	assert.False(t, logger.Logger().Core().Enabled(zapcore.DebugLevel))
+	assert.True(t, logger.Logger().Core().Enabled(zapcore.InfoLevel))
+
+	logger.Logger().Info("ut-message")
+	assert.Contains(t, readFileContent(path.Join(dir, "ut.log")), "ut-message")
+}
+
+func TestNewReloadableZapLogger_WithNonExistFile(t *testing.T) {
+	logger, err := NewReloadableZapLogger(path.Join(newTempDir(t), "zap.yaml"), YAML)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+// With config file whose level is missing
+func TestNewReloadableZapLogger_WithoutLevel(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "zap.yaml")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`outputPaths: ["stdout"]`), 0644))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestReloadableLogger_Reload(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "before.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	defer logger.Close()
+
+	// derived logger follows reloading
+	derived := logger.Logger().With(zap.String("key", "value"))
+
+	writeReloadConfig(t, filePath, "debug", path.Join(dir, "after.log"))
+	assert.Nil(t, logger.Reload())
+
+	assert.True(t, logger.Logger().Core().Enabled(zapcore.DebugLevel))
+	assert.True(t, derived.Core().Enabled(zapcore.DebugLevel))
+
+	derived.Debug("ut-message")
+	assert.Empty(t, readFileContent(path.Join(dir, "before.log")))
+	assert.Contains(t, readFileContent(path.Join(dir, "after.log")), `"key":"value"`)
+	assert.Contains(t, readFileContent(path.Join(dir, "after.log")), "ut-message")
+}
+
+// Previous core is kept if config file is invalid
+func TestReloadableLogger_ReloadWithInvalidConfig(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	defer logger.Close()
+
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`level: [`), 0644))
+	assert.NotNil(t, logger.Reload())
+
+	logger.Logger().Info("ut-message")
+	assert.Contains(t, readFileContent(path.Join(dir, "ut.log")), "ut-message")
+}
+
+func TestReloadableLogger_Watch(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	defer logger.Close()
+
+	assert.Nil(t, logger.Watch())
+	// watch twice
+	assert.NotNil(t, logger.Watch())
+
+	writeReloadConfig(t, filePath, "debug", path.Join(dir, "ut.log"))
+	assert.Eventually(t, func() bool {
+		return logger.Logger().Core().Enabled(zapcore.DebugLevel)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Config file is replaced by renaming another file
+func TestReloadableLogger_WatchWithReplacedFile(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	defer logger.Close()
+	assert.Nil(t, logger.Watch())
+
+	tmpPath := path.Join(dir, "zap.yaml.tmp")
+	writeReloadConfig(t, tmpPath, "warn", path.Join(dir, "ut.log"))
+	assert.Nil(t, os.Rename(tmpPath, filePath))
+
+	assert.Eventually(t, func() bool {
+		return !logger.Logger().Core().Enabled(zapcore.InfoLevel)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Failures of reloading are logged with logger itself
+func TestReloadableLogger_WatchWithInvalidConfig(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	defer logger.Close()
+	assert.Nil(t, logger.Watch())
+
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`level: [`), 0644))
+	assert.Eventually(t, func() bool {
+		return strings.Contains(readFileContent(path.Join(dir, "ut.log")), "failed to reload config file")
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Entries written during reloading are not lost even with async outputs
+func TestReloadableLogger_ReloadWithConcurrentWrites(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	content := `---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "ut.log") + `"]
+async:
+  flushInterval: 1s
+`
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+
+	const writers, entries = 4, 500
+	wait := sync.WaitGroup{}
+	for i := 0; i < writers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < entries; j++ {
+				logger.Logger().Info("ut-message")
+			}
+		}()
+	}
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, logger.Reload())
+	}
+
+	wait.Wait()
+	assert.Nil(t, logger.Close())
+	assert.Equal(t, writers*entries, strings.Count(readFileContent(path.Join(dir, "ut.log")), "ut-message"))
+}
+
+func TestCoreHolder_Pin(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	holder := &coreHolder{}
+	holder.store(observed)
+
+	// generation is pinned until entry is written
+	core := newProxyCore(holder)
+	checked := core.Check(zapcore.Entry{Message: "ut-message"}, nil)
+	assert.Equal(t, int64(1), holder.load().refs)
+
+	prev := holder.swap(observed)
+	checked.Write()
+	assert.Equal(t, int64(0), prev.refs)
+	assert.Equal(t, 1, logs.Len())
+
+	// with disabled level
+	assert.Nil(t, newProxyCore(holder).Check(zapcore.Entry{Level: zapcore.DebugLevel}, nil))
+	assert.Equal(t, int64(0), holder.load().refs)
+}
+
+func TestReloadableLogger_Close(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	assert.Nil(t, logger.Watch())
+
+	assert.Nil(t, logger.Close())
+	// close twice
+	assert.Nil(t, logger.Close())
+
+	assert.NotNil(t, logger.Reload())
+	assert.NotNil(t, logger.Watch())
+}
+
+func TestProxyCore_With(t *testing.T) {
+	holder := &coreHolder{}
+	holder.store(zapcore.NewNopCore())
+
+	core := newProxyCore(holder).With([]zapcore.Field{zap.String("key", "value")})
+	assert.IsType(t, &proxyCore{}, core)
+	assert.Len(t, core.(*proxyCore).fields, 1)
+
+	// cached core is used until next generation
+	first := core.(*proxyCore).current()
+	assert.Equal(t, first, core.(*proxyCore).current())
+
+	holder.store(zapcore.NewNopCore())
+	assert.NotNil(t, core.(*proxyCore).current())
+	assert.Nil(t, core.Sync())
+	assert.Nil(t, core.Write(zapcore.Entry{}, nil))
+}
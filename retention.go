@@ -0,0 +1,259 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRetentionInterval is the default interval of checking total size of files by retention.
+const DefaultRetentionInterval = time.Minute
+
+// units of byte sizes in upper case, which are powers of 1024
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"T":   1 << 40,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// RetentionConfig caps total size of file outputs and their rotated files across all outputs of logger,
+// the oldest rotated files are deleted in background once total size exceeds. It supplements age and count limits
+// of rotation, files which are being written are never deleted.
+//
+// Example config file in YAML:
+//
+//	retention:
+//	  maxTotalSize: 5GB
+//	  interval: 1m
+type RetentionConfig struct {
+	// MaxTotalSize is total size of files, like 512MB or 5GB, units are powers of 1024.
+	MaxTotalSize string `json:"maxTotalSize" yaml:"maxTotalSize"`
+	// Interval is the interval of checking total size, like 30s, DefaultRetentionInterval if not provided.
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+// retentionJanitor deletes the oldest rotated files of file outputs periodically
+type retentionJanitor struct {
+	maxTotalSize int64
+	interval     time.Duration
+	// file paths of outputs, which may be filename templates
+	filePaths []string
+	done      chan struct{}
+	once      sync.Once
+}
+
+// file of output or rotated file
+type retentionFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	active  bool
+}
+
+// Create janitor of file paths of outputs with config, it is started with start()
+func newRetentionJanitor(config *RetentionConfig, filePaths []string) (*retentionJanitor, error) {
+	maxTotalSize, err := parseByteSize(config.MaxTotalSize)
+	if err != nil || maxTotalSize <= 0 {
+		return nil, errors.Errorf("invalid max total size of retention, maxTotalSize:%s", config.MaxTotalSize)
+	}
+
+	interval := DefaultRetentionInterval
+	if len(config.Interval) > 0 {
+		if interval, err = time.ParseDuration(config.Interval); err != nil || interval <= 0 {
+			return nil, errors.Errorf("invalid interval of retention, interval:%s", config.Interval)
+		}
+	}
+
+	return &retentionJanitor{
+		maxTotalSize: maxTotalSize,
+		interval:     interval,
+		filePaths:    filePaths,
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// Start janitor of file outputs among outputs and their fallbacks in background, the returned function stops it
+func (loader *Loader) startRetentionJanitor(config *RetentionConfig, outputs []*OutputConfig) (func(), error) {
+	filePaths := make([]string, 0)
+	for _, output := range outputs {
+		for ; output != nil; output = output.Fallback {
+			filePath, ok := toFilePath(output.Path)
+			if !ok {
+				continue
+			}
+
+			filePath, err := loader.resolvePath(filePath)
+			if err != nil {
+				return nil, err
+			}
+
+			filePaths = append(filePaths, filePath)
+		}
+	}
+
+	janitor, err := newRetentionJanitor(config, filePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	go janitor.run()
+	return janitor.stop, nil
+}
+
+// Sweep files once started and periodically afterwards until stopped
+func (janitor *retentionJanitor) run() {
+	ticker := time.NewTicker(janitor.interval)
+	defer ticker.Stop()
+
+	janitor.sweep()
+	for {
+		select {
+		case <-ticker.C:
+			janitor.sweep()
+		case <-janitor.done:
+			return
+		}
+	}
+}
+
+// Stop sweeping files
+func (janitor *retentionJanitor) stop() {
+	janitor.once.Do(func() {
+		close(janitor.done)
+	})
+}
+
+// Delete the oldest rotated files until total size is within max total size, errors are ignored since they are
+// retried in the next sweep
+func (janitor *retentionJanitor) sweep() {
+	files := janitor.listFiles()
+
+	var total int64
+	for i := range files {
+		total += files[i].size
+	}
+
+	// oldest first
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	for i := range files {
+		if total <= janitor.maxTotalSize {
+			return
+		}
+
+		if files[i].active {
+			continue
+		}
+
+		if err := os.Remove(files[i].path); err == nil || os.IsNotExist(err) {
+			total -= files[i].size
+		}
+	}
+}
+
+// List files of outputs and their rotated files, like app.log, app-2020-09-01T10-00-00.000.log.gz of lumberjack
+// and app-2020-09-01.log of timed rotation. The newest file expanded from filename template which varies with time
+// is treated as the file being written.
+func (janitor *retentionJanitor) listFiles() []retentionFile {
+	// files of outputs are never treated as rotated files of others, like app-error.log and app.log
+	actives := make(map[string]bool)
+	for _, template := range janitor.filePaths {
+		if !isFilenameTimeTemplate(template) {
+			// files compressed inline are written with extension of algorithm, like app.log.zst
+			filePath := ExpandFilename(template, time.Now())
+			actives[filePath] = true
+			for _, ext := range compressionExts {
+				actives[filePath+ext] = true
+			}
+		}
+	}
+
+	files := make(map[string]retentionFile)
+	for _, template := range janitor.filePaths {
+		timeTemplate := isFilenameTimeTemplate(template)
+
+		var matches []string
+		if timeTemplate {
+			matches = globWithCompression(filenameGlob(template))
+		} else {
+			filePath := ExpandFilename(template, time.Now())
+			ext := filepath.Ext(filePath)
+			matches, _ = filepath.Glob(strings.TrimSuffix(filePath, ext) + "-*" + ext + "*")
+			matches = append(matches, globWithCompression(filePath)...)
+		}
+
+		newest := ""
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() {
+				continue
+			}
+
+			files[match] = retentionFile{path: match, size: info.Size(), modTime: info.ModTime(), active: actives[match]}
+			if len(newest) == 0 || info.ModTime().After(files[newest].modTime) {
+				newest = match
+			}
+		}
+
+		if timeTemplate && len(newest) > 0 {
+			actives[newest] = true
+			file := files[newest]
+			file.active = true
+			files[newest] = file
+		}
+	}
+
+	res := make([]retentionFile, 0, len(files))
+	for _, file := range files {
+		file.active = file.active || actives[file.path]
+		res = append(res, file)
+	}
+
+	return res
+}
+
+// Parse byte size with unit, like 512MB, 5GB or 1.5 GiB, units are powers of 1024
+func parseByteSize(text string) (int64, error) {
+	text = strings.TrimSpace(text)
+	i := strings.IndexFunc(text, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(text)
+	}
+
+	unit, ok := byteSizeUnits[strings.ToUpper(strings.TrimSpace(text[i:]))]
+	if !ok {
+		return 0, errors.Errorf("invalid unit of byte size, size:%s", text)
+	}
+
+	value, err := strconv.ParseFloat(text[:i], 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid byte size, size:%s", text)
+	}
+
+	return int64(value * float64(unit)), nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Write file with size and modification time which is minutes ago
+func writeRetentionFile(t *testing.T, filePath string, size, minutes int) {
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(strings.Repeat("x", size)), 0644))
+	modTime := time.Now().Add(-time.Duration(minutes) * time.Minute)
+	assert.Nil(t, os.Chtimes(filePath, modTime, modTime))
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"1024":    1024,
+		"512B":    512,
+		"4k":      4 << 10,
+		"512MB":   512 << 20,
+		"5GB":     5 << 30,
+		"1.5 GiB": 3 << 29,
+		" 2TB ":   2 << 40,
+	}
+
+	for text, expected := range tests {
+		size, err := parseByteSize(text)
+		assert.Nil(t, err, text)
+		assert.Equal(t, expected, size, text)
+	}
+
+	for _, text := range []string{"", "GB", "5XB", "1.2.3MB", "-1MB"} {
+		_, err := parseByteSize(text)
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestNewRetentionJanitor_WithInvalidConfig(t *testing.T) {
+	janitor, err := newRetentionJanitor(&RetentionConfig{MaxTotalSize: "5XB"}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, janitor)
+
+	janitor, err = newRetentionJanitor(&RetentionConfig{MaxTotalSize: "0"}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, janitor)
+
+	janitor, err = newRetentionJanitor(&RetentionConfig{MaxTotalSize: "5GB", Interval: "1x"}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, janitor)
+}
+
+func TestRetentionJanitor_Sweep(t *testing.T) {
+	dir := newTempDir(t)
+	writeRetentionFile(t, path.Join(dir, "app.log"), 100, 0)
+	writeRetentionFile(t, path.Join(dir, "app-error.log"), 100, 30)
+	writeRetentionFile(t, path.Join(dir, "app-2020-09-01T10-00-00.000.log.gz"), 100, 20)
+	writeRetentionFile(t, path.Join(dir, "app-2020-09-01T11-00-00.000.log"), 100, 10)
+	writeRetentionFile(t, path.Join(dir, "other.log"), 100, 40)
+
+	janitor, err := newRetentionJanitor(&RetentionConfig{MaxTotalSize: "350"},
+		[]string{path.Join(dir, "app.log"), path.Join(dir, "app-error.log")})
+	assert.Nil(t, err)
+
+	// files of outputs are kept even if they are older than rotated files
+	janitor.sweep()
+	assert.Equal(t, []string{"app-2020-09-01T11-00-00.000.log", "app-error.log", "app.log", "other.log"}, listFileNames(t, dir))
+
+	// files of outputs are never deleted
+	janitor.maxTotalSize = 1
+	janitor.sweep()
+	assert.Equal(t, []string{"app-error.log", "app.log", "other.log"}, listFileNames(t, dir))
+}
+
+func TestRetentionJanitor_WithFilenameTemplate(t *testing.T) {
+	dir := newTempDir(t)
+	writeRetentionFile(t, path.Join(dir, "app-01.log"), 100, 20)
+	writeRetentionFile(t, path.Join(dir, "app-02.log"), 100, 10)
+	writeRetentionFile(t, path.Join(dir, "app-03.log"), 100, 0)
+
+	janitor, err := newRetentionJanitor(&RetentionConfig{MaxTotalSize: "1"}, []string{path.Join(dir, "app-%d.log")})
+	assert.Nil(t, err)
+
+	// the newest file is being written
+	janitor.sweep()
+	assert.Equal(t, []string{"app-03.log"}, listFileNames(t, dir))
+}
+
+func TestRetention_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	writeRetentionFile(t, path.Join(dir, "ut-2020-09-01T10-00-00.000.log"), 100, 10)
+
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  outputPaths: ["` + path.Join(dir, "ut.log") + `"]
+retention:
+  maxTotalSize: 10B
+  interval: 10ms
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, &RetentionConfig{MaxTotalSize: "10B", Interval: "10ms"}, config.Retention)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	defer closer.Shutdown(context.Background())
+	logger.Info("ut-message")
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path.Join(dir, "ut-2020-09-01T10-00-00.000.log"))
+		return os.IsNotExist(err)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWithRetention(t *testing.T) {
+	config := NewConfigWithOptions(WithRetention("5GB"))
+	assert.Equal(t, &RetentionConfig{MaxTotalSize: "5GB"}, config.Retention)
+}
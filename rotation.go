@@ -0,0 +1,414 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationConfig rotates file outputs with driver, which is lumberjack, timed or driver registered with
+// RegisterRotator(). Timed driver rotates files on schedule instead of size, the current file keeps its name and
+// rotated files are renamed with timestamp of the period they cover, like app-2020-09-01.log.
+//
+// Example config file in YAML:
+//
+//	rotation:
+//	  driver: timed
+//	  schedule: daily
+//	  maxBackups: 7
+//	  localTime: true
+type RotationConfig struct {
+	// Driver is lumberjack, timed or driver registered with RegisterRotator(),
+	// timed if schedule is provided and lumberjack otherwise by default.
+	Driver string `json:"driver,omitempty" yaml:"driver,omitempty"`
+	// Schedule is daily, hourly or cron expression with five fields, like 0 */6 * * *.
+	Schedule string `json:"schedule" yaml:"schedule"`
+	// TimeFormat is layout of timestamp in names of rotated files, 2006-01-02 for daily schedule,
+	// 2006-01-02T15 for hourly schedule and 2006-01-02T15-04 for cron expression by default.
+	TimeFormat string `json:"timeFormat" yaml:"timeFormat"`
+	// MaxBackups is the maximum number of rotated files to retain, all of them are retained if zero.
+	MaxBackups int `json:"maxBackups" yaml:"maxBackups"`
+	// MaxAge is the maximum number of days to retain rotated files, all of them are retained if zero.
+	MaxAge int `json:"maxAge" yaml:"maxAge"`
+	// LocalTime uses local time for schedule and timestamps, UTC is used by default the same way as lumberjack.
+	LocalTime bool `json:"localTime" yaml:"localTime"`
+	// MaxSize is the maximum size in megabytes of file before it is rotated by lumberjack driver.
+	MaxSize int `json:"maxSize,omitempty" yaml:"maxSize,omitempty"`
+	// Compress compresses rotated files with gzip by lumberjack driver, which is ignored if compression is provided.
+	Compress bool `json:"compress,omitempty" yaml:"compress,omitempty"`
+	// Compression compresses files with zstd or gzip, see CompressionConfig.
+	Compression *CompressionConfig `json:"compression,omitempty" yaml:"compression,omitempty"`
+	// Symlink is path of symlink which always points at the current file, like app.log -> app-20200901.log,
+	// relative path is resolved in directory of file. Existing file which is not a symlink is never replaced.
+	Symlink string `json:"symlink,omitempty" yaml:"symlink,omitempty"`
+	// Options are settings of drivers registered with RegisterRotator(), which are ignored by built-in drivers.
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+	// Hooks are fired in order after every rotation, see RotationHookConfig.
+	Hooks []*RotationHookConfig `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+
+	// permissions of files and directories created by built-in drivers, see PermissionsConfig
+	permissions *filePermissions
+}
+
+// Driver of config, timed if schedule is provided and lumberjack otherwise by default
+func (config *RotationConfig) driver() string {
+	if len(config.Driver) > 0 {
+		return config.Driver
+	}
+
+	if len(config.Schedule) > 0 {
+		return RotationDriverTimed
+	}
+
+	return RotationDriverLumberjack
+}
+
+// timedWriter writes to file which is rotated on schedule
+type timedWriter struct {
+	// template of filename which is expanded at open and rotate time, see ExpandFilename()
+	template string
+	// whether template varies with time, files are not renamed while rotating in that case
+	timeTemplate bool
+	filename     string
+	config       RotationConfig
+	schedule     schedule
+	layout       string
+	file         *os.File
+	// extension of files compressed inline, which is appended to names of current and rotated files
+	ext string
+	// compresses entries into file if compressed inline
+	compressor compressWriter
+	// start of current period and time of the next rotation
+	start time.Time
+	next  time.Time
+	mutex sync.Mutex
+	// returns current time, replaced in tests
+	now func() time.Time
+	// called with path of rotated file after rotation
+	onRotate func(path string)
+}
+
+// Create writer of file rotated with config, file is opened while writing the first time
+func newTimedWriter(filename string, config RotationConfig) (*timedWriter, error) {
+	schedule, err := parseSchedule(config.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := config.TimeFormat
+	if len(layout) == 0 {
+		switch strings.ToLower(strings.TrimSpace(config.Schedule)) {
+		case ScheduleDaily, "@daily", "@midnight":
+			layout = "2006-01-02"
+		case ScheduleHourly, "@hourly":
+			layout = "2006-01-02T15"
+		default:
+			layout = "2006-01-02T15-04"
+		}
+	}
+
+	writer := &timedWriter{
+		template:     filename,
+		timeTemplate: isFilenameTimeTemplate(filename),
+		config:       config,
+		schedule:     schedule,
+		layout:       layout,
+		now:          time.Now,
+	}
+
+	if config.Compression != nil {
+		if err := config.Compression.validate(); err != nil {
+			return nil, err
+		}
+
+		if config.Compression.inline() {
+			writer.ext = config.Compression.ext()
+		}
+	}
+
+	return writer, nil
+}
+
+// Current time in location of config
+func (w *timedWriter) currentTime() time.Time {
+	if w.config.LocalTime {
+		return w.now().Local()
+	}
+
+	return w.now().UTC()
+}
+
+// Write implements io.Writer, file is rotated before writing if the next rotation is due
+func (w *timedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := w.currentTime()
+	if w.file == nil {
+		if err := w.open(now); err != nil {
+			return 0, err
+		}
+	}
+
+	if !w.next.IsZero() && !now.Before(w.next) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Write(p)
+	}
+
+	return w.file.Write(p)
+}
+
+// Sync implements zapcore.WriteSyncer
+func (w *timedWriter) Sync() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	if w.compressor != nil {
+		if err := w.compressor.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return w.file.Sync()
+}
+
+// Rotate implements Rotator, current file is rotated immediately
+func (w *timedWriter) Rotate() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := w.currentTime()
+	if w.file == nil {
+		if err := w.open(now); err != nil {
+			return err
+		}
+	}
+
+	return w.rotate(now)
+}
+
+// OnRotate implements RotationNotifier
+func (w *timedWriter) OnRotate(f func(path string)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.onRotate = f
+}
+
+// Close closes current file
+func (w *timedWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+
+	return w.closeFile()
+}
+
+// Open file expanded from template, existing file continues its period which starts at its modification time
+func (w *timedWriter) open(now time.Time) error {
+	w.filename = ExpandFilename(w.template, now)
+
+	w.start = now
+	if info, err := os.Stat(w.filename + w.ext); err == nil {
+		w.start = info.ModTime().In(now.Location())
+	}
+
+	if err := w.openFile(); err != nil {
+		return err
+	}
+
+	w.next = w.schedule.next(w.start)
+	return nil
+}
+
+// Rename current file with timestamp of its period and open a new one,
+// file is not renamed if filename expanded from template changes with time
+func (w *timedWriter) rotate(now time.Time) error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+
+	rotated := w.filename + w.ext
+	filename := ExpandFilename(w.template, now)
+	if filename == w.filename {
+		rotated = w.backupName(w.start)
+		if err := os.Rename(w.filename+w.ext, rotated); err != nil {
+			if !os.IsNotExist(err) {
+				return errors.Wrapf(err, "failed to rotate file, filename:%s", w.filename)
+			}
+			rotated = ""
+		}
+	}
+
+	w.filename = filename
+	if err := w.openFile(); err != nil {
+		return err
+	}
+
+	w.start = now
+	w.next = w.schedule.next(now)
+	w.removeBackups(now)
+
+	if w.onRotate != nil && len(rotated) > 0 {
+		w.onRotate(rotated)
+	}
+	return nil
+}
+
+// Open current file for appending, parent directories are created if missing
+func (w *timedWriter) openFile() error {
+	if err := w.config.permissions.mkdirAll(filepath.Dir(w.filename), DefaultDirMode); err != nil {
+		return err
+	}
+
+	file, err := w.config.permissions.openFile(w.filename + w.ext)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open file, filename:%s", w.filename+w.ext)
+	}
+
+	// entries appended to existing file are compressed into a new frame, which is decompressed along with others
+	if len(w.ext) > 0 {
+		compression := w.config.Compression
+		if w.compressor, err = newCompressWriter(compression.Algorithm, compression.Level, file); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	w.file = file
+	updateRotationSymlink(w.config.Symlink, w.filename+w.ext)
+	return nil
+}
+
+// Close current file, compressor is closed before file to write the end of its frame
+func (w *timedWriter) closeFile() error {
+	var err error
+	if w.compressor != nil {
+		err = w.compressor.Close()
+		w.compressor = nil
+	}
+
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+
+	return err
+}
+
+// Name of rotated file with timestamp, index is appended if the name is taken, like app-2020-09-01-1.log,
+// extension of inline compression is appended at last, like app-2020-09-01.log.zst
+func (w *timedWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.filename)
+	prefix := strings.TrimSuffix(w.filename, ext) + "-" + t.Format(w.layout)
+
+	name := prefix + ext + w.ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return name
+		}
+		name = prefix + "-" + strconv.Itoa(i) + ext + w.ext
+	}
+}
+
+// rotated file with timestamp parsed from its name
+type timedBackup struct {
+	path string
+	time time.Time
+}
+
+// Remove rotated files beyond max backups or older than max age, errors are ignored since they are retried
+// in the next rotation
+func (w *timedWriter) removeBackups(now time.Time) {
+	if w.config.MaxBackups <= 0 && w.config.MaxAge <= 0 {
+		return
+	}
+
+	backups := w.listBackups(now.Location())
+
+	// newest first
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+
+	cutoff := now.AddDate(0, 0, -w.config.MaxAge)
+	for i := range backups {
+		if (w.config.MaxBackups > 0 && i >= w.config.MaxBackups) ||
+			(w.config.MaxAge > 0 && backups[i].time.Before(cutoff)) {
+			os.Remove(backups[i].path)
+		}
+	}
+}
+
+// List rotated files, files expanded from template which varies with time are listed with modification time,
+// and renamed files are listed with timestamp parsed from their names, compressed files are listed as well
+func (w *timedWriter) listBackups(loc *time.Location) []timedBackup {
+	backups := make([]timedBackup, 0)
+
+	if w.timeTemplate {
+		for _, match := range globWithCompression(filenameGlob(w.template)) {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() || match == w.filename+w.ext {
+				continue
+			}
+
+			backups = append(backups, timedBackup{path: match, time: info.ModTime().In(loc)})
+		}
+
+		return backups
+	}
+
+	dir := filepath.Dir(w.filename)
+	ext := filepath.Ext(w.filename)
+	prefix := strings.TrimSuffix(filepath.Base(w.filename), ext) + "-"
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return backups
+	}
+
+	for _, info := range infos {
+		name := trimCompressionExt(info.Name())
+		if info.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		// index appended to taken names is not part of timestamp
+		if len(stamp) > len(w.layout) {
+			stamp = stamp[:len(w.layout)]
+		}
+
+		t, err := time.ParseInLocation(w.layout, stamp, loc)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, timedBackup{path: filepath.Join(dir, info.Name()), time: t})
+	}
+
+	return backups
+}
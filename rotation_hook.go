@@ -0,0 +1,425 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of built-in actions which could be used by RotationHookConfig.Action
+const (
+	// RotationHookZstd compresses rotated file with zstd into file with .zst extension and removes the original one.
+	RotationHookZstd = "zstd"
+	// RotationHookGzip compresses rotated file with gzip into file with .gz extension and removes the original one.
+	RotationHookGzip = "gzip"
+	// RotationHookChecksum writes checksum of rotated file into file with extension of algorithm, like .sha256.
+	RotationHookChecksum = "checksum"
+	// RotationHookUpload uploads rotated file to url with http.
+	RotationHookUpload = "upload"
+	// RotationHookExec runs command with rotated file.
+	RotationHookExec = "exec"
+	// RotationHookSign writes signed manifest of rotated file into file with .manifest extension, see RotationManifest.
+	RotationHookSign = "sign"
+)
+
+// DefaultRotationHookTimeout is the default timeout of upload and exec hooks.
+const DefaultRotationHookTimeout = time.Minute
+
+// RotationHook is called with path of rotated file after rotation, and returns path of file which is passed to the
+// next hook, like path of compressed file.
+type RotationHook func(ctx context.Context, path string) (string, error)
+
+// RotationHookFactory creates rotation hook with config.
+type RotationHookFactory func(config *RotationHookConfig) (RotationHook, error)
+
+// RotationHookConfig is a hook fired after rotation, hooks of rotation section run in order in background.
+//
+// Example config file in YAML:
+//
+//	rotation:
+//	  schedule: daily
+//	  hooks:
+//	    - action: zstd
+//	    - action: checksum
+//	      algorithm: sha256
+//	    - action: sign
+//	      keyFile: /run/secrets/log-signing-key.pem
+//	    - action: upload
+//	      url: https://storage.example.com/logs/{name}
+//	      headers:
+//	        Authorization: Bearer ${LOG_UPLOAD_TOKEN}
+//	    - action: exec
+//	      command: ["logger", "-t", "app", "rotated {path}"]
+type RotationHookConfig struct {
+	// Action is zstd, gzip, checksum, sign, upload, exec or action registered with RegisterRotationHook().
+	Action string `json:"action" yaml:"action"`
+	// Level is compression level of zstd and gzip, default level of them if zero.
+	Level int `json:"level,omitempty" yaml:"level,omitempty"`
+	// Algorithm is md5, sha1, sha256 or sha512 of checksum, and sha256 or sha512 of sign, sha256 by default.
+	Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	// KeyFile is path of PEM encoded private key of sign, which is ed25519, ECDSA or RSA key in PKCS#8, SEC 1 or PKCS#1.
+	// Relative path is resolved against base directory of Loader.
+	KeyFile string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	// URL is destination of upload, {name} is replaced with name of file.
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// Method is http method of upload, PUT by default.
+	Method string `json:"method,omitempty" yaml:"method,omitempty"`
+	// Headers are http headers of upload.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Command is command and its arguments of exec, {path} and {name} in arguments are replaced with path and name of file.
+	Command []string `json:"command,omitempty" yaml:"command,omitempty"`
+	// Timeout is timeout of upload and exec, like 30s, DefaultRotationHookTimeout if not provided.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Options are settings of actions registered with RegisterRotationHook(), which are ignored by built-in actions.
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// RotationNotifier is implemented by rotators which could notify rotations, rotation hooks are only supported by
+// rotators which implement it. The function is called with path of rotated file after every rotation.
+type RotationNotifier interface {
+	OnRotate(func(path string))
+}
+
+var (
+	// rotation hook factories keyed by lower case action
+	rotationHookFactories = map[string]RotationHookFactory{
+		RotationHookZstd:     newZstdRotationHook,
+		RotationHookGzip:     newGzipRotationHook,
+		RotationHookChecksum: newChecksumRotationHook,
+		RotationHookUpload:   newUploadRotationHook,
+		RotationHookExec:     newExecRotationHook,
+		RotationHookSign:     newSignRotationHook,
+	}
+	rotationHookMutex sync.RWMutex
+
+	// hash functions of checksum keyed by algorithm
+	checksumHashes = map[string]func() hash.Hash{
+		"md5":    md5.New,
+		"sha1":   sha1.New,
+		"sha256": sha256.New,
+		"sha512": sha512.New,
+	}
+)
+
+// RegisterRotationHook registers factory of rotation hooks with action, so that it could be chosen by action of hooks
+// in rotation section. Error would be returned if the action is already registered.
+func RegisterRotationHook(action string, factory RotationHookFactory) error {
+	if factory == nil {
+		return errors.Errorf("rotation hook factory is nil, action:%s", action)
+	}
+
+	action = strings.ToLower(action)
+
+	rotationHookMutex.Lock()
+	defer rotationHookMutex.Unlock()
+
+	if _, ok := rotationHookFactories[action]; ok {
+		return errors.Errorf("rotation hook is already registered, action:%s", action)
+	}
+
+	rotationHookFactories[action] = factory
+	return nil
+}
+
+// rotationHooks runs hooks in order in background with path of rotated file
+type rotationHooks struct {
+	hooks   []RotationHook
+	actions []string
+}
+
+// Create hooks with configs in order
+func newRotationHooks(configs []*RotationHookConfig) (*rotationHooks, error) {
+	res := &rotationHooks{}
+	for _, config := range configs {
+		rotationHookMutex.RLock()
+		factory, ok := rotationHookFactories[strings.ToLower(config.Action)]
+		rotationHookMutex.RUnlock()
+
+		if !ok {
+			return nil, errors.Errorf("rotation hook is not registered, action:%s", config.Action)
+		}
+
+		hook, err := factory(config)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create rotation hook, action:%s", config.Action)
+		}
+
+		res.add(config.Action, hook)
+	}
+
+	return res, nil
+}
+
+// Append hook with name of action
+func (hooks *rotationHooks) add(action string, hook RotationHook) {
+	hooks.hooks = append(hooks.hooks, hook)
+	hooks.actions = append(hooks.actions, action)
+}
+
+// Run hooks in background, errors are written to stderr since rotation happens while writing entries
+func (hooks *rotationHooks) fire(path string) {
+	go hooks.run(path)
+}
+
+// Run hooks in order, the rest of them are skipped once any of them fails
+func (hooks *rotationHooks) run(path string) {
+	for i := range hooks.hooks {
+		next, err := hooks.hooks[i](context.Background(), path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v rotation hook failed, action:%s, path:%s, error:%v\n",
+				time.Now(), hooks.actions[i], path, err)
+			return
+		}
+
+		path = next
+	}
+}
+
+// Copy rotation with key files of hooks resolved against base directory of Loader, rotation is returned as it is if
+// none of hooks has key file
+func (loader *Loader) resolveHookFiles(rotation *RotationConfig) (*RotationConfig, error) {
+	if rotation == nil {
+		return nil, nil
+	}
+
+	resolved := false
+	hooks := make([]*RotationHookConfig, len(rotation.Hooks))
+	for i, hook := range rotation.Hooks {
+		hooks[i] = hook
+		if hook == nil || len(hook.KeyFile) == 0 {
+			continue
+		}
+
+		keyFile, err := loader.resolvePath(hook.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		withKeyFile := *hook
+		withKeyFile.KeyFile = keyFile
+		hooks[i] = &withKeyFile
+		resolved = true
+	}
+
+	if !resolved {
+		return rotation, nil
+	}
+
+	res := *rotation
+	res.Hooks = hooks
+	return &res, nil
+}
+
+// Parse timeout of config
+func (config *RotationHookConfig) timeout() (time.Duration, error) {
+	if len(config.Timeout) == 0 {
+		return DefaultRotationHookTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(config.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, errors.Errorf("invalid timeout of rotation hook, timeout:%s", config.Timeout)
+	}
+
+	return timeout, nil
+}
+
+// Create hook which compresses file with zstd
+func newZstdRotationHook(config *RotationHookConfig) (RotationHook, error) {
+	return newCompressionRotationHook(CompressionZstd, config.Level)
+}
+
+// Create hook which compresses file with gzip
+func newGzipRotationHook(config *RotationHookConfig) (RotationHook, error) {
+	return newCompressionRotationHook(CompressionGzip, config.Level)
+}
+
+// Create hook which compresses file with algorithm and level
+func newCompressionRotationHook(algorithm string, level int) (RotationHook, error) {
+	if _, err := newCompressWriter(algorithm, level, ioutil.Discard); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, path string) (string, error) {
+		return compressFile(path, algorithm, level)
+	}, nil
+}
+
+// Compress file with algorithm into file with its extension and remove the original one,
+// path of compressed file is returned
+func compressFile(path, algorithm string, level int) (string, error) {
+	ext := compressionExts[algorithm]
+
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	dst, err := os.OpenFile(path+ext, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+
+	// compressed file keeps permission of the original one regardless of umask
+	var writer compressWriter
+	if err = dst.Chmod(info.Mode().Perm()); err == nil {
+		writer, err = newCompressWriter(algorithm, level, dst)
+	}
+	if err == nil {
+		if _, err = io.Copy(writer, src); err == nil {
+			err = writer.Close()
+		}
+	}
+
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(path + ext)
+		return "", err
+	}
+
+	src.Close()
+	return path + ext, os.Remove(path)
+}
+
+// Create hook which writes checksum of file into file with extension of algorithm, like sha256sum
+func newChecksumRotationHook(config *RotationHookConfig) (RotationHook, error) {
+	algorithm := strings.ToLower(config.Algorithm)
+	if len(algorithm) == 0 {
+		algorithm = "sha256"
+	}
+
+	newHash, ok := checksumHashes[algorithm]
+	if !ok {
+		return nil, errors.Errorf("invalid algorithm of checksum, algorithm:%s", config.Algorithm)
+	}
+
+	return func(ctx context.Context, path string) (string, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		h := newHash()
+		if _, err := io.Copy(h, file); err != nil {
+			return "", err
+		}
+
+		line := hex.EncodeToString(h.Sum(nil)) + "  " + filepath.Base(path) + "\n"
+		return path, ioutil.WriteFile(path+"."+algorithm, []byte(line), 0644)
+	}, nil
+}
+
+// Create hook which uploads file with http
+func newUploadRotationHook(config *RotationHookConfig) (RotationHook, error) {
+	if len(config.URL) == 0 {
+		return nil, errors.New("url of upload is empty")
+	}
+
+	method := strings.ToUpper(config.Method)
+	if len(method) == 0 {
+		method = http.MethodPut
+	}
+
+	timeout, err := config.timeout()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	return func(ctx context.Context, path string) (string, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			return "", err
+		}
+
+		u := strings.Replace(config.URL, "{name}", filepath.Base(path), -1)
+		req, err := http.NewRequestWithContext(ctx, method, u, file)
+		if err != nil {
+			return "", err
+		}
+
+		req.ContentLength = info.Size()
+		for k, v := range config.Headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		io.Copy(ioutil.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return "", errors.Errorf("failed to upload file, url:%s, status:%d", u, resp.StatusCode)
+		}
+
+		return path, nil
+	}, nil
+}
+
+// Create hook which runs command with file
+func newExecRotationHook(config *RotationHookConfig) (RotationHook, error) {
+	if len(config.Command) == 0 {
+		return nil, errors.New("command of exec is empty")
+	}
+
+	timeout, err := config.timeout()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, path string) (string, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		replacer := strings.NewReplacer("{path}", path, "{name}", filepath.Base(path))
+		args := make([]string, 0, len(config.Command))
+		for _, arg := range config.Command {
+			args = append(args, replacer.Replace(arg))
+		}
+
+		if out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+			return "", errors.Wrapf(err, "failed to run command, output:%s", strings.TrimSpace(string(out)))
+		}
+
+		return path, nil
+	}, nil
+}
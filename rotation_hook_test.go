@@ -0,0 +1,238 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"compress/gzip"
+	"context"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Create rotation hook with config
+func newTestRotationHook(t *testing.T, config *RotationHookConfig) RotationHook {
+	rotationHookMutex.RLock()
+	factory := rotationHookFactories[config.Action]
+	rotationHookMutex.RUnlock()
+
+	hook, err := factory(config)
+	assert.Nil(t, err)
+	return hook
+}
+
+// Write rotated file into temp directory
+func writeRotatedFile(t *testing.T, content string) string {
+	filePath := path.Join(newTempDir(t), "app-2020-09-01.log")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	return filePath
+}
+
+func TestRotationHook_WithZstd(t *testing.T) {
+	filePath := writeRotatedFile(t, "ut-content")
+
+	res, err := newTestRotationHook(t, &RotationHookConfig{Action: RotationHookZstd, Level: 3})(context.Background(), filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, filePath+".zst", res)
+	assert.NoFileExists(t, filePath)
+
+	file, err := os.Open(res)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	reader, err := zstd.NewReader(file)
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-content", string(content))
+}
+
+func TestRotationHook_WithGzip(t *testing.T) {
+	filePath := writeRotatedFile(t, "ut-content")
+
+	res, err := newTestRotationHook(t, &RotationHookConfig{Action: RotationHookGzip})(context.Background(), filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, filePath+".gz", res)
+	assert.NoFileExists(t, filePath)
+
+	file, err := os.Open(res)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	assert.Nil(t, err)
+	content, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, "ut-content", string(content))
+
+	// invalid level
+	_, err = newGzipRotationHook(&RotationHookConfig{Action: RotationHookGzip, Level: 42})
+	assert.NotNil(t, err)
+}
+
+func TestRotationHook_WithChecksum(t *testing.T) {
+	filePath := writeRotatedFile(t, "ut-content")
+
+	res, err := newTestRotationHook(t, &RotationHookConfig{Action: RotationHookChecksum})(context.Background(), filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, filePath, res)
+	assert.Equal(t, "d5f61cac53d5aa50ed92e73e761c9e9b38b7a750b40b9d61aadb65179eae7bf4  app-2020-09-01.log\n",
+		readFileContent(filePath+".sha256"))
+
+	res, err = newTestRotationHook(t, &RotationHookConfig{Action: RotationHookChecksum, Algorithm: "MD5"})(context.Background(), filePath)
+	assert.Nil(t, err)
+	assert.FileExists(t, filePath+".md5")
+
+	_, err = newChecksumRotationHook(&RotationHookConfig{Action: RotationHookChecksum, Algorithm: "crc"})
+	assert.NotNil(t, err)
+}
+
+func TestRotationHook_WithUpload(t *testing.T) {
+	var method, uri, auth, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		method, uri, auth, body = r.Method, r.RequestURI, r.Header.Get("Authorization"), string(content)
+		if strings.Contains(r.RequestURI, "denied") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	filePath := writeRotatedFile(t, "ut-content")
+	hook := newTestRotationHook(t, &RotationHookConfig{
+		Action:  RotationHookUpload,
+		URL:     server.URL + "/logs/{name}",
+		Headers: map[string]string{"Authorization": "Bearer ut-token"},
+	})
+
+	res, err := hook(context.Background(), filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, filePath, res)
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/logs/app-2020-09-01.log", uri)
+	assert.Equal(t, "Bearer ut-token", auth)
+	assert.Equal(t, "ut-content", body)
+
+	// failed with status
+	hook = newTestRotationHook(t, &RotationHookConfig{Action: RotationHookUpload, URL: server.URL + "/denied", Method: "post"})
+	_, err = hook(context.Background(), filePath)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.MethodPost, method)
+
+	_, err = newUploadRotationHook(&RotationHookConfig{Action: RotationHookUpload})
+	assert.NotNil(t, err)
+	_, err = newUploadRotationHook(&RotationHookConfig{Action: RotationHookUpload, URL: server.URL, Timeout: "1x"})
+	assert.NotNil(t, err)
+}
+
+func TestRotationHook_WithExec(t *testing.T) {
+	filePath := writeRotatedFile(t, "ut-content")
+
+	hook := newTestRotationHook(t, &RotationHookConfig{Action: RotationHookExec, Command: []string{"cp", "{path}", "{path}.copy"}})
+	res, err := hook(context.Background(), filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, filePath, res)
+	assert.Equal(t, "ut-content", readFileContent(filePath+".copy"))
+
+	// failed with exit code
+	hook = newTestRotationHook(t, &RotationHookConfig{Action: RotationHookExec, Command: []string{"cp", "{name}.missing", "{path}"}})
+	_, err = hook(context.Background(), filePath)
+	assert.NotNil(t, err)
+
+	_, err = newExecRotationHook(&RotationHookConfig{Action: RotationHookExec})
+	assert.NotNil(t, err)
+}
+
+func TestRegisterRotationHook(t *testing.T) {
+	assert.NotNil(t, RegisterRotationHook(RotationHookZstd, newZstdRotationHook))
+	assert.NotNil(t, RegisterRotationHook("ut-nil", nil))
+
+	paths := make(chan string, 1)
+	assert.Nil(t, RegisterRotationHook("UT-Notify", func(config *RotationHookConfig) (RotationHook, error) {
+		return func(ctx context.Context, path string) (string, error) {
+			paths <- path
+			return path, nil
+		}, nil
+	}))
+
+	// hooks run in order with path returned by the previous one
+	dir := newTempDir(t)
+	rotator, err := newRotator(path.Join(dir, "app.log"), &RotationConfig{
+		Schedule: ScheduleDaily,
+		Hooks:    []*RotationHookConfig{{Action: RotationHookGzip}, {Action: "ut-notify"}},
+	}, nil)
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	rotator.Write([]byte("ut-content\n"))
+	assert.Nil(t, rotator.Rotate())
+
+	select {
+	case res := <-paths:
+		assert.True(t, strings.HasPrefix(filepath.Base(res), "app-"))
+		assert.True(t, strings.HasSuffix(res, ".log.gz"))
+	case <-time.After(time.Second):
+		assert.Fail(t, "rotation hooks are not fired")
+	}
+}
+
+func TestNewRotator_WithInvalidHooks(t *testing.T) {
+	rotator, err := newRotator("ut.log", &RotationConfig{
+		Schedule: ScheduleDaily,
+		Hooks:    []*RotationHookConfig{{Action: "ut-unknown"}},
+	}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, rotator)
+
+	// rotators which do not notify rotations
+	assert.Nil(t, RegisterRotator("ut-silent", func(filename string, config *RotationConfig) (Rotator, error) {
+		return &memoryRotator{}, nil
+	}))
+	rotator, err = newRotator("ut.log", &RotationConfig{
+		Driver: "ut-silent",
+		Hooks:  []*RotationHookConfig{{Action: RotationHookZstd}},
+	}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, rotator)
+}
+
+func TestNotifyingLumberjack(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+
+	rotator, err := newLumberjackRotator(filePath, &RotationConfig{MaxSize: 1, Hooks: []*RotationHookConfig{{Action: RotationHookZstd}}})
+	assert.Nil(t, err)
+	defer rotator.Close()
+
+	paths := make([]string, 0)
+	rotator.(RotationNotifier).OnRotate(func(path string) {
+		paths = append(paths, path)
+	})
+
+	// rotated by size
+	chunk := []byte(strings.Repeat("x", 600*1024))
+	rotator.Write(chunk)
+	assert.Empty(t, paths)
+	rotator.Write(chunk)
+	assert.Len(t, paths, 1)
+	assert.Equal(t, len(chunk), len(readFileContent(paths[0])))
+
+	// rotated explicitly
+	time.Sleep(2 * time.Millisecond)
+	assert.Nil(t, rotator.Rotate())
+	assert.Len(t, paths, 2)
+	assert.NotEqual(t, paths[0], paths[1])
+	assert.Empty(t, readFileContent(filePath))
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Create timed writer whose clock is returned by the function as well
+func newTestTimedWriter(t *testing.T, filePath string, config RotationConfig, now time.Time) (*timedWriter, func(time.Time)) {
+	writer, err := newTimedWriter(filePath, config)
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		writer.Close()
+	})
+
+	writer.now = func() time.Time {
+		return now
+	}
+
+	return writer, func(t time.Time) {
+		now = t
+	}
+}
+
+// Names of files in directory
+func listFileNames(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+
+	res := make([]string, 0)
+	for i := range infos {
+		res = append(res, infos[i].Name())
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func TestTimedWriter_WithDailySchedule(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+
+	writer, setNow := newTestTimedWriter(t, filePath, RotationConfig{Schedule: ScheduleDaily},
+		time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC))
+
+	writer.Write([]byte("day-1\n"))
+	setNow(time.Date(2020, 9, 1, 23, 59, 0, 0, time.UTC))
+	writer.Write([]byte("day-1\n"))
+	setNow(time.Date(2020, 9, 2, 0, 0, 1, 0, time.UTC))
+	writer.Write([]byte("day-2\n"))
+
+	assert.Equal(t, []string{"app-2020-09-01.log", "app.log"}, listFileNames(t, dir))
+	assert.Equal(t, "day-1\nday-1\n", readFileContent(path.Join(dir, "app-2020-09-01.log")))
+	assert.Equal(t, "day-2\n", readFileContent(filePath))
+}
+
+func TestTimedWriter_WithCronSchedule(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+
+	writer, setNow := newTestTimedWriter(t, filePath, RotationConfig{Schedule: "*/30 * * * *", TimeFormat: "150405"},
+		time.Date(2020, 9, 1, 10, 10, 0, 0, time.UTC))
+
+	writer.Write([]byte("a\n"))
+	setNow(time.Date(2020, 9, 1, 10, 31, 0, 0, time.UTC))
+	writer.Write([]byte("b\n"))
+	setNow(time.Date(2020, 9, 1, 11, 5, 0, 0, time.UTC))
+	writer.Write([]byte("c\n"))
+
+	assert.Equal(t, []string{"app-101000.log", "app-103100.log", "app.log"}, listFileNames(t, dir))
+}
+
+func TestTimedWriter_WithMaxBackups(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+
+	writer, setNow := newTestTimedWriter(t, filePath, RotationConfig{Schedule: ScheduleHourly, MaxBackups: 2},
+		time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC))
+
+	for hour := 10; hour < 15; hour++ {
+		setNow(time.Date(2020, 9, 1, hour, 0, 0, 0, time.UTC))
+		writer.Write([]byte("entry\n"))
+	}
+
+	assert.Equal(t, []string{"app-2020-09-01T12.log", "app-2020-09-01T13.log", "app.log"}, listFileNames(t, dir))
+}
+
+func TestTimedWriter_WithMaxAge(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "app-2020-08-01.log"), []byte("old\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(path.Join(dir, "other.log"), []byte("other\n"), 0644))
+
+	writer, setNow := newTestTimedWriter(t, filePath, RotationConfig{Schedule: ScheduleDaily, MaxAge: 7},
+		time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC))
+
+	writer.Write([]byte("day-1\n"))
+	setNow(time.Date(2020, 9, 2, 10, 0, 0, 0, time.UTC))
+	writer.Write([]byte("day-2\n"))
+
+	assert.Equal(t, []string{"app-2020-09-01.log", "app.log", "other.log"}, listFileNames(t, dir))
+}
+
+func TestTimedWriter_WithExistingFile(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte("yesterday\n"), 0644))
+	modTime := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	assert.Nil(t, os.Chtimes(filePath, modTime, modTime))
+
+	// file of elapsed period is rotated before writing
+	writer, _ := newTestTimedWriter(t, filePath, RotationConfig{Schedule: ScheduleDaily},
+		time.Date(2020, 9, 2, 10, 0, 0, 0, time.UTC))
+	writer.Write([]byte("today\n"))
+	writer.Close()
+
+	assert.Equal(t, "yesterday\n", readFileContent(path.Join(dir, "app-2020-09-01.log")))
+	assert.Equal(t, "today\n", readFileContent(filePath))
+
+	// name of rotated file is taken
+	assert.Nil(t, os.Chtimes(filePath, modTime, modTime))
+	writer.Write([]byte("again\n"))
+	assert.Equal(t, "today\n", readFileContent(path.Join(dir, "app-2020-09-01-1.log")))
+	assert.Equal(t, "again\n", readFileContent(filePath))
+}
+
+func TestNewTimedWriter_WithInvalidSchedule(t *testing.T) {
+	writer, err := newTimedWriter("app.log", RotationConfig{Schedule: "weekly"})
+	assert.NotNil(t, err)
+	assert.Nil(t, writer)
+}
+
+func TestRotation_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "ut.log")
+
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + filePath + `"]
+lumberjack:
+  maxsize: 1
+rotation:
+  schedule: hourly
+  maxBackups: 3
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+	assert.Equal(t, &RotationConfig{Schedule: ScheduleHourly, MaxBackups: 3}, config.Rotation)
+
+	outputs, _ := config.toOutputConfigs()
+	assert.Equal(t, config.Rotation, outputs[0].Rotation)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	logger.Sync()
+
+	assert.Equal(t, `{"msg":"ut-message"}`+"\n", readFileContent(filePath))
+}
+
+func TestRotation_WithOutputLumberjack(t *testing.T) {
+	config := &Config{
+		Zap:      &zap.Config{},
+		Rotation: &RotationConfig{Schedule: ScheduleDaily},
+		Outputs: []*OutputConfig{
+			{Path: "audit.log", Lumberjack: &lumberjack.Logger{MaxSize: 100}},
+			{Path: "app.log"},
+		},
+	}
+
+	outputs, _ := config.toOutputConfigs()
+	assert.Nil(t, outputs[0].Rotation)
+	assert.Equal(t, config.Rotation, outputs[1].Rotation)
+}
+
+func TestWithTimedRotation(t *testing.T) {
+	config := NewConfigWithOptions(WithTimedRotation(ScheduleHourly, 24))
+	assert.Equal(t, &RotationConfig{Schedule: ScheduleHourly, MaxBackups: 24, LocalTime: true}, config.Rotation)
+}
@@ -0,0 +1,332 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of built-in drivers which could be used by RotationConfig.Driver
+const (
+	// RotationDriverLumberjack rotates files by size with lumberjack.
+	RotationDriverLumberjack = "lumberjack"
+	// RotationDriverTimed rotates files on schedule.
+	RotationDriverTimed = "timed"
+)
+
+// Rotator writes to a file which is rotated by itself, Rotate() rotates the file immediately.
+// Writes are serialized by logger, and rotator would be synced with logger if it implements zapcore.WriteSyncer.
+type Rotator interface {
+	io.WriteCloser
+	Rotate() error
+}
+
+// RotatorFactory creates rotator of file with rotation config, filename may be a template which could be expanded
+// with ExpandFilename().
+type RotatorFactory func(filename string, config *RotationConfig) (Rotator, error)
+
+var (
+	// rotator factories keyed by lower case driver
+	rotatorFactories = map[string]RotatorFactory{
+		RotationDriverLumberjack: newLumberjackRotator,
+		RotationDriverTimed:      newTimedRotator,
+	}
+	rotatorMutex sync.RWMutex
+)
+
+// RegisterRotator registers factory of rotators with driver, so that it could be chosen by rotation.driver in config.
+// Error would be returned if the driver is already registered.
+func RegisterRotator(driver string, factory RotatorFactory) error {
+	if factory == nil {
+		return errors.Errorf("rotator factory is nil, driver:%s", driver)
+	}
+
+	driver = strings.ToLower(driver)
+
+	rotatorMutex.Lock()
+	defer rotatorMutex.Unlock()
+
+	if _, ok := rotatorFactories[driver]; ok {
+		return errors.Errorf("rotator is already registered, driver:%s", driver)
+	}
+
+	rotatorFactories[driver] = factory
+	return nil
+}
+
+// Create rotator of file with driver of config, rotated files are compressed before hooks if compression mode is
+// rotate, and archived after hooks if archive is provided
+func newRotator(filename string, config *RotationConfig, archive *ArchiveConfig) (Rotator, error) {
+	driver := strings.ToLower(config.driver())
+
+	hooks := config.Hooks
+	if compression := config.Compression; compression != nil {
+		if err := compression.validate(); err != nil {
+			return nil, err
+		}
+
+		if !compression.inline() {
+			hooks = append([]*RotationHookConfig{{
+				Action: strings.ToLower(compression.Algorithm),
+				Level:  compression.Level,
+			}}, hooks...)
+		}
+	}
+
+	rotatorMutex.RLock()
+	factory, ok := rotatorFactories[driver]
+	rotatorMutex.RUnlock()
+
+	if !ok {
+		return nil, errors.Errorf("rotator is not registered, driver:%s", driver)
+	}
+
+	rotator, err := factory(filename, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create rotator, driver:%s", driver)
+	}
+
+	if rotator == nil {
+		return nil, errors.Errorf("rotator is nil, driver:%s", driver)
+	}
+
+	if len(hooks) > 0 || archive != nil {
+		if err := attachRotationHooks(rotator, driver, hooks, archive); err != nil {
+			rotator.Close()
+			return nil, err
+		}
+	}
+
+	return rotator, nil
+}
+
+// Fire hooks and archive after every rotation of rotator
+func attachRotationHooks(rotator Rotator, driver string, configs []*RotationHookConfig, archive *ArchiveConfig) error {
+	notifier, ok := rotator.(RotationNotifier)
+	if !ok {
+		return errors.Errorf("rotation hooks are not supported by rotator, driver:%s", driver)
+	}
+
+	hooks, err := newRotationHooks(configs)
+	if err != nil {
+		return err
+	}
+
+	if archive != nil {
+		hook, err := newArchiveHook(archive)
+		if err != nil {
+			return err
+		}
+		hooks.add("archive", hook)
+	}
+
+	notifier.OnRotate(hooks.fire)
+	return nil
+}
+
+// lockedRotator serializes writes and rotations of rotator like zapcore.Lock, so that it could be rotated on demand
+type lockedRotator struct {
+	sync.Mutex
+	rotator Rotator
+}
+
+// Write implements zapcore.WriteSyncer
+func (writer *lockedRotator) Write(p []byte) (int, error) {
+	writer.Lock()
+	defer writer.Unlock()
+
+	return writer.rotator.Write(p)
+}
+
+// Sync implements zapcore.WriteSyncer, rotator is synced if it implements zapcore.WriteSyncer
+func (writer *lockedRotator) Sync() error {
+	writer.Lock()
+	defer writer.Unlock()
+
+	if syncer, ok := writer.rotator.(zapcore.WriteSyncer); ok {
+		return syncer.Sync()
+	}
+
+	return nil
+}
+
+// Rotate rotates file of rotator immediately
+func (writer *lockedRotator) Rotate() error {
+	writer.Lock()
+	defer writer.Unlock()
+
+	return writer.rotator.Rotate()
+}
+
+// Create lumberjack logger of file with settings of config
+func newLumberjackRotator(filename string, config *RotationConfig) (Rotator, error) {
+	if config.Compression.inline() {
+		return nil, errors.New("inline compression is not supported by lumberjack driver")
+	}
+
+	now := time.Now()
+	if !config.LocalTime {
+		now = now.UTC()
+	}
+
+	// template is expanded once since lumberjack names rotated files by itself
+	lumber := &lumberjack.Logger{
+		Filename:   ExpandFilename(filename, now),
+		MaxSize:    config.MaxSize,
+		MaxAge:     config.MaxAge,
+		MaxBackups: config.MaxBackups,
+		LocalTime:  config.LocalTime,
+		// files are compressed by hook of compression section instead of lumberjack if provided
+		Compress: config.Compress && config.Compression == nil,
+	}
+
+	// lumberjack keeps permission and owner of existing file while rotating
+	if config.permissions != nil {
+		if err := config.permissions.mkdirAll(filepath.Dir(lumber.Filename), DefaultDirMode); err != nil {
+			return nil, err
+		}
+
+		file, err := config.permissions.openFile(lumber.Filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open file, filename:%s", lumber.Filename)
+		}
+		file.Close()
+	}
+
+	// lumberjack keeps writing to the same file, which is linked once
+	updateRotationSymlink(config.Symlink, lumber.Filename)
+
+	// lumberjack does not notify rotations, they are tracked with size of file
+	return &notifyingLumberjack{Logger: lumber}, nil
+}
+
+// layout of timestamp in names of files rotated by lumberjack
+const lumberjackBackupLayout = "2006-01-02T15-04-05.000"
+
+// notifyingLumberjack tracks size of file the same way as lumberjack, so that rotations could be notified
+type notifyingLumberjack struct {
+	*lumberjack.Logger
+	mutex    sync.Mutex
+	size     int64
+	opened   bool
+	onRotate func(path string)
+}
+
+// Write implements io.Writer, rotation is notified if file is rotated while writing
+func (lumber *notifyingLumberjack) Write(p []byte) (int, error) {
+	lumber.mutex.Lock()
+	defer lumber.mutex.Unlock()
+
+	max := int64(lumber.MaxSize) * 1024 * 1024
+	if max == 0 {
+		// default max size of lumberjack
+		max = 100 * 1024 * 1024
+	}
+
+	// existing file is rotated before the first write if it is full
+	rotated := lumber.size+int64(len(p)) > max
+	if !lumber.opened {
+		lumber.size = 0
+		if info, err := os.Stat(lumber.Filename); err == nil {
+			lumber.size = info.Size()
+		}
+		rotated = lumber.size > 0 && lumber.size+int64(len(p)) >= max
+	}
+
+	n, err := lumber.Logger.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	lumber.opened = true
+	if rotated {
+		lumber.size = int64(n)
+		lumber.notify()
+	} else {
+		lumber.size += int64(n)
+	}
+
+	return n, nil
+}
+
+// Rotate implements Rotator
+func (lumber *notifyingLumberjack) Rotate() error {
+	lumber.mutex.Lock()
+	defer lumber.mutex.Unlock()
+
+	// nothing is rotated if file does not exist
+	_, statErr := os.Stat(lumber.Filename)
+	if err := lumber.Logger.Rotate(); err != nil {
+		return err
+	}
+
+	lumber.opened, lumber.size = true, 0
+	if statErr == nil {
+		lumber.notify()
+	}
+	return nil
+}
+
+// OnRotate implements RotationNotifier
+func (lumber *notifyingLumberjack) OnRotate(f func(path string)) {
+	lumber.mutex.Lock()
+	defer lumber.mutex.Unlock()
+
+	lumber.onRotate = f
+}
+
+// Notify the newest rotated file, which is named with timestamp by lumberjack like app-2020-09-01T10-00-00.000.log
+func (lumber *notifyingLumberjack) notify() {
+	if lumber.onRotate == nil {
+		return
+	}
+
+	ext := filepath.Ext(lumber.Filename)
+	prefix := strings.TrimSuffix(lumber.Filename, ext) + "-"
+	matches, _ := filepath.Glob(prefix + "*" + ext)
+
+	newest := ""
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(match, prefix), ext)
+		if _, err := time.Parse(lumberjackBackupLayout, stamp); err == nil && match > newest {
+			newest = match
+		}
+	}
+
+	if len(newest) > 0 {
+		lumber.onRotate(newest)
+	}
+}
+
+// Create timed writer of file with settings of config
+func newTimedRotator(filename string, config *RotationConfig) (Rotator, error) {
+	writer, err := newTimedWriter(filename, *config)
+	if err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Convert lumberjack config into rotation config of lumberjack driver
+func newLumberjackRotation(lumber *lumberjack.Logger) *RotationConfig {
+	return &RotationConfig{
+		Driver:     RotationDriverLumberjack,
+		MaxSize:    lumber.MaxSize,
+		MaxAge:     lumber.MaxAge,
+		MaxBackups: lumber.MaxBackups,
+		LocalTime:  lumber.LocalTime,
+		Compress:   lumber.Compress,
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"path"
+	"testing"
+	"time"
+)
+
+// rotator which keeps written bytes in memory
+type memoryRotator struct {
+	bytes.Buffer
+	rotated int
+	closed  bool
+}
+
+func (rotator *memoryRotator) Rotate() error {
+	rotator.rotated++
+	return nil
+}
+
+func (rotator *memoryRotator) Close() error {
+	rotator.closed = true
+	return nil
+}
+
+func TestRegisterRotator_HappyCase(t *testing.T) {
+	rotator := &memoryRotator{}
+	var received *RotationConfig
+	assert.Nil(t, RegisterRotator("UT-Memory", func(filename string, config *RotationConfig) (Rotator, error) {
+		received = config
+		return rotator, nil
+	}))
+
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "ut.log") + `"]
+rotation:
+  driver: ut-memory
+  options:
+    bucket: ut-bucket
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, err := NewZapLoggerWithConfig(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+
+	assert.Equal(t, "ut-bucket", received.Options["bucket"])
+	assert.Equal(t, `{"msg":"ut-message"}`+"\n", rotator.String())
+}
+
+func TestRegisterRotator_WithDuplicateDriver(t *testing.T) {
+	assert.NotNil(t, RegisterRotator(RotationDriverLumberjack, newLumberjackRotator))
+	assert.NotNil(t, RegisterRotator("ut-nil", nil))
+}
+
+func TestNewRotator_WithBuiltInDrivers(t *testing.T) {
+	// lumberjack by default
+	rotator, err := newRotator("ut.log", &RotationConfig{MaxSize: 10, Compress: true}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &lumberjack.Logger{Filename: "ut.log", MaxSize: 10, Compress: true}, rotator.(*notifyingLumberjack).Logger)
+
+	// timed if schedule is provided
+	rotator, err = newRotator("ut.log", &RotationConfig{Schedule: ScheduleDaily}, nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &timedWriter{}, rotator)
+
+	// driver is case insensitive
+	rotator, err = newRotator("ut.log", &RotationConfig{Driver: "Lumberjack", Schedule: ScheduleDaily}, nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &notifyingLumberjack{}, rotator)
+}
+
+func TestNewRotator_WithInvalidConfig(t *testing.T) {
+	rotator, err := newRotator("ut.log", &RotationConfig{Driver: "ut-unknown"}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, rotator)
+
+	rotator, err = newRotator("ut.log", &RotationConfig{Driver: RotationDriverTimed}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, rotator)
+}
+
+func TestTimedWriter_Rotate(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "app.log")
+
+	writer, _ := newTestTimedWriter(t, filePath, RotationConfig{Schedule: ScheduleDaily}, time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC))
+	writer.Write([]byte("before\n"))
+	assert.Nil(t, writer.Rotate())
+	writer.Write([]byte("after\n"))
+
+	assert.Equal(t, "before\n", readFileContent(path.Join(dir, "app-2020-09-01.log")))
+	assert.Equal(t, "after\n", readFileContent(filePath))
+}
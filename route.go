@@ -0,0 +1,237 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+	"math"
+	"strings"
+)
+
+// RouteConfig routes entries of levels and logger names to outputs, in addition to outputs of zap config and outputs
+// section. Entries are written to every route which matches their levels and logger names, and routes are still
+// filtered by level of zap config and levels of logger names. Entries matched by exclusive routes are written to them
+// only. Outputs of routes inherit rotation and other settings of combined config.
+//
+// Example config file in YAML:
+//
+//	routes:
+//	  - names: ["audit"]
+//	    exclusive: true
+//	    outputs:
+//	      - path: /var/log/audit.log
+//	        rotation:
+//	          schedule: daily
+//	  - maxLevel: info
+//	    outputPaths: ["stdout"]
+//	  - minLevel: warn
+//	    outputPaths: ["/var/log/app-error.log"]
+//	  - minLevel: error
+//	    outputs:
+//	      - path: webhook://hooks.example.com/logs
+type RouteConfig struct {
+	// MinLevel is the lowest level of entries written to outputs, no lower bound if not provided.
+	MinLevel string `json:"minLevel,omitempty" yaml:"minLevel,omitempty"`
+	// MaxLevel is the highest level of entries written to outputs, no upper bound if not provided.
+	MaxLevel string `json:"maxLevel,omitempty" yaml:"maxLevel,omitempty"`
+	// Names are logger names given with zap.Logger.Named(), entries of loggers nested in them are matched as well,
+	// like audit.login in audit or audit.*. Entries of all logger names are matched if not provided.
+	Names []string `json:"names,omitempty" yaml:"names,omitempty"`
+	// Exclusive writes matched entries to this route and other exclusive routes matching them only, instead of
+	// outputs of zap config, outputs section and other routes.
+	Exclusive bool `json:"exclusive,omitempty" yaml:"exclusive,omitempty"`
+	// OutputPaths are output paths using rotation settings of combined config, like outputPaths of zap config.
+	OutputPaths []string `json:"outputPaths,omitempty" yaml:"outputPaths,omitempty"`
+	// Outputs are output paths which carry their own settings, like outputs section.
+	Outputs []*OutputConfig `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+// route is parsed RouteConfig with outputs of combined config
+type route struct {
+	min, max  zapcore.Level
+	names     []string
+	exclusive bool
+	outputs   []*OutputConfig
+}
+
+// Parse routes of combined config, outputs of routes inherit settings of combined config
+func (config *Config) toRoutes() ([]*route, error) {
+	res := make([]*route, 0, len(config.Routes))
+	for i, routeConfig := range config.Routes {
+		// custom levels may be out of range of zap levels
+		r := &route{
+			min:       zapcore.Level(math.MinInt8),
+			max:       zapcore.Level(math.MaxInt8),
+			exclusive: routeConfig.Exclusive,
+			outputs:   config.collectOutputs(routeConfig.OutputPaths, routeConfig.Outputs),
+		}
+
+		for _, name := range routeConfig.Names {
+			name = strings.TrimSuffix(name, loggerNameSeparator+"*")
+			if len(name) == 0 {
+				return nil, errors.Errorf("name of route is empty, index:%d", i)
+			}
+			r.names = append(r.names, name)
+		}
+
+		var err error
+		if len(routeConfig.MinLevel) > 0 {
+			if r.min, err = ParseLevel(routeConfig.MinLevel); err != nil {
+				return nil, errors.Wrapf(err, "invalid minLevel of route, index:%d", i)
+			}
+		}
+
+		if len(routeConfig.MaxLevel) > 0 {
+			if r.max, err = ParseLevel(routeConfig.MaxLevel); err != nil {
+				return nil, errors.Wrapf(err, "invalid maxLevel of route, index:%d", i)
+			}
+		}
+
+		if r.min > r.max {
+			return nil, errors.Errorf("minLevel of route is higher than maxLevel, index:%d, minLevel:%s, maxLevel:%s",
+				i, routeConfig.MinLevel, routeConfig.MaxLevel)
+		}
+
+		if len(r.outputs) == 0 {
+			return nil, errors.Errorf("outputs of route are empty, index:%d", i)
+		}
+
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// routeLevelEnabler enables levels between min and max which are enabled by base
+type routeLevelEnabler struct {
+	base     zapcore.LevelEnabler
+	min, max zapcore.Level
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (enabler *routeLevelEnabler) Enabled(level zapcore.Level) bool {
+	return level >= enabler.min && level <= enabler.max && enabler.base.Enabled(level)
+}
+
+// Whether logger name is matched by names of route
+func (r *route) matchName(loggerName string) bool {
+	if len(r.names) == 0 {
+		return true
+	}
+
+	for _, name := range r.names {
+		if matchLoggerName(loggerName, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// routedCore is core of outputs of route
+type routedCore struct {
+	*route
+	core zapcore.Core
+}
+
+// routingCore writes entries to cores of routes matching their logger names, entries matched by exclusive routes
+// are not written to core of outputs and non-exclusive routes
+type routingCore struct {
+	// core of outputs of zap config and outputs section
+	core   zapcore.Core
+	routes []*routedCore
+}
+
+// Create core routing entries to core of outputs and cores of routes
+func newRoutingCore(core zapcore.Core, routes []*routedCore) zapcore.Core {
+	return &routingCore{core: core, routes: routes}
+}
+
+// Whether entry is matched by exclusive route
+func (r *routedCore) matchExclusive(entry zapcore.Entry) bool {
+	return r.exclusive && entry.Level >= r.min && entry.Level <= r.max && r.matchName(entry.LoggerName)
+}
+
+// Whether entry is matched by any of exclusive routes
+func (core *routingCore) exclusive(entry zapcore.Entry) bool {
+	for _, r := range core.routes {
+		if r.matchExclusive(entry) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Enabled implements zapcore.Core
+func (core *routingCore) Enabled(level zapcore.Level) bool {
+	if core.core.Enabled(level) {
+		return true
+	}
+
+	for _, r := range core.routes {
+		if r.core.Enabled(level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// With implements zapcore.Core
+func (core *routingCore) With(fields []zapcore.Field) zapcore.Core {
+	routes := make([]*routedCore, 0, len(core.routes))
+	for _, r := range core.routes {
+		routes = append(routes, &routedCore{route: r.route, core: r.core.With(fields)})
+	}
+
+	return &routingCore{core: core.core.With(fields), routes: routes}
+}
+
+// Check implements zapcore.Core
+func (core *routingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	exclusive := core.exclusive(entry)
+	if !exclusive {
+		checked = core.core.Check(entry, checked)
+	}
+
+	for _, r := range core.routes {
+		if (exclusive && r.matchExclusive(entry)) || (!exclusive && !r.exclusive && r.matchName(entry.LoggerName)) {
+			checked = r.core.Check(entry, checked)
+		}
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core
+func (core *routingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	var err error
+	exclusive := core.exclusive(entry)
+	if !exclusive {
+		err = core.core.Write(entry, fields)
+	}
+
+	for _, r := range core.routes {
+		if (exclusive && r.matchExclusive(entry)) || (!exclusive && !r.exclusive && r.matchName(entry.LoggerName)) {
+			err = multierr.Append(err, r.core.Write(entry, fields))
+		}
+	}
+
+	return err
+}
+
+// Sync implements zapcore.Core
+func (core *routingCore) Sync() error {
+	err := core.core.Sync()
+	for _, r := range core.routes {
+		err = multierr.Append(err, r.core.Sync())
+	}
+
+	return err
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path"
+	"testing"
+)
+
+func TestConfig_ToRoutes(t *testing.T) {
+	config := &Config{
+		Zap:      &zap.Config{},
+		Rotation: &RotationConfig{Schedule: "daily"},
+		Routes: []*RouteConfig{
+			{MaxLevel: "INFO", OutputPaths: []string{"stdout"}},
+			{MinLevel: "warn", Outputs: []*OutputConfig{{Path: "ut-error.log"}}},
+		},
+	}
+
+	routes, err := config.toRoutes()
+	assert.Nil(t, err)
+	assert.Len(t, routes, 2)
+
+	assert.Equal(t, zapcore.InfoLevel, routes[0].max)
+	assert.True(t, routes[0].min < zapcore.DebugLevel)
+	assert.Equal(t, "stdout", routes[0].outputs[0].Path)
+
+	// outputs inherit settings of combined config
+	assert.Equal(t, zapcore.WarnLevel, routes[1].min)
+	assert.True(t, routes[1].max > zapcore.FatalLevel)
+	assert.Equal(t, config.Rotation, routes[1].outputs[0].Rotation)
+
+	// invalid routes
+	for _, route := range []*RouteConfig{
+		{MinLevel: "ut-level", OutputPaths: []string{"stdout"}},
+		{MaxLevel: "ut-level", OutputPaths: []string{"stdout"}},
+		{MinLevel: "error", MaxLevel: "info", OutputPaths: []string{"stdout"}},
+		{MinLevel: "error"},
+		{Names: []string{".*"}, OutputPaths: []string{"stdout"}},
+	} {
+		config.Routes = []*RouteConfig{route}
+		_, err := config.toRoutes()
+		assert.NotNil(t, err)
+	}
+}
+
+func TestConfig_ToRoutesWithNames(t *testing.T) {
+	config := &Config{
+		Zap:    &zap.Config{},
+		Routes: []*RouteConfig{{Names: []string{"audit.*", "security"}, Exclusive: true, OutputPaths: []string{"stdout"}}},
+	}
+
+	routes, err := config.toRoutes()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"audit", "security"}, routes[0].names)
+	assert.True(t, routes[0].exclusive)
+
+	assert.True(t, routes[0].matchName("audit"))
+	assert.True(t, routes[0].matchName("audit.login"))
+	assert.True(t, routes[0].matchName("security"))
+	assert.False(t, routes[0].matchName("auditor"))
+	assert.False(t, routes[0].matchName(""))
+}
+
+func TestRouteLevelEnabler_Enabled(t *testing.T) {
+	enabler := &routeLevelEnabler{base: zapcore.InfoLevel, min: zapcore.DebugLevel, max: zapcore.WarnLevel}
+	assert.False(t, enabler.Enabled(zapcore.DebugLevel))
+	assert.True(t, enabler.Enabled(zapcore.InfoLevel))
+	assert.True(t, enabler.Enabled(zapcore.WarnLevel))
+	assert.False(t, enabler.Enabled(zapcore.ErrorLevel))
+}
+
+func TestRoutes_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: debug
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+routes:
+  - maxLevel: info
+    outputPaths: ["` + path.Join(dir, "info.log") + `"]
+  - minLevel: warn
+    outputs:
+      - path: ` + path.Join(dir, "error.log") + `
+  - minLevel: error
+    outputPaths: ["` + path.Join(dir, "error.log") + `"]
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Debug("ut-debug")
+	logger.Warn("ut-warn")
+	logger.Error("ut-error")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	assert.Equal(t, `{"msg":"ut-debug"}`+"\n"+`{"msg":"ut-warn"}`+"\n"+`{"msg":"ut-error"}`+"\n",
+		readFileContent(path.Join(dir, "app.log")))
+	assert.Equal(t, `{"msg":"ut-debug"}`+"\n", readFileContent(path.Join(dir, "info.log")))
+	// entries are written to every matched route
+	assert.Equal(t, `{"msg":"ut-warn"}`+"\n"+`{"msg":"ut-error"}`+"\n"+`{"msg":"ut-error"}`+"\n",
+		readFileContent(path.Join(dir, "error.log")))
+
+	// invalid level
+	config.Routes[0].MaxLevel = "ut-level"
+	_, _, err = NewZapLoggerWithCloser(config)
+	assert.NotNil(t, err)
+}
+
+func TestRoutes_WithNames(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+    nameKey: logger
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+routes:
+  - names: ["audit"]
+    exclusive: true
+    outputs:
+      - path: ` + path.Join(dir, "audit.log") + `
+  - names: ["audit.login"]
+    exclusive: true
+    minLevel: warn
+    outputPaths: ["` + path.Join(dir, "login.log") + `"]
+  - names: ["db"]
+    outputPaths: ["` + path.Join(dir, "db.log") + `"]
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-app")
+	logger.Named("audit").With(zap.String("ut-key", "ut-value")).Info("ut-audit")
+	logger.Named("audit").Named("login").Warn("ut-login")
+	logger.Named("db").Info("ut-db")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+
+	assert.Equal(t, `{"msg":"ut-app"}`+"\n"+`{"logger":"db","msg":"ut-db"}`+"\n",
+		readFileContent(path.Join(dir, "app.log")))
+	// entries are written to every matched exclusive route
+	assert.Equal(t, `{"logger":"audit","msg":"ut-audit","ut-key":"ut-value"}`+"\n"+`{"logger":"audit.login","msg":"ut-login"}`+"\n",
+		readFileContent(path.Join(dir, "audit.log")))
+	assert.Equal(t, `{"logger":"audit.login","msg":"ut-login"}`+"\n", readFileContent(path.Join(dir, "login.log")))
+	assert.Equal(t, `{"logger":"db","msg":"ut-db"}`+"\n", readFileContent(path.Join(dir, "db.log")))
+}
+
+func TestRoutes_WithoutOutputs(t *testing.T) {
+	dir := newTempDir(t)
+
+	logger, err := New(
+		WithLevel(zapcore.InfoLevel),
+		WithOutput(),
+		WithRoute("error", "", path.Join(dir, "error.log")))
+	assert.Nil(t, err)
+
+	logger.Info("ut-info")
+	logger.Error("ut-error")
+	assert.Nil(t, logger.Sync())
+	assert.Contains(t, readFileContent(path.Join(dir, "error.log")), "ut-error")
+	assert.NotContains(t, readFileContent(path.Join(dir, "error.log")), "ut-info")
+}
+
+func TestWithNamedRoute(t *testing.T) {
+	config := NewConfigWithOptions(WithNamedRoute("audit", "ut-audit.log"))
+	assert.Equal(t, []*RouteConfig{
+		{Names: []string{"audit"}, Exclusive: true, OutputPaths: []string{"ut-audit.log"}},
+	}, config.Routes)
+}
+
+func TestWithRoute(t *testing.T) {
+	config := NewConfigWithOptions(WithRoute("warn", "", "ut-error.log"), WithRoute("", "info", "stdout"))
+	assert.Equal(t, []*RouteConfig{
+		{MinLevel: "warn", OutputPaths: []string{"ut-error.log"}},
+		{MaxLevel: "info", OutputPaths: []string{"stdout"}},
+	}, config.Routes)
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package sentry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap/zapcore"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// event is the event of Sentry
+type event struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Platform    string                 `json:"platform"`
+	Logger      string                 `json:"logger,omitempty"`
+	Message     *message               `json:"message,omitempty"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Release     string                 `json:"release,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Exception   *values                `json:"exception,omitempty"`
+	Threads     *values                `json:"threads,omitempty"`
+	Breadcrumbs *values                `json:"breadcrumbs,omitempty"`
+}
+
+// message is the message of event
+type message struct {
+	Formatted string `json:"formatted"`
+}
+
+// values wraps exceptions, threads and breadcrumbs of event
+type values struct {
+	Values interface{} `json:"values"`
+}
+
+// exception is the exception of event, stacktrace of entry is attached to the exception
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+// thread carries stacktrace of entry without error
+type thread struct {
+	Current    bool        `json:"current"`
+	Stacktrace *stacktrace `json:"stacktrace"`
+}
+
+// stacktrace is the stacktrace of event, frames are ordered from caller to callee
+type stacktrace struct {
+	Frames []*frame `json:"frames"`
+}
+
+// frame is a frame of stacktrace
+type frame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+}
+
+// breadcrumb is an entry recorded before event
+type breadcrumb struct {
+	Timestamp string                 `json:"timestamp"`
+	Type      string                 `json:"type"`
+	Category  string                 `json:"category,omitempty"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Map level to level of Sentry, like warning and fatal
+func toLevel(level zapcore.Level) string {
+	switch level {
+	case zapcore.DebugLevel:
+		return "debug"
+	case zapcore.InfoLevel:
+		return "info"
+	case zapcore.WarnLevel:
+		return "warning"
+	case zapcore.ErrorLevel:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// Encode fields into map
+func encodeFields(fieldLists ...[]zapcore.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fields := range fieldLists {
+		for i := range fields {
+			fields[i].AddTo(enc)
+		}
+	}
+
+	if len(enc.Fields) == 0 {
+		return nil
+	}
+
+	return enc.Fields
+}
+
+// Convert entry to breadcrumb
+func toBreadcrumb(entry zapcore.Entry, fieldLists ...[]zapcore.Field) *breadcrumb {
+	return &breadcrumb{
+		Timestamp: entry.Time.UTC().Format(time.RFC3339Nano),
+		Type:      "default",
+		Category:  entry.LoggerName,
+		Level:     toLevel(entry.Level),
+		Message:   entry.Message,
+		Data:      encodeFields(fieldLists...),
+	}
+}
+
+// Convert entry and fields to event, the first error of fields is reported as exception
+func toEvent(entry zapcore.Entry, options *options, crumbs []*breadcrumb, fieldLists ...[]zapcore.Field) *event {
+	res := &event{
+		EventID:     newEventID(),
+		Timestamp:   entry.Time.UTC().Format(time.RFC3339Nano),
+		Level:       toLevel(entry.Level),
+		Platform:    "go",
+		Logger:      entry.LoggerName,
+		Message:     &message{Formatted: entry.Message},
+		ServerName:  options.serverName,
+		Environment: options.environment,
+		Release:     options.release,
+		Extra:       encodeFields(fieldLists...),
+	}
+
+	if len(options.tags) > 0 {
+		res.Tags = options.tags
+	}
+
+	if len(crumbs) > 0 {
+		res.Breadcrumbs = &values{Values: crumbs}
+	}
+
+	trace := parseStack(entry.Stack)
+
+	if err := findError(fieldLists...); err != nil {
+		res.Exception = &values{Values: []*exception{{
+			Type:       fmt.Sprintf("%T", err),
+			Value:      err.Error(),
+			Stacktrace: trace,
+		}}}
+	} else if trace != nil {
+		res.Threads = &values{Values: []*thread{{Current: true, Stacktrace: trace}}}
+	}
+
+	return res
+}
+
+// Find the first error of fields, like zap.Error(err)
+func findError(fieldLists ...[]zapcore.Field) error {
+	for _, fields := range fieldLists {
+		for i := range fields {
+			if fields[i].Type != zapcore.ErrorType {
+				continue
+			}
+
+			if err, ok := fields[i].Interface.(error); ok && err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Parse stacktrace taken by zap, which consists of function and file:line of each frame from callee to caller
+func parseStack(stack string) *stacktrace {
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	frames := make([]*frame, 0, len(lines)/2)
+	for i := 0; i+1 < len(lines); i += 2 {
+		frame := &frame{AbsPath: strings.TrimSpace(lines[i+1])}
+
+		if index := strings.LastIndex(frame.AbsPath, ":"); index > 0 {
+			frame.Lineno, _ = strconv.Atoi(frame.AbsPath[index+1:])
+			frame.AbsPath = frame.AbsPath[:index]
+		}
+		frame.Filename = filepath.Base(frame.AbsPath)
+		frame.Module, frame.Function = splitFunction(strings.TrimSpace(lines[i]))
+
+		frames = append(frames, frame)
+	}
+
+	// frames of Sentry are ordered from caller to callee
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+
+	return &stacktrace{Frames: frames}
+}
+
+// Split function like github.com/org/repo/pkg.(*Type).Method into package and function
+func splitFunction(function string) (string, string) {
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return "", function
+	}
+
+	dot += slash + 1
+	return function[:dot], function[dot+1:]
+}
+
+// Create id of event which is 32 hex characters
+func newEventID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Encode event as json
+func encodeEvent(event *event) ([]byte, error) {
+	return json.Marshal(event)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package sentry
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestToLevel(t *testing.T) {
+	assert.Equal(t, "debug", toLevel(zapcore.DebugLevel))
+	assert.Equal(t, "info", toLevel(zapcore.InfoLevel))
+	assert.Equal(t, "warning", toLevel(zapcore.WarnLevel))
+	assert.Equal(t, "error", toLevel(zapcore.ErrorLevel))
+	assert.Equal(t, "fatal", toLevel(zapcore.DPanicLevel))
+	assert.Equal(t, "fatal", toLevel(zapcore.FatalLevel))
+}
+
+func TestToEvent_WithError(t *testing.T) {
+	entry := zapcore.Entry{
+		Level:   zapcore.ErrorLevel,
+		Time:    time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Message: "ut-message",
+		Stack:   "main.handle\n\t/app/main.go:10\nmain.main\n\t/app/main.go:5",
+	}
+	options := &options{serverName: "node", release: "v1"}
+
+	event := toEvent(entry, options, nil, []zapcore.Field{zap.Error(io.EOF)})
+	assert.Equal(t, "2020-01-02T03:04:05Z", event.Timestamp)
+	assert.Equal(t, "node", event.ServerName)
+	assert.Equal(t, "v1", event.Release)
+	assert.Nil(t, event.Tags)
+	assert.Nil(t, event.Breadcrumbs)
+	assert.Nil(t, event.Threads)
+
+	exceptions := event.Exception.Values.([]*exception)
+	assert.Equal(t, "*errors.errorString", exceptions[0].Type)
+	assert.Equal(t, "EOF", exceptions[0].Value)
+	assert.Len(t, exceptions[0].Stacktrace.Frames, 2)
+	assert.Equal(t, "handle", exceptions[0].Stacktrace.Frames[1].Function)
+}
+
+func TestToEvent_WithoutError(t *testing.T) {
+	entry := zapcore.Entry{
+		Level: zapcore.FatalLevel,
+		Stack: "main.main\n\t/app/main.go:5",
+	}
+
+	event := toEvent(entry, &options{}, []*breadcrumb{{Message: "ut-crumb"}})
+	assert.Nil(t, event.Exception)
+	assert.Nil(t, event.Extra)
+	assert.Len(t, event.Breadcrumbs.Values, 1)
+	assert.True(t, event.Threads.Values.([]*thread)[0].Current)
+
+	// With neither error nor stacktrace
+	event = toEvent(zapcore.Entry{}, &options{}, nil)
+	assert.Nil(t, event.Exception)
+	assert.Nil(t, event.Threads)
+}
+
+func TestParseStack(t *testing.T) {
+	trace := parseStack("github.com/org/repo/pkg.(*Server).Handle\n\t/src/pkg/server.go:42\n" +
+		"github.com/org/repo/pkg.Serve.func1\n\t/src/pkg/serve.go:7\n")
+
+	assert.Equal(t, []*frame{
+		{Function: "Serve.func1", Module: "github.com/org/repo/pkg", Filename: "serve.go", AbsPath: "/src/pkg/serve.go", Lineno: 7},
+		{Function: "(*Server).Handle", Module: "github.com/org/repo/pkg", Filename: "server.go", AbsPath: "/src/pkg/server.go", Lineno: 42},
+	}, trace.Frames)
+
+	// With empty stacktrace
+	assert.Nil(t, parseStack(""))
+}
+
+func TestSplitFunction(t *testing.T) {
+	module, function := splitFunction("github.com/org/repo.v2/pkg.Func")
+	assert.Equal(t, "github.com/org/repo.v2/pkg", module)
+	assert.Equal(t, "Func", function)
+
+	module, function = splitFunction("main.main")
+	assert.Equal(t, "main", module)
+	assert.Equal(t, "main", function)
+
+	module, function = splitFunction("unknown")
+	assert.Empty(t, module)
+	assert.Equal(t, "unknown", function)
+}
+
+func TestNewEventID(t *testing.T) {
+	assert.Len(t, newEventID(), 32)
+	assert.NotEqual(t, newEventID(), newEventID())
+}
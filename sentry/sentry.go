@@ -0,0 +1,300 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package sentry registers sentry scheme with rklogger.RegisterCore, so that entries of warn level and above are
+// reported to Sentry by output paths like:
+//
+//	outputPaths:
+//	  - stdout
+//	  # DSN of Sentry with scheme of sentry
+//	  - sentry://public-key@o0.ingest.sentry.io/123?level=error&breadcrumbs=warn&sampleRate=0.5&environment=prod
+//
+// Import the package for side effects in order to enable the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/sentry"
+//
+// Query parameters:
+//
+//	level:          min level of entries reported as events, error by default
+//	breadcrumbs:    min level of entries kept as breadcrumbs of next events, warn by default
+//	maxBreadcrumbs: max number of breadcrumbs attached to an event, 100 by default and 0 disables breadcrumbs
+//	sampleRate:     rate of events which are reported, from 0 to 1, 1 by default
+//	environment:    environment of events
+//	release:        release of events
+//	serverName:     server name of events, hostname by default
+//	tags:           tags of events, like key1:value1,key2:value2
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//	tls, tlsCA, tlsCert, tlsKey, tlsServerName and tlsSkipVerify of TLS, https is used unless tls=false
+//
+// Entries are not encoded by encoder of zap config. Fields are reported as extra data of events, errors of fields
+// are reported as exceptions with stacktraces of entries. Events of levels above error are sent before
+// zap exits or panics.
+package sentry
+
+import (
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/rookie-ninja/rk-logger/internal/tlsconfig"
+	"go.uber.org/zap/zapcore"
+	"math/rand"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Scheme is the scheme of output paths which are reported to Sentry.
+	Scheme = "sentry"
+
+	// default max number of breadcrumbs attached to an event
+	defaultMaxBreadcrumbs = 100
+)
+
+func init() {
+	if err := rklogger.RegisterCore(Scheme, newCoreWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// options are the options of events parsed from url
+type options struct {
+	eventLevel      zapcore.Level
+	breadcrumbLevel zapcore.Level
+	maxBreadcrumbs  int
+	sampleRate      float64
+	environment     string
+	release         string
+	serverName      string
+	tags            map[string]string
+}
+
+// breadcrumbs keeps the latest entries below level of events, it is shared by cores derived with With
+type breadcrumbs struct {
+	values []*breadcrumb
+	max    int
+	mutex  sync.Mutex
+}
+
+// Add breadcrumb, the oldest breadcrumb is dropped once it is full, nothing is kept if max is not positive
+func (crumbs *breadcrumbs) add(crumb *breadcrumb) {
+	if crumbs.max <= 0 {
+		return
+	}
+
+	crumbs.mutex.Lock()
+	defer crumbs.mutex.Unlock()
+
+	if len(crumbs.values) >= crumbs.max {
+		crumbs.values = append(crumbs.values[:0], crumbs.values[1:]...)
+	}
+	crumbs.values = append(crumbs.values, crumb)
+}
+
+// Copy breadcrumbs, nil is returned if there is no breadcrumb
+func (crumbs *breadcrumbs) snapshot() []*breadcrumb {
+	crumbs.mutex.Lock()
+	defer crumbs.mutex.Unlock()
+
+	if len(crumbs.values) == 0 {
+		return nil
+	}
+
+	return append([]*breadcrumb(nil), crumbs.values...)
+}
+
+// sentryCore implements zapcore.Core which reports entries to Sentry
+type sentryCore struct {
+	zapcore.LevelEnabler
+	options     *options
+	breadcrumbs *breadcrumbs
+	writer      *batch.Writer
+	fields      []zapcore.Field
+}
+
+// Create core with url of output path and level enabler of zap config
+func newCoreWithURL(u url.URL, enabler zapcore.LevelEnabler) (zapcore.Core, error) {
+	config, options, transport, err := parseURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCore(enabler, config, options, transport), nil
+}
+
+// Create core which sends batches of events with transport
+func newCore(enabler zapcore.LevelEnabler, config batch.Config, options *options, transport *transport) *sentryCore {
+	return &sentryCore{
+		LevelEnabler: enabler,
+		options:      options,
+		breadcrumbs:  &breadcrumbs{max: options.maxBreadcrumbs},
+		writer:       batch.NewWriter(config, transport.send),
+	}
+}
+
+// Parse batching config, options of events and transport from url
+func parseURL(u *url.URL) (batch.Config, *options, *transport, error) {
+	config := batch.DefaultConfig()
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, nil, err
+	}
+
+	// https is used by default like DSN of Sentry
+	secure := true
+	if value := query.Get("tls"); len(value) > 0 {
+		if secure, err = strconv.ParseBool(value); err != nil {
+			return config, nil, nil, errors.Wrap(err, "invalid query of sentry url, key:tls")
+		}
+	}
+
+	tlsConfig, query, err := tlsconfig.FromQuery(query)
+	if err != nil {
+		return config, nil, nil, err
+	}
+
+	options := &options{
+		eventLevel:      zapcore.ErrorLevel,
+		breadcrumbLevel: zapcore.WarnLevel,
+		maxBreadcrumbs:  defaultMaxBreadcrumbs,
+		sampleRate:      1,
+		tags:            make(map[string]string),
+	}
+
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		switch key {
+		case "level":
+			err = options.eventLevel.UnmarshalText([]byte(value))
+		case "breadcrumbs":
+			err = options.breadcrumbLevel.UnmarshalText([]byte(value))
+		case "maxBreadcrumbs":
+			options.maxBreadcrumbs, err = strconv.Atoi(value)
+			if err == nil && options.maxBreadcrumbs < 0 {
+				err = errors.New("negative number")
+			}
+		case "sampleRate":
+			options.sampleRate, err = strconv.ParseFloat(value, 64)
+			if err == nil && (options.sampleRate < 0 || options.sampleRate > 1) {
+				err = errors.New("rate is out of range [0, 1]")
+			}
+		case "environment":
+			options.environment = value
+		case "release":
+			options.release = value
+		case "serverName":
+			options.serverName = value
+		case "tags":
+			for _, pair := range strings.Split(value, ",") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) != 2 || len(kv[0]) == 0 {
+					return config, nil, nil, errors.Errorf("invalid tag in sentry url, tag:%s", pair)
+				}
+				options.tags[kv[0]] = kv[1]
+			}
+		default:
+			return config, nil, nil, errors.Errorf("unknown query of sentry url, key:%s", key)
+		}
+
+		if err != nil {
+			return config, nil, nil, errors.Wrapf(err, "invalid query of sentry url, key:%s", key)
+		}
+	}
+
+	if len(options.serverName) == 0 {
+		options.serverName, _ = os.Hostname()
+	}
+
+	transport, err := newTransport(u, secure, tlsConfig)
+	if err != nil {
+		return config, nil, nil, err
+	}
+
+	return config, options, transport, nil
+}
+
+// Enabled implements zapcore.LevelEnabler, entries below level of breadcrumbs are ignored
+func (core *sentryCore) Enabled(level zapcore.Level) bool {
+	if !core.LevelEnabler.Enabled(level) {
+		return false
+	}
+
+	if level >= core.options.eventLevel {
+		return true
+	}
+
+	return core.options.maxBreadcrumbs > 0 && level >= core.options.breadcrumbLevel
+}
+
+// With implements zapcore.Core
+func (core *sentryCore) With(fields []zapcore.Field) zapcore.Core {
+	res := make([]zapcore.Field, 0, len(core.fields)+len(fields))
+	res = append(res, core.fields...)
+	res = append(res, fields...)
+
+	return &sentryCore{
+		LevelEnabler: core.LevelEnabler,
+		options:      core.options,
+		breadcrumbs:  core.breadcrumbs,
+		writer:       core.writer,
+		fields:       res,
+	}
+}
+
+// Check implements zapcore.Core
+func (core *sentryCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core, entry is kept as breadcrumb or queued as event
+func (core *sentryCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if entry.Level < core.options.eventLevel {
+		core.breadcrumbs.add(toBreadcrumb(entry, core.fields, fields))
+		return nil
+	}
+
+	if core.options.sampleRate < 1 && rand.Float64() >= core.options.sampleRate {
+		return nil
+	}
+
+	payload, err := encodeEvent(toEvent(entry, core.options, core.breadcrumbs.snapshot(), core.fields, fields))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode sentry event")
+	}
+
+	if _, err = core.writer.Write(payload); err != nil {
+		return err
+	}
+
+	// zap panics or exits after writing entries above error level
+	if entry.Level > zapcore.ErrorLevel {
+		return core.writer.Sync()
+	}
+
+	return nil
+}
+
+// Sync implements zapcore.Core, queued events are sent
+func (core *sentryCore) Sync() error {
+	return core.writer.Sync()
+}
+
+// Close implements io.Closer, queued events are sent before closing
+func (core *sentryCore) Close() error {
+	return core.writer.Close()
+}
+
+// Stats returns delivery counters of batches which are sent, which are listed by rklogger.ListDeliveryStats()
+func (core *sentryCore) Stats() batch.Stats {
+	return core.writer.Stats()
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// name of client reported to Sentry
+const clientName = "rk-logger/1.0"
+
+// transport sends events as envelopes, it is only used by the flushing goroutine of batch.Writer
+type transport struct {
+	endpoint string
+	auth     string
+	client   *http.Client
+	// first event of batch being sent and number of its events which were sent,
+	// so that retries of batch do not send events again
+	current *[]byte
+	sent    int
+}
+
+// Create transport with DSN which is url of output path with scheme of http or https
+func newTransport(u *url.URL, secure bool, tlsConfig *tls.Config) (*transport, error) {
+	if u.User == nil || len(u.User.Username()) == 0 {
+		return nil, errors.New("public key is missing in sentry url")
+	}
+
+	if len(u.Host) == 0 {
+		return nil, errors.New("host is missing in sentry url")
+	}
+
+	prefix, projectID := path.Split(strings.TrimRight(u.Path, "/"))
+	if len(projectID) == 0 {
+		return nil, errors.New("project id is missing in sentry url")
+	}
+
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", clientName, u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	scheme := "http"
+	httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+	if secure {
+		scheme = "https"
+		httpTransport.TLSClientConfig = tlsConfig
+	}
+
+	return &transport{
+		endpoint: fmt.Sprintf("%s://%s%sapi/%s/envelope/", scheme, u.Host, prefix, projectID),
+		auth:     auth,
+		client:   &http.Client{Transport: httpTransport},
+	}, nil
+}
+
+// Send events one envelope per event, events of batch which were sent are skipped on retries.
+// Rejected events are skipped so that the rest of batch is sent, the last rejection is returned.
+func (transport *transport) send(ctx context.Context, events [][]byte) error {
+	if transport.current != &events[0] {
+		transport.current, transport.sent = &events[0], 0
+	}
+
+	var rejected error
+	for transport.sent < len(events) {
+		retry, err := transport.sendEnvelope(ctx, events[transport.sent])
+		if err != nil && retry {
+			return err
+		}
+
+		if err != nil {
+			rejected = err
+		}
+		transport.sent++
+	}
+
+	if rejected != nil {
+		return batch.Permanent(rejected)
+	}
+
+	return nil
+}
+
+// Send event in an envelope, whether failure could be retried is returned with error
+func (transport *transport) sendEnvelope(ctx context.Context, event []byte) (bool, error) {
+	body := &bytes.Buffer{}
+	fmt.Fprintf(body, `{"sent_at":"%s"}`+"\n", time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(body, `{"type":"event","length":%d}`+"\n", len(event))
+	body.Write(event)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, transport.endpoint, body)
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", transport.auth)
+
+	resp, err := transport.client.Do(req)
+	if err != nil {
+		return true, errors.Wrapf(err, "failed to send event, endpoint:%s", transport.endpoint)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusBadRequest {
+		io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+
+	reply, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = errors.Errorf("event is not accepted, endpoint:%s, status:%d, body:%s",
+		transport.endpoint, resp.StatusCode, reply)
+
+	// rate limited and unavailable events are retried with backoff, the rest of failures would fail again
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+	return retry, err
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package sentry
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// Create transport which sends to server
+func newTestTransport(t *testing.T, server *sentryServer) *transport {
+	u, _ := url.Parse(server.outputPath(""))
+	transport, err := newTransport(u, false, nil)
+	assert.Nil(t, err)
+
+	return transport
+}
+
+func TestTransport_Send(t *testing.T) {
+	server := newSentryServer(t)
+	transport := newTestTransport(t, server)
+
+	events := [][]byte{[]byte(`{"message":{"formatted":"a"}}`), []byte(`{"message":{"formatted":"b"}}`)}
+	assert.Nil(t, transport.send(context.Background(), events))
+	assert.Len(t, server.events, 2)
+	assert.Equal(t, "Sentry sentry_version=7, sentry_client=rk-logger/1.0, sentry_key=ut-key", server.auths[0])
+}
+
+func TestTransport_SendWithRetries(t *testing.T) {
+	server := newSentryServer(t)
+	server.status = []int{http.StatusOK, http.StatusTooManyRequests}
+	transport := newTestTransport(t, server)
+
+	events := [][]byte{[]byte(`{"event_id":"a"}`), []byte(`{"event_id":"b"}`)}
+	assert.NotNil(t, transport.send(context.Background(), events))
+
+	// events which were sent are skipped on retries of the same batch
+	assert.Nil(t, transport.send(context.Background(), events))
+	assert.Len(t, server.events, 3)
+	assert.Equal(t, "b", server.events[2]["event_id"])
+
+	// With another batch
+	assert.Nil(t, transport.send(context.Background(), [][]byte{[]byte(`{"event_id":"c"}`)}))
+	assert.Len(t, server.events, 4)
+}
+
+func TestTransport_SendWithRejectedEvents(t *testing.T) {
+	server := newSentryServer(t)
+	server.status = []int{http.StatusBadRequest}
+	transport := newTestTransport(t, server)
+
+	// the rest of batch is sent after rejection
+	events := [][]byte{[]byte(`{"event_id":"a"}`), []byte(`{"event_id":"b"}`)}
+	err := transport.send(context.Background(), events)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status:400")
+	assert.Len(t, server.events, 2)
+}
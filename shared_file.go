@@ -0,0 +1,85 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"sync"
+	"time"
+)
+
+// SharedFileConfig makes file outputs safe to be written by multiple processes at the same time. Every entry is
+// appended to file with a single write syscall of file opened with O_APPEND, so that entries of processes never
+// interleave within a line, and entries failed to be written completely are reported as errors instead of being
+// written in pieces. Advisory lock of file is held with flock while writing every entry if Lock is enabled.
+//
+// Limitations:
+//
+//   - Shared files could not be rotated by logger, rotate them with external tools like logrotate instead.
+//   - Network file systems like NFS may not keep O_APPEND writes atomic, and flock is not supported on windows.
+//   - Files are synced with sync policy only, writes of processes are not ordered by time of entries.
+//
+// Example config file in YAML:
+//
+//	shared:
+//	  lock: true
+type SharedFileConfig struct {
+	// Lock holds advisory lock of file with flock while writing every entry,
+	// which excludes processes locking the file as well.
+	Lock bool `json:"lock" yaml:"lock"`
+}
+
+// sharedFileWriter appends entries to file shared among processes with a single write syscall each
+type sharedFileWriter struct {
+	file  *os.File
+	lock  bool
+	mutex sync.Mutex
+}
+
+// Write implements zapcore.WriteSyncer
+func (writer *sharedFileWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.lock {
+		if err := lockFile(writer.file); err != nil {
+			return 0, err
+		}
+		defer unlockFile(writer.file)
+	}
+
+	return writeOnce(writer.file, p)
+}
+
+// Sync implements zapcore.WriteSyncer
+func (writer *sharedFileWriter) Sync() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.file.Sync()
+}
+
+// Open file shared among processes, rotation is rejected since files could not be renamed by all of the processes
+func openSharedFile(filePath string, config *SharedFileConfig, rotation *RotationConfig,
+	perms *filePermissions, policy *syncPolicy) (zapcore.WriteSyncer, func(), error) {
+	if rotation != nil {
+		return nil, nil, errors.Errorf("shared file could not be rotated, filename:%s", filePath)
+	}
+
+	if config.Lock && !fileLockSupported {
+		return nil, nil, errors.Errorf("lock of shared file is not supported on this platform, filename:%s", filePath)
+	}
+
+	file, err := perms.openFile(ExpandFilename(filePath, time.Now()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	syncer, closer := policy.wrap(&sharedFileWriter{file: file, lock: config.Lock}, func() { file.Close() })
+	return syncer, closer, nil
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"os"
+)
+
+// Write p with file, which is appended by a single write of file opened with O_APPEND on windows as well
+func writeOnce(file *os.File, p []byte) (int, error) {
+	return file.Write(p)
+}
+
+// Advisory lock of files is not supported
+func lockFile(file *os.File) error {
+	return errors.New("lock of shared files is not supported on this platform")
+}
+
+// Advisory lock of files is not supported
+func unlockFile(file *os.File) error {
+	return nil
+}
+
+// Whether advisory lock of files is supported
+const fileLockSupported = false
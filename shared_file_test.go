@@ -0,0 +1,109 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Assert that file consists of count json lines which are not interleaved
+func assertSharedFileLines(t *testing.T, filePath string, count int) {
+	lines := strings.Split(strings.TrimSuffix(readFileContent(filePath), "\n"), "\n")
+	assert.Len(t, lines, count)
+
+	for _, line := range lines {
+		entry := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal([]byte(line), &entry), line)
+	}
+}
+
+func TestSharedFileWriter_WithConcurrentWriters(t *testing.T) {
+	for _, lock := range []bool{false, fileLockSupported} {
+		filePath := path.Join(newTempDir(t), "ut.log")
+
+		// every writer opens file by itself like processes
+		wait := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			syncer, closer, err := defaultLoader.openOutput(&OutputConfig{Path: filePath, Shared: &SharedFileConfig{Lock: lock}})
+			assert.Nil(t, err)
+
+			wait.Add(1)
+			go func(writer int) {
+				defer wait.Done()
+				defer closer()
+
+				for j := 0; j < 200; j++ {
+					// entries larger than pipe buffer
+					syncer.Write([]byte(fmt.Sprintf(`{"writer":%d,"entry":%d,"msg":"%s"}`+"\n", writer, j, strings.Repeat("x", 8192))))
+				}
+			}(i)
+		}
+
+		wait.Wait()
+		assertSharedFileLines(t, filePath, 8*200)
+	}
+}
+
+// Helper process which writes entries to shared file, it is started by TestSharedFile_WithProcesses
+func TestSharedFileHelperProcess(t *testing.T) {
+	filePath := os.Getenv("RK_LOGGER_SHARED_FILE")
+	if len(filePath) == 0 {
+		return
+	}
+
+	logger, closer, err := NewZapLoggerWithCloser(NewConfigWithOptions(
+		WithJSONEncoding(),
+		WithOutput(filePath),
+		WithSharedFile(fileLockSupported)))
+	assert.Nil(t, err)
+
+	for i := 0; i < 500; i++ {
+		logger.Info(strings.Repeat("x", 4096))
+	}
+	closer.Shutdown(context.Background())
+}
+
+func TestSharedFile_WithProcesses(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	processes := make([]*exec.Cmd, 0)
+	for i := 0; i < 4; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestSharedFileHelperProcess$")
+		cmd.Env = append(os.Environ(), "RK_LOGGER_SHARED_FILE="+filePath)
+		assert.Nil(t, cmd.Start())
+		processes = append(processes, cmd)
+	}
+
+	for i := range processes {
+		assert.Nil(t, processes[i].Wait())
+	}
+
+	assertSharedFileLines(t, filePath, 4*500)
+}
+
+func TestSharedFile_WithRotation(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+
+	_, _, err := defaultLoader.openOutput(&OutputConfig{
+		Path:     filePath,
+		Rotation: &RotationConfig{Schedule: ScheduleDaily},
+		Shared:   &SharedFileConfig{},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestWithSharedFile(t *testing.T) {
+	config := NewConfigWithOptions(WithSharedFile(true))
+	assert.Equal(t, &SharedFileConfig{Lock: true}, config.Shared)
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package rklogger
+
+import (
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// Write p with a single write syscall, short write is returned as io.ErrShortWrite instead of writing the rest
+func writeOnce(file *os.File, p []byte) (int, error) {
+	for {
+		n, err := syscall.Write(int(file.Fd()), p)
+		if err == syscall.EINTR && n <= 0 {
+			continue
+		}
+
+		if n < 0 {
+			n = 0
+		}
+
+		if err != nil {
+			return n, &os.PathError{Op: "write", Path: file.Name(), Err: err}
+		}
+
+		if n < len(p) {
+			return n, io.ErrShortWrite
+		}
+
+		return n, nil
+	}
+}
+
+// Hold exclusive advisory lock of file
+func lockFile(file *os.File) error {
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return errors.Wrapf(err, "failed to lock file, filename:%s", file.Name())
+		}
+	}
+}
+
+// Release advisory lock of file
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
+
+// Whether advisory lock of files is supported
+const fileLockSupported = true
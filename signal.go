@@ -0,0 +1,122 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ReloadOnSignal reloads logger in background once one of signals is received, SIGHUP by default.
+// Since every reload reopens file outputs, it works with logrotate which moves log files and sends SIGHUP.
+//
+// Signals are relayed with signal.Notify, so handlers installed by program itself keep receiving them.
+// Programs handling SIGHUP by themselves could call Reload directly instead.
+func (logger *ReloadableLogger) ReloadOnSignal(signals ...os.Signal) error {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	if logger.closed {
+		return errors.New("reloadable logger is closed")
+	}
+
+	if logger.signals != nil {
+		return errors.New("signals are already handled")
+	}
+
+	if len(signals) < 1 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	logger.signals = make(chan os.Signal, 1)
+	logger.signalDone = make(chan struct{})
+	signal.Notify(logger.signals, signals...)
+	go logger.handleSignals(logger.signals, logger.signalDone)
+
+	return nil
+}
+
+// Stop relaying signals to logger
+func (logger *ReloadableLogger) stopSignals() {
+	if logger.signals != nil {
+		signal.Stop(logger.signals)
+		close(logger.signalDone)
+		logger.signals = nil
+	}
+}
+
+// Reload logger with received signals until done is closed
+func (logger *ReloadableLogger) handleSignals(signals chan os.Signal, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case sig := <-signals:
+			if err := logger.Reload(); err != nil {
+				logger.zapLogger.Error("failed to reload config file", zap.String("filePath", logger.filePath), zap.Stringer("signal", sig), zap.Error(err))
+			}
+		}
+	}
+}
+
+// RotateOnSignal rotates file outputs of all loggers in background once one of signals is received,
+// SIGUSR1 by default which is not available on windows. Signals are relayed with signal.Notify, so that external
+// tools like cron could force rotation with kill -USR1.
+func (factory *LoggerFactory) RotateOnSignal(signals ...os.Signal) error {
+	factory.mutex.Lock()
+	defer factory.mutex.Unlock()
+
+	if factory.closed {
+		return errors.New("logger factory is closed")
+	}
+
+	if factory.signals != nil {
+		return errors.New("signals are already handled")
+	}
+
+	if len(signals) < 1 {
+		signals = defaultRotationSignals
+	}
+
+	if len(signals) < 1 {
+		return errors.New("signals are required since there is no default signal of rotation")
+	}
+
+	factory.signals = make(chan os.Signal, 1)
+	factory.signalDone = make(chan struct{})
+	signal.Notify(factory.signals, signals...)
+	go factory.handleSignals(factory.signals, factory.signalDone)
+
+	return nil
+}
+
+// Stop relaying signals to factory
+func (factory *LoggerFactory) stopSignals() {
+	if factory.signals != nil {
+		signal.Stop(factory.signals)
+		close(factory.signalDone)
+		factory.signals = nil
+	}
+}
+
+// Rotate file outputs with received signals until done is closed, errors are reported to stderr
+func (factory *LoggerFactory) handleSignals(signals chan os.Signal, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case sig := <-signals:
+			if err := factory.RotateAll(); err != nil {
+				fmt.Fprintf(os.Stderr, "%v failed to rotate files, signal:%v, error:%v\n", time.Now(), sig, err)
+			}
+		}
+	}
+}
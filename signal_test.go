@@ -0,0 +1,114 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Send SIGHUP to current process
+func sendSIGHUP(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP could not be sent on windows")
+	}
+
+	process, err := os.FindProcess(os.Getpid())
+	assert.Nil(t, err)
+	assert.Nil(t, process.Signal(syscall.SIGHUP))
+}
+
+func TestReloadableLogger_ReloadOnSignal(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	defer logger.Close()
+
+	assert.Nil(t, logger.ReloadOnSignal())
+	// handle signals twice
+	assert.NotNil(t, logger.ReloadOnSignal())
+
+	writeReloadConfig(t, filePath, "debug", path.Join(dir, "ut.log"))
+	sendSIGHUP(t)
+
+	assert.Eventually(t, func() bool {
+		return logger.Logger().Core().Enabled(zapcore.DebugLevel)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// Log file moved by logrotate is reopened after SIGHUP
+func TestReloadableLogger_ReloadOnSignalWithRotatedFile(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	logPath := path.Join(dir, "ut.log")
+	writeReloadConfig(t, filePath, "info", logPath)
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	defer logger.Close()
+	assert.Nil(t, logger.ReloadOnSignal(syscall.SIGHUP))
+
+	logger.Logger().Info("before-rotation")
+	assert.Nil(t, os.Rename(logPath, logPath+".1"))
+	sendSIGHUP(t)
+
+	assert.Eventually(t, func() bool {
+		logger.Logger().Info("after-rotation")
+		return strings.Contains(readFileContent(logPath), "after-rotation")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, readFileContent(logPath+".1"), "before-rotation")
+	assert.NotContains(t, readFileContent(logPath), "before-rotation")
+}
+
+func TestReloadableLogger_ReloadOnSignalAfterClose(t *testing.T) {
+	dir := newTempDir(t)
+	filePath := path.Join(dir, "zap.yaml")
+	writeReloadConfig(t, filePath, "info", path.Join(dir, "ut.log"))
+
+	logger, err := NewReloadableZapLogger(filePath, YAML)
+	assert.Nil(t, err)
+	assert.Nil(t, logger.ReloadOnSignal())
+	assert.Nil(t, logger.Close())
+
+	assert.Nil(t, logger.signals)
+	assert.NotNil(t, logger.ReloadOnSignal())
+}
+
+func TestLoggerFactory_RotateOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGUSR1 is not available on windows")
+	}
+
+	dir := newTempDir(t)
+	factory, err := NewLoggerFactoryWithBytes(newRotationFactoryConfig(dir), YAML)
+	assert.Nil(t, err)
+	defer factory.Close()
+
+	assert.Nil(t, factory.RotateOnSignal())
+	// handle signals twice
+	assert.NotNil(t, factory.RotateOnSignal())
+
+	logger, _ := factory.GetLogger("app")
+	logger.Info("ut-message")
+
+	process, err := os.FindProcess(os.Getpid())
+	assert.Nil(t, err)
+	assert.Nil(t, process.Signal(defaultRotationSignals[0]))
+
+	assert.Eventually(t, func() bool {
+		return len(listFileNames(t, dir)) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, readFileContent(path.Join(dir, "app.log")))
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package rklogger
+
+import (
+	"os"
+	"syscall"
+)
+
+// signals which rotate file outputs by default
+var defaultRotationSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,14 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build windows
+// +build windows
+
+package rklogger
+
+import "os"
+
+// SIGUSR1 is not available on windows, so there is no default signal of rotation
+var defaultRotationSignals []os.Signal
@@ -0,0 +1,202 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package rklogger
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"log/slog"
+	"math"
+	"runtime"
+)
+
+// NewSlogHandler creates slog.Handler backed by zap logger built with combined config, see ToSlog.
+func NewSlogHandler(config *Config, opts ...zap.Option) (slog.Handler, error) {
+	return defaultLoader.NewSlogHandler(config, opts...)
+}
+
+// NewSlogHandler creates slog.Handler backed by zap logger built with combined config and options of Loader,
+// see ToSlog.
+func (loader *Loader) NewSlogHandler(config *Config, opts ...zap.Option) (slog.Handler, error) {
+	logger, err := loader.NewZapLoggerWithConfig(config, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &slogHandler{logger: logger}, nil
+}
+
+// ToSlog creates slog.Logger which writes records to zap logger, so that code using log/slog is backed by loggers
+// built from config files.
+//
+// Levels of slog are mapped to zap levels by every four levels, like debug to debug and warn to warn, levels between
+// them are mapped to the lower one, and levels above error are mapped to error. Groups are mapped to namespaces and
+// attrs are mapped to fields. Callers of records are used if logger adds callers.
+func ToSlog(logger *zap.Logger) *slog.Logger {
+	return slog.New(&slogHandler{logger: logger})
+}
+
+// slogHandler implements slog.Handler with zap logger
+type slogHandler struct {
+	logger *zap.Logger
+	// groups which are not added as namespaces until there are attrs in them, since empty groups are omitted
+	groups []string
+}
+
+// Map level of slog to zap level
+func toZapLevel(level slog.Level) zapcore.Level {
+	// round towards negative infinity, like -5 to -2
+	res := int(level) / 4
+	if int(level)%4 < 0 {
+		res--
+	}
+
+	if res > int(zapcore.ErrorLevel) {
+		return zapcore.ErrorLevel
+	}
+
+	if res < math.MinInt8 {
+		return zapcore.Level(math.MinInt8)
+	}
+
+	return zapcore.Level(res)
+}
+
+// Enabled implements slog.Handler
+func (handler *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return handler.logger.Core().Enabled(toZapLevel(level))
+}
+
+// Handle implements slog.Handler, time and caller of entry are replaced with the ones of record
+func (handler *slogHandler) Handle(_ context.Context, record slog.Record) error {
+	checked := handler.logger.Check(toZapLevel(record.Level), record.Message)
+	if checked == nil {
+		return nil
+	}
+
+	if !record.Time.IsZero() {
+		checked.Entry.Time = record.Time
+	}
+
+	if checked.Entry.Caller.Defined && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		checked.Entry.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	fields := make([]zapcore.Field, 0, record.NumAttrs()+len(handler.groups))
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, attr)
+		return true
+	})
+
+	if len(fields) > 0 {
+		fields = append(handler.namespaces(), fields...)
+	}
+
+	checked.Write(fields...)
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (handler *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zapcore.Field, 0, len(attrs))
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, attr)
+	}
+
+	if len(fields) == 0 {
+		return handler
+	}
+
+	return &slogHandler{logger: handler.logger.With(append(handler.namespaces(), fields...)...)}
+}
+
+// WithGroup implements slog.Handler
+func (handler *slogHandler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return handler
+	}
+
+	groups := make([]string, 0, len(handler.groups)+1)
+	return &slogHandler{logger: handler.logger, groups: append(append(groups, handler.groups...), name)}
+}
+
+// Namespaces of groups which are not added
+func (handler *slogHandler) namespaces() []zapcore.Field {
+	res := make([]zapcore.Field, 0, len(handler.groups))
+	for _, group := range handler.groups {
+		res = append(res, zap.Namespace(group))
+	}
+
+	return res
+}
+
+// Append attr as field, attrs of group without key are inlined and empty attrs are ignored
+func appendSlogAttr(fields []zapcore.Field, attr slog.Attr) []zapcore.Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	value := attr.Value
+	switch value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(attr.Key, value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(attr.Key, value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(attr.Key, value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(attr.Key, value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(attr.Key, value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(attr.Key, value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(attr.Key, value.Time()))
+	case slog.KindGroup:
+		attrs := value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+
+		if len(attr.Key) == 0 {
+			for _, nested := range attrs {
+				fields = appendSlogAttr(fields, nested)
+			}
+			return fields
+		}
+
+		return append(fields, zap.Object(attr.Key, slogGroup(attrs)))
+	default:
+		if err, ok := value.Any().(error); ok {
+			return append(fields, zap.NamedError(attr.Key, err))
+		}
+
+		return append(fields, zap.Any(attr.Key, value.Any()))
+	}
+}
+
+// slogGroup encodes attrs of group as an object
+type slogGroup []slog.Attr
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (group slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	fields := make([]zapcore.Field, 0, len(group))
+	for _, attr := range group {
+		fields = appendSlogAttr(fields, attr)
+	}
+
+	for i := range fields {
+		fields[i].AddTo(enc)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package rklogger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"log/slog"
+	"math"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Create zap logger writing entries of level and above to buffer with json encoding
+func newSlogTestLogger(level zapcore.Level, opts ...zap.Option) (*zap.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	encoderConfig := zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeTime:     zapcore.RFC3339TimeEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(buf), level)
+	return zap.New(core, opts...), buf
+}
+
+func TestToZapLevel(t *testing.T) {
+	assert.Equal(t, zapcore.DebugLevel, toZapLevel(slog.LevelDebug))
+	assert.Equal(t, zapcore.InfoLevel, toZapLevel(slog.LevelInfo))
+	assert.Equal(t, zapcore.InfoLevel, toZapLevel(slog.LevelInfo+2))
+	assert.Equal(t, zapcore.WarnLevel, toZapLevel(slog.LevelWarn))
+	assert.Equal(t, zapcore.ErrorLevel, toZapLevel(slog.LevelError))
+	assert.Equal(t, zapcore.ErrorLevel, toZapLevel(slog.LevelError+4))
+	assert.Equal(t, zapcore.Level(-2), toZapLevel(slog.LevelDebug-1))
+	assert.Equal(t, zapcore.Level(math.MinInt8), toZapLevel(slog.Level(math.MinInt32)))
+}
+
+func TestToSlog_HappyCase(t *testing.T) {
+	logger, buf := newSlogTestLogger(zapcore.InfoLevel)
+	slogger := ToSlog(logger.Named("ut"))
+
+	assert.False(t, slogger.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, slogger.Enabled(context.Background(), slog.LevelWarn))
+
+	slogger.Debug("ut-debug")
+	slogger.Warn("ut-warn",
+		"str", "ut-value",
+		"int", 42,
+		"uint", uint64(7),
+		"float", 1.5,
+		"bool", true,
+		"duration", time.Second,
+		"time", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		"err", errors.New("ut-error"),
+		"any", []int{1, 2},
+		slog.Group("request", "method", "GET", slog.Group("empty")),
+		slog.Group("", "inlined", 1),
+		slog.Attr{})
+
+	assert.Equal(t, `{"level":"warn","logger":"ut","msg":"ut-warn","str":"ut-value","int":42,"uint":7,"float":1.5,`+
+		`"bool":true,"duration":"1s","time":"2021-01-01T00:00:00Z","err":"ut-error","any":[1,2],`+
+		`"request":{"method":"GET"},"inlined":1}`+"\n", buf.String())
+}
+
+func TestToSlog_WithGroups(t *testing.T) {
+	logger, buf := newSlogTestLogger(zapcore.InfoLevel)
+	slogger := ToSlog(logger)
+
+	// empty groups are omitted
+	slogger.WithGroup("empty").Info("ut-empty")
+	slogger.WithGroup("").With("a", 1).Info("ut-unnamed")
+
+	// groups are namespaces of attrs added later
+	slogger.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h").Info("ut-nested", "c", 3)
+	slogger.WithGroup("g").With().Info("ut-record", "c", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		`{"level":"info","msg":"ut-empty"}`,
+		`{"level":"info","msg":"ut-unnamed","a":1}`,
+		`{"level":"info","msg":"ut-nested","a":1,"g":{"b":2,"h":{"c":3}}}`,
+		`{"level":"info","msg":"ut-record","g":{"c":3}}`,
+	}, lines)
+}
+
+func TestToSlog_WithCaller(t *testing.T) {
+	logger, buf := newSlogTestLogger(zapcore.InfoLevel, zap.AddCaller())
+	ToSlog(logger).Info("ut-caller")
+
+	assert.Contains(t, buf.String(), `/slog_test.go:`)
+	assert.NotContains(t, buf.String(), `slog.go`)
+}
+
+func TestNewSlogHandler(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	handler, err := NewSlogHandler(config)
+	assert.Nil(t, err)
+
+	slog.New(handler).Info("ut-message", "key", "value")
+	assert.Equal(t, `{"msg":"ut-message","key":"value"}`+"\n", readFileContent(path.Join(dir, "app.log")))
+
+	// invalid config
+	_, err = NewSlogHandler(nil)
+	assert.NotNil(t, err)
+}
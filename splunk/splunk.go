@@ -0,0 +1,262 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+// Package splunk registers splunk scheme with rklogger.RegisterWriter, so that entries are sent to
+// HTTP Event Collector of Splunk by output paths like:
+//
+//	outputPaths:
+//	  - splunk://hec.example.com:8088?token=${SPLUNK_TOKEN}&sourcetype=_json&index=app&tls=true&gzip=true
+//
+// Import the package for side effects in order to enable the scheme:
+//
+//	import _ "github.com/rookie-ninja/rk-logger/splunk"
+//
+// Path of url is the path of event endpoint, /services/collector/event by default.
+//
+// Query parameters:
+//
+//	token:      token of HTTP Event Collector, which is required
+//	sourcetype: sourcetype of events, default of token if not provided
+//	index:      index of events, default of token if not provided
+//	source:     source of events, default of token if not provided
+//	host:       host of events, hostname by default
+//	gzip:       compresses requests with gzip, false by default
+//	batchSize, batchBytes, linger, queueSize, overflow, retries, backoff, timeout, spoolDir and spoolSize of batching
+//	tls, tlsCA, tlsCert, tlsKey, tlsServerName and tlsSkipVerify of TLS, http is used unless TLS is enabled
+//
+// Entries encoded as json are sent as json events, the rest of entries are sent as string events.
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/rookie-ninja/rk-logger"
+	"github.com/rookie-ninja/rk-logger/internal/batch"
+	"github.com/rookie-ninja/rk-logger/internal/tlsconfig"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// Scheme is the scheme of output paths which are sent to HTTP Event Collector.
+	Scheme = "splunk"
+	// EventPath is the default path of event endpoint.
+	EventPath = "/services/collector/event"
+)
+
+func init() {
+	if err := rklogger.RegisterWriter(Scheme, newWriterWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// collector sends events to HTTP Event Collector, it is only used by the flushing goroutine of batch.Writer
+type collector struct {
+	endpoint   string
+	token      string
+	sourcetype string
+	index      string
+	source     string
+	host       string
+	gzip       bool
+	client     *http.Client
+}
+
+// writer encodes entries as events and sends them in batches
+type writer struct {
+	*batch.Writer
+	collector *collector
+}
+
+// event is the event of HTTP Event Collector
+type event struct {
+	Time       float64         `json:"time"`
+	Host       string          `json:"host,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Sourcetype string          `json:"sourcetype,omitempty"`
+	Index      string          `json:"index,omitempty"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// Create writer with url of output path
+func newWriterWithURL(u url.URL) (zapcore.WriteSyncer, error) {
+	config, collector, err := parseURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWriter(config, collector), nil
+}
+
+// Create writer which sends batches with collector
+func newWriter(config batch.Config, collector *collector) *writer {
+	return &writer{
+		Writer:    batch.NewWriter(config, collector.send),
+		collector: collector,
+	}
+}
+
+// Write implements zapcore.WriteSyncer, p is encoded as one event with current time
+func (writer *writer) Write(p []byte) (int, error) {
+	if _, err := writer.Writer.Write(writer.collector.encodeEvent(time.Now(), p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close flushes queued entries and closes idle connections
+func (writer *writer) Close() error {
+	err := writer.Writer.Close()
+	writer.collector.client.CloseIdleConnections()
+	return err
+}
+
+// Parse batching config and collector from url
+func parseURL(u *url.URL) (batch.Config, *collector, error) {
+	config := batch.DefaultConfig()
+
+	if len(u.Host) == 0 {
+		return config, nil, errors.Errorf("host is required in splunk url, path:%s", u.Path)
+	}
+
+	query, err := config.ParseQuery(u.Query())
+	if err != nil {
+		return config, nil, err
+	}
+
+	tlsConfig, query, err := tlsconfig.FromQuery(query)
+	if err != nil {
+		return config, nil, err
+	}
+
+	collector := &collector{}
+	for key, values := range query {
+		value := values[len(values)-1]
+
+		switch key {
+		case "token":
+			collector.token = value
+		case "sourcetype":
+			collector.sourcetype = value
+		case "index":
+			collector.index = value
+		case "source":
+			collector.source = value
+		case "host":
+			collector.host = value
+		case "gzip":
+			if collector.gzip, err = strconv.ParseBool(value); err != nil {
+				return config, nil, errors.Wrapf(err, "invalid query of splunk url, key:%s", key)
+			}
+		default:
+			return config, nil, errors.Errorf("unknown query of splunk url, key:%s", key)
+		}
+	}
+
+	if len(collector.token) == 0 {
+		return config, nil, errors.New("token is required in splunk url")
+	}
+
+	if len(collector.host) == 0 {
+		collector.host, _ = os.Hostname()
+	}
+
+	scheme := "http"
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		scheme = "https"
+		transport.TLSClientConfig = tlsConfig
+	}
+	collector.client = &http.Client{Transport: transport}
+
+	path := u.Path
+	if len(path) == 0 || path == "/" {
+		path = EventPath
+	}
+	collector.endpoint = fmt.Sprintf("%s://%s%s", scheme, u.Host, path)
+
+	return config, collector, nil
+}
+
+// Encode entry as event with metadata of collector
+func (collector *collector) encodeEvent(now time.Time, p []byte) []byte {
+	payload := bytes.TrimRight(p, "\r\n")
+	if len(payload) == 0 || payload[0] != '{' || !json.Valid(payload) {
+		payload, _ = json.Marshal(string(payload))
+	}
+
+	res, _ := json.Marshal(&event{
+		Time:       float64(now.UnixNano()/int64(time.Millisecond)) / 1000,
+		Host:       collector.host,
+		Source:     collector.source,
+		Sourcetype: collector.sourcetype,
+		Index:      collector.index,
+		Event:      payload,
+	})
+
+	return res
+}
+
+// Send events in one request, events are concatenated as HTTP Event Collector expects
+func (collector *collector) send(ctx context.Context, events [][]byte) error {
+	body := &bytes.Buffer{}
+	if collector.gzip {
+		writer := gzip.NewWriter(body)
+		for i := range events {
+			writer.Write(events[i])
+		}
+		if err := writer.Close(); err != nil {
+			return batch.Permanent(err)
+		}
+	} else {
+		for i := range events {
+			body.Write(events[i])
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, collector.endpoint, body)
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Splunk "+collector.token)
+	req.Header.Set("Content-Type", "application/json")
+	if collector.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := collector.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send events, endpoint:%s", collector.endpoint)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusBadRequest {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	reply, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = errors.Errorf("events are not accepted, endpoint:%s, status:%d, body:%s",
+		collector.endpoint, resp.StatusCode, reply)
+
+	// busy or unavailable collectors are retried, the rest of failures would fail again
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return err
+	}
+
+	return batch.Permanent(err)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package splunk
+
+import (
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collectorServer records requests of HTTP Event Collector and replies with status in order
+type collectorServer struct {
+	*httptest.Server
+	bodies  []string
+	headers []http.Header
+	status  []int
+	mutex   sync.Mutex
+}
+
+// Start collector server
+func newCollectorServer(t *testing.T) *collectorServer {
+	server := &collectorServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, _ = gzip.NewReader(r.Body)
+		}
+		body, _ := ioutil.ReadAll(reader)
+		server.bodies = append(server.bodies, r.URL.Path+" "+string(body))
+		server.headers = append(server.headers, r.Header)
+
+		status := http.StatusOK
+		if len(server.status) > 0 {
+			status, server.status = server.status[0], server.status[1:]
+		}
+
+		w.WriteHeader(status)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Create writer which sends events to server
+func newTestWriter(t *testing.T, server *collectorServer, query string) *writer {
+	u, _ := url.Parse(strings.Replace(server.URL, "http", Scheme, 1) + query)
+	config, collector, err := parseURL(u)
+	assert.Nil(t, err)
+
+	return newWriter(config, collector)
+}
+
+func TestParseURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("splunk://hec.example.com:8088?token=abc&sourcetype=_json&index=app&source=api&host=node&gzip=true&tls=true&batchSize=10")
+	config, collector, err := parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://hec.example.com:8088/services/collector/event", collector.endpoint)
+	assert.Equal(t, "abc", collector.token)
+	assert.Equal(t, "_json", collector.sourcetype)
+	assert.Equal(t, "app", collector.index)
+	assert.Equal(t, "api", collector.source)
+	assert.Equal(t, "node", collector.host)
+	assert.True(t, collector.gzip)
+	assert.Equal(t, 10, config.Size)
+
+	// With custom path and defaults
+	u, _ = url.Parse("splunk://localhost:8088/services/collector?token=abc")
+	_, collector, err = parseURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8088/services/collector", collector.endpoint)
+	assert.False(t, collector.gzip)
+}
+
+func TestParseURL_WithInvalidURL(t *testing.T) {
+	for _, raw := range []string{
+		"splunk:///services/collector?token=abc",
+		"splunk://localhost:8088",
+		"splunk://localhost:8088?token=abc&gzip=x",
+		"splunk://localhost:8088?token=abc&unknown=x",
+		"splunk://localhost:8088?token=abc&retries=x",
+		"splunk://localhost:8088?token=abc&tlsSkipVerify=x",
+	} {
+		u, _ := url.Parse(raw)
+		_, _, err := parseURL(u)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestCollector_EncodeEvent(t *testing.T) {
+	collector := &collector{host: "node", sourcetype: "_json", index: "app"}
+	now := time.Unix(1577836800, 123456789)
+
+	assert.Equal(t, `{"time":1577836800.123,"host":"node","sourcetype":"_json","index":"app","event":{"msg":"a"}}`,
+		string(collector.encodeEvent(now, []byte(`{"msg":"a"}`+"\n"))))
+
+	// With console encoding
+	assert.Equal(t, `{"time":1577836800.123,"host":"node","sourcetype":"_json","index":"app","event":"INFO\thello"}`,
+		string(collector.encodeEvent(now, []byte("INFO\thello\n"))))
+}
+
+func TestWriter_HappyCase(t *testing.T) {
+	server := newCollectorServer(t)
+	writer := newTestWriter(t, server, "?token=abc&host=node&linger=1h")
+
+	writer.Write([]byte(`{"msg":"a"}` + "\n"))
+	writer.Write([]byte(`{"msg":"b"}` + "\n"))
+	assert.Nil(t, writer.Close())
+
+	assert.Len(t, server.bodies, 1)
+	assert.Regexp(t, `^/services/collector/event \{"time":[0-9.]+,"host":"node","event":\{"msg":"a"\}\}`+
+		`\{"time":[0-9.]+,"host":"node","event":\{"msg":"b"\}\}$`, server.bodies[0])
+	assert.Equal(t, "Splunk abc", server.headers[0].Get("Authorization"))
+}
+
+func TestWriter_WithGzip(t *testing.T) {
+	server := newCollectorServer(t)
+	writer := newTestWriter(t, server, "?token=abc&gzip=true&linger=1h")
+
+	writer.Write([]byte(`{"msg":"a"}`))
+	assert.Nil(t, writer.Close())
+
+	assert.Equal(t, "gzip", server.headers[0].Get("Content-Encoding"))
+	assert.Contains(t, server.bodies[0], `"event":{"msg":"a"}`)
+}
+
+func TestWriter_WithRetries(t *testing.T) {
+	server := newCollectorServer(t)
+	server.status = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+	writer := newTestWriter(t, server, "?token=abc&linger=1h&backoff=1ms")
+	defer writer.Close()
+
+	writer.Write([]byte(`{"msg":"a"}`))
+	assert.Nil(t, writer.Sync())
+	assert.Len(t, server.bodies, 3)
+}
+
+func TestWriter_WithRejectedEvents(t *testing.T) {
+	server := newCollectorServer(t)
+	server.status = []int{http.StatusForbidden}
+	writer := newTestWriter(t, server, "?token=abc&linger=1h&backoff=1ms")
+	defer writer.Close()
+
+	writer.Write([]byte(`{"msg":"a"}`))
+	err := writer.Sync()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "status:403")
+	assert.Len(t, server.bodies, 1)
+}
+
+func TestRegister(t *testing.T) {
+	sink, closeFunc, err := zap.Open("splunk://localhost:8088?token=abc")
+	assert.Nil(t, err)
+	assert.NotNil(t, sink)
+	closeFunc()
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"log"
+	"sync"
+)
+
+// frames of log.Logger and LogWriter between callers of log.Logger and zap logger
+const stdLogCallerSkip = 4
+
+// LogWriter is an io.Writer view of zap logger, every line written to it is logged as an entry with a level, like
+// zapio.Writer of newer zap. Partial line is kept until the rest of it is written, or Sync or Close is called.
+// It is safe for concurrent use.
+type LogWriter struct {
+	logger *zap.Logger
+	level  zapcore.Level
+	buf    bytes.Buffer
+	mutex  sync.Mutex
+}
+
+// NewLogWriter creates LogWriter which logs lines written to it with zap logger at level, so that output of
+// libraries and commands writing to io.Writer could be redirected through the same outputs.
+func NewLogWriter(logger *zap.Logger, level zapcore.Level) *LogWriter {
+	return &LogWriter{logger: logger, level: level}
+}
+
+// NewStdLogger creates log.Logger whose lines are logged with zap logger at level, like ErrorLog of http.Server:
+//
+//	server := &http.Server{ErrorLog: rklogger.NewStdLogger(logger, zapcore.WarnLevel)}
+//
+// Flags and prefix of log.Logger are empty, since time and caller are added by zap logger, and caller is the one
+// which calls log.Logger.
+func NewStdLogger(logger *zap.Logger, level zapcore.Level) (*log.Logger, error) {
+	if level < zapcore.DebugLevel || level > zapcore.FatalLevel {
+		return nil, errors.Errorf("invalid level of std logger, level:%d", level)
+	}
+
+	writer := NewLogWriter(logger.WithOptions(zap.AddCallerSkip(stdLogCallerSkip)), level)
+	return log.New(writer, "", 0), nil
+}
+
+// Write implements io.Writer, complete lines are logged without trailing newlines
+func (writer *LogWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			writer.buf.Write(p)
+			break
+		}
+
+		if writer.buf.Len() > 0 {
+			writer.buf.Write(p[:i])
+			writer.flush()
+		} else {
+			writer.log(p[:i])
+		}
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+// Sync logs partial line and syncs zap logger
+func (writer *LogWriter) Sync() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	writer.flush()
+	return writer.logger.Sync()
+}
+
+// Close implements io.Closer, it logs partial line, zap logger is not closed
+func (writer *LogWriter) Close() error {
+	return writer.Sync()
+}
+
+// Log buffered partial line
+func (writer *LogWriter) flush() {
+	if writer.buf.Len() == 0 {
+		return
+	}
+
+	writer.log(writer.buf.Bytes())
+	writer.buf.Reset()
+}
+
+// Log line as message of entry, carriage return of CRLF is trimmed
+func (writer *LogWriter) log(line []byte) {
+	if checked := writer.logger.Check(writer.level, string(bytes.TrimSuffix(line, []byte{'\r'}))); checked != nil {
+		checked.Write()
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// Messages of observed entries
+func observedMessages(logs *observer.ObservedLogs) []string {
+	res := make([]string, 0)
+	for _, entry := range logs.AllUntimed() {
+		res = append(res, entry.Message)
+	}
+
+	return res
+}
+
+func TestLogWriter_HappyCase(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	writer := NewLogWriter(zap.New(observed), zapcore.WarnLevel)
+
+	n, err := io.WriteString(writer, "first\nsec")
+	assert.Nil(t, err)
+	assert.Equal(t, 9, n)
+	assert.Equal(t, []string{"first"}, observedMessages(logs))
+
+	io.WriteString(writer, "ond\r\n\nthi")
+	io.WriteString(writer, "rd")
+	assert.Equal(t, []string{"first", "second", ""}, observedMessages(logs))
+
+	// partial line is logged on close
+	assert.Nil(t, writer.Close())
+	assert.Equal(t, []string{"first", "second", "", "third"}, observedMessages(logs))
+	assert.Equal(t, zapcore.WarnLevel, logs.All()[0].Level)
+
+	// nothing to flush
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, 4, logs.Len())
+}
+
+func TestLogWriter_WithDisabledLevel(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	writer := NewLogWriter(zap.New(observed), zapcore.DebugLevel)
+
+	io.WriteString(writer, "ignored\n")
+	assert.Equal(t, 0, logs.Len())
+}
+
+func TestNewStdLogger_HappyCase(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	logger, err := NewStdLogger(zap.New(observed, zap.AddCaller()), zapcore.ErrorLevel)
+	assert.Nil(t, err)
+
+	logger.Printf("http: TLS handshake error from %s", "127.0.0.1")
+	assert.Equal(t, 1, logs.Len())
+
+	entry := logs.All()[0]
+	assert.Equal(t, zapcore.ErrorLevel, entry.Level)
+	assert.Equal(t, "http: TLS handshake error from 127.0.0.1", entry.Message)
+	assert.Equal(t, "stdlog_test.go", filepath.Base(entry.Caller.File))
+}
+
+func TestNewStdLogger_WithInvalidLevel(t *testing.T) {
+	_, err := NewStdLogger(zap.NewNop(), zapcore.FatalLevel+1)
+	assert.NotNil(t, err)
+
+	_, err = NewStdLogger(zap.NewNop(), zapcore.DebugLevel-1)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"encoding"
+	"fmt"
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+)
+
+var (
+	// Types whose keys are allowed at the root of legacy config file in strict mode.
+	// zap config and lumberjack config are combined in the same config file.
+	strictSchemaTypes = []reflect.Type{
+		reflect.TypeOf(zap.Config{}),
+		reflect.TypeOf(lumberjack.Logger{}),
+	}
+
+	// Type whose keys are allowed at the root of combined config file in strict mode.
+	configSchemaType = reflect.TypeOf(Config{})
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// UnknownKey is an unrecognized key found in config file while parsing in strict mode.
+type UnknownKey struct {
+	// Path is the dot separated path of the key, like encoderConfig.errorKey
+	Path string
+	// Line is the line number of the key, which is only available for JSON and YAML
+	Line int
+}
+
+// UnknownKeysError is returned while parsing config file in strict mode
+// if there are keys which could not be recognized.
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+// Error implements error interface.
+func (e *UnknownKeysError) Error() string {
+	keys := make([]string, 0, len(e.Keys))
+	for i := range e.Keys {
+		if e.Keys[i].Line > 0 {
+			keys = append(keys, fmt.Sprintf("%s(line:%d)", e.Keys[i].Path, e.Keys[i].Line))
+		} else {
+			keys = append(keys, e.Keys[i].Path)
+		}
+	}
+
+	return fmt.Sprintf("unknown keys in config file, keys:%s", strings.Join(keys, ", "))
+}
+
+// Validate keys of config file against strictSchemaTypes or Config depends on shape of config file
+func validateKnownKeys(raw []byte, fileType FileType) error {
+	root, withLine, err := loadYAMLNode(raw, fileType)
+	if err != nil || root == nil {
+		return err
+	}
+
+	keys := make([]UnknownKey, 0)
+	collectConfigUnknownKeys(root, "", withLine, &keys)
+
+	if len(keys) > 0 {
+		return &UnknownKeysError{Keys: keys}
+	}
+
+	return nil
+}
+
+// Validate keys of config file which contains a map of logger names to configs
+func validateKnownKeysOfMap(raw []byte, fileType FileType) error {
+	root, withLine, err := loadYAMLNode(raw, fileType)
+	if err != nil || root == nil || root.Kind != yaml.MappingNode {
+		return err
+	}
+
+	keys := make([]UnknownKey, 0)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		collectConfigUnknownKeys(root.Content[i+1], root.Content[i].Value, withLine, &keys)
+	}
+
+	if len(keys) > 0 {
+		return &UnknownKeysError{Keys: keys}
+	}
+
+	return nil
+}
+
+// Load content of config file as yaml node since json is a subset of yaml,
+// toml and hcl are converted into yaml node without line numbers.
+// nil would be returned for unknown file type which would be reported by caller.
+func loadYAMLNode(raw []byte, fileType FileType) (*yaml.Node, bool, error) {
+	root := &yaml.Node{}
+	withLine := true
+
+	if fileType == JSON || fileType == YAML {
+		if err := yaml.Unmarshal(raw, root); err != nil {
+			return nil, false, err
+		}
+	} else if fileType == TOML || fileType == HCL {
+		content, err := decodeWithFileType(raw, fileType)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if err := root.Encode(content); err != nil {
+			return nil, false, err
+		}
+		withLine = false
+	} else {
+		return nil, false, nil
+	}
+
+	// document node wraps the actual content
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	return root, withLine, nil
+}
+
+// Collect unknown keys of a config node, combined config is detected with zap section or preset
+func collectConfigUnknownKeys(node *yaml.Node, prefix string, withLine bool, res *[]UnknownKey) {
+	schema := strictSchemaTypes
+	if hasMappingKey(node, "zap") || hasMappingKey(node, "preset") {
+		schema = []reflect.Type{configSchemaType}
+	}
+
+	collectUnknownKeys(node, knownFields(schema...), prefix, withLine, res)
+}
+
+// Check whether mapping node contains key
+func hasMappingKey(node *yaml.Node, key string) bool {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Walk through mapping nodes and collect keys that are not in fields
+func collectUnknownKeys(node *yaml.Node, fields map[string]reflect.Type, prefix string, withLine bool, res *[]UnknownKey) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := key.Value
+		if len(prefix) > 0 {
+			path = prefix + "." + key.Value
+		}
+
+		fieldType, ok := fields[key.Value]
+		if !ok {
+			unknown := UnknownKey{Path: path}
+			if withLine {
+				unknown.Line = key.Line
+			}
+			*res = append(*res, unknown)
+			continue
+		}
+
+		// validate each element of list of structs, like outputs
+		if value.Kind == yaml.SequenceNode && fieldType.Kind() == reflect.Slice {
+			if nested := knownFields(fieldType.Elem()); nested != nil {
+				for j := range value.Content {
+					collectUnknownKeys(value.Content[j], nested, fmt.Sprintf("%s[%d]", path, j), withLine, res)
+				}
+			}
+			continue
+		}
+
+		if nested := knownFields(fieldType); nested != nil {
+			collectUnknownKeys(value, nested, path, withLine, res)
+		}
+	}
+}
+
+// Build key to type mapping from yaml tags of struct types
+// nil would be returned for types whose keys should not be validated, like maps and text unmarshalers
+func knownFields(types ...reflect.Type) map[string]reflect.Type {
+	var res map[string]reflect.Type
+
+	for _, typ := range types {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		if typ.Kind() != reflect.Struct || reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+			continue
+		}
+
+		if res == nil {
+			res = make(map[string]reflect.Type)
+		}
+
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if len(field.PkgPath) > 0 && !field.Anonymous {
+				// unexported field
+				continue
+			}
+
+			tag := strings.Split(field.Tag.Get("yaml"), ",")
+			name := tag[0]
+			if name == "-" {
+				continue
+			}
+
+			if len(tag) > 1 && tag[1] == "inline" {
+				for k, v := range knownFields(field.Type) {
+					res[k] = v
+				}
+				continue
+			}
+
+			if len(name) == 0 {
+				name = strings.ToLower(field.Name)
+			}
+
+			res[name] = field.Type
+		}
+	}
+
+	return res
+}
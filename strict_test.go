@@ -0,0 +1,176 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestUnknownKeysError_Error(t *testing.T) {
+	err := &UnknownKeysError{
+		Keys: []UnknownKey{
+			{Path: "outputPath", Line: 3},
+			{Path: "encoderConfig.errorKey"},
+		},
+	}
+
+	assert.Equal(t, "unknown keys in config file, keys:outputPath(line:3), encoderConfig.errorKey", err.Error())
+}
+
+func TestValidateKnownKeys_WithYaml(t *testing.T) {
+	raw := []byte(`---
+level: debug
+outputPath:
+  - stdout
+encoderConfig:
+  messageKey: msg
+  errorKey: error
+sampling:
+  initial: 3
+  thereaftr: 10
+initialFields:
+  anyKey: anyValue
+maxsize: 1
+`)
+
+	err := validateKnownKeys(raw, YAML)
+	assert.IsType(t, &UnknownKeysError{}, err)
+	assert.Equal(t, []UnknownKey{
+		{Path: "outputPath", Line: 3},
+		{Path: "encoderConfig.errorKey", Line: 7},
+		{Path: "sampling.thereaftr", Line: 10},
+	}, err.(*UnknownKeysError).Keys)
+}
+
+func TestValidateKnownKeys_WithJson(t *testing.T) {
+	raw := []byte("{\n\t\"level\": \"debug\",\n\t\"outputPath\": [\"stdout\"],\n\t\"maxsize\": 1\n}")
+
+	err := validateKnownKeys(raw, JSON)
+	assert.IsType(t, &UnknownKeysError{}, err)
+	assert.Equal(t, []UnknownKey{{Path: "outputPath", Line: 3}}, err.(*UnknownKeysError).Keys)
+}
+
+func TestValidateKnownKeys_WithToml(t *testing.T) {
+	raw := []byte("level = \"debug\"\nmaxSize = 1\n[encoderConfig]\nmessageKey = \"msg\"")
+
+	err := validateKnownKeys(raw, TOML)
+	assert.IsType(t, &UnknownKeysError{}, err)
+	assert.Equal(t, []UnknownKey{{Path: "maxSize"}}, err.(*UnknownKeysError).Keys)
+}
+
+func TestValidateKnownKeys_WithHcl(t *testing.T) {
+	raw := []byte("level = \"debug\"\nencoderConfig {\n  msgKey = \"msg\"\n}")
+
+	err := validateKnownKeys(raw, HCL)
+	assert.IsType(t, &UnknownKeysError{}, err)
+	assert.Equal(t, []UnknownKey{{Path: "encoderConfig.msgKey"}}, err.(*UnknownKeysError).Keys)
+}
+
+func TestValidateKnownKeys_WithInvalidContent(t *testing.T) {
+	assert.NotNil(t, validateKnownKeys([]byte(`key: [`), YAML))
+	assert.NotNil(t, validateKnownKeys([]byte(`key = `), TOML))
+	assert.NotNil(t, validateKnownKeys([]byte(`key = [`), HCL))
+}
+
+func TestValidateKnownKeys_WithUnknownFileType(t *testing.T) {
+	assert.Nil(t, validateKnownKeys([]byte(`{"key":"value"}`), 10))
+}
+
+func TestValidateKnownKeys_HappyCase(t *testing.T) {
+	raw := []byte(`{
+      "level": "debug",
+      "development": true,
+      "disableCaller": false,
+      "disableStacktrace": false,
+      "sampling": {"initial": 3, "thereafter": 10},
+      "encoding": "console",
+      "outputPaths": ["stdout"],
+      "errorOutputPaths": ["stderr"],
+      "initialFields": {"initFieldKey": "fieldValue"},
+      "encoderConfig": {
+        "messageKey": "message",
+        "levelKey": "level",
+        "timeKey": "time",
+        "nameKey": "logger",
+        "callerKey": "caller",
+        "functionKey": "func",
+        "stacktraceKey": "stacktrace",
+        "lineEnding": "\n",
+        "levelEncoder": "capital",
+        "timeEncoder": "iso8601",
+        "durationEncoder": "string",
+        "callerEncoder": "full",
+        "nameEncoder": "full",
+        "consoleSeparator": " "
+      },
+      "filename": "logs/rk-logger.log",
+      "maxsize": 1,
+      "maxage": 7,
+      "maxbackups": 3,
+      "localtime": true,
+      "compress": true
+    }`)
+
+	assert.Nil(t, validateKnownKeys(raw, JSON))
+}
+
+func TestLoader_NewZapLoggerWithBytes_WithStrictParsing(t *testing.T) {
+	raw := []byte("level: debug\noutputPath:\n  - stdout")
+
+	// default loader ignores unknown keys
+	logger, _, err := NewZapLoggerWithBytes(raw, YAML)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+
+	logger, config, err := NewLoader(WithStrictParsing()).NewZapLoggerWithBytes(raw, YAML)
+	assert.Nil(t, logger)
+	assert.Nil(t, config)
+	assert.IsType(t, &UnknownKeysError{}, err)
+	assert.Contains(t, err.Error(), "outputPath(line:2)")
+}
+
+func TestLoader_NewZapLoggerWithConfPath_WithStrictParsing(t *testing.T) {
+	// get current working directory
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+
+	// assets/zap.yaml contains keys which are not recognized by zap
+	logger, _, err := NewLoader(WithStrictParsing()).NewZapLoggerWithConfPath(dir+"/assets/zap.yaml", YAML)
+	assert.Nil(t, logger)
+	assert.IsType(t, &UnknownKeysError{}, err)
+}
+
+func TestLoader_NewLumberjackLoggerWithBytes_WithStrictParsing(t *testing.T) {
+	loader := NewLoader(WithStrictParsing())
+
+	logger, err := loader.NewLumberjackLoggerWithBytes([]byte(`{"maxsize": 1, "maxSize": 1}`), JSON)
+	assert.Nil(t, logger)
+	assert.IsType(t, &UnknownKeysError{}, err)
+
+	logger, err = loader.NewLumberjackLoggerWithBytes([]byte(`{"maxsize": 1, "level": "info"}`), JSON)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+}
+
+func TestValidateKnownKeys_WithOutputs(t *testing.T) {
+	raw := []byte(`
+zap:
+  level: info
+outputs:
+  - path: logs/app.log
+  - pth: logs/audit.log
+    lumberjack:
+      maxSize: 10
+`)
+
+	err := validateKnownKeys(raw, YAML)
+	assert.IsType(t, &UnknownKeysError{}, err)
+	assert.Equal(t, []UnknownKey{
+		{Path: "outputs[1].pth", Line: 6},
+		{Path: "outputs[1].lumberjack.maxSize", Line: 8},
+	}, err.(*UnknownKeysError).Keys)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"go.uber.org/zap"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// NewSugaredLoggerWithBytes inits zap sugared logger with byte array from content of config file
+// See NewZapLoggerWithBytes for details
+func NewSugaredLoggerWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (*zap.SugaredLogger, error) {
+	return defaultLoader.NewSugaredLoggerWithBytes(raw, fileType, opts...)
+}
+
+// NewSugaredLoggerWithBytes inits zap sugared logger with byte array from content of config file and options of Loader
+func (loader *Loader) NewSugaredLoggerWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (*zap.SugaredLogger, error) {
+	logger, _, err := loader.NewZapLoggerWithBytes(raw, fileType, opts...)
+	return sugar(logger, err)
+}
+
+// NewSugaredLoggerWithConfPath inits zap sugared logger with config file path
+// See NewZapLoggerWithConfPath for details
+func NewSugaredLoggerWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (*zap.SugaredLogger, error) {
+	return defaultLoader.NewSugaredLoggerWithConfPath(filePath, fileType, opts...)
+}
+
+// NewSugaredLoggerWithConfPath inits zap sugared logger with config file path and options of Loader
+func (loader *Loader) NewSugaredLoggerWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (*zap.SugaredLogger, error) {
+	logger, _, err := loader.NewZapLoggerWithConfPath(filePath, fileType, opts...)
+	return sugar(logger, err)
+}
+
+// NewSugaredLoggerWithConf inits zap sugared logger with config
+// See NewZapLoggerWithConf for details
+func NewSugaredLoggerWithConf(config *zap.Config, lumber *lumberjack.Logger, opts ...zap.Option) (*zap.SugaredLogger, error) {
+	return defaultLoader.NewSugaredLoggerWithConf(config, lumber, opts...)
+}
+
+// NewSugaredLoggerWithConf inits zap sugared logger with config and options of Loader
+func (loader *Loader) NewSugaredLoggerWithConf(config *zap.Config, lumber *lumberjack.Logger, opts ...zap.Option) (*zap.SugaredLogger, error) {
+	return sugar(loader.NewZapLoggerWithConf(config, lumber, opts...))
+}
+
+// NewSugaredLoggerWithConfig inits zap sugared logger with combined config
+func NewSugaredLoggerWithConfig(config *Config, opts ...zap.Option) (*zap.SugaredLogger, error) {
+	return defaultLoader.NewSugaredLoggerWithConfig(config, opts...)
+}
+
+// NewSugaredLoggerWithConfig inits zap sugared logger with combined config and options of Loader
+func (loader *Loader) NewSugaredLoggerWithConfig(config *Config, opts ...zap.Option) (*zap.SugaredLogger, error) {
+	return sugar(loader.NewZapLoggerWithConfig(config, opts...))
+}
+
+// NewSugared builds zap sugared logger with options, see New for details
+func NewSugared(opts ...Option) (*zap.SugaredLogger, error) {
+	return sugar(New(opts...))
+}
+
+// NewSugaredLoggerMapWithBytes inits a map of logger names to zap sugared loggers with byte array from content of config file
+// See NewConfigMapWithBytes for details of config file
+func NewSugaredLoggerMapWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (map[string]*zap.SugaredLogger, error) {
+	return defaultLoader.NewSugaredLoggerMapWithBytes(raw, fileType, opts...)
+}
+
+// NewSugaredLoggerMapWithBytes inits a map of logger names to zap sugared loggers with options of Loader
+func (loader *Loader) NewSugaredLoggerMapWithBytes(raw []byte, fileType FileType, opts ...zap.Option) (map[string]*zap.SugaredLogger, error) {
+	return sugarMap(loader.NewZapLoggerMapWithBytes(raw, fileType, opts...))
+}
+
+// NewSugaredLoggerMapWithConfPath inits a map of logger names to zap sugared loggers with config file path
+// See NewConfigMapWithBytes for details of config file
+func NewSugaredLoggerMapWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (map[string]*zap.SugaredLogger, error) {
+	return defaultLoader.NewSugaredLoggerMapWithConfPath(filePath, fileType, opts...)
+}
+
+// NewSugaredLoggerMapWithConfPath inits a map of logger names to zap sugared loggers with config file path and options of Loader
+func (loader *Loader) NewSugaredLoggerMapWithConfPath(filePath string, fileType FileType, opts ...zap.Option) (map[string]*zap.SugaredLogger, error) {
+	return sugarMap(loader.NewZapLoggerMapWithConfPath(filePath, fileType, opts...))
+}
+
+// Sugar logger, nil is returned along with error
+func sugar(logger *zap.Logger, err error) (*zap.SugaredLogger, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return logger.Sugar(), nil
+}
+
+// Sugar loggers in map, nil is returned along with error
+func sugarMap(loggers map[string]*zap.Logger, err error) (map[string]*zap.SugaredLogger, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*zap.SugaredLogger, len(loggers))
+	for name, logger := range loggers {
+		res[name] = logger.Sugar()
+	}
+
+	return res, nil
+}
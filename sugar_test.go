@@ -0,0 +1,95 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestNewSugaredLoggerWithBytes_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	raw := []byte(fmt.Sprintf(`{"level": "info", "encoding": "json", "encoderConfig": {"messageKey": "msg"}, "outputPaths": ["%s"]}`, filePath))
+
+	logger, err := NewSugaredLoggerWithBytes(raw, JSON)
+	assert.Nil(t, err)
+
+	logger.Infow("ut-message", "key", "value")
+	assert.Contains(t, readFileContent(filePath), `{"msg":"ut-message","key":"value"}`)
+}
+
+// With invalid config
+func TestNewSugaredLoggerWithBytes_WithInvalidConfig(t *testing.T) {
+	logger, err := NewSugaredLoggerWithBytes(nil, JSON)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestNewSugaredLoggerWithConfPath_HappyCase(t *testing.T) {
+	dir, _ := os.Getwd()
+	logger, err := NewSugaredLoggerWithConfPath(dir+"/assets/zap.yaml", YAML)
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+}
+
+// With non exist file
+func TestNewSugaredLoggerWithConfPath_WithNonExistFile(t *testing.T) {
+	logger, err := NewSugaredLoggerWithConfPath(path.Join(newTempDir(t), "zap.yaml"), YAML)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestNewSugaredLoggerWithConf_HappyCase(t *testing.T) {
+	logger, err := NewSugaredLoggerWithConf(NewZapStdoutConfig(), NewLumberjackConfigDefault())
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+
+	// with nil config
+	logger, err = NewSugaredLoggerWithConf(nil, nil)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestNewSugaredLoggerWithConfig_HappyCase(t *testing.T) {
+	logger, err := NewSugaredLoggerWithConfig(NewConfig(NewZapStdoutConfig(), nil))
+	assert.NotNil(t, logger)
+	assert.Nil(t, err)
+
+	// with nil config
+	logger, err = NewSugaredLoggerWithConfig(nil)
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+}
+
+func TestNewSugared_HappyCase(t *testing.T) {
+	filePath := path.Join(newTempDir(t), "ut.log")
+	logger, err := NewSugared(WithOutput(filePath))
+	assert.Nil(t, err)
+
+	logger.Infof("ut-%s", "message")
+	assert.Contains(t, readFileContent(filePath), "ut-message")
+}
+
+func TestNewSugaredLoggerMapWithBytes_HappyCase(t *testing.T) {
+	loggers, err := NewSugaredLoggerMapWithBytes(newFactoryConfig(path.Join(newTempDir(t), "ut.log")), YAML)
+	assert.Nil(t, err)
+	assert.Len(t, loggers, 2)
+	assert.NotNil(t, loggers["db"])
+
+	// with invalid config
+	loggers, err = NewSugaredLoggerMapWithBytes([]byte(`default: [`), YAML)
+	assert.Nil(t, loggers)
+	assert.NotNil(t, err)
+}
+
+func TestNewSugaredLoggerMapWithConfPath_HappyCase(t *testing.T) {
+	dir, _ := os.Getwd()
+	loggers, err := NewSugaredLoggerMapWithConfPath(dir+"/assets/loggers.yaml", YAML)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, loggers)
+}
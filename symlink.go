@@ -0,0 +1,75 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Resolve path of symlink, relative path is resolved in directory of file
+func symlinkPath(symlink, filename string) string {
+	if filepath.IsAbs(symlink) {
+		return filepath.Clean(symlink)
+	}
+
+	return filepath.Join(filepath.Dir(filename), symlink)
+}
+
+// Point symlink at target, symlink is replaced atomically so that readers never miss it, and target in the same
+// directory is linked with relative path. Existing file which is not a symlink is never replaced.
+func updateSymlink(link, target string) error {
+	if filepath.Clean(link) == filepath.Clean(target) {
+		return errors.Errorf("symlink is the same as file, symlink:%s", link)
+	}
+
+	if info, err := os.Lstat(link); err == nil && info.Mode()&os.ModeSymlink == 0 {
+		return errors.Errorf("file exists and is not a symlink, symlink:%s", link)
+	}
+
+	dest := target
+	if filepath.Dir(link) == filepath.Dir(target) {
+		dest = filepath.Base(target)
+	} else if abs, err := filepath.Abs(target); err == nil {
+		dest = abs
+	}
+
+	if current, err := os.Readlink(link); err == nil && current == dest {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return err
+	}
+
+	// hidden temporary symlink which is renamed over the existing one
+	tmp := filepath.Join(filepath.Dir(link), "."+filepath.Base(link)+".tmp")
+	os.Remove(tmp)
+	if err := os.Symlink(dest, tmp); err != nil {
+		return errors.Wrapf(err, "failed to create symlink, symlink:%s", link)
+	}
+
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return errors.Wrapf(err, "failed to replace symlink, symlink:%s", link)
+	}
+
+	return nil
+}
+
+// Point symlink of rotation at file, errors are reported to stderr since writing to file should not fail with them
+func updateRotationSymlink(symlink, filename string) {
+	if len(symlink) == 0 {
+		return
+	}
+
+	if err := updateSymlink(symlinkPath(symlink, filename), filename); err != nil {
+		fmt.Fprintf(os.Stderr, "%v failed to update symlink, file:%s, error:%v\n", time.Now(), filename, err)
+	}
+}
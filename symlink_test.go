@@ -0,0 +1,73 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateSymlink(t *testing.T) {
+	dir := newTempDir(t)
+	link := path.Join(dir, "app.log")
+
+	// linked with relative path in the same directory
+	assert.Nil(t, updateSymlink(link, path.Join(dir, "app-20200901.log")))
+	dest, err := os.Readlink(link)
+	assert.Nil(t, err)
+	assert.Equal(t, "app-20200901.log", dest)
+
+	// replaced
+	assert.Nil(t, updateSymlink(link, path.Join(dir, "app-20200902.log")))
+	dest, _ = os.Readlink(link)
+	assert.Equal(t, "app-20200902.log", dest)
+	assert.Equal(t, []string{"app.log"}, listFileNames(t, dir))
+
+	// linked with absolute path in other directory
+	other := path.Join(dir, "current", "app.log")
+	assert.Nil(t, updateSymlink(other, path.Join(dir, "app-20200902.log")))
+	dest, _ = os.Readlink(other)
+	assert.True(t, filepath.IsAbs(dest))
+
+	// files which are not symlinks are never replaced
+	regular := path.Join(dir, "regular.log")
+	assert.Nil(t, ioutil.WriteFile(regular, []byte("ut"), 0644))
+	assert.NotNil(t, updateSymlink(regular, path.Join(dir, "app-20200902.log")))
+	assert.Equal(t, "ut", readFileContent(regular))
+
+	// symlink to itself
+	assert.NotNil(t, updateSymlink(link, link))
+}
+
+func TestSymlinkPath(t *testing.T) {
+	assert.Equal(t, "/var/log/app/app.log", symlinkPath("app.log", "/var/log/app/app-20200901.log"))
+	assert.Equal(t, "/var/log/current.log", symlinkPath("/var/log/current.log", "/var/log/app/app-20200901.log"))
+}
+
+func TestTimedWriter_WithSymlink(t *testing.T) {
+	dir := newTempDir(t)
+	link := path.Join(dir, "app.log")
+
+	now := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	writer, setNow := newTestTimedWriter(t, path.Join(dir, "app-%Y%m%d.log"),
+		RotationConfig{Schedule: ScheduleDaily, Symlink: "app.log"}, now)
+
+	writer.Write([]byte("ut-first\n"))
+	assert.Equal(t, "ut-first\n", readFileContent(link))
+
+	// follows file of the next period
+	setNow(now.Add(24 * time.Hour))
+	writer.Write([]byte("ut-second\n"))
+	assert.Equal(t, "ut-second\n", readFileContent(link))
+
+	dest, err := os.Readlink(link)
+	assert.Nil(t, err)
+	assert.Equal(t, "app-20200902.log", dest)
+}
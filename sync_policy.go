@@ -0,0 +1,188 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Modes which could be used by SyncPolicyConfig
+const (
+	// SyncNever never syncs files, even if logger is synced, files are flushed by operating system.
+	SyncNever = "never"
+	// SyncEveryWrite syncs files after every entry.
+	SyncEveryWrite = "write"
+	// SyncEveryEntries syncs files after every number of entries.
+	SyncEveryEntries = "entries"
+	// SyncEveryInterval syncs files in background on interval if entries are written since the last sync.
+	SyncEveryInterval = "interval"
+)
+
+// SyncPolicyConfig controls how often file outputs are synced to disk with fsync, so that durability of logs could be
+// traded against throughput explicitly. Files are synced only if logger is synced without it, and files are always
+// synced by policies other than never while logger is synced.
+//
+// Example config file in YAML:
+//
+//	syncPolicy:
+//	  mode: entries
+//	  entries: 100
+type SyncPolicyConfig struct {
+	// Mode is never, write, entries or interval.
+	Mode string `json:"mode" yaml:"mode"`
+	// Entries is number of entries between syncs of entries mode.
+	Entries int `json:"entries,omitempty" yaml:"entries,omitempty"`
+	// Interval is interval between syncs of interval mode, like 1s.
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+// syncPolicy is parsed SyncPolicyConfig
+type syncPolicy struct {
+	mode     string
+	entries  int
+	interval time.Duration
+}
+
+// Parse policy of config, nil is returned if config is nil
+func newSyncPolicy(config *SyncPolicyConfig) (*syncPolicy, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	policy := &syncPolicy{mode: strings.ToLower(config.Mode)}
+	switch policy.mode {
+	case SyncNever, SyncEveryWrite:
+	case SyncEveryEntries:
+		if config.Entries <= 0 {
+			return nil, errors.Errorf("entries of sync policy should be positive, entries:%d", config.Entries)
+		}
+		policy.entries = config.Entries
+	case SyncEveryInterval:
+		interval, err := time.ParseDuration(config.Interval)
+		if err != nil || interval <= 0 {
+			return nil, errors.Errorf("invalid interval of sync policy, interval:%s", config.Interval)
+		}
+		policy.interval = interval
+	default:
+		return nil, errors.Errorf("invalid mode of sync policy, mode:%s", config.Mode)
+	}
+
+	return policy, nil
+}
+
+// Wrap file write syncer with policy, the returned function stops syncing in background and closes the write syncer
+func (policy *syncPolicy) wrap(syncer zapcore.WriteSyncer, closer func()) (zapcore.WriteSyncer, func()) {
+	if policy == nil {
+		return syncer, closer
+	}
+
+	writer := &syncPolicyWriter{
+		WriteSyncer: syncer,
+		policy:      policy,
+		done:        make(chan struct{}),
+	}
+
+	if policy.mode == SyncEveryInterval {
+		writer.wait.Add(1)
+		go writer.syncOnInterval()
+	}
+
+	return writer, func() {
+		close(writer.done)
+		writer.wait.Wait()
+		closer()
+	}
+}
+
+// syncPolicyWriter syncs write syncer of file with policy
+type syncPolicyWriter struct {
+	zapcore.WriteSyncer
+	policy *syncPolicy
+	// entries written since the last sync
+	pending int
+	mutex   sync.Mutex
+	done    chan struct{}
+	wait    sync.WaitGroup
+}
+
+// Write implements zapcore.WriteSyncer, file is synced after writing if policy requires
+func (writer *syncPolicyWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	n, err := writer.WriteSyncer.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	writer.pending++
+	switch writer.policy.mode {
+	case SyncEveryWrite:
+		return n, writer.sync()
+	case SyncEveryEntries:
+		if writer.pending >= writer.policy.entries {
+			return n, writer.sync()
+		}
+	}
+
+	return n, nil
+}
+
+// Sync implements zapcore.WriteSyncer, nothing is synced with never mode
+func (writer *syncPolicyWriter) Sync() error {
+	if writer.policy.mode == SyncNever {
+		return nil
+	}
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	return writer.sync()
+}
+
+// Rotate rotates file immediately if write syncer is a rotator
+func (writer *syncPolicyWriter) Rotate() error {
+	if rotator, ok := writer.WriteSyncer.(*lockedRotator); ok {
+		return rotator.Rotate()
+	}
+
+	return nil
+}
+
+// Sync write syncer and reset pending entries
+func (writer *syncPolicyWriter) sync() error {
+	writer.pending = 0
+	return writer.WriteSyncer.Sync()
+}
+
+// Sync pending entries on interval until done is closed, errors are reported to stderr
+func (writer *syncPolicyWriter) syncOnInterval() {
+	defer writer.wait.Done()
+
+	ticker := time.NewTicker(writer.policy.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-writer.done:
+			return
+		case <-ticker.C:
+			writer.mutex.Lock()
+			if writer.pending > 0 {
+				if err := writer.sync(); err != nil {
+					fmt.Fprintf(os.Stderr, "%v failed to sync file, error:%v\n", time.Now(), err)
+				}
+			}
+			writer.mutex.Unlock()
+		}
+	}
+}
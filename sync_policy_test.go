@@ -0,0 +1,161 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSyncer counts syncs of write syncer
+type countingSyncer struct {
+	writes int32
+	syncs  int32
+}
+
+func (syncer *countingSyncer) Write(p []byte) (int, error) {
+	atomic.AddInt32(&syncer.writes, 1)
+	return len(p), nil
+}
+
+func (syncer *countingSyncer) Sync() error {
+	atomic.AddInt32(&syncer.syncs, 1)
+	return nil
+}
+
+// Wrap counting syncer with policy of config
+func newTestSyncPolicyWriter(t *testing.T, config *SyncPolicyConfig) (*countingSyncer, *syncPolicyWriter) {
+	policy, err := newSyncPolicy(config)
+	assert.Nil(t, err)
+
+	syncer := &countingSyncer{}
+	writer, closer := policy.wrap(syncer, func() {})
+	t.Cleanup(closer)
+
+	return syncer, writer.(*syncPolicyWriter)
+}
+
+func TestNewSyncPolicy(t *testing.T) {
+	policy, err := newSyncPolicy(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, policy)
+
+	policy, err = newSyncPolicy(&SyncPolicyConfig{Mode: "Interval", Interval: "1s"})
+	assert.Nil(t, err)
+	assert.Equal(t, &syncPolicy{mode: SyncEveryInterval, interval: time.Second}, policy)
+
+	for _, config := range []*SyncPolicyConfig{
+		{Mode: "ut-mode"},
+		{Mode: SyncEveryEntries},
+		{Mode: SyncEveryInterval, Interval: "-1s"},
+	} {
+		_, err := newSyncPolicy(config)
+		assert.NotNil(t, err)
+	}
+
+	// syncer is not wrapped without policy
+	syncer := &countingSyncer{}
+	res, closer := policy.wrap(syncer, func() {})
+	assert.NotEqual(t, syncer, res)
+	closer()
+	res, _ = (*syncPolicy)(nil).wrap(syncer, func() {})
+	assert.Equal(t, syncer, res)
+}
+
+func TestSyncPolicyWriter_WithWrite(t *testing.T) {
+	syncer, writer := newTestSyncPolicyWriter(t, &SyncPolicyConfig{Mode: SyncEveryWrite})
+
+	writer.Write([]byte("ut-first\n"))
+	writer.Write([]byte("ut-second\n"))
+	assert.Equal(t, int32(2), syncer.syncs)
+}
+
+func TestSyncPolicyWriter_WithEntries(t *testing.T) {
+	syncer, writer := newTestSyncPolicyWriter(t, &SyncPolicyConfig{Mode: SyncEveryEntries, Entries: 3})
+
+	for i := 0; i < 7; i++ {
+		writer.Write([]byte("ut-message\n"))
+	}
+	assert.Equal(t, int32(2), syncer.syncs)
+
+	// pending entries are reset by sync of logger
+	assert.Nil(t, writer.Sync())
+	writer.Write([]byte("ut-message\n"))
+	writer.Write([]byte("ut-message\n"))
+	assert.Equal(t, int32(3), syncer.syncs)
+}
+
+func TestSyncPolicyWriter_WithNever(t *testing.T) {
+	syncer, writer := newTestSyncPolicyWriter(t, &SyncPolicyConfig{Mode: SyncNever})
+
+	writer.Write([]byte("ut-message\n"))
+	assert.Nil(t, writer.Sync())
+	assert.Equal(t, int32(1), syncer.writes)
+	assert.Equal(t, int32(0), syncer.syncs)
+}
+
+func TestSyncPolicyWriter_WithInterval(t *testing.T) {
+	syncer, writer := newTestSyncPolicyWriter(t, &SyncPolicyConfig{Mode: SyncEveryInterval, Interval: "10ms"})
+
+	// nothing is synced without entries
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&syncer.syncs))
+
+	writer.Write([]byte("ut-message\n"))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&syncer.syncs) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSyncPolicy_WithConfigFile(t *testing.T) {
+	dir := newTempDir(t)
+	raw := []byte(`---
+zap:
+  level: info
+  encoding: json
+  encoderConfig:
+    messageKey: msg
+  outputPaths: ["` + path.Join(dir, "app.log") + `"]
+lumberjack:
+  maxsize: 1
+syncPolicy:
+  mode: write
+`)
+
+	config, err := NewConfigWithBytes(raw, YAML)
+	assert.Nil(t, err)
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	logger.Info("ut-message")
+	assert.Equal(t, `{"msg":"ut-message"}`+"\n", readFileContent(path.Join(dir, "app.log")))
+
+	// rotators are still rotated on demand
+	syncer, closeSyncer, err := defaultLoader.openOutput(&OutputConfig{
+		Path:       path.Join(dir, "db.log"),
+		Lumberjack: config.Lumberjack,
+		SyncPolicy: config.SyncPolicy,
+	})
+	assert.Nil(t, err)
+	defer closeSyncer()
+	syncer.Write([]byte("ut-message\n"))
+	assert.Nil(t, syncer.(*syncPolicyWriter).Rotate())
+	assert.Len(t, listFileNames(t, dir), 3)
+
+	// invalid policy
+	_, _, err = defaultLoader.openOutput(&OutputConfig{Path: path.Join(dir, "db.log"), SyncPolicy: &SyncPolicyConfig{}})
+	assert.NotNil(t, err)
+
+	assert.Nil(t, closer.Shutdown(context.Background()))
+}
+
+func TestWithSyncPolicy(t *testing.T) {
+	config := NewConfigWithOptions(WithSyncPolicy(SyncPolicyConfig{Mode: SyncNever}))
+	assert.Equal(t, &SyncPolicyConfig{Mode: SyncNever}, config.SyncPolicy)
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap/zapcore"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SyslogScheme is the scheme of output path which writes to syslog, like:
+//
+//	outputPaths:
+//	  # local syslog daemon through /dev/log, /var/run/syslog or /var/run/log
+//	  - syslog://
+//	  # local syslog daemon through unix socket
+//	  - syslog:///dev/log
+//	  # remote syslog daemon
+//	  - syslog://logs.example.com:514?network=tcp&facility=local0&tag=app&format=rfc5424
+//
+// Query parameters:
+//
+//	network:  udp, tcp, unix or unixgram, udp for remote address and unixgram for unix socket by default
+//	facility: kern, user, mail, daemon, auth, syslog, lpr, news, uucp, cron, authpriv, ftp or local0 to local7, user by default
+//	severity: emerg, alert, crit, err, warning, notice, info or debug, info by default
+//	tag:      app name in messages, name of executable by default
+//	format:   rfc3164 or rfc5424, rfc3164 by default
+//
+// Every entry is sent with the same severity since write syncer does not know level of entries.
+// Messages over tcp are framed with octet counting for rfc5424 and trailing newline for rfc3164.
+const SyslogScheme = "syslog"
+
+const (
+	// SyslogRFC3164 is the BSD syslog format.
+	SyslogRFC3164 = "rfc3164"
+	// SyslogRFC5424 is the IETF syslog format.
+	SyslogRFC5424 = "rfc5424"
+
+	// timestamp of rfc5424 allows up to microseconds
+	syslogRFC5424TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var (
+	syslogFacilities = map[string]int{
+		"kern":     0,
+		"user":     1,
+		"mail":     2,
+		"daemon":   3,
+		"auth":     4,
+		"syslog":   5,
+		"lpr":      6,
+		"news":     7,
+		"uucp":     8,
+		"cron":     9,
+		"authpriv": 10,
+		"ftp":      11,
+		"local0":   16,
+		"local1":   17,
+		"local2":   18,
+		"local3":   19,
+		"local4":   20,
+		"local5":   21,
+		"local6":   22,
+		"local7":   23,
+	}
+
+	syslogSeverities = map[string]int{
+		"emerg":   0,
+		"alert":   1,
+		"crit":    2,
+		"err":     3,
+		"warning": 4,
+		"notice":  5,
+		"info":    6,
+		"debug":   7,
+	}
+
+	// unix sockets of local syslog daemon on linux, macOS and BSD
+	syslogLocalPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+)
+
+func init() {
+	if err := RegisterWriter(SyslogScheme, newSyslogWriterWithURL); err != nil {
+		panic(err)
+	}
+}
+
+// syslogWriter writes every entry as a syslog message, connection is re-established once on failure
+type syslogWriter struct {
+	network  string
+	address  string
+	priority int
+	tag      string
+	format   string
+	hostname string
+	pid      int
+	conn     net.Conn
+	mutex    sync.Mutex
+}
+
+// Create syslog writer from url and connect to syslog daemon
+func newSyslogWriterWithURL(u url.URL) (zapcore.WriteSyncer, error) {
+	writer, err := parseSyslogURL(&u)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writer.connect(); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Parse settings of syslog writer from url
+func parseSyslogURL(u *url.URL) (*syslogWriter, error) {
+	query := u.Query()
+	for key := range query {
+		switch key {
+		case "network", "facility", "severity", "tag", "format":
+		default:
+			return nil, errors.Errorf("unknown query of syslog url, key:%s", key)
+		}
+	}
+
+	writer := &syslogWriter{
+		network: query.Get("network"),
+		tag:     query.Get("tag"),
+		format:  query.Get("format"),
+		pid:     os.Getpid(),
+	}
+
+	if len(u.Host) > 0 {
+		writer.address = u.Host
+		if len(writer.network) == 0 {
+			writer.network = "udp"
+		}
+	} else if len(u.Path) > 0 {
+		writer.address = fromFileURLPath(u.Path)
+		if len(writer.network) == 0 {
+			writer.network = "unixgram"
+		}
+	}
+
+	switch writer.network {
+	case "", "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	default:
+		return nil, errors.Errorf("network is not supported in syslog url, network:%s", writer.network)
+	}
+
+	facility, severity := query.Get("facility"), query.Get("severity")
+	if len(facility) == 0 {
+		facility = "user"
+	}
+	if len(severity) == 0 {
+		severity = "info"
+	}
+
+	facilityCode, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, errors.Errorf("facility is not supported in syslog url, facility:%s", facility)
+	}
+
+	severityCode, ok := syslogSeverities[severity]
+	if !ok {
+		return nil, errors.Errorf("severity is not supported in syslog url, severity:%s", severity)
+	}
+
+	writer.priority = facilityCode*8 + severityCode
+
+	if len(writer.format) == 0 {
+		writer.format = SyslogRFC3164
+	}
+	if writer.format != SyslogRFC3164 && writer.format != SyslogRFC5424 {
+		return nil, errors.Errorf("format is not supported in syslog url, format:%s", writer.format)
+	}
+
+	if len(writer.tag) == 0 {
+		writer.tag = filepath.Base(os.Args[0])
+	}
+
+	writer.hostname, _ = os.Hostname()
+	if len(writer.hostname) == 0 {
+		writer.hostname = "-"
+	}
+
+	return writer, nil
+}
+
+// Connect to syslog daemon, local unix sockets are tried in order if address is missing
+func (writer *syslogWriter) connect() error {
+	if writer.conn != nil {
+		writer.conn.Close()
+		writer.conn = nil
+	}
+
+	if len(writer.address) > 0 {
+		conn, err := net.Dial(writer.network, writer.address)
+		if err != nil {
+			return errors.Wrapf(err, "failed to connect to syslog, address:%s", writer.address)
+		}
+
+		writer.conn = conn
+		return nil
+	}
+
+	for _, address := range syslogLocalPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if len(writer.network) > 0 && writer.network != network {
+				continue
+			}
+
+			if conn, err := net.Dial(network, address); err == nil {
+				writer.conn = conn
+				return nil
+			}
+		}
+	}
+
+	return errors.New("failed to connect to local syslog")
+}
+
+// Write implements zapcore.WriteSyncer, p is sent as one message without trailing newline
+func (writer *syslogWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	msg := bytes.TrimRight(p, "\r\n")
+	if writer.conn != nil {
+		if _, err := writer.conn.Write(writer.frame(msg, time.Now())); err == nil {
+			return len(p), nil
+		}
+	}
+
+	// reconnect once, syslog daemon might be restarted
+	if err := writer.connect(); err != nil {
+		return 0, err
+	}
+
+	if _, err := writer.conn.Write(writer.frame(msg, time.Now())); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer, messages are not buffered
+func (writer *syslogWriter) Sync() error {
+	return nil
+}
+
+// Close closes connection to syslog daemon
+func (writer *syslogWriter) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.conn == nil {
+		return nil
+	}
+
+	err := writer.conn.Close()
+	writer.conn = nil
+	return err
+}
+
+// Format message with header of syslog format and framing of network
+func (writer *syslogWriter) frame(msg []byte, now time.Time) []byte {
+	var res string
+	if writer.format == SyslogRFC5424 {
+		res = fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+			writer.priority, now.Format(syslogRFC5424TimeFormat), writer.hostname, writer.tag, writer.pid, msg)
+	} else if writer.isLocal() {
+		// local daemon fills hostname by itself
+		res = fmt.Sprintf("<%d>%s %s[%d]: %s",
+			writer.priority, now.Format(time.Stamp), writer.tag, writer.pid, msg)
+	} else {
+		res = fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+			writer.priority, now.Format(time.Stamp), writer.hostname, writer.tag, writer.pid, msg)
+	}
+
+	if !writer.isStream() {
+		return []byte(res)
+	}
+
+	// octet counting framing of RFC6587
+	if writer.format == SyslogRFC5424 {
+		return []byte(fmt.Sprintf("%d %s", len(res), res))
+	}
+
+	return []byte(res + "\n")
+}
+
+// Check whether writer is connected to local syslog daemon with unix socket
+func (writer *syslogWriter) isLocal() bool {
+	return len(writer.address) == 0 || writer.network == "unix" || writer.network == "unixgram"
+}
+
+// Check whether writer is connected with stream network which requires framing
+func (writer *syslogWriter) isStream() bool {
+	if writer.conn == nil || writer.conn.RemoteAddr() == nil {
+		return false
+	}
+
+	switch writer.conn.RemoteAddr().Network() {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	default:
+		return false
+	}
+}
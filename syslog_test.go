@@ -0,0 +1,252 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Start udp server and return its address along with channel of received messages
+func newSyslogUDPServer(t *testing.T) (string, chan string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	messages := make(chan string, 10)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			messages <- string(buf[:n])
+		}
+	}()
+
+	return conn.LocalAddr().String(), messages
+}
+
+// Wait for message from channel
+func receiveSyslogMessage(t *testing.T, messages chan string) string {
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "syslog message is not received")
+		return ""
+	}
+}
+
+func TestParseSyslogURL_HappyCase(t *testing.T) {
+	u, _ := url.Parse("syslog://logs.example.com:514?network=tcp&facility=local0&severity=err&tag=app&format=rfc5424")
+	writer, err := parseSyslogURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", writer.network)
+	assert.Equal(t, "logs.example.com:514", writer.address)
+	assert.Equal(t, 16*8+3, writer.priority)
+	assert.Equal(t, "app", writer.tag)
+	assert.Equal(t, SyslogRFC5424, writer.format)
+}
+
+// Without query
+func TestParseSyslogURL_WithDefaults(t *testing.T) {
+	u, _ := url.Parse("syslog://localhost:514")
+	writer, err := parseSyslogURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "udp", writer.network)
+	assert.Equal(t, 1*8+6, writer.priority)
+	assert.Equal(t, SyslogRFC3164, writer.format)
+	assert.NotEmpty(t, writer.tag)
+
+	// unix socket
+	u, _ = url.Parse("syslog:///dev/log")
+	writer, err = parseSyslogURL(u)
+	assert.Nil(t, err)
+	assert.Equal(t, "unixgram", writer.network)
+	assert.True(t, writer.isLocal())
+
+	// local syslog daemon
+	u, _ = url.Parse("syslog://")
+	writer, err = parseSyslogURL(u)
+	assert.Nil(t, err)
+	assert.Empty(t, writer.address)
+	assert.True(t, writer.isLocal())
+}
+
+// With invalid query
+func TestParseSyslogURL_WithInvalidQuery(t *testing.T) {
+	for _, raw := range []string{
+		"syslog://localhost:514?network=sctp",
+		"syslog://localhost:514?facility=unknown",
+		"syslog://localhost:514?severity=unknown",
+		"syslog://localhost:514?format=unknown",
+		"syslog://localhost:514?unknown=1",
+	} {
+		u, _ := url.Parse(raw)
+		writer, err := parseSyslogURL(u)
+		assert.Nil(t, writer, raw)
+		assert.NotNil(t, err, raw)
+	}
+}
+
+func TestSyslogWriter_WithRFC3164(t *testing.T) {
+	address, messages := newSyslogUDPServer(t)
+	config := NewConfigWithOptions(WithOutput(fmt.Sprintf("syslog://%s?facility=local0&tag=ut", address)))
+	config.Zap.EncoderConfig = NewMinimalConfig().Zap.EncoderConfig
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	defer closer.Shutdown(context.Background())
+
+	logger.Info("ut-message")
+
+	hostname, _ := os.Hostname()
+	msg := receiveSyslogMessage(t, messages)
+	assert.True(t, strings.HasPrefix(msg, "<134>"), msg)
+	assert.True(t, strings.HasSuffix(msg, fmt.Sprintf(" %s ut[%d]: INFO\tut-message", hostname, os.Getpid())), msg)
+}
+
+func TestSyslogWriter_WithRFC5424(t *testing.T) {
+	address, messages := newSyslogUDPServer(t)
+	config := NewConfigWithOptions(WithOutput(fmt.Sprintf("syslog://%s?tag=ut&format=rfc5424&severity=warning", address)))
+	config.Zap.EncoderConfig = NewMinimalConfig().Zap.EncoderConfig
+
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+	defer closer.Shutdown(context.Background())
+
+	logger.Info("ut-message")
+
+	hostname, _ := os.Hostname()
+	fields := strings.SplitN(receiveSyslogMessage(t, messages), " ", 8)
+	assert.Len(t, fields, 8)
+	assert.Equal(t, "<12>1", fields[0])
+	_, err = time.Parse(syslogRFC5424TimeFormat, fields[1])
+	assert.Nil(t, err)
+	assert.Equal(t, []string{hostname, "ut", fmt.Sprint(os.Getpid()), "-", "-", "INFO\tut-message"}, fields[2:])
+}
+
+// Messages over tcp are framed
+func TestSyslogWriter_WithTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	u, _ := url.Parse(fmt.Sprintf("syslog://%s?network=tcp&tag=ut", listener.Addr().String()))
+	writer, err := newSyslogWriterWithURL(*u)
+	assert.Nil(t, err)
+	defer writer.(*syslogWriter).Close()
+
+	n, err := writer.Write([]byte("ut-message\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, len("ut-message\n"), n)
+	assert.True(t, strings.HasSuffix(receiveSyslogMessage(t, received), "ut-message\n"))
+}
+
+func TestSyslogWriter_FrameWithOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	writer := &syslogWriter{
+		network:  "tcp",
+		address:  listener.Addr().String(),
+		priority: 14,
+		tag:      "ut",
+		format:   SyslogRFC5424,
+		hostname: "host",
+		pid:      1,
+	}
+	assert.Nil(t, writer.connect())
+	defer writer.Close()
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	msg := "<14>1 2020-01-02T03:04:05.000006Z host ut 1 - - ut-message"
+	assert.Equal(t, fmt.Sprintf("%d %s", len(msg), msg), string(writer.frame([]byte("ut-message"), now)))
+}
+
+// Writer reconnects once connection is closed
+func TestSyslogWriter_Reconnect(t *testing.T) {
+	address, messages := newSyslogUDPServer(t)
+	u, _ := url.Parse(fmt.Sprintf("syslog://%s?tag=ut", address))
+	writer, err := newSyslogWriterWithURL(*u)
+	assert.Nil(t, err)
+
+	syslog := writer.(*syslogWriter)
+	syslog.conn.Close()
+
+	_, err = writer.Write([]byte("ut-message"))
+	assert.Nil(t, err)
+	assert.Contains(t, receiveSyslogMessage(t, messages), "ut-message")
+
+	assert.Nil(t, syslog.Close())
+	// close twice
+	assert.Nil(t, syslog.Close())
+	assert.Nil(t, syslog.Sync())
+}
+
+// With unix socket of local syslog daemon
+func TestSyslogWriter_WithUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unixgram is not supported on windows")
+	}
+
+	socketPath := path.Join(newTempDir(t), "log.sock")
+	conn, err := net.ListenPacket("unixgram", socketPath)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	u, _ := url.Parse("syslog://" + socketPath + "?tag=ut")
+	writer, err := newSyslogWriterWithURL(*u)
+	assert.Nil(t, err)
+	defer writer.(*syslogWriter).Close()
+
+	_, err = writer.Write([]byte("ut-message"))
+	assert.Nil(t, err)
+
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(string(buf[:n]), fmt.Sprintf(" ut[%d]: ut-message", os.Getpid())))
+}
+
+// With unreachable address
+func TestNewSyslogWriterWithURL_WithUnreachableAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	u, _ := url.Parse(fmt.Sprintf("syslog://%s?network=tcp", address))
+	writer, err := newSyslogWriterWithURL(*u)
+	assert.Nil(t, writer)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// limiter is a token bucket which allows burst of requests up to limit in a period
+type limiter struct {
+	limit  float64
+	period time.Duration
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// Parse rate limit like 10/1m, period is one second if missing
+func parseRateLimit(value string) (*limiter, error) {
+	parts := strings.SplitN(value, "/", 2)
+
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return nil, errors.Errorf("invalid rate limit, rateLimit:%s", value)
+	}
+
+	period := time.Second
+	if len(parts) > 1 {
+		if period, err = time.ParseDuration(parts[1]); err != nil || period <= 0 {
+			return nil, errors.Errorf("invalid period of rate limit, rateLimit:%s", value)
+		}
+	}
+
+	return &limiter{
+		limit:  float64(limit),
+		period: period,
+		tokens: float64(limit),
+		now:    time.Now,
+	}, nil
+}
+
+// Take a token if it is available, tokens are refilled continuously
+func (limiter *limiter) allow() bool {
+	now := limiter.now()
+	if !limiter.last.IsZero() {
+		limiter.tokens += float64(now.Sub(limiter.last)) / float64(limiter.period) * limiter.limit
+		if limiter.tokens > limiter.limit {
+			limiter.tokens = limiter.limit
+		}
+	}
+	limiter.last = now
+
+	if limiter.tokens < 1 {
+		return false
+	}
+
+	limiter.tokens--
+	return true
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	limiter, err := parseRateLimit("10/1m")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(10), limiter.limit)
+	assert.Equal(t, time.Minute, limiter.period)
+
+	// With default period
+	limiter, err = parseRateLimit("3")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Second, limiter.period)
+
+	// With invalid rate limit
+	for _, value := range []string{"", "x", "0", "-1/1s", "1/x", "1/0s"} {
+		_, err = parseRateLimit(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	limiter, _ := parseRateLimit("2/1m")
+	limiter.now = func() time.Time { return now }
+
+	// burst up to limit
+	assert.True(t, limiter.allow())
+	assert.True(t, limiter.allow())
+	assert.False(t, limiter.allow())
+
+	// tokens are refilled with time
+	now = now.Add(30 * time.Second)
+	assert.True(t, limiter.allow())
+	assert.False(t, limiter.allow())
+
+	// tokens are capped by limit
+	now = now.Add(time.Hour)
+	assert.True(t, limiter.allow())
+	assert.True(t, limiter.allow())
+	assert.False(t, limiter.allow())
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"encoding/json"
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Entry is an entry posted to webhook, it is encoded as json object by default.
+type Entry struct {
+	Level      string                 `json:"level"`
+	Time       time.Time              `json:"time"`
+	Logger     string                 `json:"logger,omitempty"`
+	Caller     string                 `json:"caller,omitempty"`
+	Message    string                 `json:"message"`
+	Stacktrace string                 `json:"stacktrace,omitempty"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Payload is the data of payload template, which holds entries of a batch.
+//
+// Template of Slack could be like:
+//
+//	{"text": {{ join .Messages "\n" | json }}}
+type Payload struct {
+	Entries []*Entry
+}
+
+// Messages returns level and message of each entry, like [error] connection refused.
+func (payload *Payload) Messages() []string {
+	res := make([]string, 0, len(payload.Entries))
+	for i := range payload.Entries {
+		res = append(res, "["+payload.Entries[i].Level+"] "+payload.Entries[i].Message)
+	}
+
+	return res
+}
+
+// Convert entry and fields to Entry
+func toEntry(entry zapcore.Entry, fieldLists ...[]zapcore.Field) *Entry {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, fields := range fieldLists {
+		for i := range fields {
+			fields[i].AddTo(enc)
+		}
+	}
+
+	res := &Entry{
+		Level:      entry.Level.String(),
+		Time:       entry.Time,
+		Logger:     entry.LoggerName,
+		Message:    entry.Message,
+		Stacktrace: entry.Stack,
+	}
+
+	if entry.Caller.Defined {
+		res.Caller = entry.Caller.TrimmedPath()
+	}
+
+	if len(enc.Fields) > 0 {
+		res.Fields = enc.Fields
+	}
+
+	return res
+}
+
+// Parse payload template, json and join functions are available besides builtin functions
+func newTemplate(text string) (*template.Template, error) {
+	return template.New(Scheme).Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			res, err := json.Marshal(v)
+			return string(res), err
+		},
+		"join": strings.Join,
+	}).Parse(text)
+}
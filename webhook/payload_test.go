@@ -0,0 +1,60 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package webhook
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"testing"
+	"time"
+)
+
+func TestToEntry(t *testing.T) {
+	now := time.Now()
+	entry := toEntry(zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       now,
+		LoggerName: "ut",
+		Message:    "ut-message",
+		Caller:     zapcore.NewEntryCaller(0, "/src/pkg/file.go", 10, true),
+		Stack:      "ut-stack",
+	}, []zapcore.Field{zap.String("key", "value")})
+
+	assert.Equal(t, &Entry{
+		Level:      "error",
+		Time:       now,
+		Logger:     "ut",
+		Caller:     "pkg/file.go:10",
+		Message:    "ut-message",
+		Stacktrace: "ut-stack",
+		Fields:     map[string]interface{}{"key": "value"},
+	}, entry)
+
+	// Without fields and caller
+	entry = toEntry(zapcore.Entry{Message: "ut-message"})
+	assert.Nil(t, entry.Fields)
+	assert.Empty(t, entry.Caller)
+}
+
+func TestPayload_Messages(t *testing.T) {
+	payload := &Payload{Entries: []*Entry{{Level: "error", Message: "a"}, {Level: "fatal", Message: "b"}}}
+	assert.Equal(t, []string{"[error] a", "[fatal] b"}, payload.Messages())
+}
+
+func TestNewTemplate(t *testing.T) {
+	tmpl, err := newTemplate(`{{ range .Entries }}{{ json .Fields }}{{ end }}`)
+	assert.Nil(t, err)
+
+	buf := &bytes.Buffer{}
+	payload := &Payload{Entries: []*Entry{{Fields: map[string]interface{}{"key": "value"}}}}
+	assert.Nil(t, tmpl.Execute(buf, payload))
+	assert.Equal(t, `{"key":"value"}`, buf.String())
+
+	// With invalid template
+	_, err = newTemplate(`{{ unknown }}`)
+	assert.NotNil(t, err)
+}
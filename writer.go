@@ -0,0 +1,151 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// WriterFactory creates write syncer with url of output path.
+// Write syncer would be closed with logger if it implements io.Closer.
+type WriterFactory func(u url.URL) (zapcore.WriteSyncer, error)
+
+var (
+	// writer factories keyed by lower case scheme
+	writerFactories = make(map[string]WriterFactory)
+	writerMutex     sync.RWMutex
+)
+
+// RegisterWriter registers factory of write syncers for output paths with scheme, like kafka://broker:9092/topic.
+//
+// The scheme is registered with zap.RegisterSink as well, so that output paths with the scheme work with
+// zap.Config.Build() too. Error would be returned if the scheme is already registered as writer or core.
+func RegisterWriter(scheme string, factory WriterFactory) error {
+	if factory == nil {
+		return errors.Errorf("writer factory is nil, scheme:%s", scheme)
+	}
+
+	scheme = strings.ToLower(scheme)
+
+	if isCoreScheme(scheme) {
+		return errors.Errorf("core is already registered, scheme:%s", scheme)
+	}
+
+	writerMutex.Lock()
+	defer writerMutex.Unlock()
+
+	if _, ok := writerFactories[scheme]; ok {
+		return errors.Errorf("writer is already registered, scheme:%s", scheme)
+	}
+
+	err := zap.RegisterSink(scheme, func(u *url.URL) (zap.Sink, error) {
+		syncer, err := factory(*u)
+		if err != nil {
+			return nil, err
+		}
+
+		return &writerSink{WriteSyncer: syncer, unregister: registerDeliveryStats(u, syncer)}, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to register writer, scheme:%s", scheme)
+	}
+
+	writerFactories[scheme] = factory
+	return nil
+}
+
+// Check whether lower case scheme is registered as writer
+func isWriterScheme(scheme string) bool {
+	writerMutex.RLock()
+	defer writerMutex.RUnlock()
+
+	_, ok := writerFactories[scheme]
+	return ok
+}
+
+// Find factory of write syncers registered with scheme of output path
+func lookupWriter(outputPath string) (WriterFactory, *url.URL, bool) {
+	u, err := url.Parse(outputPath)
+	if err != nil || len(u.Scheme) < 2 {
+		return nil, nil, false
+	}
+
+	writerMutex.RLock()
+	defer writerMutex.RUnlock()
+
+	factory, ok := writerFactories[strings.ToLower(u.Scheme)]
+	return factory, u, ok
+}
+
+// Create write syncer with factory, the returned function closes the write syncer
+func openWriter(factory WriterFactory, u *url.URL) (zapcore.WriteSyncer, func(), error) {
+	syncer, err := factory(*u)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create writer, scheme:%s", u.Scheme)
+	}
+
+	if syncer == nil {
+		return nil, nil, errors.Errorf("writer is nil, scheme:%s", u.Scheme)
+	}
+
+	sink := &writerSink{WriteSyncer: syncer, unregister: registerDeliveryStats(u, syncer)}
+	return &lockedWriter{sink: sink}, func() { sink.Close() }, nil
+}
+
+// lockedWriter serializes writes like zapcore.Lock, HealthChecker of write syncer is kept for failover outputs
+type lockedWriter struct {
+	sync.Mutex
+	sink *writerSink
+}
+
+// Write implements zapcore.WriteSyncer
+func (writer *lockedWriter) Write(p []byte) (int, error) {
+	writer.Lock()
+	defer writer.Unlock()
+
+	return writer.sink.Write(p)
+}
+
+// Sync implements zapcore.WriteSyncer
+func (writer *lockedWriter) Sync() error {
+	writer.Lock()
+	defer writer.Unlock()
+
+	return writer.sink.Sync()
+}
+
+// HealthCheck implements HealthChecker, write syncers which do not implement it are considered available
+func (writer *lockedWriter) HealthCheck(ctx context.Context) error {
+	if checker, ok := writer.sink.WriteSyncer.(HealthChecker); ok {
+		return checker.HealthCheck(ctx)
+	}
+
+	return nil
+}
+
+// writerSink implements zap.Sink with write syncer created by WriterFactory
+type writerSink struct {
+	zapcore.WriteSyncer
+	// unregister delivery counters of write syncer
+	unregister func()
+}
+
+// Close implements zap.Sink, write syncer is closed if it implements io.Closer
+func (sink *writerSink) Close() error {
+	sink.unregister()
+	if closer, ok := sink.WriteSyncer.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
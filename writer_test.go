@@ -0,0 +1,108 @@
+// Copyright (c) 2020 rookie-ninja
+//
+// Use of this source code is governed by an Apache-style
+// license that can be found in the LICENSE file.
+package rklogger
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"net/url"
+	"testing"
+)
+
+var (
+	utWriters = make(map[string]*memorySink)
+	_         = RegisterWriter("ut-writer", func(u url.URL) (zapcore.WriteSyncer, error) {
+		if u.Host == "invalid" {
+			return nil, errors.New("ut-error")
+		}
+
+		sink := &memorySink{}
+		utWriters[u.Host] = sink
+		return sink, nil
+	})
+)
+
+func TestRegisterWriter_WithCombinedConfig(t *testing.T) {
+	config := NewConfigWithOptions(WithOutput("ut-writer://combined"))
+	logger, closer, err := NewZapLoggerWithCloser(config)
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Nil(t, closer.Shutdown(context.Background()))
+	assert.Contains(t, utWriters["combined"].String(), "ut-message")
+	assert.True(t, utWriters["combined"].synced)
+	assert.True(t, utWriters["combined"].closed)
+}
+
+// Writers work with zap.Config.Build() as well
+func TestRegisterWriter_WithZapConfig(t *testing.T) {
+	config := NewZapStdoutConfig()
+	config.OutputPaths = []string{"UT-WRITER://zap"}
+
+	logger, err := config.Build()
+	assert.Nil(t, err)
+
+	logger.Info("ut-message")
+	assert.Contains(t, utWriters["zap"].String(), "ut-message")
+}
+
+// With error from factory
+func TestRegisterWriter_WithFactoryError(t *testing.T) {
+	logger, err := NewZapLoggerWithConfig(NewConfigWithOptions(WithOutput("ut-writer://invalid"), WithNameLevel("db", zapcore.DebugLevel)))
+	assert.Nil(t, logger)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to create writer, scheme:ut-writer")
+}
+
+// With registered scheme
+func TestRegisterWriter_WithDuplicateScheme(t *testing.T) {
+	err := RegisterWriter("UT-Writer", func(u url.URL) (zapcore.WriteSyncer, error) {
+		return &memorySink{}, nil
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "writer is already registered, scheme:ut-writer")
+
+	// scheme registered with zap.RegisterSink
+	err = RegisterWriter(LumberjackScheme, func(u url.URL) (zapcore.WriteSyncer, error) {
+		return &memorySink{}, nil
+	})
+	assert.NotNil(t, err)
+}
+
+// With invalid scheme and nil factory
+func TestRegisterWriter_WithInvalidArguments(t *testing.T) {
+	assert.NotNil(t, RegisterWriter("ut writer", func(u url.URL) (zapcore.WriteSyncer, error) {
+		return &memorySink{}, nil
+	}))
+	assert.NotNil(t, RegisterWriter("ut-nil", nil))
+
+	_, _, ok := lookupWriter("ut writer://ut")
+	assert.False(t, ok)
+}
+
+func TestLookupWriter(t *testing.T) {
+	factory, u, ok := lookupWriter("ut-writer://host/path")
+	assert.True(t, ok)
+	assert.NotNil(t, factory)
+	assert.Equal(t, "host", u.Host)
+
+	for _, outputPath := range []string{"stdout", "ut.log", "C:/logs/ut.log", "file:///ut.log", "%"} {
+		_, _, ok = lookupWriter(outputPath)
+		assert.False(t, ok, outputPath)
+	}
+}
+
+// With nil write syncer from factory
+func TestOpenWriter_WithNilWriteSyncer(t *testing.T) {
+	u, _ := url.Parse("ut-nil://ut")
+	syncer, closer, err := openWriter(func(u url.URL) (zapcore.WriteSyncer, error) {
+		return nil, nil
+	}, u)
+	assert.Nil(t, syncer)
+	assert.Nil(t, closer)
+	assert.NotNil(t, err)
+}